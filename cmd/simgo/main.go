@@ -2,124 +2,1372 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"slices"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
 
 	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+	"github.com/0ceanslim/go-simplicity/pkg/fix"
+	"github.com/0ceanslim/go-simplicity/pkg/gen"
 	"github.com/0ceanslim/go-simplicity/pkg/jets"
+	"github.com/0ceanslim/go-simplicity/pkg/report"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+	"github.com/0ceanslim/go-simplicity/pkg/testgen"
+	"github.com/0ceanslim/go-simplicity/pkg/transpiler"
 )
 
-const version = "1.3.41"
+const version = compiler.Version
 
-var (
-	input    = flag.String("input", "", "Input Go source file")
-	output   = flag.String("output", "", "Output SimplicityHL file (default: stdout)")
-	target   = flag.String("target", "simplicityhl", "Target format: simplicityhl, simplicity")
-	debug    = flag.Bool("debug", false, "Enable debug output")
-	help     = flag.Bool("help", false, "Show help message")
-	listJets = flag.Bool("list-jets", false, "List all registered jets and exit")
-	ver      = flag.Bool("version", false, "Print version and exit")
+// Exit codes. Wrappers can tell a contract that does not compile from an
+// environment problem and from a bug in simgo itself.
+const (
+	exitOK          = 0 // Success, or a run that accepts
+	exitDiagnostics = 1 // The contract does not compile, the flags are invalid, or a run rejects
+	exitIO          = 2 // An input could not be read or an output written
+	exitInternal    = 3 // simgo failed: a recovered panic, or output it cannot read back
 )
 
+// issuesURL is where internal errors should be reported.
+const issuesURL = "https://github.com/0ceanslim/go-simplicity/issues"
+
+// exitError ends run with code, reporting err when it is not nil.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+
+func (e *exitError) Unwrap() error { return e.err }
+
+// fail returns an error that ends run with code.
+func fail(code int, format string, args ...any) error {
+	return &exitError{code: code, err: fmt.Errorf(format, args...)}
+}
+
+// compileFailed classifies a compile error: a panic the compiler recovered
+// is an internal error, files that could not be read, such as imported
+// packages, or not overwritten are I/O errors and anything else is a
+// diagnostic about the contract.
+func compileFailed(prefix string, err error) error {
+	var pathErr *fs.PathError
+	var refused *gen.OverwriteError
+	var internal *compiler.InternalError
+	if errors.As(err, &internal) {
+		return fail(exitInternal, "%s: %w", prefix, err)
+	}
+	if errors.As(err, &pathErr) || errors.As(err, &refused) {
+		return fail(exitIO, "%s: %w", prefix, err)
+	}
+	return fail(exitDiagnostics, "%s: %w", prefix, err)
+}
+
+// buildFlags are the flags of the default compile command.
+type buildFlags struct {
+	input, output, target, mode *string
+	chain, dialect, extraJets   *string
+	debug, trace, help          *bool
+	listJets, ver               *bool
+	explain                     *string
+	force, includeIgnored       *bool
+	tags                        *string
+
+	witnessValues, reportFile   *string
+	splitOutput                 *string
+	selfCheck, allowTrivialMain *bool
+	noTransliterate, noComments *bool
+	checkedArithmetic, optimize *bool
+	noInline, noThresholdTrees  *bool
+	noCallFolding               *bool
+	noDCE, strict               *bool
+	optLevel                    compiler.OptLevel
+	maxNodes                    *int
+	maxWitnessBits              *int
+
+	indent          *string
+	blankLines      *int
+	trailingNewline *bool
+
+	entries  stringList
+	includes stringList
+}
+
+// logLevel returns the level -debug and -trace select.
+func (f *buildFlags) logLevel() compiler.LogLevel {
+	switch {
+	case *f.trace:
+		return compiler.LogTrace
+	case *f.debug:
+		return compiler.LogDebug
+	}
+	return compiler.LogInfo
+}
+
+func newBuildFlags(flags *flag.FlagSet) *buildFlags {
+	f := &buildFlags{
+		input:     flags.String("input", "", "Input Go source file"),
+		output:    flags.String("output", "", "Output SimplicityHL file or directory (default: stdout)"),
+		target:    flags.String("target", "simplicityhl", "Target format: simplicityhl, simplicity"),
+		mode:      flags.String("mode", "program", "Output kind: program, library"),
+		chain:     flags.String("chain", "elements", "Chain whose jets the program may use: elements, bitcoin"),
+		dialect:   flags.String("dialect", transpiler.LatestDialect, "SimplicityHL syntax version to write: "+strings.Join(transpiler.Dialects(), ", ")),
+		extraJets: flags.String("extra-jets", "", "JSON file of jets to declare beyond the builtin table"),
+		debug:     flags.Bool("debug", false, "Log each phase of the compile to stderr"),
+		trace:     flags.Bool("trace", false, "Log as -debug does, and the parsed Go AST"),
+		help:      flags.Bool("help", false, "Show help message"),
+		listJets:  flags.Bool("list-jets", false, "List all registered jets and exit"),
+		ver:       flags.Bool("version", false, "Print version and exit"),
+		explain:   flags.String("explain", "", "Print the explanation of a diagnostic code, such as SIM0001, and exit"),
+		force:     flags.Bool("force", false, "Overwrite output files that were not generated by simgo"),
+
+		tags:           flags.String("tags", "", "Comma-separated build tags that satisfy build constraints"),
+		includeIgnored: flags.Bool("include-ignored", false, "With a glob -input, also compile files excluded by build constraints"),
+
+		witnessValues: flags.String("witness-values", "", "JSON file of witness values substituted at compile time"),
+		reportFile:    flags.String("report", "", "Write a JSON report of the compiled functions to this file"),
+		splitOutput:   flags.String("split-output", "", "Also write each generated function to its own file in this directory, with a manifest"),
+		selfCheck:     flags.Bool("self-check", false, "Re-parse the generated SimplicityHL and fail if it is invalid"),
+		strict:        flags.Bool("strict", false, "Fail wherever the transpiler would fall back on a guess"),
+
+		allowTrivialMain: flags.Bool("allow-trivial-main", false, "Compile a program that accepts whatever the witness and transaction"),
+		noTransliterate:  flags.Bool("no-transliterate", false, "Reject non-ASCII identifiers instead of transliterating them"),
+		noComments:       flags.Bool("no-comments", false, "Leave Go doc comments out of the output"),
+
+		checkedArithmetic: flags.Bool("checked-arithmetic", false, "Fail the spend on a division by zero instead of computing the jet's result"),
+		optimize:          flags.Bool("optimize", false, "Compute a jet call that a function repeats once, in a let binding"),
+		noInline:          flags.Bool("no-inline", false, "Call helper functions instead of inlining their bodies"),
+		noThresholdTrees:  flags.Bool("no-threshold-trees", false, "Keep k-of-n counting instead of lowering it to a comparison tree"),
+		noCallFolding:     flags.Bool("no-call-folding", false, "Call helpers whose arguments are constants instead of evaluating them"),
+		noDCE:             flags.Bool("no-dce", false, "Keep the functions that main does not reach"),
+		maxNodes:          flags.Int("max-nodes", 0, "Fail once the program's estimated size passes this many expression nodes (0: default, -1: no limit)"),
+		maxWitnessBits:    flags.Int("max-witness-bits", 0, "Warn of each witness entry larger than this many bits (0: default, -1: no limit)"),
+
+		indent:          flags.String("indent", "4", "Indentation: number of spaces, or \"tab\""),
+		blankLines:      flags.Int("blank-lines", 1, "Blank lines between top-level functions"),
+		trailingNewline: flags.Bool("trailing-newline", true, "End output with a newline"),
+	}
+	flags.Var(&f.entries, "entry", "Exported function compiled as the program root (repeatable, or \"all-exported\")")
+	flags.Var(&f.includes, "include", "Compiled SimplicityHL library whose functions Go stubs call (repeatable)")
+	for level, usage := range map[compiler.OptLevel]string{
+		compiler.O0: "Translate each function as written: call helpers, emit all of them",
+		compiler.O1: "Also evaluate calls with constant arguments and leave out the functions that main does not reach",
+		compiler.O2: "Also inline helpers, lower k-of-n counting and hoist repeated jet calls",
+	} {
+		flags.BoolFunc(strings.TrimPrefix(level.String(), "-"), usage, func(string) error {
+			f.optLevel = level
+			return nil
+		})
+	}
+	return f
+}
+
+// allExported selects every exported function as an entry point.
+const allExported = "all-exported"
+
+// stringList collects the values of a repeated flag, such as -entry.
+type stringList []string
+
+func (e *stringList) String() string { return strings.Join(*e, ",") }
+
+func (e *stringList) Set(value string) error {
+	*e = append(*e, value)
+	return nil
+}
+
 func main() {
-	flag.Parse()
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes simgo with the command-line arguments args and returns its
+// exit code. The compiled program is the only thing written to stdout;
+// diagnostics go to stderr, prefixed with their severity. A panic is
+// recovered and reported as an internal error.
+func run(args []string, stdout, stderr io.Writer) (code int) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(stderr, "internal error: %v\n%s\n", r, debug.Stack())
+			fmt.Fprintf(stderr, "This is a bug in simgo %s. Please report it at %s, with the input that triggered it.\n", version, issuesURL)
+			code = exitInternal
+		}
+	}()
+
+	var err error
+	switch {
+	case len(args) > 0 && args[0] == "gen":
+		err = runGen(args[1:], stdout, stderr)
+	case len(args) > 0 && args[0] == "test-gen":
+		err = runTestGen(args[1:], stdout, stderr)
+	case len(args) > 0 && args[0] == "run":
+		err = runRun(args[1:], stdout, stderr)
+	case len(args) > 0 && args[0] == "explain":
+		err = runExplain(args[1:], stdout, stderr)
+	case len(args) > 0 && args[0] == "capabilities":
+		err = runCapabilities(args[1:], stdout, stderr)
+	case len(args) > 0 && args[0] == "fix":
+		err = runFix(args[1:], stdout, stderr)
+	case len(args) > 0 && args[0] == "expr":
+		err = runExpr(args[1:], stdout, stderr)
+	case len(args) > 0 && args[0] == "build":
+		// build is the default compile mode, spelled out.
+		err = runBuild(args[1:], stdout, stderr)
+	default:
+		err = runBuild(args, stdout, stderr)
+	}
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+
+	return reportError(stderr, err)
+}
 
-	if *ver {
-		fmt.Printf("simgo version %s\n", version)
-		return
+// reportError prints err to stderr by severity and returns its exit code.
+func reportError(stderr io.Writer, err error) int {
+	code := exitDiagnostics
+	var exit *exitError
+	if errors.As(err, &exit) {
+		code = exit.code
+		if exit.err == nil {
+			return code
+		}
+	}
+	severity := "error"
+	if code == exitInternal {
+		severity = "internal error"
+	}
+	fmt.Fprintf(stderr, "%s: %v\n", severity, err)
+	var internal *compiler.InternalError
+	if errors.As(err, &internal) {
+		fmt.Fprintf(stderr, "%s\n", internal.Stack)
 	}
+	if code == exitInternal {
+		fmt.Fprintf(stderr, "This is a bug in simgo %s. Please report it at %s, with the input that triggered it.\n", version, issuesURL)
+	}
+	return code
+}
 
-	if *listJets {
-		printJets()
-		return
+// parseFlags parses args into flags, which reports its own errors to stderr.
+func parseFlags(flags *flag.FlagSet, args []string, stderr io.Writer) error {
+	flags.SetOutput(stderr)
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &exitError{code: exitDiagnostics}
 	}
+	return nil
+}
 
-	if *help {
-		printHelp()
-		return
+// noArgs rejects the arguments left after the flags of cmd, which takes
+// none: flag stops at the first, so a flag after it, as in
+// simgo run prog.go -witness w.json, would be ignored.
+func noArgs(flags *flag.FlagSet, cmd string) error {
+	if flags.NArg() == 0 {
+		return nil
 	}
+	return fail(exitDiagnostics, "%s takes no arguments, got %s: give the input with -input, and put every flag before any argument", cmd, flags.Arg(0))
+}
 
-	if *input == "" {
-		fmt.Fprintf(os.Stderr, "Error: Input file is required\n\n")
-		printUsage()
-		os.Exit(1)
+// runBuild implements the default compile command.
+func runBuild(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("simgo", flag.ContinueOnError)
+	f := newBuildFlags(flags)
+	flags.Usage = func() { printUsage(flags, stderr) }
+	if err := parseFlags(flags, args, stderr); err != nil {
+		return err
+	}
+	if err := noArgs(flags, "simgo build"); err != nil {
+		return err
 	}
 
-	// Check if input file exists
-	if _, err := os.Stat(*input); os.IsNotExist(err) {
-		log.Fatalf("Input file does not exist: %s", *input)
+	if *f.ver {
+		fmt.Fprintf(stdout, "simgo version %s\n", version)
+		return nil
+	}
+
+	extraJets, err := readExtraJets(*f.extraJets)
+	if err != nil {
+		return err
+	}
+	if *f.listJets {
+		return printJets(stdout, extraJets)
+	}
+
+	if *f.explain != "" {
+		return explain(stdout, *f.explain)
+	}
+
+	if *f.help {
+		printHelp(stdout)
+		return nil
+	}
+
+	if *f.input == "" {
+		fmt.Fprintf(stderr, "error: input file is required\n\n")
+		printUsage(flags, stderr)
+		return &exitError{code: exitDiagnostics}
+	}
+
+	style, err := parseStyle(*f.indent, *f.blankLines, *f.trailingNewline)
+	if err != nil {
+		return fmt.Errorf("invalid formatting options: %w", err)
+	}
+
+	config := compiler.Config{
+		Target:     *f.target,
+		LogLevel:   f.logLevel(),
+		Logger:     compiler.NewLogger(stderr),
+		Style:      style,
+		Mode:       *f.mode,
+		Chain:      *f.chain,
+		SHLDialect: *f.dialect,
+		ExtraJets:  extraJets,
+		SelfCheck:  *f.selfCheck,
+		Strict:     *f.strict,
+		BuildTags:  splitTags(*f.tags),
+
+		AllowTrivialMain:  *f.allowTrivialMain,
+		NoTransliteration: *f.noTransliterate,
+		NoComments:        *f.noComments,
+		CheckedArithmetic: *f.checkedArithmetic,
+		Optimize:          *f.optimize,
+		MaxNodes:          *f.maxNodes,
+		MaxWitnessBits:    *f.maxWitnessBits,
+
+		OptLevel:         f.optLevel,
+		NoInline:         *f.noInline,
+		NoThresholdTrees: *f.noThresholdTrees,
+		NoCallFolding:    *f.noCallFolding,
+		NoDCE:            *f.noDCE,
+	}
+	for _, path := range f.includes {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fail(exitIO, "failed to read included module: %w", err)
+		}
+		config.Includes = append(config.Includes, compiler.Include{Path: path, Source: string(data)})
+	}
+	if *f.witnessValues != "" {
+		data, err := os.ReadFile(*f.witnessValues)
+		if err != nil {
+			return fail(exitIO, "failed to read witness values: %w", err)
+		}
+		if config.WitnessValues, err = eval.ParseWitnessJSON(data); err != nil {
+			return err
+		}
+	}
+
+	if *f.splitOutput != "" && (isGlob(*f.input) || len(f.entries) > 1 || slices.Contains(f.entries, allExported)) {
+		return fmt.Errorf("-split-output takes a single input and entry point")
+	}
+	if isGlob(*f.input) {
+		return runBatch(f, config, stderr)
 	}
 
 	// Read input file
-	source, err := os.ReadFile(*input)
+	source, err := os.ReadFile(*f.input)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fail(exitIO, "input file does not exist: %s", *f.input)
+	}
 	if err != nil {
-		log.Fatalf("Failed to read input file: %v", err)
+		return fail(exitIO, "failed to read input file: %w", err)
+	}
+
+	// Several entry points produce one output file each
+	names, err := resolveEntries(string(source), *f.input, f.entries)
+	if err != nil {
+		return fmt.Errorf("invalid -entry: %w", err)
+	}
+	if len(names) > 1 || slices.Contains(f.entries, allExported) {
+		return compileEntries(f, string(source), config, names, stderr)
+	}
+	if len(names) == 1 {
+		config.Entry = names[0]
 	}
 
 	// Create compiler instance
-	c := compiler.New(compiler.Config{
-		Target: *target,
-		Debug:  *debug,
-	})
+	c := compiler.New(config)
 
 	// Compile Go source to target format
-	result, err := c.Compile(string(source), *input)
+	result, err := c.Compile(string(source), *f.input)
+	printWarnings(stderr, c.Warnings())
 	if err != nil {
-		log.Fatalf("Compilation failed: %v", err)
+		return compileFailed("compilation failed", err)
+	}
+	if err := writeReport(*f.reportFile, *f.input, c.Result()); err != nil {
+		return err
+	}
+	if *f.splitOutput != "" {
+		if err := writeSplit(*f.splitOutput, *f.input, c.Result(), *f.force); err != nil {
+			return err
+		}
+		if *f.output == "" {
+			return nil
+		}
 	}
 
 	// Write output
-	if *output == "" {
-		fmt.Print(result)
+	if *f.output == "" {
+		if _, err := io.WriteString(stdout, result); err != nil {
+			return fail(exitIO, "failed to write output: %w", err)
+		}
+		return nil
+	}
+	path := outputPath(*f.output, *f.input)
+	if err := writeOutput(path, *f.input, result, *f.force); err != nil {
+		return err
+	}
+	if f.logLevel() >= compiler.LogDebug {
+		fmt.Fprintf(stderr, "Successfully compiled %s to %s\n", *f.input, path)
+	}
+	return nil
+}
+
+// outputPath resolves the -output flag: a directory, or a path ending in a
+// separator, receives a file named after the input (swap.go → swap.simf).
+func outputPath(output, input string) string {
+	if info, err := os.Stat(output); (err == nil && info.IsDir()) || strings.HasSuffix(output, string(filepath.Separator)) {
+		return filepath.Join(output, strings.TrimSuffix(filepath.Base(input), ".go")+".simf")
+	}
+	return output
+}
+
+// writeOutput writes program to path under a provenance header naming
+// input, as gen.WriteFile does. An existing file lacking the header is
+// only replaced with force.
+func writeOutput(path, input, program string, force bool) error {
+	err := gen.WriteFile(path, input, program, force)
+	var refused *gen.OverwriteError
+	switch {
+	case errors.As(err, &refused):
+		return fail(exitIO, "%v", err)
+	case err != nil:
+		return fail(exitIO, "failed to write output file: %w", err)
+	}
+	return nil
+}
+
+// manifestFile lists the files of a -split-output directory.
+const manifestFile = "manifest.json"
+
+// splitManifest is the manifest.json of a -split-output directory.
+type splitManifest struct {
+	Source  string      `json:"source"`
+	Entry   string      `json:"entry,omitempty"` // Empty in library mode
+	Program splitFile   `json:"program"`
+	Pieces  []splitFile `json:"pieces"`
+}
+
+// splitFile is one file of a -split-output directory and the SHA-256 of
+// its contents.
+type splitFile struct {
+	Name   string `json:"name,omitempty"` // Function the file defines; none for the modules
+	File   string `json:"file"`
+	SHA256 string `json:"sha256"`
+}
+
+// writeSplit writes the program of result to dir as program.simf, with
+// each of its pieces in a file of its own as well: fn_<name>.simf for a
+// function and mod.simf for the witness and param modules, which no
+// function name can clash with. program.simf is the pieces concatenated.
+// manifest.json lists the files in program order with their hashes; the
+// files of an earlier manifest that this one no longer lists are removed.
+func writeSplit(dir, input string, result *compiler.CompileResult, force bool) error {
+	manifest := splitManifest{Source: filepath.Base(input), Entry: result.Entry}
+	written := make(map[string]bool)
+	write := func(name, file, code string) (splitFile, error) {
+		written[file] = true
+		if err := writeOutput(filepath.Join(dir, file), input, code, force); err != nil {
+			return splitFile{}, err
+		}
+		return splitFile{Name: name, File: file, SHA256: fmt.Sprintf("%x", sha256.Sum256(gen.GeneratedFile(input, code)))}, nil
+	}
+	for _, piece := range result.Pieces {
+		file := "mod.simf"
+		if piece.Name != "" {
+			file = "fn_" + piece.Name + ".simf"
+		}
+		entry, err := write(piece.Name, file, piece.Code+"\n")
+		if err != nil {
+			return err
+		}
+		manifest.Pieces = append(manifest.Pieces, entry)
+	}
+	var err error
+	if manifest.Program, err = write("", "program.simf", result.Code); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fail(exitInternal, "failed to encode manifest: %w", err)
+	}
+	// The pieces of a function since removed go with it.
+	path := filepath.Join(dir, manifestFile)
+	var previous splitManifest
+	if old, err := os.ReadFile(path); err == nil && json.Unmarshal(old, &previous) == nil {
+		for _, piece := range previous.Pieces {
+			stale := filepath.Join(dir, filepath.Base(piece.File))
+			if existing, err := os.ReadFile(stale); err == nil && !written[filepath.Base(piece.File)] && gen.IsGenerated(existing) {
+				if err := os.Remove(stale); err != nil {
+					return fail(exitIO, "failed to remove %s: %w", stale, err)
+				}
+			}
+		}
+	}
+	if err := gen.WriteAtomic(path, append(data, '\n')); err != nil {
+		return fail(exitIO, "failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// isGlob reports whether the -input flag is a glob pattern for batch mode.
+func isGlob(input string) bool {
+	return strings.ContainsAny(input, "*?[")
+}
+
+// batchFile is one file of a batch compile and its outcome.
+type batchFile struct {
+	input, output string
+	warnings      []string
+	err           error
+}
+
+// runBatch compiles every Go file matching the -input pattern to its own
+// .simf in the -output directory. Files are compiled concurrently and
+// independently, and a failing file does not stop the others; each failure
+// is reported, followed by a summary.
+func runBatch(f *buildFlags, config compiler.Config, stderr io.Writer) error {
+	if *f.output == "" {
+		return fmt.Errorf("a glob -input requires -output to name a directory")
+	}
+	if len(f.entries) > 0 || *f.reportFile != "" {
+		return fmt.Errorf("-entry and -report cannot be combined with a glob -input")
+	}
+	files, err := batchFiles(*f.input, *f.output, config.BuildTags, *f.includeIgnored, stderr)
+	if err != nil {
+		return err
+	}
+
+	// One goroutine per file, at most GOMAXPROCS of them compiling at once.
+	// Each has its own Compiler; imported helper packages are loaded once
+	// into the shared cache.
+	config.Packages = compiler.NewPackageCache()
+	slots := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+	for _, file := range files {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			file.err = compileFile(file, config, *f.force)
+		}()
+	}
+	wg.Wait()
+
+	code, failed := exitOK, 0
+	for _, file := range files {
+		printWarnings(stderr, file.warnings)
+		if file.err != nil {
+			failed++
+			code = max(code, reportError(stderr, file.err))
+		} else if f.logLevel() >= compiler.LogDebug {
+			fmt.Fprintf(stderr, "Successfully compiled %s to %s\n", file.input, file.output)
+		}
+	}
+	fmt.Fprintf(stderr, "%d succeeded, %d failed\n", len(files)-failed, failed)
+	if failed > 0 {
+		return &exitError{code: code}
+	}
+	return nil
+}
+
+// batchFiles expands pattern into the files of a batch, skipping tests and,
+// unless includeIgnored is set, files whose build constraints tags do not
+// satisfy, such as the examples' //go:build ignore. Two inputs may not
+// share an output.
+func batchFiles(pattern, outDir string, tags []string, includeIgnored bool, stderr io.Writer) ([]*batchFile, error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -input pattern %q: %w", pattern, err)
+	}
+
+	var files []*batchFile
+	ignored := 0
+	outputs := make(map[string]string)
+	for _, path := range matches {
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		if !includeIgnored {
+			src, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fail(exitIO, "failed to read input file: %w", err)
+			}
+			match, err := compiler.MatchBuildTags(src, tags)
+			if err != nil {
+				return nil, fmt.Errorf("failed to evaluate build constraints for %s: %w", path, err)
+			}
+			if !match {
+				ignored++
+				continue
+			}
+		}
+		out := outputPath(outDir+string(filepath.Separator), path)
+		if prev, ok := outputs[out]; ok {
+			return nil, fmt.Errorf("%s and %s would both be written to %s", prev, path, out)
+		}
+		outputs[out] = path
+		files = append(files, &batchFile{input: path, output: out})
+	}
+
+	if ignored > 0 {
+		fmt.Fprintf(stderr, "warning: skipped %d file(s) excluded by build constraints; pass -include-ignored to compile them\n", ignored)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Go files to compile match %s", pattern)
+	}
+	return files, nil
+}
+
+// compileFile compiles one file of a batch. It runs on its own goroutine,
+// so a panic is recovered here and reported as an internal error.
+func compileFile(file *batchFile, config compiler.Config, force bool) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fail(exitInternal, "%s: %v\n%s", file.input, r, debug.Stack())
+		}
+	}()
+	source, err := os.ReadFile(file.input)
+	if err != nil {
+		return fail(exitIO, "failed to read input file: %w", err)
+	}
+	c := compiler.New(config)
+	result, err := c.Compile(string(source), file.input)
+	file.warnings = c.Warnings()
+	if err != nil {
+		return compileFailed("compilation of "+file.input+" failed", err)
+	}
+	return writeOutput(file.output, file.input, result, force)
+}
+
+// resolveEntries expands the -entry flags into function names, replacing
+// "all-exported" with every exported function in the source.
+func resolveEntries(source, filename string, flags []string) ([]string, error) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range flags {
+		expanded := []string{name}
+		if name == allExported {
+			var err error
+			expanded, err = compiler.ExportedFunctions(source, filename)
+			if err != nil {
+				return nil, err
+			}
+			if len(expanded) == 0 {
+				return nil, fmt.Errorf("%s has no exported functions", filename)
+			}
+		}
+		for _, n := range expanded {
+			if !seen[n] {
+				seen[n] = true
+				names = append(names, n)
+			}
+		}
+	}
+	return names, nil
+}
+
+// compileEntries compiles each entry point to <output>/<Entry>.simf. The
+// -output flag names the directory and is required.
+func compileEntries(f *buildFlags, source string, config compiler.Config, names []string, stderr io.Writer) error {
+	if *f.output == "" {
+		return fmt.Errorf("multiple entry points require -output to name a directory")
+	}
+	var results []*compiler.CompileResult
+	for _, name := range names {
+		config.Entry = name
+		c := compiler.New(config)
+		result, err := c.Compile(source, *f.input)
+		printWarnings(stderr, c.Warnings())
+		if err != nil {
+			return compileFailed("compilation of entry "+name+" failed", err)
+		}
+		results = append(results, c.Result())
+		path := filepath.Join(*f.output, name+".simf")
+		if err := writeOutput(path, *f.input, result, *f.force); err != nil {
+			return err
+		}
+		if f.logLevel() >= compiler.LogDebug {
+			fmt.Fprintf(stderr, "Successfully compiled %s (%s) to %s\n", *f.input, name, path)
+		}
+	}
+	return writeReport(*f.reportFile, *f.input, results...)
+}
+
+// writeReport writes the -report file, if one was requested.
+func writeReport(path, input string, results ...*compiler.CompileResult) error {
+	if path == "" {
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := report.New(input, results...).Write(&buf); err != nil {
+		return fail(exitIO, "failed to write report: %w", err)
+	}
+	if err := gen.WriteAtomic(path, buf.Bytes()); err != nil {
+		return fail(exitIO, "failed to write report: %w", err)
+	}
+	return nil
+}
+
+// printWarnings reports the warnings of a compile on stderr.
+func printWarnings(stderr io.Writer, warnings []string) {
+	for _, w := range warnings {
+		fmt.Fprintf(stderr, "warning: %s\n", w)
+	}
+}
+
+// splitTags parses a comma-separated -tags flag.
+func splitTags(tags string) []string {
+	var list []string
+	for _, tag := range strings.Split(tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			list = append(list, tag)
+		}
+	}
+	return list
+}
+
+// parseStyle builds the output style from the formatting flags.
+func parseStyle(indent string, blankLines int, trailingNewline bool) (transpiler.Style, error) {
+	style := transpiler.DefaultStyle()
+	if indent == "tab" {
+		style.Indent = "\t"
 	} else {
-		err := os.WriteFile(*output, []byte(result), 0644)
+		n, err := strconv.Atoi(indent)
+		if err != nil || n < 0 {
+			return style, fmt.Errorf("-indent must be a non-negative number or \"tab\", got %q", indent)
+		}
+		style.Indent = strings.Repeat(" ", n)
+	}
+	if blankLines < 0 {
+		return style, fmt.Errorf("-blank-lines must be non-negative, got %d", blankLines)
+	}
+	style.BlankLinesBetweenFuncs = blankLines
+	style.TrailingNewline = trailingNewline
+	return style, nil
+}
+
+// runGen implements `simgo gen [options] [dir]`, which compiles every
+// //simplicity:contract entry point in a package directory. It is meant to be
+// invoked from a //go:generate line, so dir defaults to the current directory.
+func runGen(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("gen", flag.ContinueOnError)
+	outDir := flags.String("out", "", "Output directory for .simf files (default: package directory)")
+	tags := flags.String("tags", "", "Comma-separated build tags used to select files")
+	force := flags.Bool("force", false, "Overwrite .simf files that simgo did not generate")
+	genTarget := flags.String("target", "simplicityhl", "Target format: simplicityhl, simplicity")
+	genIndent := flags.String("indent", "4", "Indentation: number of spaces, or \"tab\"")
+	genBlankLines := flags.Int("blank-lines", 1, "Blank lines between top-level functions")
+	genTrailingNewline := flags.Bool("trailing-newline", true, "End output with a newline")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: simgo gen [options] [dir]\n")
+		flags.PrintDefaults()
+	}
+	if err := parseFlags(flags, args, stderr); err != nil {
+		return err
+	}
+
+	dir := "."
+	if flags.NArg() > 0 {
+		dir = flags.Arg(0)
+	}
+
+	style, err := parseStyle(*genIndent, *genBlankLines, *genTrailingNewline)
+	if err != nil {
+		return fmt.Errorf("invalid formatting options: %w", err)
+	}
+
+	results, err := gen.Generate(dir, gen.Options{
+		OutDir: *outDir,
+		Tags:   splitTags(*tags),
+		Config: compiler.Config{Target: *genTarget, Style: style},
+		Force:  *force,
+	})
+	for _, r := range results {
+		fmt.Fprintf(stderr, "generated %s from %s (%s)\n", r.Output, r.Contract.File, r.Contract.Entry)
+	}
+	if err != nil {
+		return compileFailed("generation failed", err)
+	}
+	if len(results) == 0 {
+		fmt.Fprintf(stderr, "no %s entry points found in %s\n", gen.Directive, dir)
+		return nil
+	}
+	fmt.Fprintf(stderr, "%d contract(s) generated\n", len(results))
+	return nil
+}
+
+// runTestGen implements `simgo test-gen -input contract.go`, which turns the
+// table-driven Go tests next to a contract into Simplicity test vectors.
+func runTestGen(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("test-gen", flag.ContinueOnError)
+	in := flags.String("input", "", "Contract Go source file")
+	format := flags.String("format", "json", "Output format: json (one vector file) or simf (.simf + .wit per case)")
+	out := flags.String("output", "", "Output file for json (default: stdout) or directory for simf")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: simgo test-gen -input <go-file> [-format json|simf] [-output path]\n")
+		flags.PrintDefaults()
+	}
+	if err := parseFlags(flags, args, stderr); err != nil {
+		return err
+	}
+
+	if *in == "" {
+		flags.Usage()
+		return &exitError{code: exitDiagnostics}
+	}
+
+	config := compiler.Config{Target: "simplicityhl"}
+	suite, warnings, err := testgen.Extract(*in, config)
+	for _, w := range warnings {
+		fmt.Fprintf(stderr, "warning: %s\n", w)
+	}
+	if err != nil {
+		return compileFailed("test generation failed", err)
+	}
+
+	switch *format {
+	case "json":
+		w := stdout
+		if *out != "" {
+			f, err := os.Create(*out)
+			if err != nil {
+				return fail(exitIO, "failed to create output file: %w", err)
+			}
+			defer f.Close()
+			w = f
+		}
+		if err := testgen.WriteJSON(w, suite); err != nil {
+			return fail(exitIO, "failed to write test vectors: %w", err)
+		}
+	case "simf":
+		if *out == "" {
+			return fmt.Errorf("-format simf requires -output to name a directory")
+		}
+		written, err := testgen.WritePairs(*out, suite, config)
 		if err != nil {
-			log.Fatalf("Failed to write output file: %v", err)
-		}
-		if *debug {
-			fmt.Printf("Successfully compiled %s to %s\n", *input, *output)
-		}
-	}
-}
-
-func printUsage() {
-	fmt.Fprintf(os.Stderr, "Usage: %s -input <go-file> [options]\n", os.Args[0])
-	flag.PrintDefaults()
-}
-
-func printHelp() {
-	fmt.Printf("go-simplicity - Go to Simplicity transpiler\n\n")
-	fmt.Printf("USAGE:\n")
-	fmt.Printf("    %s -input <go-file> [options]\n\n", os.Args[0])
-	fmt.Printf("OPTIONS:\n")
-	fmt.Printf("    -input string\n")
-	fmt.Printf("        Input Go source file (required)\n")
-	fmt.Printf("    -output string\n")
-	fmt.Printf("        Output SimplicityHL file (default: stdout)\n")
-	fmt.Printf("    -target string\n")
-	fmt.Printf("        Target format: simplicityhl, simplicity (default: simplicityhl)\n")
-	fmt.Printf("    -debug\n")
-	fmt.Printf("        Enable debug output\n")
-	fmt.Printf("    -list-jets\n")
-	fmt.Printf("        List all registered jets and exit\n")
-	fmt.Printf("    -version\n")
-	fmt.Printf("        Print version and exit\n")
-	fmt.Printf("    -help\n")
-	fmt.Printf("        Show this help message\n\n")
-	fmt.Printf("EXAMPLES:\n")
-	fmt.Printf("    # Compile to stdout\n")
-	fmt.Printf("    %s -input examples/basic_swap.go\n\n", os.Args[0])
-	fmt.Printf("    # Compile to file\n")
-	fmt.Printf("    %s -input examples/basic_swap.go -output basic_swap.shl\n\n", os.Args[0])
-	fmt.Printf("    # Enable debug output\n")
-	fmt.Printf("    %s -input examples/basic_swap.go -debug\n\n", os.Args[0])
-}
-
-func printJets() {
+			return fail(exitIO, "failed to write test programs: %w", err)
+		}
+		fmt.Fprintf(stderr, "%d test case(s) written to %s\n", len(written)/2, *out)
+	default:
+		return fmt.Errorf("unsupported -format: %s", *format)
+	}
+	return nil
+}
+
+// runRun compiles a contract and evaluates it with the built-in evaluator.
+// It succeeds when the program accepts and fails with exitDiagnostics when
+// it rejects, reporting the failing check at its Go source position.
+func runRun(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	in := flags.String("input", "", "Contract Go source file")
+	witnessFile := flags.String("witness", "", "JSON file of witness values (default: the compiled placeholders)")
+	txFile := flags.String("tx", "", "JSON description of the spending transaction, for introspection jets")
+	entry := flags.String("entry", "", "Exported function compiled as the program root (default: main)")
+	tags := flags.String("tags", "", "Comma-separated build tags that satisfy build constraints")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: simgo run -input <go-file> [-witness values.json] [-tx tx.json] [-entry name] [-tags list]\n")
+		flags.PrintDefaults()
+	}
+	if err := parseFlags(flags, args, stderr); err != nil {
+		return err
+	}
+	if err := noArgs(flags, "simgo run"); err != nil {
+		return err
+	}
+
+	if *in == "" {
+		flags.Usage()
+		return &exitError{code: exitDiagnostics}
+	}
+
+	source, err := os.ReadFile(*in)
+	if err != nil {
+		return fail(exitIO, "failed to read input file: %w", err)
+	}
+	c := compiler.New(compiler.Config{Target: "simplicityhl", Entry: *entry, BuildTags: splitTags(*tags)})
+	result, err := c.Compile(string(source), *in)
+	printWarnings(stderr, c.Warnings())
+	if err != nil {
+		return compileFailed("compilation failed", err)
+	}
+	prog, err := shlparse.Parse(result)
+	if err != nil {
+		return fail(exitInternal, "generated SimplicityHL could not be parsed: %w", err)
+	}
+
+	var opts eval.Options
+	if *witnessFile != "" {
+		data, err := os.ReadFile(*witnessFile)
+		if err != nil {
+			return fail(exitIO, "failed to read witness file: %w", err)
+		}
+		if opts.Witness, err = eval.ParseWitnessJSON(data); err != nil {
+			return err
+		}
+	}
+	if *txFile != "" {
+		data, err := os.ReadFile(*txFile)
+		if err != nil {
+			return fail(exitIO, "failed to read tx file: %w", err)
+		}
+		if opts.Tx, err = eval.ParseTx(data); err != nil {
+			return fmt.Errorf("invalid tx environment %s: %w", *txFile, err)
+		}
+	}
+
+	err = eval.Run(prog, opts)
+	var rejection *eval.Rejection
+	var needsTx *eval.ContextError
+	switch {
+	case err == nil:
+		fmt.Fprintln(stdout, "accepted")
+		return nil
+	case errors.As(err, &rejection):
+		fmt.Fprintf(stderr, "rejected: %s: %s\n", sourceLocation(c, rejection.Line), rejection.Reason)
+		return &exitError{code: exitDiagnostics}
+	case errors.As(err, &needsTx):
+		return fmt.Errorf("evaluation failed: %s: jet::%s requires -tx context", sourceLocation(c, needsTx.Line), needsTx.Jet)
+	default:
+		return fmt.Errorf("evaluation failed: %w", err)
+	}
+}
+
+// sourceLocation describes a line of generated SimplicityHL by the Go
+// position it was compiled from, falling back to the generated line.
+func sourceLocation(c *compiler.Compiler, line int) string {
+	if pos, ok := c.SourcePosition(line); ok {
+		return pos.String()
+	}
+	return fmt.Sprintf("line %d of the generated program", line)
+}
+
+func printUsage(flags *flag.FlagSet, w io.Writer) {
+	fmt.Fprintf(w, "Usage: simgo -input <go-file> [options]\n")
+	flags.PrintDefaults()
+}
+
+// runExplain implements simgo explain: the explanation of one diagnostic
+// code, or a list of them all.
+func runExplain(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("explain", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: simgo explain [code]\n")
+	}
+	if err := parseFlags(flags, args, stderr); err != nil {
+		return err
+	}
+	switch flags.NArg() {
+	case 0:
+		for _, e := range diag.All() {
+			fmt.Fprintf(stdout, "%s  %s\n", e.Code, e.Title)
+		}
+		return nil
+	case 1:
+		return explain(stdout, flags.Arg(0))
+	}
+	flags.Usage()
+	return &exitError{code: exitDiagnostics}
+}
+
+// runCapabilities implements simgo capabilities: what each target and
+// chain supports, or one of them with -target or -chain.
+func runCapabilities(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("capabilities", flag.ContinueOnError)
+	target := flags.String("target", "", "Only this target")
+	chain := flags.String("chain", "", "Only this chain")
+	format := flags.String("format", "text", "Output format: text, json")
+	extraJetsFile := flags.String("extra-jets", "", "JSON file of jets to declare beyond the builtin table")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: simgo capabilities [-target name] [-chain name] [-extra-jets file] [-format text|json]\n")
+		flags.PrintDefaults()
+	}
+	if err := parseFlags(flags, args, stderr); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return &exitError{code: exitDiagnostics}
+	}
+	if *format != "text" && *format != "json" {
+		return fail(exitDiagnostics, "unsupported -format: %s", *format)
+	}
+	extraJets, err := readExtraJets(*extraJetsFile)
+	if err != nil {
+		return err
+	}
+
+	var matrix []compiler.Capability
+	for _, c := range compiler.CapabilityMatrix() {
+		if (*target == "" || c.Target == *target) && (*chain == "" || c.Chain == *chain) {
+			if len(extraJets) > 0 {
+				c = compiler.Capabilities(compiler.Config{Target: c.Target, Chain: c.Chain, ExtraJets: extraJets})
+			}
+			matrix = append(matrix, c)
+		}
+	}
+	if len(matrix) == 0 {
+		// An unknown target or chain: say so as Compile would
+		config := compiler.Config{Target: *target, Chain: *chain}
+		if config.Target == "" {
+			config.Target = "simplicityhl"
+		}
+		return fail(exitDiagnostics, "%v", compiler.Capabilities(config).Err())
+	}
+
+	if *format == "json" {
+		data, err := json.MarshalIndent(matrix, "", "  ")
+		if err != nil {
+			return fail(exitInternal, "failed to encode capabilities: %w", err)
+		}
+		fmt.Fprintf(stdout, "%s\n", data)
+		return nil
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "TARGET\tCHAIN\tJETS\tMAX WORD\tWITNESSES\tCMR\n")
+	for _, c := range matrix {
+		if !c.Supported {
+			fmt.Fprintf(tw, "%s\t%s\t-\t-\t-\t-\t(%s)\n", c.Target, c.Chain, c.Reason)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\tu%d\t%s\t%s\n", c.Target, c.Chain, len(c.Jets), c.MaxWordBits, yesNo(c.Witnesses), yesNo(c.CMR))
+	}
+	return tw.Flush()
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// explain prints the long explanation of code.
+func explain(w io.Writer, code string) error {
+	e, ok := diag.Lookup(code)
+	if !ok {
+		return fail(exitDiagnostics, "unknown diagnostic code %s; simgo explain lists them", code)
+	}
+	fmt.Fprintf(w, "%s: %s\n\n%s\n", e.Code, e.Title, e.Text)
+	return nil
+}
+
+// runFix implements simgo fix: it prints the fixes for a contract as JSON
+// or, with -write, applies the safe ones to the file in place.
+func runFix(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("fix", flag.ContinueOnError)
+	in := flags.String("input", "", "Contract Go source file")
+	write := flags.Bool("write", false, "Apply the safe fixes to the input file instead of printing them")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: simgo fix -input <go-file> [-write]\n")
+		flags.PrintDefaults()
+	}
+	if err := parseFlags(flags, args, stderr); err != nil {
+		return err
+	}
+
+	if *in == "" {
+		flags.Usage()
+		return &exitError{code: exitDiagnostics}
+	}
+
+	source, err := os.ReadFile(*in)
+	if err != nil {
+		return fail(exitIO, "failed to read input file: %w", err)
+	}
+	fixes, err := fix.Find(*in, source)
+	if err != nil {
+		return fail(exitDiagnostics, "fix failed: %w", err)
+	}
+	if !*write {
+		data, err := json.MarshalIndent(fixes, "", "  ")
+		if err != nil {
+			return fail(exitInternal, "failed to encode fixes: %w", err)
+		}
+		fmt.Fprintf(stdout, "%s\n", data)
+		return nil
+	}
+
+	var safe []fix.Fix
+	for _, f := range fixes {
+		if f.Safe {
+			safe = append(safe, f)
+		} else {
+			fmt.Fprintf(stderr, "warning: %s:%d:%d: %s (not applied)\n", f.File, f.Pos.Line, f.Pos.Column, f.Message)
+		}
+	}
+	if len(safe) == 0 {
+		return nil
+	}
+	out, err := fix.Apply(source, safe)
+	if err != nil {
+		return fail(exitInternal, "failed to apply fixes: %w", err)
+	}
+	if err := gen.WriteAtomic(*in, out); err != nil {
+		return fail(exitIO, "failed to write %s: %w", *in, err)
+	}
+	fmt.Fprintf(stderr, "%d fix(es) applied to %s\n", len(safe), *in)
+	return nil
+}
+
+// runExpr implements simgo expr: it compiles one Go expression and prints
+// the SimplicityHL it lowers to.
+func runExpr(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("expr", flag.ContinueOnError)
+	typeList := flags.String("types", "", "Comma-separated name:type pairs typing the variables, as in amount:uint64,sigValid:bool")
+	chain := flags.String("chain", "elements", "Chain whose jets the expression may use: elements, bitcoin")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: simgo expr [-types name:type,...] <expression>\n")
+		flags.PrintDefaults()
+	}
+	if err := parseFlags(flags, args, stderr); err != nil {
+		return err
+	}
+	switch {
+	case flags.NArg() == 0:
+		flags.Usage()
+		return &exitError{code: exitDiagnostics}
+	case strings.HasPrefix(flags.Arg(1), "-"):
+		// flag stops at the expression, so a flag after it would be
+		// taken for a second argument.
+		return fail(exitDiagnostics, "flag %s follows the expression: flags of simgo expr go before it", flags.Arg(1))
+	case flags.NArg() > 1:
+		return fail(exitDiagnostics, "simgo expr takes one expression, got %d arguments: quote the expression", flags.NArg())
+	}
+
+	vars := make(map[string]string)
+	for _, pair := range splitTags(*typeList) {
+		name, typ, ok := strings.Cut(pair, ":")
+		if !ok || name == "" || typ == "" {
+			return fail(exitDiagnostics, "invalid -types entry %q: want name:type", pair)
+		}
+		vars[strings.TrimSpace(name)] = strings.TrimSpace(typ)
+	}
+	e, err := compiler.CompileExpr(compiler.Config{Target: "simplicityhl", Chain: *chain}, flags.Arg(0), vars)
+	if err != nil {
+		return compileFailed("compilation failed", err)
+	}
+	var guessed []string
+	for _, p := range e.Params {
+		if p.Guessed {
+			guessed = append(guessed, p.Name+":"+p.Type)
+		}
+	}
+	if len(guessed) > 0 {
+		fmt.Fprintf(stderr, "guessed types %s; set them with -types\n", strings.Join(guessed, ","))
+	}
+	printWarnings(stderr, e.Warnings)
+	fmt.Fprintf(stdout, "%s\n", e.Code)
+	return nil
+}
+
+func printHelp(w io.Writer) {
+	fmt.Fprintf(w, "go-simplicity - Go to Simplicity transpiler\n\n")
+	fmt.Fprintf(w, "USAGE:\n")
+	fmt.Fprintf(w, "    simgo [build] -input <go-file> [options]\n")
+	fmt.Fprintf(w, "    simgo gen [-out dir] [-tags list] [dir]\n")
+	fmt.Fprintf(w, "    simgo test-gen -input <go-file> [-format json|simf] [-output path]\n")
+	fmt.Fprintf(w, "    simgo run -input <go-file> [-witness values.json] [-tx tx.json] [-entry name] [-tags list]\n")
+	fmt.Fprintf(w, "    simgo explain [code]\n")
+	fmt.Fprintf(w, "    simgo capabilities [-target name] [-chain name] [-extra-jets file] [-format text|json]\n")
+	fmt.Fprintf(w, "    simgo fix -input <go-file> [-write]\n")
+	fmt.Fprintf(w, "    simgo expr [-types name:type,...] <expression>\n\n")
+	fmt.Fprintf(w, "OPTIONS:\n")
+	fmt.Fprintf(w, "    -input string\n")
+	fmt.Fprintf(w, "        Input Go source file (required); a glob such as 'contracts/*.go'\n")
+	fmt.Fprintf(w, "        compiles each match to <output>/<file>.simf, continuing past failures\n")
+	fmt.Fprintf(w, "    -tags string\n")
+	fmt.Fprintf(w, "        Comma-separated build tags satisfying //go:build constraints; GOOS,\n")
+	fmt.Fprintf(w, "        GOARCH and release tags never exclude a contract file\n")
+	fmt.Fprintf(w, "    -include-ignored\n")
+	fmt.Fprintf(w, "        With a glob -input, also compile files excluded by build constraints\n")
+	fmt.Fprintf(w, "        such as //go:build ignore\n")
+	fmt.Fprintf(w, "    -output string\n")
+	fmt.Fprintf(w, "        Output SimplicityHL file (default: stdout); missing directories are\n")
+	fmt.Fprintf(w, "        created, and a directory receives <input>.simf. Files are written\n")
+	fmt.Fprintf(w, "        atomically under a \"Code generated by simgo\" header\n")
+	fmt.Fprintf(w, "    -force\n")
+	fmt.Fprintf(w, "        Overwrite an output file that lacks the simgo header\n")
+	fmt.Fprintf(w, "    -target string\n")
+	fmt.Fprintf(w, "        Target format: simplicityhl, simplicity (default: simplicityhl)\n")
+	fmt.Fprintf(w, "    -mode string\n")
+	fmt.Fprintf(w, "        Output kind: program, library (default: program); a library holds\n")
+	fmt.Fprintf(w, "        only fn definitions, with no witness module and no main\n")
+	fmt.Fprintf(w, "    -chain string\n")
+	fmt.Fprintf(w, "        Chain whose jets the program may use: elements, bitcoin (default:\n")
+	fmt.Fprintf(w, "        elements); bitcoin rejects the transaction introspection jets\n")
+	fmt.Fprintf(w, "    -dialect string\n")
+	fmt.Fprintf(w, "        SimplicityHL syntax version to write: %s (default:\n", strings.Join(transpiler.Dialects(), ", "))
+	fmt.Fprintf(w, "        latest), for a downstream compiler pinned to an older one\n")
+	fmt.Fprintf(w, "    -extra-jets string\n")
+	fmt.Fprintf(w, "        JSON file of jets beyond the builtin table, an array of {\"name\",\n")
+	fmt.Fprintf(w, "        \"params\", \"returns\"} objects; call them as jet.Custom(\"name\", ...)\n")
+	fmt.Fprintf(w, "    -entry string\n")
+	fmt.Fprintf(w, "        Exported function compiled as the program root instead of main();\n")
+	fmt.Fprintf(w, "        repeat it, or pass \"all-exported\", to write one file per entry into -output\n")
+	fmt.Fprintf(w, "    -include string\n")
+	fmt.Fprintf(w, "        SimplicityHL library compiled earlier (-mode library), copied into the\n")
+	fmt.Fprintf(w, "        output; Go functions declared without a body call its functions and\n")
+	fmt.Fprintf(w, "        must match their signatures (repeatable)\n")
+	fmt.Fprintf(w, "    -witness-values string\n")
+	fmt.Fprintf(w, "        JSON file mapping witness names to values (hex for byte arrays);\n")
+	fmt.Fprintf(w, "        each value replaces the compiled one and must match its declared type\n")
+	fmt.Fprintf(w, "    -split-output string\n")
+	fmt.Fprintf(w, "        Write the program to program.simf in this directory and each function\n")
+	fmt.Fprintf(w, "        to fn_<name>.simf, the modules to mod.simf, with a manifest.json of\n")
+	fmt.Fprintf(w, "        the files and their SHA-256 hashes; -output still writes the program\n")
+	fmt.Fprintf(w, "    -report string\n")
+	fmt.Fprintf(w, "        Write a JSON report: per-function types, jets, size and reaching\n")
+	fmt.Fprintf(w, "        entry points, plus the witness and param inventory\n")
+	fmt.Fprintf(w, "    -self-check\n")
+	fmt.Fprintf(w, "        Re-parse the generated SimplicityHL and fail on invalid output\n")
+	fmt.Fprintf(w, "    -strict\n")
+	fmt.Fprintf(w, "        Fail wherever the transpiler would fall back on a guess, such as an\n")
+	fmt.Fprintf(w, "        expression it cannot lower or a witness type inferred from its value\n")
+	fmt.Fprintf(w, "    -allow-trivial-main\n")
+	fmt.Fprintf(w, "        Compile a program whose acceptance depends on no witness value or\n")
+	fmt.Fprintf(w, "        the spending transaction, which anyone can spend\n")
+	fmt.Fprintf(w, "    -no-transliterate\n")
+	fmt.Fprintf(w, "        Reject identifiers that are not ASCII instead of transliterating\n")
+	fmt.Fprintf(w, "        accented letters, as in amountÉlevé → amount_eleve\n")
+	fmt.Fprintf(w, "    -checked-arithmetic\n")
+	fmt.Fprintf(w, "        Assert that a divisor known only at spend time is not zero, where\n")
+	fmt.Fprintf(w, "        the divide and modulo jets return 0 and the dividend\n")
+	fmt.Fprintf(w, "    -O0, -O1, -O2\n")
+	fmt.Fprintf(w, "        Optimization level. -O0 translates each function as written, calling\n")
+	fmt.Fprintf(w, "        helpers, for auditing against the Go; -O1 also evaluates calls whose\n")
+	fmt.Fprintf(w, "        arguments are constants and leaves out functions that main does not\n")
+	fmt.Fprintf(w, "        reach; -O2 also inlines helpers, lowers k-of-n counting to comparison\n")
+	fmt.Fprintf(w, "        trees and hoists repeated jet calls. Without a level, helpers are\n")
+	fmt.Fprintf(w, "        inlined, calls with constant arguments evaluated and counting lowered\n")
+	fmt.Fprintf(w, "    -no-inline, -no-call-folding, -no-threshold-trees, -no-dce\n")
+	fmt.Fprintf(w, "        Turn off one pass, whatever the level\n")
+	fmt.Fprintf(w, "    -optimize\n")
+	fmt.Fprintf(w, "        Compute a jet call that a function repeats once, in a let binding\n")
+	fmt.Fprintf(w, "        in the nearest scope around every use, whatever the level\n")
+	fmt.Fprintf(w, "    -max-nodes int\n")
+	fmt.Fprintf(w, "        Fail once the program's estimated size passes this many expression\n")
+	fmt.Fprintf(w, "        nodes (default: 1048576; -1: no limit)\n")
+	fmt.Fprintf(w, "    -max-witness-bits int\n")
+	fmt.Fprintf(w, "        Warn of each witness entry larger than this many bits, which the\n")
+	fmt.Fprintf(w, "        spender pays fees for (default: 2048; -1: no limit)\n")
+	fmt.Fprintf(w, "    -debug\n")
+	fmt.Fprintf(w, "        Log each phase of the compile to stderr: its duration and what it found\n")
+	fmt.Fprintf(w, "    -trace\n")
+	fmt.Fprintf(w, "        Log as -debug does, and the parsed Go AST\n")
+	fmt.Fprintf(w, "    -no-comments\n")
+	fmt.Fprintf(w, "        Leave out the Go doc comments otherwise written above the fn and\n")
+	fmt.Fprintf(w, "        const items they document\n")
+	fmt.Fprintf(w, "    -indent string\n")
+	fmt.Fprintf(w, "        Indentation: number of spaces, or \"tab\" (default: 4)\n")
+	fmt.Fprintf(w, "    -blank-lines int\n")
+	fmt.Fprintf(w, "        Blank lines between top-level functions (default: 1)\n")
+	fmt.Fprintf(w, "    -trailing-newline\n")
+	fmt.Fprintf(w, "        End output with a newline (default: true)\n")
+	fmt.Fprintf(w, "    -explain string\n")
+	fmt.Fprintf(w, "        Print the explanation of a diagnostic code, the [SIM0001] ending an\n")
+	fmt.Fprintf(w, "        error or warning, with an example of the fix, and exit\n")
+	fmt.Fprintf(w, "    -list-jets\n")
+	fmt.Fprintf(w, "        List all registered jets and exit\n")
+	fmt.Fprintf(w, "    -version\n")
+	fmt.Fprintf(w, "        Print version and exit\n")
+	fmt.Fprintf(w, "    -help\n")
+	fmt.Fprintf(w, "        Show this help message\n\n")
+	fmt.Fprintf(w, "EXIT CODES:\n")
+	fmt.Fprintf(w, "    0  success, or run accepted\n")
+	fmt.Fprintf(w, "    1  compile diagnostics, invalid flags, or run rejected\n")
+	fmt.Fprintf(w, "    2  an input could not be read or an output written\n")
+	fmt.Fprintf(w, "    3  internal error in simgo; please report it\n\n")
+	fmt.Fprintf(w, "EXAMPLES:\n")
+	fmt.Fprintf(w, "    # Compile to stdout\n")
+	fmt.Fprintf(w, "    simgo -input examples/basic_swap.go\n\n")
+	fmt.Fprintf(w, "    # Compile to file\n")
+	fmt.Fprintf(w, "    simgo -input examples/basic_swap.go -output basic_swap.shl\n\n")
+	fmt.Fprintf(w, "    # Log each compile phase\n")
+	fmt.Fprintf(w, "    simgo -input examples/basic_swap.go -debug\n\n")
+	fmt.Fprintf(w, "    # Compile with a fixed set of witness values\n")
+	fmt.Fprintf(w, "    simgo build -input swap.go -witness-values alice.json\n\n")
+	fmt.Fprintf(w, "    # Explain an error code\n")
+	fmt.Fprintf(w, "    simgo explain SIM0001\n\n")
+	fmt.Fprintf(w, "    # Show how an expression lowers, its variables typed or guessed\n")
+	fmt.Fprintf(w, "    simgo expr -types amount:uint64 'amount >= 1000 && sigValid'\n\n")
+	fmt.Fprintf(w, "    # Apply the safe fix-its, such as removing fmt.Println, in place\n")
+	fmt.Fprintf(w, "    simgo fix -input contract.go -write\n\n")
+	fmt.Fprintf(w, "    # Compile each spend path to its own file\n")
+	fmt.Fprintf(w, "    simgo -input channel.go -entry all-exported -output build/channel\n\n")
+	fmt.Fprintf(w, "    # Compile every example, which are tagged //go:build ignore\n")
+	fmt.Fprintf(w, "    simgo build -input 'examples/*.go' -output build/ -include-ignored\n\n")
+	fmt.Fprintf(w, "    # Evaluate a contract; exits non-zero if it rejects\n")
+	fmt.Fprintf(w, "    simgo run -input examples/testable/p2pk_testable.go\n\n")
+	fmt.Fprintf(w, "    # Evaluate a spend path against a local transaction\n")
+	fmt.Fprintf(w, "    simgo run -input examples/vault.go -witness examples/vault.witness.json -tx examples/vault.tx.json\n\n")
+	fmt.Fprintf(w, "    # Compile every //simplicity:contract in a package (for go:generate)\n")
+	fmt.Fprintf(w, "    //go:generate simgo gen -out build/contracts\n\n")
+}
+
+// readExtraJets reads the jet declarations of the JSON file path, for
+// Config.ExtraJets. An empty path declares none.
+func readExtraJets(path string) ([]jets.JetDecl, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fail(exitIO, "failed to read extra jets: %w", err)
+	}
+	var decls []jets.JetDecl
+	if err := json.Unmarshal(data, &decls); err != nil {
+		return nil, fail(exitDiagnostics, "invalid extra jets %s: %v", path, err)
+	}
+	if _, err := declareJets(decls); err != nil {
+		return nil, err
+	}
+	return decls, nil
+}
+
+// declareJets returns the jet table extended with decls.
+func declareJets(decls []jets.JetDecl) (*jets.JetRegistry, error) {
 	reg := jets.NewRegistry()
+	for _, decl := range decls {
+		if err := reg.Declare(decl); err != nil {
+			return nil, fail(exitDiagnostics, "invalid extra jets: %v", err)
+		}
+	}
+	return reg, nil
+}
+
+func printJets(w io.Writer, extra []jets.JetDecl) error {
+	reg, err := declareJets(extra)
+	if err != nil {
+		return err
+	}
 	all := reg.AllJets()
 
 	// Collect and sort by Go name for deterministic output
@@ -129,11 +1377,12 @@ func printJets() {
 	}
 	sort.Strings(names)
 
-	fmt.Printf("%-30s  %s\n", "Go name", "Simplicity name")
-	fmt.Printf("%-30s  %s\n", "-------", "---------------")
+	fmt.Fprintf(w, "%-30s  %s\n", "Go name", "Simplicity name")
+	fmt.Fprintf(w, "%-30s  %s\n", "-------", "---------------")
 	for _, name := range names {
 		info := all[name]
-		fmt.Printf("jet.%-26s  jet::%s\n", info.GoName, info.SimplicityName)
+		fmt.Fprintf(w, "jet.%-26s  jet::%s\n", info.GoName, info.SimplicityName)
 	}
-	fmt.Printf("\n%d jets registered\n", len(names))
+	fmt.Fprintf(w, "\n%d jets registered\n", len(names))
+	return nil
 }
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const p2pk = `package main
+
+import "simplicity/jet"
+
+func main() {
+	var pubkey [32]byte
+	var sig [64]byte
+	jet.BIP340Verify(pubkey, jet.SigAllHash(), sig)
+}
+`
+
+const minimum = `package main
+
+import "simplicity/jet"
+
+func main() {
+	var amount uint64 = 10
+	jet.Verify(jet.Le64(5, amount))
+}
+`
+
+// printingMinimum is minimum with output that simgo fix removes.
+const printingMinimum = `package main
+
+import (
+	"fmt"
+
+	"simplicity/jet"
+)
+
+func main() {
+	var amount uint64 = 10
+	fmt.Println(amount)
+	jet.Verify(jet.Le64(5, amount))
+}
+`
+
+// writeFile writes content to name in a fresh temporary directory.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// panicWriter fails the way a bug inside simgo would.
+type panicWriter struct{}
+
+func (panicWriter) Write([]byte) (int, error) { panic("boom") }
+
+func TestRunExitCodes(t *testing.T) {
+	contract := writeFile(t, "p2pk.go", p2pk)
+	accepting := writeFile(t, "minimum.go", minimum)
+	broken := writeFile(t, "broken.go", "package main\n\nfunc main() {\n\tfor {}\n}\n")
+	extraJets := writeFile(t, "jets.json", `[{"name": "fold_sum_32", "params": ["u32", "u32"], "returns": "u32"}]`)
+	clashingJets := writeFile(t, "clash.json", `[{"name": "sig_all_hash", "go_name": "MyHash", "returns": "u256"}]`)
+	customJet := writeFile(t, "custom.go", strings.Replace(minimum, "jet.Verify(", "sum := jet.Custom(\"fold_sum_32\", 1, 2)\n\tjet.Verify(sum == 3 && ", 1))
+	printing := writeFile(t, "printing.go", printingMinimum)
+	library := writeFile(t, "checks.simf", "fn at_least(x: u64) -> bool {\n    jet::le_64(1000, x)\n}\n")
+	guessing := writeFile(t, "guessing.go", "package main\n\nimport \"simplicity/jet\"\n\nfunc main() {\n\tvar a uint32\n\tok := jet.Lt32(1, a) && jet.Lt32(a, 9)\n\tjet.Verify(ok)\n}\n")
+	including := writeFile(t, "including.go", "package main\n\nimport \"simplicity/jet\"\n\nfunc atLeast(x uint64) bool\n\nfunc main() {\n\tvar amount uint64\n\tjet.Verify(atLeast(amount))\n}\n")
+	missing := filepath.Join(t.TempDir(), "missing.go")
+	// A regular file where a directory is needed cannot be created.
+	unwritable := filepath.Join(contract, "out.simf")
+
+	tests := []struct {
+		name       string
+		args       []string
+		code       int
+		stdout     string // substring, "" for empty output
+		stderr     string // substring, "" for empty output
+		stdoutOnly bool
+	}{
+		{"compiles", []string{"-input", contract}, exitOK, "fn main()", "", true},
+		{"build", []string{"build", "-input", contract}, exitOK, "fn main()", "", true},
+		{"opt level", []string{"-O2", "-input", contract}, exitOK, "fn main()", "", true},
+		// Logs go to stderr, leaving the program alone on stdout.
+		{"debug", []string{"-debug", "-input", contract}, exitOK, "fn main()", "debug: rendering took", false},
+		{"trace", []string{"-trace", "-input", contract}, exitOK, "fn main()", "trace: AST of", false},
+		{"version", []string{"-version"}, exitOK, "simgo version", "", true},
+		{"help flag", []string{"-h"}, exitOK, "", "Usage: simgo", false},
+		{"unknown flag", []string{"-nope"}, exitDiagnostics, "", "flag provided but not defined", false},
+		{"no input", nil, exitDiagnostics, "", "error: input file is required", false},
+		{"diagnostics", []string{"-input", broken}, exitDiagnostics, "", "error: compilation failed:", false},
+		{"diagnostic code", []string{"-input", broken}, exitDiagnostics, "", "[SIM0001]", false},
+		{"dialect", []string{"-dialect", "simfony-0.3", "-input", contract}, exitOK, "jet::bip0340_verify(", "", true},
+		{"extra jets", []string{"-extra-jets", extraJets, "-input", customJet}, exitOK, "jet::fold_sum_32(1, 2)", "", true},
+		{"extra jets listed", []string{"-list-jets", "-extra-jets", extraJets}, exitOK, "jet.FoldSum32", "", true},
+		{"extra jets clash", []string{"-extra-jets", clashingJets, "-input", contract}, exitDiagnostics, "", "conflicts with the builtin jet SigAllHash", false},
+		{"include", []string{"-include", library, "-input", including}, exitOK, "fn at_least(x: u64) -> bool", "", true},
+		{"include missing", []string{"-include", missing, "-input", including}, exitIO, "", "error: failed to read included module", false},
+		{"strict", []string{"-strict", "-input", contract}, exitOK, "fn main()", "", true},
+		{"strict fallback", []string{"-strict", "-input", guessing}, exitDiagnostics, "", "the type of witness::OK is guessed as bool", false},
+		{"unknown dialect", []string{"-dialect", "simfony-9", "-input", contract}, exitDiagnostics, "", "unknown SimplicityHL dialect simfony-9", false},
+		{"bad style", []string{"-input", contract, "-indent", "x"}, exitDiagnostics, "", "error: invalid formatting options", false},
+		{"missing input", []string{"-input", missing}, exitIO, "", "error: input file does not exist", false},
+		{"build argument", []string{"build", "-input", contract, "x.go", "-output", unwritable}, exitDiagnostics, "", "simgo build takes no arguments, got x.go", false},
+		{"positional input", []string{contract, "-output", unwritable}, exitDiagnostics, "", "simgo build takes no arguments, got " + contract, false},
+		{"unwritable output", []string{"-input", contract, "-output", unwritable}, exitIO, "", "error: failed to write output file", false},
+		{"run accepts", []string{"run", "-input", accepting}, exitOK, "accepted", "", true},
+		{"run rejects", []string{"run", "-input", accepting, "-witness", writeFile(t, "w.json", `{"AMOUNT": "1"}`)}, exitDiagnostics, "", "rejected: ", false},
+		{"run argument", []string{"run", accepting, "-witness", writeFile(t, "w.json", `{"AMOUNT": "1"}`)}, exitDiagnostics, "", "simgo run takes no arguments, got " + accepting, false},
+		{"run missing", []string{"run", "-input", missing}, exitIO, "", "error: failed to read input file", false},
+		{"explain", []string{"explain", "sim0001"}, exitOK, "SIM0001: loop without a constant bound", "", true},
+		{"explain flag", []string{"-explain", "SIM0102"}, exitOK, "var fee uint16 = 300", "", true},
+		{"explain list", []string{"explain"}, exitOK, "SIM0401  invalid compiler configuration", "", true},
+		{"explain unknown", []string{"explain", "SIM9999"}, exitDiagnostics, "", "error: unknown diagnostic code SIM9999", false},
+		{"capabilities", []string{"capabilities"}, exitOK, "simplicityhl  bitcoin", "", true},
+		{"capabilities json", []string{"capabilities", "-chain", "bitcoin", "-format", "json"}, exitOK, `"max_word_bits": 256`, "", true},
+		{"capabilities chain", []string{"capabilities", "-chain", "litecoin"}, exitDiagnostics, "", "error: unsupported chain: litecoin", false},
+		{"capabilities extra jets", []string{"capabilities", "-extra-jets", extraJets, "-chain", "bitcoin", "-format", "json"}, exitOK, `"fold_sum_32"`, "", true},
+		{"capabilities format", []string{"capabilities", "-format", "xml"}, exitDiagnostics, "", "error: unsupported -format: xml", false},
+		{"fix", []string{"fix", "-input", printing}, exitOK, `"message": "fmt.Println has no effect`, "", true},
+		{"fix missing", []string{"fix", "-input", missing}, exitIO, "", "error: failed to read input file", false},
+		{"expr", []string{"expr", "-types", "amount:uint64", "amount >= 1000"}, exitOK, "jet::le_64(1000, amount)\n", "", true},
+		{"expr guessed", []string{"expr", "amount >= 1000"}, exitOK, "jet::le_32(1000, amount)", "guessed types amount:uint32", false},
+		{"expr error", []string{"expr", "amount >"}, exitDiagnostics, "", "error: compilation failed: failed to parse expression: expr:1:9", false},
+		{"expr types", []string{"expr", "-types", "amount", "amount > 1"}, exitDiagnostics, "", `invalid -types entry "amount"`, false},
+		{"expr trailing flag", []string{"expr", "amount > 1", "-types", "amount:uint64"}, exitDiagnostics, "", "flag -types follows the expression: flags of simgo expr go before it", false},
+		{"expr unquoted", []string{"expr", "amount", ">", "1"}, exitDiagnostics, "", "simgo expr takes one expression, got 3 arguments", false},
+		{"test-gen format", []string{"test-gen", "-input", contract, "-format", "xml"}, exitDiagnostics, "", "error: unsupported -format: xml", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(tt.args, &stdout, &stderr)
+			if code != tt.code {
+				t.Fatalf("exit code %d, want %d\nstdout: %s\nstderr: %s", code, tt.code, stdout.String(), stderr.String())
+			}
+			if tt.stdout == "" && stdout.Len() > 0 {
+				t.Errorf("unexpected stdout: %s", stdout.String())
+			}
+			if !strings.Contains(stdout.String(), tt.stdout) {
+				t.Errorf("stdout %q does not contain %q", stdout.String(), tt.stdout)
+			}
+			if tt.stdoutOnly && stderr.Len() > 0 {
+				t.Errorf("unexpected stderr: %s", stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("stderr %q does not contain %q", stderr.String(), tt.stderr)
+			}
+		})
+	}
+}
+
+func TestRunFixWrite(t *testing.T) {
+	contract := writeFile(t, "printing.go", printingMinimum)
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"fix", "-input", contract, "-write"}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("exit code %d\nstderr: %s", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "2 fix(es) applied") {
+		t.Errorf("stderr %q does not report the fixes", stderr.String())
+	}
+	data, err := os.ReadFile(contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); got != minimum {
+		t.Errorf("fixed file:\n%s\nwant:\n%s", got, minimum)
+	}
+}
+
+func TestRunRecoversPanics(t *testing.T) {
+	contract := writeFile(t, "p2pk.go", p2pk)
+	var stderr bytes.Buffer
+	if code := run([]string{"-input", contract}, panicWriter{}, &stderr); code != exitInternal {
+		t.Fatalf("exit code %d, want %d", code, exitInternal)
+	}
+	for _, want := range []string{"internal error: boom", "This is a bug in simgo", issuesURL} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("stderr does not contain %q:\n%s", want, stderr.String())
+		}
+	}
+}
+
+func TestRunOutputFiles(t *testing.T) {
+	contract := writeFile(t, "swap.go", p2pk)
+	dir := t.TempDir()
+	compile := func(args ...string) (int, string) {
+		var stdout, stderr bytes.Buffer
+		code := run(append([]string{"-input", contract}, args...), &stdout, &stderr)
+		return code, stderr.String()
+	}
+	read := func(path string) string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	// Missing parent directories are created.
+	nested := filepath.Join(dir, "build", "contracts", "swap.simf")
+	if code, stderr := compile("-output", nested); code != exitOK {
+		t.Fatalf("nested output: exit %d: %s", code, stderr)
+	}
+	if got := read(nested); !strings.HasPrefix(got, "// Code generated by simgo from swap.go. DO NOT EDIT.\n") || !strings.Contains(got, "fn main()") {
+		t.Errorf("nested output:\n%s", got)
+	}
+
+	// A directory receives a file named after the input.
+	for _, out := range []string{dir, filepath.Join(dir, "fresh") + string(filepath.Separator)} {
+		if code, stderr := compile("-output", out); code != exitOK {
+			t.Fatalf("-output %s: exit %d: %s", out, code, stderr)
+		}
+		read(filepath.Join(out, "swap.simf"))
+	}
+
+	// simgo's own output is replaced, anything else needs -force.
+	if code, stderr := compile("-output", nested); code != exitOK {
+		t.Fatalf("regenerating: exit %d: %s", code, stderr)
+	}
+	handwritten := filepath.Join(dir, "handwritten.simf")
+	if err := os.WriteFile(handwritten, []byte("fn main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code, stderr := compile("-output", handwritten)
+	if code != exitIO || !strings.Contains(stderr, "pass -force to replace it") {
+		t.Errorf("overwriting a handwritten file: exit %d: %s", code, stderr)
+	}
+	if got := read(handwritten); got != "fn main() {}\n" {
+		t.Errorf("handwritten file was modified:\n%s", got)
+	}
+	if code, stderr := compile("-output", handwritten, "-force"); code != exitOK {
+		t.Fatalf("-force: exit %d: %s", code, stderr)
+	}
+	if got := read(handwritten); !strings.HasPrefix(got, "// Code generated by simgo") {
+		t.Errorf("-force did not replace the file:\n%s", got)
+	}
+
+	// No temporary files are left behind.
+	entries, err := os.ReadDir(filepath.Dir(nested))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only swap.simf in %s, found %d entries", filepath.Dir(nested), len(entries))
+	}
+}
+
+// TestRunGenOutputFiles checks that gen writes as the compile command
+// does: under a provenance header, and over a file simgo did not generate
+// only with -force.
+func TestRunGenOutputFiles(t *testing.T) {
+	pkg, out := t.TempDir(), t.TempDir()
+	contract := "package claim\n\nimport \"simplicity/jet\"\n\n//simplicity:contract\nfunc Claim(amount uint64) bool {\n\treturn jet.Le64(1000, amount)\n}\n"
+	if err := os.WriteFile(filepath.Join(pkg, "claim.go"), []byte(contract), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gen := func(args ...string) (int, string) {
+		var stdout, stderr bytes.Buffer
+		code := run(append(append([]string{"gen", "-out", out}, args...), pkg), &stdout, &stderr)
+		return code, stderr.String()
+	}
+	path := filepath.Join(out, "Claim.simf")
+	handwritten := "fn main() {}\n"
+	if err := os.WriteFile(path, []byte(handwritten), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code, stderr := gen()
+	if code != exitIO || !strings.Contains(stderr, "refusing to overwrite "+path) {
+		t.Errorf("overwriting a handwritten file: exit %d: %s", code, stderr)
+	}
+	if got, _ := os.ReadFile(path); string(got) != handwritten {
+		t.Errorf("handwritten file was modified:\n%s", got)
+	}
+	for _, args := range [][]string{{"-force"}, nil} {
+		if code, stderr := gen(args...); code != exitOK {
+			t.Fatalf("gen %v: exit %d: %s", args, code, stderr)
+		}
+		got, _ := os.ReadFile(path)
+		if !strings.HasPrefix(string(got), "// Code generated by simgo from claim.go. DO NOT EDIT.\n") || !strings.Contains(string(got), "fn claim(") {
+			t.Errorf("gen %v wrote:\n%s", args, got)
+		}
+	}
+	entries, err := os.ReadDir(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only Claim.simf in %s, found %d entries", out, len(entries))
+	}
+}
+
+// withHelper is minimum with its check in a helper function.
+const withHelper = `package main
+
+import "simplicity/jet"
+
+// aboveMinimum checks an amount.
+func aboveMinimum(amount uint64) bool {
+	return jet.Le64(5, amount)
+}
+
+func main() {
+	var amount uint64 = 10
+	jet.Verify(aboveMinimum(amount))
+}
+`
+
+func TestRunSplitOutput(t *testing.T) {
+	contract := writeFile(t, "minimum.go", withHelper)
+	dir := filepath.Join(t.TempDir(), "split")
+	split := func(args ...string) string {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		if code := run(append([]string{"-input", contract, "-split-output", dir}, args...), &stdout, &stderr); code != exitOK {
+			t.Fatalf("exit code %d\nstderr: %s", code, stderr.String())
+		}
+		if stdout.Len() > 0 {
+			t.Errorf("unexpected stdout: %s", stdout.String())
+		}
+		return stderr.String()
+	}
+	split("-O0")
+
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest splitManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, piece := range manifest.Pieces {
+		files = append(files, piece.File)
+	}
+	if got, want := strings.Join(files, " "), "mod.simf fn_above_minimum.simf fn_main.simf"; got != want {
+		t.Errorf("pieces %s, want %s", got, want)
+	}
+	var joined []string
+	for _, f := range append([]splitFile{manifest.Program}, manifest.Pieces...) {
+		data, err := os.ReadFile(filepath.Join(dir, f.File))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sum := fmt.Sprintf("%x", sha256.Sum256(data)); sum != f.SHA256 {
+			t.Errorf("%s: sha256 %s, manifest has %s", f.File, sum, f.SHA256)
+		}
+		code, ok := strings.CutPrefix(string(data), "// Code generated by simgo from minimum.go. DO NOT EDIT.\n")
+		if !ok {
+			t.Errorf("%s has no provenance header", f.File)
+		}
+		if f != manifest.Program {
+			joined = append(joined, strings.TrimSuffix(code, "\n"))
+		}
+	}
+	program, _ := os.ReadFile(filepath.Join(dir, "program.simf"))
+	if got := strings.Join(joined, "\n\n") + "\n"; !strings.HasSuffix(string(program), "\n"+got) {
+		t.Errorf("pieces do not make up program.simf:\n%s\nprogram:\n%s", got, program)
+	}
+	if helper, _ := os.ReadFile(filepath.Join(dir, "fn_above_minimum.simf")); !strings.Contains(string(helper), "// aboveMinimum checks an amount.\nfn above_minimum(") {
+		t.Errorf("helper piece:\n%s", helper)
+	}
+
+	// Once dead-code elimination drops the helper, so does its file.
+	split("-O2")
+	if _, err := os.Stat(filepath.Join(dir, "fn_above_minimum.simf")); !os.IsNotExist(err) {
+		t.Errorf("stale piece left behind: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "fn_main.simf")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	src := t.TempDir()
+	for name, content := range map[string]string{
+		"good.go":      p2pk,
+		"bad.go":       "package main\n\nfunc main() {\n\tfor {}\n}\n",
+		"ignored.go":   "//go:build ignore\n\n" + minimum,
+		"good_test.go": "package main\n",
+	} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pattern := filepath.Join(src, "*.go")
+
+	tests := []struct {
+		args    []string
+		code    int
+		summary string
+		written []string
+	}{
+		{nil, exitDiagnostics, "1 succeeded, 1 failed", []string{"good.simf"}},
+		{[]string{"-include-ignored"}, exitDiagnostics, "2 succeeded, 1 failed", []string{"good.simf", "ignored.simf"}},
+	}
+	for _, tt := range tests {
+		out := t.TempDir()
+		var stdout, stderr bytes.Buffer
+		code := run(append([]string{"build", "-input", pattern, "-output", out}, tt.args...), &stdout, &stderr)
+		if code != tt.code {
+			t.Fatalf("%v: exit code %d, want %d\n%s", tt.args, code, tt.code, stderr.String())
+		}
+		if stdout.Len() > 0 {
+			t.Errorf("%v: unexpected stdout: %s", tt.args, stdout.String())
+		}
+		for _, want := range []string{tt.summary, "error: compilation of " + filepath.Join(src, "bad.go") + " failed"} {
+			if !strings.Contains(stderr.String(), want) {
+				t.Errorf("%v: stderr does not contain %q:\n%s", tt.args, want, stderr.String())
+			}
+		}
+		entries, err := os.ReadDir(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var written []string
+		for _, e := range entries {
+			written = append(written, e.Name())
+		}
+		if strings.Join(written, ",") != strings.Join(tt.written, ",") {
+			t.Errorf("%v: wrote %v, want %v", tt.args, written, tt.written)
+		}
+	}
+
+	var stderr bytes.Buffer
+	if code := run([]string{"-input", pattern}, io.Discard, &stderr); code != exitDiagnostics || !strings.Contains(stderr.String(), "requires -output") {
+		t.Errorf("batch without -output: exit %d: %s", code, stderr.String())
+	}
+}
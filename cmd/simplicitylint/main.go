@@ -0,0 +1,18 @@
+// Command simplicitylint reports the Go features of contracts that
+// Simplicity cannot express, with the simplicitycheck analyzer:
+//
+//	simplicitylint ./contracts/...
+//
+// It takes the flags of a go/analysis driver, such as -json, and -chain to
+// set the target chain, and can also run under go vet with -vettool.
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/0ceanslim/go-simplicity/pkg/analysis/simplicitycheck"
+)
+
+func main() {
+	singlechecker.Main(simplicitycheck.Analyzer)
+}
@@ -0,0 +1,53 @@
+//go:build ignore
+
+// Annex-free spend
+//
+// This example demonstrates script-path introspection with an Option jet:
+// the owner may spend, but only from a Simplicity leaf and only when the
+// input carries no taproot annex.
+//
+// The annex is witness data reserved for future soft forks. Refusing it
+// keeps the spend's witness to what the contract expects, whatever
+// meaning later rules give the annex.
+//
+// jet.AnnexHash returns Option<u256>, which Go sees as a *[32]byte that
+// is nil when there is no annex. Testing it in an if statement compiles to
+// a match on jet::current_annex_hash(): the None arm holds the spend and
+// the Some arm rejects.
+//
+// The generated program is checked in as examples/annex_free.simf. Run it
+// with the built-in evaluator, then add "annex_hash" to the input to see it
+// reject:
+//
+//	go run cmd/simgo/main.go run -input examples/annex_free.go \
+//	    -tx examples/annex_free.tx.json -witness examples/annex_free.witness.json
+//
+// Usage:
+//
+//	go run cmd/simgo/main.go -input examples/annex_free.go
+package main
+
+import "simplicity/jet"
+
+// OwnerPubkey is the BIP-340 x-only public key of the owner
+const OwnerPubkey = 0xdff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659
+
+// SimplicityLeafVersion is the tapleaf version of Simplicity leaves on
+// Elements
+const SimplicityLeafVersion uint8 = 0xbe
+
+func main() {
+	var sig [64]byte
+
+	// The program runs from a Simplicity leaf
+	version := jet.TapleafVersion()
+	jet.Verify(jet.Eq8(version, SimplicityLeafVersion))
+
+	if annex := jet.AnnexHash(); annex == nil {
+		msg := jet.SigAllHash()
+		jet.BIP340Verify(OwnerPubkey, msg, sig)
+	} else {
+		// An annex is never accepted
+		jet.Verify(false)
+	}
+}
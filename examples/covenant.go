@@ -50,7 +50,7 @@ func main() {
 	// 1. Retrieve the script hash of output 0
 	hash := jet.OutputScriptHash(OutputIndex)
 	// 2. Enforce the output script matches the expected covenant target
-	jet.Eq256(hash, ExpectedScriptHash)
+	jet.Verify(jet.Eq256(hash, ExpectedScriptHash))
 	// 3. Verify the owner's signature
 	msg := jet.SigAllHash()
 	jet.BIP340Verify(OwnerPubkey, msg, sig)
@@ -52,7 +52,7 @@ func main() {
 	outerHash := jet.SHA256Finalize(jet.SHA256Add(jet.SHA256Init(), innerHash))
 
 	// Verify the double-SHA256 result matches the stored hash lock
-	jet.Eq256(outerHash, HashLock)
+	jet.Verify(jet.Eq256(outerHash, HashLock))
 
 	// Verify the owner's signature
 	msg := jet.SigAllHash()
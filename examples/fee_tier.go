@@ -0,0 +1,35 @@
+// examples/fee_tier.go
+//go:build ignore
+// +build ignore
+
+package main
+
+import "simplicity/jet"
+
+// TierZeroHeight is the block height from which a tier 0 spend is valid
+const TierZeroHeight uint32 = 800000
+
+// MinFee is the least fee a spend in tier pays
+func MinFee(tier uint8) uint64 {
+	switch tier {
+	case 1:
+		return 1000
+	case 2, 3:
+		return 5000
+	}
+	return 20000
+}
+
+func main() {
+	// The tier the spender claims, and the fee it pays, supplied as witnesses
+	var tier uint8
+	var fee uint64
+
+	switch tier {
+	case 0:
+		// Tier 0 pays no fee floor but waits for its height
+		jet.CheckLockHeight(TierZeroHeight)
+	default:
+		jet.Verify(jet.Le64(MinFee(tier), fee))
+	}
+}
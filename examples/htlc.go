@@ -2,58 +2,48 @@
 
 // HTLC (Hash Time Locked Contract) Example
 //
-// This example demonstrates a Hash Time Locked Contract that:
-// 1. Allows the recipient to claim funds by revealing a preimage and signature (Left path)
-// 2. Allows the sender to reclaim funds after timeout with just a signature (Right path)
+// This example demonstrates a Hash Time Locked Contract with two spending
+// paths, selected by the witness:
+//  1. Left (claim): the recipient reveals the preimage of HashLock and signs
+//  2. Right (refund): once the chain reaches RefundHeight, the sender signs
 //
-// The witness is an Either type:
-// - Left: (preimage, recipient_signature) for successful completion
-// - Right: sender_signature for cancellation/timeout
+// An atomic swap is a pair of these contracts, one on each chain, locked to
+// the same hash: claiming one reveals the preimage that claims the other.
 //
-// Expected SimplicityHL output:
+// The witness is an Either type:
+// - Left: (preimage, recipient_sig) for the claim
+// - Right: sender_sig for the refund
 //
-//   mod witness {
-//       const HTLC_WITNESS: Either<(u256, [u8; 64]), [u8; 64]> = /* witness */;
-//   }
-//   mod param {
-//       const RECIPIENT_PUBKEY: u256 = 0x...;
-//       const SENDER_PUBKEY: u256 = 0x...;
-//       const HASH_LOCK: u256 = 0x...;
-//   }
+// The generated program is checked in as examples/htlc.simf, and the report
+// written by -report lists the witness layout: the is_left branch selector,
+// the preimage and recipient_sig of the Left arm and the sender_sig of the
+// Right arm. Claim with the built-in evaluator:
 //
-//   fn main() {
-//       match witness::HTLC_WITNESS {
-//           Left(data) => {
-//               let (preimage, sig): (u256, [u8; 64]) = data;
-//               let hash: u256 = jet::sha_256(preimage);
-//               jet::eq_256(hash, param::HASH_LOCK);
-//               jet::bip_0340_verify((param::RECIPIENT_PUBKEY, jet::sig_all_hash()), sig)
-//           },
-//           Right(sig) => {
-//               jet::bip_0340_verify((param::SENDER_PUBKEY, jet::sig_all_hash()), sig)
-//           },
-//       }
-//   }
+//	go run cmd/simgo/main.go run -input examples/htlc.go \
+//	    -tx examples/htlc.tx.json -witness examples/htlc.witness.json
 //
 // Usage:
-//   go run cmd/simgo/main.go -input examples/htlc.go
-
+//
+//	go run cmd/simgo/main.go build -input examples/htlc.go -report htlc.json
 package main
 
 import "simplicity/jet"
 
 // RecipientPubkey is the BIP-340 x-only public key for the recipient (Alice)
-const RecipientPubkey = 0x9bef8d556d80e43ae7e0becb3f7de6b4e5e4f7e8d9a0b1c2d3e4f5a6b7c8d9e0
+const RecipientPubkey = 0xdff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659
 
 // SenderPubkey is the BIP-340 x-only public key for the sender (Bob)
-const SenderPubkey = 0xe37d58a1aae4ba05c9b2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4
+const SenderPubkey = 0xf9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9
 
 // HashLock is the SHA-256 hash that must be revealed to claim funds
-const HashLock = 0xa1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8e9f0a1b2
+const HashLock = 0x425ed4e4a36b30ea21b90e21c712c649e8214c29b7eaf68089d1039c6e55384c
+
+// RefundHeight is the block height from which Bob may refund
+const RefundHeight uint32 = 800000
 
 // HTLCWitness represents the witness data for the HTLC
-// IsLeft=true: Complete path with preimage and recipient signature
-// IsLeft=false: Cancel path with sender signature
+// IsLeft=true:  claim path with preimage and recipient signature
+// IsLeft=false: refund path with sender signature
 type HTLCWitness struct {
 	IsLeft bool
 	// Left path: preimage (32 bytes) + signature (64 bytes)
@@ -67,16 +57,14 @@ func main() {
 	var w HTLCWitness
 
 	if w.IsLeft {
-		// Complete path: recipient claims with preimage + signature
-		// 1. Hash the preimage
+		// Claim path: recipient reveals the preimage and signs
 		hash := jet.SHA256Finalize(jet.SHA256Add32(jet.SHA256Init(), w.Preimage))
-		// 2. Verify hash matches the lock
-		jet.Eq256(hash, HashLock)
-		// 3. Verify recipient's signature
+		jet.Verify(jet.Eq256(hash, HashLock))
 		msg := jet.SigAllHash()
 		jet.BIP340Verify(RecipientPubkey, msg, w.RecipientSig)
 	} else {
-		// Cancel path: sender reclaims with signature (after timeout)
+		// Refund path: sender reclaims once the lock height is reached
+		jet.CheckLockHeight(RefundHeight)
 		msg := jet.SigAllHash()
 		jet.BIP340Verify(SenderPubkey, msg, w.SenderSig)
 	}
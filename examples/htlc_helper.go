@@ -61,7 +61,7 @@ type AtomicSwapWitness struct {
 // verifyHashlock checks that preimage hashes to the expected HashLock constant.
 func verifyHashlock(preimage [32]byte) {
 	hash := jet.SHA256Finalize(jet.SHA256Add32(jet.SHA256Init(), preimage))
-	jet.Eq256(hash, HashLock)
+	jet.Verify(jet.Eq256(hash, HashLock))
 }
 
 func main() {
@@ -26,21 +26,21 @@
 //       let msg: u256 = jet::sig_all_hash();
 //
 //       // Unrolled signature checks with counter accumulation
-//       let count_0: u32 = match witness::SIG_0 {
+//       let t_77_2: u32 = match witness::SIG_0 {
 //           Some(sig) => { jet::bip_0340_verify((param::ALICE_PUBKEY, msg), sig); 1 },
 //           None => 0,
 //       };
-//       let count_1: u32 = count_0 + match witness::SIG_1 {
+//       let t_83_2: u32 = match witness::SIG_1 {
 //           Some(sig) => { jet::bip_0340_verify((param::BOB_PUBKEY, msg), sig); 1 },
 //           None => 0,
 //       };
-//       let count_2: u32 = count_1 + match witness::SIG_2 {
-//           Some(sig) => { jet::bip_0340_verify((param::CHARLIE_PUBKEY, msg), sig); 1 },
-//           None => 0,
-//       };
+//       let (_, t_83_2_2): (bool, u32) = jet::add_32(t_77_2, t_83_2);
+//       // The third signature is counted as the second is
+//       let t_89_2: u32 = match witness::SIG_2 { ... };
+//       let (_, t_89_2_2): (bool, u32) = jet::add_32(t_83_2_2, t_89_2);
 //
 //       // Require at least 2 valid signatures
-//       jet::verify(jet::le_32(2, count_2))
+//       jet::verify(jet::le_32(2, t_89_2_2))
 //   }
 //
 // Usage:
@@ -0,0 +1,85 @@
+//go:build ignore
+
+// MuSig2 cooperative close with a timeout
+//
+// This example demonstrates key aggregation done by the compiler:
+// 1. Left path: Alice and Bob sign together under their aggregate key
+// 2. Right path: after TimeoutHeight, Alice spends alone with her own key
+//
+// std.KeyAggCoefficientless aggregates the two keys as BIP-327 does. Both
+// are constants, so the compiler computes the aggregate while compiling and
+// the program contains only the resulting key; no key-aggregation
+// coefficients or curve arithmetic end up in the contract.
+//
+// Expected SimplicityHL output:
+//
+//	mod witness {
+//	    const W: Either<[u8; 64], [u8; 64]> = Left(0x...);
+//	}
+//	mod param {
+//	    const ALICE_PUBKEY: u256 = 0xf9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9;
+//	    const BOB_PUBKEY: u256 = 0xdff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659;
+//	    const TIMEOUT_HEIGHT: u32 = 900000;
+//	}
+//
+//	fn main() {
+//	    match witness::W {
+//	        Left(data: [u8; 64]) => {
+//	            let msg = jet::sig_all_hash();
+//	            jet::bip_0340_verify((0xc311e86f2238ee927139c3473e050648943b86c7a84b00e67622d36833d702bd, msg), data);
+//	        },
+//	        Right(sig: [u8; 64]) => {
+//	            jet::check_lock_height(param::TIMEOUT_HEIGHT);
+//	            let msg = jet::sig_all_hash();
+//	            jet::bip_0340_verify((param::ALICE_PUBKEY, msg), sig);
+//	        }
+//	    }
+//	}
+//
+// Usage:
+//
+//	go run cmd/simgo/main.go -input examples/musig_cooperative.go
+package main
+
+import (
+	"simplicity/jet"
+
+	"github.com/0ceanslim/go-simplicity/std"
+	"github.com/0ceanslim/go-simplicity/std/bitcoin"
+)
+
+// AlicePubkey is Alice's BIP-340 x-only public key
+const AlicePubkey = 0xf9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9
+
+// BobPubkey is Bob's BIP-340 x-only public key
+const BobPubkey = 0xdff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659
+
+// TimeoutHeight is the block height after which Alice may spend alone
+const TimeoutHeight uint32 = 900000
+
+// CooperativeWitness selects the spending path
+// IsLeft=true:  cooperative path — one aggregate signature by Alice and Bob
+// IsLeft=false: timeout path — Alice's signature after TimeoutHeight
+type CooperativeWitness struct {
+	IsLeft   bool
+	CoopSig  bitcoin.Signature
+	AliceSig bitcoin.Signature
+}
+
+func main() {
+	var w CooperativeWitness
+
+	// Folded by the compiler into a single constant key
+	coopKey := std.KeyAggCoefficientless(AlicePubkey, BobPubkey)
+
+	if w.IsLeft {
+		// Cooperative path: one MuSig2 signature under the aggregate key
+		msg := jet.SigAllHash()
+		jet.BIP340Verify(coopKey, msg, w.CoopSig)
+	} else {
+		// Timeout path: Alice alone, once the height is reached
+		jet.CheckLockHeight(TimeoutHeight)
+		msg := jet.SigAllHash()
+		jet.BIP340Verify(AlicePubkey, msg, w.AliceSig)
+	}
+}
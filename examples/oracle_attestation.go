@@ -0,0 +1,77 @@
+//go:build ignore
+
+// Oracle attestation
+//
+// This example demonstrates a contract that pays out on an oracle's signed
+// price: a call option the holder exercises once a trusted oracle attests
+// that the price reached Strike at or after Maturity.
+//
+// The oracle signs a 12-byte message, the price (u64) followed by the
+// timestamp (u32), both big-endian, under the BIP-340 tagged hash
+// "simgo/oracle-price". The contract rebuilds that message from the
+// witness with std.Uint64Bytes, std.Uint32Bytes and std.Concat, hashes it
+// with std.TaggedHash, verifies the oracle's signature over it and then
+// compares the attested values against the contract's terms. The tag is
+// hashed by the compiler; the program only adds its hash, the price and
+// the timestamp to a SHA-256 context.
+//
+// The witness is a struct, destructured at the top of main:
+// - price and timestamp: the attested values
+// - oracle_sig: the oracle's signature over their tagged hash
+// - holder_sig: the holder's signature over the spending transaction
+//
+// The generated program is checked in as examples/oracle_attestation.simf.
+// Exercise the option with the built-in evaluator:
+//
+//	go run cmd/simgo/main.go run -input examples/oracle_attestation.go \
+//	    -tx examples/oracle_attestation.tx.json -witness examples/oracle_attestation.witness.json
+//
+// Usage:
+//
+//	go run cmd/simgo/main.go -input examples/oracle_attestation.go
+package main
+
+import (
+	"simplicity/jet"
+
+	"github.com/0ceanslim/go-simplicity/std"
+)
+
+// OraclePubkey is the BIP-340 x-only public key of the price oracle
+const OraclePubkey = 0xdd308afec5777e13121fa72b9cc1b7cc0139715309b086c960e18fd969774eb8
+
+// HolderPubkey is the BIP-340 x-only public key of the option holder
+const HolderPubkey = 0xdff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659
+
+// Strike is the lowest attested price at which the option pays out
+const Strike uint64 = 50000
+
+// Maturity is the earliest attestation time accepted, as a Unix timestamp
+const Maturity uint32 = 1767225600
+
+// Attestation is the witness: the oracle's signed observation and the
+// holder's signature.
+type Attestation struct {
+	Price     uint64
+	Timestamp uint32
+	OracleSig [64]byte
+	HolderSig [64]byte
+}
+
+func main() {
+	var w Attestation
+
+	// Rebuild and check the message the oracle signed
+	price := std.Uint64Bytes(w.Price)
+	stamp := std.Uint32Bytes(w.Timestamp)
+	attested := std.TaggedHash("simgo/oracle-price", std.Concat(price[:], stamp[:]))
+	jet.BIP340Verify(OraclePubkey, attested, w.OracleSig)
+
+	// The attestation must meet the terms of the option
+	jet.Verify(jet.Le64(Strike, w.Price))
+	jet.Verify(jet.Le32(Maturity, w.Timestamp))
+
+	// Only the holder exercises it
+	msg := jet.SigAllHash()
+	jet.BIP340Verify(HolderPubkey, msg, w.HolderSig)
+}
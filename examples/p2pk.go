@@ -26,7 +26,11 @@
 
 package main
 
-import "simplicity/jet"
+import (
+	"simplicity/jet"
+
+	"github.com/0ceanslim/go-simplicity/std/bitcoin"
+)
 
 // AlicePubkey is the BIP-340 x-only public key for Alice
 // In a real contract, this would be the actual public key
@@ -34,7 +38,7 @@ const AlicePubkey = 0x9bef8d556d80e43ae7e0becb3f7de6b4e5e4f7e8d9a0b1c2d3e4f5a6b7
 
 func main() {
 	// Declare signature as witness data (provided at spending time)
-	var sig [64]byte
+	var sig bitcoin.Signature
 
 	// Get the transaction sighash using the Simplicity jet
 	msg := jet.SigAllHash()
@@ -0,0 +1,58 @@
+//go:build ignore
+
+// Reissuance guard
+//
+// This example demonstrates a covenant that holds the reissuance token of
+// a Liquid asset and caps every reissuance. Spending the token UTXO is the
+// only way to mint more of the asset, so the guard decides how much can be
+// minted at once: at most MaxReissuance, and only with the issuer's
+// signature.
+//
+// The token must go back to the guard in output 0, so the next reissuance
+// passes through the same checks. jet.IssuanceAssetAmount(index) is nil
+// when the input at index has no issuance; the guard tests it on its own
+// input, which moves the token without minting when there is none.
+//
+// Issuance introspection exists only on Elements chains; compiling the
+// guard with -chain bitcoin is an error naming the jet.
+//
+// The generated program is checked in as examples/reissuance_guard.simf.
+// Reissue with the built-in evaluator:
+//
+//	go run cmd/simgo/main.go run -input examples/reissuance_guard.go \
+//	    -tx examples/reissuance_guard.tx.json -witness examples/reissuance_guard.witness.json
+//
+// Usage:
+//
+//	go run cmd/simgo/main.go -input examples/reissuance_guard.go
+package main
+
+import "simplicity/jet"
+
+// IssuerPubkey is the BIP-340 x-only public key of the asset issuer
+const IssuerPubkey = 0xdff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659
+
+// MaxReissuance is the largest amount of the asset one reissuance mints
+const MaxReissuance uint64 = 1000000
+
+func main() {
+	var sig [64]byte
+
+	// Only the issuer spends the token
+	msg := jet.SigAllHash()
+	jet.BIP340Verify(IssuerPubkey, msg, sig)
+
+	// The token goes back to the guard
+	script := jet.CurrentScriptHash()
+	next := jet.OutputScriptHash(0)
+	jet.Verify(jet.Eq256(script, next))
+	token := jet.CurrentAsset()
+	kept := jet.OutputAsset(0)
+	jet.Verify(jet.Eq256(token, kept))
+
+	// A reissuance mints at most MaxReissuance
+	index := jet.CurrentIndex()
+	if amount := jet.IssuanceAssetAmount(index); amount != nil {
+		jet.Verify(jet.Le64(*amount, MaxReissuance))
+	}
+}
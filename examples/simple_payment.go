@@ -3,14 +3,19 @@
 
 package main
 
+import "github.com/0ceanslim/go-simplicity/std"
+
 // Simple payment validation contract
 // This demonstrates basic Simplicity concepts without unsupported features
 
 // CheckSig simulates signature verification
 // In real Simplicity, this would be a jet
 func CheckSig(pubkey [32]byte, sig [64]byte, msg [32]byte) bool {
-	// Simplified: just check that pubkey and sig are not zero
-	if pubkey[0] == 0 && sig[0] == 0 {
+	// Simplified: just check that pubkey and sig are not all zeros
+	if std.IsZero32(pubkey) {
+		return false
+	}
+	if std.IsZero64(sig) {
 		return false
 	}
 	return true
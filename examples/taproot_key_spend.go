@@ -52,11 +52,11 @@ func main() {
 
 	// 1. Assert the Taproot internal key matches the expected commitment
 	key := jet.InternalKey()
-	jet.Eq256(key, ExpectedInternalKey)
+	jet.Verify(jet.Eq256(key, ExpectedInternalKey))
 
 	// 2. Assert the tapleaf version is the standard Tapscript version (0xc0)
 	version := jet.TapleafVersion()
-	jet.Eq8(version, ExpectedTapleafVersion)
+	jet.Verify(jet.Eq8(version, ExpectedTapleafVersion))
 
 	// 3. Verify the owner's BIP-340 signature
 	msg := jet.SigAllHash()
@@ -76,7 +76,7 @@ func main() {
 	if w.IsLeft {
 		// Complete path: verify preimage hashes to HashLock, then verify sig.
 		hash := jet.SHA256Finalize(jet.SHA256Add32(jet.SHA256Init(), w.Preimage))
-		jet.Eq256(hash, HashLock)
+		jet.Verify(jet.Eq256(hash, HashLock))
 		jet.BIP340Verify(RecipientPubkey, RecipientTestMsg, AliceTestSig)
 	} else {
 		// Cancel path: sender reclaims.
@@ -33,7 +33,10 @@
 //   }
 //
 // Usage:
-//   go run cmd/simgo/main.go -input examples/testable/p2pk_testable.go
+//   go run cmd/simgo/main.go -allow-trivial-main -input examples/testable/p2pk_testable.go
+//
+// The program reads no witness, so it accepts every spend; -allow-trivial-main
+// lets it compile anyway.
 
 package main
 
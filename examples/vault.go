@@ -88,7 +88,7 @@ func main() {
 		jet.CheckLockHeight(ColdKeyUnlock)
 		// 2. Enforce output 0 sends to the pre-committed vault recovery script
 		scriptHash := jet.OutputScriptHash(VaultOutputIndex)
-		jet.Eq256(scriptHash, VaultScript)
+		jet.Verify(jet.Eq256(scriptHash, VaultScript))
 		// 3. Verify cold key signature
 		msg := jet.SigAllHash()
 		jet.BIP340Verify(ColdKeyPubkey, msg, w.ColdKeySig)
@@ -0,0 +1,92 @@
+//go:build ignore
+
+// Vault covenant
+//
+// This example demonstrates a covenant vault whose coins can only move to
+// pre-committed outputs:
+//  1. Left (hot key): the hot key signs a spend that moves the full input
+//     value to the unvaulting output template
+//  2. Right (cold path): anyone may sweep the input value, less a fee of
+//     at most MaxSweepFee, to the cold storage script, once the input is
+//     ColdDelay blocks old
+//
+// std.RequireOutput commits to an output: it expands into the output
+// introspection jets and asserts that the output exists, pays exactly the
+// given explicit value and locks it to the given script hash. The hot key
+// pays the fee from another input, so the vault's full value must reach
+// the output. The sweep needs no signature, so whoever broadcasts it may
+// pay its fee from the vault instead: std.FeeAtMost caps the fee and
+// std.OutputValueAtLeast keeps the rest in the cold output, computing
+// value-MaxSweepFee with a checked subtraction.
+// std.CheckSequenceAtLeast checks the BIP-68 relative lock of the vault's
+// own input, so the cold sweep cannot borrow the age of the fee input.
+//
+// The witness is an Either type:
+// - Left: hot_key_sig for the unvaulting spend
+// - Right: nothing; the covenant alone authorizes the sweep
+//
+// The generated program is checked in as examples/vault_covenant.simf.
+// Try both paths with the built-in evaluator:
+//
+//	go run cmd/simgo/main.go run -input examples/vault_covenant.go \
+//	    -tx examples/vault_covenant.tx.json -witness examples/vault_covenant.witness.json
+//
+// Usage:
+//
+//	go run cmd/simgo/main.go -input examples/vault_covenant.go
+package main
+
+import (
+	"simplicity/jet"
+
+	"github.com/0ceanslim/go-simplicity/std"
+	"github.com/0ceanslim/go-simplicity/std/bitcoin"
+)
+
+// HotKeyPubkey is the BIP-340 x-only public key that may start unvaulting
+const HotKeyPubkey = 0xdff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659
+
+// UnvaultScript is the script hash of the unvaulting output template
+const UnvaultScript = 0xa1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8e9f0a1b2
+
+// ColdScript is the script hash of the cold storage output
+const ColdScript = 0x7d865e959b2466918c9863afca942d0fb89d7c9ac0c99bafc3749504ded97730
+
+// ColdDelay is the relative delay, in blocks, before the cold sweep
+const ColdDelay uint16 = 144
+
+// MaxSweepFee is the largest fee the cold sweep may take from the vault
+const MaxSweepFee uint64 = 1000
+
+// VaultOutput is the output index the covenant constrains
+const VaultOutput uint32 = 0
+
+// VaultWitness selects the spending path.
+// IsLeft=true:  hot key path — unvault with a hot key signature
+// IsLeft=false: cold path — sweep to cold storage after ColdDelay
+type VaultWitness struct {
+	IsLeft    bool
+	HotKeySig bitcoin.Signature // Left: signature of the unvaulting spend
+	Sweep     struct{}          // Right: no data, the covenant authorizes it
+}
+
+func main() {
+	var w VaultWitness
+
+	if w.IsLeft {
+		// Hot key path: the full value goes to the unvaulting template
+		value := jet.CurrentAmount()
+		std.RequireOutput(VaultOutput, value, UnvaultScript)
+		msg := jet.SigAllHash()
+		jet.BIP340Verify(HotKeyPubkey, msg, w.HotKeySig)
+	} else {
+		// Cold path: after the delay, the value less the fee goes to cold
+		// storage
+		std.CheckSequenceAtLeast(ColdDelay)
+		script := jet.OutputScriptHash(VaultOutput)
+		jet.Verify(jet.Eq256(script, ColdScript))
+		value := jet.CurrentAmount()
+		std.FeeAtMost(MaxSweepFee)
+		std.OutputValueAtLeast(VaultOutput, value-MaxSweepFee)
+	}
+}
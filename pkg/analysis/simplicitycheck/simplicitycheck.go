@@ -0,0 +1,97 @@
+// Package simplicitycheck defines an Analyzer that reports the Go features
+// of contracts that Simplicity cannot express, as the compiler would,
+// so that an editor or go vet can flag them before simgo runs.
+//
+// The checks are the compiler's own: compiler.Validate for each file that
+// declares a contract with //simplicity:contract, and compiler.Impurities
+// for each package such a file imports, so the analyzer and the compiler
+// never disagree about what a contract may contain.
+package simplicitycheck
+
+import (
+	"go/types"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/gen"
+)
+
+const doc = `report Go features contracts cannot use
+
+The simplicitycheck analyzer reports, in each file marked with the
+//simplicity:contract directive, what simgo would reject or warn of:
+unbounded and range loops, slices, maps, channels, interfaces, strings,
+signed integers, jets missing on the target chain, and imports of packages
+that are not pure, that is that declare more than constants, types and
+functions or import anything but the compiler-provided packages.
+
+The compiler-provided packages, such as simplicity/jet, have no Go source,
+so the driver reports that they do not import; the analyzer runs anyway.`
+
+// Analyzer reports the contract-compatibility problems of Go files.
+var Analyzer = &analysis.Analyzer{
+	Name:             "simplicitycheck",
+	Doc:              doc,
+	URL:              "https://github.com/0ceanslim/go-simplicity",
+	Run:              run,
+	RunDespiteErrors: true,
+	FactTypes:        []analysis.Fact{new(impure)},
+}
+
+// chain is the -chain flag, Config.Chain of the check.
+var chain string
+
+func init() {
+	Analyzer.Flags.StringVar(&chain, "chain", "", "target chain whose jets contracts may call, as simgo -chain")
+}
+
+// impure is the fact of a package a contract may not import, with the
+// problems that make it so, as the compiler reports them.
+type impure struct {
+	Problems []string
+}
+
+func (*impure) AFact() {}
+
+func (f *impure) String() string {
+	return "impure: " + strings.Join(f.Problems, "; ")
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	if problems := compiler.Impurities(pass.Fset, pass.Files); len(problems) > 0 {
+		f := new(impure)
+		for _, p := range problems {
+			f.Problems = append(f.Problems, p.Format(pass.Fset))
+		}
+		pass.ExportPackageFact(f)
+	}
+
+	imports := make(map[string]*types.Package)
+	for _, pkg := range pass.Pkg.Imports() {
+		imports[pkg.Path()] = pkg
+	}
+	config := compiler.Config{Target: "simplicityhl", Chain: chain}
+	for _, file := range pass.Files {
+		if len(gen.FileContracts(pass.Fset, pass.Fset.File(file.Pos()).Name(), file)) == 0 {
+			continue
+		}
+		for _, p := range compiler.Validate(pass.Fset, file, config) {
+			pass.Report(analysis.Diagnostic{Pos: p.Pos, Category: string(p.Code), Message: p.Code.Sprintf("%s", p.Message)})
+		}
+		for _, spec := range file.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || compiler.IsBuiltinImport(path) || imports[path] == nil {
+				continue
+			}
+			if f := new(impure); pass.ImportPackageFact(imports[path], f) {
+				pass.Report(analysis.Diagnostic{Pos: spec.Pos(), Category: string(diag.ImpurePackage),
+					Message: diag.ImpurePackage.Sprintf("imported package %s is not pure: %s", path, strings.Join(f.Problems, "; "))})
+			}
+		}
+	}
+	return nil, nil
+}
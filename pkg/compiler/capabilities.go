@@ -0,0 +1,118 @@
+package compiler
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/jets"
+	"github.com/0ceanslim/go-simplicity/pkg/transpiler"
+)
+
+// Capability is what the compiler can produce for one target and chain:
+// the answer to "can I use X with target Y". Compile consults the same
+// structure, so its configuration and jet errors agree with it.
+type Capability struct {
+	Target string `json:"target"`
+	Chain  string `json:"chain"`
+	Mode   string `json:"mode"`
+	// Supported reports whether the compiler can compile for the target
+	// and chain at all; Reason says why not.
+	Supported bool   `json:"supported"`
+	Reason    string `json:"reason,omitempty"`
+	// Jets are the SimplicityHL names of the jets a program may call,
+	// sorted, Config.ExtraJets included. Go functions of pkg/jet lowered
+	// to a compiler helper are listed by the jet the helper calls.
+	Jets []string `json:"jets"`
+	// MaxWordBits is the width of the widest unsigned integer, u256 for
+	// SimplicityHL.
+	MaxWordBits int `json:"max_word_bits"`
+	// Witnesses reports whether the output declares a witness module,
+	// which library mode does not.
+	Witnesses bool `json:"witnesses"`
+	// CMR reports whether the compiler computes the commitment Merkle root
+	// of the program, CompileResult.CMR.
+	CMR bool `json:"cmr"`
+	// Dialects are the versions of the output syntax Config.SHLDialect
+	// selects from, newest first.
+	Dialects []string `json:"dialects"`
+}
+
+// Capabilities describes what config's target, chain and mode support. An
+// empty chain is ChainElements and an empty mode "program", as in Compile.
+func Capabilities(config Config) Capability {
+	c := Capability{Target: config.Target, Chain: config.Chain, Mode: config.Mode, Jets: []string{}, Dialects: []string{}}
+	if c.Chain == "" {
+		c.Chain = jets.ChainElements
+	}
+	if c.Mode == "" {
+		c.Mode = "program"
+	}
+	table, err := config.jetTable()
+	switch {
+	case c.Target == "simplicity":
+		c.Reason = "direct Simplicity compilation not yet implemented"
+	case c.Target != "simplicityhl":
+		c.Reason = fmt.Sprintf("unsupported target: %s", c.Target)
+	case c.Chain != jets.ChainElements && c.Chain != jets.ChainBitcoin:
+		c.Reason = fmt.Sprintf("unsupported chain: %s (want %s or %s)", c.Chain, jets.ChainElements, jets.ChainBitcoin)
+	case err != nil:
+		c.Reason = fmt.Sprintf("invalid ExtraJets: %v", err)
+	default:
+		c.Supported = true
+	}
+	if !c.Supported {
+		return c
+	}
+
+	seen := make(map[string]bool)
+	for _, info := range table.AllJets() {
+		if jet := info.Jet(); info.Available(c.Chain) && !seen[jet] {
+			seen[jet] = true
+			c.Jets = append(c.Jets, jet)
+		}
+	}
+	sort.Strings(c.Jets)
+	c.MaxWordBits = 256
+	c.Witnesses = c.Mode != "library"
+	c.Dialects = transpiler.Dialects()
+	return c
+}
+
+// CapabilityMatrix lists the Capabilities of every target and chain the
+// compiler knows, in program mode.
+func CapabilityMatrix() []Capability {
+	var matrix []Capability
+	for _, target := range []string{"simplicityhl", "simplicity"} {
+		for _, chain := range []string{jets.ChainElements, jets.ChainBitcoin} {
+			matrix = append(matrix, Capabilities(Config{Target: target, Chain: chain}))
+		}
+	}
+	return matrix
+}
+
+// jetTable returns the builtin jet table extended with c.ExtraJets.
+func (c Config) jetTable() (*jets.JetRegistry, error) {
+	table := jets.NewRegistry()
+	for _, decl := range c.ExtraJets {
+		if err := table.Declare(decl); err != nil {
+			return nil, err
+		}
+	}
+	return table, nil
+}
+
+// HasJet reports whether a program may call the SimplicityHL jet name.
+func (c Capability) HasJet(name string) bool {
+	i := sort.SearchStrings(c.Jets, name)
+	return i < len(c.Jets) && c.Jets[i] == name
+}
+
+// Err returns the configuration error Compile fails with for c, or nil if
+// c is supported.
+func (c Capability) Err() error {
+	if c.Supported {
+		return nil
+	}
+	return diag.InvalidConfig.Errorf("%s", c.Reason)
+}
@@ -3,128 +3,918 @@
 package compiler
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	gotypes "go/types"
+	"io"
+	"os"
+	"runtime/debug"
+	"runtime/metrics"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/jets"
+	"github.com/0ceanslim/go-simplicity/pkg/optimize"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
 	"github.com/0ceanslim/go-simplicity/pkg/transpiler"
+	"github.com/0ceanslim/go-simplicity/pkg/types"
 )
 
 // Config holds compiler configuration
 type Config struct {
-	Target string // "simplicityhl" or "simplicity"
-	Debug  bool
+	Target string           // "simplicityhl" or "simplicity"
+	Style  transpiler.Style // Output formatting; the zero value selects transpiler.DefaultStyle
+	Entry  string           // Go function compiled as the program root (default: main)
+	Mode   string           // "program" (default) or "library"
+	Chain  string           // "elements" (default) or "bitcoin", the jets available
+
+	// SHLDialect is the version of the SimplicityHL syntax to write, for a
+	// downstream compiler that accepts no other: one of
+	// transpiler.Dialects(), such as "simfony-0.3". The empty string selects
+	// transpiler.LatestDialect. Output in another dialect is rewritten as a
+	// whole, so CompileReader writes it in one piece.
+	SHLDialect string
+
+	// WitnessValues replaces witness constants by emitted name, e.g.
+	// {"AMOUNT": "1000"}. Values use SimplicityHL literal syntax, hex for
+	// byte arrays, and must match the witness's declared type.
+	WitnessValues map[string]string
+
+	// MaxOutputBytes fails compilation once the generated program grows
+	// past this many bytes, for services that compile untrusted input.
+	// Zero means no limit.
+	MaxOutputBytes int
+
+	// MaxNodes fails compilation once the estimated size of the generated
+	// program, in SimplicityHL expression nodes as FunctionInfo.Nodes
+	// counts them, grows past this many: an unrolled loop, inlined helper
+	// or generic instantiation that multiplies the program fails at its Go
+	// position instead of writing it out. Zero selects
+	// transpiler.DefaultMaxNodes; a negative value means no limit.
+	MaxNodes int
+
+	// MaxWitnessBits warns of each witness entry larger than this many
+	// bits, as types.TypeMapper.BitSize counts them, since the spender
+	// pays fees for every byte of witness. Zero selects
+	// DefaultMaxWitnessBits; a negative value means no limit.
+	MaxWitnessBits int
+
+	// TypeMapper is a pre-configured type mapper, typically extended with
+	// RegisterType for domain types. Nil selects the default mappings. The
+	// mapper is read, never modified, so one may be shared across compilers.
+	TypeMapper *types.TypeMapper
+
+	// ExtraJets declares jets beyond the builtin table, such as proposed
+	// jets under experiment. Programs call them as jet.Custom("my_jet", x),
+	// by their Go name, or through a stub package of JetDecl.Package. A
+	// declaration that collides with a builtin jet fails each compile.
+	ExtraJets []jets.JetDecl
+
+	// Includes are modules compiled earlier, such as a shared library,
+	// whose functions the program calls through Go stubs: declarations
+	// without a body, checked against the included signatures. The
+	// dialect's include directive refers to each, or, as in every
+	// SimplicityHL version so far, the module is copied into the output.
+	// Output is then written in one piece, as with SelfCheck.
+	Includes []Include
+
+	// PreTransforms rewrite the parsed file in order, after validation and
+	// before transpilation, e.g. to lower project-specific helper calls into
+	// supported constructs. A hook must leave the AST well-formed: the
+	// rewritten file is not validated again. A hook error aborts the compile.
+	PreTransforms []func(*ast.File, *token.FileSet) error
+
+	// SelfCheck re-parses the generated program with pkg/shlparse and fails
+	// the compile if it is not valid SimplicityHL. CompileReader then writes
+	// the program in one piece.
+	SelfCheck bool
+
+	// BuildTags satisfy build constraints, in the file being compiled and
+	// in the packages it imports. A file named for compilation is compiled
+	// even if it excludes itself, with a warning.
+	BuildTags []string
+
+	// Packages shares imported packages across compilers, typically every
+	// compiler of a batch. Nil loads them afresh for each compile.
+	Packages *PackageCache
+
+	// AllowTrivialMain compiles a program whose acceptance depends on no
+	// witness value and nothing of the spending transaction, such as one
+	// whose checks all fold to true, or that gives its witnesses only to
+	// helpers that never read them. Without it such a program fails to
+	// compile, since anyone could spend it.
+	AllowTrivialMain bool
+
+	// Strict fails the compile, with SIM0214, wherever the transpiler would
+	// fall back on a guess: an expression it cannot lower and compiles to
+	// true, a statement it leaves out, a witness whose type it infers from
+	// the value, or a main that checks nothing and asserts a witness or
+	// helper picked by name. CompileResult.Fallbacks lists the ones a
+	// compile without it relied on. It also fails the compile, with
+	// SIM0311, on a call whose return value is discarded.
+	Strict bool
+
+	// NoTransliteration rejects identifiers that are not ASCII, such as
+	// amountÉlevé, instead of transliterating them to ASCII SimplicityHL
+	// names such as amount_eleve.
+	NoTransliteration bool
+
+	// NoComments leaves the Go doc comments of functions, constants and
+	// witness declarations out of the generated program, where they are
+	// otherwise written as // comments above the items they document.
+	NoComments bool
+
+	// CheckedArithmetic fails the spend where Go's integer division would
+	// panic: a division or remainder by a witness, parameter or other
+	// value known only at spend time asserts that it is not zero, where
+	// the divide and modulo jets return 0 and the dividend. Without it,
+	// such a divisor that the program never checks draws a warning.
+	CheckedArithmetic bool
+
+	// OptLevel selects the optimization passes; the options below turn
+	// single passes off, or CSE on, whatever the level. When DCE or CSE
+	// runs, the program is rewritten as a whole, so CompileReader writes it
+	// in one piece, and the MaxNodes estimate is of the program before.
+	OptLevel OptLevel
+
+	// NoInline calls helper functions rather than substituting their
+	// bodies at each call site. Calls with known arguments are still
+	// evaluated, unless NoCallFolding is set.
+	NoInline bool
+
+	// NoCallFolding calls a helper whose arguments are all constants
+	// rather than evaluating its body at compile time and using the
+	// result, as CheckTimelock(1640995300) otherwise is.
+	NoCallFolding bool
+
+	// NoThresholdTrees turns off the lowering of k-of-n counting, such as
+	// a 2-of-3 multisig that counts valid signatures, to a comparison tree.
+	NoThresholdTrees bool
+
+	// NoDCE keeps the functions that main does not reach, at O1 and O2.
+	NoDCE bool
+
+	// Optimize computes a jet call that a generated function repeats, such
+	// as jet::sig_all_hash() in both arms of a match, once in a let binding
+	// in the nearest scope around every use; see optimize.CSE. O2 implies
+	// it.
+	Optimize bool
+
+	// Debug logs at LogDebug at least.
+	//
+	// Deprecated: Set LogLevel to LogDebug.
+	Debug bool
+
+	// LogLevel selects the messages a compile logs about itself: none by
+	// default, phase timings and counts at LogDebug, and the AST as well
+	// at LogTrace.
+	LogLevel LogLevel
+
+	// Logger receives the log messages. Nil writes them to os.Stderr with
+	// NewLogger.
+	Logger Logger
 }
 
 // Compiler represents the Go to Simplicity compiler
 type Compiler struct {
 	config     Config
+	passes     passes
+	jets       *jets.JetRegistry // The jet table extended with ExtraJets
 	fset       *token.FileSet
 	transpiler *transpiler.Transpiler
+	file       *ast.File // Source of the most recent successful Compile
+	imports    []transpiler.Import
+	output     string
+	origin     []int             // Line of the transpiled program each output line comes from, when optimized
+	emitted    map[string]string // Bodies of the functions DCE left in, when it ran
+	warnings   []string
+	logger     Logger
+	phase      string // Phase of the running compile, for InternalError
+	phaseStart time.Time
+	stats      Stats // Of the most recent compile
+	samples    []metrics.Sample
+	// Allocation counters when the running phase started
+	phaseBytes, phaseObjects uint64
 }
 
 // New creates a new compiler instance
 func New(config Config) *Compiler {
-	return &Compiler{
-		config:     config,
-		fset:       token.NewFileSet(),
-		transpiler: transpiler.New(),
+	fset := token.NewFileSet()
+	if config.Packages != nil {
+		fset = config.Packages.fset
+	}
+	maxNodes := config.MaxNodes
+	if maxNodes == 0 {
+		maxNodes = transpiler.DefaultMaxNodes
+	}
+	passes, _ := config.passes()  // An unsupported level fails each compile
+	table, _ := config.jetTable() // As do invalid ExtraJets, in Capabilities
+	logger := config.Logger
+	if logger == nil {
+		logger = NewLogger(os.Stderr)
 	}
+	c := &Compiler{
+		config: config,
+		passes: passes,
+		jets:   table,
+		fset:   fset,
+		logger: logger,
+	}
+	c.transpiler = transpiler.NewWithOptions(transpiler.Options{
+		Style:          config.Style,
+		Entry:          config.Entry,
+		Library:        config.Mode == "library",
+		WitnessValues:  config.WitnessValues,
+		MaxOutputBytes: config.MaxOutputBytes,
+		MaxNodes:       max(maxNodes, 0),
+		TypeMapper:     config.TypeMapper,
+		Jets:           table,
+		FileSet:        fset,
+
+		NoThresholdTrees:  !passes.thresholdTrees,
+		NoInline:          !passes.inline,
+		NoCallFolding:     !passes.foldCalls,
+		NoComments:        config.NoComments,
+		CheckedArithmetic: config.CheckedArithmetic,
+		Strict:            config.Strict,
+		OnGenerate:        func() { c.enter("rendering") },
+	})
+	return c
 }
 
-// Compile compiles Go source code to the target format
+// Compile compiles Go source code to the target format.
+//
+// Output is deterministic: the same source and Config yield byte-identical
+// output on every run and platform, whatever was compiled before. Emitted
+// collections follow source order or are sorted, and generated names are
+// derived from the source rather than from counters kept across calls.
 func (c *Compiler) Compile(source, filename string) (string, error) {
+	return c.CompileContext(context.Background(), source, filename)
+}
+
+// CompileContext is Compile with cancellation, for services that bound the
+// time spent on one input. The context is checked between phases and
+// inside analysis loops such as loop unrolling. When it expires the error
+// names the phase that was running and wraps ctx.Err().
+func (c *Compiler) CompileContext(ctx context.Context, source, filename string) (string, error) {
+	var out strings.Builder
+	out.Grow(len(source)) // Generated code is about as long as its source
+	if err := c.compile(ctx, source, filename, &out); err != nil {
+		return "", err
+	}
+	c.output = out.String()
+	return c.output, nil
+}
+
+// CompileReader compiles the Go source read from r and writes the output
+// to w, one write for each top-level item. Compile is the string-based
+// equivalent. Nothing is written until the program passes its checks, so a
+// compile that fails leaves w untouched. A write error is returned as is.
+// The CompileResult of a compile to a writer has no Code.
+func (c *Compiler) CompileReader(r io.Reader, filename string, w io.Writer) error {
+	return c.compile(context.Background(), r, filename, w)
+}
+
+// compile runs the pipeline on src, which is a string or an io.Reader. A
+// panic in any phase is returned as an *InternalError, so that a bug in the
+// compiler fails one compile rather than the program embedding it.
+func (c *Compiler) compile(ctx context.Context, src interface{}, filename string, w io.Writer) (err error) {
+	c.phase, c.phaseStart, c.stats = "configuration", time.Time{}, Stats{} // Too quick to time
+	defer func() {
+		if r := recover(); r != nil {
+			err = &InternalError{Phase: c.phase, Value: r, Stack: debug.Stack(), Version: Version}
+		}
+		c.phase = ""
+	}()
+	if err := c.pipeline(ctx, src, filename, w); err != nil {
+		c.logf(LogDebug, "%s failed", c.phase)
+		return err
+	}
+	c.enter("")
+	c.logf(LogDebug, "compile took %v and allocated %d bytes in %d objects", c.stats.Wall.Round(time.Microsecond), c.stats.AllocBytes, c.stats.Allocs)
+	return nil
+}
+
+// pipeline runs the phases of compile, recording the running one in
+// c.phase.
+func (c *Compiler) pipeline(ctx context.Context, src interface{}, filename string, w io.Writer) error {
+	c.file, c.output, c.origin, c.emitted, c.warnings = nil, "", nil, nil, nil
+	switch c.config.Mode {
+	case "", "program":
+	case "library":
+		if c.config.Entry != "" {
+			return diag.InvalidConfig.Errorf("library mode has no entry point, got entry %s", c.config.Entry)
+		}
+	default:
+		return diag.InvalidConfig.Errorf("unsupported mode: %s", c.config.Mode)
+	}
+	caps := Capabilities(c.config)
+	if err := caps.Err(); err != nil {
+		return err
+	}
+	if len(c.config.WitnessValues) > 0 && !caps.Witnesses {
+		return diag.InvalidConfig.Errorf("%s mode has no witness module to assign values to", caps.Mode)
+	}
+	dialect, err := transpiler.LookupDialect(c.config.SHLDialect)
+	if err != nil {
+		return diag.InvalidConfig.Wrap(err)
+	}
+	if _, err := c.config.passes(); err != nil {
+		return err
+	}
+	modules, err := parseIncludes(c.config.Includes)
+	if err != nil {
+		return err
+	}
+
 	// Parse Go source
-	file, err := parser.ParseFile(c.fset, filename, source, parser.ParseComments)
+	c.enter("parsing")
+	if err := canceled(ctx, "parsing"); err != nil {
+		return err
+	}
+	file, err := parser.ParseFile(c.fset, filename, src, parser.ParseComments)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse Go source: %w", err)
+		return diag.SyntaxError.Errorf("failed to parse Go source: %w", err)
+	}
+	if err := c.checkBuildConstraint(file, filename); err != nil {
+		return err
 	}
 
-	if c.config.Debug {
-		fmt.Printf("Parsed AST for %s\n", filename)
-		ast.Print(c.fset, file)
+	c.logf(LogDebug, "parsed %s: %d declarations, %d imports", filename, len(file.Decls), len(file.Imports))
+	if c.logLevel() >= LogTrace {
+		var dump strings.Builder
+		ast.Fprint(&dump, c.fset, file, nil)
+		c.logf(LogTrace, "AST of %s:\n%s", filename, dump.String())
 	}
 
 	// Validate that the Go code is compatible with Simplicity
+	c.enter("validation")
+	if err := canceled(ctx, "validation"); err != nil {
+		return err
+	}
 	if err := c.validateGoCode(file); err != nil {
-		return "", fmt.Errorf("go code validation failed: %w", err)
+		return fmt.Errorf("go code validation failed: %w", err)
 	}
+	c.warnings = append(c.warnings, confidentialWarnings(c.fset, file)...)
+	c.warnings = append(c.warnings, unreachableWarnings(c.fset, file)...)
+	c.warnings = append(c.warnings, shadowWarnings(c.fset, file)...)
+	c.warnings = append(c.warnings, truncationWarnings(c.fset, file)...)
+	c.warnings = append(c.warnings, hashOrderWarnings(c.fset, file)...)
+	divisorWarnings, err := divisorChecks(c.fset, file, c.config.CheckedArithmetic)
+	if err != nil {
+		return err
+	}
+	c.warnings = append(c.warnings, divisorWarnings...)
 
-	// Transpile to target format
-	switch c.config.Target {
-	case "simplicityhl":
-		return c.transpiler.ToSimplicityHL(file)
-	case "simplicity":
-		return "", fmt.Errorf("direct Simplicity compilation not yet implemented")
-	default:
-		return "", fmt.Errorf("unsupported target: %s", c.config.Target)
+	for i, transform := range c.config.PreTransforms {
+		c.enter(fmt.Sprintf("pre-transform %d", i))
+		if err := transform(file, c.fset); err != nil {
+			return fmt.Errorf("pre-transform %d: %w", i, err)
+		}
+	}
+
+	// Check the names the file uses, after the hooks have rewritten it
+	c.enter("resolution")
+	if c.config.Mode != "library" && (c.config.Entry == "" || c.config.Entry == "main") {
+		if err := c.checkMain(file); err != nil {
+			return err
+		}
 	}
+	if err := undefinedNames(c.fset, file, c.config.TypeMapper); err != nil {
+		return err
+	}
+
+	// Resolve imported user packages
+	c.enter("import loading")
+	imports, err := c.loadImports(ctx, file, filename)
+	if cerr := canceled(ctx, "import loading"); cerr != nil {
+		return cerr
+	}
+	if err != nil {
+		return err
+	}
+	c.transpiler.SetImports(imports)
+
+	// Transpile to SimplicityHL, the one target Capabilities supports
+	c.enter("analysis")
+	var generated bytes.Buffer
+	generated.Grow(int(file.FileEnd - file.FileStart))
+	// Nothing reaches w until the checks below pass, as a program they
+	// reject, such as one anyone can spend, must not be written out. Output
+	// that is not rewritten is then written as the transpiler wrote it.
+	buffered := c.config.SelfCheck || c.passes.dce || c.passes.cse || dialect.Name != transpiler.LatestDialect || len(modules) > 0
+	held := &heldWriter{buf: &generated}
+	if !buffered {
+		if err := c.transpiler.WriteSimplicityHL(ctx, file, held); err != nil {
+			return err
+		}
+	} else {
+		if err := c.transpiler.WriteSimplicityHL(ctx, file, &generated); err != nil {
+			return err
+		}
+	}
+	c.logf(LogDebug, "transpiled %d functions, %d witnesses, %d constants, about %d nodes",
+		len(c.transpiler.Functions()), len(c.transpiler.Witnesses()), len(c.transpiler.Constants()), c.transpiler.Nodes())
+	c.logWitnesses()
+	if c.passes.dce || c.passes.cse {
+		c.enter("optimization")
+		if err := canceled(ctx, "optimization"); err != nil {
+			return err
+		}
+		code := generated.String()
+		if c.passes.dce {
+			code, c.origin = optimize.DCE(code)
+			c.emitted = functionBodies(code)
+		}
+		if c.passes.cse {
+			var origin []int
+			code, origin = optimize.CSEAt(code, func(line int) (int, int, bool) {
+				pos, ok := c.SourcePosition(line)
+				return pos.Line, pos.Column, ok
+			})
+			c.origin = composeOrigin(c.origin, origin)
+		}
+		generated.Reset()
+		generated.WriteString(code)
+	}
+	c.enter("checking")
+	// The checks read the program with the included modules copied in,
+	// so that calls of their functions type-check.
+	program, programOrigin := generated.String(), c.origin
+	if err := c.checkStubs(modules); err != nil {
+		return err
+	}
+	if len(modules) > 0 {
+		code, origin := withIncludes(program, modules, "")
+		c.origin = composeOrigin(c.origin, origin)
+		generated.Reset()
+		generated.WriteString(code)
+	}
+	if c.config.SelfCheck {
+		if err := selfCheck(generated.String()); err != nil {
+			return err
+		}
+	}
+	if err := c.checkTypes(generated.String()); err != nil {
+		return err
+	}
+	if c.config.Mode != "library" {
+		if err := c.checkAcceptance(generated.String()); err != nil {
+			return err
+		}
+	}
+	c.warnings = append(c.warnings, c.witnessWarnings()...)
+	if len(modules) > 0 && dialect.Include != "" {
+		code, origin := withIncludes(program, modules, dialect.Include)
+		c.origin = composeOrigin(programOrigin, origin)
+		generated.Reset()
+		generated.WriteString(code)
+	}
+	if dialect.Name != transpiler.LatestDialect {
+		// The checks above read the latest dialect; the output is rewritten last.
+		code, origin := dialect.Rewrite(generated.String(), c.jets)
+		c.origin = composeOrigin(c.origin, origin)
+		generated.Reset()
+		generated.WriteString(code)
+	}
+	if buffered {
+		if _, err := w.Write(generated.Bytes()); err != nil {
+			return err
+		}
+	} else if err := held.flush(w); err != nil {
+		return err
+	}
+	c.file, c.imports = file, imports
+	return nil
 }
 
-// validateGoCode checks if the Go code uses only supported features
-func (c *Compiler) validateGoCode(file *ast.File) error {
+// heldWriter keeps the writes of the transpiler, one top-level item each,
+// in buf until the checks of the program pass.
+type heldWriter struct {
+	buf  *bytes.Buffer
+	ends []int // Offset in buf of the end of each write
+}
+
+func (h *heldWriter) Write(p []byte) (int, error) {
+	n, err := h.buf.Write(p)
+	h.ends = append(h.ends, h.buf.Len())
+	return n, err
+}
+
+// flush writes the items to w in order, one write each.
+func (h *heldWriter) flush(w io.Writer) error {
+	start := 0
+	for _, end := range h.ends {
+		if _, err := w.Write(h.buf.Bytes()[start:end]); err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
+
+// composeOrigin maps the lines of a pass's output, whose origins in its
+// input are next, through the origins of that input, first: nil when the
+// input is the transpiled program itself.
+func composeOrigin(first, next []int) []int {
+	if first == nil {
+		return next
+	}
+	for i, line := range next {
+		if line >= 1 && line <= len(first) {
+			next[i] = first[line-1]
+		}
+	}
+	return next
+}
+
+// selfCheck reports generated code that pkg/shlparse rejects. Such output
+// is a transpiler bug, not a problem with the contract.
+func selfCheck(code string) error {
+	if _, err := shlparse.Parse(code); err != nil {
+		var perr *shlparse.Error
+		if errors.As(err, &perr) {
+			return fmt.Errorf("internal error: generated invalid SimplicityHL at line %d: %s", perr.Line, perr.Msg)
+		}
+		return fmt.Errorf("internal error: generated invalid SimplicityHL: %w", err)
+	}
+	return nil
+}
+
+// canceled reports an expired context as an error naming the phase.
+func canceled(ctx context.Context, phase string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("compile canceled during %s: %w", phase, err)
+	}
+	return nil
+}
+
+// Warnings returns the problems noted by the most recent Compile call that
+// did not stop it.
+func (c *Compiler) Warnings() []string {
+	return c.warnings
+}
+
+// Witnesses returns the witness entries declared by the most recent
+// successful Compile call.
+func (c *Compiler) Witnesses() []transpiler.WitnessValue {
+	return c.transpiler.Witnesses()
+}
+
+// SourcePosition maps a 1-based line of the most recent Compile output to
+// the Go source it was generated from. It reports false for lines without a
+// Go origin, such as module headers.
+func (c *Compiler) SourcePosition(line int) (token.Position, bool) {
+	if c.origin != nil {
+		if line < 1 || line > len(c.origin) {
+			return token.Position{}, false
+		}
+		line = c.origin[line-1]
+	}
+	lines := c.transpiler.SourceMap()
+	if line < 1 || line > len(lines) || !lines[line-1].IsValid() {
+		return token.Position{}, false
+	}
+	return c.fset.Position(lines[line-1]), true
+}
+
+// ExportedFunctions returns the exported top-level functions declared in
+// source, in declaration order. These are the candidate entry points for
+// Config.Entry.
+func ExportedFunctions(source, filename string) ([]string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), filename, source, 0)
+	if err != nil {
+		return nil, diag.SyntaxError.Errorf("failed to parse Go source: %w", err)
+	}
+
+	var names []string
+	for _, decl := range file.Decls {
+		if funcDecl, ok := decl.(*ast.FuncDecl); ok && funcDecl.Recv == nil && funcDecl.Name.IsExported() {
+			names = append(names, funcDecl.Name.Name)
+		}
+	}
+	return names, nil
+}
+
+// Problem is a finding of the feature validation of a Go file: a
+// construct Simplicity has no equivalent of, or, when Warning is set, one
+// that compiles but may not mean what it does in Go.
+type Problem struct {
+	Pos     token.Pos
+	Code    diag.Code // Unset when the error reporting the problem has the code
+	Message string    // Without the position or the code
+	Warning bool
+}
+
+// Format returns the problem as the compiler reports it, positioned in
+// fset and ending with its code.
+func (p Problem) Format(fset *token.FileSet) string {
+	if p.Code == "" {
+		return fmt.Sprintf("%s: %s", fset.Position(p.Pos), p.Message)
+	}
+	return p.Code.Sprintf("%s: %s", fset.Position(p.Pos), p.Message)
+}
+
+// Validate checks that file, parsed into fset, uses only the Go features
+// the compiler supports under config, and returns the problems in the
+// order the validator finds them. It is the check Compile makes before
+// translating, and the one the simplicitycheck analyzer reports. Jets are
+// checked against Capabilities(config), for a supported target.
+func Validate(fset *token.FileSet, file *ast.File, config Config) []Problem {
 	validator := &goValidator{
-		errors: []string{},
+		fset: fset,
+		caps: Capabilities(config),
+
+		transliterate: !config.NoTransliteration,
+		strict:        config.Strict,
+	}
+	var stubs []string
+	if validator.caps.Supported {
+		validator.registry, _ = config.jetTable() // Capabilities has checked it
+		stubs = validator.registry.Packages()
 	}
+	validator.jets = transpiler.JetPackageNames(file, stubs...)
 
+	validator.checkImports(file)
 	ast.Inspect(file, validator.visit)
+	ast.Inspect(file, validator.visitUnimportedJets)
+	if validator.registry != nil {
+		ast.Inspect(file, validator.visitChainJets)
+	}
+	// Dynamic typing is looked for everywhere, including below the nodes
+	// visit stops at, such as loop bodies and call arguments.
+	ast.Inspect(file, validator.visitDynamicTyping)
+	ast.Inspect(file, validator.visitStrings)
+	validator.checkNames(file)
+	validator.checkSignedInts(file)
+	validator.checkDiscardedValues(file)
+	return validator.problems
+}
 
-	if len(validator.errors) > 0 {
-		return fmt.Errorf("unsupported Go features detected:\n%s", strings.Join(validator.errors, "\n"))
+// validateGoCode checks if the Go code uses only supported features, and
+// records the warnings of the check.
+func (c *Compiler) validateGoCode(file *ast.File) error {
+	var errors []string
+	for _, p := range Validate(c.fset, file, c.config) {
+		if p.Warning {
+			c.warnings = append(c.warnings, p.Format(c.fset))
+		} else {
+			errors = append(errors, p.Format(c.fset))
+		}
+	}
+	if len(errors) > 0 {
+		return fmt.Errorf("unsupported Go features detected:\n%s", strings.Join(errors, "\n"))
 	}
 
 	return nil
 }
 
+// checkMain requires the parameterless main() that roots a program compiled
+// without an explicit entry. When it is missing, the error lists the
+// exported functions that could be compiled instead.
+func (c *Compiler) checkMain(file *ast.File) error {
+	var exported []string
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil {
+			continue
+		}
+		if fn.Name.Name == "main" {
+			pos := c.fset.Position(fn.Pos())
+			if n := fn.Type.Params.NumFields(); n > 0 {
+				return diag.MainSignature.Errorf("%s: main must take no parameters, has %d; use -entry with an exported function for a spend path with arguments", pos, n)
+			}
+			if fn.Type.Results.NumFields() > 0 {
+				return diag.MainSignature.Errorf("%s: main must not return values; use -entry with an exported function for a predicate", pos)
+			}
+			return nil
+		}
+		if fn.Name.IsExported() {
+			exported = append(exported, fn.Name.Name)
+		}
+	}
+	if len(exported) == 0 {
+		return diag.NoMain.Errorf("no main function found: a program needs func main() as its root, or use -mode library for a file of helpers")
+	}
+	return diag.NoMain.Errorf("no main function found; exported functions: %s. Compile one with -entry <name>, or all of them as helpers with -mode library",
+		strings.Join(exported, ", "))
+}
+
 type goValidator struct {
-	errors []string
+	fset     *token.FileSet
+	problems []Problem
+	consts   bool            // Inside a const declaration
+	jets     map[string]bool // Local names of the jet package
+	names    map[string]bool // Local names of every import
+	std      map[string]bool // Local names of the std package
+	caps     Capability      // Of the Config validated against
+
+	// registry looks up the jet a Go function calls, to check it against
+	// caps. Nil for an unsupported target, whose jets are not checked.
+	registry *jets.JetRegistry
+
+	transliterate bool // Config.NoTransliteration is unset
+	strict        bool // Config.Strict is set
+}
+
+// report records an error at pos.
+func (v *goValidator) report(pos token.Pos, code diag.Code, format string, args ...any) {
+	v.problems = append(v.problems, Problem{Pos: pos, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// checkImports validates the import declarations of file: dot imports are
+// rejected, since calls into the package would look like local calls, and
+// under simplicity/ only the compiler-provided packages exist.
+func (v *goValidator) checkImports(file *ast.File) {
+	v.names = make(map[string]bool)
+	v.std = make(map[string]bool)
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := path[strings.LastIndex(path, "/")+1:]
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		v.names[name] = true
+		if path == transpiler.StdImportPath {
+			v.std[name] = true
+		}
+		if spec.Name != nil && spec.Name.Name == "." {
+			v.report(spec.Pos(), diag.DotImport, "dot import of %s is not supported: it makes selector resolution ambiguous; import the package by name, as in import j %q", path, path)
+			continue
+		}
+		if isCompilerNamespace(path) && !IsBuiltinImport(path) {
+			v.report(spec.Pos(), diag.UnknownPackage, "unknown compiler package %s; the compiler provides %s", path, strings.Join(builtinPaths(), ", "))
+		}
+	}
+}
+
+// visitUnimportedJets reports jet.X selectors in a file that imports no
+// package under the name jet, typically because it aliases the jet package.
+// Resolution goes through the imports, so they would not be jets.
+func (v *goValidator) visitUnimportedJets(n ast.Node) bool {
+	sel, ok := n.(*ast.SelectorExpr)
+	if !ok {
+		return true
+	}
+	if ident, ok := sel.X.(*ast.Ident); ok && ident.Name == "jet" && !v.names["jet"] {
+		v.report(sel.Pos(), diag.UnimportedJet, "jet.%s: the file does not import %s under the name jet", sel.Sel.Name, transpiler.JetImportPath)
+	}
+	return true
+}
+
+// visitChainJets reports jets that the target chain does not have, and
+// jet.Custom calls that name no jet.
+func (v *goValidator) visitChainJets(n ast.Node) bool {
+	var sel *ast.SelectorExpr
+	var call *ast.CallExpr
+	switch n := n.(type) {
+	case *ast.SelectorExpr:
+		sel = n
+	case *ast.CallExpr:
+		sel, _ = n.Fun.(*ast.SelectorExpr)
+		call = n
+	}
+	if sel == nil || call != nil && sel.Sel.Name != jets.CustomGoName {
+		return true
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok || !v.jets[ident.Name] {
+		return true
+	}
+	info, ok := v.registry.Lookup(sel.Sel.Name)
+	if call != nil {
+		var err error
+		if info, err = transpiler.CustomJet(v.registry, call); err != nil {
+			code, _ := diag.CodeOf(err)
+			v.report(call.Pos(), code, "%v", errors.Unwrap(err))
+			return false
+		}
+		ok = true
+	}
+	if ok && !v.caps.HasJet(info.Jet()) {
+		v.report(sel.Pos(), diag.JetNotOnChain, "%s.%s (jet::%s) reads the transaction environment, which SimplicityHL defines only for chain %s; chain %s, set by -chain or Config.Chain, has the core jets alone",
+			ident.Name, sel.Sel.Name, info.Jet(), jets.ChainElements, v.caps.Chain)
+	}
+	return true
+}
+
+// builtinPaths lists the compiler-provided packages in order.
+func builtinPaths() []string {
+	paths := make([]string, 0, len(builtinPackages))
+	for path := range builtinPackages {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// sumTypeHint is the alternative to dynamic typing offered with type
+// assertion and type switch errors.
+const sumTypeHint = "Simplicity has no dynamic typing; declare the alternatives as a sum type " +
+	"(a struct with IsLeft bool maps to Either<L, R>, one with IsSome bool and Value T to Option<T>) and branch on its tag"
+
+// visitDynamicTyping reports type assertions and type switches.
+func (v *goValidator) visitDynamicTyping(n ast.Node) bool {
+	switch node := n.(type) {
+	case *ast.TypeSwitchStmt:
+		var subject ast.Expr
+		switch assign := node.Assign.(type) {
+		case *ast.AssignStmt:
+			subject = assign.Rhs[0].(*ast.TypeAssertExpr).X
+		case *ast.ExprStmt:
+			subject = assign.X.(*ast.TypeAssertExpr).X
+		}
+		v.report(node.Pos(), diag.DynamicTyping, "type switch on %s is not supported: %s", gotypes.ExprString(subject), sumTypeHint)
+		// The x.(type) guard is part of the switch; its cases may hold more
+		ast.Inspect(node.Body, v.visitDynamicTyping)
+		return false
+	case *ast.TypeAssertExpr:
+		v.report(node.Pos(), diag.DynamicTyping, "type assertion %s is not supported: %s", gotypes.ExprString(node), sumTypeHint)
+	}
+	return true
+}
+
+// zeroCheckHint points a rejected loop whose body compares a value with
+// zero, as a loop testing a byte array for zeros does, at the std helpers
+// that make the test without a loop.
+func zeroCheckHint(body *ast.BlockStmt) string {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if bin, ok := n.(*ast.BinaryExpr); ok && (bin.Op == token.NEQ || bin.Op == token.EQL) {
+			for _, side := range []ast.Expr{bin.X, bin.Y} {
+				if lit, ok := side.(*ast.BasicLit); ok && lit.Kind == token.INT && lit.Value == "0" {
+					found = true
+				}
+			}
+		}
+		return !found
+	})
+	if !found {
+		return ""
+	}
+	return "; to test whether a byte array is all zeros, std.IsZero20, std.IsZero32 and std.IsZero64 take a whole [20]byte, [32]byte or [64]byte"
 }
 
 func (v *goValidator) visit(n ast.Node) bool {
 	switch node := n.(type) {
 	case *ast.ForStmt:
 		if !v.isBoundedForLoop(node) {
-			v.errors = append(v.errors, "unbounded loops are not supported in Simplicity (use bounded for loops like 'for i := 0; i < N; i++')")
+			v.report(node.Pos(), diag.UnboundedLoop, "unbounded loops are not supported in Simplicity: a for loop with a constant bound, as in for i := 0; i < 4; i++, is unrolled automatically, but the bound of this one is not constant because %s%s",
+				loopBoundProblem(node), zeroCheckHint(node.Body))
 			return false
 		}
 		return true
 	case *ast.RangeStmt:
-		v.errors = append(v.errors, "range loops are not supported in Simplicity")
+		if _, ok := transpiler.RangeIntLoop(node, "_"); ok {
+			return true
+		}
+		v.report(node.Pos(), diag.RangeLoop, "range loops are not supported in Simplicity: a range over an integer constant, as in for i := range 4 or range N with const N = 4, is unrolled like for i := 0; i < 4; i++, but this one ranges over %s%s",
+			gotypes.ExprString(node.X), zeroCheckHint(node.Body))
 		return false
 	case *ast.GoStmt:
-		v.errors = append(v.errors, "goroutines are not supported in Simplicity")
+		v.report(node.Pos(), diag.Goroutine, "goroutines are not supported in Simplicity")
 		return false
 	case *ast.ChanType:
-		v.errors = append(v.errors, "channels are not supported in Simplicity")
+		v.report(node.Pos(), diag.Channel, "channels are not supported in Simplicity")
 		return false
 	case *ast.InterfaceType:
-		v.errors = append(v.errors, "interfaces are not supported in Simplicity")
+		v.report(node.Pos(), diag.Interface, "interfaces are not supported in Simplicity")
 		return false
 	case *ast.ArrayType:
 		if node.Len == nil {
-			v.errors = append(v.errors, "slices are not supported, use fixed-size arrays")
+			v.report(node.Pos(), diag.Slice, "slices are not supported: %s", sliceHint(node))
 			return false
 		}
 	case *ast.MapType:
-		v.errors = append(v.errors, "maps are not supported in Simplicity")
+		v.report(node.Pos(), diag.Map, "maps are not supported in Simplicity")
 		return false
 	case *ast.CallExpr:
 		return v.visitCallExpr(node)
 	case *ast.TypeSpec:
 		if _, ok := node.Type.(*ast.InterfaceType); ok {
-			v.errors = append(v.errors, "interfaces are not supported in Simplicity")
+			v.report(node.Pos(), diag.Interface, "interfaces are not supported in Simplicity")
 		}
 	}
 	return true
 }
 
 // visitCallExpr validates a call expression node.
-// jet.X() calls are always allowed; make() calls are checked for unsupported types.
+// Jet calls, through whatever name the file imports the jet package as, are
+// always allowed; make() calls are checked for unsupported types.
 func (v *goValidator) visitCallExpr(node *ast.CallExpr) bool {
 	if sel, ok := node.Fun.(*ast.SelectorExpr); ok {
-		if ident, ok := sel.X.(*ast.Ident); ok && ident.Name == "jet" {
+		if ident, ok := sel.X.(*ast.Ident); ok && v.jets[ident.Name] {
 			return true
 		}
 	}
@@ -141,18 +931,200 @@ func (v *goValidator) validateMakeArgs(args []ast.Expr) {
 	}
 	switch t := args[0].(type) {
 	case *ast.MapType:
-		v.errors = append(v.errors, "maps are not supported in Simplicity")
+		v.report(t.Pos(), diag.Map, "maps are not supported in Simplicity")
 	case *ast.ChanType:
-		v.errors = append(v.errors, "channels are not supported in Simplicity")
+		v.report(t.Pos(), diag.Channel, "channels are not supported in Simplicity")
 	case *ast.ArrayType:
 		if t.Len == nil {
-			v.errors = append(v.errors, "slices are not supported, use fixed-size arrays")
+			v.report(t.Pos(), diag.Slice, "slices are not supported: %s", sliceHint(t))
+		}
+	}
+}
+
+// stringHint is the alternative to strings offered with string errors.
+const stringHint = "Simplicity has no strings; use a fixed-size byte array such as [32]byte"
+
+// stdStringArgs are the std functions whose first argument may be a string
+// literal.
+var stdStringArgs = map[string]bool{"TaggedHash": true, "HashFromHexRaw": true, "HashFromHexDisplay": true}
+
+// visitStrings reports string types and string literals outside the
+// places they mean something: hex constants, which decode to byte arrays,
+// panic messages, which are dropped, std.TaggedHash tags, which the
+// compiler hashes, and the hex of std.HashFromHexRaw and
+// std.HashFromHexDisplay, which it decodes.
+func (v *goValidator) visitStrings(n ast.Node) bool {
+	switch node := n.(type) {
+	case *ast.ImportSpec:
+		return false
+	case *ast.GenDecl:
+		v.consts = node.Tok == token.CONST
+	case *ast.Field:
+		if isIdent(node.Type, "string") {
+			v.stringDecl(node.Type.Pos(), node.Names, "has type string")
+			return false
+		}
+		ast.Inspect(node.Type, v.visitStrings)
+		return false // Struct tags are strings
+	case *ast.ValueSpec:
+		if node.Type != nil && isIdent(node.Type, "string") {
+			v.stringDecl(node.Pos(), node.Names, "has type string")
+			return false
+		}
+		if node.Type != nil {
+			ast.Inspect(node.Type, v.visitStrings)
+		}
+		for i, value := range node.Values {
+			lit, ok := value.(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING || i >= len(node.Names) {
+				ast.Inspect(value, v.visitStrings)
+				continue
+			}
+			switch {
+			case !v.consts:
+				v.stringDecl(node.Pos(), node.Names[i:i+1], "is a string")
+			case !types.IsHexString(lit.Value):
+				v.report(lit.Pos(), diag.String, "constant %s is a string that is not hex; only hex strings, which decode to byte arrays, are supported", node.Names[i].Name)
+			}
+		}
+		return false
+	case *ast.CallExpr:
+		if isIdent(node.Fun, "panic") {
+			for _, arg := range node.Args {
+				if lit, ok := arg.(*ast.BasicLit); !ok || lit.Kind != token.STRING {
+					ast.Inspect(arg, v.visitStrings)
+				}
+			}
+			return false
+		}
+		if sel, ok := node.Fun.(*ast.SelectorExpr); ok && stdStringArgs[sel.Sel.Name] && len(node.Args) > 0 {
+			if pkg, ok := sel.X.(*ast.Ident); ok && v.std[pkg.Name] {
+				if lit, ok := node.Args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+					for _, arg := range node.Args[1:] {
+						ast.Inspect(arg, v.visitStrings)
+					}
+					return false
+				}
+			}
+		}
+		// The name of the jet that jet.Custom calls
+		if sel, ok := node.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == jets.CustomGoName && len(node.Args) > 0 {
+			if pkg, ok := sel.X.(*ast.Ident); ok && v.jets[pkg.Name] {
+				if lit, ok := node.Args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+					for _, arg := range node.Args[1:] {
+						ast.Inspect(arg, v.visitStrings)
+					}
+					return false
+				}
+			}
+		}
+	case *ast.BasicLit:
+		if node.Kind == token.STRING {
+			v.report(node.Pos(), diag.String, "string literal %s is not supported: %s", node.Value, stringHint)
+		}
+	case *ast.Ident:
+		if node.Name == "string" {
+			v.report(node.Pos(), diag.String, "type string is not supported: %s", stringHint)
+		}
+	}
+	return true
+}
+
+// checkNames reports, once each, the identifiers of file that are not
+// ASCII: all of them when transliteration is off, and otherwise those with
+// a letter that has no ASCII spelling.
+func (v *goValidator) checkNames(file *ast.File) {
+	seen := make(map[string]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if _, ok := n.(*ast.ImportSpec); ok {
+			return false
+		}
+		ident, ok := n.(*ast.Ident)
+		if !ok || seen[ident.Name] {
+			return true
+		}
+		seen[ident.Name] = true
+		ascii, ok := transpiler.ASCIIName(ident.Name)
+		switch {
+		case !ok:
+			v.report(ident.Pos(), diag.NonASCIIName, "identifier %s has a letter with no ASCII spelling; SimplicityHL identifiers are ASCII", ident.Name)
+		case ascii != ident.Name && !v.transliterate:
+			v.report(ident.Pos(), diag.NonASCIIName, "identifier %s is not ASCII; rename it, e.g. to %s, or allow transliteration", ident.Name, ascii)
 		}
+		return true
+	})
+}
+
+// unsignedInts maps each Go integer type Simplicity has no equivalent of,
+// being signed or of the platform's width, to the unsigned type to declare.
+var unsignedInts = map[string]string{
+	"int": "uint64", "int8": "uint8", "int16": "uint16", "int32": "uint32", "int64": "uint64", "uint": "uint64",
+}
+
+// checkSignedInts warns of the integer types of unsignedInts in the type
+// positions and conversions of file. The contract compiles, but a value
+// that would be negative in Go is not one in the program.
+func (v *goValidator) checkSignedInts(file *ast.File) {
+	seen := make(map[*ast.Ident]bool)
+	check := func(expr ast.Expr) {
+		ast.Inspect(expr, func(n ast.Node) bool {
+			ident, ok := n.(*ast.Ident)
+			if !ok || ident.Obj != nil || seen[ident] {
+				return true
+			}
+			seen[ident] = true
+			if to, ok := unsignedInts[ident.Name]; ok {
+				v.problems = append(v.problems, Problem{Pos: ident.Pos(), Code: diag.SignedInt, Warning: true,
+					Message: fmt.Sprintf("%s has no Simplicity equivalent, whose integers are unsigned and of fixed width; declare %s, after checking that no value is negative", ident.Name, to)})
+			}
+			return true
+		})
 	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Field:
+			check(n.Type)
+		case *ast.ValueSpec:
+			if n.Type != nil {
+				check(n.Type)
+			}
+		case *ast.TypeSpec:
+			check(n.Type)
+			return false
+		case *ast.CompositeLit:
+			if n.Type != nil {
+				check(n.Type)
+			}
+		case *ast.CallExpr:
+			if ident, ok := n.Fun.(*ast.Ident); ok && len(n.Args) == 1 {
+				check(ident)
+			}
+		}
+		return true
+	})
+}
+
+// stringDecl reports a declaration of names as a string.
+func (v *goValidator) stringDecl(pos token.Pos, names []*ast.Ident, what string) {
+	desc := "unnamed field"
+	if len(names) > 0 {
+		parts := make([]string, len(names))
+		for i, name := range names {
+			parts[i] = name.Name
+		}
+		desc = strings.Join(parts, ", ")
+	}
+	v.report(pos, diag.String, "%s %s: %s", desc, what, stringHint)
+}
+
+func isIdent(expr ast.Expr, name string) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == name
 }
 
 // isBoundedForLoop checks if a for loop has compile-time bounds.
-// Accepted pattern: for i := 0; i < N; i++ where N is an integer literal.
+// Accepted pattern: for i := 0; i < N; i++ where N is an integer literal
+// or a named integer constant.
 func (v *goValidator) isBoundedForLoop(forStmt *ast.ForStmt) bool {
 	if forStmt.Init == nil || forStmt.Cond == nil || forStmt.Post == nil {
 		return false
@@ -160,6 +1132,38 @@ func (v *goValidator) isBoundedForLoop(forStmt *ast.ForStmt) bool {
 	return validLoopInit(forStmt.Init) && validLoopCond(forStmt.Cond) && validLoopPost(forStmt.Post)
 }
 
+// loopBoundProblem explains why forStmt, which isBoundedForLoop rejects,
+// has no constant bound.
+func loopBoundProblem(forStmt *ast.ForStmt) string {
+	switch {
+	case forStmt.Cond == nil:
+		return "it has no condition"
+	case forStmt.Init == nil:
+		return "it declares no counter; start it with i := 0"
+	case !validLoopInit(forStmt.Init):
+		return "its init statement does not start a counter at 0, as i := 0 does"
+	case !validLoopPost(forStmt.Post):
+		return "it does not step its counter by one with i++"
+	}
+	binary, ok := forStmt.Cond.(*ast.BinaryExpr)
+	if !ok || (binary.Op != token.LSS && binary.Op != token.LEQ) {
+		return fmt.Sprintf("its condition %s does not compare the counter with < or <=", gotypes.ExprString(forStmt.Cond))
+	}
+	if _, ok := binary.Y.(*ast.Ident); ok {
+		return fmt.Sprintf("its bound %s is a variable, not an integer constant such as 4 or N with const N = 4", binary.Y)
+	}
+	return fmt.Sprintf("its bound %s is not an integer literal or a named constant", gotypes.ExprString(binary.Y))
+}
+
+// sliceHint suggests the fixed-size array to declare in place of slice.
+func sliceHint(slice *ast.ArrayType) string {
+	elem := gotypes.ExprString(slice.Elt)
+	if elem == "byte" || elem == "uint8" {
+		return "declare a fixed-size array like [32]" + elem
+	}
+	return fmt.Sprintf("declare a fixed-size array like [4]%s, sized for the most elements the contract needs", elem)
+}
+
 // validLoopInit checks that the loop initialiser is: i := 0
 func validLoopInit(init ast.Stmt) bool {
 	assign, ok := init.(*ast.AssignStmt)
@@ -170,7 +1174,8 @@ func validLoopInit(init ast.Stmt) bool {
 	return ok && lit.Kind == token.INT && lit.Value == "0"
 }
 
-// validLoopCond checks that the loop condition is: i < N or i <= N where N is a literal.
+// validLoopCond checks that the loop condition is: i < N or i <= N where N
+// is a literal or a named constant.
 func validLoopCond(cond ast.Expr) bool {
 	binary, ok := cond.(*ast.BinaryExpr)
 	if !ok {
@@ -179,7 +1184,7 @@ func validLoopCond(cond ast.Expr) bool {
 	if binary.Op != token.LSS && binary.Op != token.LEQ {
 		return false
 	}
-	_, ok = binary.Y.(*ast.BasicLit)
+	_, ok = transpiler.LoopBound(binary.Y)
 	return ok
 }
 
@@ -0,0 +1,211 @@
+package compiler
+
+import (
+	"go/ast"
+	"go/token"
+	gotypes "go/types"
+	"strconv"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/transpiler"
+)
+
+// confidentialJet is a jet whose explicit value is read from an Option of a
+// confidential type. The transpiler unwraps both, so a blinded amount fails
+// the spend exactly as a missing input or output does.
+type confidentialJet struct {
+	what   string // "output amount"
+	helper string // std function that makes the assertion explicit
+}
+
+var confidentialJets = map[string]confidentialJet{
+	"OutputAmount": {"output amount", "ExplicitOutputValue"},
+	"InputAmount":  {"input amount", "ExplicitInputValue"},
+}
+
+// confidentialSource is a call of a confidentialJet and the first place its
+// value, or one computed from it, is compared.
+type confidentialSource struct {
+	call     *ast.CallExpr
+	jet      confidentialJet
+	compared token.Pos
+}
+
+// confidentialAnalysis follows the values of the confidentialJets through
+// the locals of each function to the comparisons that consume them.
+type confidentialAnalysis struct {
+	fset    *token.FileSet
+	jets    map[string]bool // Local names of the jet package
+	std     map[string]bool // Local names of the std package
+	sources []*confidentialSource
+	locals  map[string][]*confidentialSource // Sources each local is computed from
+}
+
+// confidentialWarnings reports each call of a confidentialJet whose value
+// is compared, since the comparison silently assumes an explicit amount. A
+// value that is only passed on, or never used, is not reported.
+func confidentialWarnings(fset *token.FileSet, file *ast.File) []string {
+	a := &confidentialAnalysis{
+		fset: fset,
+		jets: transpiler.JetPackageNames(file),
+		std:  stdPackageNames(file),
+	}
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+			a.locals = make(map[string][]*confidentialSource)
+			ast.Inspect(fn.Body, a.visit)
+		}
+	}
+
+	var warnings []string
+	for _, src := range a.sources {
+		if !src.compared.IsValid() {
+			continue
+		}
+		call := gotypes.ExprString(src.call)
+		index := "i"
+		if len(src.call.Args) == 1 {
+			index = gotypes.ExprString(src.call.Args[0])
+		}
+		warnings = append(warnings, diag.ConfidentialCompare.Sprintf("%s: %s is compared at %s, but the %s may be confidential, which fails the spend as a missing value would; use std.%s(%s), which asserts explicitness",
+			fset.Position(src.call.Pos()), call, fset.Position(src.compared), src.jet.what, src.jet.helper, index))
+	}
+	return warnings
+}
+
+// stdPackageNames returns the names under which file imports the std
+// package.
+func stdPackageNames(file *ast.File) map[string]bool {
+	return packageNames(file, transpiler.StdImportPath)
+}
+
+// packageNames returns the names under which file imports the package of
+// the import path want.
+func packageNames(file *ast.File, want string) map[string]bool {
+	names := make(map[string]bool)
+	for _, spec := range file.Imports {
+		if path, err := strconv.Unquote(spec.Path.Value); err == nil && path == want {
+			name := path[strings.LastIndex(path, "/")+1:]
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			names[name] = true
+		}
+	}
+	return names
+}
+
+func (a *confidentialAnalysis) visit(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.AssignStmt:
+		if len(n.Lhs) != len(n.Rhs) {
+			return true
+		}
+		for i, lhs := range n.Lhs {
+			if ident, ok := lhs.(*ast.Ident); ok && ident.Name != "_" {
+				from := a.sourcesOf(n.Rhs[i])
+				if n.Tok != token.DEFINE && n.Tok != token.ASSIGN {
+					from = append(from, a.locals[ident.Name]...) // x += y
+				}
+				a.locals[ident.Name] = from
+			}
+		}
+	case *ast.ValueSpec:
+		for i, name := range n.Names {
+			if i < len(n.Values) {
+				a.locals[name.Name] = a.sourcesOf(n.Values[i])
+			} else {
+				delete(a.locals, name.Name)
+			}
+		}
+	case *ast.BinaryExpr:
+		switch n.Op {
+		case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+			a.compare(n.OpPos, n.X, n.Y)
+		}
+	case *ast.CallExpr:
+		if a.comparison(n) {
+			a.compare(n.Pos(), n.Args...)
+		}
+	}
+	return true
+}
+
+// comparison reports whether call compares its arguments: a jet such as
+// Le64 or Eq128, or a std helper that asserts an amount.
+func (a *confidentialAnalysis) comparison(call *ast.CallExpr) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	name := sel.Sel.Name
+	switch {
+	case a.jets[pkg.Name]:
+		return strings.HasPrefix(name, "Eq") || strings.HasPrefix(name, "Le") || strings.HasPrefix(name, "Lt") || name == "FeeAdjustedLe128"
+	case a.std[pkg.Name]:
+		return name == "RequireOutput" || name == "OutputValueAtLeast" || name == "FeeAtMost"
+	}
+	return false
+}
+
+// compare records the first comparison of the sources of operands.
+func (a *confidentialAnalysis) compare(pos token.Pos, operands ...ast.Expr) {
+	for _, operand := range operands {
+		for _, src := range a.sourcesOf(operand) {
+			if !src.compared.IsValid() {
+				src.compared = pos
+			}
+		}
+	}
+}
+
+// sourcesOf returns the calls of confidentialJets that expr is computed
+// from, directly or through locals. A call met for the first time becomes a
+// source.
+func (a *confidentialAnalysis) sourcesOf(expr ast.Expr) []*confidentialSource {
+	var from []*confidentialSource
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Ident:
+			from = append(from, a.locals[n.Name]...)
+		case *ast.CallExpr:
+			if src := a.source(n); src != nil {
+				from = append(from, src)
+				return false
+			}
+		case *ast.FuncLit:
+			return false
+		}
+		return true
+	})
+	return from
+}
+
+// source returns the source of call when it calls a confidentialJet.
+func (a *confidentialAnalysis) source(call *ast.CallExpr) *confidentialSource {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return nil
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok || !a.jets[pkg.Name] {
+		return nil
+	}
+	jet, ok := confidentialJets[sel.Sel.Name]
+	if !ok {
+		return nil
+	}
+	for _, src := range a.sources {
+		if src.call == call {
+			return src
+		}
+	}
+	src := &confidentialSource{call: call, jet: jet}
+	a.sources = append(a.sources, src)
+	return src
+}
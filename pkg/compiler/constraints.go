@@ -0,0 +1,138 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+)
+
+// platformTags are the build tags that describe the host a Go binary runs
+// on. A contract runs on no host, so constraints on them never decide
+// whether a file is part of the contract.
+var platformTags = map[string]bool{
+	"cgo": true, "unix": true, "gc": true, "gccgo": true,
+
+	"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true,
+	"hurd": true, "illumos": true, "ios": true, "js": true, "linux": true, "nacl": true,
+	"netbsd": true, "openbsd": true, "plan9": true, "solaris": true, "wasip1": true,
+	"windows": true, "zos": true,
+
+	"386": true, "amd64": true, "arm": true, "arm64": true, "loong64": true, "mips": true,
+	"mips64": true, "mips64le": true, "mipsle": true, "ppc64": true, "ppc64le": true,
+	"riscv64": true, "s390x": true, "wasm": true,
+}
+
+// isPlatformTag reports whether tag describes the host, including release
+// tags such as go1.22.
+func isPlatformTag(tag string) bool {
+	return platformTags[tag] || strings.HasPrefix(tag, "go1.")
+}
+
+// maxPlatformTags bounds the platform tags in one constraint whose
+// combinations are tried; a constraint with more is treated as satisfied.
+const maxPlatformTags = 10
+
+// BuildConstraint returns the //go:build (or legacy // +build) constraint of
+// a parsed file, or nil if it has none.
+func BuildConstraint(file *ast.File) (constraint.Expr, error) {
+	var exprs []constraint.Expr
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			break
+		}
+		for _, c := range group.List {
+			if !constraint.IsGoBuild(c.Text) && !constraint.IsPlusBuild(c.Text) {
+				continue
+			}
+			expr, err := constraint.Parse(c.Text)
+			if err != nil {
+				return nil, diag.BadBuildConstraint.Errorf("invalid build constraint %q: %w", c.Text, err)
+			}
+			if constraint.IsGoBuild(c.Text) {
+				return expr, nil
+			}
+			exprs = append(exprs, expr)
+		}
+	}
+	if len(exprs) == 0 {
+		return nil, nil
+	}
+	expr := exprs[0]
+	for _, e := range exprs[1:] {
+		expr = &constraint.AndExpr{X: expr, Y: e}
+	}
+	return expr, nil
+}
+
+// MatchBuildTags reports whether the Go source src belongs to a contract
+// built with tags. Only the build constraint comments are read. GOOS, GOARCH
+// and release tags are irrelevant to contracts, so a constraint holds if
+// some choice of them satisfies it: //go:build linux && oracle matches
+// exactly when tags contains oracle.
+func MatchBuildTags(src []byte, tags []string) (bool, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		return false, err
+	}
+	expr, err := BuildConstraint(file)
+	if err != nil {
+		return false, err
+	}
+	return expr == nil || matchConstraint(expr, tags), nil
+}
+
+// matchConstraint evaluates expr with tags set, trying every assignment of
+// the platform tags it mentions.
+func matchConstraint(expr constraint.Expr, tags []string) bool {
+	set := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		set[tag] = true
+	}
+	var platform []string
+	seen := make(map[string]bool)
+	expr.Eval(func(tag string) bool {
+		if isPlatformTag(tag) && !set[tag] && !seen[tag] {
+			seen[tag] = true
+			platform = append(platform, tag)
+		}
+		return false
+	})
+	if len(platform) > maxPlatformTags {
+		return true
+	}
+	for mask := 0; mask < 1<<len(platform); mask++ {
+		chosen := make(map[string]bool, len(platform))
+		for i, tag := range platform {
+			chosen[tag] = mask&(1<<i) != 0
+		}
+		if expr.Eval(func(tag string) bool { return set[tag] || chosen[tag] }) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkBuildConstraint warns when the file being compiled excludes itself
+// under Config.BuildTags. Naming a file compiles it regardless, as go run
+// does; the ignore tag that keeps standalone files such as the examples out
+// of go build is expected there and does not warn.
+func (c *Compiler) checkBuildConstraint(file *ast.File, filename string) error {
+	expr, err := BuildConstraint(file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	if expr == nil || matchConstraint(expr, append([]string{"ignore"}, c.config.BuildTags...)) {
+		return nil
+	}
+	tags := "no tags"
+	if len(c.config.BuildTags) > 0 {
+		tags = "tags " + strings.Join(c.config.BuildTags, ",")
+	}
+	c.warnings = append(c.warnings, diag.ExcludedFile.Sprintf("%s: //go:build %s excludes this file with %s; compiling it anyway", filename, expr, tags))
+	return nil
+}
@@ -0,0 +1,94 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	gotypes "go/types"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+)
+
+// checkDiscardedValues reports each call that is a statement of its own
+// and returns a value, which the statement discards, as in
+//
+//	Validate(amount)
+//
+// where the bool of a check is almost always meant to decide the spend. The
+// result types are those of the file's function declarations and of the
+// jet table. Calls that return nothing, std.Assert among them, are not
+// reported, nor are std helpers, which the transpiler checks itself. Under
+// Strict the problem is an error rather than a warning.
+func (v *goValidator) checkDiscardedValues(file *ast.File) {
+	funcs := make(map[string]*ast.FuncDecl)
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+			funcs[fn.Name.Name] = fn
+		}
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		stmt, ok := n.(*ast.ExprStmt)
+		if !ok {
+			return true
+		}
+		call, ok := ast.Unparen(stmt.X).(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		results := v.callResults(funcs, call)
+		if len(results) == 0 {
+			return true
+		}
+		what := "the " + results[0] + " that " + gotypes.ExprString(call.Fun) + " returns is"
+		if len(results) > 1 {
+			what = "the values (" + strings.Join(results, ", ") + ") that " + gotypes.ExprString(call.Fun) + " returns are"
+		}
+		fix := fmt.Sprintf("bind it, as in result := %s, and use it", gotypes.ExprString(call))
+		if len(results) == 1 && results[0] == "bool" {
+			fix = fmt.Sprintf("write std.Assert(%s) to require it, or bind it and use it", gotypes.ExprString(call))
+		} else if len(results) > 1 {
+			fix = "bind them and use them"
+		}
+		v.problems = append(v.problems, Problem{Pos: call.Pos(), Code: diag.DiscardedValue, Warning: !v.strict,
+			Message: fmt.Sprintf("%s discarded; %s", what, fix)})
+		return true
+	})
+}
+
+// callResults returns the types of the values call returns, as Go writes
+// them, or nil for a call of anything but one of funcs or a jet, or one
+// that returns nothing.
+func (v *goValidator) callResults(funcs map[string]*ast.FuncDecl, call *ast.CallExpr) []string {
+	fun := call.Fun
+	switch f := fun.(type) {
+	case *ast.IndexExpr: // An instantiation of a generic helper
+		fun = f.X
+	case *ast.IndexListExpr:
+		fun = f.X
+	}
+	switch f := fun.(type) {
+	case *ast.Ident:
+		decl, ok := funcs[f.Name]
+		if !ok || decl.Type.Results == nil {
+			return nil
+		}
+		var results []string
+		for _, field := range decl.Type.Results.List {
+			for range max(len(field.Names), 1) {
+				results = append(results, gotypes.ExprString(field.Type))
+			}
+		}
+		return results
+	case *ast.SelectorExpr:
+		pkg, ok := f.X.(*ast.Ident)
+		if !ok || !v.jets[pkg.Name] || v.registry == nil {
+			return nil
+		}
+		info, ok := v.registry.Lookup(f.Sel.Name)
+		if !ok || info.ReturnType == "" || info.ReturnType == "()" {
+			return nil
+		}
+		return []string{info.ReturnType}
+	}
+	return nil
+}
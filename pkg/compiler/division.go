@@ -0,0 +1,316 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	gotypes "go/types"
+	"math/big"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/transpiler"
+)
+
+// truncationWarnings reports each integer division whose operands fold to
+// constants and leave a remainder, such as a fee of Amount * Rate / 10000,
+// with the remainder the quotient drops. Go rounds the quotient down,
+// which in a fee or a share may favour the wrong party; std.DivCeil rounds
+// it up. Package constants and the const blocks that derive them are
+// checked as well as function bodies.
+func truncationWarnings(fset *token.FileSet, file *ast.File) []string {
+	a := newFoldAnalysis(fset, file)
+	ast.Inspect(file, func(n ast.Node) bool {
+		div, ok := n.(*ast.BinaryExpr)
+		if !ok || div.Op != token.QUO {
+			return true
+		}
+		x, okX := a.constant(div.X)
+		y, okY := a.constant(div.Y)
+		if !okX || !okY || x.Int == nil || y.Int == nil || y.Int.Sign() == 0 {
+			return true
+		}
+		q, r := new(big.Int).QuoRem(x.Int, y.Int, new(big.Int))
+		if r.Sign() != 0 {
+			a.warn(div.OpPos, diag.TruncatingDivision, "%s truncates: %s / %s is %s with a remainder of %s, which is dropped; std.DivCeil rounds up instead",
+				gotypes.ExprString(div), x.Int, y.Int, q, r)
+		}
+		return true
+	})
+	return a.warnings
+}
+
+// valueKey names a value of a function, a parameter, witness or local, or
+// a field path below one such as w.Rate.
+type valueKey struct {
+	obj  *ast.Object
+	path string
+}
+
+// divisorAnalysis follows the divisors of a file back through the locals
+// they are copied into to the values they start from, and collects how
+// the file tests each value against constants.
+type divisorAnalysis struct {
+	*foldAnalysis
+	jets     map[string]bool              // Local names of the jet package
+	assigned map[*ast.Object][]ast.Expr   // Values assigned to each local; nil for an op-assignment
+	checked  map[valueKey]bool            // Tested in a way that tells zero apart
+	trivial  map[valueKey]ast.Expr        // Compared only with >= 0, which always holds
+	params   map[*ast.Object]paramOf      // Parameters of the file's functions
+	calls    map[*ast.Object][][]ast.Expr // Arguments of the calls of each function
+	errors   []string
+}
+
+// paramOf locates a parameter: its function and its index.
+type paramOf struct {
+	fn    *ast.Object
+	index int
+}
+
+// divisorChecks fails a division or remainder by a constant zero, which
+// the divide and modulo jets would quietly compute, and warns about each
+// divisor known only at spend time, a witness, a parameter or a value
+// derived from one, that the program never tests against zero. A test
+// anywhere in the function counts, such as rate > 0, rate != 0 or
+// !jet.IsZero64(rate); rate >= 0 always holds and does not. So does every
+// call of the helper the divisor is a parameter of passing a nonzero
+// constant. Under checked arithmetic there are no warnings, since the
+// compiled program asserts each such divisor is nonzero.
+func divisorChecks(fset *token.FileSet, file *ast.File, checked bool) ([]string, error) {
+	a := &divisorAnalysis{
+		foldAnalysis: newFoldAnalysis(fset, file),
+		jets:         transpiler.JetPackageNames(file),
+		assigned:     make(map[*ast.Object][]ast.Expr),
+		checked:      make(map[valueKey]bool),
+		trivial:      make(map[valueKey]ast.Expr),
+		params:       make(map[*ast.Object]paramOf),
+		calls:        make(map[*ast.Object][][]ast.Expr),
+	}
+	ast.Inspect(file, a.collect)
+	ast.Inspect(file, a.tests)
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.BinaryExpr:
+			if n.Op == token.QUO || n.Op == token.REM {
+				a.divisor(n.Op, n.Y, checked)
+			}
+		case *ast.AssignStmt:
+			if (n.Tok == token.QUO_ASSIGN || n.Tok == token.REM_ASSIGN) && len(n.Rhs) == 1 {
+				op := token.QUO
+				if n.Tok == token.REM_ASSIGN {
+					op = token.REM
+				}
+				a.divisor(op, n.Rhs[0], checked)
+			}
+		}
+		return true
+	})
+	if len(a.errors) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(a.errors, "\n"))
+	}
+	return a.warnings, nil
+}
+
+// collect records the assignments, parameters and calls of the file.
+func (a *divisorAnalysis) collect(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.FuncDecl:
+		if n.Recv == nil && n.Name.Obj != nil {
+			i := 0
+			for _, field := range n.Type.Params.List {
+				for _, name := range field.Names {
+					if name.Obj != nil {
+						a.params[name.Obj] = paramOf{n.Name.Obj, i}
+					}
+					i++
+				}
+			}
+		}
+	case *ast.AssignStmt:
+		for i, lhs := range n.Lhs {
+			ident, ok := lhs.(*ast.Ident)
+			if !ok || ident.Obj == nil {
+				continue
+			}
+			var value ast.Expr
+			if n.Tok == token.DEFINE || n.Tok == token.ASSIGN {
+				if len(n.Rhs) != len(n.Lhs) {
+					continue
+				}
+				value = n.Rhs[i]
+			}
+			a.assigned[ident.Obj] = append(a.assigned[ident.Obj], value)
+		}
+	case *ast.IncDecStmt:
+		if ident, ok := n.X.(*ast.Ident); ok && ident.Obj != nil {
+			a.assigned[ident.Obj] = append(a.assigned[ident.Obj], nil)
+		}
+	case *ast.CallExpr:
+		if ident, ok := n.Fun.(*ast.Ident); ok && ident.Obj != nil {
+			if _, isFunc := ident.Obj.Decl.(*ast.FuncDecl); isFunc {
+				a.calls[ident.Obj] = append(a.calls[ident.Obj], n.Args)
+			}
+		}
+	}
+	return true
+}
+
+// tests records the comparisons of values with constants in the file, in
+// operators and comparison jets. It runs after collect, so that every
+// local a value is copied into is known.
+func (a *divisorAnalysis) tests(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.BinaryExpr:
+		a.compare(n.Op, n.X, n.Y, n)
+	case *ast.CallExpr:
+		jet := a.jetName(n)
+		switch {
+		case strings.HasPrefix(jet, "IsZero") && len(n.Args) == 1:
+			if v, ok := a.value(n.Args[0]); ok {
+				a.checked[v] = true
+			}
+		case len(n.Args) != 2:
+		case strings.HasPrefix(jet, "Lt"):
+			a.compare(token.LSS, n.Args[0], n.Args[1], n)
+		case strings.HasPrefix(jet, "Le"):
+			a.compare(token.LEQ, n.Args[0], n.Args[1], n)
+		case strings.HasPrefix(jet, "Eq"):
+			a.compare(token.EQL, n.Args[0], n.Args[1], n)
+		}
+	}
+	return true
+}
+
+// jetName returns the name of the jet call calls, or "".
+func (a *divisorAnalysis) jetName(call *ast.CallExpr) string {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	if pkg, ok := sel.X.(*ast.Ident); ok && a.jets[pkg.Name] {
+		return sel.Sel.Name
+	}
+	return ""
+}
+
+// compare records the test x op y where one side is a value and the other
+// a constant.
+func (a *divisorAnalysis) compare(op token.Token, x, y ast.Expr, test ast.Expr) {
+	flipped := map[token.Token]token.Token{token.LSS: token.GTR, token.LEQ: token.GEQ, token.GTR: token.LSS, token.GEQ: token.LEQ, token.EQL: token.EQL, token.NEQ: token.NEQ}
+	if _, ok := flipped[op]; !ok {
+		return
+	}
+	v, ok := a.value(x)
+	c, isConst := a.constant(y)
+	if !ok || !isConst {
+		if v, ok = a.value(y); !ok {
+			return
+		}
+		if c, isConst = a.constant(x); !isConst {
+			return
+		}
+		op = flipped[op]
+	}
+	if c.Int == nil {
+		return
+	}
+	switch zero := c.Int.Sign() == 0; {
+	case op == token.GEQ && zero:
+		// v >= 0 always holds.
+		if _, seen := a.trivial[v]; !seen {
+			a.trivial[v] = test
+		}
+	case op == token.LEQ || op == token.LSS:
+		// v <= c and v < c contain zero whatever c is.
+	default:
+		a.checked[v] = true
+	}
+}
+
+// value returns the key of expr when it names a variable of a function or
+// a field path below one, following locals assigned exactly once from
+// another value.
+func (a *divisorAnalysis) value(expr ast.Expr) (valueKey, bool) {
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		if e.Obj == nil || e.Obj.Kind != ast.Var {
+			return valueKey{}, false
+		}
+		if values := a.assigned[e.Obj]; len(values) == 1 && values[0] != nil {
+			if _, isDefine := e.Obj.Decl.(*ast.AssignStmt); isDefine {
+				if v, ok := a.value(values[0]); ok && v.obj != e.Obj {
+					return v, true
+				}
+			}
+		}
+		return valueKey{obj: e.Obj}, true
+	case *ast.SelectorExpr:
+		v, ok := a.value(e.X)
+		v.path += "." + e.Sel.Name
+		return v, ok
+	}
+	return valueKey{}, false
+}
+
+// divisor checks the divisor of a division or remainder with op.
+func (a *divisorAnalysis) divisor(op token.Token, divisor ast.Expr, checked bool) {
+	if c, ok := a.constant(divisor); ok {
+		if c.Int != nil && c.Int.Sign() == 0 {
+			a.errors = append(a.errors, diag.DivisionByZero.Sprintf("%s: %s divides by zero", a.fset.Position(divisor.Pos()), gotypes.ExprString(divisor)))
+		}
+		return
+	}
+	v, ok := a.value(divisor)
+	if checked || !ok || a.checked[v] || a.constantLocal(v) || a.nonzeroArgument(v) {
+		return
+	}
+	name := gotypes.ExprString(divisor)
+	result := "0"
+	if op == token.REM {
+		result = "the dividend"
+	}
+	if test, ok := a.trivial[v]; ok {
+		a.warn(divisor.Pos(), diag.DivisorMayBeZero, "%s may be zero at spend time: %s always holds for an unsigned integer, so it does not rule zero out; require %s > 0 before dividing, or compile with -checked-arithmetic",
+			name, gotypes.ExprString(test), name)
+		return
+	}
+	a.warn(divisor.Pos(), diag.DivisorMayBeZero, "%s may be zero at spend time, and the %s jet then returns %s where Go would panic; require %s > 0 before dividing, or compile with -checked-arithmetic",
+		name, map[token.Token]string{token.QUO: "divide", token.REM: "modulo"}[op], result, name)
+}
+
+// constantLocal reports whether v is a local whose every assignment is a
+// nonzero constant.
+func (a *divisorAnalysis) constantLocal(v valueKey) bool {
+	if _, isDefine := v.obj.Decl.(*ast.AssignStmt); !isDefine || v.path != "" {
+		return false
+	}
+	for _, value := range a.assigned[v.obj] {
+		if value == nil {
+			return false
+		}
+		c, ok := a.constant(value)
+		if !ok || c.Int == nil || c.Int.Sign() == 0 {
+			return false
+		}
+	}
+	return len(a.assigned[v.obj]) > 0
+}
+
+// nonzeroArgument reports whether v is a parameter of a function that the
+// file calls, and every call passes a nonzero constant for it.
+func (a *divisorAnalysis) nonzeroArgument(v valueKey) bool {
+	p, ok := a.params[v.obj]
+	if !ok || v.path != "" || len(a.calls[p.fn]) == 0 {
+		return false
+	}
+	for _, args := range a.calls[p.fn] {
+		if p.index >= len(args) {
+			return false
+		}
+		c, ok := a.constant(args[p.index])
+		if !ok || c.Int == nil || c.Int.Sign() == 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,366 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	gotypes "go/types"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/jets"
+)
+
+// exprFile is the name positions within the expression of CompileExpr
+// are reported under, as in expr:1:5.
+const exprFile = "expr"
+
+// exprFunc names the function CompileExpr wraps the expression in, one
+// that SimplicityHL spells the same.
+const exprFunc = "simgoexpr"
+
+// exprPrefix precedes the expression on its line of the wrapper, so that
+// its columns are those of the wrapper less len(exprPrefix).
+const exprPrefix = "\treturn "
+
+// Expression is an expression compiled on its own, as simgo expr does.
+type Expression struct {
+	Code     string      // SimplicityHL that computes the expression: an expression, or let bindings ending in one
+	Params   []ExprParam // The variables of the expression, in order of first use
+	Result   string      // Go type of the expression
+	Warnings []string
+}
+
+// ExprParam is a variable of an expression and the Go type it was given.
+type ExprParam struct {
+	Name    string
+	Type    string
+	Guessed bool // The type was guessed from how the expression uses it
+}
+
+// CompileExpr compiles the Go expression expr, such as
+// "amount >= 1000 && sigValid", to SimplicityHL. The expression becomes
+// the body of a library function whose parameters are its variables,
+// typed from vars, which maps a variable name to a Go type, or else
+// guessed from how the expression uses them: bool as an operand of && or
+// !, the parameter type of a jet argument, that of the other operand of a
+// comparison or arithmetic, and uint32 otherwise. The function's body is
+// returned. Errors within the expression are positioned in it, as in
+// expr:1:5.
+//
+// CompileExpr uses config as Compile would, in library mode.
+func CompileExpr(config Config, expr string, vars map[string]string) (*Expression, error) {
+	fset := token.NewFileSet()
+	x, err := parser.ParseExprFrom(fset, exprFile, expr, 0)
+	if err != nil {
+		return nil, diag.SyntaxError.Errorf("failed to parse expression: %w", err)
+	}
+	for name, typ := range vars {
+		if !token.IsIdentifier(name) {
+			return nil, diag.InvalidConfig.Errorf("variable %q is not a Go identifier", name)
+		}
+		if _, err := parser.ParseExpr(typ); err != nil {
+			return nil, diag.InvalidConfig.Errorf("type %q of %s is not a Go type", typ, name)
+		}
+	}
+
+	g := &exprGuesser{given: vars, types: make(map[string]string), jets: jets.NewRegistry()}
+	result := g.guess(x, "")
+	if result == "" {
+		result = "bool"
+	}
+	e := &Expression{Result: result}
+	for _, name := range g.order {
+		p := ExprParam{Name: name, Type: vars[name]}
+		if p.Type == "" {
+			p.Type, p.Guessed = g.types[name], true
+		}
+		if p.Type == "" {
+			p.Type = "uint32"
+		}
+		e.Params = append(e.Params, p)
+	}
+
+	var src strings.Builder
+	src.WriteString("package main\n\nimport (\n\t\"simplicity/jet\"\n")
+	if g.std {
+		src.WriteString("\t\"github.com/0ceanslim/go-simplicity/std\"\n")
+	}
+	src.WriteString(")\n\nfunc " + exprFunc + "(")
+	for i, p := range e.Params {
+		if i > 0 {
+			src.WriteString(", ")
+		}
+		src.WriteString(p.Name + " " + p.Type)
+	}
+	src.WriteString(") " + result + " {\n")
+	line := strings.Count(src.String(), "\n") + 1
+	src.WriteString(exprPrefix + expr + "\n}\n")
+
+	config.Mode, config.Entry, config.WitnessValues = "library", "", nil
+	c := New(config)
+	code, err := c.Compile(src.String(), exprFile)
+	if err != nil {
+		return nil, exprError(err, line, strings.Count(expr, "\n")+1)
+	}
+	e.Warnings = c.Warnings()
+	if e.Code, err = functionBody(code, exprFunc); err != nil {
+		return nil, err
+	}
+	if (e.Code == "true" || e.Code == "false") && len(e.Params) > 0 {
+		names := make([]string, len(e.Params))
+		for i, p := range e.Params {
+			names[i] = p.Name
+		}
+		e.Warnings = append(e.Warnings, fmt.Sprintf("%s: the expression reads %s but lowered to the constant %s", exprFile, strings.Join(names, ", "), e.Code))
+	}
+	return e, nil
+}
+
+// exprPos matches a position in the wrapper of CompileExpr.
+var exprPos = regexp.MustCompile(regexp.QuoteMeta(exprFile) + `:(\d+):(\d+)`)
+
+// exprError moves the positions of err, an error compiling the wrapper of
+// an expression that takes lines lines from line, into the expression. A
+// position in the wrapper itself, such as a parameter of an unsupported
+// type, is reported as the expression's.
+func exprError(err error, line, lines int) error {
+	msg := exprPos.ReplaceAllStringFunc(err.Error(), func(pos string) string {
+		m := exprPos.FindStringSubmatch(pos)
+		l, _ := strconv.Atoi(m[1])
+		col, _ := strconv.Atoi(m[2])
+		switch {
+		case l < line || l >= line+lines:
+			return exprFile
+		case l == line:
+			col -= len(exprPrefix)
+		}
+		return fmt.Sprintf("%s:%d:%d", exprFile, l-line+1, col)
+	})
+	if code, ok := diag.CodeOf(err); ok {
+		return code.Errorf("%s", strings.TrimSuffix(msg, " ["+string(code)+"]"))
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+// functionBody returns the body of the function name in code, the output
+// of a library compile, without the indentation of the function.
+func functionBody(code, name string) (string, error) {
+	lines := strings.Split(code, "\n")
+	start := -1
+	for i, l := range lines {
+		if strings.HasPrefix(l, "fn "+name+"(") {
+			start = i + 1
+			break
+		}
+	}
+	if start < 0 {
+		return "", fmt.Errorf("generated library has no function %s", name)
+	}
+	var body []string
+	for _, l := range lines[start:] {
+		if l == "}" {
+			break
+		}
+		body = append(body, l)
+	}
+	indent := -1
+	for _, l := range body {
+		if t := strings.TrimLeft(l, " \t"); t != "" && (indent < 0 || len(l)-len(t) < indent) {
+			indent = len(l) - len(t)
+		}
+	}
+	for i, l := range body {
+		if len(l) >= indent && indent > 0 {
+			body[i] = l[indent:]
+		}
+	}
+	return strings.TrimSpace(strings.Join(body, "\n")), nil
+}
+
+// exprGuesser types the variables of an expression from their use.
+type exprGuesser struct {
+	given map[string]string // Types given for variables
+	types map[string]string // Types guessed for the others
+	order []string          // Variables in order of first use
+	seen  map[string]bool
+	std   bool // The expression calls std
+	jets  *jets.JetRegistry
+}
+
+// guess records the type of each variable in e, where want is the type
+// the context of e expects, or "", and returns the type of e, or "" when
+// it is untyped or unknown.
+func (g *exprGuesser) guess(e ast.Expr, want string) string {
+	switch e := e.(type) {
+	case *ast.Ident:
+		if gotypes.Universe.Lookup(e.Name) != nil {
+			if e.Name == "true" || e.Name == "false" {
+				return "bool"
+			}
+			return ""
+		}
+		g.use(e.Name)
+		if typ := g.typeOf(e.Name); typ != "" {
+			return typ
+		}
+		if want != "" {
+			g.types[e.Name] = want
+		}
+		return want
+	case *ast.ParenExpr:
+		return g.guess(e.X, want)
+	case *ast.UnaryExpr:
+		if e.Op == token.NOT {
+			g.guess(e.X, "bool")
+			return "bool"
+		}
+		return g.guess(e.X, want)
+	case *ast.BinaryExpr:
+		switch e.Op {
+		case token.LAND, token.LOR:
+			g.guess(e.X, "bool")
+			g.guess(e.Y, "bool")
+			return "bool"
+		case token.SHL, token.SHR:
+			g.guess(e.Y, "uint32")
+			return g.guess(e.X, want)
+		}
+		operand := g.known(e.X)
+		if operand == "" {
+			operand = g.known(e.Y)
+		}
+		comparison := e.Op == token.EQL || e.Op == token.NEQ || e.Op == token.LSS || e.Op == token.LEQ || e.Op == token.GTR || e.Op == token.GEQ
+		if operand == "" && !comparison {
+			operand = want
+		}
+		if operand == "" {
+			operand = "uint32"
+		}
+		g.guess(e.X, operand)
+		g.guess(e.Y, operand)
+		if comparison {
+			return "bool"
+		}
+		return operand
+	case *ast.CallExpr:
+		return g.guessCall(e)
+	case *ast.IndexExpr:
+		g.guess(e.Index, "uint32")
+		array := g.guess(e.X, "[32]byte")
+		if strings.HasPrefix(array, "[") {
+			return array[strings.Index(array, "]")+1:]
+		}
+	case *ast.SelectorExpr:
+		g.guess(e.X, "")
+	case *ast.CompositeLit:
+		for _, elt := range e.Elts {
+			g.guess(elt, "")
+		}
+	}
+	return ""
+}
+
+// guessCall is guess for a call: of a jet, whose arguments have the types
+// of its parameters; of len; a conversion; or a function of std.
+func (g *exprGuesser) guessCall(call *ast.CallExpr) string {
+	if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+		if pkg, ok := sel.X.(*ast.Ident); ok && (pkg.Name == "jet" || pkg.Name == "std") {
+			g.std = g.std || pkg.Name == "std"
+			info, isJet := g.jets.Lookup(sel.Sel.Name)
+			for i, arg := range call.Args {
+				want := ""
+				if isJet && pkg.Name == "jet" && i < len(info.ParamTypes) {
+					want = goType(info.ParamTypes[i])
+				}
+				g.guess(arg, want)
+			}
+			if isJet && pkg.Name == "jet" {
+				return goType(info.ReturnType)
+			}
+			return ""
+		}
+	}
+	if ident, ok := call.Fun.(*ast.Ident); ok && ident.Name == "len" && len(call.Args) == 1 {
+		g.guess(call.Args[0], "[32]byte")
+		return "uint32"
+	}
+	if ident, ok := call.Fun.(*ast.Ident); ok && len(call.Args) == 1 {
+		if _, isType := gotypes.Universe.Lookup(ident.Name).(*gotypes.TypeName); isType {
+			g.guess(call.Args[0], ident.Name)
+			return ident.Name
+		}
+	}
+	for _, arg := range call.Args {
+		g.guess(arg, "")
+	}
+	return ""
+}
+
+// known returns the type of e if it is a variable with a type already.
+func (g *exprGuesser) known(e ast.Expr) string {
+	for {
+		paren, ok := e.(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		e = paren.X
+	}
+	switch e := e.(type) {
+	case *ast.Ident:
+		return g.typeOf(e.Name)
+	case *ast.CallExpr:
+		// A conversion, as in uint64(n), or a jet call, whose result the
+		// jet registry gives.
+		if ident, ok := e.Fun.(*ast.Ident); ok {
+			if _, ok := gotypes.Universe.Lookup(ident.Name).(*gotypes.TypeName); ok {
+				return ident.Name
+			}
+		}
+		if sel, ok := e.Fun.(*ast.SelectorExpr); ok {
+			if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Name == "jet" {
+				if info, ok := g.jets.Lookup(sel.Sel.Name); ok {
+					return goType(info.ReturnType)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func (g *exprGuesser) typeOf(name string) string {
+	if typ, ok := g.given[name]; ok {
+		return typ
+	}
+	return g.types[name]
+}
+
+func (g *exprGuesser) use(name string) {
+	if g.seen == nil {
+		g.seen = make(map[string]bool)
+	}
+	if !g.seen[name] {
+		g.seen[name] = true
+		g.order = append(g.order, name)
+	}
+}
+
+// goType returns the Go type a contract uses for a SimplicityHL type of
+// the jet registry, or "" when there is none.
+func goType(shl string) string {
+	switch shl {
+	case "bool":
+		return "bool"
+	case "u8", "u16", "u32", "u64":
+		return "uint" + shl[1:]
+	case "u256":
+		return "[32]byte"
+	}
+	if strings.HasPrefix(shl, "[u8; ") && strings.HasSuffix(shl, "]") {
+		return "[" + shl[len("[u8; "):len(shl)-1] + "]byte"
+	}
+	return ""
+}
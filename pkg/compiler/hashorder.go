@@ -0,0 +1,208 @@
+package compiler
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/types"
+)
+
+// hashOrderWarnings reports each bare hex digest, a hex integer or string
+// of 64 digits written as it is rather than passed to std.HashFromHexRaw
+// or std.HashFromHexDisplay, that the file gives a bitcoin.Hash: as the
+// value it is declared or assigned, converted to one, passed for a
+// parameter of that type or returned as such a result. Bitcoin tools
+// display txids and block hashes byte-reversed, so hex copied from one
+// names another hash than the program computes, and the spend never
+// matches. Constants are followed to their values; names are resolved by
+// the parser, so the file must keep its objects.
+func hashOrderWarnings(fset *token.FileSet, file *ast.File) []string {
+	bitcoin := packageNames(file, types.BitcoinImportPath)
+	if len(bitcoin) == 0 {
+		return nil
+	}
+	isHash := func(typ ast.Expr) bool {
+		sel, ok := typ.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != "Hash" {
+			return false
+		}
+		pkg, ok := sel.X.(*ast.Ident)
+		return ok && bitcoin[pkg.Name]
+	}
+	hashTyped := func(ident *ast.Ident) bool {
+		if ident.Obj == nil {
+			return false
+		}
+		switch decl := ident.Obj.Decl.(type) {
+		case *ast.ValueSpec:
+			return decl.Type != nil && isHash(decl.Type)
+		case *ast.Field:
+			return isHash(decl.Type)
+		}
+		return false
+	}
+	funcs := make(map[string]*ast.FuncDecl)
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+			funcs[fn.Name.Name] = fn
+		}
+	}
+
+	var warnings []string
+	check := func(value ast.Expr, target string) {
+		digits, what, ok := bareDigest(value)
+		if !ok {
+			return
+		}
+		warnings = append(warnings, diag.HashByteOrder.Sprintf("%s: %s is given to %s, a bitcoin.Hash, without its byte order: Bitcoin tools display txids and block hashes byte-reversed from the raw digest, the order SHA-256 produces and the jets compare, so hex copied from one never matches. Write std.HashFromHexRaw(%q) for a raw digest, or std.HashFromHexDisplay(%q) for a txid or block hash in display order",
+			fset.Position(value.Pos()), what, target, digits, digits))
+	}
+	for _, decl := range file.Decls {
+		var results []ast.Expr // Result types of the function decl declares
+		if fn, ok := decl.(*ast.FuncDecl); ok {
+			results = fieldTypes(fn.Type.Results)
+		}
+		ast.Inspect(decl, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncLit:
+				return false // Its returns are not decl's
+			case *ast.ValueSpec:
+				if n.Type != nil && isHash(n.Type) {
+					for i, value := range n.Values {
+						if i < len(n.Names) {
+							check(value, n.Names[i].Name)
+						}
+					}
+				}
+			case *ast.AssignStmt:
+				if n.Tok != token.ASSIGN || len(n.Lhs) != len(n.Rhs) {
+					return true
+				}
+				for i, lhs := range n.Lhs {
+					if ident, ok := lhs.(*ast.Ident); ok && hashTyped(ident) {
+						check(n.Rhs[i], ident.Name)
+					}
+				}
+			case *ast.CallExpr:
+				if isHash(n.Fun) && len(n.Args) == 1 {
+					check(n.Args[0], "the conversion")
+					return true
+				}
+				ident, ok := n.Fun.(*ast.Ident)
+				if !ok {
+					return true
+				}
+				fn, ok := funcs[ident.Name]
+				if !ok {
+					return true
+				}
+				names := fieldNames(fn.Type.Params)
+				for i, typ := range fieldTypes(fn.Type.Params) {
+					if i < len(n.Args) && isHash(typ) {
+						check(n.Args[i], "parameter "+names[i]+" of "+fn.Name.Name)
+					}
+				}
+			case *ast.ReturnStmt:
+				if len(n.Results) != len(results) {
+					return true
+				}
+				for i, typ := range results {
+					if isHash(typ) {
+						check(n.Results[i], "the result of "+decl.(*ast.FuncDecl).Name.Name)
+					}
+				}
+			}
+			return true
+		})
+	}
+	return warnings
+}
+
+// bareDigest returns the 64 hex digits of expr, and how it is written, when
+// it is a hex integer or string of exactly 64 digits, or a constant whose
+// value is one.
+func bareDigest(expr ast.Expr) (string, string, bool) {
+	return constantDigest(expr, 0)
+}
+
+// constantDigest is bareDigest for expr reached through depth constants
+// declared from one another.
+func constantDigest(expr ast.Expr, depth int) (string, string, bool) {
+	expr = ast.Unparen(expr)
+	if ident, ok := expr.(*ast.Ident); ok {
+		if ident.Obj == nil || ident.Obj.Kind != ast.Con || depth > maxConstDepth {
+			return "", "", false
+		}
+		spec, ok := ident.Obj.Decl.(*ast.ValueSpec)
+		if !ok {
+			return "", "", false
+		}
+		for i, name := range spec.Names {
+			if name.Name == ident.Name && i < len(spec.Values) {
+				digits, _, ok := constantDigest(spec.Values[i], depth+1)
+				return digits, "constant " + ident.Name, ok
+			}
+		}
+		return "", "", false
+	}
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok {
+		return "", "", false
+	}
+	s := lit.Value
+	switch lit.Kind {
+	case token.INT:
+		s = strings.ReplaceAll(s, "_", "")
+	case token.STRING:
+		var err error
+		if s, err = strconv.Unquote(s); err != nil {
+			return "", "", false
+		}
+	default:
+		return "", "", false
+	}
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if len(digits) != 64 || (lit.Kind == token.INT && digits == s) || strings.Trim(digits, "0123456789abcdefABCDEF") != "" {
+		return "", "", false
+	}
+	return digits, "the hex " + digits[:4] + "…" + digits[60:], true
+}
+
+// maxConstDepth bounds the constants bareDigest follows, which a file
+// that does not type-check may declare from one another in a cycle.
+const maxConstDepth = 16
+
+// fieldTypes returns the type of each name fields declares, or of each
+// field when it has none, such as an unnamed result.
+func fieldTypes(fields *ast.FieldList) []ast.Expr {
+	if fields == nil {
+		return nil
+	}
+	var typs []ast.Expr
+	for _, field := range fields.List {
+		for range max(len(field.Names), 1) {
+			typs = append(typs, field.Type)
+		}
+	}
+	return typs
+}
+
+// fieldNames is fieldTypes for the names, "_" for an unnamed field.
+func fieldNames(fields *ast.FieldList) []string {
+	if fields == nil {
+		return nil
+	}
+	var names []string
+	for _, field := range fields.List {
+		if len(field.Names) == 0 {
+			names = append(names, "_")
+		}
+		for _, name := range field.Names {
+			names = append(names, name.Name)
+		}
+	}
+	return names
+}
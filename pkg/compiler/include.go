@@ -0,0 +1,178 @@
+package compiler
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+	"github.com/0ceanslim/go-simplicity/pkg/transpiler"
+)
+
+// Include is a SimplicityHL module compiled before the program, typically
+// a library compiled once with Mode "library" and shared by several entry
+// programs. The program calls its functions without transpiling them
+// again: the Go source declares each one it calls as a stub, a function
+// declaration without a body, whose parameters and result must be those
+// of the included function.
+type Include struct {
+	Path   string // Names the module in errors and in the output
+	Source string
+}
+
+// module is a parsed Include.
+type module struct {
+	Include
+	prog *shlparse.Program
+	code string // Source without its generated-code header
+}
+
+// generatedHeader is the first line of a generated file, such as the one
+// simgo writes above a compiled library.
+var generatedHeader = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// parseIncludes parses the modules of Config.Includes. A module holds
+// functions and type aliases only: the main, witness module and param
+// module of a program cannot be shared with another.
+func parseIncludes(includes []Include) ([]module, error) {
+	modules := make([]module, 0, len(includes))
+	defined := make(map[string]string) // Function name → path of the module defining it
+	for _, inc := range includes {
+		prog, err := shlparse.Parse(inc.Source)
+		if err != nil {
+			return nil, diag.InvalidConfig.Errorf("include %s: %v", inc.Path, err)
+		}
+		if len(prog.Modules) > 0 {
+			return nil, diag.InvalidConfig.Errorf("include %s: declares mod %s; include a module compiled in library mode", inc.Path, prog.Modules[0].Name)
+		}
+		for _, fn := range prog.Funcs {
+			if fn.Name == "main" {
+				return nil, diag.InvalidConfig.Errorf("include %s: defines main; include a module compiled in library mode", inc.Path)
+			}
+			if prev, ok := defined[fn.Name]; ok {
+				return nil, diag.InvalidConfig.Errorf("include %s: fn %s is defined by %s as well", inc.Path, fn.Name, prev)
+			}
+			defined[fn.Name] = inc.Path
+		}
+		code := inc.Source
+		if first, rest, ok := strings.Cut(code, "\n"); ok && generatedHeader.MatchString(first) {
+			code = rest
+		}
+		modules = append(modules, module{Include: inc, prog: prog, code: strings.Trim(code, "\n")})
+	}
+	return modules, nil
+}
+
+// checkStubs checks the functions of the program against the included
+// modules: a stub must be defined by one of them, with the signature the
+// Go declares, and a function with a body must not be.
+func (c *Compiler) checkStubs(modules []module) error {
+	for _, fn := range c.transpiler.Functions() {
+		var def *shlparse.Func
+		var path string
+		for _, m := range modules {
+			if f := m.prog.Func(fn.Name); f != nil {
+				def, path = f, m.Path
+			}
+		}
+		pos := c.fset.Position(fn.Pos)
+		switch {
+		case def == nil && fn.Stub:
+			return diag.IncludeMismatch.Errorf("%s: %s has no body, and no included module defines fn %s", pos, fn.GoName, fn.Name)
+		case def == nil:
+			continue
+		case !fn.Stub:
+			return diag.IncludeMismatch.Errorf("%s: %s compiles to fn %s, which %s defines as well; declare it without a body to call the included one", pos, fn.GoName, fn.Name, path)
+		}
+		if got, want := stubSignature(fn), includedSignature(def); got != want {
+			return diag.IncludeMismatch.Errorf("%s: %s is declared as fn %s%s, but %s defines fn %s%s", pos, fn.GoName, fn.Name, got, path, def.Name, want)
+		}
+	}
+	return nil
+}
+
+// stubSignature renders the parameter and result types of fn, a stub, as
+// includedSignature renders those of a parsed function.
+func stubSignature(fn transpiler.Function) string {
+	params := make([]string, len(fn.Parameters))
+	for i, p := range fn.Parameters {
+		params[i] = p.Type
+		if t, err := shlparse.ParseType(p.Type); err == nil {
+			params[i] = shlparse.TypeString(t)
+		}
+	}
+	sig := "(" + strings.Join(params, ", ") + ")"
+	if fn.ReturnType == "" {
+		return sig
+	}
+	if t, err := shlparse.ParseType(fn.ReturnType); err == nil {
+		return sig + " -> " + shlparse.TypeString(t)
+	}
+	return sig + " -> " + fn.ReturnType
+}
+
+// includedSignature renders the parameter and result types of fn.
+func includedSignature(fn *shlparse.Func) string {
+	params := make([]string, len(fn.Params))
+	for i, p := range fn.Params {
+		params[i] = shlparse.TypeString(p.Type)
+	}
+	sig := "(" + strings.Join(params, ", ") + ")"
+	if fn.Result == nil {
+		return sig
+	}
+	return sig + " -> " + shlparse.TypeString(fn.Result)
+}
+
+// withIncludes inserts the modules into code, the compiled program, after
+// its witness and param modules: the text of each when directive is
+// empty, and otherwise the directive, a format with one %q verb, applied
+// to its path. It returns the program and, for each of its lines, the
+// 1-based line of code it comes from, 0 for a line of a module.
+func withIncludes(code string, modules []module, directive string) (string, []int) {
+	lines := strings.Split(code, "\n")
+	at, inModule := 0, false // Lines before at are the modules of the program
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "mod "):
+			inModule = true
+		case inModule && line == "}":
+			at, inModule = i+1, false
+		}
+	}
+
+	var included []string
+	for _, m := range modules {
+		if directive != "" {
+			included = append(included, fmt.Sprintf(directive, m.Path))
+			continue
+		}
+		included = append(included, "// Included from "+m.Path)
+		included = append(included, strings.Split(m.code, "\n")...)
+		included = append(included, "")
+	}
+	if directive != "" {
+		included = append(included, "")
+	}
+
+	out := make([]string, 0, len(lines)+len(included)+1)
+	origin := make([]int, 0, cap(out))
+	for i := 0; i < at; i++ {
+		out, origin = append(out, lines[i]), append(origin, i+1)
+	}
+	if at > 0 {
+		out, origin = append(out, ""), append(origin, 0)
+	}
+	for _, line := range included {
+		out, origin = append(out, line), append(origin, 0)
+	}
+	rest := lines[at:]
+	if at > 0 && len(rest) > 0 && rest[0] == "" {
+		rest, at = rest[1:], at+1 // The blank line after the modules is already there
+	}
+	for i, line := range rest {
+		out, origin = append(out, line), append(origin, at+i+1)
+	}
+	return strings.Join(out, "\n"), origin
+}
@@ -0,0 +1,28 @@
+package compiler
+
+import "fmt"
+
+// Version is the version of the compiler, reported with internal errors.
+const Version = "1.3.41"
+
+// InternalError reports a panic inside the compiler. It is a bug in
+// go-simplicity rather than a problem with the contract, so it carries what
+// a bug report needs: the phase that was running, the panic value and the
+// stack of the goroutine that panicked.
+type InternalError struct {
+	Phase   string // e.g. "analysis", "pre-transform 0"
+	Value   any    // The value passed to panic
+	Stack   []byte
+	Version string // Version of the compiler that panicked
+}
+
+func (e *InternalError) Error() string {
+	return fmt.Sprintf("panic during %s: %v", e.Phase, e.Value)
+}
+
+// Unwrap returns the panic value if it is an error, such as a runtime
+// error.
+func (e *InternalError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
@@ -0,0 +1,246 @@
+package compiler
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/transpiler"
+	"github.com/0ceanslim/go-simplicity/pkg/types"
+)
+
+// builtinPackages are the import paths of the compiler-provided packages,
+// which are not loaded from disk.
+var builtinPackages = map[string]bool{
+	transpiler.JetImportPath: true,
+	transpiler.StdImportPath: true,
+	types.BitcoinImportPath:  true,
+}
+
+// IsBuiltinImport reports whether path names a compiler-provided package,
+// which is not loaded from source.
+func IsBuiltinImport(path string) bool {
+	return builtinPackages[path]
+}
+
+// isCompilerNamespace reports whether path lies under simplicity/, where
+// only the compiler-provided packages exist.
+func isCompilerNamespace(path string) bool {
+	return path == "simplicity" || strings.HasPrefix(path, "simplicity/")
+}
+
+// loadImports locates the user packages imported by file, relative to the
+// directory of filename, and checks that each one is pure. Packages whose
+// types are registered with Config.TypeMapper are not loaded, nor are the
+// stub packages of Config.ExtraJets.
+func (c *Compiler) loadImports(ctx context.Context, file *ast.File, filename string) ([]transpiler.Import, error) {
+	var paths []string
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || IsBuiltinImport(path) {
+			continue
+		}
+		// A blank import binds no name, so nothing of it can be called.
+		if spec.Name != nil && spec.Name.Name == "_" {
+			continue
+		}
+		if c.config.TypeMapper != nil && c.config.TypeMapper.ProvidesPackage(path) {
+			continue
+		}
+		if slices.Contains(c.jets.Packages(), path) {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	dir := filepath.Dir(filename)
+	if cache := c.config.Packages; cache != nil {
+		var imports []transpiler.Import
+		for _, path := range paths {
+			imp, err := cache.load(ctx, dir, path, c.config.BuildTags)
+			if err != nil {
+				return nil, err
+			}
+			imports = append(imports, imp)
+		}
+		return imports, nil
+	}
+	return loadPackages(ctx, c.fset, dir, paths, c.config.BuildTags)
+}
+
+// loadPackages loads and checks the packages named by paths, resolved
+// relative to dir, parsing into fset the files selected by tags.
+func loadPackages(ctx context.Context, fset *token.FileSet, dir string, paths, tags []string) ([]transpiler.Import, error) {
+	cfg := &packages.Config{
+		Context: ctx,
+		Mode:    packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedImports,
+		Dir:     dir,
+		Fset:    fset,
+	}
+	if len(tags) > 0 {
+		cfg.BuildFlags = []string{"-tags=" + strings.Join(tags, ",")}
+	}
+	pkgs, err := packages.Load(cfg, paths...)
+	if err != nil {
+		return nil, diag.PackageLoad.Errorf("failed to load imported packages: %w", err)
+	}
+
+	var imports []transpiler.Import
+	for _, pkg := range pkgs {
+		if len(pkg.Syntax) == 0 {
+			var errs []string
+			for _, e := range pkg.Errors {
+				errs = append(errs, e.Msg)
+			}
+			return nil, diag.PackageLoad.Errorf("failed to load package %s: %s", pkg.PkgPath, strings.Join(errs, "; "))
+		}
+		if err := checkPurity(pkg); err != nil {
+			return nil, err
+		}
+		imports = append(imports, transpiler.Import{
+			Path:  pkg.PkgPath,
+			Name:  pkg.Name,
+			Files: pkg.Syntax,
+		})
+	}
+	return imports, nil
+}
+
+// PackageCache shares imported packages between compilers, so a batch of
+// contracts built on the same helper library loads and checks it once. It
+// is safe for concurrent use. Cached syntax trees are never modified, and
+// every compiler using the cache parses into its FileSet, so positions in
+// imported files resolve from any of them.
+type PackageCache struct {
+	fset *token.FileSet
+
+	mu       sync.Mutex
+	packages map[packageKey]*cachedPackage
+}
+
+// packageKey identifies an import path as resolved from one directory
+// with one set of build tags.
+type packageKey struct {
+	dir, path, tags string
+}
+
+// cachedPackage is a package loaded, or being loaded, by one compiler while
+// others wait on done.
+type cachedPackage struct {
+	done chan struct{}
+	imp  transpiler.Import
+	err  error
+}
+
+// NewPackageCache returns an empty package cache.
+func NewPackageCache() *PackageCache {
+	return &PackageCache{
+		fset:     token.NewFileSet(),
+		packages: make(map[packageKey]*cachedPackage),
+	}
+}
+
+// load returns the package path as imported from dir, loading it on first
+// use. A load cut short by ctx is not cached, so a later compile retries it.
+func (pc *PackageCache) load(ctx context.Context, dir, path string, tags []string) (transpiler.Import, error) {
+	key := packageKey{dir, path, strings.Join(tags, ",")}
+	pc.mu.Lock()
+	entry, ok := pc.packages[key]
+	if !ok {
+		entry = &cachedPackage{done: make(chan struct{})}
+		pc.packages[key] = entry
+	}
+	pc.mu.Unlock()
+
+	if ok {
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			return transpiler.Import{}, ctx.Err()
+		}
+		return entry.imp, entry.err
+	}
+
+	imports, err := loadPackages(ctx, pc.fset, dir, []string{path}, tags)
+	if err == nil {
+		entry.imp = imports[0]
+	}
+	entry.err = err
+	if ctx.Err() != nil {
+		pc.mu.Lock()
+		delete(pc.packages, key)
+		pc.mu.Unlock()
+	}
+	close(entry.done)
+	return entry.imp, entry.err
+}
+
+// Impurities returns what keeps the package of files, parsed into fset,
+// from being compiled into a contract: an imported package may only
+// declare constants, types, and functions, it may only import
+// compiler-provided packages, and every file must pass the same feature
+// validation as the contract itself. The problems of the first two kinds
+// have no code of their own: they are reported as diag.ImpurePackage.
+func Impurities(fset *token.FileSet, files []*ast.File) []Problem {
+	var problems []Problem
+	impure := func(pos token.Pos, format string, args ...any) {
+		problems = append(problems, Problem{Pos: pos, Message: fmt.Sprintf(format, args...)})
+	}
+	for _, file := range files {
+		for _, spec := range file.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			switch {
+			case !IsBuiltinImport(path):
+				impure(spec.Pos(), "imports %s", path)
+			case spec.Name != nil && spec.Name.Name == ".":
+				impure(spec.Pos(), "dot-imports %s", path)
+			}
+		}
+		for _, decl := range file.Decls {
+			switch d := decl.(type) {
+			case *ast.GenDecl:
+				if d.Tok == token.VAR {
+					impure(d.Pos(), "declares package-level variables")
+				}
+			case *ast.FuncDecl:
+				switch {
+				case d.Recv != nil:
+					impure(d.Pos(), "declares method %s", d.Name.Name)
+				case d.Name.Name == "init":
+					impure(d.Pos(), "declares an init function")
+				}
+			}
+		}
+
+		validator := &goValidator{fset: fset, jets: transpiler.JetPackageNames(file)}
+		ast.Inspect(file, validator.visit)
+		problems = append(problems, validator.problems...)
+	}
+	return problems
+}
+
+// checkPurity verifies that an imported package can be compiled into a
+// contract, as Impurities decides.
+func checkPurity(pkg *packages.Package) error {
+	var problems []string
+	for _, p := range Impurities(pkg.Fset, pkg.Syntax) {
+		problems = append(problems, p.Format(pkg.Fset))
+	}
+
+	if len(problems) > 0 {
+		return diag.ImpurePackage.Errorf("imported package %s is not pure:\n%s", pkg.PkgPath, strings.Join(problems, "\n"))
+	}
+	return nil
+}
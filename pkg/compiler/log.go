@@ -0,0 +1,81 @@
+package compiler
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// LogLevel selects how much a compile logs about itself, as simgo's -debug
+// and -trace flags do. Warnings about the contract are not log messages:
+// they are returned by Warnings at every level.
+type LogLevel int
+
+const (
+	// LogInfo, the default, logs nothing.
+	LogInfo LogLevel = iota
+	// LogDebug logs a summary of each phase: how long it took and, after
+	// transpilation, the functions, witnesses and constants it found.
+	LogDebug
+	// LogTrace adds the parsed Go AST, which runs to thousands of lines
+	// for any contract of size.
+	LogTrace
+)
+
+// String returns the lowercase name of the level.
+func (l LogLevel) String() string {
+	switch l {
+	case LogInfo:
+		return "info"
+	case LogDebug:
+		return "debug"
+	case LogTrace:
+		return "trace"
+	}
+	return "LogLevel(" + strconv.Itoa(int(l)) + ")"
+}
+
+// Logger receives the log messages of a compile, for embedders that route
+// them to their own logging. Messages are formatted as by fmt.Sprintf and
+// have no trailing newline. A Logger shared by compilers running at once
+// must be safe for concurrent use.
+type Logger interface {
+	Logf(level LogLevel, format string, args ...any)
+}
+
+// NewLogger returns a Logger that writes each message to w on a line of its
+// own, prefixed with its level, e.g. "debug: parsing took 1.2ms". Writes
+// are serialized, so the compilers of a batch may share it.
+func NewLogger(w io.Writer) Logger {
+	return &writerLogger{w: w}
+}
+
+type writerLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *writerLogger) Logf(level LogLevel, format string, args ...any) {
+	msg := strings.TrimSuffix(fmt.Sprintf(format, args...), "\n")
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintf(l.w, "%s: %s\n", level, msg)
+}
+
+// logLevel is the level of c, with the deprecated Debug option.
+func (c *Compiler) logLevel() LogLevel {
+	if c.config.Debug && c.config.LogLevel < LogDebug {
+		return LogDebug
+	}
+	return c.config.LogLevel
+}
+
+// logf logs a message at level if c logs that much.
+func (c *Compiler) logf(level LogLevel, format string, args ...any) {
+	if level > c.logLevel() {
+		return
+	}
+	c.logger.Logf(level, format, args...)
+}
@@ -0,0 +1,73 @@
+package compiler
+
+import (
+	"strconv"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+)
+
+// OptLevel selects the passes that simplify the generated program, as
+// simgo's -O0, -O1 and -O2 flags do. Constants are folded at every level:
+// loop bounds, array sizes and the locals inlined into later expressions
+// need their values to be translated at all.
+type OptLevel int
+
+const (
+	// OptDefault applies the passes the compiler applied before it had
+	// levels: helpers are inlined or, called with constants, evaluated,
+	// and k-of-n counting is lowered to a comparison tree, but every
+	// function is emitted and nothing is hoisted, so output is still
+	// written item by item.
+	OptDefault OptLevel = iota
+	// O0 translates each function as it is written, for auditing the
+	// output line by line against the Go: helpers are called rather than
+	// inlined or evaluated, and counting loops are unrolled.
+	O0
+	// O1 adds dead-code elimination to O0: a call of a helper whose
+	// arguments are constants is evaluated, and functions that main does
+	// not reach are left out; see optimize.DCE.
+	O1
+	// O2 adds inlining, k-of-n comparison trees and the hoisting of
+	// repeated jet calls to O1; see optimize.CSE.
+	O2
+)
+
+// String returns the flag that selects the level, or "default".
+func (l OptLevel) String() string {
+	switch l {
+	case OptDefault:
+		return "default"
+	case O0, O1, O2:
+		return "-O" + strconv.Itoa(int(l-O0))
+	}
+	return "OptLevel(" + strconv.Itoa(int(l)) + ")"
+}
+
+// passes are the optimization passes of one compile.
+type passes struct {
+	inline, foldCalls, thresholdTrees bool // In the transpiler
+	dce, cse                          bool // On the generated program
+}
+
+// passes resolves the level of c and its per-pass overrides: a No option
+// turns its pass off at any level, and Optimize turns CSE on at any level.
+func (c Config) passes() (passes, error) {
+	var p passes
+	switch c.OptLevel {
+	case OptDefault:
+		p = passes{inline: true, foldCalls: true, thresholdTrees: true}
+	case O0:
+	case O1:
+		p = passes{foldCalls: true, dce: true}
+	case O2:
+		p = passes{inline: true, foldCalls: true, thresholdTrees: true, dce: true, cse: true}
+	default:
+		return p, diag.InvalidConfig.Errorf("unsupported optimization level: %v", c.OptLevel)
+	}
+	p.inline = p.inline && !c.NoInline
+	p.foldCalls = p.foldCalls && !c.NoCallFolding
+	p.thresholdTrees = p.thresholdTrees && !c.NoThresholdTrees
+	p.dce = p.dce && !c.NoDCE
+	p.cse = p.cse || c.Optimize
+	return p, nil
+}
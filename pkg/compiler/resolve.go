@@ -0,0 +1,152 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	gotypes "go/types"
+	"sort"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/types"
+)
+
+// resolver finds the identifiers of a file that the parser could not
+// resolve to a declaration, a predeclared name or an import. The
+// transpiler would write them to the output as they are, where they fail
+// far from the Go that caused them, or fold a call of an undefined
+// function away.
+type resolver struct {
+	fset       *token.FileSet
+	unresolved map[*ast.Ident]bool
+	types      map[string]bool // Type names the type mapper provides, such as u256
+	funcs      []string        // Functions the file declares
+	names      []string        // Names in scope in the declaration being checked
+	errors     []string
+}
+
+// undefinedNames reports each undefined identifier of file outside a type,
+// where names such as u256 and Option come from the type mapper, with the
+// declared names nearest to it: any name in scope for an identifier,
+// functions for a call.
+func undefinedNames(fset *token.FileSet, file *ast.File, mapper *types.TypeMapper) error {
+	r := &resolver{fset: fset, unresolved: make(map[*ast.Ident]bool), types: make(map[string]bool)}
+	for _, ident := range file.Unresolved {
+		if ident.Name != "_" && gotypes.Universe.Lookup(ident.Name) == nil {
+			r.unresolved[ident] = true
+		}
+	}
+	if len(r.unresolved) == 0 {
+		return nil
+	}
+	if mapper == nil {
+		mapper = types.NewTypeMapper()
+	}
+	for _, name := range mapper.SupportedTypes() {
+		r.types[name] = true
+	}
+
+	var pkgNames []string
+	for name, obj := range file.Scope.Objects {
+		pkgNames = append(pkgNames, name)
+		if obj.Kind == ast.Fun {
+			r.funcs = append(r.funcs, name)
+		}
+	}
+	sort.Strings(pkgNames)
+	sort.Strings(r.funcs)
+
+	for _, decl := range file.Decls {
+		r.names = pkgNames
+		if fn, ok := decl.(*ast.FuncDecl); ok {
+			r.names = append(localNames(fn), pkgNames...)
+		}
+		ast.Inspect(decl, r.visit)
+	}
+	if len(r.errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(r.errors, "\n"))
+	}
+	return nil
+}
+
+func (r *resolver) visit(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.Field:
+		ast.Inspect(n.Type, r.visitType)
+		return false
+	case *ast.ValueSpec:
+		if n.Type != nil {
+			ast.Inspect(n.Type, r.visitType)
+		}
+		for _, value := range n.Values {
+			ast.Inspect(value, r.visit)
+		}
+		return false
+	case *ast.TypeSpec:
+		return false
+	case *ast.CompositeLit:
+		if n.Type != nil {
+			ast.Inspect(n.Type, r.visitType)
+		}
+		for _, elt := range n.Elts {
+			ast.Inspect(elt, r.visit)
+		}
+		return false
+	case *ast.SelectorExpr:
+		// The package of a qualified name, such as jet in jet.Verify, is
+		// an import, which the validator checks.
+		if _, ok := n.X.(*ast.Ident); ok {
+			return false
+		}
+	case *ast.IndexExpr:
+		// The type argument of a generic call, as in Eq[u256](a, b).
+		ast.Inspect(n.X, r.visit)
+		if ident, ok := n.Index.(*ast.Ident); ok && r.unresolved[ident] {
+			return false
+		}
+		ast.Inspect(n.Index, r.visit)
+		return false
+	case *ast.CallExpr:
+		if ident, ok := n.Fun.(*ast.Ident); ok && r.unresolved[ident] {
+			if !r.types[ident.Name] {
+				r.errors = append(r.errors, diag.UndefinedName.Sprintf("%s: call of undefined function %s%s", r.fset.Position(ident.Pos()), ident.Name, diag.DidYouMean(ident.Name, r.funcs)))
+			}
+			for _, arg := range n.Args {
+				ast.Inspect(arg, r.visit)
+			}
+			return false
+		}
+	case *ast.Ident:
+		if r.unresolved[n] {
+			r.errors = append(r.errors, diag.UndefinedName.Sprintf("%s: undefined: %s%s", r.fset.Position(n.Pos()), n.Name, diag.DidYouMean(n.Name, r.names)))
+		}
+	}
+	return true
+}
+
+// visitType checks the array lengths of a type, the only values in it.
+func (r *resolver) visitType(n ast.Node) bool {
+	if array, ok := n.(*ast.ArrayType); ok {
+		if array.Len != nil {
+			ast.Inspect(array.Len, r.visit)
+		}
+		ast.Inspect(array.Elt, r.visitType)
+		return false
+	}
+	return true
+}
+
+// localNames returns the parameters, results and locals fn declares.
+func localNames(fn *ast.FuncDecl) []string {
+	var names []string
+	ast.Inspect(fn, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && ident != fn.Name && ident.Obj != nil {
+			if kind := ident.Obj.Kind; kind == ast.Var || kind == ast.Con {
+				names = append(names, ident.Name)
+			}
+		}
+		return true
+	})
+	return names
+}
@@ -0,0 +1,292 @@
+package compiler
+
+import (
+	"go/ast"
+	"go/token"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/transpiler"
+)
+
+// CompileResult describes the output of a Compile call in structured form.
+//
+// CompileReader does not retain the program it writes, so after it Code is
+// empty and the Jets and Nodes of each function are unknown.
+type CompileResult struct {
+	Code      string
+	Entry     string // Go entry function; empty in library mode
+	Functions []FunctionInfo
+	Witnesses []transpiler.WitnessValue
+	// WitnessBits is the size of each of Witnesses, in bits, and
+	// TotalWitnessBits their sum: see WitnessBits.
+	WitnessBits      []int
+	TotalWitnessBits int
+	Constants        []transpiler.Constant
+	CMR              string // Commitment Merkle root; empty until the compiler computes one
+	Nodes            int    // Estimated size of the program, which Config.MaxNodes limits
+	Stats            Stats  // Time and allocations of each phase of the compile
+	Pieces           []Piece
+	// Fallbacks are the places the transpiler guessed at, each as
+	// "file:line:col: reason", in the order it met them; Config.Strict
+	// makes the first a compile error.
+	Fallbacks []string
+}
+
+// Piece is a top-level item of the generated program, in the order of
+// Code: a function with the doc comment above it, or the witness and param
+// modules, which have no Name. Joined with blank lines, the pieces are the
+// program.
+type Piece struct {
+	Name string // SimplicityHL name of the function
+	Code string // Without the blank lines around it
+}
+
+// FunctionInfo describes one generated function and the Go function it was
+// compiled from.
+type FunctionInfo struct {
+	GoName     string // Package-qualified for imported helpers
+	Name       string // SimplicityHL name
+	Pos        token.Position
+	Params     []transpiler.Parameter
+	ReturnType string   // Empty for functions returning unit
+	Jets       []string // Jets in the generated body, sorted, without the jet:: prefix
+	Calls      []string // Go functions called directly, sorted
+	Nodes      int      // SimplicityHL expression nodes; 0 if the body could not be parsed
+	Reachable  bool     // Called from the entry function, directly or transitively
+}
+
+var jetRef = regexp.MustCompile(`jet::([a-z0-9_]+)`)
+
+// Result returns the structured result of the most recent successful
+// Compile call, or nil if there is none.
+func (c *Compiler) Result() *CompileResult {
+	if c.file == nil {
+		return nil
+	}
+	result := &CompileResult{
+		Code:      c.output,
+		Witnesses: c.transpiler.Witnesses(),
+		Constants: c.transpiler.Constants(),
+		Nodes:     c.transpiler.Nodes(),
+		Stats:     c.stats,
+	}
+	result.WitnessBits, result.TotalWitnessBits = WitnessBits(result.Witnesses)
+	for _, f := range c.transpiler.Fallbacks() {
+		result.Fallbacks = append(result.Fallbacks, c.fset.Position(f.Pos).String()+": "+f.Reason)
+	}
+	if c.config.Mode != "library" {
+		result.Entry = c.config.Entry
+		if result.Entry == "" {
+			result.Entry = "main"
+		}
+	}
+
+	functions := c.transpiler.Functions()
+	if result.Entry == "main" {
+		// A main() entry is emitted as the SimplicityHL main itself.
+		for _, decl := range c.file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == "main" {
+				functions = append(functions, transpiler.Function{Name: "main", GoName: "main", Pos: fn.Pos()})
+			}
+		}
+	}
+
+	result.Pieces = c.pieces()
+
+	decls := c.goFunctions()
+	bodies := functionBodies(c.output)
+	index := make(map[string]int, len(functions))
+	for _, fn := range functions {
+		if _, ok := c.emitted[fn.Name]; c.emitted != nil && !ok {
+			continue // Left out by DCE
+		}
+		text := bodies[fn.Name]
+		info := FunctionInfo{
+			GoName:     fn.GoName,
+			Name:       fn.Name,
+			Pos:        c.fset.Position(fn.Pos),
+			Params:     fn.Parameters,
+			ReturnType: fn.ReturnType,
+			Jets:       jetNames(text),
+			Nodes:      transpiler.CountNodes(text),
+		}
+		if decl, ok := decls[fn.GoName]; ok {
+			info.Calls = decl.calls(decls)
+		}
+		index[info.GoName] = len(result.Functions)
+		result.Functions = append(result.Functions, info)
+	}
+
+	// Helpers are usually inlined, so reachability follows the Go call
+	// graph rather than calls in the generated code.
+	if result.Entry != "" {
+		work := []string{result.Entry}
+		for len(work) > 0 {
+			name := work[0]
+			work = work[1:]
+			i, ok := index[name]
+			if !ok || result.Functions[i].Reachable {
+				continue
+			}
+			result.Functions[i].Reachable = true
+			work = append(work, result.Functions[i].Calls...)
+		}
+	}
+	return result
+}
+
+// pieces splits the output into the items the transpiler rendered. An
+// optimized line belongs to the item of the line it comes from; one that
+// comes from no line, such as a let binding that optimization introduced,
+// to the item of the line before it.
+func (c *Compiler) pieces() []Piece {
+	if c.output == "" {
+		return nil
+	}
+	items := c.transpiler.Items()
+	lines := make([][]string, len(items))
+	item := 0
+	for i, line := range strings.Split(strings.TrimSuffix(c.output, "\n"), "\n") {
+		from := i + 1
+		if c.origin != nil {
+			from = 0
+			if i < len(c.origin) {
+				from = c.origin[i]
+			}
+		}
+		if from > 0 {
+			item = sort.Search(len(items), func(j int) bool { return items[j].EndLine >= from })
+		}
+		if item < len(items) {
+			lines[item] = append(lines[item], line)
+		}
+	}
+	var pieces []Piece
+	for i, l := range lines {
+		if code := strings.Trim(strings.Join(l, "\n"), "\n"); code != "" {
+			pieces = append(pieces, Piece{Name: items[i].Name, Code: code})
+		}
+	}
+	return pieces
+}
+
+// goFunction is a top-level Go function along with the package it is
+// declared in: "" for the contract file, or an imported package's name.
+type goFunction struct {
+	decl    *ast.FuncDecl
+	pkg     string
+	aliases map[string]string // Local import name → package name
+}
+
+// goFunctions indexes the contract's functions and those of its imported
+// packages by GoName.
+func (c *Compiler) goFunctions() map[string]goFunction {
+	byPath := make(map[string]string, len(c.imports))
+	for _, imp := range c.imports {
+		byPath[imp.Path] = imp.Name
+	}
+	funcs := make(map[string]goFunction)
+	add := func(file *ast.File, pkg string) {
+		aliases := make(map[string]string)
+		for _, spec := range file.Imports {
+			path := strings.Trim(spec.Path.Value, "\"")
+			if name, ok := byPath[path]; ok {
+				local := name
+				if spec.Name != nil {
+					local = spec.Name.Name
+				}
+				aliases[local] = name
+			}
+		}
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+				name := fn.Name.Name
+				if pkg != "" {
+					name = pkg + "." + name
+				}
+				funcs[name] = goFunction{decl: fn, pkg: pkg, aliases: aliases}
+			}
+		}
+	}
+	add(c.file, "")
+	for _, imp := range c.imports {
+		for _, file := range imp.Files {
+			add(file, imp.Name)
+		}
+	}
+	return funcs
+}
+
+// calls returns the GoNames of the known functions fn calls directly.
+func (fn goFunction) calls(funcs map[string]goFunction) []string {
+	seen := make(map[string]bool)
+	var names []string
+	ast.Inspect(fn.decl, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		var name string
+		switch f := call.Fun.(type) {
+		case *ast.Ident:
+			name = f.Name
+			if fn.pkg != "" {
+				name = fn.pkg + "." + name
+			}
+		case *ast.SelectorExpr:
+			if x, ok := f.X.(*ast.Ident); ok {
+				if pkg, ok := fn.aliases[x.Name]; ok {
+					name = pkg + "." + f.Sel.Name
+				}
+			}
+		}
+		if _, known := funcs[name]; known && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+// functionBodies splits generated code into its top-level functions, keyed
+// by name. Each value is the complete definition including the signature.
+func functionBodies(code string) map[string]string {
+	bodies := make(map[string]string)
+	var name string
+	var lines []string
+	for _, line := range strings.Split(code, "\n") {
+		if name == "" {
+			rest, ok := strings.CutPrefix(line, "fn ")
+			if !ok {
+				continue
+			}
+			if i := strings.Index(rest, "("); i > 0 {
+				name, lines = rest[:i], []string{line}
+			}
+			continue
+		}
+		lines = append(lines, line)
+		if line == "}" {
+			bodies[name] = strings.Join(lines, "\n")
+			name = ""
+		}
+	}
+	return bodies
+}
+
+func jetNames(text string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, m := range jetRef.FindAllStringSubmatch(text, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,127 @@
+package compiler
+
+import (
+	"go/ast"
+	"go/token"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+)
+
+// shadowWarnings reports each := in a branch of an if or switch that
+// declares a variable of the name of one outside the statement which is
+// read after it, as in
+//
+//	valid := false
+//	if x > 0 {
+//		valid := true
+//	}
+//	jet.Verify(valid)
+//
+// where the branch sets a variable of its own and the outer one keeps its
+// value: in a contract, almost always an assignment meant for the outer
+// variable. Names are resolved by the parser, so the file must keep its
+// objects.
+func shadowWarnings(fset *token.FileSet, file *ast.File) []string {
+	var warnings []string
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		reads := variableReads(fn.Body)
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			var branches []ast.Node
+			switch s := n.(type) {
+			case *ast.IfStmt:
+				branches = []ast.Node{s.Body}
+				if s.Else != nil {
+					branches = append(branches, s.Else)
+				}
+			case *ast.SwitchStmt:
+				branches = []ast.Node{s.Body}
+			case *ast.TypeSwitchStmt:
+				branches = []ast.Node{s.Body}
+			default:
+				return true
+			}
+			for _, branch := range branches {
+				ast.Inspect(branch, func(m ast.Node) bool {
+					switch m.(type) {
+					case *ast.IfStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt:
+						return false // Reported against the nested statement
+					}
+					assign, ok := m.(*ast.AssignStmt)
+					if !ok || assign.Tok != token.DEFINE {
+						return true
+					}
+					for _, lhs := range assign.Lhs {
+						ident, ok := lhs.(*ast.Ident)
+						if !ok || ident.Obj == nil || ident.Obj.Decl != assign {
+							continue // Assigned rather than declared by :=
+						}
+						if outer, read := shadowedRead(reads, ident.Name, n); read != nil {
+							warnings = append(warnings, diag.Shadowed.Sprintf("%s: %s := declares a new %s for the branch, shadowing the %s of line %d, which line %d reads after the %s; the branch does not change it. Write %s = to assign the outer %s",
+								fset.Position(ident.Pos()), ident.Name, ident.Name, ident.Name, fset.Position(outer.Pos()).Line, fset.Position(read.Pos()).Line, statementKind(n), ident.Name, ident.Name))
+						}
+					}
+					return true
+				})
+			}
+			return true
+		})
+	}
+	return warnings
+}
+
+// shadowedRead returns the variable called name declared before stmt and
+// the first read of it after stmt, if there is one.
+func shadowedRead(reads []*ast.Ident, name string, stmt ast.Node) (*ast.Object, *ast.Ident) {
+	for _, read := range reads {
+		if read.Name == name && read.Pos() >= stmt.End() && read.Obj.Pos() < stmt.Pos() {
+			return read.Obj, read
+		}
+	}
+	return nil, nil
+}
+
+// variableReads returns, in order, the identifiers under body that read a
+// local variable or parameter: all of them but those that declare one or
+// that a plain assignment writes.
+func variableReads(body *ast.BlockStmt) []*ast.Ident {
+	written := make(map[*ast.Ident]bool)
+	var reads []*ast.Ident
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if n.Tok == token.ASSIGN || n.Tok == token.DEFINE {
+				for _, lhs := range n.Lhs {
+					if ident, ok := lhs.(*ast.Ident); ok {
+						written[ident] = true
+					}
+				}
+			}
+		case *ast.ValueSpec:
+			for _, name := range n.Names {
+				written[name] = true
+			}
+		case *ast.Field:
+			for _, name := range n.Names {
+				written[name] = true
+			}
+		case *ast.Ident:
+			if n.Obj != nil && n.Obj.Kind == ast.Var && !written[n] {
+				reads = append(reads, n)
+			}
+		}
+		return true
+	})
+	return reads
+}
+
+// statementKind names the statement of a shadowWarnings report.
+func statementKind(stmt ast.Node) string {
+	if _, ok := stmt.(*ast.IfStmt); ok {
+		return "if"
+	}
+	return "switch"
+}
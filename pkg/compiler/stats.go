@@ -0,0 +1,60 @@
+package compiler
+
+import (
+	"runtime/metrics"
+	"time"
+)
+
+// PhaseStats is the cost of one phase of a compile.
+type PhaseStats struct {
+	Phase      string // e.g. "parsing", "analysis", "rendering"
+	Wall       time.Duration
+	AllocBytes uint64 // Heap bytes allocated while the phase ran
+	Allocs     uint64 // Heap objects allocated while the phase ran
+}
+
+// Stats is the cost of a compile, phase by phase, so that a slow build can
+// be told parse-bound from analysis-bound: a huge file spends its time in
+// parsing, a huge unroll in analysis and rendering. Stats are always
+// collected: the allocation counters are read from runtime/metrics, which
+// does not stop the world. They count the whole process, so compiles
+// running at once share theirs, and the runtime adds a thread's allocations
+// as its cache refills, so a phase of a few microseconds may show none and
+// the next one its share.
+type Stats struct {
+	Phases     []PhaseStats // In the order they ran
+	Wall       time.Duration
+	AllocBytes uint64
+	Allocs     uint64
+}
+
+// allocMetrics are the runtime/metrics counters behind Stats.
+var allocMetrics = []string{"/gc/heap/allocs:bytes", "/gc/heap/allocs:objects"}
+
+// allocs returns the heap bytes and objects allocated so far.
+func (c *Compiler) allocs() (uint64, uint64) {
+	if c.samples == nil {
+		c.samples = make([]metrics.Sample, len(allocMetrics))
+		for i, name := range allocMetrics {
+			c.samples[i].Name = name
+		}
+	}
+	metrics.Read(c.samples)
+	return c.samples[0].Value.Uint64(), c.samples[1].Value.Uint64()
+}
+
+// enter ends the running phase, recording its cost if it was timed, and
+// starts phase. An empty phase ends the last one.
+func (c *Compiler) enter(phase string) {
+	bytes, objects := c.allocs()
+	now := time.Now()
+	if !c.phaseStart.IsZero() {
+		p := PhaseStats{Phase: c.phase, Wall: now.Sub(c.phaseStart), AllocBytes: bytes - c.phaseBytes, Allocs: objects - c.phaseObjects}
+		c.stats.Phases = append(c.stats.Phases, p)
+		c.stats.Wall += p.Wall
+		c.stats.AllocBytes += p.AllocBytes
+		c.stats.Allocs += p.Allocs
+		c.logf(LogDebug, "%s took %v and allocated %d bytes in %d objects", p.Phase, p.Wall.Round(time.Microsecond), p.AllocBytes, p.Allocs)
+	}
+	c.phase, c.phaseStart, c.phaseBytes, c.phaseObjects = phase, now, bytes, objects
+}
@@ -0,0 +1,170 @@
+package compiler
+
+import (
+	"errors"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+)
+
+// errTrivialMain is the error for a program that anyone can spend.
+var errTrivialMain = diag.TrivialMain.Errorf("program accepts unconditionally: no check of main depends on a witness value or the spending transaction, so anyone can spend it (set AllowTrivialMain if this is intended)")
+
+// checkAcceptance fails a program whose outcome is fixed at compile time,
+// typically because every check folded to a constant, and warns when that
+// outcome is rejection, at the Go position of the check that fails.
+// Without a witness, running the program settles its outcome, unless it
+// reaches a jet that reads the transaction.
+func (c *Compiler) checkAcceptance(code string) error {
+	prog, err := shlparse.Parse(code)
+	if err != nil || prog.Func("main") == nil || readsWitness(prog) {
+		return nil
+	}
+	err = eval.Run(prog, eval.Options{})
+	var rejection *eval.Rejection
+	switch {
+	case err == nil:
+		if !c.config.AllowTrivialMain {
+			return errTrivialMain
+		}
+	case errors.As(err, &rejection):
+		c.warnings = append(c.warnings, diag.NeverSatisfied.Sprintf("%s: program can never be satisfied: no check of main depends on a witness value or the spending transaction, and this one always fails with %s (line %d of the generated program)",
+			c.generatedPosition(rejection.Line, "main"), rejection.Reason, rejection.Line))
+	}
+	return nil
+}
+
+// readsWitness reports whether a check of main may depend on a witness:
+// whether main, or a function it calls, gives a witness, or a value
+// computed from one, to a jet, a builtin or a match. A witness passed to a
+// function that never reads the parameter it binds does not count, so the
+// answer is the same whether such a call is inlined or not.
+func readsWitness(prog *shlparse.Program) bool {
+	w := &witnessFlow{prog: prog, funcs: make(map[string]flow)}
+	f := w.expr(prog.Func("main").Body, nil)
+	return f.value || f.effect
+}
+
+// flow is what evaluating an expression takes from the witnesses: its
+// value is computed from one, or a check it makes reads one.
+type flow struct {
+	value, effect bool
+}
+
+func (f flow) or(g flow) flow {
+	return flow{f.value || g.value, f.effect || g.effect}
+}
+
+// witnessFlow follows witness values through the functions of prog.
+type witnessFlow struct {
+	prog  *shlparse.Program
+	funcs map[string]flow // By function and the parameters computed from a witness
+}
+
+// call returns the flow of a call of fn whose arguments have the flows of
+// args.
+func (w *witnessFlow) call(fn *shlparse.Func, args []flow) flow {
+	tainted := make(map[string]bool)
+	key := []byte(fn.Name + ":")
+	for i, p := range fn.Params {
+		from := i < len(args) && args[i].value
+		tainted[p.Name] = from
+		key = append(key, map[bool]byte{false: '0', true: '1'}[from])
+	}
+	if f, ok := w.funcs[string(key)]; ok {
+		return f
+	}
+	w.funcs[string(key)] = flow{} // SimplicityHL has no recursion
+	f := w.expr(fn.Body, tainted)
+	w.funcs[string(key)] = f
+	return f
+}
+
+// expr returns the flow of e, where tainted holds the locals in scope that
+// are computed from a witness.
+func (w *witnessFlow) expr(e shlparse.Expr, tainted map[string]bool) flow {
+	var f flow
+	switch e := e.(type) {
+	case *shlparse.Path:
+		f.value = e.Module == "witness"
+	case *shlparse.Ident:
+		f.value = tainted[e.Name]
+	case *shlparse.Call:
+		args := make([]flow, len(e.Args))
+		var all flow
+		for i, arg := range e.Args {
+			args[i] = w.expr(arg, tainted)
+			all = all.or(args[i])
+		}
+		if fn := w.prog.Func(e.Func); fn != nil {
+			f = w.call(fn, args)
+			f.effect = f.effect || all.effect
+		} else {
+			f = flow{value: all.value, effect: all.value || all.effect}
+		}
+	case *shlparse.Cast:
+		f = w.expr(e.Arg, tainted)
+	case *shlparse.Tuple:
+		for _, elem := range e.Elems {
+			f = f.or(w.expr(elem, tainted))
+		}
+	case *shlparse.Array:
+		for _, elem := range e.Elems {
+			f = f.or(w.expr(elem, tainted))
+		}
+	case *shlparse.Match:
+		f = w.expr(e.Scrutinee, tainted)
+		f.effect = f.effect || f.value // The arm taken depends on it
+		for _, arm := range e.Arms {
+			scope := bind(tainted, arm.Binding, f.value)
+			f = f.or(w.expr(arm.Body, scope))
+		}
+	case *shlparse.Block:
+		scope := tainted
+		for _, stmt := range e.Stmts {
+			switch s := stmt.(type) {
+			case *shlparse.Let:
+				v := w.expr(s.Value, scope)
+				f.effect = f.effect || v.effect
+				scope = bind(scope, s.Pattern, v.value)
+			case *shlparse.ExprStmt:
+				f.effect = f.effect || w.expr(s.X, scope).effect
+			}
+		}
+		if e.Result != nil {
+			r := w.expr(e.Result, scope)
+			f = flow{r.value, f.effect || r.effect}
+		}
+	}
+	return f
+}
+
+// bind returns tainted with the names p binds, computed from a witness or
+// not, in scope.
+func bind(tainted map[string]bool, p shlparse.Pattern, from bool) map[string]bool {
+	if p == nil {
+		return tainted
+	}
+	scope := make(map[string]bool, len(tainted)+1)
+	for name, t := range tainted {
+		scope[name] = t
+	}
+	var visit func(shlparse.Pattern)
+	visit = func(p shlparse.Pattern) {
+		switch p := p.(type) {
+		case *shlparse.IdentPattern:
+			scope[p.Name] = from
+		case *shlparse.TuplePattern:
+			for _, elem := range p.Elems {
+				visit(elem)
+			}
+		case *shlparse.ArrayPattern:
+			for _, elem := range p.Elems {
+				visit(elem)
+			}
+		}
+	}
+	visit(p)
+	return scope
+}
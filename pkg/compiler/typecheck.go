@@ -0,0 +1,170 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/jets"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+)
+
+// checkTypes reports the constants of the generated program whose values
+// have a type other than their declared one, the functions whose bodies
+// have a type other than their declared result, the calls whose arguments
+// have types other than the parameters', and the names it uses but does
+// not define: a gap in the
+// translation, such as an operator lowered to the wrong jet, that
+// SimplicityHL would reject far from the Go that caused it. Code that does
+// not parse is an error, as it is for selfCheck, since there is nothing to
+// check.
+func (c *Compiler) checkTypes(code string) error {
+	prog, err := shlparse.Parse(code)
+	if err != nil {
+		return c.syntaxError(err)
+	}
+	mismatches := shlparse.TypeCheck(prog, c.jetTypes)
+	if len(mismatches) == 0 {
+		return nil
+	}
+	goNames := make(map[string]string)
+	for _, fn := range c.transpiler.Functions() {
+		goNames[fn.Name] = fn.GoName
+	}
+	goName := func(name string) string {
+		if goName := goNames[name]; goName != "" {
+			return goName
+		}
+		return name
+	}
+
+	var messages []string
+	for _, m := range mismatches {
+		var msg string
+		if m.Undefined != "" && m.Func == "" {
+			msg = fmt.Sprintf("undefined identifier %s: a module constant refers to a name the translation does not define", m.Undefined)
+		} else if m.Undefined != "" {
+			msg = fmt.Sprintf("undefined identifier %s: %s refers to a name the translation does not define", m.Undefined, goName(m.Func))
+		} else if m.Const != "" {
+			msg = fmt.Sprintf("%s is declared %s, but its value was translated to an expression of type %s",
+				m.Const, shlparse.TypeString(m.Want), shlparse.TypeString(m.Got))
+		} else if m.Callee == "" {
+			msg = fmt.Sprintf("%s returns %s, but its body was translated to an expression of type %s",
+				goName(m.Func), shlparse.TypeString(m.Want), shlparse.TypeString(m.Got))
+		} else {
+			callee := goName(m.Callee)
+			if m.Param != "" {
+				callee += ", " + m.Param + ","
+			}
+			msg = fmt.Sprintf("argument %d of %s takes %s, but the call was translated to pass an expression of type %s",
+				m.Arg+1, callee, shlparse.TypeString(m.Want), shlparse.TypeString(m.Got))
+		}
+		messages = append(messages, fmt.Sprintf("%s: %s (line %d of the generated program)", c.generatedPosition(m.Line, m.Func), msg, m.Line))
+	}
+	if len(messages) == 1 {
+		return diag.TypeMismatch.Errorf("%s", messages[0])
+	}
+	return diag.TypeMismatch.Errorf("generated code does not type-check:\n%s", strings.Join(messages, "\n"))
+}
+
+// syntaxError reports err, the error shlparse.Parse returns for the
+// generated program, at the Go position of the line it names.
+func (c *Compiler) syntaxError(err error) error {
+	var perr *shlparse.Error
+	if !errors.As(err, &perr) {
+		return fmt.Errorf("internal error: generated invalid SimplicityHL: %w", err)
+	}
+	return fmt.Errorf("%s: internal error: generated invalid SimplicityHL at line %d: %s", c.generatedPosition(perr.Line, ""), perr.Line, perr.Msg)
+}
+
+// generatedPosition is the Go position of a line of the generated code,
+// or that of the declaration of the function fn holding it when the line
+// has none.
+func (c *Compiler) generatedPosition(line int, fn string) string {
+	if pos, ok := c.SourcePosition(line); ok {
+		return pos.String()
+	}
+	for _, f := range c.transpiler.Functions() {
+		if f.Name == fn && f.Pos.IsValid() {
+			return c.fset.Position(f.Pos).String()
+		}
+	}
+	return "generated code"
+}
+
+// sizedJet matches the arithmetic and comparison jets of an integer width,
+// such as add_32.
+var sizedJet = regexp.MustCompile(`^(add|subtract|multiply|divide|modulo|and|or|xor|min|max|complement|lt|le|eq)_(8|16|32|64|128|256)$`)
+
+var (
+	jetResultsOnce sync.Once
+	jetResults     map[string]shlparse.Type
+)
+
+// jetTypes types the jets of the generated program for
+// shlparse.TypeCheck. The arithmetic and comparison jets are typed
+// exactly; the others by the return type that pkg/jets registers for
+// them, the value the Go code sees, with their arguments unchecked, since
+// several take their registered parameters grouped in tuples.
+func jetTypes(name string) ([]shlparse.Type, shlparse.Type, bool) {
+	if m := sizedJet.FindStringSubmatch(name); m != nil {
+		bits, _ := strconv.Atoi(m[2])
+		word := &shlparse.UInt{Bits: bits}
+		switch m[1] {
+		case "add", "subtract":
+			return []shlparse.Type{word, word}, &shlparse.TupleType{Elems: []shlparse.Type{&shlparse.Bool{}, word}}, true
+		case "multiply":
+			if bits == 256 {
+				return nil, nil, false
+			}
+			return []shlparse.Type{word, word}, &shlparse.UInt{Bits: 2 * bits}, true
+		case "complement":
+			return []shlparse.Type{word}, word, true
+		case "lt", "le", "eq":
+			return []shlparse.Type{word, word}, &shlparse.Bool{}, true
+		default:
+			return []shlparse.Type{word, word}, word, true
+		}
+	}
+	jetResultsOnce.Do(func() {
+		jetResults = make(map[string]shlparse.Type)
+		for _, info := range jets.NewRegistry().AllJets() {
+			if info.Jet() != info.SimplicityName {
+				continue // A helper of the program, typed by its fn
+			}
+			if t, err := shlparse.ParseType(info.ReturnType); err == nil {
+				jetResults[info.Jet()] = t
+			}
+		}
+	})
+	t, ok := jetResults[name]
+	return nil, t, ok
+}
+
+// jetTypes is the package jetTypes extended with the jets of
+// Config.ExtraJets, which are typed exactly by their declarations, since
+// they are called with their parameters as declared.
+func (c *Compiler) jetTypes(name string) ([]shlparse.Type, shlparse.Type, bool) {
+	if params, result, ok := jetTypes(name); ok {
+		return params, result, true
+	}
+	info, ok := c.jets.LookupJet(name)
+	if !ok || !info.Custom {
+		return nil, nil, false
+	}
+	result, err := shlparse.ParseType(info.ReturnType)
+	if err != nil {
+		return nil, nil, false
+	}
+	params := make([]shlparse.Type, len(info.ParamTypes))
+	for i, param := range info.ParamTypes {
+		if params[i], err = shlparse.ParseType(param); err != nil {
+			return nil, result, true
+		}
+	}
+	return params, result, true
+}
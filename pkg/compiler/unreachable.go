@@ -0,0 +1,214 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	gotypes "go/types"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/transpiler"
+)
+
+// foldAnalysis folds expressions of a file over its constants and
+// helpers.
+type foldAnalysis struct {
+	fset     *token.FileSet
+	std      map[string]bool // Local names of the std package
+	folder   *transpiler.Folder
+	consts   map[ast.Node]bool // Package const specs the folder knows
+	warnings []string
+}
+
+// unreachableAnalysis folds the conditions of if and switch statements.
+type unreachableAnalysis struct {
+	*foldAnalysis
+}
+
+// newFoldAnalysis returns a foldAnalysis that knows the package constants
+// of file.
+func newFoldAnalysis(fset *token.FileSet, file *ast.File) *foldAnalysis {
+	funcs := make(map[string]*ast.FuncDecl)
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+			funcs[fn.Name.Name] = fn
+		}
+	}
+	a := &foldAnalysis{
+		fset:   fset,
+		std:    stdPackageNames(file),
+		folder: transpiler.NewFolder(nil, funcs),
+		consts: make(map[ast.Node]bool),
+	}
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			valueSpec := spec.(*ast.ValueSpec)
+			if len(valueSpec.Values) != len(valueSpec.Names) {
+				continue
+			}
+			for i, name := range valueSpec.Names {
+				value := valueSpec.Values[i]
+				if valueSpec.Type != nil {
+					value = &ast.CallExpr{Fun: valueSpec.Type, Args: []ast.Expr{value}}
+				}
+				if v, ok := a.folder.Fold(value); ok {
+					a.folder.Define(name.Name, v)
+					a.consts[valueSpec] = true
+				}
+			}
+		}
+	}
+	return a
+}
+
+// unreachableWarnings reports each branch that constant folding rules out:
+// the body of an if whose condition is always false, its else when the
+// condition always holds, and a switch case that cannot be selected. A
+// branch that only calls std.Unreachable is already marked as such and is
+// not reported.
+func unreachableWarnings(fset *token.FileSet, file *ast.File) []string {
+	a := unreachableAnalysis{newFoldAnalysis(fset, file)}
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+			ast.Inspect(fn.Body, a.visit)
+		}
+	}
+	return a.warnings
+}
+
+func (a *unreachableAnalysis) visit(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.IfStmt:
+		if n.Init != nil {
+			return true
+		}
+		holds, known := a.condition(n.Cond)
+		switch {
+		case !known:
+		case holds && n.Else != nil && !a.marked(n.Else):
+			a.warn(n.Else.Pos(), diag.Unreachable, "the else branch is unreachable: %s always holds", gotypes.ExprString(n.Cond))
+		case !holds && !a.marked(n.Body):
+			a.warn(n.Body.Pos(), diag.Unreachable, "the branch is unreachable: %s is always false", gotypes.ExprString(n.Cond))
+		}
+	case *ast.SwitchStmt:
+		if n.Init == nil && n.Tag == nil {
+			a.switchCases(n)
+		}
+	case *ast.FuncLit:
+		return false
+	}
+	return true
+}
+
+// switchCases reports the cases of a tagless switch that cannot be
+// selected: those whose conditions are all false, and every case after
+// one that always holds, along with the default.
+func (a *unreachableAnalysis) switchCases(s *ast.SwitchStmt) {
+	var taken ast.Expr
+	var def *ast.CaseClause
+	for _, stmt := range s.Body.List {
+		clause := stmt.(*ast.CaseClause)
+		if len(clause.List) == 0 {
+			def = clause
+			continue
+		}
+		marked := a.marked(&ast.BlockStmt{List: clause.Body})
+		if taken != nil {
+			if !marked {
+				a.warn(clause.Pos(), diag.Unreachable, "the case is unreachable: the earlier case %s always holds", gotypes.ExprString(taken))
+			}
+			continue
+		}
+		never := true
+		for _, cond := range clause.List {
+			holds, known := a.condition(cond)
+			if known && holds {
+				taken = cond
+			}
+			never = never && known && !holds
+		}
+		if never && !marked {
+			a.warn(clause.Pos(), diag.Unreachable, "the case is unreachable: %s is always false", exprList(clause.List))
+		}
+	}
+	if def != nil && taken != nil && !a.marked(&ast.BlockStmt{List: def.Body}) {
+		a.warn(def.Pos(), diag.Unreachable, "the default case is unreachable: the case %s always holds", gotypes.ExprString(taken))
+	}
+}
+
+// condition folds cond. It reports known only for a bool that depends on
+// nothing but literals, package constants and calls of the file's helpers.
+func (a *foldAnalysis) condition(cond ast.Expr) (holds, known bool) {
+	v, ok := a.constant(cond)
+	if !ok || v.Type != "bool" {
+		return false, false
+	}
+	return v.Bool, true
+}
+
+// constant folds expr when it depends on nothing but literals, package
+// constants and calls of the file's helpers.
+func (a *foldAnalysis) constant(expr ast.Expr) (transpiler.Value, bool) {
+	constant := true
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && ident.Obj != nil {
+			switch decl := ident.Obj.Decl.(type) {
+			case *ast.FuncDecl:
+			case *ast.ValueSpec:
+				constant = constant && a.consts[decl]
+			default:
+				constant = false
+			}
+		}
+		return constant
+	})
+	if !constant {
+		return transpiler.Value{}, false
+	}
+	return a.folder.Fold(expr)
+}
+
+// marked reports whether the branch stmt only calls std.Unreachable.
+func (a *foldAnalysis) marked(stmt ast.Stmt) bool {
+	block, ok := stmt.(*ast.BlockStmt)
+	if !ok || len(block.List) != 1 {
+		return false
+	}
+	var call ast.Expr
+	switch s := block.List[0].(type) {
+	case *ast.ExprStmt:
+		call = s.X
+	case *ast.ReturnStmt:
+		if len(s.Results) == 1 {
+			call = s.Results[0]
+		}
+	}
+	c, ok := call.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := c.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && a.std[pkg.Name] && sel.Sel.Name == "Unreachable"
+}
+
+func (a *foldAnalysis) warn(pos token.Pos, code diag.Code, format string, args ...interface{}) {
+	a.warnings = append(a.warnings, fmt.Sprintf("%s: ", a.fset.Position(pos))+code.Sprintf(format, args...))
+}
+
+// exprList returns exprs as they are written in a case clause.
+func exprList(exprs []ast.Expr) string {
+	strs := make([]string, len(exprs))
+	for i, e := range exprs {
+		strs[i] = gotypes.ExprString(e)
+	}
+	return strings.Join(strs, ", ")
+}
@@ -0,0 +1,65 @@
+package compiler
+
+import (
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/transpiler"
+	"github.com/0ceanslim/go-simplicity/pkg/types"
+	"github.com/0ceanslim/go-simplicity/pkg/value"
+)
+
+// DefaultMaxWitnessBits is the size past which a witness entry draws a
+// warning: four 512-bit signatures' worth, twice what any contract in
+// examples/ places in one entry.
+const DefaultMaxWitnessBits = 2048
+
+// WitnessBits returns the size in bits of each witness entry, in order, and
+// their total: what the spender pays for, whatever the values.
+func WitnessBits(witnesses []transpiler.WitnessValue) ([]int, int) {
+	tm := types.NewTypeMapper()
+	sizes := make([]int, len(witnesses))
+	total := 0
+	for i, w := range witnesses {
+		sizes[i] = tm.GetBitSize(w.DeclaredType())
+		total += sizes[i]
+	}
+	return sizes, total
+}
+
+// witnessWarnings reports the witness entries of the compiled program that
+// are larger than Config.MaxWitnessBits, and those computed from constants
+// alone, which could be params.
+func (c *Compiler) witnessWarnings() []string {
+	limit := c.config.MaxWitnessBits
+	if limit == 0 {
+		limit = DefaultMaxWitnessBits
+	}
+	witnesses := c.transpiler.Witnesses()
+	sizes, _ := WitnessBits(witnesses)
+	var warnings []string
+	for i, w := range witnesses {
+		pos := c.fset.Position(w.Pos)
+		if limit > 0 && sizes[i] > limit {
+			warnings = append(warnings, diag.LargeWitness.Sprintf("%s: witness %s is %d bits, more than the limit of %d; the spender pays fees for every byte of it",
+				pos, w.Name, sizes[i], limit))
+		}
+		if w.Constant {
+			warnings = append(warnings, diag.ConstantWitness.Sprintf("%s: witness %s is computed from constants alone; a package-level const would make it a param, which the spender neither pays for nor sets",
+				pos, w.Name))
+		}
+	}
+	return warnings
+}
+
+// logWitnesses lists the witness entries and their values at debug level,
+// each value rendered for its type as value.Format does.
+func (c *Compiler) logWitnesses() {
+	if LogDebug > c.logLevel() {
+		return
+	}
+	witnesses := c.transpiler.Witnesses()
+	sizes, _ := WitnessBits(witnesses)
+	for i, w := range witnesses {
+		typ := w.DeclaredType()
+		c.logf(LogDebug, "witness %s: %s = %s (%d bits)", w.Name, typ, value.Display(typ, w.Value), sizes[i])
+	}
+}
@@ -0,0 +1,150 @@
+// Package diag is the registry of diagnostic codes. Every error and warning
+// the compiler reports about a contract ends with a code such as [SIM0001],
+// which keeps its meaning across releases: messages may be reworded, but a
+// code is never reused for another problem. `simgo explain SIM0001` prints
+// the long explanation of a code with an example of the fix.
+//
+// Codes are grouped by hundreds: SIM00xx are Go features Simplicity has no
+// equivalent of, SIM01xx values and types, SIM02xx the shape of the program,
+// SIM03xx warnings and SIM04xx the compiler's configuration.
+package diag
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Code identifies a kind of diagnostic.
+type Code string
+
+const (
+	UnboundedLoop     Code = "SIM0001"
+	RangeLoop         Code = "SIM0002"
+	Goroutine         Code = "SIM0003"
+	Channel           Code = "SIM0004"
+	Interface         Code = "SIM0005"
+	Slice             Code = "SIM0006"
+	Map               Code = "SIM0007"
+	DynamicTyping     Code = "SIM0008"
+	String            Code = "SIM0009"
+	NonASCIIName      Code = "SIM0010"
+	DotImport         Code = "SIM0011"
+	UnknownPackage    Code = "SIM0012"
+	UnimportedJet     Code = "SIM0013"
+	JetNotOnChain     Code = "SIM0014"
+	SyntaxError       Code = "SIM0015"
+	UndefinedName     Code = "SIM0016"
+	LoopBranch        Code = "SIM0017"
+	UnsupportedSyntax Code = "SIM0099"
+
+	DivisionByZero Code = "SIM0101"
+	ValueOverflow  Code = "SIM0102"
+	UnknownJet     Code = "SIM0103"
+
+	NoMain             Code = "SIM0201"
+	MainSignature      Code = "SIM0202"
+	EntryNotFound      Code = "SIM0203"
+	EntrySignature     Code = "SIM0204"
+	DuplicateWitness   Code = "SIM0205"
+	UnknownWitness     Code = "SIM0206"
+	ProgramTooLarge    Code = "SIM0207"
+	TrivialMain        Code = "SIM0208"
+	BadBuildConstraint Code = "SIM0209"
+	ImpurePackage      Code = "SIM0210"
+	PackageLoad        Code = "SIM0211"
+	TypeMismatch       Code = "SIM0212"
+	IncludeMismatch    Code = "SIM0213"
+	SilentFallback     Code = "SIM0214"
+
+	ConfidentialCompare Code = "SIM0301"
+	TruncatingDivision  Code = "SIM0302"
+	DivisorMayBeZero    Code = "SIM0303"
+	Unreachable         Code = "SIM0304"
+	ExcludedFile        Code = "SIM0305"
+	NeverSatisfied      Code = "SIM0306"
+	SignedInt           Code = "SIM0307"
+	Shadowed            Code = "SIM0308"
+	LargeWitness        Code = "SIM0309"
+	ConstantWitness     Code = "SIM0310"
+	DiscardedValue      Code = "SIM0311"
+	HashByteOrder       Code = "SIM0312"
+
+	InvalidConfig Code = "SIM0401"
+)
+
+// Explanation describes a code: Title in a few words, for listings, and
+// Text at length, with an example of the fix.
+type Explanation struct {
+	Code  Code
+	Title string
+	Text  string
+}
+
+// Lookup returns the explanation of code, which may be written in any case.
+func Lookup(code string) (Explanation, bool) {
+	e, ok := registry[Code(strings.ToUpper(code))]
+	return e, ok
+}
+
+// All returns the explanation of every code, in code order.
+func All() []Explanation {
+	all := make([]Explanation, 0, len(registry))
+	for _, e := range registry {
+		all = append(all, e)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Code < all[j].Code })
+	return all
+}
+
+// Sprintf formats a message and appends the code to it.
+func (c Code) Sprintf(format string, args ...any) string {
+	return fmt.Sprintf(format, args...) + " [" + string(c) + "]"
+}
+
+// Errorf formats an error, as fmt.Errorf does, with the code appended.
+func (c Code) Errorf(format string, args ...any) error {
+	return &Error{Code: c, Err: fmt.Errorf(format, args...)}
+}
+
+// Wrap appends the code to err, unless err already carries one. A nil err
+// stays nil.
+func (c Code) Wrap(err error) error {
+	if err == nil || len(Find(err.Error())) > 0 {
+		return err
+	}
+	return &Error{Code: c, Err: err}
+}
+
+// Error is an error with a diagnostic code.
+type Error struct {
+	Code Code
+	Err  error
+}
+
+func (e *Error) Error() string { return e.Err.Error() + " [" + string(e.Code) + "]" }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// CodeOf returns the code of the first *Error in err's chain.
+func CodeOf(err error) (Code, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code, true
+	}
+	return "", false
+}
+
+var codeRef = regexp.MustCompile(`\[(SIM\d{4})\]`)
+
+// Find returns the codes in text, such as the message of an error that
+// lists several problems, in the order they appear.
+func Find(text string) []Code {
+	var codes []Code
+	for _, m := range codeRef.FindAllStringSubmatch(text, -1) {
+		codes = append(codes, Code(m[1]))
+	}
+	return codes
+}
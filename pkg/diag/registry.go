@@ -0,0 +1,342 @@
+package diag
+
+// registry holds every code. An entry may be reworded, but its code must
+// keep its meaning, and a retired code stays here so that it is not reused;
+// tests/testdata/diag_codes.golden locks the titles.
+var registry = map[Code]Explanation{
+	UnboundedLoop: {UnboundedLoop, "loop without a constant bound", `Simplicity programs have no loops: the compiler unrolls a for loop into
+one copy of its body per iteration, so it must see the number of
+iterations. Only the form for i := 0; i < N; i++ (or i <= N) with N an
+integer literal or a named integer constant, such as const N = 3, is
+accepted; the message says which part of the loop differs.
+
+    for valid := false; !valid; {     // rejected
+        valid = check(next())
+    }
+
+    for i := 0; i < N; i++ {          // accepted: N copies of the body
+        total += amounts[i]
+    }`},
+	RangeLoop: {RangeLoop, "range loop", `Only a range over an integer constant, a literal or a named constant, is
+unrolled, as the counted loop it abbreviates. Write a range over an array
+or a variable with a constant bound over the length of the fixed-size
+array instead.
+
+    for i, b := range hash { ... }    // rejected
+    for i := range 32 {               // accepted
+        b := hash[i]
+        ...
+    }
+
+A loop testing a byte array for zeros is better written as one call to
+std.IsZero20, std.IsZero32 or std.IsZero64.`},
+	Goroutine: {Goroutine, "goroutine", `A Simplicity program is a single expression evaluated once; there is no
+concurrency. Call the function directly.
+
+    go verify(sig)    // rejected
+    verify(sig)       // accepted`},
+	Channel: {Channel, "channel", `Channels need concurrency, which Simplicity does not have. Pass values as
+function arguments and results instead.
+
+    results := make(chan bool)    // rejected
+    ok := verify(sig)             // accepted`},
+	Interface: {Interface, "interface", `Simplicity types are fixed when the program is committed to, so there is
+no dynamic dispatch. Use a concrete type, or a sum type for a value that
+may be one of two things:
+
+    type Spend interface{ ... }                 // rejected
+    type Spend struct {                         // accepted: Either<Claim, Refund>
+        IsLeft bool
+        Left   Claim
+        Right  Refund
+    }`},
+	Slice: {Slice, "slice", `Simplicity values have a size fixed by their type. Use an array of the
+largest size the contract needs.
+
+    var keys []Pubkey       // rejected
+    var keys [3]Pubkey      // accepted`},
+	Map: {Map, "map", `Maps have no fixed size and no Simplicity equivalent. A lookup among a few
+known keys is a chain of comparisons; a table is an array indexed by a
+small integer.
+
+    limits := map[uint8]uint64{0: 100, 1: 200}    // rejected
+    limits := [2]uint64{100, 200}                 // accepted`},
+	DynamicTyping: {DynamicTyping, "type assertion or type switch", `Simplicity has no dynamic typing. Declare the alternatives as a sum type
+and branch on its tag: a struct with IsLeft bool maps to Either<L, R>, one
+with IsSome bool and Value T to Option<T>.
+
+    switch w := witness.(type) { ... }    // rejected
+    if witness.IsLeft { ... }             // accepted`},
+	String: {String, "string", `Simplicity has no strings. Use a fixed-size byte array; a constant may be
+written as a hex string, which decodes to one.
+
+    var name string = "alice"                   // rejected
+    const Key = "0x79be667ef9dcbbac55a0..."     // accepted: [u8; 32]
+
+String literals are also accepted as panic messages, which are dropped,
+and as the tag of std.TaggedHash, which the compiler hashes.`},
+	NonASCIIName: {NonASCIIName, "identifier that is not ASCII", `SimplicityHL identifiers are ASCII. Letters with an ASCII spelling, such
+as é, are transliterated unless transliteration is turned off; others must
+be renamed.
+
+    montantÉlevé := amount > 1000    // montant_eleve, or rejected without transliteration
+    große := amount > 1000           // rename, e.g. to large`},
+	DotImport: {DotImport, "dot import", `A dot import makes the package's functions look like local ones, so the
+compiler cannot tell a jet from a helper. Import the package by name.
+
+    import . "simplicity/jet"    // rejected
+    import j "simplicity/jet"    // accepted: j.Verify(...)`},
+	UnknownPackage: {UnknownPackage, "unknown compiler package", `Under simplicity/ only the packages the compiler provides exist, such as
+simplicity/jet. Check the import path for a typo.
+
+    import "simplicity/jets"    // rejected
+    import "simplicity/jet"     // accepted`},
+	UnimportedJet: {UnimportedJet, "jet package not imported as jet", `The file calls jet.X, but no import is named jet: the calls would not
+resolve to jets. Import the jet package under the name the calls use.
+
+    import j "simplicity/jet"
+    jet.Verify(ok)              // rejected
+    j.Verify(ok)                // accepted`},
+	JetNotOnChain: {JetNotOnChain, "jet not available on the target chain", `The jet reads the transaction environment, which SimplicityHL defines only
+for Elements. A program for another chain, set with -chain or
+Config.Chain, may use the core jets alone.
+
+    simgo -chain bitcoin -input vault.go    // rejected if vault.go reads the transaction
+    simgo -input vault.go                   // accepted: chain elements`},
+	SyntaxError: {SyntaxError, "Go source does not parse", `The file is not valid Go. Fix the syntax error at the position given; go
+vet reports the same error.`},
+	UndefinedName: {UndefinedName, "undefined name", `The name is not declared in the file: not as a constant, variable,
+parameter, type or function. It is usually misspelt, and the message names
+the declared names it is closest to.
+
+    jet.Verify(jet.Le64(MinAmont, amount))     // rejected
+    jet.Verify(jet.Le64(MinAmount, amount))    // accepted
+
+A helper called from another file of the package must be moved into the
+contract's file or into an imported helper package.`},
+	LoopBranch: {LoopBranch, "break or continue that cannot be unrolled", `A break or continue of a counted loop is resolved as the loop is
+unrolled. One whose condition is known once the index is, such as
+i%2 == 1, leaves out the rest of its iteration or the iterations after it;
+one whose condition is only known when the program runs is lowered when the
+loop updates a single accumulator declared just before it, with the break
+or continue as the last statement of an if directly in the loop body. The
+message names the statement and the first iteration that reaches it.
+
+    for i := 0; i < 8; i++ {          // rejected: two accumulators
+        if xs[i] == 0 {
+            break
+        }
+        total += xs[i]
+        n++
+    }
+
+    for i := 0; i < 8; i++ {          // accepted
+        if xs[i] == 0 {
+            break
+        }
+        total += xs[i]
+    }`},
+	UnsupportedSyntax: {UnsupportedSyntax, "construct the translator does not support", `The Go is valid and uses only supported features, but this use of them is
+not one the translator can express in SimplicityHL, such as a call to an
+unknown function or a statement form it does not lower. The message names
+the construct; rewrite it with the patterns in the readme's Supported
+Contract Patterns.
+
+    x := helper()(1)          // rejected: a call of a call result
+    f := helper(); x := f     // rewrite with a named intermediate`},
+	DivisionByZero: {DivisionByZero, "division by constant zero", `Go panics when dividing by zero, while the Simplicity divide jets return 0.
+A constant zero divisor is always a mistake.
+
+    share := amount / 0        // rejected
+    share := amount / parts    // accepted, with parts checked to be nonzero`},
+	ValueOverflow: {ValueOverflow, "value does not fit its type", `A constant or witness value is wider than its declared type: an integer
+larger than the type's maximum, or a byte array given more bytes than it
+holds.
+
+    var fee uint8 = 300     // rejected: u8 holds 0 to 255
+    var fee uint16 = 300    // accepted`},
+	UnknownJet: {UnknownJet, "unknown jet", `The called jet is not in the registry. simgo -list-jets prints the jets it
+knows, with their Go names.
+
+    jet.Sha256(data)                 // rejected
+    jet.SHA256Add32(ctx, data)       // accepted`},
+	NoMain: {NoMain, "no main function", `A program is rooted at func main(). Compile an exported function instead
+with -entry, or compile the file as helpers with -mode library.
+
+    simgo -input helpers.go                  // rejected: no main
+    simgo -entry Spend -input helpers.go     // accepted`},
+	MainSignature: {MainSignature, "main takes parameters or returns values", `main is the program root and takes no arguments. For a spend path with
+arguments, or a predicate returning bool, compile an exported function with
+-entry: its parameters become witnesses.
+
+    func main(sig [64]byte) { ... }           // rejected
+    func Spend(sig [64]byte) bool { ... }     // accepted with -entry Spend`},
+	EntryNotFound: {EntryNotFound, "entry function not found", `The function named with -entry or Config.Entry is not declared in the
+file. Names are case-sensitive.
+
+    simgo -entry spend -input vault.go    // rejected if the function is Spend
+    simgo -entry Spend -input vault.go    // accepted`},
+	EntrySignature: {EntrySignature, "entry function with an unsupported signature", `An entry function returns nothing, or a bool that the program asserts,
+and every parameter is named, since each becomes a witness.
+
+    func Spend(uint64) uint64 { ... }      // rejected
+    func Spend(amount uint64) bool { ... } // accepted`},
+	DuplicateWitness: {DuplicateWitness, "two witnesses with one name", `Witness names are the upper snake case of Go names, so amountIn and
+amount_in both become AMOUNT_IN. Rename one of them.
+
+    var amountIn uint64; var amount_in uint64    // rejected
+    var amountIn uint64; var feeIn uint64        // accepted`},
+	UnknownWitness: {UnknownWitness, "witness value for no declared witness", `A witness value given with -witness-values or Config.WitnessValues names a
+witness the program does not declare, or does not parse as a value of its
+type. Names are the emitted upper snake case ones.
+
+    {"amount": "1000"}    // rejected
+    {"AMOUNT": "1000"}    // accepted`},
+	ProgramTooLarge: {ProgramTooLarge, "program exceeds a size limit", `The generated program grew past Config.MaxOutputBytes or the node limit
+of Config.MaxNodes (-max-nodes), typically by unrolling a long loop or
+inlining a large helper at many call sites. Shorten the loop, call the
+helper instead of inlining it (-no-inline), or raise the limit. A loop of
+a helper, or a nest of loops by the product of their bounds, unrolls to at
+most transpiler.MaxUnrollIterations iterations, which no option raises.
+
+    for i := 0; i < 100000; i++ { ... }    // rejected
+    for i := 0; i < 16; i++ { ... }        // accepted`},
+	TrivialMain: {TrivialMain, "program accepts unconditionally", `No check of main depends on a witness or the spending transaction, so
+anyone could spend the coins, usually because every check folded to true.
+Check a witness, or set AllowTrivialMain (-allow-trivial-main) if an
+anyone-can-spend program is intended.
+
+    func main() { jet.Verify(1 < 2) }                       // rejected
+    func main() { jet.Verify(jet.Le32(800000, height)) }    // accepted`},
+	BadBuildConstraint: {BadBuildConstraint, "invalid build constraint", `The //go:build line does not parse. Build constraints use Go's syntax:
+tags combined with &&, || and !.
+
+    //go:build oracle and testnet    // rejected
+    //go:build oracle && testnet     // accepted`},
+	ImpurePackage: {ImpurePackage, "imported package is not pure", `Imported helper packages are compiled into the program, so they may hold
+only constants, types and functions: no imports other than the compiler's
+packages, no methods and no package-level variables.
+
+    package helpers; import "fmt"             // rejected
+    package helpers; import "simplicity/jet"  // accepted`},
+	PackageLoad: {PackageLoad, "imported package does not load", `An imported package has errors of its own, such as a type error. go build
+on the package reports the same errors.`},
+	TypeMismatch: {TypeMismatch, "generated code has the wrong type", `The compiler translated a function or a call to SimplicityHL of another
+type than the Go declares, such as a body of type bool for a function that
+returns u64, or refers to a name the program does not define. A
+construct the translation does not handle has been lowered wrongly; the
+message gives its Go position and both types, or the name.
+Rewriting the expression with jet calls, or splitting it into locals,
+usually avoids it, and the construct is worth reporting as a bug.
+
+    func widen(a uint32) uint64 { return uint64(a) } // the conversion is lost
+    func widen(a uint64) uint64 { return a }         // accepted`},
+	IncludeMismatch: {IncludeMismatch, "stub does not match the included module", `A function declared without a body is a stub of a function that a module
+included with -include defines, and must have its parameters and result.
+A stub that no included module defines, or a function with a body that
+one does, is rejected as well, since the program would define or call
+the wrong one.
+
+    func minAmount(x uint32) bool  // lib.simf has fn min_amount(x: u64) -> bool
+    func minAmount(x uint64) bool  // accepted`},
+	SilentFallback: {SilentFallback, "strict mode forbids a fallback", `Where the transpiler cannot lower a construct it falls back on a guess:
+an expression becomes the placeholder true, a statement is left out, a
+witness gets a type inferred from its value, and a main that checks
+nothing asserts a witness or helper picked by name. With -strict each
+such fallback fails the compile instead; CompileResult.Fallbacks lists
+the ones a compile without it relied on.
+
+    jet.Verify(jet.Eq32(b[i:][0], 1))  // b[i:] compiles to true without -strict
+    jet.Verify(jet.Eq32(b[i], 1))      // accepted`},
+	ConfidentialCompare: {ConfidentialCompare, "comparison of a value that may be confidential", `On Elements an amount or asset may be confidential, and the explicit
+introspection jets then return nothing, which fails the spend as a missing
+value would. Use the std helper the warning names, which asserts that the
+value is explicit.
+
+    amount := jet.OutputAmount(0)          // warned when compared
+    amount := std.ExplicitOutputValue(0)   // accepted`},
+	TruncatingDivision: {TruncatingDivision, "division that drops a remainder", `Integer division rounds down. Constants that do not divide evenly lose
+their remainder, which in a fee or share calculation is usually a bug.
+
+    fee := amount * 3 / 1000          // warned if the remainder is nonzero
+    fee := std.DivCeil(amount*3, 1000) // rounds up instead`},
+	DivisorMayBeZero: {DivisorMayBeZero, "divisor that may be zero", `The divide and modulo jets return 0 and the dividend for a zero divisor,
+where Go panics. Check the divisor before dividing, or compile with
+-checked-arithmetic to fail the spend on zero.
+
+    share := amount / parts           // warned
+    jet.Verify(parts > 0)
+    share := amount / parts           // accepted`},
+	Unreachable: {Unreachable, "branch that can never run", `The condition folds to a constant, so one branch is dead code. Often the
+condition compares against the wrong constant.
+
+    if MinHeight > 0 { ... } else { ... }    // the else branch is unreachable`},
+	ExcludedFile: {ExcludedFile, "file excluded by its build constraint", `The //go:build line excludes the file under the given tags, but a file
+named for compilation is compiled anyway, as go run does. Pass the tags
+the file expects with -tags.
+
+    simgo -input oracle.go               // warned: //go:build oracle
+    simgo -tags oracle -input oracle.go  // accepted`},
+	NeverSatisfied: {NeverSatisfied, "program can never be satisfied", `No check of main depends on a witness or the transaction, and one of them
+always fails, so the coins could never be spent. Usually a check folded to
+false; the warning gives its line.
+
+    func main() { jet.Verify(2 < 1) }    // warned`},
+	SignedInt: {SignedInt, "signed integer", `Simplicity's integers are unsigned and of a fixed width, so a signed type
+such as int64, or int and uint, whose width depends on the platform, has no
+equivalent. Declare the unsigned type of the width, once no value can be
+negative; simgo fix offers the rewrite.
+
+    var count int       // warned
+    var count uint64    // accepted`},
+	Shadowed: {Shadowed, "variable shadowed in a branch", `A := in a branch of an if or switch declares a new variable with the name
+of one outside, which is read after the statement. The branch changes its
+own variable, and the outer one keeps its value, so a check of it after
+the branch does not see the branch at all.
+
+    valid := false
+    if x > 0 {
+        valid := true     // warned: declares a second valid
+    }
+    jet.Verify(valid)     // always false
+
+Write valid = true to assign the outer variable, which after the if holds
+the value of the branch taken, or rename the inner one.`},
+	LargeWitness: {LargeWitness, "large witness", `A witness entry is larger than the limit, 2048 bits unless -max-witness-bits
+(Config.MaxWitnessBits) sets another. The spender pays fees for every byte
+of witness, so check that the whole value is needed: a hash of it, or the
+one element of an array that the program reads, is often enough.
+
+    var outputs [16][32]byte    // warned: 4096 bits
+    var output [32]byte         // accepted`},
+	ConstantWitness: {ConstantWitness, "witness computed from constants", `A local that the program places in the witness is computed from constants
+alone, so its value is known at compile time. The spender pays for a
+witness and may set it to anything; a package-level const compiles to a
+param, which costs nothing to spend and cannot be changed.
+
+    var fee uint64 = MinFee * 2    // warned, in main
+    const Fee = MinFee * 2         // accepted, at package level`},
+	DiscardedValue: {DiscardedValue, "discarded return value", `A call of a helper or jet that returns a value is a statement of its own,
+so the value is discarded. A bool that a check returns is almost always
+meant to decide the spend; assert it, or bind it and use it. With -strict
+(Config.Strict) the warning is an error. Calls that return nothing, such
+as std.Assert, are not reported.
+
+    Validate(amount)                // warned: the bool is discarded
+    std.Assert(Validate(amount))    // accepted`},
+	HashByteOrder: {HashByteOrder, "hash of unstated byte order", `A 64-digit hex constant is given to a bitcoin.Hash as it is written.
+SHA-256 produces a digest in raw order, and the jets compare it in that
+order, but block explorers and bitcoind display txids and block hashes
+byte-reversed. Hex copied from them names a different hash, and a
+contract comparing with it never matches. std.HashFromHexRaw and
+std.HashFromHexDisplay state the order; the compiler decodes both.
+
+    var prev bitcoin.Hash = 0x4a5e…a33b               // warned
+    var prev = std.HashFromHexDisplay("4a5e…a33b")    // accepted: a txid
+    var lock = std.HashFromHexRaw("425e…384c")        // accepted: a digest`},
+	InvalidConfig: {InvalidConfig, "invalid compiler configuration", `A configuration value is not one the compiler knows, such as a mode,
+chain, target or optimization level. The message lists the accepted values.
+
+    simgo -mode lib -input c.go        // rejected
+    simgo -mode library -input c.go    // accepted`},
+}
@@ -0,0 +1,84 @@
+package diag
+
+import (
+	"sort"
+	"strings"
+)
+
+// Suggest returns the candidates close enough to name to be what was
+// meant, nearest first: those within an edit distance of a third of the
+// name's length, at least 1, where changing a letter's case alone costs
+// nothing. name itself and duplicates are left out.
+func Suggest(name string, candidates []string) []string {
+	limit := max(1, len(name)/3)
+	type match struct {
+		name string
+		dist int
+	}
+	var matches []match
+	seen := map[string]bool{name: true}
+	for _, c := range candidates {
+		if seen[c] {
+			continue
+		}
+		seen[c] = true
+		if d := distance(strings.ToLower(name), strings.ToLower(c)); d <= limit {
+			matches = append(matches, match{c, d})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].dist != matches[j].dist {
+			return matches[i].dist < matches[j].dist
+		}
+		return matches[i].name < matches[j].name
+	})
+	names := make([]string, len(matches))
+	for i, m := range matches {
+		names[i] = m.name
+	}
+	return names
+}
+
+// DidYouMean returns a hint naming the nearest candidates to name, as in
+// "; did you mean amount?", to be appended to a message, or "" when none
+// is near. At most three are named.
+func DidYouMean(name string, candidates []string) string {
+	names := Suggest(name, candidates)
+	if len(names) == 0 {
+		return ""
+	}
+	if len(names) > 3 {
+		names = names[:3]
+	}
+	return "; did you mean " + orList(names) + "?"
+}
+
+// orList joins names as "a", "a or b" or "a, b or c".
+func orList(names []string) string {
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// distance is the Levenshtein distance between a and b, counted in bytes,
+// which is exact for the ASCII identifiers SimplicityHL allows.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
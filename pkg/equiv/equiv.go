@@ -0,0 +1,322 @@
+// Package equiv checks that a compiled contract means what its Go source
+// means. It runs an entry function natively, with a small interpreter over
+// the Go subset the transpiler accepts, and evaluates the generated program
+// with pkg/eval on the same inputs, reporting every input on which one
+// accepts and the other rejects.
+//
+// The entry's parameters are its inputs, as they are the program's
+// witnesses. Each must be a bool or an unsigned integer. When the inputs
+// have at most Options.MaxCases combinations all of them are run; otherwise
+// each integer takes its boundary values and the constants of the source
+// one either side, and that grid is sampled:
+//
+//	report, err := equiv.Check(source, "vault.go", compiler.Config{Entry: "AmountOk"}, equiv.Options{})
+//	for _, m := range report.Mismatches {
+//		fmt.Println(m)
+//	}
+//
+// The Go side knows jet.Verify, the arithmetic, comparison and bitwise jets
+// and the std assertions and thresholds; a function using anything else,
+// such as introspection or signatures, is reported as unsupported.
+package equiv
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+	"github.com/0ceanslim/go-simplicity/pkg/transpiler"
+)
+
+// DefaultMaxCases is the number of cases run when Options.MaxCases is 0.
+const DefaultMaxCases = 4096
+
+// Options controls the inputs Check runs.
+type Options struct {
+	MaxCases int   // Most cases to run (default: DefaultMaxCases)
+	Seed     int64 // Seed of the sample taken from a larger grid
+	// Tx is the transaction the program is evaluated against, for entry
+	// points that also read it. The Go side does not see it.
+	Tx *eval.Tx
+}
+
+// Mismatch is an input on which the Go function and the program disagree.
+type Mismatch struct {
+	Inputs         map[string]string // Witness values by emitted name
+	GoAccepts      bool
+	ProgramAccepts bool
+	Reason         string // Why the side that rejects rejects
+}
+
+func (m Mismatch) String() string {
+	verdict := func(accepts bool) string {
+		if accepts {
+			return "accepts"
+		}
+		return "rejects"
+	}
+	return fmt.Sprintf("%s: Go %s, program %s (%s)", formatInputs(m.Inputs), verdict(m.GoAccepts), verdict(m.ProgramAccepts), m.Reason)
+}
+
+// Report is the result of one Check.
+type Report struct {
+	Entry      string
+	Cases      int  // Inputs run
+	Exhaustive bool // Whether Cases covers every input
+	Mismatches []Mismatch
+}
+
+// input is a parameter of the entry and the values it is run with.
+type input struct {
+	name   string // Emitted witness name
+	bits   int    // 0 for a bool
+	values []value
+}
+
+// Check compiles source with config and compares the compiled entry point
+// with the Go function of the same name. It returns an error when the
+// source does not compile, when an input has a type the harness does not
+// generate values for, or when either side cannot be run.
+func Check(source, filename string, config compiler.Config, opts Options) (*Report, error) {
+	if opts.MaxCases <= 0 {
+		opts.MaxCases = DefaultMaxCases
+	}
+	if config.Target == "" {
+		config.Target = "simplicityhl"
+	}
+	entry := config.Entry
+	if entry == "" {
+		entry = "main"
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, source, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+	in := newInterp(file)
+	fn, ok := in.funcs[entry]
+	if !ok {
+		return nil, fmt.Errorf("entry function %s not found", entry)
+	}
+
+	c := compiler.New(config)
+	code, err := c.Compile(source, filename)
+	if err != nil {
+		return nil, err
+	}
+	prog, err := shlparse.Parse(code)
+	if err != nil {
+		return nil, fmt.Errorf("generated program does not parse: %w", err)
+	}
+	witnesses := make(map[string]bool)
+	for _, w := range c.Witnesses() {
+		witnesses[w.Name] = true
+	}
+
+	inputs, err := entryInputs(fset, fn)
+	if err != nil {
+		return nil, err
+	}
+	constants := sourceConstants(file)
+
+	report := &Report{Entry: entry}
+	cases, exhaustive := grid(inputs, constants, opts)
+	report.Exhaustive = exhaustive
+	args := make([]value, len(inputs))
+	for _, tuple := range cases {
+		witness := make(map[string]string, len(inputs))
+		shown := make(map[string]string, len(inputs))
+		for i, v := range tuple {
+			args[i] = v
+			shown[inputs[i].name] = v.String()
+			// A parameter the program never reads has no witness.
+			if witnesses[inputs[i].name] {
+				witness[inputs[i].name] = v.String()
+			}
+		}
+		goAccepts, goReason, err := in.run(fn, args)
+		if err != nil {
+			return nil, positioned(fset, err)
+		}
+		var programReason string
+		err = eval.Run(prog, eval.Options{Witness: witness, Tx: opts.Tx})
+		var rejection *eval.Rejection
+		programAccepts := err == nil
+		if errors.As(err, &rejection) {
+			programReason = rejection.Error()
+		} else if err != nil {
+			return nil, fmt.Errorf("evaluating %s: %w", formatInputs(shown), err)
+		}
+		report.Cases++
+		if goAccepts != programAccepts {
+			reason := goReason
+			if !programAccepts {
+				reason = programReason
+			}
+			report.Mismatches = append(report.Mismatches, Mismatch{Inputs: shown, GoAccepts: goAccepts, ProgramAccepts: programAccepts, Reason: reason})
+		}
+	}
+	return report, nil
+}
+
+// formatInputs formats inputs as NAME=value pairs in name order.
+func formatInputs(inputs map[string]string) string {
+	if len(inputs) == 0 {
+		return "no inputs"
+	}
+	names := make([]string, 0, len(inputs))
+	for name := range inputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + inputs[name]
+	}
+	return strings.Join(pairs, " ")
+}
+
+// positioned prefixes err with the Go position of the construct it is
+// about, if it has one.
+func positioned(fset *token.FileSet, err error) error {
+	var unsupported *unsupportedError
+	if errors.As(err, &unsupported) {
+		return fmt.Errorf("%s: %w", fset.Position(unsupported.pos), err)
+	}
+	return err
+}
+
+// entryInputs returns the parameters of fn with their emitted names.
+func entryInputs(fset *token.FileSet, fn *ast.FuncDecl) ([]input, error) {
+	var inputs []input
+	for _, field := range fn.Type.Params.List {
+		ident, ok := field.Type.(*ast.Ident)
+		bits, isInt := 0, false
+		if ok {
+			bits, isInt = intTypes[ident.Name]
+		}
+		if !isInt && (!ok || ident.Name != "bool") {
+			return nil, fmt.Errorf("%s: parameter of unsupported type: only bools and unsigned integers are generated", fset.Position(field.Type.Pos()))
+		}
+		for _, name := range field.Names {
+			inputs = append(inputs, input{name: transpiler.WitnessName(name.Name), bits: bits})
+		}
+	}
+	return inputs, nil
+}
+
+// sourceConstants returns the integer literals of file. A comparison is
+// most likely to be translated wrong at the constant it compares with.
+func sourceConstants(file *ast.File) []uint64 {
+	seen := make(map[uint64]bool)
+	var constants []uint64
+	ast.Inspect(file, func(n ast.Node) bool {
+		lit, ok := n.(*ast.BasicLit)
+		if !ok || lit.Kind != token.INT {
+			return true
+		}
+		if n, ok := constant.Uint64Val(constant.MakeFromLiteral(lit.Value, lit.Kind, 0)); ok && !seen[n] {
+			seen[n] = true
+			constants = append(constants, n)
+		}
+		return true
+	})
+	sort.Slice(constants, func(i, j int) bool { return constants[i] < constants[j] })
+	return constants
+}
+
+// grid returns the argument tuples to run and whether they are all the
+// inputs there are.
+func grid(inputs []input, constants []uint64, opts Options) ([][]value, bool) {
+	total, exhaustive := 1, true
+	for _, in := range inputs {
+		if in.bits > 30 || total<<(max(in.bits, 1)) > opts.MaxCases {
+			exhaustive = false
+			break
+		}
+		total <<= max(in.bits, 1)
+	}
+	for i := range inputs {
+		inputs[i].values = domain(inputs[i].bits, constants, exhaustive)
+	}
+
+	size := 1
+	for _, in := range inputs {
+		if size *= len(in.values); size > opts.MaxCases {
+			break
+		}
+	}
+	var cases [][]value
+	if size <= opts.MaxCases {
+		indices := make([]int, len(inputs))
+		for {
+			tuple := make([]value, len(inputs))
+			for i, in := range inputs {
+				tuple[i] = in.values[indices[i]]
+			}
+			cases = append(cases, tuple)
+			i := len(indices) - 1
+			for ; i >= 0; i-- {
+				if indices[i]++; indices[i] < len(inputs[i].values) {
+					break
+				}
+				indices[i] = 0
+			}
+			if i < 0 {
+				return cases, exhaustive
+			}
+		}
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	for len(cases) < opts.MaxCases {
+		tuple := make([]value, len(inputs))
+		for i, in := range inputs {
+			tuple[i] = in.values[rng.Intn(len(in.values))]
+		}
+		cases = append(cases, tuple)
+	}
+	return cases, false
+}
+
+// domain returns the values an input of the given width is run with:
+// every value, or its boundaries and the constants of the source.
+func domain(bits int, constants []uint64, all bool) []value {
+	if bits == 0 {
+		return []value{boolValue(false), boolValue(true)}
+	}
+	if all {
+		values := make([]value, 1<<bits)
+		for i := range values {
+			values[i] = value{bits: bits, n: uint64(i)}
+		}
+		return values
+	}
+	top := wrap(^uint64(0), bits)
+	candidates := []uint64{0, 1, 2, top >> 1, top>>1 + 1, top - 1, top}
+	for _, c := range constants {
+		if c <= top {
+			candidates = append(candidates, c-1, c, c+1)
+		}
+	}
+	seen := make(map[uint64]bool)
+	var values []value
+	for _, n := range candidates {
+		if n = wrap(n, bits); !seen[n] {
+			seen[n] = true
+			values = append(values, value{bits: bits, n: n})
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].n < values[j].n })
+	return values
+}
@@ -0,0 +1,844 @@
+package equiv
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"regexp"
+	"strconv"
+
+	"github.com/0ceanslim/go-simplicity/pkg/transpiler"
+)
+
+// value is a Go value of the subset the interpreter runs: a bool, or an
+// unsigned integer of 8 to 64 bits. An untyped integer constant has 0 bits
+// until an operation with a typed operand gives it a width.
+type value struct {
+	isBool bool
+	bits   int
+	n      uint64
+}
+
+func boolValue(b bool) value {
+	if b {
+		return value{isBool: true, n: 1}
+	}
+	return value{isBool: true}
+}
+
+func (v value) bool() bool { return v.n != 0 }
+
+func (v value) String() string {
+	if v.isBool {
+		return strconv.FormatBool(v.bool())
+	}
+	return strconv.FormatUint(v.n, 10)
+}
+
+// wrap truncates n to bits, as Go's unsigned arithmetic does.
+func wrap(n uint64, bits int) uint64 {
+	if bits == 0 || bits == 64 {
+		return n
+	}
+	return n & (1<<bits - 1)
+}
+
+// failure is the Go function failing the spend: a false jet.Verify or
+// std.Assert, a panic, or a run-time panic such as a division by zero.
+type failure struct {
+	pos    token.Pos
+	reason string
+}
+
+func (f *failure) Error() string { return f.reason }
+
+// unsupportedError is a construct the interpreter does not run.
+type unsupportedError struct {
+	pos  token.Pos
+	what string
+}
+
+func (e *unsupportedError) Error() string { return e.what + " is not supported" }
+
+// errBreak and errContinue carry a break or continue out of the statements
+// of the loop or switch it leaves.
+var (
+	errBreak    = errors.New("break outside a loop or switch")
+	errContinue = errors.New("continue outside a loop")
+)
+
+// intTypes are the Go unsigned integer types and their widths.
+var intTypes = map[string]int{"uint8": 8, "byte": 8, "uint16": 16, "uint32": 32, "uint64": 64, "uint": 64}
+
+// jetOp matches the arithmetic, comparison and bitwise jets the
+// interpreter runs, such as Le32 and Add64.
+var jetOp = regexp.MustCompile(`^(Le|Lt|Eq|Add|Subtract|Multiply|And|Or|Xor|Complement|Max|Min)(8|16|32|64)$`)
+
+// maxSteps bounds the statements one run executes, so that a loop the
+// interpreter cannot see the end of fails instead of hanging.
+const maxSteps = 1 << 20
+
+// interp runs the functions of one Go file.
+type interp struct {
+	funcs  map[string]*ast.FuncDecl
+	consts map[string]ast.Expr // Package constants by name
+	types  map[string]ast.Expr // Package constant types by name, where declared
+	cache  map[string]value    // Package constants evaluated so far
+	jet    map[string]bool     // Local names of the simplicity/jet import
+	std    map[string]bool     // Local names of the std import
+	steps  int
+}
+
+func newInterp(file *ast.File) *interp {
+	in := &interp{
+		funcs:  make(map[string]*ast.FuncDecl),
+		consts: make(map[string]ast.Expr),
+		types:  make(map[string]ast.Expr),
+		cache:  make(map[string]value),
+		jet:    make(map[string]bool),
+		std:    make(map[string]bool),
+	}
+	for _, imp := range file.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		name := path[len(path)-len(lastElem(path)):]
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		switch path {
+		case "simplicity/jet":
+			in.jet[name] = true
+		case "github.com/0ceanslim/go-simplicity/std":
+			in.std[name] = true
+		}
+	}
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil {
+				in.funcs[d.Name.Name] = d
+			}
+		case *ast.GenDecl:
+			if d.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range d.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for i, name := range vs.Names {
+					if i < len(vs.Values) {
+						in.consts[name.Name] = vs.Values[i]
+						if vs.Type != nil {
+							in.types[name.Name] = vs.Type
+						}
+					}
+				}
+			}
+		}
+	}
+	return in
+}
+
+func lastElem(path string) string {
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i] == '/' {
+			return path[i+1:]
+		}
+	}
+	return path
+}
+
+// frame holds the locals of one function call, innermost scope last.
+type frame struct {
+	scopes []map[string]*value
+}
+
+func (f *frame) push() { f.scopes = append(f.scopes, make(map[string]*value)) }
+func (f *frame) pop()  { f.scopes = f.scopes[:len(f.scopes)-1] }
+
+func (f *frame) lookup(name string) (*value, bool) {
+	for i := len(f.scopes) - 1; i >= 0; i-- {
+		if v, ok := f.scopes[i][name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func (f *frame) define(name string, v value) {
+	if name != "_" {
+		f.scopes[len(f.scopes)-1][name] = &v
+	}
+}
+
+// call runs fn with args and returns its result, if it has one.
+func (in *interp) call(fn *ast.FuncDecl, args []value) (value, error) {
+	f := &frame{}
+	f.push()
+	i := 0
+	for _, field := range fn.Type.Params.List {
+		for _, name := range field.Names {
+			v, err := in.convert(field.Type, args[i])
+			if err != nil {
+				return value{}, err
+			}
+			f.define(name.Name, v)
+			i++
+		}
+	}
+	result, returned, err := in.block(f, fn.Body.List)
+	if err != nil {
+		return value{}, err
+	}
+	if !returned && fn.Type.Results != nil {
+		return value{}, &unsupportedError{fn.Body.Rbrace, "a function that ends without returning"}
+	}
+	return result, nil
+}
+
+// block runs stmts in a scope of their own.
+func (in *interp) block(f *frame, stmts []ast.Stmt) (value, bool, error) {
+	f.push()
+	defer f.pop()
+	for _, stmt := range stmts {
+		if result, returned, err := in.stmt(f, stmt); err != nil || returned {
+			return result, returned, err
+		}
+	}
+	return value{}, false, nil
+}
+
+func (in *interp) stmt(f *frame, stmt ast.Stmt) (value, bool, error) {
+	if in.steps++; in.steps > maxSteps {
+		return value{}, false, &unsupportedError{stmt.Pos(), "a run of more than " + strconv.Itoa(maxSteps) + " statements"}
+	}
+	switch s := stmt.(type) {
+	case *ast.ReturnStmt:
+		if len(s.Results) == 0 {
+			return value{}, true, nil
+		}
+		if len(s.Results) > 1 {
+			return value{}, false, &unsupportedError{s.Pos(), "returning more than one value"}
+		}
+		v, err := in.expr(f, s.Results[0])
+		return v, true, err
+	case *ast.ExprStmt:
+		_, err := in.expr(f, s.X)
+		return value{}, false, err
+	case *ast.DeclStmt:
+		gen, ok := s.Decl.(*ast.GenDecl)
+		if !ok || (gen.Tok != token.VAR && gen.Tok != token.CONST) {
+			return value{}, false, &unsupportedError{s.Pos(), "this declaration"}
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, name := range vs.Names {
+				var v value
+				if i < len(vs.Values) {
+					var err error
+					if v, err = in.expr(f, vs.Values[i]); err != nil {
+						return value{}, false, err
+					}
+				} else if v, ok = zero(vs.Type); !ok {
+					return value{}, false, &unsupportedError{vs.Pos(), "a variable of this type"}
+				}
+				if vs.Type != nil {
+					var err error
+					if v, err = in.convert(vs.Type, v); err != nil {
+						return value{}, false, err
+					}
+				}
+				f.define(name.Name, typed(v))
+			}
+		}
+		return value{}, false, nil
+	case *ast.AssignStmt:
+		return value{}, false, in.assign(f, s)
+	case *ast.IncDecStmt:
+		ident, ok := s.X.(*ast.Ident)
+		if !ok {
+			return value{}, false, &unsupportedError{s.Pos(), "incrementing this expression"}
+		}
+		v, ok := f.lookup(ident.Name)
+		if !ok || v.isBool {
+			return value{}, false, &unsupportedError{s.Pos(), "incrementing " + ident.Name}
+		}
+		if s.Tok == token.INC {
+			v.n = wrap(v.n+1, v.bits)
+		} else {
+			v.n = wrap(v.n-1, v.bits)
+		}
+		return value{}, false, nil
+	case *ast.BlockStmt:
+		return in.block(f, s.List)
+	case *ast.IfStmt:
+		f.push()
+		defer f.pop()
+		if s.Init != nil {
+			if _, _, err := in.stmt(f, s.Init); err != nil {
+				return value{}, false, err
+			}
+		}
+		cond, err := in.expr(f, s.Cond)
+		if err != nil {
+			return value{}, false, err
+		}
+		if cond.bool() {
+			return in.block(f, s.Body.List)
+		}
+		if s.Else != nil {
+			return in.stmt(f, s.Else)
+		}
+		return value{}, false, nil
+	case *ast.ForStmt:
+		f.push()
+		defer f.pop()
+		if s.Init != nil {
+			if _, _, err := in.stmt(f, s.Init); err != nil {
+				return value{}, false, err
+			}
+		}
+		for {
+			if s.Cond != nil {
+				cond, err := in.expr(f, s.Cond)
+				if err != nil || !cond.bool() {
+					return value{}, false, err
+				}
+			}
+			result, returned, err := in.block(f, s.Body.List)
+			if err == errBreak {
+				return value{}, false, nil
+			}
+			if err != nil && err != errContinue || returned {
+				return result, returned, err
+			}
+			if s.Post != nil {
+				if _, _, err := in.stmt(f, s.Post); err != nil {
+					return value{}, false, err
+				}
+			}
+		}
+	case *ast.RangeStmt:
+		// The transpiler unrolls a range over an integer constant as the
+		// for loop it abbreviates; #range cannot be a Go name.
+		if loop, ok := transpiler.RangeIntLoop(s, "#range"); ok {
+			return in.stmt(f, loop)
+		}
+	case *ast.SwitchStmt:
+		result, returned, err := in.switchStmt(f, s)
+		if err == errBreak {
+			return value{}, false, nil
+		}
+		return result, returned, err
+	case *ast.BranchStmt:
+		switch {
+		case s.Label != nil:
+			return value{}, false, &unsupportedError{s.Pos(), "a labeled " + s.Tok.String()}
+		case s.Tok == token.BREAK:
+			return value{}, false, errBreak
+		case s.Tok == token.CONTINUE:
+			return value{}, false, errContinue
+		}
+	case *ast.EmptyStmt:
+		return value{}, false, nil
+	}
+	return value{}, false, &unsupportedError{stmt.Pos(), fmt.Sprintf("%T", stmt)}
+}
+
+func (in *interp) switchStmt(f *frame, s *ast.SwitchStmt) (value, bool, error) {
+	f.push()
+	defer f.pop()
+	if s.Init != nil {
+		if _, _, err := in.stmt(f, s.Init); err != nil {
+			return value{}, false, err
+		}
+	}
+	tag := boolValue(true)
+	if s.Tag != nil {
+		var err error
+		if tag, err = in.expr(f, s.Tag); err != nil {
+			return value{}, false, err
+		}
+	}
+	var chosen *ast.CaseClause
+	for _, stmt := range s.Body.List {
+		clause := stmt.(*ast.CaseClause)
+		if clause.List == nil {
+			if chosen == nil {
+				chosen = clause
+			}
+			continue
+		}
+		for _, e := range clause.List {
+			v, err := in.expr(f, e)
+			if err != nil {
+				return value{}, false, err
+			}
+			if v.n == tag.n {
+				return in.block(f, clause.Body)
+			}
+		}
+	}
+	if chosen != nil {
+		return in.block(f, chosen.Body)
+	}
+	return value{}, false, nil
+}
+
+func (in *interp) assign(f *frame, s *ast.AssignStmt) error {
+	if len(s.Lhs) != len(s.Rhs) {
+		return &unsupportedError{s.Pos(), "assigning the results of a call"}
+	}
+	values := make([]value, len(s.Rhs))
+	for i, rhs := range s.Rhs {
+		v, err := in.expr(f, rhs)
+		if err != nil {
+			return err
+		}
+		values[i] = v
+	}
+	for i, lhs := range s.Lhs {
+		ident, ok := lhs.(*ast.Ident)
+		if !ok {
+			return &unsupportedError{lhs.Pos(), "assigning to this expression"}
+		}
+		v := values[i]
+		switch s.Tok {
+		case token.DEFINE:
+			f.define(ident.Name, typed(v))
+			continue
+		case token.ASSIGN:
+		default:
+			if ident.Name == "_" {
+				return &unsupportedError{lhs.Pos(), "this assignment"}
+			}
+			old, ok := f.lookup(ident.Name)
+			if !ok {
+				return &unsupportedError{lhs.Pos(), "assigning to " + ident.Name}
+			}
+			op := map[token.Token]token.Token{
+				token.ADD_ASSIGN: token.ADD, token.SUB_ASSIGN: token.SUB, token.MUL_ASSIGN: token.MUL,
+				token.QUO_ASSIGN: token.QUO, token.REM_ASSIGN: token.REM, token.AND_ASSIGN: token.AND,
+				token.OR_ASSIGN: token.OR, token.XOR_ASSIGN: token.XOR, token.SHL_ASSIGN: token.SHL,
+				token.SHR_ASSIGN: token.SHR,
+			}[s.Tok]
+			var err error
+			if v, err = binary(s.TokPos, op, *old, v); err != nil {
+				return err
+			}
+		}
+		if ident.Name == "_" {
+			continue
+		}
+		old, ok := f.lookup(ident.Name)
+		if !ok {
+			return &unsupportedError{lhs.Pos(), "assigning to " + ident.Name}
+		}
+		if !old.isBool && !v.isBool {
+			v.bits = old.bits
+			v.n = wrap(v.n, v.bits)
+		}
+		*old = v
+	}
+	return nil
+}
+
+// typed gives an untyped integer constant the default width of its Go
+// type, uint64 here since the subset has no signed integers.
+func typed(v value) value {
+	if !v.isBool && v.bits == 0 {
+		v.bits = 64
+	}
+	return v
+}
+
+// zero returns the zero value of typ.
+func zero(typ ast.Expr) (value, bool) {
+	ident, ok := typ.(*ast.Ident)
+	if !ok {
+		return value{}, false
+	}
+	if ident.Name == "bool" {
+		return boolValue(false), true
+	}
+	if bits, ok := intTypes[ident.Name]; ok {
+		return value{bits: bits}, true
+	}
+	return value{}, false
+}
+
+// convert converts v to typ, as a conversion or an assignment to a
+// variable of that type does.
+func (in *interp) convert(typ ast.Expr, v value) (value, error) {
+	ident, ok := typ.(*ast.Ident)
+	if !ok {
+		return value{}, &unsupportedError{typ.Pos(), "this type"}
+	}
+	if ident.Name == "bool" {
+		if !v.isBool {
+			return value{}, &unsupportedError{typ.Pos(), "converting an integer to bool"}
+		}
+		return v, nil
+	}
+	bits, ok := intTypes[ident.Name]
+	if !ok || v.isBool {
+		return value{}, &unsupportedError{typ.Pos(), "type " + ident.Name}
+	}
+	return value{bits: bits, n: wrap(v.n, bits)}, nil
+}
+
+func (in *interp) expr(f *frame, expr ast.Expr) (value, error) {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return in.expr(f, e.X)
+	case *ast.BasicLit:
+		if e.Kind != token.INT {
+			return value{}, &unsupportedError{e.Pos(), "this literal"}
+		}
+		n, ok := constant.Uint64Val(constant.MakeFromLiteral(e.Value, e.Kind, 0))
+		if !ok {
+			return value{}, &unsupportedError{e.Pos(), "an integer wider than 64 bits"}
+		}
+		return value{n: n}, nil
+	case *ast.Ident:
+		if v, ok := f.lookup(e.Name); ok {
+			return *v, nil
+		}
+		switch e.Name {
+		case "true":
+			return boolValue(true), nil
+		case "false":
+			return boolValue(false), nil
+		}
+		return in.constant(e)
+	case *ast.UnaryExpr:
+		x, err := in.expr(f, e.X)
+		if err != nil {
+			return value{}, err
+		}
+		switch e.Op {
+		case token.NOT:
+			return boolValue(!x.bool()), nil
+		case token.XOR:
+			return value{bits: x.bits, n: wrap(^x.n, x.bits)}, nil
+		case token.SUB:
+			return value{bits: x.bits, n: wrap(-x.n, x.bits)}, nil
+		case token.ADD:
+			return x, nil
+		}
+	case *ast.BinaryExpr:
+		x, err := in.expr(f, e.X)
+		if err != nil {
+			return value{}, err
+		}
+		switch e.Op {
+		case token.LAND:
+			if !x.bool() {
+				return x, nil
+			}
+			return in.expr(f, e.Y)
+		case token.LOR:
+			if x.bool() {
+				return x, nil
+			}
+			return in.expr(f, e.Y)
+		}
+		y, err := in.expr(f, e.Y)
+		if err != nil {
+			return value{}, err
+		}
+		return binary(e.OpPos, e.Op, x, y)
+	case *ast.CallExpr:
+		return in.callExpr(f, e)
+	}
+	return value{}, &unsupportedError{expr.Pos(), fmt.Sprintf("%T", expr)}
+}
+
+// constant evaluates the package constant ident names.
+func (in *interp) constant(ident *ast.Ident) (value, error) {
+	if v, ok := in.cache[ident.Name]; ok {
+		return v, nil
+	}
+	init, ok := in.consts[ident.Name]
+	if !ok {
+		return value{}, &unsupportedError{ident.Pos(), "identifier " + ident.Name}
+	}
+	// Constants do not refer to locals, and Go rejects cycles among them.
+	v, err := in.expr(&frame{}, init)
+	if err != nil {
+		return value{}, err
+	}
+	if typ, ok := in.types[ident.Name]; ok {
+		if v, err = in.convert(typ, v); err != nil {
+			return value{}, err
+		}
+	}
+	in.cache[ident.Name] = v
+	return v, nil
+}
+
+// binary applies op to x and y. An untyped constant takes the width of
+// the other operand.
+func binary(pos token.Pos, op token.Token, x, y value) (value, error) {
+	if x.isBool != y.isBool && op != token.SHL && op != token.SHR {
+		return value{}, &unsupportedError{pos, "mixing bool and integer operands"}
+	}
+	if x.isBool {
+		switch op {
+		case token.EQL:
+			return boolValue(x.n == y.n), nil
+		case token.NEQ:
+			return boolValue(x.n != y.n), nil
+		}
+		return value{}, &unsupportedError{pos, op.String() + " on bools"}
+	}
+	width := x.bits
+	if width == 0 {
+		width = y.bits
+	}
+	if x.bits != 0 && y.bits != 0 && x.bits != y.bits && op != token.SHL && op != token.SHR {
+		return value{}, &unsupportedError{pos, "operands of different widths"}
+	}
+	n := func(n uint64) (value, error) { return value{bits: width, n: wrap(n, width)}, nil }
+	switch op {
+	case token.ADD:
+		return n(x.n + y.n)
+	case token.SUB:
+		return n(x.n - y.n)
+	case token.MUL:
+		return n(x.n * y.n)
+	case token.QUO, token.REM:
+		if y.n == 0 {
+			return value{}, &failure{pos, "integer divide by zero"}
+		}
+		if op == token.QUO {
+			return n(x.n / y.n)
+		}
+		return n(x.n % y.n)
+	case token.AND:
+		return n(x.n & y.n)
+	case token.OR:
+		return n(x.n | y.n)
+	case token.XOR:
+		return n(x.n ^ y.n)
+	case token.AND_NOT:
+		return n(x.n &^ y.n)
+	case token.SHL, token.SHR:
+		width = x.bits
+		if y.n >= 64 {
+			return n(0)
+		}
+		if op == token.SHL {
+			return n(x.n << y.n)
+		}
+		return n(x.n >> y.n)
+	case token.EQL:
+		return boolValue(x.n == y.n), nil
+	case token.NEQ:
+		return boolValue(x.n != y.n), nil
+	case token.LSS:
+		return boolValue(x.n < y.n), nil
+	case token.LEQ:
+		return boolValue(x.n <= y.n), nil
+	case token.GTR:
+		return boolValue(x.n > y.n), nil
+	case token.GEQ:
+		return boolValue(x.n >= y.n), nil
+	}
+	return value{}, &unsupportedError{pos, "operator " + op.String()}
+}
+
+func (in *interp) args(f *frame, call *ast.CallExpr) ([]value, error) {
+	if call.Ellipsis.IsValid() {
+		return nil, &unsupportedError{call.Ellipsis, "a variadic call"}
+	}
+	args := make([]value, len(call.Args))
+	for i, arg := range call.Args {
+		v, err := in.expr(f, arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return args, nil
+}
+
+func (in *interp) callExpr(f *frame, call *ast.CallExpr) (value, error) {
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		if _, local := f.lookup(fun.Name); local {
+			break
+		}
+		if fun.Name == "panic" {
+			return value{}, &failure{call.Pos(), "panic"}
+		}
+		if _, ok := intTypes[fun.Name]; ok || fun.Name == "bool" {
+			if len(call.Args) != 1 {
+				break
+			}
+			v, err := in.expr(f, call.Args[0])
+			if err != nil {
+				return value{}, err
+			}
+			return in.convert(fun, v)
+		}
+		fn, ok := in.funcs[fun.Name]
+		if !ok || fn.Type.TypeParams != nil {
+			break
+		}
+		args, err := in.args(f, call)
+		if err != nil {
+			return value{}, err
+		}
+		if want := countParams(fn); want != len(args) {
+			return value{}, &unsupportedError{call.Pos(), fmt.Sprintf("calling %s with %d arguments", fun.Name, len(args))}
+		}
+		return in.call(fn, args)
+	case *ast.SelectorExpr:
+		pkg, ok := fun.X.(*ast.Ident)
+		if !ok {
+			break
+		}
+		args, err := in.args(f, call)
+		if err != nil {
+			return value{}, err
+		}
+		switch {
+		case in.jet[pkg.Name]:
+			return jetCall(call, fun.Sel.Name, args)
+		case in.std[pkg.Name]:
+			return stdCall(call, fun.Sel.Name, args)
+		}
+	}
+	return value{}, &unsupportedError{call.Pos(), "this call"}
+}
+
+func countParams(fn *ast.FuncDecl) int {
+	n := 0
+	for _, field := range fn.Type.Params.List {
+		n += len(field.Names)
+	}
+	return n
+}
+
+// jetCall runs jet.name: Verify and the arithmetic, comparison and bitwise
+// jets, whose Go functions return the value without the carry or borrow.
+func jetCall(call *ast.CallExpr, name string, args []value) (value, error) {
+	if name == "Verify" && len(args) == 1 && args[0].isBool {
+		if !args[0].bool() {
+			return value{}, &failure{call.Pos(), "jet.Verify failed"}
+		}
+		return value{}, nil
+	}
+	m := jetOp.FindStringSubmatch(name)
+	if m == nil {
+		return value{}, &unsupportedError{call.Pos(), "jet." + name}
+	}
+	width, _ := strconv.Atoi(m[2])
+	want := 2
+	if m[1] == "Complement" {
+		want = 1
+	}
+	if len(args) != want {
+		return value{}, &unsupportedError{call.Pos(), fmt.Sprintf("jet.%s with %d arguments", name, len(args))}
+	}
+	for i, a := range args {
+		if a.isBool || (a.bits != 0 && a.bits != width) {
+			return value{}, &unsupportedError{call.Args[i].Pos(), fmt.Sprintf("a %s argument of jet.%s", describe(a), name)}
+		}
+		args[i] = value{bits: width, n: wrap(a.n, width)}
+	}
+	x := args[0]
+	w := func(n uint64) (value, error) { return value{bits: width, n: wrap(n, width)}, nil }
+	switch m[1] {
+	case "Complement":
+		return w(^x.n)
+	case "Multiply":
+		if width == 64 {
+			return value{}, &unsupportedError{call.Pos(), "jet.Multiply64, whose result is 128 bits"}
+		}
+		return value{bits: 2 * width, n: x.n * args[1].n}, nil
+	}
+	y := args[1]
+	switch m[1] {
+	case "Le":
+		return boolValue(x.n <= y.n), nil
+	case "Lt":
+		return boolValue(x.n < y.n), nil
+	case "Eq":
+		return boolValue(x.n == y.n), nil
+	case "Add":
+		return w(x.n + y.n)
+	case "Subtract":
+		return w(x.n - y.n)
+	case "And":
+		return w(x.n & y.n)
+	case "Or":
+		return w(x.n | y.n)
+	case "Xor":
+		return w(x.n ^ y.n)
+	case "Max":
+		return w(max(x.n, y.n))
+	default: // Min
+		return w(min(x.n, y.n))
+	}
+}
+
+func describe(v value) string {
+	if v.isBool {
+		return "bool"
+	}
+	return "uint" + strconv.Itoa(v.bits)
+}
+
+// stdCall runs the std helpers over bools: Assert, Verify, Threshold and
+// Unreachable.
+func stdCall(call *ast.CallExpr, name string, args []value) (value, error) {
+	switch name {
+	case "Assert", "Verify":
+		if len(args) == 1 && args[0].isBool {
+			if !args[0].bool() {
+				return value{}, &failure{call.Pos(), "std." + name + " failed"}
+			}
+			if name == "Verify" {
+				return args[0], nil
+			}
+			return value{}, nil
+		}
+	case "Threshold":
+		if len(args) > 0 && !args[0].isBool {
+			count := 0
+			for _, a := range args[1:] {
+				if !a.isBool {
+					return value{}, &unsupportedError{call.Pos(), "std.Threshold of non-bool conditions"}
+				}
+				if a.bool() {
+					count++
+				}
+			}
+			return boolValue(uint64(count) >= args[0].n), nil
+		}
+	case "Unreachable":
+		return value{}, &failure{call.Pos(), "std.Unreachable reached"}
+	}
+	return value{}, &unsupportedError{call.Pos(), "std." + name}
+}
+
+// run calls fn with args and reports whether the spend it describes is
+// accepted: a function with a result must return true, and any function
+// must not fail on the way.
+func (in *interp) run(fn *ast.FuncDecl, args []value) (bool, string, error) {
+	in.steps = 0
+	result, err := in.call(fn, args)
+	var fail *failure
+	if errors.As(err, &fail) {
+		return false, fail.reason, nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	if fn.Type.Results != nil && !result.bool() {
+		return false, fn.Name.Name + " returned false", nil
+	}
+	return true, "", nil
+}
@@ -0,0 +1,458 @@
+package eval
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+)
+
+// Options configures a run.
+type Options struct {
+	// Witness overrides constants of the witness module, keyed by name.
+	// Values use SimplicityHL literal syntax, e.g. "0x01ab", "Left(5)".
+	Witness map[string]string
+	// Tx is the spending transaction read by introspection jets. Without
+	// it those jets fail with a *ContextError.
+	Tx *Tx
+}
+
+// Rejection reports that the program ran into a failing check: an
+// assertion, jet::verify, a signature check, or an unwrap of the wrong
+// variant.
+type Rejection struct {
+	Line   int // Line of the failing expression in the SimplicityHL source
+	Reason string
+}
+
+func (r *Rejection) Error() string {
+	return fmt.Sprintf("line %d: %s", r.Line, r.Reason)
+}
+
+// ContextError reports a jet that reads the spending transaction when no
+// transaction environment was given.
+type ContextError struct {
+	Line int
+	Jet  string
+}
+
+func (e *ContextError) Error() string {
+	return fmt.Sprintf("line %d: jet::%s requires -tx context", e.Line, e.Jet)
+}
+
+// maxCallDepth bounds nested function calls. SimplicityHL has no recursion,
+// so hitting it means the program is malformed.
+const maxCallDepth = 256
+
+type machine struct {
+	prog    *shlparse.Program
+	modules map[string]map[string]Value
+	tx      *Tx
+	depth   int
+}
+
+type scope struct {
+	vars   map[string]Value
+	parent *scope
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{vars: make(map[string]Value), parent: parent}
+}
+
+func (s *scope) lookup(name string) (Value, bool) {
+	for ; s != nil; s = s.parent {
+		if v, ok := s.vars[name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// Run evaluates the main function of prog. It returns nil when the program
+// accepts, a *Rejection when a check fails, and any other error when the
+// program cannot be evaluated.
+func Run(prog *shlparse.Program, opts Options) error {
+	m := &machine{prog: prog, modules: make(map[string]map[string]Value), tx: opts.Tx}
+	for _, mod := range prog.Modules {
+		consts := make(map[string]Value)
+		for _, c := range mod.Consts {
+			v, err := m.eval(c.Value, newScope(nil))
+			if err != nil {
+				return fmt.Errorf("%s::%s: %w", mod.Name, c.Name, err)
+			}
+			consts[c.Name] = v
+		}
+		m.modules[mod.Name] = consts
+	}
+
+	// Sorted, so that the first error reported does not vary between runs.
+	names := make([]string, 0, len(opts.Witness))
+	for name := range opts.Witness {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		text := opts.Witness[name]
+		witness, ok := m.modules["witness"]
+		if !ok {
+			return fmt.Errorf("unknown witness %s: program has no witness module", name)
+		}
+		if _, ok := witness[name]; !ok {
+			return fmt.Errorf("unknown witness %s", name)
+		}
+		expr, err := shlparse.ParseExpr(text)
+		if err != nil {
+			return fmt.Errorf("witness %s: %w", name, err)
+		}
+		v, err := m.eval(expr, newScope(nil))
+		if err != nil {
+			return fmt.Errorf("witness %s: %w", name, err)
+		}
+		witness[name] = v
+	}
+
+	main := prog.Func("main")
+	if main == nil {
+		return fmt.Errorf("program has no main function")
+	}
+	_, err := m.eval(main.Body, newScope(nil))
+	return err
+}
+
+func (m *machine) eval(expr shlparse.Expr, sc *scope) (Value, error) {
+	switch e := expr.(type) {
+	case *shlparse.Literal:
+		return parseLiteral(e)
+	case *shlparse.BoolLit:
+		return e.Value, nil
+	case *shlparse.Ident:
+		if e.Name == "None" {
+			return Sum{Right: false, V: Unit{}}, nil
+		}
+		if v, ok := sc.lookup(e.Name); ok {
+			return v, nil
+		}
+		return nil, fmt.Errorf("line %d: undefined variable %s", e.Line, e.Name)
+	case *shlparse.Path:
+		if v, ok := m.modules[e.Module][e.Name]; ok {
+			return v, nil
+		}
+		return nil, fmt.Errorf("line %d: undefined constant %s::%s", e.Line, e.Module, e.Name)
+	case *shlparse.Tuple:
+		if len(e.Elems) == 0 {
+			return Unit{}, nil
+		}
+		elems, err := m.evalList(e.Elems, sc)
+		return Tuple(elems), err
+	case *shlparse.Array:
+		elems, err := m.evalList(e.Elems, sc)
+		return Array(elems), err
+	case *shlparse.Cast:
+		v, err := m.eval(e.Arg, sc)
+		if err != nil {
+			return nil, err
+		}
+		return m.cast(e, v)
+	case *shlparse.Match:
+		return m.match(e, sc)
+	case *shlparse.Block:
+		return m.block(e, sc)
+	case *shlparse.Call:
+		return m.call(e, sc)
+	}
+	return nil, fmt.Errorf("line %d: unsupported expression", shlparse.Line(expr))
+}
+
+func (m *machine) evalList(exprs []shlparse.Expr, sc *scope) ([]Value, error) {
+	values := make([]Value, len(exprs))
+	for i, x := range exprs {
+		v, err := m.eval(x, sc)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func parseLiteral(lit *shlparse.Literal) (Value, error) {
+	text := strings.ReplaceAll(lit.Text, "_", "")
+	base, digits, bits := 10, text, 0
+	switch {
+	case strings.HasPrefix(text, "0x"):
+		base, digits = 16, text[2:]
+		bits = 4 * len(digits)
+	case strings.HasPrefix(text, "0b"):
+		base, digits = 2, text[2:]
+		bits = len(digits)
+	}
+	v, ok := new(big.Int).SetString(digits, base)
+	if !ok {
+		return nil, fmt.Errorf("line %d: invalid literal %s", lit.Line, lit.Text)
+	}
+	return Word{Bits: bits, V: v}, nil
+}
+
+func (m *machine) block(b *shlparse.Block, parent *scope) (Value, error) {
+	sc := newScope(parent)
+	for _, stmt := range b.Stmts {
+		switch s := stmt.(type) {
+		case *shlparse.Let:
+			v, err := m.eval(s.Value, sc)
+			if err != nil {
+				return nil, err
+			}
+			if err := bind(s.Pattern, v, sc, s.Line); err != nil {
+				return nil, err
+			}
+		case *shlparse.ExprStmt:
+			if _, err := m.eval(s.X, sc); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if b.Result == nil {
+		return Unit{}, nil
+	}
+	return m.eval(b.Result, sc)
+}
+
+func bind(pat shlparse.Pattern, v Value, sc *scope, line int) error {
+	switch p := pat.(type) {
+	case *shlparse.IdentPattern:
+		if p.Name != "_" {
+			sc.vars[p.Name] = v
+		}
+		return nil
+	case *shlparse.TuplePattern:
+		tuple, ok := v.(Tuple)
+		if !ok || len(tuple) != len(p.Elems) {
+			return fmt.Errorf("line %d: cannot destructure %s into %d elements", line, Format(v), len(p.Elems))
+		}
+		for i, elem := range p.Elems {
+			if err := bind(elem, tuple[i], sc, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *shlparse.ArrayPattern:
+		elems, ok := arrayElems(v, len(p.Elems))
+		if !ok {
+			return fmt.Errorf("line %d: cannot destructure %s into %d elements", line, Format(v), len(p.Elems))
+		}
+		for i, elem := range p.Elems {
+			if err := bind(elem, elems[i], sc, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("line %d: unsupported pattern", line)
+}
+
+// arrayElems returns the n elements of the array v. An array written as
+// one hex literal, as byte arrays are, splits into n equal words, the
+// first element in the most significant bits.
+func arrayElems(v Value, n int) ([]Value, bool) {
+	switch a := v.(type) {
+	case Array:
+		return a, len(a) == n
+	case Word:
+		if n == 0 || a.Bits == 0 || a.Bits%n != 0 {
+			return nil, false
+		}
+		bits := a.Bits / n
+		mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(bits)), big.NewInt(1))
+		elems := make([]Value, n)
+		for i := range elems {
+			shift := uint(bits * (n - 1 - i))
+			elems[i] = Word{Bits: bits, V: new(big.Int).And(new(big.Int).Rsh(a.V, shift), mask)}
+		}
+		return elems, true
+	}
+	return nil, false
+}
+
+func (m *machine) match(e *shlparse.Match, sc *scope) (Value, error) {
+	v, err := m.eval(e.Scrutinee, sc)
+	if err != nil {
+		return nil, err
+	}
+	for _, arm := range e.Arms {
+		var payload Value
+		switch s := v.(type) {
+		case bool:
+			if (arm.Ctor == "true") != s || (arm.Ctor != "true" && arm.Ctor != "false") {
+				continue
+			}
+		case Sum:
+			right := arm.Ctor == "Right" || arm.Ctor == "Some"
+			if right != s.Right || arm.Ctor == "true" || arm.Ctor == "false" {
+				continue
+			}
+			payload = s.V
+		default:
+			return nil, fmt.Errorf("line %d: cannot match on %s", e.Line, Format(v))
+		}
+
+		armScope := newScope(sc)
+		if arm.Binding != nil {
+			if err := bind(arm.Binding, payload, armScope, arm.Line); err != nil {
+				return nil, err
+			}
+		}
+		return m.eval(arm.Body, armScope)
+	}
+	return nil, fmt.Errorf("line %d: no match arm for %s", e.Line, Format(v))
+}
+
+func (m *machine) call(e *shlparse.Call, sc *scope) (Value, error) {
+	args, err := m.evalList(e.Args, sc)
+	if err != nil {
+		return nil, err
+	}
+	arity := func(n int) error {
+		if len(args) != n {
+			return fmt.Errorf("line %d: %s takes %d arguments, got %d", e.Line, e.Func, n, len(args))
+		}
+		return nil
+	}
+
+	switch e.Func {
+	case "assert!":
+		if err := arity(1); err != nil {
+			return nil, err
+		}
+		ok, err := asBool(args[0], e.Line)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, &Rejection{Line: e.Line, Reason: "assertion failed"}
+		}
+		return Unit{}, nil
+	case "panic!":
+		return nil, &Rejection{Line: e.Line, Reason: "panic"}
+	case "Left", "Right", "Some":
+		if err := arity(1); err != nil {
+			return nil, err
+		}
+		return Sum{Right: e.Func != "Left", V: args[0]}, nil
+	case "unwrap", "unwrap_left", "unwrap_right":
+		if err := arity(1); err != nil {
+			return nil, err
+		}
+		s, ok := args[0].(Sum)
+		if !ok {
+			return nil, fmt.Errorf("line %d: %s of non-sum value %s", e.Line, e.Func, Format(args[0]))
+		}
+		wantRight := e.Func != "unwrap_left"
+		if s.Right != wantRight {
+			reason := map[string]string{
+				"unwrap":       "unwrap of None",
+				"unwrap_left":  "unwrap_left of a Right value",
+				"unwrap_right": "unwrap_right of a Left value",
+			}[e.Func]
+			return nil, &Rejection{Line: e.Line, Reason: reason}
+		}
+		return s.V, nil
+	}
+
+	if name, ok := strings.CutPrefix(e.Func, "jet::"); ok {
+		return m.jet(name, args, e.Line)
+	}
+
+	fn := m.prog.Func(e.Func)
+	if fn == nil {
+		return nil, fmt.Errorf("line %d: undefined function %s", e.Line, e.Func)
+	}
+	if err := arity(len(fn.Params)); err != nil {
+		return nil, err
+	}
+	if m.depth >= maxCallDepth {
+		return nil, fmt.Errorf("line %d: call depth exceeds %d", e.Line, maxCallDepth)
+	}
+	m.depth++
+	defer func() { m.depth-- }()
+
+	fnScope := newScope(nil)
+	for i, p := range fn.Params {
+		fnScope.vars[p.Name] = args[i]
+	}
+	return m.eval(fn.Body, fnScope)
+}
+
+// cast implements <T>::into(x) for the conversions the transpiler emits:
+// splitting an integer into its high and low halves, joining two halves,
+// and viewing a bool as Either<(), ()>.
+func (m *machine) cast(e *shlparse.Cast, v Value) (Value, error) {
+	switch from := m.resolve(e.From).(type) {
+	case *shlparse.UInt:
+		if w, ok := v.(Word); ok && from.Bits >= 2 {
+			half := uint(from.Bits / 2)
+			hi := new(big.Int).Rsh(w.V, half)
+			return Tuple{word(int(half), hi), word(int(half), w.V)}, nil
+		}
+	case *shlparse.Bool:
+		if b, ok := v.(bool); ok {
+			return Sum{Right: b, V: Unit{}}, nil
+		}
+	case *shlparse.TupleType:
+		t, ok := v.(Tuple)
+		if ok && len(from.Elems) == 2 && len(t) == 2 {
+			hiType, hiOK := m.resolve(from.Elems[0]).(*shlparse.UInt)
+			loType, loOK := m.resolve(from.Elems[1]).(*shlparse.UInt)
+			hi, hiW := t[0].(Word)
+			lo, loW := t[1].(Word)
+			if hiOK && loOK && hiW && loW {
+				joined := new(big.Int).Lsh(hi.V, uint(loType.Bits))
+				joined.Or(joined, lo.V)
+				return word(hiType.Bits+loType.Bits, joined), nil
+			}
+		}
+	case *shlparse.ArrayType:
+		// A byte array reads as one big-endian integer. A hex literal is
+		// already one word.
+		switch a := v.(type) {
+		case Word:
+			return a, nil
+		case Array:
+			joined, bits := new(big.Int), 0
+			for _, elem := range a {
+				w, ok := elem.(Word)
+				if !ok {
+					return nil, fmt.Errorf("line %d: unsupported conversion <%s>::into(%s)", e.Line, e.From, Format(v))
+				}
+				joined.Lsh(joined, uint(w.Bits)).Or(joined, w.V)
+				bits += w.Bits
+			}
+			return word(bits, joined), nil
+		}
+	}
+	return nil, fmt.Errorf("line %d: unsupported conversion <%s>::into(%s)", e.Line, e.From, Format(v))
+}
+
+// resolve expands type aliases declared in the program.
+func (m *machine) resolve(t shlparse.Type) shlparse.Type {
+	named, ok := t.(*shlparse.Named)
+	if !ok {
+		return t
+	}
+	for _, a := range m.prog.Aliases {
+		if a.Name == named.Name {
+			return m.resolve(a.Type)
+		}
+	}
+	return t
+}
+
+func asBool(v Value, line int) (bool, error) {
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("line %d: expected bool, got %s", line, Format(v))
+	}
+	return b, nil
+}
@@ -0,0 +1,255 @@
+package eval
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/secp256k1"
+)
+
+// introspectionJets read the spending transaction or its environment.
+var introspectionJets = map[string]bool{
+	"sig_all_hash":          true,
+	"check_lock_height":     true,
+	"check_lock_time":       true,
+	"check_lock_distance":   true,
+	"check_lock_duration":   true,
+	"tx_is_final":           true,
+	"tx_lock_height":        true,
+	"tx_lock_time":          true,
+	"tx_lock_distance":      true,
+	"tx_lock_duration":      true,
+	"lock_time":             true,
+	"version":               true,
+	"transaction_id":        true,
+	"genesis_block_hash":    true,
+	"num_inputs":            true,
+	"num_outputs":           true,
+	"current_index":         true,
+	"current_prev_outpoint": true,
+	"current_script_hash":   true,
+	"current_sequence":      true,
+	"current_annex_hash":    true,
+	"current_asset":         true,
+	"current_amount":        true,
+	"input_prev_outpoint":   true,
+	"input_script_hash":     true,
+	"input_asset":           true,
+	"input_amount":          true,
+	"output_script_hash":    true,
+	"output_asset":          true,
+	"output_amount":         true,
+	"total_fee":             true,
+	"internal_key":          true,
+	"tapleaf_version":       true,
+	"tappath":               true,
+	"script_cmr":            true,
+	"issuance":              true,
+	"issuance_asset_amount": true,
+	"issuance_token_amount": true,
+	"new_issuance_contract": true,
+	"reissuance_blinding":   true,
+	"reissuance_entropy":    true,
+	"input_pegin":           true,
+	"current_pegin":         true,
+}
+
+// sizedJet matches the word-size suffix of arithmetic jets like add_32.
+var sizedJet = regexp.MustCompile(`^([a-z_]+)_(8|16|32|64|128|256)$`)
+
+func (m *machine) jet(name string, args []Value, line int) (Value, error) {
+	if introspectionJets[name] {
+		if m.tx == nil {
+			return nil, &ContextError{Line: line, Jet: name}
+		}
+		return m.txJet(name, args, line)
+	}
+	arity := func(n int) error {
+		if len(args) != n {
+			return fmt.Errorf("line %d: jet::%s takes %d arguments, got %d", line, name, n, len(args))
+		}
+		return nil
+	}
+
+	switch name {
+	case "verify":
+		if err := arity(1); err != nil {
+			return nil, err
+		}
+		ok, err := asBool(args[0], line)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, &Rejection{Line: line, Reason: "jet::verify failed"}
+		}
+		return Unit{}, nil
+	case "sha_256_ctx_8_init":
+		if err := arity(0); err != nil {
+			return nil, err
+		}
+		return &shaCtx{}, nil
+	case "sha_256_ctx_8_finalize":
+		if err := arity(1); err != nil {
+			return nil, err
+		}
+		ctx, ok := args[0].(*shaCtx)
+		if !ok {
+			return nil, fmt.Errorf("line %d: jet::%s expects a SHA-256 context", line, name)
+		}
+		sum := sha256.Sum256(ctx.data)
+		return Word{Bits: 256, V: new(big.Int).SetBytes(sum[:])}, nil
+	case "bip_0340_verify":
+		if err := arity(2); err != nil {
+			return nil, err
+		}
+		keyMsg, ok := args[0].(Tuple)
+		if !ok || len(keyMsg) != 2 {
+			return nil, fmt.Errorf("line %d: jet::%s expects ((pubkey, msg), sig)", line, name)
+		}
+		pk, err := wordArg(keyMsg[0], line)
+		if err != nil {
+			return nil, err
+		}
+		msg, err := wordArg(keyMsg[1], line)
+		if err != nil {
+			return nil, err
+		}
+		sig, err := wordArg(args[1], line)
+		if err != nil {
+			return nil, err
+		}
+		if !secp256k1.VerifySchnorr(bytesOf(pk, 32), bytesOf(msg, 32), bytesOf(sig, 64)) {
+			return nil, &Rejection{Line: line, Reason: "jet::bip_0340_verify: invalid signature"}
+		}
+		return Unit{}, nil
+	}
+
+	if n, ok := strings.CutPrefix(name, "sha_256_ctx_8_add_"); ok {
+		size, err := strconv.Atoi(n)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: unknown jet jet::%s", line, name)
+		}
+		if err := arity(2); err != nil {
+			return nil, err
+		}
+		ctx, ok := args[0].(*shaCtx)
+		if !ok {
+			return nil, fmt.Errorf("line %d: jet::%s expects a SHA-256 context", line, name)
+		}
+		data, err := wordArg(args[1], line)
+		if err != nil {
+			return nil, err
+		}
+		next := append(append([]byte(nil), ctx.data...), bytesOf(data, size)...)
+		return &shaCtx{data: next}, nil
+	}
+
+	if sub := sizedJet.FindStringSubmatch(name); sub != nil {
+		bits, _ := strconv.Atoi(sub[2])
+		return m.wordJet(sub[1], bits, name, args, line)
+	}
+	return nil, fmt.Errorf("line %d: jet::%s is not supported by the built-in evaluator", line, name)
+}
+
+// wordJet implements the fixed-width integer jets: op is the jet name
+// without its size suffix.
+func (m *machine) wordJet(op string, bits int, name string, args []Value, line int) (Value, error) {
+	unary := map[string]bool{"complement": true, "is_zero": true}
+	ternary := map[string]bool{"full_add": true, "full_subtract": true}
+	n := 2
+	switch {
+	case unary[op]:
+		n = 1
+	case ternary[op]:
+		n = 3
+	}
+	if len(args) != n {
+		return nil, fmt.Errorf("line %d: jet::%s takes %d arguments, got %d", line, name, n, len(args))
+	}
+
+	// full_add and full_subtract take the incoming carry or borrow first.
+	carry := big.NewInt(0)
+	if ternary[op] {
+		c, err := asBool(args[0], line)
+		if err != nil {
+			return nil, err
+		}
+		if c {
+			carry.SetInt64(1)
+		}
+		args = args[1:]
+	}
+	words := make([]*big.Int, len(args))
+	for i, a := range args {
+		w, err := wordArg(a, line)
+		if err != nil {
+			return nil, err
+		}
+		words[i] = w.V
+	}
+	a := words[0]
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+
+	switch op {
+	case "add", "full_add":
+		sum := new(big.Int).Add(a, words[1])
+		sum.Add(sum, carry)
+		return Tuple{sum.Cmp(limit) >= 0, word(bits, sum)}, nil
+	case "subtract", "full_subtract":
+		diff := new(big.Int).Sub(a, words[1])
+		diff.Sub(diff, carry)
+		return Tuple{diff.Sign() < 0, word(bits, diff)}, nil
+	case "multiply":
+		return word(2*bits, new(big.Int).Mul(a, words[1])), nil
+	case "divide":
+		if words[1].Sign() == 0 {
+			return word(bits, big.NewInt(0)), nil
+		}
+		return word(bits, new(big.Int).Quo(a, words[1])), nil
+	case "modulo":
+		if words[1].Sign() == 0 {
+			return word(bits, a), nil
+		}
+		return word(bits, new(big.Int).Rem(a, words[1])), nil
+	case "lt":
+		return a.Cmp(words[1]) < 0, nil
+	case "le":
+		return a.Cmp(words[1]) <= 0, nil
+	case "eq":
+		return a.Cmp(words[1]) == 0, nil
+	case "and":
+		return word(bits, new(big.Int).And(a, words[1])), nil
+	case "or":
+		return word(bits, new(big.Int).Or(a, words[1])), nil
+	case "xor":
+		return word(bits, new(big.Int).Xor(a, words[1])), nil
+	case "complement":
+		return word(bits, new(big.Int).Xor(a, mask(bits))), nil
+	case "is_zero":
+		return a.Sign() == 0, nil
+	case "max":
+		if a.Cmp(words[1]) >= 0 {
+			return word(bits, a), nil
+		}
+		return word(bits, words[1]), nil
+	case "min":
+		if a.Cmp(words[1]) <= 0 {
+			return word(bits, a), nil
+		}
+		return word(bits, words[1]), nil
+	}
+	return nil, fmt.Errorf("line %d: jet::%s is not supported by the built-in evaluator", line, name)
+}
+
+func wordArg(v Value, line int) (Word, error) {
+	w, ok := v.(Word)
+	if !ok {
+		return Word{}, fmt.Errorf("line %d: expected an integer, got %s", line, Format(v))
+	}
+	return w, nil
+}
@@ -0,0 +1,538 @@
+package eval
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// Tx describes the spending transaction seen by introspection jets. Hashes
+// and keys are 32-byte hex strings, with or without a 0x prefix.
+//
+// The evaluator does not serialize transactions, so values that are
+// derived from the whole transaction, such as sig_all_hash, are read from
+// the file rather than computed.
+type Tx struct {
+	Chain        string     `json:"chain"`         // liquid (default), liquidtestnet or elements
+	Version      uint32     `json:"version"`       // Default 2
+	LockTime     uint32     `json:"locktime"`      // Block height below 500000000, otherwise a timestamp
+	CurrentIndex uint32     `json:"current_index"` // Input being spent
+	Inputs       []TxInput  `json:"inputs"`
+	Outputs      []TxOutput `json:"outputs"`
+
+	SigAllHash       string `json:"sig_all_hash,omitempty"`
+	TransactionID    string `json:"transaction_id,omitempty"`
+	GenesisBlockHash string `json:"genesis_block_hash,omitempty"`
+	InternalKey      string `json:"internal_key,omitempty"`
+	ScriptCMR        string `json:"script_cmr,omitempty"`
+	TapleafVersion   *uint8 `json:"tapleaf_version,omitempty"`
+}
+
+// TxInput is an input of the spending transaction together with the output
+// it spends.
+type TxInput struct {
+	PrevTxid   string  `json:"prev_txid"`
+	PrevVout   uint32  `json:"prev_vout"`
+	Value      uint64  `json:"value"`
+	Asset      string  `json:"asset,omitempty"` // Default: the chain's policy asset
+	ScriptHash string  `json:"script_hash"`
+	Sequence   *uint32 `json:"sequence,omitempty"`   // Default 0xffffffff
+	AnnexHash  string  `json:"annex_hash,omitempty"` // SHA-256 of the taproot annex; none when empty
+
+	// Confidential blinds the amount: the jets return a commitment in
+	// place of Value.
+	Confidential bool `json:"confidential,omitempty"`
+
+	Issuance *TxIssuance `json:"issuance,omitempty"`
+	Pegin    string      `json:"pegin,omitempty"` // Genesis hash of the parent chain of a peg-in
+}
+
+// TxIssuance is the asset issuance of an input. A reissuance gives the
+// blinding nonce of the reissuance token and the asset entropy; a new
+// issuance gives neither and may name a contract hash.
+type TxIssuance struct {
+	AssetAmount   uint64 `json:"asset_amount"`
+	TokenAmount   uint64 `json:"token_amount"`
+	ContractHash  string `json:"contract_hash,omitempty"` // Default: all zeros
+	BlindingNonce string `json:"blinding_nonce,omitempty"`
+	Entropy       string `json:"entropy,omitempty"`
+}
+
+func (is *TxIssuance) reissuance() bool {
+	return is.BlindingNonce != ""
+}
+
+// TxOutput is an output of the spending transaction. An output whose
+// script hash is that of the empty script, e3b0c442…b855, pays the fee.
+type TxOutput struct {
+	Value      uint64 `json:"value"`
+	Asset      string `json:"asset,omitempty"` // Default: the chain's policy asset
+	ScriptHash string `json:"script_hash"`
+
+	Confidential bool `json:"confidential,omitempty"` // As for TxInput; a fee output cannot be
+}
+
+// policyAssets holds the asset each chain pays fees in. Regtest chains mint
+// their own, so elements has none and every asset must be given explicitly.
+var policyAssets = map[string]string{
+	"liquid":        "6f0279e9ed041c3d710a9f57d0c02928416460c4b722ae3457a11eec381c526d",
+	"liquidtestnet": "144c654344aa716d6f3abcc1ca90e5641e4e2a7f633bc09fe3baf64585819a49",
+	"elements":      "",
+}
+
+// arrayIndex matches the ".0" element steps encoding/json uses in field
+// paths, which ParseTx reports as "[0]".
+var arrayIndex = regexp.MustCompile(`\.(\d+)`)
+
+const (
+	finalSequence  = 0xffffffff
+	lockTimeCutoff = 500000000
+	zeroHash       = "0000000000000000000000000000000000000000000000000000000000000000"
+)
+
+// ParseTx decodes and validates a transaction environment. Errors name the
+// offending field, e.g. "inputs[1].asset".
+func ParseTx(data []byte) (*Tx, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var tx Tx
+	if err := dec.Decode(&tx); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			field := arrayIndex.ReplaceAllString(typeErr.Field, "[$1]")
+			return nil, fmt.Errorf("%s: expected %s, got %s", field, typeErr.Type, typeErr.Value)
+		}
+		return nil, err
+	}
+	if err := tx.validate(); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+func (tx *Tx) validate() error {
+	if tx.Chain == "" {
+		tx.Chain = "liquid"
+	}
+	policy, ok := policyAssets[tx.Chain]
+	if !ok {
+		return fmt.Errorf("chain: unsupported chain %q (want liquid, liquidtestnet or elements)", tx.Chain)
+	}
+	if tx.Version == 0 {
+		tx.Version = 2
+	}
+	if len(tx.Inputs) == 0 {
+		return fmt.Errorf("inputs: at least one input is required")
+	}
+	if int(tx.CurrentIndex) >= len(tx.Inputs) {
+		return fmt.Errorf("current_index: %d is out of range for %d input(s)", tx.CurrentIndex, len(tx.Inputs))
+	}
+
+	for i := range tx.Inputs {
+		in := &tx.Inputs[i]
+		field := fmt.Sprintf("inputs[%d]", i)
+		if err := checkHash(field+".prev_txid", in.PrevTxid, true); err != nil {
+			return err
+		}
+		if err := checkHash(field+".script_hash", in.ScriptHash, true); err != nil {
+			return err
+		}
+		if err := checkHash(field+".annex_hash", in.AnnexHash, false); err != nil {
+			return err
+		}
+		if err := defaultAsset(field, &in.Asset, policy, tx.Chain); err != nil {
+			return err
+		}
+		if err := checkHash(field+".pegin", in.Pegin, false); err != nil {
+			return err
+		}
+		if in.Issuance != nil {
+			if err := in.Issuance.validate(field + ".issuance"); err != nil {
+				return err
+			}
+		}
+		if in.Sequence == nil {
+			seq := uint32(finalSequence)
+			in.Sequence = &seq
+		}
+	}
+	for i := range tx.Outputs {
+		out := &tx.Outputs[i]
+		field := fmt.Sprintf("outputs[%d]", i)
+		if err := checkHash(field+".script_hash", out.ScriptHash, true); err != nil {
+			return err
+		}
+		if err := defaultAsset(field, &out.Asset, policy, tx.Chain); err != nil {
+			return err
+		}
+		if out.Confidential && hashWord(out.ScriptHash).V.Cmp(emptyScriptHash.V) == 0 {
+			return fmt.Errorf("%s.confidential: a fee output has an explicit value", field)
+		}
+	}
+
+	for _, h := range []struct{ field, value string }{
+		{"sig_all_hash", tx.SigAllHash},
+		{"transaction_id", tx.TransactionID},
+		{"genesis_block_hash", tx.GenesisBlockHash},
+		{"internal_key", tx.InternalKey},
+		{"script_cmr", tx.ScriptCMR},
+	} {
+		if err := checkHash(h.field, h.value, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (is *TxIssuance) validate(field string) error {
+	for _, h := range []struct{ field, value string }{
+		{"contract_hash", is.ContractHash},
+		{"blinding_nonce", is.BlindingNonce},
+		{"entropy", is.Entropy},
+	} {
+		if err := checkHash(field+"."+h.field, h.value, false); err != nil {
+			return err
+		}
+	}
+	switch {
+	case is.reissuance() && is.Entropy == "":
+		return fmt.Errorf("%s.entropy: required for a reissuance", field)
+	case is.reissuance() && is.ContractHash != "":
+		return fmt.Errorf("%s.contract_hash: only a new issuance has one", field)
+	case !is.reissuance() && is.Entropy != "":
+		return fmt.Errorf("%s.entropy: a new issuance derives its entropy; give blinding_nonce for a reissuance", field)
+	}
+	if is.ContractHash == "" && !is.reissuance() {
+		is.ContractHash = zeroHash
+	}
+	return nil
+}
+
+func defaultAsset(field string, asset *string, policy, chain string) error {
+	if *asset == "" {
+		if policy == "" {
+			return fmt.Errorf("%s.asset: required on chain %s, which has no default policy asset", field, chain)
+		}
+		*asset = policy
+	}
+	return checkHash(field+".asset", *asset, true)
+}
+
+func checkHash(field, value string, required bool) error {
+	if value == "" {
+		if required {
+			return fmt.Errorf("%s: required", field)
+		}
+		return nil
+	}
+	if _, err := parseHash(value); err != nil {
+		return fmt.Errorf("%s: %v", field, err)
+	}
+	return nil
+}
+
+func parseHash(s string) (*big.Int, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil || len(b) != 32 {
+		return nil, fmt.Errorf("expected 32-byte hex, got %q", s)
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// emptyScriptHash is the script hash of a fee output, whose script is
+// empty.
+var emptyScriptHash = hashWord("e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+
+// hashWord converts a validated hash to a u256.
+func hashWord(s string) Word {
+	v, _ := parseHash(s)
+	return Word{Bits: 256, V: v}
+}
+
+func u32(v uint32) Word {
+	return Word{Bits: 32, V: new(big.Int).SetUint64(uint64(v))}
+}
+
+func u64(v uint64) Word {
+	return Word{Bits: 64, V: new(big.Int).SetUint64(v)}
+}
+
+func u16(v uint32) Word {
+	return Word{Bits: 16, V: new(big.Int).SetUint64(uint64(v))}
+}
+
+// explicit wraps an unblinded value in the Right arm of a confidential type
+// such as Asset1 or Amount1.
+func explicit(v Value) Value {
+	return Sum{Right: true, V: v}
+}
+
+func some(v Value) Value {
+	return Sum{Right: true, V: v}
+}
+
+func none() Value {
+	return Sum{Right: false, V: Unit{}}
+}
+
+func (tx *Tx) final() bool {
+	for _, in := range tx.Inputs {
+		if *in.Sequence != finalSequence {
+			return false
+		}
+	}
+	return true
+}
+
+func (tx *Tx) lockHeight() uint32 {
+	if tx.final() || tx.LockTime >= lockTimeCutoff {
+		return 0
+	}
+	return tx.LockTime
+}
+
+func (tx *Tx) lockTime() uint32 {
+	if tx.final() || tx.LockTime < lockTimeCutoff {
+		return 0
+	}
+	return tx.LockTime
+}
+
+// relativeLock returns the largest BIP-68 relative lock over all inputs,
+// counted in blocks (duration false) or 512-second units (duration true).
+func (tx *Tx) relativeLock(duration bool) uint32 {
+	if tx.Version < 2 {
+		return 0
+	}
+	var max uint32
+	for _, in := range tx.Inputs {
+		seq := *in.Sequence
+		if seq&(1<<31) != 0 || (seq&(1<<22) != 0) != duration {
+			continue
+		}
+		if v := seq & 0xffff; v > max {
+			max = v
+		}
+	}
+	return max
+}
+
+func (tx *Tx) input(i int) Value {
+	in := tx.Inputs[i]
+	return Tuple{explicit(hashWord(in.Asset)), amount(in.Value, in.Confidential)}
+}
+
+func (tx *Tx) output(i int) Value {
+	out := tx.Outputs[i]
+	return Tuple{explicit(hashWord(out.Asset)), amount(out.Value, out.Confidential)}
+}
+
+// amount is an Amount1: the explicit value, or a commitment to it. The
+// commitment stands in for a Pedersen commitment, which the evaluator does
+// not compute; scripts can only compare it.
+func amount(value uint64, confidential bool) Value {
+	if !confidential {
+		return explicit(u64(value))
+	}
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], value)
+	x := sha256.Sum256(b[:])
+	return Sum{V: Tuple{Word{Bits: 1, V: big.NewInt(0)}, Word{Bits: 256, V: new(big.Int).SetBytes(x[:])}}}
+}
+
+// txJet evaluates an introspection jet against the environment.
+func (m *machine) txJet(name string, args []Value, line int) (Value, error) {
+	tx := m.tx
+	// Indexed jets take a u32 input or output index and return None when it
+	// is out of range.
+	index := func(n int) (int, bool, error) {
+		if len(args) != 1 {
+			return 0, false, fmt.Errorf("line %d: jet::%s takes 1 argument, got %d", line, name, len(args))
+		}
+		w, err := wordArg(args[0], line)
+		if err != nil {
+			return 0, false, err
+		}
+		if !w.V.IsInt64() || w.V.Int64() >= int64(n) {
+			return 0, false, nil
+		}
+		return int(w.V.Int64()), true, nil
+	}
+	check := func(limit uint32, what string) (Value, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("line %d: jet::%s takes 1 argument, got %d", line, name, len(args))
+		}
+		w, err := wordArg(args[0], line)
+		if err != nil {
+			return nil, err
+		}
+		if w.V.Cmp(new(big.Int).SetUint64(uint64(limit))) > 0 {
+			return nil, &Rejection{Line: line, Reason: fmt.Sprintf("jet::%s: %s %d is below %s", name, what, limit, w.V)}
+		}
+		return Unit{}, nil
+	}
+	field := func(value, key string) (Value, error) {
+		if value == "" {
+			return nil, fmt.Errorf("line %d: jet::%s needs %s in the tx environment", line, name, key)
+		}
+		return hashWord(value), nil
+	}
+	current := tx.Inputs[tx.CurrentIndex]
+
+	switch name {
+	case "version":
+		return u32(tx.Version), nil
+	case "lock_time":
+		return u32(tx.LockTime), nil
+	case "tx_is_final":
+		return tx.final(), nil
+	case "tx_lock_height":
+		return u32(tx.lockHeight()), nil
+	case "tx_lock_time":
+		return u32(tx.lockTime()), nil
+	case "tx_lock_distance":
+		return u16(tx.relativeLock(false)), nil
+	case "tx_lock_duration":
+		return u16(tx.relativeLock(true)), nil
+	case "check_lock_height":
+		return check(tx.lockHeight(), "lock height")
+	case "check_lock_time":
+		return check(tx.lockTime(), "lock time")
+	case "check_lock_distance":
+		return check(tx.relativeLock(false), "relative lock distance")
+	case "check_lock_duration":
+		return check(tx.relativeLock(true), "relative lock duration")
+	case "num_inputs":
+		return u32(uint32(len(tx.Inputs))), nil
+	case "num_outputs":
+		return u32(uint32(len(tx.Outputs))), nil
+	case "current_index":
+		return u32(tx.CurrentIndex), nil
+	case "current_sequence":
+		return u32(*current.Sequence), nil
+	case "current_annex_hash":
+		if current.AnnexHash == "" {
+			return none(), nil
+		}
+		return some(hashWord(current.AnnexHash)), nil
+	case "current_prev_outpoint":
+		return Tuple{hashWord(current.PrevTxid), u32(current.PrevVout)}, nil
+	case "current_script_hash":
+		return hashWord(current.ScriptHash), nil
+	case "current_asset":
+		return explicit(hashWord(current.Asset)), nil
+	case "current_amount":
+		return tx.input(int(tx.CurrentIndex)), nil
+	case "input_prev_outpoint", "input_script_hash", "input_asset", "input_amount":
+		i, ok, err := index(len(tx.Inputs))
+		if err != nil || !ok {
+			return none(), err
+		}
+		in := tx.Inputs[i]
+		switch name {
+		case "input_prev_outpoint":
+			return some(Tuple{hashWord(in.PrevTxid), u32(in.PrevVout)}), nil
+		case "input_script_hash":
+			return some(hashWord(in.ScriptHash)), nil
+		case "input_asset":
+			return some(explicit(hashWord(in.Asset))), nil
+		}
+		return some(tx.input(i)), nil
+	case "current_pegin":
+		if current.Pegin == "" {
+			return none(), nil
+		}
+		return some(hashWord(current.Pegin)), nil
+	case "issuance", "issuance_asset_amount", "issuance_token_amount", "new_issuance_contract",
+		"reissuance_blinding", "reissuance_entropy", "input_pegin":
+		// Some(None) when the input has no such value
+		i, ok, err := index(len(tx.Inputs))
+		if err != nil || !ok {
+			return none(), err
+		}
+		in := tx.Inputs[i]
+		if name == "input_pegin" {
+			if in.Pegin == "" {
+				return some(none()), nil
+			}
+			return some(some(hashWord(in.Pegin))), nil
+		}
+		is := in.Issuance
+		if is == nil {
+			return some(none()), nil
+		}
+		switch name {
+		case "issuance":
+			return some(some(is.reissuance())), nil
+		case "issuance_asset_amount":
+			return some(some(explicit(u64(is.AssetAmount)))), nil
+		case "issuance_token_amount":
+			return some(some(explicit(u64(is.TokenAmount)))), nil
+		case "new_issuance_contract":
+			if is.reissuance() {
+				return some(none()), nil
+			}
+			return some(some(hashWord(is.ContractHash))), nil
+		case "reissuance_blinding":
+			if !is.reissuance() {
+				return some(none()), nil
+			}
+			return some(some(hashWord(is.BlindingNonce))), nil
+		}
+		if !is.reissuance() {
+			return some(none()), nil
+		}
+		return some(some(hashWord(is.Entropy))), nil
+	case "output_script_hash", "output_asset", "output_amount":
+		i, ok, err := index(len(tx.Outputs))
+		if err != nil || !ok {
+			return none(), err
+		}
+		out := tx.Outputs[i]
+		switch name {
+		case "output_script_hash":
+			return some(hashWord(out.ScriptHash)), nil
+		case "output_asset":
+			return some(explicit(hashWord(out.Asset))), nil
+		}
+		return some(tx.output(i)), nil
+	case "total_fee":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("line %d: jet::%s takes 1 argument, got %d", line, name, len(args))
+		}
+		asset, err := wordArg(args[0], line)
+		if err != nil {
+			return nil, err
+		}
+		var total uint64
+		for _, out := range tx.Outputs {
+			if hashWord(out.ScriptHash).V.Cmp(emptyScriptHash.V) == 0 && hashWord(out.Asset).V.Cmp(asset.V) == 0 {
+				total += out.Value
+			}
+		}
+		return u64(total), nil
+	case "sig_all_hash":
+		return field(tx.SigAllHash, "sig_all_hash")
+	case "transaction_id":
+		return field(tx.TransactionID, "transaction_id")
+	case "genesis_block_hash":
+		return field(tx.GenesisBlockHash, "genesis_block_hash")
+	case "internal_key":
+		return field(tx.InternalKey, "internal_key")
+	case "script_cmr":
+		return field(tx.ScriptCMR, "script_cmr")
+	case "tapleaf_version":
+		if tx.TapleafVersion == nil {
+			return nil, fmt.Errorf("line %d: jet::%s needs tapleaf_version in the tx environment", line, name)
+		}
+		return Word{Bits: 8, V: big.NewInt(int64(*tx.TapleafVersion))}, nil
+	}
+	return nil, fmt.Errorf("line %d: jet::%s is not supported by the built-in evaluator", line, name)
+}
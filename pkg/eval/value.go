@@ -0,0 +1,100 @@
+// Package eval runs generated SimplicityHL programs with a small built-in
+// interpreter. It covers pure computation: arithmetic, comparison, SHA-256
+// and BIP-340 jets. Jets that introspect the spending transaction read a
+// Tx environment and fail with a ContextError when none is given.
+package eval
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Value is a runtime value: Unit, bool, Word, Tuple, Array, Sum or a
+// SHA-256 context.
+type Value interface{}
+
+// Unit is the value of the empty tuple.
+type Unit struct{}
+
+// Word is an unsigned integer. Byte arrays are words too: [u8; 32] is held
+// as a 256-bit big-endian number. Bits is 0 for literals whose width has not
+// been fixed by a type yet.
+type Word struct {
+	Bits int
+	V    *big.Int
+}
+
+// Tuple is a product value.
+type Tuple []Value
+
+// Array is an array of non-byte elements.
+type Array []Value
+
+// Sum is a value of Either or Option. Left and None have Right == false;
+// None carries Unit.
+type Sum struct {
+	Right bool
+	V     Value
+}
+
+// shaCtx is the running state of a sha_256_ctx_8 computation.
+type shaCtx struct {
+	data []byte
+}
+
+func word(bits int, v *big.Int) Word {
+	if bits > 0 {
+		v = new(big.Int).And(v, mask(bits))
+	}
+	return Word{Bits: bits, V: v}
+}
+
+func mask(bits int) *big.Int {
+	m := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	return m.Sub(m, big.NewInt(1))
+}
+
+// bytesOf renders w as n big-endian bytes.
+func bytesOf(w Word, n int) []byte {
+	out := make([]byte, n)
+	return w.V.FillBytes(out)
+}
+
+// Format renders v in SimplicityHL literal syntax.
+func Format(v Value) string {
+	switch v := v.(type) {
+	case Unit:
+		return "()"
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case Word:
+		if v.Bits >= 8 && v.Bits%8 == 0 && v.Bits > 64 {
+			return fmt.Sprintf("0x%x", bytesOf(v, v.Bits/8))
+		}
+		return v.V.String()
+	case Tuple:
+		parts := make([]string, len(v))
+		for i, e := range v {
+			parts[i] = Format(e)
+		}
+		return "(" + strings.Join(parts, ", ") + ")"
+	case Array:
+		parts := make([]string, len(v))
+		for i, e := range v {
+			parts[i] = Format(e)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case Sum:
+		if v.Right {
+			return "Right(" + Format(v.V) + ")"
+		}
+		return "Left(" + Format(v.V) + ")"
+	case *shaCtx:
+		return "<sha256 context>"
+	}
+	return fmt.Sprintf("%v", v)
+}
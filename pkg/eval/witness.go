@@ -0,0 +1,33 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ParseWitnessJSON decodes a witness file: a JSON object mapping witness
+// names to values. A value is either a string in SimplicityHL literal syntax
+// or an object with "value" and "type" fields, the .wit format written by
+// simgo test-gen.
+func ParseWitnessJSON(data []byte) (map[string]string, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid witness file: %w", err)
+	}
+	values := make(map[string]string, len(raw))
+	for name, msg := range raw {
+		var s string
+		if err := json.Unmarshal(msg, &s); err == nil {
+			values[name] = s
+			continue
+		}
+		var entry struct {
+			Value string `json:"value"`
+		}
+		if err := json.Unmarshal(msg, &entry); err != nil || entry.Value == "" {
+			return nil, fmt.Errorf("invalid witness file: %s must be a string or an object with a value", name)
+		}
+		values[name] = entry.Value
+	}
+	return values, nil
+}
@@ -0,0 +1,422 @@
+// Package fix finds mechanical rewrites of a contract's Go source that the
+// compiler would otherwise reject or misread, and applies them, as simgo
+// fix does. Each Fix holds the edits that make it, as byte ranges of the
+// source with their replacement text, so an editor can apply them too.
+//
+// A fix is Safe when it keeps the meaning of every value the contract can
+// compute: removing fmt output, or an array parameter in place of a slice
+// every call passes an array of one size to. Declaring int as uint64 is
+// not safe, since a negative value wraps around instead; it is for a
+// reviewer to apply.
+package fix
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	gotypes "go/types"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+)
+
+// Position is a place in a source file. Offset counts bytes from the
+// start; Line and Column count from 1, Column in bytes.
+type Position struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Edit replaces the source from Start up to End with NewText.
+type Edit struct {
+	Start   Position `json:"start"`
+	End     Position `json:"end"`
+	NewText string   `json:"new_text"`
+}
+
+// Fix is a diagnostic with the edits that resolve it.
+type Fix struct {
+	File    string    `json:"file"`
+	Pos     Position  `json:"pos"`
+	Code    diag.Code `json:"code,omitempty"` // Code of the compile error the fix resolves, if any
+	Message string    `json:"message"`
+	Safe    bool      `json:"safe"`
+	Edits   []Edit    `json:"edits"`
+}
+
+// Find returns the fixes for src, ordered by position.
+func Find(filename string, src []byte) ([]Fix, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, diag.SyntaxError.Errorf("failed to parse Go source: %w", err)
+	}
+	f := &finder{fset: fset, file: file, src: src}
+	f.printCalls()
+	f.sliceParams()
+	f.signedInts()
+	sort.SliceStable(f.fixes, func(i, j int) bool { return f.fixes[i].Pos.Offset < f.fixes[j].Pos.Offset })
+	return f.fixes, nil
+}
+
+// Apply makes the edits of fixes to src and formats the result with
+// go/format. Edits of different fixes must not overlap.
+func Apply(src []byte, fixes []Fix) ([]byte, error) {
+	var edits []Edit
+	for _, fix := range fixes {
+		edits = append(edits, fix.Edits...)
+	}
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].Start.Offset < edits[j].Start.Offset })
+	var out bytes.Buffer
+	at := 0
+	for _, e := range edits {
+		if e.Start.Offset < at || e.End.Offset < e.Start.Offset || e.End.Offset > len(src) {
+			return nil, fmt.Errorf("edit at %d:%d overlaps another or lies outside the source", e.Start.Line, e.Start.Column)
+		}
+		out.Write(src[at:e.Start.Offset])
+		out.WriteString(e.NewText)
+		at = e.End.Offset
+	}
+	out.Write(src[at:])
+	formatted, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("fixed source does not format: %w", err)
+	}
+	return formatted, nil
+}
+
+// finder collects the fixes of one file.
+type finder struct {
+	fset  *token.FileSet
+	file  *ast.File
+	src   []byte
+	fixes []Fix
+}
+
+func (f *finder) position(pos token.Pos) Position {
+	p := f.fset.Position(pos)
+	return Position{Offset: p.Offset, Line: p.Line, Column: p.Column}
+}
+
+func (f *finder) edit(from, to token.Pos, text string) Edit {
+	return Edit{Start: f.position(from), End: f.position(to), NewText: text}
+}
+
+func (f *finder) add(pos token.Pos, code diag.Code, safe bool, edits []Edit, format string, args ...any) {
+	f.fixes = append(f.fixes, Fix{
+		File:    f.fset.Position(pos).Filename,
+		Pos:     f.position(pos),
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+		Safe:    safe,
+		Edits:   edits,
+	})
+}
+
+// lineEdit removes the source from to, with its lines when nothing else
+// is on them.
+func (f *finder) lineEdit(from, to token.Pos) Edit {
+	tf := f.fset.File(from)
+	start, end := tf.Offset(from), tf.Offset(to)
+	lineStart := bytes.LastIndexByte(f.src[:start], '\n') + 1
+	lineEnd := len(f.src)
+	if i := bytes.IndexByte(f.src[end:], '\n'); i >= 0 {
+		lineEnd = end + i + 1
+	}
+	if len(bytes.TrimSpace(f.src[lineStart:start])) == 0 && len(bytes.TrimSpace(f.src[end:lineEnd])) == 0 {
+		from, to = tf.Pos(lineStart), tf.Pos(lineEnd)
+	}
+	return f.edit(from, to, "")
+}
+
+// printCalls removes the statements calling fmt, which a contract has no
+// output for, and the import of fmt once nothing else uses it.
+func (f *finder) printCalls() {
+	var spec *ast.ImportSpec
+	var decl *ast.GenDecl
+	name := ""
+	for _, d := range f.file.Decls {
+		gen, ok := d.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		for _, s := range gen.Specs {
+			if s := s.(*ast.ImportSpec); s.Path.Value == `"fmt"` {
+				spec, decl, name = s, gen, "fmt"
+				if s.Name != nil {
+					name = s.Name.Name
+				}
+			}
+		}
+	}
+	if spec == nil || name == "_" || name == "." {
+		return
+	}
+
+	uses, removed := 0, 0
+	ast.Inspect(f.file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok && ident.Name == name && ident.Obj == nil {
+				uses++
+			}
+		}
+		var list []ast.Stmt
+		switch n := n.(type) {
+		case *ast.BlockStmt:
+			list = n.List
+		case *ast.CaseClause:
+			list = n.Body
+		}
+		for _, stmt := range list {
+			expr, ok := stmt.(*ast.ExprStmt)
+			if !ok {
+				continue
+			}
+			call, ok := expr.X.(*ast.CallExpr)
+			if !ok {
+				continue
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				continue
+			}
+			if ident, ok := sel.X.(*ast.Ident); ok && ident.Name == name && ident.Obj == nil {
+				removed++
+				f.add(stmt.Pos(), "", true, []Edit{f.lineEdit(stmt.Pos(), stmt.End())},
+					"%s has no effect in a contract, which has no output; remove it", gotypes.ExprString(call.Fun))
+			}
+		}
+		return true
+	})
+	if removed == 0 || uses != removed {
+		return
+	}
+	edit := f.lineEdit(spec.Pos(), spec.End())
+	switch {
+	case len(decl.Specs) == 1:
+		edit = f.lineEdit(decl.Pos(), decl.End())
+	case len(decl.Specs) == 2 && decl.Lparen.IsValid() && !f.hasComments(decl):
+		// The import left standing loses its parentheses, which gofmt
+		// would keep.
+		other := decl.Specs[0]
+		if other == spec {
+			other = decl.Specs[1]
+		}
+		edit = f.edit(decl.Pos(), decl.End(), "import "+f.text(other.Pos(), other.End()))
+	}
+	f.add(spec.Pos(), "", true, []Edit{edit}, "import of %s is unused once its calls are removed", spec.Path.Value)
+}
+
+// hasComments reports whether a comment lies within n.
+func (f *finder) hasComments(n ast.Node) bool {
+	for _, group := range f.file.Comments {
+		if group.Pos() >= n.Pos() && group.End() <= n.End() {
+			return true
+		}
+	}
+	return false
+}
+
+// text returns the source from one position up to another.
+func (f *finder) text(from, to token.Pos) string {
+	return string(f.src[f.fset.Position(from).Offset:f.fset.Position(to).Offset])
+}
+
+// sliceParams declares a []byte parameter as [N]byte when every call of
+// its function passes the whole of an [N]byte, as in isHash(digest[:]),
+// and the body only indexes it or takes its length.
+func (f *finder) sliceParams() {
+	funcs := make(map[*ast.Object]*ast.FuncDecl)
+	for _, d := range f.file.Decls {
+		if fn, ok := d.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Obj != nil {
+			funcs[fn.Name.Obj] = fn
+		}
+	}
+	calls := make(map[*ast.FuncDecl][]*ast.CallExpr)
+	ast.Inspect(f.file, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if ident, ok := call.Fun.(*ast.Ident); ok && ident.Obj != nil && funcs[ident.Obj] != nil {
+				fn := funcs[ident.Obj]
+				calls[fn] = append(calls[fn], call)
+			}
+		}
+		return true
+	})
+
+	for _, d := range f.file.Decls {
+		fn, ok := d.(*ast.FuncDecl)
+		if !ok || len(calls[fn]) == 0 {
+			continue
+		}
+		index := 0
+		for _, field := range fn.Type.Params.List {
+			first := index
+			index += max(1, len(field.Names))
+			slice, ok := field.Type.(*ast.ArrayType)
+			if !ok || slice.Len != nil || !isByte(slice.Elt) || len(field.Names) == 0 {
+				continue
+			}
+			size := 0
+			var edits []Edit
+			ok = true
+			for i := first; i < index && ok; i++ {
+				ok = onlyIndexed(fn.Body, field.Names[i-first].Obj)
+				for _, call := range calls[fn] {
+					if !ok {
+						break
+					}
+					n, arg := 0, (*ast.SliceExpr)(nil)
+					if i < len(call.Args) {
+						arg, _ = call.Args[i].(*ast.SliceExpr)
+					}
+					if arg != nil && arg.Low == nil && arg.High == nil && arg.Max == nil {
+						n = byteArrayLen(arg.X)
+					}
+					if n == 0 || (size != 0 && n != size) {
+						ok = false
+						break
+					}
+					size = n
+					edits = append(edits, f.edit(arg.Pos(), arg.End(), gotypes.ExprString(arg.X)))
+				}
+			}
+			if !ok {
+				continue
+			}
+			edits = append([]Edit{f.edit(slice.Pos(), slice.End(), fmt.Sprintf("[%d]%s", size, gotypes.ExprString(slice.Elt)))}, edits...)
+			f.add(slice.Pos(), diag.Slice, true, edits, "%s of %s is a slice, and every call passes a [%d]%s; declare it [%d]%s",
+				joinNames(field.Names), fn.Name.Name, size, gotypes.ExprString(slice.Elt), size, gotypes.ExprString(slice.Elt))
+		}
+	}
+}
+
+// signedInts declares the signed and platform-sized integer types as the
+// unsigned type of their width, or uint64, in type positions and
+// conversions.
+func (f *finder) signedInts() {
+	unsigned := map[string]string{
+		"int": "uint64", "int8": "uint8", "int16": "uint16", "int32": "uint32", "int64": "uint64", "uint": "uint64",
+	}
+	seen := make(map[*ast.Ident]bool)
+	check := func(expr ast.Expr) {
+		ast.Inspect(expr, func(n ast.Node) bool {
+			ident, ok := n.(*ast.Ident)
+			if !ok || ident.Obj != nil || seen[ident] {
+				return true
+			}
+			seen[ident] = true
+			if to, ok := unsigned[ident.Name]; ok {
+				f.add(ident.Pos(), diag.SignedInt, false, []Edit{f.edit(ident.Pos(), ident.End(), to)},
+					"%s has no Simplicity equivalent, whose integers are unsigned and of fixed width; declare %s, after checking that no value is negative", ident.Name, to)
+			}
+			return true
+		})
+	}
+	ast.Inspect(f.file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Field:
+			check(n.Type)
+		case *ast.ValueSpec:
+			if n.Type != nil {
+				check(n.Type)
+			}
+		case *ast.TypeSpec:
+			check(n.Type)
+			return false
+		case *ast.CompositeLit:
+			if n.Type != nil {
+				check(n.Type)
+			}
+		case *ast.CallExpr:
+			if ident, ok := n.Fun.(*ast.Ident); ok && len(n.Args) == 1 {
+				check(ident)
+			}
+		}
+		return true
+	})
+}
+
+// isByte reports whether expr is byte or uint8.
+func isByte(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Obj == nil && (ident.Name == "byte" || ident.Name == "uint8")
+}
+
+// byteArrayLen returns N when expr names a variable or parameter declared
+// as [N]byte with a literal N, or 0.
+func byteArrayLen(expr ast.Expr) int {
+	ident, ok := expr.(*ast.Ident)
+	if !ok || ident.Obj == nil {
+		return 0
+	}
+	var typ ast.Expr
+	switch decl := ident.Obj.Decl.(type) {
+	case *ast.Field:
+		typ = decl.Type
+	case *ast.ValueSpec:
+		typ = decl.Type
+	}
+	array, ok := typ.(*ast.ArrayType)
+	if !ok || !isByte(array.Elt) {
+		return 0
+	}
+	lit, ok := array.Len.(*ast.BasicLit)
+	if !ok || lit.Kind != token.INT {
+		return 0
+	}
+	n, err := strconv.Atoi(lit.Value)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// onlyIndexed reports whether body uses obj only as x[i] or len(x), which
+// mean the same for an array as for a slice.
+func onlyIndexed(body *ast.BlockStmt, obj *ast.Object) bool {
+	if body == nil || obj == nil {
+		return false
+	}
+	allowed := make(map[*ast.Ident]bool)
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.IndexExpr:
+			if ident, ok := n.X.(*ast.Ident); ok {
+				allowed[ident] = true
+			}
+		case *ast.CallExpr:
+			if fun, ok := n.Fun.(*ast.Ident); ok && fun.Name == "len" && fun.Obj == nil && len(n.Args) == 1 {
+				if ident, ok := n.Args[0].(*ast.Ident); ok {
+					allowed[ident] = true
+				}
+			}
+		}
+		return true
+	})
+	ok := true
+	ast.Inspect(body, func(n ast.Node) bool {
+		if ident, isIdent := n.(*ast.Ident); isIdent && ident.Obj == obj && !allowed[ident] {
+			ok = false
+		}
+		return ok
+	})
+	return ok
+}
+
+// joinNames lists the names of a parameter field.
+func joinNames(names []*ast.Ident) string {
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name.Name
+	}
+	if len(parts) == 1 {
+		return "parameter " + parts[0]
+	}
+	return "parameters " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,219 @@
+// Package gen discovers contracts marked with //simplicity:contract in a Go
+// package directory and compiles each one to its own .simf file. It backs the
+// `simgo gen` subcommand, which is intended to be run from go:generate.
+// WriteFile writes each file as every simgo command writes its output.
+package gen
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+)
+
+// Directive marks a file or function as a contract entry point. An optional
+// argument overrides the output name: //simplicity:contract refund
+const Directive = "//simplicity:contract"
+
+// Contract is one annotated entry point found in a package.
+type Contract struct {
+	Name  string         // Output base name, without the .simf extension
+	File  string         // Path of the Go source file
+	Entry string         // Go function compiled as the program root
+	Pos   token.Position // Location of the directive
+}
+
+// Options configures discovery and generation.
+type Options struct {
+	OutDir string          // Directory receiving the .simf files (default: the package directory)
+	Tags   []string        // Build tags used to select files, also passed to the compiler
+	Config compiler.Config // Base compiler configuration; Entry is set per contract
+	Force  bool            // Replace output files that simgo did not generate
+}
+
+// Result describes one generated file.
+type Result struct {
+	Contract Contract
+	Output   string // Path of the written .simf file
+}
+
+// Discover scans the Go files in dir whose build constraints tags satisfy,
+// as decided by compiler.MatchBuildTags, and returns every annotated
+// contract sorted by name. Files without a directive are skipped. Two contracts that would write the same output file are
+// reported as an error.
+func Discover(dir string, tags []string) ([]Contract, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package directory: %w", err)
+	}
+
+	fset := token.NewFileSet()
+	var contracts []Contract
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		match, err := compiler.MatchBuildTags(src, tags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate build constraints for %s: %w", name, err)
+		}
+		if !match {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		contracts = append(contracts, FileContracts(fset, path, file)...)
+	}
+
+	if err := checkNames(contracts); err != nil {
+		return nil, err
+	}
+	sort.Slice(contracts, func(i, j int) bool { return contracts[i].Name < contracts[j].Name })
+	return contracts, nil
+}
+
+// FileContracts returns the contracts declared in file, parsed from path
+// with its comments. A directive in a comment above the package clause
+// marks main() as the entry point and names the output after the file; a
+// directive in a function's doc comment marks that function and names the
+// output after it.
+func FileContracts(fset *token.FileSet, path string, file *ast.File) []Contract {
+	var contracts []Contract
+	for _, group := range file.Comments {
+		if group.End() >= file.Package {
+			break
+		}
+		if arg, pos, ok := findDirective(fset, group); ok {
+			name := strings.TrimSuffix(filepath.Base(path), ".go")
+			if arg != "" {
+				name = arg
+			}
+			contracts = append(contracts, Contract{Name: name, File: path, Entry: "main", Pos: pos})
+			break
+		}
+	}
+
+	for _, decl := range file.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok || funcDecl.Recv != nil || funcDecl.Doc == nil {
+			continue
+		}
+		if arg, pos, ok := findDirective(fset, funcDecl.Doc); ok {
+			name := funcDecl.Name.Name
+			if arg != "" {
+				name = arg
+			}
+			contracts = append(contracts, Contract{Name: name, File: path, Entry: funcDecl.Name.Name, Pos: pos})
+		}
+	}
+	return contracts
+}
+
+// findDirective looks for the contract directive in a comment group and
+// returns its optional argument.
+func findDirective(fset *token.FileSet, group *ast.CommentGroup) (string, token.Position, bool) {
+	for _, c := range group.List {
+		rest, ok := strings.CutPrefix(c.Text, Directive)
+		if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+			continue
+		}
+		return strings.TrimSpace(rest), fset.Position(c.Pos()), true
+	}
+	return "", token.Position{}, false
+}
+
+// checkNames rejects output names that are not plain file names and contracts
+// whose output files would overwrite each other. Names are compared
+// case-insensitively so the result does not depend on the filesystem.
+func checkNames(contracts []Contract) error {
+	seen := make(map[string]Contract)
+	var errs []string
+	for _, c := range contracts {
+		if c.Name == "." || c.Name == ".." || strings.ContainsAny(c.Name, `/\`) {
+			errs = append(errs, fmt.Sprintf("%s: invalid contract name %q", c.Pos, c.Name))
+			continue
+		}
+		key := strings.ToLower(c.Name)
+		if prev, ok := seen[key]; ok {
+			errs = append(errs, fmt.Sprintf("%s: contract %q collides with %q declared at %s", c.Pos, c.Name, prev.Name, prev.Pos))
+			continue
+		}
+		seen[key] = c
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid contract names:\n%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// Generate discovers the contracts in dir and writes one .simf file per
+// contract to opts.OutDir, as WriteFile does. Nothing is written if
+// discovery fails, or if an output file exists that simgo did not generate
+// and opts.Force is not set.
+func Generate(dir string, opts Options) ([]Result, error) {
+	contracts, err := Discover(dir, opts.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	outDir := opts.OutDir
+	if outDir == "" {
+		outDir = dir
+	}
+	for _, c := range contracts {
+		if err := checkOverwrite(filepath.Join(outDir, c.Name+".simf"), opts.Force); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	var results []Result
+	for _, c := range contracts {
+		source, err := os.ReadFile(c.File)
+		if err != nil {
+			return results, fmt.Errorf("failed to read %s: %w", c.File, err)
+		}
+
+		config := opts.Config
+		if config.Target == "" {
+			config.Target = "simplicityhl"
+		}
+		config.Entry = c.Entry
+		if config.BuildTags == nil {
+			config.BuildTags = opts.Tags
+		}
+		code, err := compiler.New(config).Compile(string(source), c.File)
+		if err != nil {
+			return results, fmt.Errorf("%s: contract %s: %w", c.Pos, c.Name, err)
+		}
+
+		out := filepath.Join(outDir, c.Name+".simf")
+		if err := WriteFile(out, c.File, code, opts.Force); err != nil {
+			var refused *OverwriteError
+			if errors.As(err, &refused) {
+				return results, err
+			}
+			return results, fmt.Errorf("failed to write %s: %w", out, err)
+		}
+		results = append(results, Result{Contract: c, Output: out})
+	}
+	return results, nil
+}
@@ -0,0 +1,81 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Provenance starts the header of every file simgo writes, so that a later
+// run can tell its own output from a file it must not overwrite.
+const Provenance = "// Code generated by simgo"
+
+// OverwriteError reports an existing file that WriteFile refuses to
+// replace, as simgo did not generate it.
+type OverwriteError struct {
+	Path string
+}
+
+func (e *OverwriteError) Error() string {
+	return fmt.Sprintf("refusing to overwrite %s, which was not generated by simgo; pass -force to replace it", e.Path)
+}
+
+// GeneratedFile returns the contents of a file holding program, compiled
+// from input: the program under a provenance header.
+func GeneratedFile(input, program string) []byte {
+	return []byte(fmt.Sprintf("%s from %s. DO NOT EDIT.\n", Provenance, filepath.Base(input)) + program)
+}
+
+// IsGenerated reports whether data, the contents of a file, starts with
+// the provenance header.
+func IsGenerated(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(Provenance))
+}
+
+// checkOverwrite returns an *OverwriteError for an existing file at path
+// that lacks the provenance header, unless force is set.
+func checkOverwrite(path string, force bool) error {
+	existing, err := os.ReadFile(path)
+	if err == nil && !force && !IsGenerated(existing) {
+		return &OverwriteError{Path: path}
+	}
+	return nil
+}
+
+// WriteFile writes program to path under a provenance header naming input,
+// atomically as WriteAtomic does. An existing file lacking the header is
+// only replaced with force; otherwise the error is an *OverwriteError.
+func WriteFile(path, input, program string, force bool) error {
+	if err := checkOverwrite(path, force); err != nil {
+		return err
+	}
+	return WriteAtomic(path, GeneratedFile(input, program))
+}
+
+// WriteAtomic writes data to a temporary file next to path and renames it
+// into place, creating missing parent directories. A failed write leaves
+// any previous file at path untouched rather than truncated.
+func WriteAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,90 @@
+// Package gensym names the temporaries that lowerings and optimization
+// passes introduce into generated SimplicityHL, such as the intermediate
+// results of total := a + b + c or a jet call that CSE computes once.
+//
+// A temporary is named after the position of the source it computes,
+// t_<line>_<col>, so its name does not depend on how many temporaries other
+// functions, other passes or the order of passes introduced before it. A
+// second temporary at the same position gets a disambiguator, t_42_7_2,
+// t_42_7_3 and so on, in the order it is asked for. A lowering that needs
+// several related temporaries takes one name and appends words to it, as in
+// t_42_7_hi and t_42_7_lo; a word is never a number, so it cannot collide
+// with a disambiguator.
+//
+// SimplicityHL identifiers start with a letter, so a temporary cannot hide
+// behind a leading underscore. Instead no user identifier takes the form:
+// the transpiler gives a Go name that converts to one a trailing underscore,
+// as it does a keyword.
+package gensym
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	tempName = regexp.MustCompile(`^t_[0-9]+_[0-9]+(_[a-z0-9]+)*$`)
+	tempRef  = regexp.MustCompile(`\bt_[0-9]+_[0-9]+(_[a-z0-9]+)*\b`)
+)
+
+// IsTemp reports whether name has the form of a temporary.
+func IsTemp(name string) bool {
+	return tempName.MatchString(name)
+}
+
+// Names hands out the temporaries of one program. The zero value is ready
+// to use.
+type Names struct {
+	taken map[string]bool // Name → whether At handed it out
+}
+
+// At returns a new temporary for the source at line and col.
+func (n *Names) At(line, col int) string {
+	if n.taken == nil {
+		n.taken = make(map[string]bool)
+	}
+	name := fmt.Sprintf("t_%d_%d", line, col)
+	for i := 2; n.isTaken(name); i++ {
+		name = fmt.Sprintf("t_%d_%d_%d", line, col, i)
+	}
+	n.taken[name] = true
+	return name
+}
+
+// Reserve marks the temporaries that code, a generated program, already
+// binds as taken, for a pass that adds temporaries of its own to it. The
+// name a related temporary such as t_42_7_hi was built from is taken too.
+func (n *Names) Reserve(code string) {
+	for _, name := range tempRef.FindAllString(code, -1) {
+		parts := strings.Split(name, "_")
+		for k := 3; k <= len(parts); k++ {
+			n.Take(strings.Join(parts[:k], "_"))
+		}
+	}
+}
+
+// Take marks name as taken, so At never hands it out.
+func (n *Names) Take(name string) {
+	if n.taken == nil {
+		n.taken = make(map[string]bool)
+	}
+	if _, ok := n.taken[name]; !ok {
+		n.taken[name] = false
+	}
+}
+
+// Issued reports whether At handed out name.
+func (n *Names) Issued(name string) bool {
+	return n.taken[name]
+}
+
+func (n *Names) isTaken(name string) bool {
+	_, ok := n.taken[name]
+	return ok
+}
+
+// Reset forgets every temporary handed out, for the next program.
+func (n *Names) Reset() {
+	n.taken = nil
+}
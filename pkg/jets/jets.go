@@ -1,29 +1,193 @@
 // Package jets provides the registry of Simplicity jet functions and their
-// Go-to-Simplicity name mappings.
+// Go-to-Simplicity name mappings. It is the one table of every jet the
+// transpiler can emit: the Go stubs of std/jets are generated from it, and
+// the names each output dialect knows a jet by are recorded in it.
 package jets
 
+import (
+	"fmt"
+	"go/token"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/types"
+)
+
 // JetInfo describes a Simplicity jet function
 type JetInfo struct {
 	GoName         string   // Go function name (e.g., "BIP340Verify")
 	SimplicityName string   // Simplicity jet name (e.g., "bip_0340_verify")
 	ParamTypes     []string // Parameter types
 	ReturnType     string   // Return type
+	// ResultType is the SimplicityHL type of the jet's result when it wraps
+	// the value of ReturnType that Go code sees: an Option for an input or
+	// output that may not exist, or the Asset1 and Amount1 forms that may
+	// be confidential.
+	ResultType string
+	// Dialects names the jet in the output dialects that know it by
+	// another name than SimplicityName, keyed by dialect.
+	Dialects map[string]string
+	// Custom reports whether the jet was declared with Declare rather than
+	// being one of the table.
+	Custom bool
+	// Package is the import path of the user stub package declaring the
+	// jet, for a Custom jet that has one.
+	Package string
 }
 
 // JetRegistry holds all known jet mappings
 type JetRegistry struct {
-	jets map[string]JetInfo
+	jets  map[string]JetInfo
+	byJet map[string]string // SimplicityName → GoName
+}
+
+// CustomGoName is the function of the jet package that calls a declared
+// jet by its SimplicityHL name, as in jet.Custom("my_jet", x, y).
+const CustomGoName = "Custom"
+
+// JetDecl declares a jet that is not in the table, such as a proposed jet
+// being experimented with, so that programs can call it.
+type JetDecl struct {
+	// Name is the SimplicityHL name of the jet, such as my_jet.
+	Name string `json:"name"`
+	// GoName is the Go function that calls the jet, as in jet.MyJet(x).
+	// Empty selects Name in CamelCase. Calling jet.Custom("my_jet", x)
+	// works either way.
+	GoName string `json:"go_name,omitempty"`
+	// Package is the import path of a user package of Go stubs that
+	// declares GoName. Files that import it call the jet as pkg.GoName(x),
+	// and the package is not loaded from disk.
+	Package string `json:"package,omitempty"`
+	// Dialects names the jet in the output dialects that know it by
+	// another name, as JetInfo.Dialects does.
+	Dialects   map[string]string `json:"dialects,omitempty"`
+	ParamTypes []string          `json:"params"`
+	ReturnType string            `json:"returns"`
 }
 
+// builtin is the table of standard jets, checked by validate when the
+// package is initialized so that a malformed entry fails every test.
+var builtin = func() map[string]JetInfo {
+	r := &JetRegistry{jets: make(map[string]JetInfo)}
+	r.registerBuiltinJets()
+	for name, info := range r.jets {
+		if err := validate(name, info); err != nil {
+			panic("jets: " + err.Error())
+		}
+	}
+	return r.jets
+}()
+
 // NewRegistry creates a new jet registry with all known jets
 func NewRegistry() *JetRegistry {
 	r := &JetRegistry{
-		jets: make(map[string]JetInfo),
+		jets:  make(map[string]JetInfo, len(builtin)),
+		byJet: make(map[string]string, len(builtin)),
+	}
+	for name, info := range builtin {
+		r.jets[name] = info
+		r.byJet[info.SimplicityName] = name
 	}
-	r.registerBuiltinJets()
 	return r
 }
 
+// jetName is the form of a SimplicityHL jet name.
+var jetName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// validate checks that info, registered as name, is well-formed: its names
+// are identifiers and its types parse.
+func validate(name string, info JetInfo) error {
+	if info.GoName != name {
+		return fmt.Errorf("jet registered as %s has GoName %s", name, info.GoName)
+	}
+	if !jetName.MatchString(info.SimplicityName) {
+		return fmt.Errorf("jet %s: invalid SimplicityName %q", name, info.SimplicityName)
+	}
+	for dialect, alias := range info.Dialects {
+		if !jetName.MatchString(alias) {
+			return fmt.Errorf("jet %s: invalid name %q in dialect %s", name, alias, dialect)
+		}
+	}
+	for i, param := range info.ParamTypes {
+		if _, err := types.Parse(param); err != nil {
+			return fmt.Errorf("jet %s: parameter %d: %w", name, i, err)
+		}
+	}
+	if _, err := types.Parse(info.ReturnType); err != nil {
+		return fmt.Errorf("jet %s: return type: %w", name, err)
+	}
+	if info.ResultType != "" {
+		if _, err := types.Parse(info.ResultType); err != nil {
+			return fmt.Errorf("jet %s: result type: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Declare adds the jet decl declares to r. Its names must be free: a jet
+// that the table or an earlier Declare has under either name is an error,
+// as is a malformed name or type.
+func (r *JetRegistry) Declare(decl JetDecl) error {
+	goName := decl.GoName
+	if goName == "" {
+		goName = camelCase(decl.Name)
+	}
+	if !token.IsIdentifier(goName) || !token.IsExported(goName) || goName == CustomGoName {
+		return fmt.Errorf("declare jet %s: invalid Go name %q", decl.Name, goName)
+	}
+	info := JetInfo{
+		GoName:         goName,
+		SimplicityName: decl.Name,
+		ParamTypes:     append([]string{}, decl.ParamTypes...),
+		ReturnType:     decl.ReturnType,
+		Dialects:       decl.Dialects,
+		Custom:         true,
+		Package:        decl.Package,
+	}
+	if err := validate(goName, info); err != nil {
+		return fmt.Errorf("declare %w", err)
+	}
+	for _, prev := range []string{r.byJet[decl.Name], goName} {
+		if existing, ok := r.jets[prev]; ok {
+			kind := "builtin jet"
+			if existing.Custom {
+				kind = "declared jet"
+			}
+			return fmt.Errorf("declare jet %s: conflicts with the %s %s (jet::%s)", decl.Name, kind, existing.GoName, existing.SimplicityName)
+		}
+	}
+	r.jets[goName] = info
+	r.byJet[decl.Name] = goName
+	return nil
+}
+
+// camelCase turns a jet name such as my_jet into the Go name MyJet.
+func camelCase(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+// Packages returns the sorted import paths of the stub packages of the
+// declared jets.
+func (r *JetRegistry) Packages() []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, info := range r.jets {
+		if info.Package != "" && !seen[info.Package] {
+			seen[info.Package] = true
+			paths = append(paths, info.Package)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
 // registerBuiltinJets adds all standard Simplicity jets
 func (r *JetRegistry) registerBuiltinJets() {
 	// Signature verification
@@ -32,6 +196,7 @@ func (r *JetRegistry) registerBuiltinJets() {
 		SimplicityName: "bip_0340_verify",
 		ParamTypes:     []string{"u256", "u256", "[u8; 64]"}, // pubkey, msg, sig
 		ReturnType:     "()",
+		Dialects:       map[string]string{"simfony-0.3": "bip0340_verify"},
 	}
 
 	// Transaction introspection
@@ -157,6 +322,8 @@ func (r *JetRegistry) registerBuiltinJets() {
 	// 64-bit arithmetic
 	r.jets["Add64"] = JetInfo{GoName: "Add64", SimplicityName: "add_64", ParamTypes: []string{"u64", "u64"}, ReturnType: "(bool, u64)"}
 	r.jets["Subtract64"] = JetInfo{GoName: "Subtract64", SimplicityName: "subtract_64", ParamTypes: []string{"u64", "u64"}, ReturnType: "(bool, u64)"}
+	// full_subtract_64: (borrow in, a, b) -> (borrow out, a - b - borrow in)
+	r.jets["FullSubtract64"] = JetInfo{GoName: "FullSubtract64", SimplicityName: "full_subtract_64", ParamTypes: []string{"bool", "u64", "u64"}, ReturnType: "(bool, u64)"}
 	r.jets["Multiply64"] = JetInfo{GoName: "Multiply64", SimplicityName: "multiply_64", ParamTypes: []string{"u64", "u64"}, ReturnType: "u128"}
 	r.jets["Divide64"] = JetInfo{GoName: "Divide64", SimplicityName: "divide_64", ParamTypes: []string{"u64", "u64"}, ReturnType: "u64"}
 	r.jets["Modulo64"] = JetInfo{GoName: "Modulo64", SimplicityName: "modulo_64", ParamTypes: []string{"u64", "u64"}, ReturnType: "u64"}
@@ -223,9 +390,9 @@ func (r *JetRegistry) registerBuiltinJets() {
 	// -------------------------------------------------------------------------
 	r.jets["NumInputs"] = JetInfo{GoName: "NumInputs", SimplicityName: "num_inputs", ParamTypes: []string{}, ReturnType: "u32"}
 	r.jets["NumOutputs"] = JetInfo{GoName: "NumOutputs", SimplicityName: "num_outputs", ParamTypes: []string{}, ReturnType: "u32"}
-	r.jets["InputPrevOutpoint"] = JetInfo{GoName: "InputPrevOutpoint", SimplicityName: "input_prev_outpoint", ParamTypes: []string{"u32"}, ReturnType: "(u256, u32)"}
-	r.jets["OutputScriptHash"] = JetInfo{GoName: "OutputScriptHash", SimplicityName: "output_script_hash", ParamTypes: []string{"u32"}, ReturnType: "u256"}
-	r.jets["InputScriptHash"] = JetInfo{GoName: "InputScriptHash", SimplicityName: "input_script_hash", ParamTypes: []string{"u32"}, ReturnType: "u256"}
+	r.jets["InputPrevOutpoint"] = JetInfo{GoName: "InputPrevOutpoint", SimplicityName: "input_prev_outpoint", ParamTypes: []string{"u32"}, ReturnType: "(u256, u32)", ResultType: "Option<(u256, u32)>"}
+	r.jets["OutputScriptHash"] = JetInfo{GoName: "OutputScriptHash", SimplicityName: "output_script_hash", ParamTypes: []string{"u32"}, ReturnType: "u256", ResultType: "Option<u256>"}
+	r.jets["InputScriptHash"] = JetInfo{GoName: "InputScriptHash", SimplicityName: "input_script_hash", ParamTypes: []string{"u32"}, ReturnType: "u256", ResultType: "Option<u256>"}
 	r.jets["CurrentSequence"] = JetInfo{GoName: "CurrentSequence", SimplicityName: "current_sequence", ParamTypes: []string{}, ReturnType: "u32"}
 	r.jets["Version"] = JetInfo{GoName: "Version", SimplicityName: "version", ParamTypes: []string{}, ReturnType: "u32"}
 	r.jets["TransactionId"] = JetInfo{GoName: "TransactionId", SimplicityName: "transaction_id", ParamTypes: []string{}, ReturnType: "u256"}
@@ -234,6 +401,9 @@ func (r *JetRegistry) registerBuiltinJets() {
 	r.jets["TapleafVersion"] = JetInfo{GoName: "TapleafVersion", SimplicityName: "tapleaf_version", ParamTypes: []string{}, ReturnType: "u8"}
 	r.jets["Tappath"] = JetInfo{GoName: "Tappath", SimplicityName: "tappath", ParamTypes: []string{}, ReturnType: "u256"}
 	r.jets["ScriptCmr"] = JetInfo{GoName: "ScriptCmr", SimplicityName: "script_cmr", ParamTypes: []string{}, ReturnType: "u256"}
+	// AnnexHash is the SHA-256 of the current input's taproot annex, None
+	// when the input has no annex. Go code sees it as a *[32]byte.
+	r.jets["AnnexHash"] = JetInfo{GoName: "AnnexHash", SimplicityName: "current_annex_hash", ParamTypes: []string{}, ReturnType: "Option<u256>"}
 
 	// -------------------------------------------------------------------------
 	// SHA-256 variant jets (additional byte-width add operations)
@@ -277,27 +447,43 @@ func (r *JetRegistry) registerBuiltinJets() {
 	r.jets["Eq128"] = JetInfo{GoName: "Eq128", SimplicityName: "eq_128", ParamTypes: []string{"u128", "u128"}, ReturnType: "bool"}
 
 	// Output amount jets — read asset and value of a specific output by index
-	r.jets["OutputAsset"] = JetInfo{GoName: "OutputAsset", SimplicityName: "output_asset", ParamTypes: []string{"u32"}, ReturnType: "u256"}
-	r.jets["OutputAmount"] = JetInfo{GoName: "OutputAmount", SimplicityName: "output_amount", ParamTypes: []string{"u32"}, ReturnType: "u64"}
+	r.jets["OutputAsset"] = JetInfo{GoName: "OutputAsset", SimplicityName: "output_asset", ParamTypes: []string{"u32"}, ReturnType: "u256", ResultType: "Option<Asset1>"}
+	r.jets["OutputAmount"] = JetInfo{GoName: "OutputAmount", SimplicityName: "output_amount", ParamTypes: []string{"u32"}, ReturnType: "u64", ResultType: "Option<(Asset1, Amount1)>"}
 
 	// Input amount jets — read asset and value of a specific input by index
-	r.jets["InputAsset"] = JetInfo{GoName: "InputAsset", SimplicityName: "input_asset", ParamTypes: []string{"u32"}, ReturnType: "u256"}
-	r.jets["InputAmount"] = JetInfo{GoName: "InputAmount", SimplicityName: "input_amount", ParamTypes: []string{"u32"}, ReturnType: "u64"}
+	r.jets["InputAsset"] = JetInfo{GoName: "InputAsset", SimplicityName: "input_asset", ParamTypes: []string{"u32"}, ReturnType: "u256", ResultType: "Option<Asset1>"}
+	r.jets["InputAmount"] = JetInfo{GoName: "InputAmount", SimplicityName: "input_amount", ParamTypes: []string{"u32"}, ReturnType: "u64", ResultType: "Option<(Asset1, Amount1)>"}
 
 	// Current input jets — read asset and value of the input currently being spent
-	r.jets["CurrentAsset"] = JetInfo{GoName: "CurrentAsset", SimplicityName: "current_asset", ParamTypes: []string{}, ReturnType: "u256"}
-	r.jets["CurrentAmount"] = JetInfo{GoName: "CurrentAmount", SimplicityName: "current_amount", ParamTypes: []string{}, ReturnType: "u64"}
+	r.jets["CurrentAsset"] = JetInfo{GoName: "CurrentAsset", SimplicityName: "current_asset", ParamTypes: []string{}, ReturnType: "u256", ResultType: "Asset1"}
+	r.jets["CurrentAmount"] = JetInfo{GoName: "CurrentAmount", SimplicityName: "current_amount", ParamTypes: []string{}, ReturnType: "u64", ResultType: "(Asset1, Amount1)"}
+
+	// TotalFee is the sum of the fee outputs of the spending transaction in
+	// the given explicit asset.
+	r.jets["TotalFee"] = JetInfo{GoName: "TotalFee", SimplicityName: "total_fee", ParamTypes: []string{"u256"}, ReturnType: "u64"}
 
 	// -------------------------------------------------------------------------
-	// Elements asset issuance jets (Liquid/Elements only)
-	// Used for LP token creation and reissuance in AMM pools.
-	// IssuanceAssetAmount: explicit amount of new asset minted at input N
+	// Elements asset issuance and peg-in jets (Liquid/Elements only)
+	// Used to restrict issuance and reissuance, e.g. in AMM pools and
+	// reissuance-token covenants. The indexed jets take an input index; the
+	// value is absent when that input has no issuance or peg-in, so Go code
+	// sees a pointer and tests it against nil.
+	// InputIsIssuance: input N carries an issuance or reissuance
+	// IssuanceAssetAmount: explicit amount of the asset minted at input N
 	// IssuanceTokenAmount: explicit amount of reissuance token minted at input N
-	// NewIssuanceContract: contract hash that determines the issued asset ID
+	// NewIssuanceContract: contract hash of a new issuance at input N
+	// ReissuanceBlinding: blinding nonce of a reissuance at input N
+	// ReissuanceEntropy: asset entropy of a reissuance at input N
+	// InputPegin, CurrentPegin: parent chain genesis hash of a peg-in
 	// -------------------------------------------------------------------------
-	r.jets["IssuanceAssetAmount"] = JetInfo{GoName: "IssuanceAssetAmount", SimplicityName: "issuance_asset_amount", ParamTypes: []string{"u32"}, ReturnType: "u64"}
-	r.jets["IssuanceTokenAmount"] = JetInfo{GoName: "IssuanceTokenAmount", SimplicityName: "issuance_token_amount", ParamTypes: []string{"u32"}, ReturnType: "u64"}
-	r.jets["NewIssuanceContract"] = JetInfo{GoName: "NewIssuanceContract", SimplicityName: "new_issuance_contract", ParamTypes: []string{"u32"}, ReturnType: "u256"}
+	r.jets["InputIsIssuance"] = JetInfo{GoName: "InputIsIssuance", SimplicityName: "std_input_is_issuance", ParamTypes: []string{"u32"}, ReturnType: "bool"}
+	r.jets["IssuanceAssetAmount"] = JetInfo{GoName: "IssuanceAssetAmount", SimplicityName: "issuance_asset_amount", ParamTypes: []string{"u32"}, ReturnType: "Option<u64>"}
+	r.jets["IssuanceTokenAmount"] = JetInfo{GoName: "IssuanceTokenAmount", SimplicityName: "issuance_token_amount", ParamTypes: []string{"u32"}, ReturnType: "Option<u64>"}
+	r.jets["NewIssuanceContract"] = JetInfo{GoName: "NewIssuanceContract", SimplicityName: "new_issuance_contract", ParamTypes: []string{"u32"}, ReturnType: "Option<u256>"}
+	r.jets["ReissuanceBlinding"] = JetInfo{GoName: "ReissuanceBlinding", SimplicityName: "reissuance_blinding", ParamTypes: []string{"u32"}, ReturnType: "Option<u256>"}
+	r.jets["ReissuanceEntropy"] = JetInfo{GoName: "ReissuanceEntropy", SimplicityName: "reissuance_entropy", ParamTypes: []string{"u32"}, ReturnType: "Option<u256>"}
+	r.jets["InputPegin"] = JetInfo{GoName: "InputPegin", SimplicityName: "input_pegin", ParamTypes: []string{"u32"}, ReturnType: "Option<u256>"}
+	r.jets["CurrentPegin"] = JetInfo{GoName: "CurrentPegin", SimplicityName: "current_pegin", ParamTypes: []string{}, ReturnType: "Option<u256>"}
 
 	// -------------------------------------------------------------------------
 	// Synthetic transpiler-only jets (always inlined, never emitted as jet calls)
@@ -316,12 +502,111 @@ func (r *JetRegistry) registerBuiltinJets() {
 	}
 }
 
+// Chains the compiler targets. Liquid and its test networks are Elements
+// chains.
+const (
+	ChainElements = "elements"
+	ChainBitcoin  = "bitcoin"
+)
+
+// elementsJets read the spending transaction or its taproot environment.
+// SimplicityHL defines them only for Elements; on Bitcoin a program has the
+// core jets alone.
+var elementsJets = map[string]bool{
+	"sig_all_hash": true, "version": true, "lock_time": true, "transaction_id": true, "genesis_block_hash": true,
+	"check_lock_height": true, "check_lock_time": true, "check_lock_distance": true, "check_lock_duration": true,
+	"tx_is_final": true, "tx_lock_height": true, "tx_lock_time": true, "tx_lock_distance": true, "tx_lock_duration": true,
+	"num_inputs": true, "num_outputs": true, "input_prev_outpoint": true, "input_script_hash": true, "output_script_hash": true,
+	"current_index": true, "current_prev_outpoint": true, "current_script_hash": true, "current_sequence": true, "current_annex_hash": true,
+	"internal_key": true, "tapleaf_version": true, "tappath": true, "script_cmr": true,
+	"input_asset": true, "input_amount": true, "output_asset": true, "output_amount": true, "current_asset": true, "current_amount": true,
+	"issuance": true, "issuance_asset_amount": true, "issuance_token_amount": true, "new_issuance_contract": true,
+	"reissuance_blinding": true, "reissuance_entropy": true, "input_pegin": true, "current_pegin": true,
+	"total_fee": true,
+}
+
+// helperJets maps the compiler helpers registered as jets, which the
+// transpiler defines in the program, to the jet each helper calls.
+var helperJets = map[string]string{
+	"std_input_is_issuance": "issuance",
+}
+
+// Jet returns the SimplicityHL jet j calls: its SimplicityName, or the jet
+// behind it for a jet lowered to a compiler helper.
+func (j JetInfo) Jet() string {
+	if jet, ok := helperJets[j.SimplicityName]; ok {
+		return jet
+	}
+	return j.SimplicityName
+}
+
+// Result returns the SimplicityHL type of the jet's result.
+func (j JetInfo) Result() string {
+	if j.ResultType != "" {
+		return j.ResultType
+	}
+	return j.ReturnType
+}
+
+// Name returns the name of the jet j calls in the output dialect, which is
+// the name Jet returns unless the dialect knows it by another.
+func (j JetInfo) Name(dialect string) string {
+	if name, ok := j.Dialects[dialect]; ok {
+		return name
+	}
+	return j.Jet()
+}
+
+// Signature returns the type of the jet as Simplicity sees it: the product
+// of its parameter types, () for none and the type itself for one, and the
+// type of its result.
+func (j JetInfo) Signature() (source, target types.Type) {
+	params := make([]types.Type, len(j.ParamTypes))
+	for i, param := range j.ParamTypes {
+		params[i] = mustParse(param)
+	}
+	switch len(params) {
+	case 0:
+		source = &types.Unit{}
+	case 1:
+		source = params[0]
+	default:
+		source = &types.Tuple{Elems: params}
+	}
+	return source, mustParse(j.Result())
+}
+
+// mustParse parses a type of the table, which validate has checked.
+func mustParse(s string) types.Type {
+	t, err := types.Parse(s)
+	if err != nil {
+		panic("jets: " + err.Error())
+	}
+	return t
+}
+
+// Available reports whether the jet exists on chain, ChainElements or
+// ChainBitcoin.
+func (j JetInfo) Available(chain string) bool {
+	return chain != ChainBitcoin || !elementsJets[j.Jet()]
+}
+
 // Lookup returns the jet info for a given Go function name
 func (r *JetRegistry) Lookup(goName string) (JetInfo, bool) {
 	info, ok := r.jets[goName]
 	return info, ok
 }
 
+// LookupJet returns the jet info for a SimplicityHL jet name, the
+// SimplicityName of a registered jet.
+func (r *JetRegistry) LookupJet(name string) (JetInfo, bool) {
+	goName, ok := r.byJet[name]
+	if !ok {
+		return JetInfo{}, false
+	}
+	return r.jets[goName], true
+}
+
 // AllJets returns a copy of all registered jets.
 func (r *JetRegistry) AllJets() map[string]JetInfo {
 	copy := make(map[string]JetInfo, len(r.jets))
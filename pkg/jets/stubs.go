@@ -0,0 +1,111 @@
+package jets
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/types"
+)
+
+// StubsHeader opens the Go file WriteStubs generates.
+const StubsHeader = "// Code generated by go run gen.go from the jet table of pkg/jets. DO NOT EDIT.\n"
+
+// WriteStubs writes the Go source of package std/jets: a function per jet
+// of the table, with the Go types contracts pass and receive, whose body
+// panics since a jet runs only in a compiled program. Generating the stubs
+// from the table keeps them and what the transpiler emits in step.
+func WriteStubs(w io.Writer) error {
+	names := make([]string, 0, len(builtin))
+	for name := range builtin {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	b.WriteString(StubsHeader + "\npackage jets\n")
+	for _, name := range names {
+		info := builtin[name]
+		params := make([]string, len(info.ParamTypes))
+		for i, param := range info.ParamTypes {
+			typ, err := goType(mustParse(param))
+			if err != nil {
+				return fmt.Errorf("jet %s: parameter %d: %w", name, i, err)
+			}
+			params[i] = fmt.Sprintf("x%d %s", i, typ)
+		}
+		results, err := goResults(mustParse(info.ReturnType))
+		if err != nil {
+			return fmt.Errorf("jet %s: return type: %w", name, err)
+		}
+		fmt.Fprintf(&b, "\n// %s calls jet::%s.\nfunc %s(%s) %s {\n\tpanic(unavailable)\n}\n",
+			name, info.Jet(), name, strings.Join(params, ", "), results)
+	}
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// goResults renders the Go results of a jet returning t: none for (), and
+// one per element of a tuple.
+func goResults(t types.Type) (string, error) {
+	switch t := t.(type) {
+	case *types.Unit:
+		return "", nil
+	case *types.Tuple:
+		results := make([]string, len(t.Elems))
+		for i, elem := range t.Elems {
+			typ, err := goType(elem)
+			if err != nil {
+				return "", err
+			}
+			results[i] = typ
+		}
+		return "(" + strings.Join(results, ", ") + ")", nil
+	}
+	return goType(t)
+}
+
+// goType renders the Go type contracts use for a value of t: the unsigned
+// integer of its width, a byte array for u128, u256 and [u8; N], and a
+// pointer for an Option, which is nil for None.
+func goType(t types.Type) (string, error) {
+	switch t := t.(type) {
+	case *types.Bool:
+		return "bool", nil
+	case *types.UInt:
+		switch {
+		case t.Bits <= 8:
+			return "uint8", nil
+		case t.Bits <= 64:
+			return fmt.Sprintf("uint%d", t.Bits), nil
+		}
+		return fmt.Sprintf("[%d]byte", t.Bits/8), nil
+	case *types.Array:
+		elem, err := goType(t.Elem)
+		if err != nil {
+			return "", err
+		}
+		if elem == "uint8" {
+			elem = "byte"
+		}
+		return fmt.Sprintf("[%d]%s", t.Len, elem), nil
+	case *types.Option:
+		elem, err := goType(t.Elem)
+		if err != nil {
+			return "", err
+		}
+		return "*" + elem, nil
+	case *types.Named:
+		if t.Name == "Ctx8" {
+			return "Ctx8", nil
+		}
+	}
+	return "", fmt.Errorf("no Go type for %s", t)
+}
@@ -0,0 +1,415 @@
+// Package optimize rewrites generated SimplicityHL programs so that they
+// compute less, without changing which spends they accept.
+package optimize
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/gensym"
+	"github.com/0ceanslim/go-simplicity/pkg/jets"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+)
+
+// A jet call that a function computes more than once, such as
+//
+//	Left(data: [u8; 32]) => {
+//	    let msg = jet::sig_all_hash();
+//	    ...
+//	},
+//	Right(sig: [u8; 64]) => {
+//	    let msg = jet::sig_all_hash();
+//
+// in two arms of a match, is computed once by a let binding in the nearest
+// scope around every occurrence, here the block holding the match:
+//
+//	let t_3_19: u256 = jet::sig_all_hash();
+//	match witness::W {
+//
+// The binding is a temporary (see pkg/gensym), named after the position
+// of the first call it replaces.
+//
+// Only calls that cannot fail are hoisted, since one evaluated before a
+// match runs on every path through it: jets with a result, over operands
+// built from literals, variables, witnesses, parameters, casts, tuples,
+// arrays, constructors and other such calls. Assertions, unit jets such as
+// bip_0340_verify, functions of the program and matches stay where they
+// are. Two occurrences are the same expression when they are written the
+// same and their variables refer to the same bindings, so a call of a
+// variable that a let or match arm shadows in between is not shared. An
+// expression repeated only inside one arm that is not a block stays in
+// that arm.
+
+// jetTypes maps the jets with a result to its type.
+var jetTypes = func() map[string]string {
+	types := make(map[string]string)
+	for _, info := range jets.NewRegistry().AllJets() {
+		if typ := info.Result(); typ != "()" && typ != "" {
+			types[info.SimplicityName] = typ
+		}
+	}
+	return types
+}()
+
+// CSE computes each pure jet call that a function of code repeats only
+// once, in a let binding of a temporary that code does not already take,
+// named after the line and column of the first call it replaces. It
+// returns the rewritten program and, for each of its lines, the 1-based
+// line of code it comes from; a binding comes from the line of the first
+// call it replaces. Code that does not parse is returned unchanged.
+func CSE(code string) (string, []int) {
+	return CSEAt(code, nil)
+}
+
+// CSEAt is CSE naming each binding after position(line) instead, the
+// source a line of code was generated from, such as a Go statement: the
+// names then stay the same when functions before it change. A line
+// position reports false for is named after itself.
+func CSEAt(code string, position func(line int) (int, int, bool)) (string, []int) {
+	origin := make([]int, strings.Count(code, "\n")+1)
+	for i := range origin {
+		origin[i] = i + 1
+	}
+	var names gensym.Names
+	names.Reserve(code)
+	for {
+		prog, err := shlparse.Parse(code)
+		if err != nil {
+			return code, origin
+		}
+		// One hoist per function and round: the calls left inside a
+		// hoisted one, or around it, are counted again on the new text.
+		var hoists []hoist
+		for _, fn := range prog.Funcs {
+			if h, ok := bestHoist(code, fn); ok {
+				first := h.occurrences[0]
+				line, col := origin[first.Line-1], first.Off-strings.LastIndexByte(code[:first.Off], '\n')
+				if position != nil {
+					if l, c, ok := position(line); ok {
+						line, col = l, c
+					}
+				}
+				h.name = names.At(line, col)
+				hoists = append(hoists, h)
+			}
+		}
+		if len(hoists) == 0 {
+			return code, origin
+		}
+		code, origin = apply(code, origin, hoists)
+	}
+}
+
+// A hoist binds the call of occurrences to name before the statement at
+// offset at, whose line starts with indent, and replaces each occurrence.
+type hoist struct {
+	name, typ   string
+	at          int
+	indent      string
+	occurrences []*shlparse.Call
+}
+
+// env is the chain of bindings in scope, innermost first. Each binding
+// has an id of its own.
+type env struct {
+	name string
+	id   int
+	next *env
+}
+
+func (e *env) lookup(name string) int {
+	for ; e != nil; e = e.next {
+		if e.name == name {
+			return e.id
+		}
+	}
+	return 0
+}
+
+// A frame is a scope on the way from a function body to an occurrence: a
+// block, at the statement holding the occurrence, or a match arm.
+type frame struct {
+	block *shlparse.Block
+	index int  // Statement of block; len(block.Stmts) for its result
+	env   *env // Bindings before the statement
+	arm   *shlparse.Arm
+}
+
+type occurrence struct {
+	call   *shlparse.Call
+	env    *env
+	frames []frame
+}
+
+// walker collects the hoistable calls of one function by key.
+type walker struct {
+	ids    int
+	frames []frame
+	occ    map[string][]occurrence
+	keys   []string // In order of first occurrence
+
+	// annotated holds the type of calls bound by a let with one, which
+	// the transpiler wrote and which is preferred to the registry's.
+	annotated map[*shlparse.Call]string
+}
+
+func (w *walker) bind(e *env, pat shlparse.Pattern) *env {
+	switch p := pat.(type) {
+	case *shlparse.IdentPattern:
+		w.ids++
+		return &env{name: p.Name, id: w.ids, next: e}
+	case *shlparse.TuplePattern:
+		for _, elem := range p.Elems {
+			e = w.bind(e, elem)
+		}
+	case *shlparse.ArrayPattern:
+		for _, elem := range p.Elems {
+			e = w.bind(e, elem)
+		}
+	}
+	return e
+}
+
+func (w *walker) block(b *shlparse.Block, e *env) {
+	for i, stmt := range b.Stmts {
+		w.frames = append(w.frames, frame{block: b, index: i, env: e})
+		switch s := stmt.(type) {
+		case *shlparse.Let:
+			w.expr(s.Value, e)
+			if call, ok := s.Value.(*shlparse.Call); ok && s.Type != nil {
+				w.annotated[call] = s.Type.String()
+			}
+			e = w.bind(e, s.Pattern)
+		case *shlparse.ExprStmt:
+			w.expr(s.X, e)
+		}
+		w.frames = w.frames[:len(w.frames)-1]
+	}
+	if b.Result != nil {
+		w.frames = append(w.frames, frame{block: b, index: len(b.Stmts), env: e})
+		w.expr(b.Result, e)
+		w.frames = w.frames[:len(w.frames)-1]
+	}
+}
+
+// expr returns the key of x and whether x is pure: it cannot fail and
+// depends only on the bindings its key names. It records the pure jet
+// calls under x as occurrences.
+func (w *walker) expr(x shlparse.Expr, e *env) (string, bool) {
+	switch x := x.(type) {
+	case *shlparse.Literal:
+		return x.Text, true
+	case *shlparse.BoolLit:
+		return strconv.FormatBool(x.Value), true
+	case *shlparse.Ident:
+		return x.Name + "#" + strconv.Itoa(e.lookup(x.Name)), true
+	case *shlparse.Path:
+		return x.Module + "::" + x.Name, true
+	case *shlparse.Cast:
+		key, pure := w.expr(x.Arg, e)
+		return "<" + x.From.String() + ">(" + key + ")", pure
+	case *shlparse.Tuple:
+		key, pure := w.list(x.Elems, e)
+		return "(" + key + ")", pure
+	case *shlparse.Array:
+		key, pure := w.list(x.Elems, e)
+		return "[" + key + "]", pure
+	case *shlparse.Call:
+		args, pure := w.list(x.Args, e)
+		key := x.Func
+		if x.TypeArg != nil {
+			key += "<" + x.TypeArg.String() + ">"
+		}
+		key += "(" + args + ")"
+		switch {
+		case x.Func == "Left" || x.Func == "Right" || x.Func == "Some":
+		case strings.HasPrefix(x.Func, "jet::") && jetTypes[strings.TrimPrefix(x.Func, "jet::")] != "":
+			if pure {
+				if _, seen := w.occ[key]; !seen {
+					w.keys = append(w.keys, key)
+				}
+				w.occ[key] = append(w.occ[key], occurrence{call: x, env: e, frames: append([]frame(nil), w.frames...)})
+			}
+		default:
+			pure = false
+		}
+		return key, pure
+	case *shlparse.Match:
+		w.expr(x.Scrutinee, e)
+		for _, arm := range x.Arms {
+			w.frames = append(w.frames, frame{arm: arm})
+			inner := e
+			if arm.Binding != nil {
+				inner = w.bind(e, arm.Binding)
+			}
+			w.expr(arm.Body, inner)
+			w.frames = w.frames[:len(w.frames)-1]
+		}
+	case *shlparse.Block:
+		w.block(x, e)
+	}
+	return "", false
+}
+
+func (w *walker) list(xs []shlparse.Expr, e *env) (string, bool) {
+	keys := make([]string, len(xs))
+	pure := true
+	for i, x := range xs {
+		var ok bool
+		keys[i], ok = w.expr(x, e)
+		pure = pure && ok
+	}
+	return strings.Join(keys, ","), pure
+}
+
+// bestHoist returns the hoist of the longest call that fn repeats, if it
+// has a scope to be bound in.
+func bestHoist(code string, fn *shlparse.Func) (hoist, bool) {
+	w := &walker{occ: make(map[string][]occurrence), annotated: make(map[*shlparse.Call]string)}
+	var e *env
+	for _, param := range fn.Params {
+		e = w.bind(e, &shlparse.IdentPattern{Name: param.Name})
+	}
+	w.block(fn.Body, e)
+
+	var best hoist
+	found := false
+	for _, key := range w.keys {
+		occs := w.occ[key]
+		if len(occs) < 2 {
+			continue
+		}
+		call := occs[0].call
+		text := code[call.Off:call.End]
+		if found && len(text) <= len(code[best.occurrences[0].Off:best.occurrences[0].End]) {
+			continue
+		}
+		if h, ok := hoistFor(code, occs, w.annotated); ok {
+			best, found = h, true
+		}
+	}
+	return best, found
+}
+
+// hoistFor places the binding of occs before the statement where the
+// block nearest to all of them first reaches one.
+func hoistFor(code string, occs []occurrence, annotated map[*shlparse.Call]string) (hoist, bool) {
+	first := occs[0]
+	if strings.Contains(code[first.call.Off:first.call.End], "\n") {
+		return hoist{}, false
+	}
+	common := len(first.frames)
+	for _, o := range occs[1:] {
+		common = min(common, commonFrames(first.frames, o.frames))
+	}
+	if common == 0 || first.frames[common-1].block == nil {
+		// The occurrences share a match arm, which has no statements of
+		// its own to put the binding among.
+		return hoist{}, false
+	}
+	at := first.frames[common-1]
+	for _, o := range occs[1:] {
+		if f := o.frames[common-1]; f.index < at.index {
+			at = f
+		}
+	}
+
+	// Every variable of the call must be in scope, unshadowed, where the
+	// binding goes.
+	visible := true
+	shlparse.Inspect(first.call, func(x shlparse.Expr) bool {
+		if id, ok := x.(*shlparse.Ident); ok && first.env.lookup(id.Name) != at.env.lookup(id.Name) {
+			visible = false
+		}
+		return visible
+	})
+	if !visible {
+		return hoist{}, false
+	}
+
+	var off int
+	if at.index < len(at.block.Stmts) {
+		switch s := at.block.Stmts[at.index].(type) {
+		case *shlparse.Let:
+			off = s.Off
+		case *shlparse.ExprStmt:
+			off = s.Off
+		}
+	} else {
+		switch r := at.block.Result.(type) {
+		case *shlparse.Call:
+			off = r.Off
+		case *shlparse.Match:
+			off = r.Off
+		default:
+			return hoist{}, false
+		}
+	}
+	lineStart := strings.LastIndexByte(code[:off], '\n') + 1
+	indent := code[lineStart:off]
+	if strings.TrimLeft(indent, " \t") != "" {
+		return hoist{}, false
+	}
+
+	h := hoist{
+		typ:    jetTypes[strings.TrimPrefix(first.call.Func, "jet::")],
+		at:     lineStart,
+		indent: indent,
+	}
+	for _, o := range occs {
+		h.occurrences = append(h.occurrences, o.call)
+		if typ, ok := annotated[o.call]; ok {
+			h.typ = typ
+		}
+	}
+	return h, true
+}
+
+// commonFrames returns how many scopes a and b share from the function
+// body in. Two statements of one block share that block.
+func commonFrames(a, b []frame) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n].block == b[n].block && a[n].arm == b[n].arm {
+		n++
+		if a[n-1].block != nil && a[n-1].index != b[n-1].index {
+			break
+		}
+	}
+	return n
+}
+
+// apply makes the edits of hoists to code, from the end so that the
+// offsets of the edits before stay valid, and keeps origin in step.
+func apply(code string, origin []int, hoists []hoist) (string, []int) {
+	type edit struct {
+		start, end int
+		text       string
+		line       int // Origin of a line inserted at start, or 0
+	}
+	var edits []edit
+	for _, h := range hoists {
+		first := h.occurrences[0]
+		text := code[first.Off:first.End]
+		binding := h.indent + "let " + h.name + ": " + h.typ + " = " + text + ";\n"
+		edits = append(edits, edit{h.at, h.at, binding, origin[first.Line-1]})
+		for _, call := range h.occurrences {
+			edits = append(edits, edit{call.Off, call.End, h.name, 0})
+		}
+	}
+	sort.Slice(edits, func(i, j int) bool {
+		if edits[i].start != edits[j].start {
+			return edits[i].start > edits[j].start
+		}
+		// An insertion before the statement goes in after replacements in it.
+		return edits[i].end > edits[j].end
+	})
+	for _, e := range edits {
+		code = code[:e.start] + e.text + code[e.end:]
+		if e.line != 0 {
+			line := strings.Count(code[:e.start], "\n")
+			origin = append(origin[:line], append([]int{e.line}, origin[line:]...)...)
+		}
+	}
+	return code, origin
+}
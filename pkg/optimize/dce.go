@@ -0,0 +1,90 @@
+package optimize
+
+import (
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+)
+
+// A function that main does not call, directly or through the functions it
+// calls, is dead: a helper inlined at every call site is still emitted for
+// reference, and one that no spend path uses may be left over from an
+// entry point compiled on its own. DCE leaves such functions out, together
+// with the doc comment above them and the blank lines after them.
+
+// DCE removes the functions of code that main does not reach. It returns
+// the rewritten program and, for each of its lines, the 1-based line of
+// code it comes from. Code without a main, such as a library, and code
+// that does not parse are returned unchanged.
+func DCE(code string) (string, []int) {
+	lines := strings.SplitAfter(code, "\n")
+	origin := make([]int, 0, len(lines))
+	prog, err := shlparse.Parse(code)
+	if err != nil || !hasMain(prog) {
+		for i := range lines {
+			origin = append(origin, i+1)
+		}
+		return code, origin
+	}
+
+	funcs := make(map[string]*shlparse.Func, len(prog.Funcs))
+	for _, fn := range prog.Funcs {
+		funcs[fn.Name] = fn
+	}
+	live := map[string]bool{"main": true}
+	work := []*shlparse.Func{funcs["main"]}
+	for len(work) > 0 {
+		fn := work[len(work)-1]
+		work = work[:len(work)-1]
+		shlparse.Inspect(fn.Body, func(e shlparse.Expr) bool {
+			if call, ok := e.(*shlparse.Call); ok && !live[call.Func] {
+				if callee, ok := funcs[call.Func]; ok {
+					live[call.Func] = true
+					work = append(work, callee)
+				}
+			}
+			return true
+		})
+	}
+
+	dead := make([]bool, len(lines))
+	for _, fn := range prog.Funcs {
+		if live[fn.Name] {
+			continue
+		}
+		start := fn.Line - 1
+		for start > 0 && strings.HasPrefix(lines[start-1], "//") {
+			start--
+		}
+		// The printer closes a top-level function with a } of its own.
+		end := fn.Line
+		for end < len(lines) && strings.TrimRight(lines[end-1], "\n") != "}" {
+			end++
+		}
+		for end < len(lines) && strings.TrimSpace(lines[end]) == "" && end+1 < len(lines) {
+			end++
+		}
+		for i := start; i < end; i++ {
+			dead[i] = true
+		}
+	}
+
+	var b strings.Builder
+	b.Grow(len(code))
+	for i, line := range lines {
+		if !dead[i] {
+			b.WriteString(line)
+			origin = append(origin, i+1)
+		}
+	}
+	return b.String(), origin
+}
+
+func hasMain(prog *shlparse.Program) bool {
+	for _, fn := range prog.Funcs {
+		if fn.Name == "main" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+// Package report defines the JSON compile report written by simgo -report.
+// Tools that consume reports decode them with Decode and the types below.
+package report
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/types"
+	"github.com/0ceanslim/go-simplicity/pkg/value"
+)
+
+// Version is the report format version. It changes whenever a field is
+// removed or changes meaning; new fields may be added without a bump.
+const Version = 1
+
+// Report is the top-level JSON document.
+type Report struct {
+	Version   int        `json:"version"`
+	Source    string     `json:"source"`
+	Programs  []Program  `json:"programs"`
+	Functions []Function `json:"functions"`
+}
+
+// Program describes one compiled program. A source compiled with several
+// entry points yields one program per entry.
+type Program struct {
+	Entry       string  `json:"entry,omitempty"` // Empty in library mode
+	CMR         string  `json:"cmr,omitempty"`
+	Witnesses   []Value `json:"witnesses"`
+	WitnessBits int     `json:"witness_bits"` // Total size of the witnesses
+	Params      []Value `json:"params"`
+	Stats       *Stats  `json:"stats,omitempty"` // Absent from reports written before it
+}
+
+// Stats is the cost of compiling a program, in total and phase by phase.
+// Times are in nanoseconds.
+type Stats struct {
+	WallNS     int64   `json:"wall_ns"`
+	AllocBytes uint64  `json:"alloc_bytes"`
+	Allocs     uint64  `json:"allocs"`
+	Phases     []Phase `json:"phases"`
+}
+
+// Phase is the cost of one phase of a compile, such as "parsing".
+type Phase struct {
+	Name       string `json:"name"`
+	WallNS     int64  `json:"wall_ns"`
+	AllocBytes uint64 `json:"alloc_bytes"`
+	Allocs     uint64 `json:"allocs"`
+}
+
+// Value is a witness or param module entry. A witness declared with an
+// Either struct also lists its branch selector and the fields of each arm.
+// Bits is the size of a witness, which the spender pays for, and 0 for a
+// param or a type of unknown size. Display renders the value for people, as
+// value.Format does, and Encoding is the hex of its compact bit encoding;
+// both are absent for a type or value the value package cannot read, such
+// as one of a custom type.
+type Value struct {
+	Name     string  `json:"name"`
+	Type     string  `json:"type"`
+	Value    string  `json:"value"`
+	Display  string  `json:"display,omitempty"`
+	Encoding string  `json:"encoding,omitempty"`
+	Bits     int     `json:"bits,omitempty"`
+	Selector string  `json:"selector,omitempty"`
+	Fields   []Field `json:"fields,omitempty"`
+}
+
+// Field is a struct field carried by one arm of an Either witness.
+type Field struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Branch string `json:"branch"` // "Left" or "Right", or a chain such as "Right.Left"
+}
+
+// Function describes a generated function and the Go function it came from.
+type Function struct {
+	GoName   string   `json:"go_name"`
+	Name     string   `json:"name"`
+	Position string   `json:"position"`
+	Params   []Param  `json:"params"`
+	Returns  string   `json:"returns,omitempty"`
+	Jets     []string `json:"jets"`
+	Nodes    int      `json:"nodes"`   // Estimated size; 0 if unknown
+	Entries  []string `json:"entries"` // Entry points that reach the function
+}
+
+// Param is a function parameter.
+type Param struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// New builds a report from the results of compiling source, one result per
+// entry point. Functions shared by several entries are listed once.
+func New(source string, results ...*compiler.CompileResult) *Report {
+	r := &Report{Version: Version, Source: source, Programs: []Program{}, Functions: []Function{}}
+	index := make(map[string]int)
+	for _, result := range results {
+		program := Program{Entry: result.Entry, CMR: result.CMR, Witnesses: []Value{}, WitnessBits: result.TotalWitnessBits, Params: []Value{}}
+		for i, w := range result.Witnesses {
+			value := Value{Name: strings.ToUpper(w.Name), Type: w.DeclaredType(), Value: w.Value, Selector: w.Selector}
+			value.describe()
+			if i < len(result.WitnessBits) {
+				value.Bits = result.WitnessBits[i]
+			}
+			for _, f := range w.Fields {
+				value.Fields = append(value.Fields, Field{Name: f.Name, Type: f.Type, Branch: f.Branch})
+			}
+			program.Witnesses = append(program.Witnesses, value)
+		}
+		for _, c := range result.Constants {
+			param := Value{Name: c.Name, Type: c.Type, Value: c.Value}
+			param.describe()
+			program.Params = append(program.Params, param)
+		}
+		program.Stats = &Stats{
+			WallNS:     result.Stats.Wall.Nanoseconds(),
+			AllocBytes: result.Stats.AllocBytes,
+			Allocs:     result.Stats.Allocs,
+			Phases:     []Phase{},
+		}
+		for _, p := range result.Stats.Phases {
+			program.Stats.Phases = append(program.Stats.Phases, Phase{Name: p.Phase, WallNS: p.Wall.Nanoseconds(), AllocBytes: p.AllocBytes, Allocs: p.Allocs})
+		}
+		r.Programs = append(r.Programs, program)
+
+		for _, info := range result.Functions {
+			i, ok := index[info.Name]
+			if !ok {
+				fn := Function{
+					GoName:   info.GoName,
+					Name:     info.Name,
+					Position: info.Pos.String(),
+					Params:   []Param{},
+					Returns:  info.ReturnType,
+					Jets:     append([]string{}, info.Jets...),
+					Nodes:    info.Nodes,
+					Entries:  []string{},
+				}
+				for _, p := range info.Params {
+					fn.Params = append(fn.Params, Param{Name: p.Name, Type: p.Type})
+				}
+				i = len(r.Functions)
+				index[info.Name] = i
+				r.Functions = append(r.Functions, fn)
+			}
+			if info.Reachable {
+				r.Functions[i].Entries = append(r.Functions[i].Entries, result.Entry)
+			}
+		}
+	}
+	return r
+}
+
+// describe sets the Display and Encoding of v, if its value reads as one
+// of its type.
+func (v *Value) describe() {
+	t, err := types.Parse(v.Type)
+	if err != nil {
+		return
+	}
+	x, err := value.Parse(t, v.Value)
+	if err != nil {
+		return
+	}
+	data, err := value.Encode(t, x)
+	if err != nil {
+		return
+	}
+	v.Display = value.Format(t, x)
+	v.Encoding = hex.EncodeToString(data)
+}
+
+// Write encodes r as indented JSON.
+func (r *Report) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// Decode reads a report, rejecting versions this package does not know.
+func Decode(rd io.Reader) (*Report, error) {
+	var r Report
+	if err := json.NewDecoder(rd).Decode(&r); err != nil {
+		return nil, fmt.Errorf("invalid report: %w", err)
+	}
+	if r.Version != Version {
+		return nil, fmt.Errorf("unsupported report version %d (want %d)", r.Version, Version)
+	}
+	return &r, nil
+}
@@ -0,0 +1,205 @@
+// Package secp256k1 implements the secp256k1 arithmetic that simgo needs:
+// BIP-340 signature verification for the evaluator and BIP-327 key
+// aggregation, which the compiler runs at build time so that programs only
+// carry the resulting key. It favours clarity over speed, is not constant
+// time and never handles secret keys.
+package secp256k1
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// secp256k1 curve parameters.
+var (
+	curveP, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+	curveN, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+	curveG    = point{
+		x: fromHex("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"),
+		y: fromHex("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8"),
+	}
+)
+
+func fromHex(s string) *big.Int {
+	v, _ := new(big.Int).SetString(s, 16)
+	return v
+}
+
+// point is an affine curve point; x == nil is the point at infinity.
+type point struct {
+	x, y *big.Int
+}
+
+func (a point) infinite() bool {
+	return a.x == nil
+}
+
+func addPoints(a, b point) point {
+	switch {
+	case a.infinite():
+		return b
+	case b.infinite():
+		return a
+	}
+	var lambda *big.Int
+	if a.x.Cmp(b.x) == 0 {
+		sum := new(big.Int).Add(a.y, b.y)
+		if sum.Mod(sum, curveP).Sign() == 0 {
+			return point{}
+		}
+		// Tangent: 3x² / 2y
+		num := new(big.Int).Mul(a.x, a.x)
+		num.Mul(num, big.NewInt(3))
+		den := new(big.Int).Lsh(a.y, 1)
+		lambda = num.Mul(num, den.ModInverse(den, curveP))
+	} else {
+		num := new(big.Int).Sub(b.y, a.y)
+		den := new(big.Int).Sub(b.x, a.x)
+		den.Mod(den, curveP)
+		lambda = num.Mul(num, den.ModInverse(den, curveP))
+	}
+	lambda.Mod(lambda, curveP)
+
+	x := new(big.Int).Mul(lambda, lambda)
+	x.Sub(x, a.x).Sub(x, b.x).Mod(x, curveP)
+	y := new(big.Int).Sub(a.x, x)
+	y.Mul(y, lambda).Sub(y, a.y).Mod(y, curveP)
+	return point{x: x, y: y}
+}
+
+func scalarMult(k *big.Int, a point) point {
+	result := point{}
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		result = addPoints(result, result)
+		if k.Bit(i) == 1 {
+			result = addPoints(result, a)
+		}
+	}
+	return result
+}
+
+// liftX returns the curve point with the given x coordinate and an even y,
+// as defined by BIP-340.
+func liftX(x *big.Int) (point, bool) {
+	if x.Cmp(curveP) >= 0 {
+		return point{}, false
+	}
+	c := new(big.Int).Exp(x, big.NewInt(3), curveP)
+	c.Add(c, big.NewInt(7)).Mod(c, curveP)
+	exp := new(big.Int).Add(curveP, big.NewInt(1))
+	exp.Rsh(exp, 2)
+	y := new(big.Int).Exp(c, exp, curveP)
+	if new(big.Int).Exp(y, big.NewInt(2), curveP).Cmp(c) != 0 {
+		return point{}, false
+	}
+	if y.Bit(0) == 1 {
+		y.Sub(curveP, y)
+	}
+	return point{x: x, y: y}, true
+}
+
+func taggedHash(tag string, parts ...[]byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, p := range parts {
+		h.Write(p)
+	}
+	return h.Sum(nil)
+}
+
+// VerifySchnorr checks a BIP-340 signature over msg by the x-only public key
+// pubkey.
+func VerifySchnorr(pubkey, msg, sig []byte) bool {
+	pk, ok := liftX(new(big.Int).SetBytes(pubkey))
+	if !ok {
+		return false
+	}
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	if r.Cmp(curveP) >= 0 || s.Cmp(curveN) >= 0 {
+		return false
+	}
+	e := new(big.Int).SetBytes(taggedHash("BIP0340/challenge", sig[:32], pubkey, msg))
+	e.Mod(e, curveN)
+
+	// R = s·G - e·P
+	negE := new(big.Int).Sub(curveN, e)
+	R := addPoints(scalarMult(s, curveG), scalarMult(negE, pk))
+	return !R.infinite() && R.y.Bit(0) == 0 && R.x.Cmp(r) == 0
+}
+
+// ErrInfinity is returned when aggregated keys cancel out.
+var ErrInfinity = errors.New("aggregate key is the point at infinity")
+
+// liftCompressed returns the point of a 33-byte SEC compressed key.
+func liftCompressed(key []byte) (point, error) {
+	if len(key) != 33 || (key[0] != 2 && key[0] != 3) {
+		return point{}, fmt.Errorf("%x is not a compressed public key", key)
+	}
+	p, ok := liftX(new(big.Int).SetBytes(key[1:]))
+	if !ok {
+		return point{}, fmt.Errorf("%x is not on the curve", key[1:])
+	}
+	if key[0] == 3 {
+		p.y = new(big.Int).Sub(curveP, p.y)
+	}
+	return p, nil
+}
+
+// KeyAgg aggregates 33-byte compressed public keys as BIP-327 KeyAgg does
+// and returns the x-only aggregate key. The order of keys matters.
+func KeyAgg(keys [][]byte) ([]byte, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no keys to aggregate")
+	}
+	points := make([]point, len(keys))
+	for i, key := range keys {
+		p, err := liftCompressed(key)
+		if err != nil {
+			return nil, err
+		}
+		points[i] = p
+	}
+
+	list := taggedHash("KeyAgg list", keys...)
+	// The first key that differs from the first gets coefficient 1.
+	var second []byte
+	for _, key := range keys[1:] {
+		if !bytes.Equal(key, keys[0]) {
+			second = key
+			break
+		}
+	}
+
+	q := point{}
+	for i, key := range keys {
+		a := big.NewInt(1)
+		if second == nil || !bytes.Equal(key, second) {
+			a.SetBytes(taggedHash("KeyAgg coefficient", list, key))
+			a.Mod(a, curveN)
+		}
+		q = addPoints(q, scalarMult(a, points[i]))
+	}
+	if q.infinite() {
+		return nil, ErrInfinity
+	}
+	return q.x.FillBytes(make([]byte, 32)), nil
+}
+
+// KeyAggXOnly aggregates x-only public keys, each taken with an even y
+// coordinate as BIP-340 does.
+func KeyAggXOnly(keys [][]byte) ([]byte, error) {
+	compressed := make([][]byte, len(keys))
+	for i, key := range keys {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("%x is not a 32-byte x-only public key", key)
+		}
+		compressed[i] = append([]byte{2}, key...)
+	}
+	return KeyAgg(compressed)
+}
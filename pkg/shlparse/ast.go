@@ -0,0 +1,316 @@
+// Package shlparse parses the subset of SimplicityHL that the transpiler
+// emits into a syntax tree, so that generated programs can be checked and
+// evaluated without an external toolchain.
+package shlparse
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Program is a parsed SimplicityHL source file.
+type Program struct {
+	Modules []*Module
+	Aliases []*TypeAlias
+	Funcs   []*Func
+}
+
+// Module returns the module with the given name (witness, param), or nil.
+func (p *Program) Module(name string) *Module {
+	for _, m := range p.Modules {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// Func returns the function with the given name, or nil.
+func (p *Program) Func(name string) *Func {
+	for _, f := range p.Funcs {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Module is a `mod name { const ...; }` block.
+type Module struct {
+	Name   string
+	Consts []*Const
+	Line   int
+}
+
+// Const is a constant declared inside a module.
+type Const struct {
+	Name  string
+	Type  Type
+	Value Expr
+	Line  int
+}
+
+// TypeAlias is a `type Name = T;` declaration.
+type TypeAlias struct {
+	Name string
+	Type Type
+	Line int
+}
+
+// Func is a function definition. Result is nil for functions returning
+// unit.
+type Func struct {
+	Name   string
+	Params []*Param
+	Result Type
+	Body   *Block
+	Line   int
+}
+
+// Param is a function parameter.
+type Param struct {
+	Name string
+	Type Type
+}
+
+// Stmt is a statement inside a block.
+type Stmt interface {
+	stmtLine() int
+}
+
+// Let binds the value of an expression to a pattern.
+type Let struct {
+	Pattern Pattern
+	Type    Type // nil when the binding has no annotation
+	Value   Expr
+	Line    int
+	Off     int // Byte offset of let in the source
+}
+
+// ExprStmt is an expression evaluated for its effect.
+type ExprStmt struct {
+	X    Expr
+	Line int
+	Off  int // Byte offset of the expression in the source
+}
+
+func (s *Let) stmtLine() int      { return s.Line }
+func (s *ExprStmt) stmtLine() int { return s.Line }
+
+// Pattern is the left-hand side of a let binding.
+type Pattern interface {
+	patternNode()
+}
+
+// IdentPattern binds a single name; "_" discards the value.
+type IdentPattern struct {
+	Name string
+}
+
+// TuplePattern destructures a tuple.
+type TuplePattern struct {
+	Elems []Pattern
+}
+
+// ArrayPattern destructures an array.
+type ArrayPattern struct {
+	Elems []Pattern
+}
+
+func (*IdentPattern) patternNode() {}
+func (*TuplePattern) patternNode() {}
+func (*ArrayPattern) patternNode() {}
+
+// Expr is an expression.
+type Expr interface {
+	exprLine() int
+}
+
+// Literal is an integer literal in decimal, hexadecimal (0x) or binary (0b).
+type Literal struct {
+	Text string
+	Line int
+}
+
+// BoolLit is true or false.
+type BoolLit struct {
+	Value bool
+	Line  int
+}
+
+// Ident references a local variable or function parameter.
+type Ident struct {
+	Name string
+	Line int
+}
+
+// Path references a module constant such as witness::SIG.
+type Path struct {
+	Module string
+	Name   string
+	Line   int
+}
+
+// Call is a call of a function, jet, macro or builtin. Func holds the name as
+// written: "jet::add_32", "assert!", "unwrap_left", "Some", "check".
+// TypeArg is set for the turbofish form unwrap_left::<T>(x).
+type Call struct {
+	Func    string
+	TypeArg Type
+	Args    []Expr
+	Line    int
+	Off     int // Byte offset of the name in the source
+	End     int // Byte offset just past the closing parenthesis
+}
+
+// Cast is a conversion of the form <T>::into(x).
+type Cast struct {
+	From Type
+	Arg  Expr
+	Line int
+}
+
+// Tuple is a tuple expression; the empty tuple is unit.
+type Tuple struct {
+	Elems []Expr
+	Line  int
+}
+
+// Array is an array expression [a, b, c].
+type Array struct {
+	Elems []Expr
+	Line  int
+}
+
+// Match is a match expression over a sum type or boolean.
+type Match struct {
+	Scrutinee Expr
+	Arms      []*Arm
+	Line      int
+	Off       int // Byte offset of match in the source
+}
+
+// Arm is one arm of a match. Ctor is Left, Right, Some, None, true or false.
+// Binding and Type are nil when the constructor carries no value.
+type Arm struct {
+	Ctor    string
+	Binding Pattern
+	Type    Type
+	Body    Expr
+	Line    int
+}
+
+// Block is a sequence of statements with an optional final expression.
+type Block struct {
+	Stmts  []Stmt
+	Result Expr // nil when the block evaluates to unit
+	Line   int
+}
+
+func (e *Literal) exprLine() int { return e.Line }
+func (e *BoolLit) exprLine() int { return e.Line }
+func (e *Ident) exprLine() int   { return e.Line }
+func (e *Path) exprLine() int    { return e.Line }
+func (e *Call) exprLine() int    { return e.Line }
+func (e *Cast) exprLine() int    { return e.Line }
+func (e *Tuple) exprLine() int   { return e.Line }
+func (e *Array) exprLine() int   { return e.Line }
+func (e *Match) exprLine() int   { return e.Line }
+func (e *Block) exprLine() int   { return e.Line }
+
+// Line returns the source line an expression starts on.
+func Line(e Expr) int {
+	return e.exprLine()
+}
+
+// Inspect traverses e in depth-first order, calling f for each expression.
+// If f returns false, the children of that expression are skipped.
+func Inspect(e Expr, f func(Expr) bool) {
+	if e == nil || !f(e) {
+		return
+	}
+	switch e := e.(type) {
+	case *Call:
+		for _, arg := range e.Args {
+			Inspect(arg, f)
+		}
+	case *Cast:
+		Inspect(e.Arg, f)
+	case *Tuple:
+		for _, elem := range e.Elems {
+			Inspect(elem, f)
+		}
+	case *Array:
+		for _, elem := range e.Elems {
+			Inspect(elem, f)
+		}
+	case *Match:
+		Inspect(e.Scrutinee, f)
+		for _, arm := range e.Arms {
+			Inspect(arm.Body, f)
+		}
+	case *Block:
+		for _, stmt := range e.Stmts {
+			switch s := stmt.(type) {
+			case *Let:
+				Inspect(s.Value, f)
+			case *ExprStmt:
+				Inspect(s.X, f)
+			}
+		}
+		Inspect(e.Result, f)
+	}
+}
+
+// Type is a SimplicityHL type expression.
+type Type interface {
+	fmt.Stringer
+}
+
+// UInt is an unsigned integer type u1 through u256.
+type UInt struct {
+	Bits int
+}
+
+// Bool is the boolean type.
+type Bool struct{}
+
+// TupleType is a product type; the empty tuple is unit.
+type TupleType struct {
+	Elems []Type
+}
+
+// ArrayType is [T; N].
+type ArrayType struct {
+	Elem Type
+	Len  int
+}
+
+// Either is Either<L, R>.
+type Either struct {
+	Left, Right Type
+}
+
+// Option is Option<T>.
+type Option struct {
+	Elem Type
+}
+
+// Named is a type alias such as Ctx8 or Asset1.
+type Named struct {
+	Name string
+}
+
+func (t *UInt) String() string { return fmt.Sprintf("u%d", t.Bits) }
+func (*Bool) String() string   { return "bool" }
+func (t *TupleType) String() string {
+	parts := make([]string, len(t.Elems))
+	for i, e := range t.Elems {
+		parts[i] = e.String()
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+func (t *ArrayType) String() string { return fmt.Sprintf("[%s; %d]", t.Elem, t.Len) }
+func (t *Either) String() string    { return fmt.Sprintf("Either<%s, %s>", t.Left, t.Right) }
+func (t *Option) String() string    { return fmt.Sprintf("Option<%s>", t.Elem) }
+func (t *Named) String() string     { return t.Name }
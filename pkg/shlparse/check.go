@@ -0,0 +1,164 @@
+package shlparse
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ParseType parses a single SimplicityHL type, such as the declared type of
+// a witness constant.
+func ParseType(src string) (Type, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	t, err := p.typ()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.errorf(tok, "unexpected %s after type", tok)
+	}
+	return t, nil
+}
+
+// CheckValue reports whether e is a constant value of type t: a literal,
+// tuple, array or sum constructor whose integers fit their bit widths.
+// Named types are not resolved and accept any value.
+func CheckValue(e Expr, t Type) error {
+	mismatch := func(reason string) error {
+		if reason != "" {
+			reason = " (" + reason + ")"
+		}
+		return fmt.Errorf("expected %s, got %s%s", t, describe(e), reason)
+	}
+
+	switch t := t.(type) {
+	case *Named:
+		return nil
+	case *Bool:
+		if _, ok := e.(*BoolLit); !ok {
+			return mismatch("")
+		}
+		return nil
+	case *UInt:
+		lit, ok := e.(*Literal)
+		if !ok {
+			return mismatch("")
+		}
+		return checkInt(lit.Text, t.Bits, mismatch)
+	case *ArrayType:
+		// Byte arrays are written as one hex literal.
+		if u, ok := t.Elem.(*UInt); ok && u.Bits == 8 {
+			if lit, ok := e.(*Literal); ok {
+				digits := strings.ReplaceAll(lit.Text, "_", "")
+				if !strings.HasPrefix(digits, "0x") {
+					return mismatch("byte arrays are written in hex")
+				}
+				if n := len(digits) - 2; n != 2*t.Len {
+					return mismatch(fmt.Sprintf("%d hex digits, want %d", n, 2*t.Len))
+				}
+				return checkInt(lit.Text, 8*t.Len, mismatch)
+			}
+		}
+		arr, ok := e.(*Array)
+		if !ok {
+			return mismatch("")
+		}
+		if len(arr.Elems) != t.Len {
+			return mismatch(fmt.Sprintf("%d elements, want %d", len(arr.Elems), t.Len))
+		}
+		for _, elem := range arr.Elems {
+			if err := CheckValue(elem, t.Elem); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *TupleType:
+		tup, ok := e.(*Tuple)
+		if !ok {
+			return mismatch("")
+		}
+		if len(tup.Elems) != len(t.Elems) {
+			return mismatch(fmt.Sprintf("%d elements, want %d", len(tup.Elems), len(t.Elems)))
+		}
+		for i, elem := range tup.Elems {
+			if err := CheckValue(elem, t.Elems[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *Either:
+		call, ok := e.(*Call)
+		if !ok || len(call.Args) != 1 || (call.Func != "Left" && call.Func != "Right") {
+			return mismatch("")
+		}
+		if call.Func == "Left" {
+			return CheckValue(call.Args[0], t.Left)
+		}
+		return CheckValue(call.Args[0], t.Right)
+	case *Option:
+		if id, ok := e.(*Ident); ok && id.Name == "None" {
+			return nil
+		}
+		call, ok := e.(*Call)
+		if !ok || len(call.Args) != 1 || call.Func != "Some" {
+			return mismatch("")
+		}
+		return CheckValue(call.Args[0], t.Elem)
+	}
+	return mismatch("")
+}
+
+// checkInt validates an integer literal against a width of bits. Hex and
+// binary literals must spell out the full width, as SimplicityHL requires.
+func checkInt(text string, bits int, mismatch func(string) error) error {
+	digits := strings.ReplaceAll(text, "_", "")
+	base := 10
+	switch {
+	case strings.HasPrefix(digits, "0x"):
+		digits, base = digits[2:], 16
+		if bits%4 != 0 {
+			return mismatch("hex needs a width divisible by 4")
+		}
+		if len(digits) != bits/4 {
+			return mismatch(fmt.Sprintf("%d hex digits, want %d", len(digits), bits/4))
+		}
+	case strings.HasPrefix(digits, "0b"):
+		digits, base = digits[2:], 2
+		if len(digits) != bits {
+			return mismatch(fmt.Sprintf("%d binary digits, want %d", len(digits), bits))
+		}
+	}
+	v, ok := new(big.Int).SetString(digits, base)
+	if !ok {
+		return mismatch("invalid integer")
+	}
+	if v.BitLen() > bits {
+		return mismatch("out of range")
+	}
+	return nil
+}
+
+// describe renders e briefly for error messages.
+func describe(e Expr) string {
+	switch e := e.(type) {
+	case *Literal:
+		return e.Text
+	case *BoolLit:
+		return fmt.Sprintf("%t", e.Value)
+	case *Ident:
+		return e.Name
+	case *Path:
+		return e.Module + "::" + e.Name
+	case *Call:
+		return e.Func + "(...)"
+	case *Tuple:
+		return "a tuple"
+	case *Array:
+		return "an array"
+	}
+	return "an expression"
+}
@@ -0,0 +1,121 @@
+package shlparse
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokInt
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+	col  int
+	off  int // Byte offset in the source
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of input"
+	case tokIdent, tokInt:
+		return t.text
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// Error is a syntax error at a position in the SimplicityHL source.
+type Error struct {
+	Line int
+	Col  int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d:%d: %s", e.Line, e.Col, e.Msg)
+}
+
+// punctuation lists the multi-character operators before the single ones so
+// that the longest match wins.
+var punctuation = []string{"::", "->", "=>", "{", "}", "(", ")", "[", "]", "<", ">", ",", ";", ":", "=", "!"}
+
+// lex splits src into tokens, dropping whitespace and // comments.
+func lex(src string) ([]token, error) {
+	var toks []token
+	line, col := 1, 1
+	size := len(src)
+	advance := func(n int) {
+		for _, r := range src[:n] {
+			if r == '\n' {
+				line++
+				col = 1
+			} else {
+				col++
+			}
+		}
+		src = src[n:]
+	}
+
+	for src != "" {
+		c := src[0]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			advance(1)
+			continue
+		case strings.HasPrefix(src, "//"):
+			end := strings.IndexByte(src, '\n')
+			if end < 0 {
+				end = len(src)
+			}
+			advance(end)
+			continue
+		case isIdentStart(c):
+			n := 1
+			for n < len(src) && isIdentPart(src[n]) {
+				n++
+			}
+			toks = append(toks, token{tokIdent, src[:n], line, col, size - len(src)})
+			advance(n)
+			continue
+		case c >= '0' && c <= '9':
+			n := 1
+			for n < len(src) && isIdentPart(src[n]) {
+				n++
+			}
+			toks = append(toks, token{tokInt, src[:n], line, col, size - len(src)})
+			advance(n)
+			continue
+		}
+
+		matched := false
+		for _, p := range punctuation {
+			if strings.HasPrefix(src, p) {
+				toks = append(toks, token{tokPunct, p, line, col, size - len(src)})
+				advance(len(p))
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil, &Error{Line: line, Col: col, Msg: fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+	toks = append(toks, token{kind: tokEOF, line: line, col: col, off: size})
+	return toks, nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
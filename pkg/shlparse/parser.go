@@ -0,0 +1,622 @@
+package shlparse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Parse parses SimplicityHL source into a Program.
+func Parse(src string) (*Program, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	return p.program()
+}
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// is reports whether the next token is the punctuation or keyword text.
+func (p *parser) is(text string) bool {
+	t := p.peek()
+	return (t.kind == tokPunct || t.kind == tokIdent) && t.text == text
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return &Error{Line: t.line, Col: t.col, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) expect(text string) (token, error) {
+	t := p.next()
+	if (t.kind != tokPunct && t.kind != tokIdent) || t.text != text {
+		return t, p.errorf(t, "expected %q, found %s", text, t)
+	}
+	return t, nil
+}
+
+func (p *parser) ident() (token, error) {
+	t := p.next()
+	if t.kind != tokIdent {
+		return t, p.errorf(t, "expected identifier, found %s", t)
+	}
+	return t, nil
+}
+
+func (p *parser) program() (*Program, error) {
+	prog := &Program{}
+	for p.peek().kind != tokEOF {
+		t := p.peek()
+		switch {
+		case p.is("mod"):
+			m, err := p.module()
+			if err != nil {
+				return nil, err
+			}
+			prog.Modules = append(prog.Modules, m)
+		case p.is("type"):
+			a, err := p.typeAlias()
+			if err != nil {
+				return nil, err
+			}
+			prog.Aliases = append(prog.Aliases, a)
+		case p.is("fn"):
+			f, err := p.function()
+			if err != nil {
+				return nil, err
+			}
+			prog.Funcs = append(prog.Funcs, f)
+		default:
+			return nil, p.errorf(t, "expected mod, type or fn, found %s", t)
+		}
+	}
+	return prog, nil
+}
+
+func (p *parser) module() (*Module, error) {
+	start := p.next()
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	m := &Module{Name: name.text, Line: start.line}
+	for !p.is("}") {
+		kw, err := p.expect("const")
+		if err != nil {
+			return nil, err
+		}
+		cname, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		typ, err := p.typ()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect("="); err != nil {
+			return nil, err
+		}
+		value, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(";"); err != nil {
+			return nil, err
+		}
+		m.Consts = append(m.Consts, &Const{Name: cname.text, Type: typ, Value: value, Line: kw.line})
+	}
+	p.next()
+	return m, nil
+}
+
+func (p *parser) typeAlias() (*TypeAlias, error) {
+	start := p.next()
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect("="); err != nil {
+		return nil, err
+	}
+	typ, err := p.typ()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	return &TypeAlias{Name: name.text, Type: typ, Line: start.line}, nil
+}
+
+func (p *parser) function() (*Func, error) {
+	start := p.next()
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect("("); err != nil {
+		return nil, err
+	}
+	f := &Func{Name: name.text, Line: start.line}
+	for !p.is(")") {
+		pname, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		typ, err := p.typ()
+		if err != nil {
+			return nil, err
+		}
+		f.Params = append(f.Params, &Param{Name: pname.text, Type: typ})
+		if !p.is(")") {
+			if _, err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	p.next()
+	if p.is("->") {
+		p.next()
+		if f.Result, err = p.typ(); err != nil {
+			return nil, err
+		}
+	}
+	if f.Body, err = p.block(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (p *parser) block() (*Block, error) {
+	start, err := p.expect("{")
+	if err != nil {
+		return nil, err
+	}
+	b := &Block{Line: start.line}
+	for !p.is("}") {
+		t := p.peek()
+		if p.is("let") {
+			s, err := p.let()
+			if err != nil {
+				return nil, err
+			}
+			b.Stmts = append(b.Stmts, s)
+			continue
+		}
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case p.is(";"):
+			p.next()
+			b.Stmts = append(b.Stmts, &ExprStmt{X: x, Line: t.line, Off: t.off})
+		case p.is("}"):
+			b.Result = x
+		default:
+			next := p.peek()
+			return nil, p.errorf(next, "expected \";\" or \"}\", found %s", next)
+		}
+	}
+	p.next()
+	return b, nil
+}
+
+func (p *parser) let() (*Let, error) {
+	start := p.next()
+	pat, err := p.pattern()
+	if err != nil {
+		return nil, err
+	}
+	s := &Let{Pattern: pat, Line: start.line, Off: start.off}
+	if p.is(":") {
+		p.next()
+		if s.Type, err = p.typ(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.expect("="); err != nil {
+		return nil, err
+	}
+	if s.Value, err = p.expr(); err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (p *parser) pattern() (Pattern, error) {
+	switch {
+	case p.is("("):
+		elems, err := p.patternList(")")
+		return &TuplePattern{Elems: elems}, err
+	case p.is("["):
+		elems, err := p.patternList("]")
+		return &ArrayPattern{Elems: elems}, err
+	}
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	return &IdentPattern{Name: name.text}, nil
+}
+
+// patternList parses the comma-separated patterns of a tuple or array
+// pattern, from its opening bracket to close.
+func (p *parser) patternList(close string) ([]Pattern, error) {
+	p.next()
+	var elems []Pattern
+	for !p.is(close) {
+		elem, err := p.pattern()
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, elem)
+		if !p.is(close) {
+			if _, err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	p.next()
+	return elems, nil
+}
+
+func (p *parser) expr() (Expr, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokInt:
+		p.next()
+		return &Literal{Text: t.text, Line: t.line}, nil
+	case p.is("{"):
+		return p.block()
+	case p.is("("):
+		return p.tuple()
+	case p.is("["):
+		return p.array()
+	case p.is("<"):
+		return p.cast()
+	case p.is("match"):
+		return p.match()
+	case p.is("true"), p.is("false"):
+		p.next()
+		return &BoolLit{Value: t.text == "true", Line: t.line}, nil
+	case t.kind == tokIdent:
+		return p.nameExpr()
+	}
+	return nil, p.errorf(t, "expected expression, found %s", t)
+}
+
+// nameExpr parses an identifier, a module path, or a call of a function,
+// jet, macro, or constructor.
+func (p *parser) nameExpr() (Expr, error) {
+	first := p.next()
+	var buf [2]string
+	parts := append(buf[:0], first.text)
+	var typeArg Type
+	for p.is("::") {
+		p.next()
+		if p.is("<") {
+			p.next()
+			var err error
+			if typeArg, err = p.typ(); err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(">"); err != nil {
+				return nil, err
+			}
+			break
+		}
+		part, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, part.text)
+	}
+	name := strings.Join(parts, "::")
+	if p.is("!") {
+		p.next()
+		name += "!"
+	}
+
+	if !p.is("(") {
+		if typeArg != nil || strings.HasSuffix(name, "!") {
+			next := p.peek()
+			return nil, p.errorf(next, "expected \"(\" after %s, found %s", name, next)
+		}
+		switch len(parts) {
+		case 1:
+			return &Ident{Name: name, Line: first.line}, nil
+		case 2:
+			return &Path{Module: parts[0], Name: parts[1], Line: first.line}, nil
+		}
+		return nil, p.errorf(first, "unsupported path %s", name)
+	}
+
+	args, err := p.exprList("(", ")")
+	if err != nil {
+		return nil, err
+	}
+	end := p.toks[p.pos-1] // The closing parenthesis
+	return &Call{Func: name, TypeArg: typeArg, Args: args, Line: first.line, Off: first.off, End: end.off + len(end.text)}, nil
+}
+
+// exprList parses a comma-separated list of expressions between open and
+// close, allowing a trailing comma.
+func (p *parser) exprList(open, close string) ([]Expr, error) {
+	if _, err := p.expect(open); err != nil {
+		return nil, err
+	}
+	var list []Expr
+	for !p.is(close) {
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, x)
+		if !p.is(close) {
+			if _, err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	p.next()
+	return list, nil
+}
+
+func (p *parser) tuple() (Expr, error) {
+	start := p.next()
+	tuple := &Tuple{Line: start.line}
+	for !p.is(")") {
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		tuple.Elems = append(tuple.Elems, x)
+		if p.is(")") {
+			// A single parenthesized expression without a comma is grouping.
+			if len(tuple.Elems) == 1 {
+				p.next()
+				return x, nil
+			}
+			break
+		}
+		if _, err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+	p.next()
+	return tuple, nil
+}
+
+func (p *parser) array() (Expr, error) {
+	start := p.peek()
+	elems, err := p.exprList("[", "]")
+	if err != nil {
+		return nil, err
+	}
+	return &Array{Elems: elems, Line: start.line}, nil
+}
+
+func (p *parser) cast() (Expr, error) {
+	start := p.next()
+	from, err := p.typ()
+	if err != nil {
+		return nil, err
+	}
+	for _, text := range []string{">", "::", "into"} {
+		if _, err := p.expect(text); err != nil {
+			return nil, err
+		}
+	}
+	args, err := p.exprList("(", ")")
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != 1 {
+		return nil, p.errorf(start, "into takes one argument, got %d", len(args))
+	}
+	return &Cast{From: from, Arg: args[0], Line: start.line}, nil
+}
+
+func (p *parser) match() (Expr, error) {
+	start := p.next()
+	scrutinee, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	m := &Match{Scrutinee: scrutinee, Line: start.line, Off: start.off}
+	for !p.is("}") {
+		arm, err := p.arm()
+		if err != nil {
+			return nil, err
+		}
+		m.Arms = append(m.Arms, arm)
+		if p.is(",") {
+			p.next()
+		} else if !p.is("}") {
+			next := p.peek()
+			return nil, p.errorf(next, "expected \",\" or \"}\" after match arm, found %s", next)
+		}
+	}
+	p.next()
+	return m, nil
+}
+
+func (p *parser) arm() (*Arm, error) {
+	ctor, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	switch ctor.text {
+	case "Left", "Right", "Some", "None", "true", "false":
+	default:
+		return nil, p.errorf(ctor, "unsupported match pattern %s", ctor.text)
+	}
+	arm := &Arm{Ctor: ctor.text, Line: ctor.line}
+	if p.is("(") {
+		p.next()
+		if arm.Binding, err = p.pattern(); err != nil {
+			return nil, err
+		}
+		if p.is(":") {
+			p.next()
+			if arm.Type, err = p.typ(); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.expect("=>"); err != nil {
+		return nil, err
+	}
+	if arm.Body, err = p.expr(); err != nil {
+		return nil, err
+	}
+	return arm, nil
+}
+
+func (p *parser) typ() (Type, error) {
+	t := p.peek()
+	switch {
+	case p.is("("):
+		p.next()
+		tt := &TupleType{}
+		for !p.is(")") {
+			elem, err := p.typ()
+			if err != nil {
+				return nil, err
+			}
+			tt.Elems = append(tt.Elems, elem)
+			if !p.is(")") {
+				if _, err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+		}
+		p.next()
+		return tt, nil
+	case p.is("["):
+		p.next()
+		elem, err := p.typ()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(";"); err != nil {
+			return nil, err
+		}
+		n := p.next()
+		size, err := strconv.Atoi(n.text)
+		if n.kind != tokInt || err != nil {
+			return nil, p.errorf(n, "expected array length, found %s", n)
+		}
+		if _, err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return &ArrayType{Elem: elem, Len: size}, nil
+	case t.kind != tokIdent:
+		return nil, p.errorf(t, "expected type, found %s", t)
+	}
+
+	p.next()
+	switch t.text {
+	case "bool":
+		return &Bool{}, nil
+	case "Either":
+		args, err := p.typeArgs(t, 2)
+		if err != nil {
+			return nil, err
+		}
+		return &Either{Left: args[0], Right: args[1]}, nil
+	case "Option":
+		args, err := p.typeArgs(t, 1)
+		if err != nil {
+			return nil, err
+		}
+		return &Option{Elem: args[0]}, nil
+	}
+	if strings.HasPrefix(t.text, "u") {
+		if bits, err := strconv.Atoi(t.text[1:]); err == nil {
+			switch bits {
+			case 1, 2, 4, 8, 16, 32, 64, 128, 256:
+				return &UInt{Bits: bits}, nil
+			}
+			return nil, p.errorf(t, "unsupported integer type %s", t.text)
+		}
+	}
+	return &Named{Name: t.text}, nil
+}
+
+func (p *parser) typeArgs(name token, n int) ([]Type, error) {
+	if _, err := p.expect("<"); err != nil {
+		return nil, err
+	}
+	var args []Type
+	for !p.is(">") {
+		arg, err := p.typ()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if !p.is(">") {
+			if _, err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	p.next()
+	if len(args) != n {
+		return nil, p.errorf(name, "%s takes %d type arguments, got %d", name.text, n, len(args))
+	}
+	return args, nil
+}
+
+// ParseExpr parses a single SimplicityHL expression, such as a witness value
+// supplied on the command line.
+func ParseExpr(src string) (Expr, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	x, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %s after expression", t)
+	}
+	return x, nil
+}
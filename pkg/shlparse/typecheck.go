@@ -0,0 +1,452 @@
+package shlparse
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TypeError is a mismatch found by TypeCheck: an expression of type Got
+// where the program declares Want, or a reference to the name Undefined,
+// which nothing in scope declares.
+type TypeError struct {
+	Line int    // Line of the mistyped expression
+	Func string // Function whose body holds it
+	// Callee and Arg locate a call argument: the function called and the
+	// index of the argument. Callee is empty for the body of Func, whose
+	// value has the wrong type for its return type.
+	Callee string
+	Arg    int
+	Param  string // Name of the parameter, for calls of program functions
+	Got    Type
+	Want   Type
+	// Undefined is the name of an undefined identifier; Callee, Got and
+	// Want are empty then.
+	Undefined string
+	// Const is the path of the module constant, such as witness::R, whose
+	// value has the type Got rather than its declared Want; Func is empty
+	// then.
+	Const string
+}
+
+func (e *TypeError) Error() string {
+	if e.Undefined != "" && e.Func == "" {
+		return fmt.Sprintf("line %d: %s is not defined in the module", e.Line, e.Undefined)
+	}
+	if e.Undefined != "" {
+		return fmt.Sprintf("line %d: %s is not defined in fn %s", e.Line, e.Undefined, e.Func)
+	}
+	if e.Const != "" {
+		return fmt.Sprintf("line %d: const %s is %s, but its value is %s", e.Line, e.Const, TypeString(e.Want), TypeString(e.Got))
+	}
+	if e.Callee == "" {
+		return fmt.Sprintf("line %d: fn %s returns %s, but its body is %s", e.Line, e.Func, TypeString(e.Want), TypeString(e.Got))
+	}
+	return fmt.Sprintf("line %d: argument %d of %s is %s, but %s is passed", e.Line, e.Arg+1, e.Callee, TypeString(e.Want), TypeString(e.Got))
+}
+
+// JetTypes returns the types of the jet called name, without the jet::
+// prefix. A nil params leaves the arguments of the jet unchecked, and ok
+// false the jet altogether.
+type JetTypes func(name string) (params []Type, result Type, ok bool)
+
+// TypeCheck infers the types of the expressions of prog and reports each
+// module constant whose value has a type other than its declared one, each
+// function whose body has a type other than its declared result, each
+// call that passes an argument of a type other than the parameter's, to a
+// function of prog or a jet that jets knows, and each identifier that no
+// parameter, let or match arm in scope declares. An expression whose type is
+// not known, such as the result of an unknown jet, is not checked, so that
+// every report is a real mismatch.
+func TypeCheck(prog *Program, jets JetTypes) []*TypeError {
+	c := &typeChecker{prog: prog, jets: jets, aliases: make(map[string]Type)}
+	for _, a := range prog.Aliases {
+		c.aliases[a.Name] = a.Type
+	}
+	for _, m := range prog.Modules {
+		for _, k := range m.Consts {
+			if got := c.infer(k.Value, &typeScope{}); !c.assignable(k.Type, got) {
+				c.errs = append(c.errs, &TypeError{Line: k.Line, Const: m.Name + "::" + k.Name, Got: got, Want: k.Type})
+			}
+		}
+	}
+	for _, fn := range prog.Funcs {
+		c.fn = fn
+		sc := &typeScope{vars: make(map[string]Type)}
+		for _, p := range fn.Params {
+			sc.vars[p.Name] = p.Type
+		}
+		got := c.infer(fn.Body, sc)
+		want := fn.Result
+		if want == nil {
+			want = unit
+		}
+		if !c.assignable(want, got) {
+			line := fn.Line
+			if fn.Body.Result != nil {
+				line = Line(fn.Body.Result)
+			}
+			c.errs = append(c.errs, &TypeError{Line: line, Func: fn.Name, Got: got, Want: want})
+		}
+	}
+	return c.errs
+}
+
+// unit is the type of a block without a result.
+var unit = &TupleType{}
+
+// untyped is the type of an integer literal, which takes the type of the
+// unsigned integer or byte array it is used as when it fits.
+type untyped struct {
+	text string
+}
+
+func (t *untyped) String() string { return "the literal " + t.text }
+
+// TypeString formats t, which may be partly unknown: a sum constructor such
+// as Left(5) does not tell the type of its other side, written "_".
+func TypeString(t Type) string {
+	switch t := t.(type) {
+	case nil:
+		return "_"
+	case *TupleType:
+		parts := make([]string, len(t.Elems))
+		for i, e := range t.Elems {
+			parts[i] = TypeString(e)
+		}
+		return "(" + strings.Join(parts, ", ") + ")"
+	case *ArrayType:
+		return fmt.Sprintf("[%s; %d]", TypeString(t.Elem), t.Len)
+	case *Either:
+		return fmt.Sprintf("Either<%s, %s>", TypeString(t.Left), TypeString(t.Right))
+	case *Option:
+		return fmt.Sprintf("Option<%s>", TypeString(t.Elem))
+	}
+	return t.String()
+}
+
+type typeChecker struct {
+	prog    *Program
+	jets    JetTypes
+	aliases map[string]Type
+	fn      *Func
+	errs    []*TypeError
+}
+
+// funcName is the name of the function being checked, "" for the value
+// of a module constant.
+func (c *typeChecker) funcName() string {
+	if c.fn == nil {
+		return ""
+	}
+	return c.fn.Name
+}
+
+// typeScope holds the types of the locals of a block or match arm.
+type typeScope struct {
+	vars   map[string]Type
+	parent *typeScope
+}
+
+func (s *typeScope) lookup(name string) (Type, bool) {
+	for ; s != nil; s = s.parent {
+		if t, ok := s.vars[name]; ok {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// infer returns the type of e, or nil when it is not known.
+func (c *typeChecker) infer(e Expr, sc *typeScope) Type {
+	switch e := e.(type) {
+	case *Literal:
+		return &untyped{text: e.Text}
+	case *BoolLit:
+		return &Bool{}
+	case *Ident:
+		t, ok := sc.lookup(e.Name)
+		if !ok && e.Name != "None" {
+			c.errs = append(c.errs, &TypeError{Line: e.Line, Func: c.funcName(), Undefined: e.Name})
+		}
+		return t
+	case *Path:
+		if m := c.prog.Module(e.Module); m != nil {
+			for _, k := range m.Consts {
+				if k.Name == e.Name {
+					return k.Type
+				}
+			}
+		}
+	case *Cast:
+		c.infer(e.Arg, sc)
+	case *Tuple:
+		t := &TupleType{Elems: make([]Type, len(e.Elems))}
+		for i, elem := range e.Elems {
+			t.Elems[i] = c.infer(elem, sc)
+		}
+		return t
+	case *Array:
+		t := &ArrayType{Len: len(e.Elems)}
+		for _, elem := range e.Elems {
+			if elemType := c.infer(elem, sc); t.Elem == nil {
+				t.Elem = elemType
+			}
+		}
+		return t
+	case *Match:
+		scrutinee := c.resolve(c.infer(e.Scrutinee, sc))
+		var result Type
+		for _, arm := range e.Arms {
+			armScope := &typeScope{vars: make(map[string]Type), parent: sc}
+			if arm.Binding != nil {
+				payload := arm.Type
+				if payload == nil {
+					payload = armPayload(scrutinee, arm.Ctor)
+				}
+				c.bind(arm.Binding, payload, armScope)
+			}
+			if t := c.infer(arm.Body, armScope); result == nil {
+				result = t
+			}
+		}
+		return result
+	case *Block:
+		blockScope := &typeScope{vars: make(map[string]Type), parent: sc}
+		for _, stmt := range e.Stmts {
+			switch s := stmt.(type) {
+			case *Let:
+				t := c.infer(s.Value, blockScope)
+				if s.Type != nil {
+					t = s.Type
+				}
+				c.bind(s.Pattern, t, blockScope)
+			case *ExprStmt:
+				c.infer(s.X, blockScope)
+			}
+		}
+		if e.Result == nil {
+			return unit
+		}
+		return c.infer(e.Result, blockScope)
+	case *Call:
+		return c.inferCall(e, sc)
+	}
+	return nil
+}
+
+// inferCall returns the type of a call and checks its arguments.
+func (c *typeChecker) inferCall(e *Call, sc *typeScope) Type {
+	args := make([]Type, len(e.Args))
+	for i, arg := range e.Args {
+		args[i] = c.infer(arg, sc)
+	}
+	arg := func(i int) Type {
+		if i < len(args) {
+			return c.resolve(args[i])
+		}
+		return nil
+	}
+	switch e.Func {
+	case "assert!":
+		c.checkArgs(e, args, []Type{&Bool{}}, nil)
+		return unit
+	case "panic!":
+		return nil // Any type: the arm it ends does not return
+	case "Left":
+		return &Either{Left: arg(0)}
+	case "Right":
+		return &Either{Right: arg(0)}
+	case "Some":
+		return &Option{Elem: arg(0)}
+	case "unwrap_left":
+		if t, ok := arg(0).(*Either); ok {
+			return t.Left
+		}
+		return nil
+	case "unwrap_right":
+		if t, ok := arg(0).(*Either); ok {
+			return t.Right
+		}
+		return nil
+	case "unwrap":
+		if t, ok := arg(0).(*Option); ok {
+			return t.Elem
+		}
+		return nil
+	}
+	if name, ok := strings.CutPrefix(e.Func, "jet::"); ok {
+		if c.jets == nil {
+			return nil
+		}
+		params, result, ok := c.jets(name)
+		if !ok {
+			return nil
+		}
+		if params != nil {
+			c.checkArgs(e, args, params, nil)
+		}
+		return result
+	}
+	fn := c.prog.Func(e.Func)
+	if fn == nil {
+		return nil
+	}
+	params := make([]Type, len(fn.Params))
+	names := make([]string, len(fn.Params))
+	for i, p := range fn.Params {
+		params[i], names[i] = p.Type, p.Name
+	}
+	c.checkArgs(e, args, params, names)
+	if fn.Result == nil {
+		return unit
+	}
+	return fn.Result
+}
+
+// checkArgs reports the arguments of e whose types are not those of the
+// parameters. A call with the wrong number of arguments is left to the
+// parser's callers, which report it at evaluation.
+func (c *typeChecker) checkArgs(e *Call, args, params []Type, names []string) {
+	if len(args) != len(params) {
+		return
+	}
+	for i, want := range params {
+		if c.assignable(want, args[i]) {
+			continue
+		}
+		err := &TypeError{Line: Line(e.Args[i]), Func: c.funcName(), Callee: e.Func, Arg: i, Got: args[i], Want: want}
+		if names != nil {
+			err.Param = names[i]
+		}
+		c.errs = append(c.errs, err)
+	}
+}
+
+// bind gives the names of pattern their types in t.
+func (c *typeChecker) bind(pattern Pattern, t Type, sc *typeScope) {
+	switch p := pattern.(type) {
+	case *IdentPattern:
+		if p.Name != "_" {
+			sc.vars[p.Name] = t
+		}
+	case *TuplePattern:
+		tuple, _ := c.resolve(t).(*TupleType)
+		for i, elem := range p.Elems {
+			var elemType Type
+			if tuple != nil && i < len(tuple.Elems) {
+				elemType = tuple.Elems[i]
+			}
+			c.bind(elem, elemType, sc)
+		}
+	case *ArrayPattern:
+		var elemType Type
+		if array, ok := c.resolve(t).(*ArrayType); ok {
+			elemType = array.Elem
+		}
+		for _, elem := range p.Elems {
+			c.bind(elem, elemType, sc)
+		}
+	}
+}
+
+// byteArrayBits returns the width in bits of t if it is a byte array.
+func byteArrayBits(t Type) (int, bool) {
+	if a, ok := t.(*ArrayType); ok {
+		if u, ok := a.Elem.(*UInt); ok && u.Bits == 8 {
+			return 8 * a.Len, true
+		}
+	}
+	return 0, false
+}
+
+// armPayload is the type of the value an arm of ctor binds out of a
+// scrutinee of type t.
+func armPayload(t Type, ctor string) Type {
+	switch t := t.(type) {
+	case *Either:
+		if ctor == "Left" {
+			return t.Left
+		}
+		return t.Right
+	case *Option:
+		return t.Elem
+	}
+	return nil
+}
+
+// resolve replaces the aliases of t declared by the program. Other named
+// types, such as Ctx8, stay named.
+func (c *typeChecker) resolve(t Type) Type {
+	for i := 0; i < len(c.aliases); i++ {
+		named, ok := t.(*Named)
+		if !ok || c.aliases[named.Name] == nil {
+			break
+		}
+		t = c.aliases[named.Name]
+	}
+	return t
+}
+
+// assignable reports whether a value of type got may be used where want is
+// declared. Unknown and named types are assignable to and from anything,
+// and a byte array to and from the integer of its width, such as a
+// [u8; 32] hash passed to jet::eq_256: the transpiler maps Go's [32]byte
+// to either.
+func (c *typeChecker) assignable(want, got Type) bool {
+	want, got = c.resolve(want), c.resolve(got)
+	if want == nil || got == nil {
+		return true
+	}
+	if bits, ok := byteArrayBits(want); ok {
+		if g, ok := got.(*UInt); ok {
+			return g.Bits == bits
+		}
+	}
+	if bits, ok := byteArrayBits(got); ok {
+		if w, ok := want.(*UInt); ok {
+			return w.Bits == bits
+		}
+	}
+	if _, ok := want.(*Named); ok {
+		return true
+	}
+	if _, ok := got.(*Named); ok {
+		return true
+	}
+	if lit, ok := got.(*untyped); ok {
+		switch want.(type) {
+		case *UInt, *ArrayType:
+			return CheckValue(&Literal{Text: lit.text}, want) == nil
+		}
+		return false
+	}
+	switch w := want.(type) {
+	case *UInt:
+		g, ok := got.(*UInt)
+		return ok && g.Bits == w.Bits
+	case *Bool:
+		_, ok := got.(*Bool)
+		return ok
+	case *TupleType:
+		g, ok := got.(*TupleType)
+		if !ok || len(g.Elems) != len(w.Elems) {
+			return false
+		}
+		for i := range w.Elems {
+			if !c.assignable(w.Elems[i], g.Elems[i]) {
+				return false
+			}
+		}
+		return true
+	case *ArrayType:
+		g, ok := got.(*ArrayType)
+		return ok && g.Len == w.Len && c.assignable(w.Elem, g.Elem)
+	case *Either:
+		g, ok := got.(*Either)
+		return ok && c.assignable(w.Left, g.Left) && c.assignable(w.Right, g.Right)
+	case *Option:
+		g, ok := got.(*Option)
+		return ok && c.assignable(w.Elem, g.Elem)
+	}
+	return true
+}
@@ -0,0 +1,699 @@
+// Package testgen lifts table-driven Go tests of contract predicates into
+// Simplicity test vectors. It backs the `simgo test-gen` subcommand.
+//
+// A contract predicate is an exported function returning bool. Its neighbour
+// _test.go files are scanned for calls with literal arguments whose result is
+// compared against a literal bool, either through a table:
+//
+//	tests := []struct {
+//		amount uint64
+//		want   bool
+//	}{{1500, true}, {10, false}}
+//	for _, tc := range tests {
+//		if got := AmountOk(tc.amount); got != tc.want { ... }
+//	}
+//
+// or directly: if !AmountOk(1500) { ... }. Cases whose inputs are not
+// literals are skipped with a warning.
+package testgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/transpiler"
+	simtypes "github.com/0ceanslim/go-simplicity/pkg/types"
+)
+
+// FormatVersion is the version of the JSON test-vector format.
+const FormatVersion = 1
+
+// WitnessEntry is one witness value in the SimplicityHL .wit format.
+type WitnessEntry struct {
+	Value string `json:"value"`
+	Type  string `json:"type"`
+}
+
+// Vector is a single test case.
+type Vector struct {
+	Name     string                  `json:"name"`
+	Entry    string                  `json:"entry"`
+	Witness  map[string]WitnessEntry `json:"witness"`
+	Expected bool                    `json:"expected"`
+}
+
+// Suite holds the vectors extracted for one contract file.
+type Suite struct {
+	Version  int      `json:"version"`
+	Contract string   `json:"contract"`
+	Vectors  []Vector `json:"vectors"`
+}
+
+// Warning describes a test case that was skipped.
+type Warning struct {
+	Pos     token.Position
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s", w.Pos, w.Message)
+}
+
+// predicate is an entry point candidate found in the contract file.
+type predicate struct {
+	name   string
+	params []string
+}
+
+// testCase is a call to a predicate with its argument expressions.
+type testCase struct {
+	name     string
+	entry    string
+	args     []ast.Expr
+	expected bool
+	pos      token.Position
+}
+
+// Extract compiles each predicate exercised by the _test.go files next to
+// contractPath and returns the resulting test vectors.
+func Extract(contractPath string, config compiler.Config) (*Suite, []Warning, error) {
+	source, err := os.ReadFile(contractPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read contract: %w", err)
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, contractPath, source, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse contract: %w", err)
+	}
+	predicates := findPredicates(file)
+
+	testFiles, err := filepath.Glob(filepath.Join(filepath.Dir(contractPath), "*_test.go"))
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(testFiles)
+
+	var cases []testCase
+	var warnings []Warning
+	for _, path := range testFiles {
+		testFile, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		c, w := findCases(fset, testFile, predicates)
+		cases = append(cases, c...)
+		warnings = append(warnings, w...)
+	}
+
+	if config.Target == "" {
+		config.Target = "simplicityhl"
+	}
+	witnessTypes := make(map[string]map[string]transpiler.WitnessValue)
+	suite := &Suite{Version: FormatVersion, Contract: contractPath}
+	for _, tc := range cases {
+		types, ok := witnessTypes[tc.entry]
+		if !ok {
+			entryConfig := config
+			entryConfig.Entry = tc.entry
+			c := compiler.New(entryConfig)
+			if _, err := c.Compile(string(source), contractPath); err != nil {
+				return nil, warnings, fmt.Errorf("entry %s: %w", tc.entry, err)
+			}
+			types = make(map[string]transpiler.WitnessValue)
+			for _, w := range c.Witnesses() {
+				types[w.Name] = w
+			}
+			witnessTypes[tc.entry] = types
+		}
+
+		vector := Vector{Name: tc.name, Entry: tc.entry, Witness: make(map[string]WitnessEntry), Expected: tc.expected}
+		skipped := false
+		for i, param := range predicates[tc.entry].params {
+			name := transpiler.WitnessName(param)
+			value, err := witnessLiteral(tc.args[i], types[name])
+			if err != nil {
+				warnings = append(warnings, Warning{Pos: tc.pos, Message: fmt.Sprintf("skipping %s: argument %s: %v", tc.name, param, err)})
+				skipped = true
+				break
+			}
+			vector.Witness[name] = WitnessEntry{Value: value, Type: types[name].Type}
+		}
+		if !skipped {
+			suite.Vectors = append(suite.Vectors, vector)
+		}
+	}
+	return suite, warnings, nil
+}
+
+// findPredicates returns the exported functions of file that take
+// parameters and return a single bool.
+func findPredicates(file *ast.File) map[string]predicate {
+	predicates := make(map[string]predicate)
+	for _, decl := range file.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok || funcDecl.Recv != nil || !funcDecl.Name.IsExported() {
+			continue
+		}
+		results := funcDecl.Type.Results
+		if results == nil || len(results.List) != 1 || len(results.List[0].Names) > 1 {
+			continue
+		}
+		if ident, ok := results.List[0].Type.(*ast.Ident); !ok || ident.Name != "bool" {
+			continue
+		}
+		p := predicate{name: funcDecl.Name.Name}
+		for _, field := range funcDecl.Type.Params.List {
+			for _, name := range field.Names {
+				p.params = append(p.params, name.Name)
+			}
+		}
+		predicates[p.name] = p
+	}
+	return predicates
+}
+
+// findCases collects the predicate calls asserted by the Test functions of a
+// _test.go file.
+func findCases(fset *token.FileSet, file *ast.File, predicates map[string]predicate) ([]testCase, []Warning) {
+	var cases []testCase
+	var warnings []Warning
+	for _, decl := range file.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok || funcDecl.Body == nil || !strings.HasPrefix(funcDecl.Name.Name, "Test") {
+			continue
+		}
+		f := &caseFinder{
+			fset:       fset,
+			test:       funcDecl.Name.Name,
+			predicates: predicates,
+			tables:     make(map[string]*ast.CompositeLit),
+		}
+		ast.Inspect(funcDecl.Body, f.visit)
+		cases = append(cases, f.cases...)
+		warnings = append(warnings, f.warnings...)
+	}
+	return cases, warnings
+}
+
+type caseFinder struct {
+	fset       *token.FileSet
+	test       string
+	predicates map[string]predicate
+	tables     map[string]*ast.CompositeLit // variable name → []struct{...}{...}
+	cases      []testCase
+	warnings   []Warning
+}
+
+func (f *caseFinder) visit(n ast.Node) bool {
+	switch node := n.(type) {
+	case *ast.AssignStmt:
+		for i, lhs := range node.Lhs {
+			if ident, ok := lhs.(*ast.Ident); ok && i < len(node.Rhs) {
+				if lit := structTable(node.Rhs[i]); lit != nil {
+					f.tables[ident.Name] = lit
+				}
+			}
+		}
+	case *ast.ValueSpec:
+		for i, ident := range node.Names {
+			if i < len(node.Values) {
+				if lit := structTable(node.Values[i]); lit != nil {
+					f.tables[ident.Name] = lit
+				}
+			}
+		}
+	case *ast.RangeStmt:
+		table := structTable(node.X)
+		if ident, ok := node.X.(*ast.Ident); ok {
+			table = f.tables[ident.Name]
+		}
+		row, ok := node.Value.(*ast.Ident)
+		if table != nil && ok {
+			f.tableCases(table, row.Name, node.Body)
+			return false
+		}
+	case *ast.IfStmt:
+		f.directCase(node.Cond)
+	}
+	return true
+}
+
+// structTable returns expr if it is a slice or array literal of structs.
+func structTable(expr ast.Expr) *ast.CompositeLit {
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		return nil
+	}
+	arr, ok := lit.Type.(*ast.ArrayType)
+	if !ok {
+		return nil
+	}
+	if _, ok := arr.Elt.(*ast.StructType); !ok {
+		return nil
+	}
+	return lit
+}
+
+// tableCases expands a range loop over a table into one case per row.
+func (f *caseFinder) tableCases(table *ast.CompositeLit, row string, body *ast.BlockStmt) {
+	var fields []string
+	for _, field := range table.Type.(*ast.ArrayType).Elt.(*ast.StructType).Fields.List {
+		for _, name := range field.Names {
+			fields = append(fields, name.Name)
+		}
+	}
+
+	// Find the predicate call and the field holding the expected result.
+	var call *ast.CallExpr
+	var argFields []string
+	used := make(map[string]bool)
+	ast.Inspect(body, func(n ast.Node) bool {
+		c, ok := n.(*ast.CallExpr)
+		if !ok || call != nil {
+			return true
+		}
+		ident, ok := c.Fun.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		if _, ok := f.predicates[ident.Name]; !ok {
+			return true
+		}
+		var names []string
+		for _, arg := range c.Args {
+			name := rowField(arg, row)
+			if name == "" {
+				return true
+			}
+			names = append(names, name)
+		}
+		call, argFields = c, names
+		for _, name := range names {
+			used[name] = true
+		}
+		return false
+	})
+	if call == nil {
+		return
+	}
+
+	expectField := ""
+	ast.Inspect(body, func(n ast.Node) bool {
+		bin, ok := n.(*ast.BinaryExpr)
+		if !ok || (bin.Op != token.EQL && bin.Op != token.NEQ) || expectField != "" {
+			return true
+		}
+		for _, side := range []ast.Expr{bin.X, bin.Y} {
+			if name := rowField(side, row); name != "" && !used[name] {
+				expectField = name
+			}
+		}
+		return true
+	})
+	entry := call.Fun.(*ast.Ident).Name
+	if expectField == "" {
+		f.warn(call.Pos(), fmt.Sprintf("skipping %s: no expected result compared against %s", f.test, entry))
+		return
+	}
+
+	for i, elt := range table.Elts {
+		rowLit, ok := elt.(*ast.CompositeLit)
+		if !ok {
+			continue
+		}
+		values := rowValues(rowLit, fields)
+		name := fmt.Sprintf("%s/%d", f.test, i)
+		if desc, ok := stringLiteral(values["name"]); ok {
+			name = fmt.Sprintf("%s/%s", f.test, desc)
+		}
+		expected, ok := boolLiteral(values[expectField])
+		if !ok {
+			f.warn(rowLit.Pos(), fmt.Sprintf("skipping %s: expected result %s is not a bool literal", name, expectField))
+			continue
+		}
+		var args []ast.Expr
+		for _, field := range argFields {
+			args = append(args, values[field])
+		}
+		f.cases = append(f.cases, testCase{
+			name:     name,
+			entry:    entry,
+			args:     args,
+			expected: expected,
+			pos:      f.fset.Position(rowLit.Pos()),
+		})
+	}
+}
+
+// directCase recognises if-conditions that assert a single predicate call:
+// !Pred(...) and Pred(...) != true expect acceptance; Pred(...) and
+// Pred(...) != false expect rejection.
+func (f *caseFinder) directCase(cond ast.Expr) {
+	var call ast.Expr
+	expected := false
+	switch c := cond.(type) {
+	case *ast.UnaryExpr:
+		if c.Op != token.NOT {
+			return
+		}
+		call, expected = c.X, true
+	case *ast.BinaryExpr:
+		var lit bool
+		var ok bool
+		if lit, ok = boolLiteral(c.Y); ok {
+			call = c.X
+		} else if lit, ok = boolLiteral(c.X); ok {
+			call = c.Y
+		} else {
+			return
+		}
+		switch c.Op {
+		case token.NEQ:
+			expected = lit
+		case token.EQL:
+			expected = !lit
+		default:
+			return
+		}
+	default:
+		call = cond
+	}
+
+	callExpr, ok := call.(*ast.CallExpr)
+	if !ok {
+		return
+	}
+	ident, ok := callExpr.Fun.(*ast.Ident)
+	if !ok {
+		return
+	}
+	if _, ok := f.predicates[ident.Name]; !ok {
+		return
+	}
+	pos := f.fset.Position(callExpr.Pos())
+	f.cases = append(f.cases, testCase{
+		name:     fmt.Sprintf("%s/line%d", f.test, pos.Line),
+		entry:    ident.Name,
+		args:     callExpr.Args,
+		expected: expected,
+		pos:      pos,
+	})
+}
+
+func (f *caseFinder) warn(pos token.Pos, msg string) {
+	f.warnings = append(f.warnings, Warning{Pos: f.fset.Position(pos), Message: msg})
+}
+
+// rowField returns F if expr is row.F.
+func rowField(expr ast.Expr, row string) string {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	if ident, ok := sel.X.(*ast.Ident); ok && ident.Name == row {
+		return sel.Sel.Name
+	}
+	return ""
+}
+
+// rowValues maps field names to the expressions of one table row, accepting
+// both keyed and positional literals.
+func rowValues(lit *ast.CompositeLit, fields []string) map[string]ast.Expr {
+	values := make(map[string]ast.Expr)
+	for i, elt := range lit.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			if key, ok := kv.Key.(*ast.Ident); ok {
+				values[key.Name] = kv.Value
+			}
+		} else if i < len(fields) {
+			values[fields[i]] = elt
+		}
+	}
+	return values
+}
+
+func boolLiteral(expr ast.Expr) (bool, bool) {
+	ident, ok := expr.(*ast.Ident)
+	if !ok || (ident.Name != "true" && ident.Name != "false") {
+		return false, false
+	}
+	return ident.Name == "true", true
+}
+
+func stringLiteral(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// witnessLiteral renders a Go literal as the value of witness. A composite
+// literal of an Either or path struct becomes the Left or Right value of
+// the arm it sets.
+func witnessLiteral(expr ast.Expr, witness transpiler.WitnessValue) (string, error) {
+	if len(witness.Fields) == 0 {
+		return literalValue(expr, witness.Type)
+	}
+	fields, err := keyedFields(expr)
+	if err != nil {
+		return "", err
+	}
+
+	var branch string
+	if witness.Selector != "" {
+		// An omitted selector is false, as in Go
+		b := false
+		if selector, ok := fields[witness.Selector]; ok {
+			if b, ok = boolLiteral(selector); !ok {
+				return "", fmt.Errorf("%s is not a literal", witness.Selector)
+			}
+		}
+		branch = "Right"
+		if b == (witness.Selector == "is_left") {
+			branch = "Left"
+		}
+	} else {
+		for _, f := range witness.Fields {
+			path, _, _ := strings.Cut(f.Name, ".")
+			if value, ok := fields[path]; ok && !isNil(value) {
+				if branch != "" && branch != f.Branch {
+					return "", fmt.Errorf("more than one path is set")
+				}
+				branch = f.Branch
+			}
+		}
+		if branch == "" {
+			return "", fmt.Errorf("no path is set")
+		}
+	}
+
+	// The payload of the arm: one field as itself, several as a tuple,
+	// and a struct payload of a path as a tuple of its fields.
+	var values []string
+	tuple := false
+	for _, f := range witness.Fields {
+		if f.Branch != branch {
+			continue
+		}
+		path, field, nested := strings.Cut(f.Name, ".")
+		value := fields[path]
+		if nested {
+			tuple = true
+			inner, err := keyedFields(value)
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", path, err)
+			}
+			value = inner[field]
+		} else if u, ok := value.(*ast.UnaryExpr); ok && u.Op == token.AND {
+			value = u.X
+		}
+		v, err := literalValue(value, f.Type)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", f.Name, err)
+		}
+		values = append(values, v)
+	}
+	payload := values[0]
+	switch {
+	case len(values) > 1:
+		payload = "(" + strings.Join(values, ", ") + ")"
+	case tuple:
+		payload = "(" + payload + ",)"
+	}
+
+	constructors := strings.Split(branch, ".")
+	for i := len(constructors) - 1; i >= 0; i-- {
+		payload = constructors[i] + "(" + payload + ")"
+	}
+	return payload, nil
+}
+
+// keyedFields maps the snake_case field names of a keyed struct literal,
+// or a pointer to one, to their values.
+func keyedFields(expr ast.Expr) (map[string]ast.Expr, error) {
+	if u, ok := expr.(*ast.UnaryExpr); ok && u.Op == token.AND {
+		expr = u.X
+	}
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		return nil, fmt.Errorf("not a literal")
+	}
+	fields := make(map[string]ast.Expr, len(lit.Elts))
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			return nil, fmt.Errorf("struct literal fields must be keyed")
+		}
+		key, ok := kv.Key.(*ast.Ident)
+		if !ok {
+			return nil, fmt.Errorf("struct literal fields must be keyed")
+		}
+		fields[strings.ToLower(transpiler.WitnessName(key.Name))] = kv.Value
+	}
+	return fields, nil
+}
+
+func isNil(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == "nil"
+}
+
+// literalValue renders a Go literal as a SimplicityHL value of simType.
+func literalValue(expr ast.Expr, simType string) (string, error) {
+	if expr == nil {
+		return "", fmt.Errorf("missing value")
+	}
+	if simType == "bool" {
+		if b, ok := boolLiteral(expr); ok {
+			return strconv.FormatBool(b), nil
+		}
+		return "", fmt.Errorf("not a literal")
+	}
+
+	if strings.HasPrefix(simType, "[u8; ") {
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(simType, "[u8; "), "]"))
+		if err != nil {
+			return "", fmt.Errorf("unsupported witness type %s", simType)
+		}
+		return byteArrayLiteral(expr, n, simType)
+	}
+
+	bits := 0
+	if strings.HasPrefix(simType, "u") {
+		bits, _ = strconv.Atoi(simType[1:])
+	}
+	if bits == 0 {
+		return "", fmt.Errorf("unsupported witness type %s", simType)
+	}
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.INT {
+		return "", fmt.Errorf("not a literal")
+	}
+	value, ok := simtypes.ParseIntLiteral(lit.Value)
+	if !ok {
+		return "", fmt.Errorf("invalid integer %s", lit.Value)
+	}
+	if value.BitLen() > bits {
+		return "", fmt.Errorf("%s does not fit in %s", lit.Value, simType)
+	}
+	return simtypes.EncodeInt(simType, value)
+}
+
+// byteArrayLiteral renders [N]byte{...} with literal elements as hex.
+func byteArrayLiteral(expr ast.Expr, n int, simType string) (string, error) {
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		return "", fmt.Errorf("not a literal")
+	}
+	if len(lit.Elts) > n {
+		return "", fmt.Errorf("%d elements do not fit in [u8; %d]", len(lit.Elts), n)
+	}
+	buf := make([]byte, n)
+	for i, elt := range lit.Elts {
+		b, ok := elt.(*ast.BasicLit)
+		if !ok || (b.Kind != token.INT && b.Kind != token.CHAR) {
+			return "", fmt.Errorf("element %d is not a literal", i)
+		}
+		v, err := strconv.ParseUint(b.Value, 0, 8)
+		if err != nil {
+			return "", fmt.Errorf("element %d: %s is not a byte", i, b.Value)
+		}
+		buf[i] = byte(v)
+	}
+	return simtypes.EncodeBytes(simType, buf)
+}
+
+// WriteJSON writes the suite as an indented JSON test-vector file.
+func WriteJSON(w io.Writer, suite *Suite) error {
+	data, err := json.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// WritePairs writes one <Entry>_<n>.simf program and matching .wit witness
+// file per vector into dir and returns the paths written. The expected
+// outcome is recorded in a comment at the top of each program.
+func WritePairs(dir string, suite *Suite, config compiler.Config) ([]string, error) {
+	source, err := os.ReadFile(suite.Contract)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read contract: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if config.Target == "" {
+		config.Target = "simplicityhl"
+	}
+
+	programs := make(map[string]string)
+	counts := make(map[string]int)
+	var written []string
+	for _, v := range suite.Vectors {
+		program, ok := programs[v.Entry]
+		if !ok {
+			entryConfig := config
+			entryConfig.Entry = v.Entry
+			program, err = compiler.New(entryConfig).Compile(string(source), suite.Contract)
+			if err != nil {
+				return written, fmt.Errorf("entry %s: %w", v.Entry, err)
+			}
+			programs[v.Entry] = program
+		}
+
+		counts[v.Entry]++
+		base := filepath.Join(dir, fmt.Sprintf("%s_%d", v.Entry, counts[v.Entry]))
+		outcome := "accept"
+		if !v.Expected {
+			outcome = "reject"
+		}
+		header := fmt.Sprintf("// %s: expect %s\n", v.Name, outcome)
+		if err := os.WriteFile(base+".simf", []byte(header+program), 0644); err != nil {
+			return written, err
+		}
+		wit, err := json.MarshalIndent(v.Witness, "", "  ")
+		if err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(base+".wit", append(wit, '\n'), 0644); err != nil {
+			return written, err
+		}
+		written = append(written, base+".simf", base+".wit")
+	}
+	return written, nil
+}
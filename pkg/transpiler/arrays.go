@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"go/ast"
 	"go/token"
+	gotypes "go/types"
 	"strconv"
 	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
 )
 
 // UnrolledLoop represents a for loop that has been unrolled
@@ -13,35 +16,12 @@ type UnrolledLoop struct {
 	IndexVar   string
 	Iterations int
 	BodyStmts  [][]string // Body statements for each iteration
-}
-
-// evaluateIndexExpr handles array indexing like arr[i] or arr[0]
-func (t *Transpiler) evaluateIndexExpr(expr *ast.IndexExpr) (string, error) {
-	// Get the array expression
-	arrayExpr, err := t.evaluateExpression(expr.X)
-	if err != nil {
-		return "", err
-	}
-
-	// Get the index
-	indexExpr, err := t.evaluateExpression(expr.Index)
-	if err != nil {
-		return "", err
-	}
-
-	// Check if index is a literal number
-	if idx, err := strconv.Atoi(indexExpr); err == nil {
-		// Use bracket notation for literal indices
-		return fmt.Sprintf("%s[%d]", arrayExpr, idx), nil
-	}
-
-	// For variable indices, use bracket notation
-	return fmt.Sprintf("%s[%s]", arrayExpr, indexExpr), nil
+	Pos        token.Pos  // Position of the for statement
 }
 
 // unrollForLoop converts a bounded for loop into unrolled statements
 func (t *Transpiler) unrollForLoop(forStmt *ast.ForStmt) (*UnrolledLoop, error) {
-	unrolled := &UnrolledLoop{}
+	unrolled := &UnrolledLoop{Pos: forStmt.Pos()}
 
 	// Extract loop bounds from: for i := 0; i < N; i++
 	// Init: i := 0
@@ -73,26 +53,114 @@ func (t *Transpiler) unrollForLoop(forStmt *ast.ForStmt) (*UnrolledLoop, error)
 		return nil, fmt.Errorf("could not determine loop bounds")
 	}
 
-	// Unroll the body for each iteration
-	for i := 0; i < unrolled.Iterations; i++ {
+	// Unroll the body for each iteration. Large bounds make this the most
+	// expensive step, so it honors cancellation and the output limits.
+	size, broken := 0, false
+	for i := 0; i < unrolled.Iterations && !broken; i++ {
+		if err := t.ctx.Err(); err != nil {
+			return nil, err
+		}
 		var iterStmts []string
 		for _, stmt := range forStmt.Body.List {
-			stmtStr, err := t.analyzeStatementWithIndex(stmt, unrolled.IndexVar, i)
+			if branch, isBranch, err := t.unrolledBranch(stmt, unrolled.IndexVar, i); err != nil {
+				return nil, err
+			} else if isBranch {
+				broken = branch == token.BREAK
+				if branch != token.ILLEGAL {
+					break
+				}
+				continue
+			}
+			if assign, ok := stmt.(*ast.AssignStmt); ok && isIdentNamed(assign.Lhs[0], "_") {
+				continue // _ = sigs[i] reads the element and discards it
+			}
+			stmtStr, err := t.countedSignature(stmt, unrolled.IndexVar, i)
 			if err != nil {
 				return nil, err
 			}
-			if stmtStr != "" {
-				iterStmts = append(iterStmts, stmtStr)
+			iterStmts = append(iterStmts, stmtStr)
+			size += len(stmtStr)
+		}
+		if limit := t.printer.limit; limit > 0 && size > limit {
+			return nil, diag.ProgramTooLarge.Errorf("unrolling %d iterations exceeds the output limit of %d bytes", unrolled.Iterations, limit)
+		}
+		if i == 0 {
+			// Every iteration is about the size of the first.
+			what := fmt.Sprintf("unrolling %d iterations", unrolled.Iterations)
+			if err := t.checkSize(forStmt.Pos(), what, unrolled.Iterations, iterStmts...); err != nil {
+				return nil, err
 			}
 		}
-		unrolled.BodyStmts = append(unrolled.BodyStmts, iterStmts)
+		if len(iterStmts) > 0 {
+			unrolled.BodyStmts = append(unrolled.BodyStmts, iterStmts)
+		}
 	}
 
 	return unrolled, nil
 }
 
+// unrolledBranch resolves stmt in iteration k of a loop of main when it is
+// a break or continue, or an if statement whose body is one alone. It
+// returns the branch the iteration takes, token.ILLEGAL for none, and
+// reports whether stmt was one. The condition must be known once the index
+// is: main has no accumulator whose updates a condition known only when the
+// program runs could select.
+func (t *Transpiler) unrolledBranch(stmt ast.Stmt, index string, k int) (token.Token, bool, error) {
+	var cond ast.Expr
+	branch, ok := stmt.(*ast.BranchStmt)
+	if ifStmt, isIf := stmt.(*ast.IfStmt); isIf && ifStmt.Init == nil && ifStmt.Else == nil && len(ifStmt.Body.List) == 1 {
+		branch, ok = ifStmt.Body.List[0].(*ast.BranchStmt)
+		cond = ifStmt.Cond
+	}
+	fail := func(branch *ast.BranchStmt, why string) error {
+		return diag.LoopBranch.Wrap(t.errorAt(branch.Pos(), "the %s in iteration %s = %d cannot be lowered: %s", branch.Tok, index, k, why))
+	}
+	if !ok {
+		if inner := loopBranch(&ast.BlockStmt{List: []ast.Stmt{stmt}}); inner != nil {
+			return 0, false, fail(inner, "in main a break or continue is lowered as the only statement of an if statement directly in the loop body, or as a statement of its own")
+		}
+		return token.ILLEGAL, false, nil
+	}
+	if why := branchProblem(branch); why != "" {
+		return 0, false, fail(branch, why)
+	}
+	if cond == nil {
+		return branch.Tok, true, nil
+	}
+	v, known := t.folder.Fold(withIndex(cond, index, k))
+	if !known || v.Int != nil {
+		return 0, false, fail(branch, fmt.Sprintf("its condition %s is only known when the program runs, and in main a loop breaks or continues only on a condition known once %s is; compute the result in a helper that accumulates it", gotypes.ExprString(cond), index))
+	}
+	if v.Bool {
+		return branch.Tok, true, nil
+	}
+	return token.ILLEGAL, true, nil
+}
+
+// countedSignature returns the match that counts the signature stmt, in
+// iteration k of a loop of main, checks: the 1 or 0 of if sigs[i].IsSome
+// { ... count++ }, which the counter of the loop adds up. Main has no other
+// accumulator, so any other statement is an error.
+func (t *Transpiler) countedSignature(stmt ast.Stmt, index string, k int) (string, error) {
+	if ifStmt, ok := stmt.(*ast.IfStmt); ok && ifStmt.Init == nil && ifStmt.Else == nil {
+		if text, err := t.analyzeIfStmtWithIndex(ifStmt, index, k); err != nil || text != "" {
+			return text, err
+		}
+	}
+	return "", diag.UnsupportedSyntax.Wrap(t.errorAt(stmt.Pos(), "%s in iteration %s = %d cannot be lowered: in main a loop accumulates only a count of signatures, as if sigs[i].IsSome { count++ } does; compute the result in a helper that accumulates it", statementKind(stmt), index, k))
+}
+
 // analyzeStatementWithIndex analyzes a statement, substituting index variable
 func (t *Transpiler) analyzeStatementWithIndex(stmt ast.Stmt, indexVar string, indexVal int) (string, error) {
+	if assign, ok := stmt.(*ast.AssignStmt); ok && assign.Tok != token.DEFINE && len(assign.Lhs) == 1 && !isIdentNamed(assign.Lhs[0], "_") {
+		// Only a counter of signatures accumulates across the iterations.
+		return "", diag.UnsupportedSyntax.Wrap(t.errorAt(assign.Pos(), "the assignment to %s in iteration %s = %d cannot be lowered: in main a loop accumulates only a count of signatures; compute the result in a helper that accumulates it", gotypes.ExprString(assign.Lhs[0]), indexVar, indexVal))
+	}
+	if assign, ok, err := t.reassignment(stmt); err != nil {
+		return "", err
+	} else if ok {
+		stmt = assign
+	}
 	switch s := stmt.(type) {
 	case *ast.IfStmt:
 		return t.analyzeIfStmtWithIndex(s, indexVar, indexVal)
@@ -100,8 +168,6 @@ func (t *Transpiler) analyzeStatementWithIndex(stmt ast.Stmt, indexVar string, i
 		return t.analyzeExprStmtWithIndex(s, indexVar, indexVal)
 	case *ast.AssignStmt:
 		return t.analyzeAssignStmtWithIndex(s, indexVar, indexVal)
-	case *ast.IncDecStmt:
-		return t.analyzeIncDecStmtWithIndex(s, indexVar, indexVal)
 	default:
 		return "", nil
 	}
@@ -117,45 +183,51 @@ func (t *Transpiler) analyzeIfStmtWithIndex(ifStmt *ast.IfStmt, indexVar string,
 		return "", nil
 	}
 
-	// Generate match expression for Option
+	// Generate match expression for Option. The fragment is rendered in
+	// canonical indentation relative to its first line; the printer re-indents.
 	sb.WriteString(fmt.Sprintf("match %s {\n", condStr))
 
 	// Some branch (the if body)
-	sb.WriteString("        Some(sig) => {\n")
+	sb.WriteString(canonicalIndent + "Some(sig) => {\n")
 	for _, bodyStmt := range ifStmt.Body.List {
-		stmtStr, err := t.analyzeStatementWithIndex(bodyStmt, indexVar, indexVal)
+		var stmtStr string
+		var err error
+		if inc, isInc := bodyStmt.(*ast.IncDecStmt); isInc && pattern == "Some" {
+			stmtStr, err = t.analyzeIncDecStmtWithIndex(inc, indexVar, indexVal)
+		} else {
+			stmtStr, err = t.analyzeStatementWithIndex(bodyStmt, indexVar, indexVal)
+		}
 		if err != nil {
 			return "", err
 		}
-		if stmtStr != "" {
-			sb.WriteString(fmt.Sprintf("            %s\n", stmtStr))
+		for _, line := range strings.Split(stmtStr, "\n") {
+			if line != "" {
+				sb.WriteString(canonicalIndent + canonicalIndent + line + "\n")
+			}
 		}
 	}
 	if pattern == "Some" {
-		sb.WriteString("            1\n") // Return 1 for valid signature
+		sb.WriteString(canonicalIndent + canonicalIndent + "1\n") // Return 1 for valid signature
 	}
-	sb.WriteString("        },\n")
+	sb.WriteString(canonicalIndent + "},\n")
 
 	// None branch
-	sb.WriteString("        None => 0,\n")
-	sb.WriteString("    }")
+	sb.WriteString(canonicalIndent + "None => 0,\n")
+	sb.WriteString("}")
 
 	return sb.String(), nil
 }
 
 // extractConditionWithIndex extracts condition info with index substitution
-func (t *Transpiler) extractConditionWithIndex(cond ast.Expr, _ string, indexVal int) (string, string) {
+func (t *Transpiler) extractConditionWithIndex(cond ast.Expr, indexVar string, indexVal int) (string, string) {
 	if sel, ok := cond.(*ast.SelectorExpr); ok {
-		// sigs[i].IsSome -> witness::SIGS[indexVal]
-		if idx, ok := sel.X.(*ast.IndexExpr); ok {
-			arrayName, _ := t.evaluateExpression(idx.X)
-			// Resolve to witness reference
-			arrayName = t.resolveArrayRef(arrayName)
-			fieldName := sel.Sel.Name
-
-			if fieldName == "IsSome" {
-				return fmt.Sprintf("%s[%d]", arrayName, indexVal), "Some"
+		// sigs[i].IsSome -> the element indexVal of witness::SIGS
+		if idx, ok := sel.X.(*ast.IndexExpr); ok && sel.Sel.Name == "IsSome" {
+			element, err := t.expr.Translate(withIndex(idx, indexVar, indexVal))
+			if err != nil || element == placeholder {
+				return "", ""
 			}
+			return element, "Some"
 		}
 	}
 	return "", ""
@@ -184,11 +256,11 @@ func (t *Transpiler) analyzeExprStmtWithIndex(stmt *ast.ExprStmt, indexVar strin
 func (t *Transpiler) analyzeCallExprWithIndex(callExpr *ast.CallExpr, indexVar string, indexVal int) (string, error) {
 	// Check for jet calls
 	if sel, ok := callExpr.Fun.(*ast.SelectorExpr); ok {
-		if ident, ok := sel.X.(*ast.Ident); ok && ident.Name == "jet" {
+		if t.isJet(sel.X) {
 			jetName := sel.Sel.Name
 			jetInfo, found := t.jetRegistry.Lookup(jetName)
 			if !found {
-				return "", fmt.Errorf("unknown jet: %s", jetName)
+				return "", diag.UnknownJet.Errorf("unknown jet: %s", jetName)
 			}
 
 			// Evaluate arguments with index substitution
@@ -223,10 +295,10 @@ func (t *Transpiler) evaluateExprWithIndex(expr ast.Expr, indexVar string, index
 		if e.Name == indexVar {
 			return strconv.Itoa(indexVal), nil
 		}
-		return t.evaluateJetArg(e)
+		return t.expr.TranslateArg(e)
 	case *ast.IndexExpr:
 		// array[i] with i substituted
-		arrayExpr, err := t.evaluateExpression(e.X)
+		arrayExpr, err := t.expr.Translate(e.X)
 		if err != nil {
 			return "", err
 		}
@@ -237,7 +309,7 @@ func (t *Transpiler) evaluateExprWithIndex(expr ast.Expr, indexVar string, index
 			return fmt.Sprintf("%s[%d]", arrayExpr, indexVal), nil
 		}
 
-		indexExpr, err := t.evaluateExpression(e.Index)
+		indexExpr, err := t.expr.Translate(e.Index)
 		if err != nil {
 			return "", err
 		}
@@ -245,7 +317,7 @@ func (t *Transpiler) evaluateExprWithIndex(expr ast.Expr, indexVar string, index
 	case *ast.SelectorExpr:
 		// Handle sigs[i].Value -> witness::SIGS[indexVal]
 		if idx, ok := e.X.(*ast.IndexExpr); ok {
-			arrayName, _ := t.evaluateExpression(idx.X)
+			arrayName, _ := t.expr.Translate(idx.X)
 			arrayName = t.resolveArrayRef(arrayName)
 
 			// Check if index is the loop variable
@@ -257,11 +329,13 @@ func (t *Transpiler) evaluateExprWithIndex(expr ast.Expr, indexVar string, index
 				return fmt.Sprintf("%s[%d].%s", arrayName, indexVal, t.toSnakeCase(e.Sel.Name)), nil
 			}
 		}
-		return t.evaluateJetArg(e)
+		return t.expr.TranslateArg(e)
 	case *ast.CallExpr:
 		return t.analyzeCallExprWithIndex(e, indexVar, indexVal)
+	case *ast.BinaryExpr:
+		return t.expr.TranslateArg(withIndex(e, indexVar, indexVal))
 	default:
-		return t.evaluateExpression(expr)
+		return t.expr.Translate(expr)
 	}
 }
 
@@ -283,7 +357,9 @@ func (t *Transpiler) analyzeAssignStmtWithIndex(stmt *ast.AssignStmt, indexVar s
 	return "", nil
 }
 
-// analyzeIncDecStmtWithIndex handles increment/decrement statements
+// analyzeIncDecStmtWithIndex handles the increment of the signature
+// counter in the Some arm of an unrolled loop, which the 1 the arm returns
+// accumulates.
 func (t *Transpiler) analyzeIncDecStmtWithIndex(stmt *ast.IncDecStmt, _ string, _ int) (string, error) {
 	// validCount++ becomes part of accumulation logic
 	if ident, ok := stmt.X.(*ast.Ident); ok {
@@ -0,0 +1,202 @@
+package transpiler
+
+import (
+	"fmt"
+	"go/ast"
+	gotypes "go/types"
+	"math/big"
+
+	simtypes "github.com/0ceanslim/go-simplicity/pkg/types"
+)
+
+// bitcoinConstants are the constants of the std/bitcoin package, which are
+// inlined wherever they are used.
+var bitcoinConstants = map[string]Value{
+	"DustLimit": {Type: "u64", Int: big.NewInt(546)},
+	"MaxMoney":  {Type: "u64", Int: big.NewInt(21_000_000 * 100_000_000)},
+}
+
+// bitcoinConstructors maps the value constructors of std/bitcoin to the
+// type they convert their argument to.
+var bitcoinConstructors = map[string]string{
+	"NewAmount": "Amount",
+}
+
+// bitcoinResults maps the functions of std/bitcoin to the type they
+// return.
+var bitcoinResults = map[string]string{
+	"NewAmount": "Amount",
+	"XOnly":     "XOnlyPubkey",
+}
+
+// bitcoinMember returns the name selected by sel when sel.X names the
+// std/bitcoin package in the file types was scoped to.
+func bitcoinMember(types *simtypes.TypeMapper, sel *ast.SelectorExpr) (string, bool) {
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return "", false
+	}
+	path, ok := types.ImportPath(ident.Name)
+	return sel.Sel.Name, ok && path == simtypes.BitcoinImportPath
+}
+
+// bitcoinConversion returns the type that call converts its argument to
+// when it is a std/bitcoin constructor, as in bitcoin.NewAmount(5000), or a
+// conversion to one of its types, as in bitcoin.Amount(fee).
+func bitcoinConversion(types *simtypes.TypeMapper, call *ast.CallExpr) (string, bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || len(call.Args) != 1 {
+		return "", false
+	}
+	name, ok := bitcoinMember(types, sel)
+	if !ok {
+		return "", false
+	}
+	if target, ok := bitcoinConstructors[name]; ok {
+		name = target
+	}
+	typ, err := types.MapGoType(&ast.SelectorExpr{X: sel.X, Sel: ast.NewIdent(name)})
+	return typ, err == nil
+}
+
+// bitcoinTypeOf returns the name of the std/bitcoin type x was declared
+// with: a variable or parameter of type bitcoin.CompressedPubkey, or one
+// initialized from a std/bitcoin constructor. Types are resolved within the
+// file, so values whose type is not written there are not recognized.
+func bitcoinTypeOf(types *simtypes.TypeMapper, x ast.Expr) (string, bool) {
+	switch x := x.(type) {
+	case *ast.ParenExpr:
+		return bitcoinTypeOf(types, x.X)
+	case *ast.SelectorExpr:
+		return bitcoinMember(types, x)
+	case *ast.CallExpr:
+		sel, ok := x.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return "", false
+		}
+		name, ok := bitcoinMember(types, sel)
+		if !ok {
+			return "", false
+		}
+		if result, ok := bitcoinResults[name]; ok {
+			return result, true
+		}
+		return name, true
+	case *ast.Ident:
+		if x.Obj == nil {
+			return "", false
+		}
+		switch decl := x.Obj.Decl.(type) {
+		case *ast.Field:
+			return bitcoinTypeOf(types, decl.Type)
+		case *ast.ValueSpec:
+			if decl.Type != nil {
+				return bitcoinTypeOf(types, decl.Type)
+			}
+			for i, name := range decl.Names {
+				if name.Name == x.Name && i < len(decl.Values) {
+					return bitcoinTypeOf(types, decl.Values[i])
+				}
+			}
+		case *ast.AssignStmt:
+			for i, lhs := range decl.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok && ident.Name == x.Name && len(decl.Rhs) == len(decl.Lhs) {
+					return bitcoinTypeOf(types, decl.Rhs[i])
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// checkKeyTypes rejects a std/bitcoin value other than an XOnlyPubkey as
+// the key of jet.BIP340Verify. A compressed key maps to (u8, u256) and
+// would not type-check in SimplicityHL; the others are not keys at all.
+func (t *Transpiler) checkKeyTypes(file *ast.File) error {
+	var err error
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || err != nil || len(call.Args) != 3 {
+			return err == nil
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || !t.isJet(sel.X) || sel.Sel.Name != "BIP340Verify" {
+			return true
+		}
+		key := call.Args[0]
+		switch name, _ := bitcoinTypeOf(t.typeMapper, key); name {
+		case "", "XOnlyPubkey", "Pubkey":
+		case "CompressedPubkey":
+			err = t.errorAt(key.Pos(), "jet.BIP340Verify takes a bitcoin.XOnlyPubkey, but %s is a bitcoin.CompressedPubkey; drop its parity byte with bitcoin.XOnly(%s)",
+				gotypes.ExprString(key), gotypes.ExprString(key))
+		default:
+			err = t.errorAt(key.Pos(), "jet.BIP340Verify takes a bitcoin.XOnlyPubkey, but %s is a bitcoin.%s", gotypes.ExprString(key), name)
+		}
+		return err == nil
+	})
+	return err
+}
+
+// xOnlyHelperName is the SimplicityHL function bitcoin.XOnly calls.
+const xOnlyHelperName = "bitcoin_x_only"
+
+// xOnlyHelper drops the parity byte of a compressed key. It is emitted
+// once when bitcoin.XOnly is used, since the tuple pattern needs a let.
+const xOnlyHelper = `fn bitcoin_x_only(key: (u8, u256)) -> u256 {
+    let (_, x): (u8, u256) = key;
+    x
+}`
+
+// xOnlyKey translates bitcoin.XOnly(key), which drops the parity byte of
+// a compressed key, to a call of xOnlyHelper.
+func (t *Transpiler) xOnlyKey(call *ast.CallExpr) (string, error) {
+	key, err := t.xOnlyArg(call)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s(%s)", xOnlyHelperName, key), nil
+}
+
+// xOnlyArg checks and translates the key passed to bitcoin.XOnly.
+func (t *Transpiler) xOnlyArg(call *ast.CallExpr) (string, error) {
+	if len(call.Args) != 1 {
+		return "", t.errorAt(call.Pos(), "bitcoin.XOnly takes one bitcoin.CompressedPubkey, got %d arguments", len(call.Args))
+	}
+	if name, ok := bitcoinTypeOf(t.typeMapper, call.Args[0]); ok && name != "CompressedPubkey" {
+		return "", t.errorAt(call.Args[0].Pos(), "bitcoin.XOnly takes a bitcoin.CompressedPubkey, but %s is a bitcoin.%s", gotypes.ExprString(call.Args[0]), name)
+	}
+	key, err := t.expr.Translate(call.Args[0])
+	if err != nil {
+		return "", err
+	}
+	t.helpers[xOnlyHelperName] = true
+	return key, nil
+}
+
+// xOnlyBinding records name := bitcoin.XOnly(key) in the entry function as
+// a let binding of the derived key. It reports false for other statements.
+func (t *Transpiler) xOnlyBinding(name string, s *ast.AssignStmt) (bool, error) {
+	call, ok := s.Rhs[0].(*ast.CallExpr)
+	if !ok {
+		return false, nil
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false, nil
+	}
+	if member, ok := bitcoinMember(t.typeMapper, sel); !ok || member != "XOnly" {
+		return false, nil
+	}
+	key, err := t.xOnlyArg(call)
+	if err != nil {
+		return false, err
+	}
+	t.jetCalls = append(t.jetCalls, JetCall{
+		VarName:    t.toSnakeCase(name),
+		JetName:    xOnlyHelperName,
+		Args:       key,
+		ReturnType: "u256",
+		Pos:        s.Pos(),
+	})
+	return true, nil
+}
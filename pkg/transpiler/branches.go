@@ -0,0 +1,479 @@
+package transpiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	gotypes "go/types"
+	"strconv"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+)
+
+// A break or continue of a counted loop is resolved as the loop is
+// unrolled, one iteration at a time:
+//
+//	var total uint64
+//	for i := 0; i < 8; i++ {
+//		if i%2 == 1 {
+//			continue
+//		}
+//		if xs[i] == 0 {
+//			break
+//		}
+//		total += xs[i]
+//	}
+//	return total
+//
+// A condition the compiler knows once it knows i, such as i%2 == 1,
+// decides the iteration outright: a continue leaves out the rest of it, and
+// a break the iterations after it. The iterations that remain are lowered
+// as any accumulation is, to a tree when each of them runs the same update.
+//
+// A condition on the values, such as xs[i] == 0, is only known when the
+// program runs. The iterations are then chained instead: each binds
+// whether the loop is still running, which a break clears for the
+// iterations after it and a continue for the rest of its own, and each
+// update takes effect only while it is set:
+//
+//	let t_11_3: bool = jet::eq_64(xs_0, 0);
+//	let t_12_4: bool = match t_11_3 { true => false, false => true, };
+//	let (_, t_14_9): (bool, u64) = jet::add_64(0, xs_0);
+//	let t_14_3: u64 = match t_12_4 { true => t_14_9, false => 0, };
+//
+// The update of an iteration the loop has left is still computed, and
+// discarded, so an update that divides, which may fail, is not chained. A
+// break or continue that cannot be resolved, such as one of an enclosing
+// loop or a loop updating more than one accumulator, is an error naming it
+// and the iteration that reaches it.
+
+// loopStep is a statement of the body of a loop that breaks or continues,
+// read as if cond { update; branch }, where any part may be missing.
+type loopStep struct {
+	stmt   ast.Stmt
+	cond   ast.Expr        // nil when the step is unconditional
+	update ast.Stmt        // Assignment to the accumulator, or nil
+	branch *ast.BranchStmt // break or continue, or nil
+	at     ast.Stmt        // The statement why is about
+	why    string          // Why the step cannot be lowered, "" when it can
+}
+
+// loopBranch returns the first break or continue in body that leaves the
+// loop body belongs to, or nil. A break in a switch or select leaves that
+// instead; any branch in a nested loop leaves the nested loop.
+func loopBranch(body *ast.BlockStmt) *ast.BranchStmt {
+	var found *ast.BranchStmt
+	var visit func(n ast.Node, breaks bool)
+	visit = func(n ast.Node, breaks bool) {
+		ast.Inspect(n, func(n ast.Node) bool {
+			switch s := n.(type) {
+			case *ast.ForStmt, *ast.RangeStmt, *ast.FuncLit:
+				return false
+			case *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+				if breaks {
+					for _, child := range childStmts(s) {
+						visit(child, false)
+					}
+					return false
+				}
+			case *ast.BranchStmt:
+				if found == nil && (s.Tok == token.CONTINUE || s.Tok == token.BREAK && (breaks || s.Label != nil)) {
+					found = s
+				}
+			}
+			return found == nil
+		})
+	}
+	visit(body, true)
+	return found
+}
+
+// childStmts returns the clauses of a switch or select statement.
+func childStmts(stmt ast.Node) []ast.Stmt {
+	switch s := stmt.(type) {
+	case *ast.SwitchStmt:
+		return s.Body.List
+	case *ast.TypeSwitchStmt:
+		return s.Body.List
+	case *ast.SelectStmt:
+		return s.Body.List
+	}
+	return nil
+}
+
+// loopSteps reads the statements of body, the body of a loop that updates
+// the accumulator acc, "" for a loop without one.
+func loopSteps(body *ast.BlockStmt, acc string) []loopStep {
+	steps := make([]loopStep, len(body.List))
+	for i, stmt := range body.List {
+		steps[i] = loopStepOf(stmt, acc)
+	}
+	return steps
+}
+
+func loopStepOf(stmt ast.Stmt, acc string) loopStep {
+	s := loopStep{stmt: stmt, at: stmt}
+	update := func(u ast.Stmt) {
+		switch {
+		case acc == "":
+			s.at, s.why = u, "a loop that breaks or continues is lowered when it updates one accumulator declared just before it, and there is none"
+		case !isUpdate(u, acc):
+			s.at, s.why = u, "a loop that breaks or continues is lowered when each of its other statements updates "+acc+", the accumulator declared just before it, and this one does not"
+		default:
+			s.update = u
+		}
+	}
+	switch st := stmt.(type) {
+	case *ast.BranchStmt:
+		s.branch, s.why = st, branchProblem(st)
+	case *ast.IfStmt:
+		body := st.Body.List
+		if n := len(body); n > 0 {
+			if branch, ok := body[n-1].(*ast.BranchStmt); ok {
+				s.branch, s.why, body = branch, branchProblem(branch), body[:n-1]
+			}
+		}
+		s.cond = st.Cond
+		switch {
+		case s.why != "":
+		case st.Init != nil:
+			s.why = "an if statement in a loop that breaks or continues is lowered without an init statement; declare the variable before the if"
+		case st.Else != nil:
+			s.at, s.why = st.Else, "an if statement in a loop that breaks or continues is lowered without an else; negate the condition instead"
+			if branch := loopBranch(&ast.BlockStmt{List: []ast.Stmt{st.Else}}); branch != nil {
+				s.at = branch
+			}
+		case len(body) > 1:
+			s.at, s.why = body[1], "an if statement in a loop that breaks or continues is lowered when it updates the accumulator once and then breaks or continues"
+		case len(body) == 1:
+			if branch := loopBranch(&ast.BlockStmt{List: body}); branch != nil {
+				s.at, s.why = branch, "a break or continue is lowered as the last statement of an if statement directly in the loop body, or as a statement of its own"
+				break
+			}
+			update(body[0])
+		}
+	case *ast.AssignStmt, *ast.IncDecStmt:
+		update(st)
+	case *ast.EmptyStmt:
+	default:
+		update(st)
+		if branch := loopBranch(&ast.BlockStmt{List: []ast.Stmt{st}}); branch != nil {
+			s.at, s.why = branch, "a break or continue is lowered as the last statement of an if statement directly in the loop body, or as a statement of its own"
+		}
+	}
+	return s
+}
+
+// branchProblem explains why branch is not lowered, or returns "".
+func branchProblem(branch *ast.BranchStmt) string {
+	switch {
+	case branch.Tok != token.BREAK && branch.Tok != token.CONTINUE:
+		return "only break and continue are lowered"
+	case branch.Label != nil:
+		return fmt.Sprintf("it names the label %s, and only a break or continue of the loop it is in is lowered", branch.Label.Name)
+	}
+	return ""
+}
+
+// isUpdate reports whether stmt assigns to, increments or decrements acc.
+func isUpdate(stmt ast.Stmt, acc string) bool {
+	switch stmt.(type) {
+	case *ast.AssignStmt, *ast.IncDecStmt:
+		_, ok := updateValue(stmt, acc)
+		return ok && writes(stmt) == acc
+	}
+	return false
+}
+
+// loopRun is an update the resolved iterations of a loop run: stmt, the
+// update or the if statement that makes it conditional, in iteration k.
+type loopRun struct {
+	k    int
+	stmt ast.Stmt
+}
+
+// lowerBranchingLoop lowers loop when it is a counted loop that breaks or
+// continues, together with decl, the declaration of its accumulator, when
+// decl is not nil. It reports false when loop does not break or continue,
+// or decl does not declare an accumulator; a loop that breaks or continues
+// and cannot be lowered is an error.
+func (t *Transpiler) lowerBranchingLoop(decl, loop ast.Stmt) ([]string, bool, error) {
+	l, ok := loop.(*ast.ForStmt)
+	if !ok || loopBranch(l.Body) == nil {
+		return nil, false, nil
+	}
+	a := &accumulation{pos: loop.Pos()}
+	if decl != nil && !t.accumulatorDecl(decl, a) {
+		return nil, false, nil
+	}
+	if !t.countingLoop(l, &a.loopBounds) || a.index == a.acc {
+		branch := loopBranch(l.Body)
+		return nil, false, t.errorAt(branch.Pos(), "the %s cannot be lowered: the loop does not count from one constant to another, as for i := 0; i < 4; i++ does", branch.Tok)
+	}
+	if err := t.checkIterations(a); err != nil {
+		return nil, false, err
+	}
+	steps := loopSteps(l.Body, a.acc)
+	runs, dynamic, err := t.resolveSteps(a, steps)
+	if err != nil {
+		return nil, false, err
+	}
+	if a.acc == "" {
+		return nil, true, nil
+	}
+	if !dynamic && a.typ != "bool" && len(runs) > 0 {
+		uniform := true
+		for _, run := range runs {
+			uniform = uniform && run.stmt == runs[0].stmt
+		}
+		if uniform {
+			a.update, a.iters = runs[0].stmt, nil
+			for _, run := range runs {
+				a.iters = append(a.iters, []int{run.k})
+			}
+			if reduced, ok := t.lowerAccumulation(a); ok {
+				return reduced, true, nil
+			}
+		}
+	}
+	a.iters = a.span()
+	r := &reduction{a: a, lowering: derivedLowering{t: t, name: t.toSnakeCase(a.acc), pos: a.pos}}
+	if err := r.gated(steps); err != nil {
+		return nil, false, err
+	}
+	t.params[a.acc] = a.typ
+	return append(r.destructures, r.lines...), true, nil
+}
+
+// resolveSteps runs the iterations of a loop over steps as far as their
+// conditions are known at compile time, returning the updates they run. It
+// reports dynamic, and stops, at the first break or continue whose
+// condition is only known when the program runs.
+func (t *Transpiler) resolveSteps(a *accumulation, steps []loopStep) (runs []loopRun, dynamic bool, err error) {
+	for k := a.from; k < a.to; k++ {
+	iteration:
+		for _, s := range steps {
+			if s.why != "" {
+				return nil, false, t.stepError(a, s, k)
+			}
+			taken, known := true, true
+			if s.cond != nil {
+				v, ok := t.folder.Fold(withIndex(s.cond, a.index, k))
+				taken, known = ok && v.Int == nil && v.Bool, ok && v.Int == nil
+			}
+			switch {
+			case !known && s.branch != nil:
+				return nil, true, nil
+			case !taken && known:
+				continue
+			case s.update != nil && known:
+				runs = append(runs, loopRun{k, s.update})
+			case s.update != nil:
+				runs = append(runs, loopRun{k, s.stmt})
+			}
+			if s.branch != nil {
+				if s.branch.Tok == token.BREAK {
+					return runs, false, nil
+				}
+				break iteration
+			}
+		}
+	}
+	return runs, false, nil
+}
+
+// withIndex returns expr with the loop index replaced by k.
+func withIndex(expr ast.Expr, index string, k int) ast.Expr {
+	out := substitute(expr, func(e ast.Expr) (ast.Expr, bool) {
+		if isIdentNamed(e, index) {
+			return &ast.BasicLit{ValuePos: e.Pos(), Kind: token.INT, Value: strconv.Itoa(k)}, true
+		}
+		return nil, false
+	})
+	if out == nil {
+		return expr
+	}
+	return out
+}
+
+// stepError reports that step s cannot be lowered in iteration k.
+func (t *Transpiler) stepError(a *accumulation, s loopStep, k int) error {
+	return diag.LoopBranch.Wrap(t.errorAt(s.at.Pos(), "%s in iteration %s = %d cannot be lowered: %s", statementKind(s.at), a.index, k, s.why))
+}
+
+// gated chains the iterations of a loop over steps, each binding whether
+// the loop still runs for the statements after it.
+func (r *reduction) gated(steps []loopStep) error {
+	a, t := r.a, r.lowering.t
+	acc := a.start()
+	running := "" // Whether the loop still runs: "" while it certainly does, "false" once it certainly does not
+	for k := a.from; k < a.to && running != "false"; k++ {
+		live := running
+	iteration:
+		for _, s := range steps {
+			if live == "false" {
+				break
+			}
+			if s.why != "" {
+				return t.stepError(a, s, k)
+			}
+			gate := live
+			if s.cond != nil {
+				cond, ok := r.iteration(s.cond, []int{k}, acc)
+				if !ok {
+					s.why = fmt.Sprintf("its condition %s uses %s other than to index an array", gotypes.ExprString(s.cond), a.index)
+					return t.stepError(a, s, k)
+				}
+				if v, known := t.folder.Fold(cond); known && v.Int == nil {
+					if !v.Bool {
+						continue
+					}
+				} else {
+					ref, err := t.expr.TranslateArg(cond)
+					if err != nil || ref == placeholder {
+						s.why = fmt.Sprintf("its condition %s is not lowered", gotypes.ExprString(s.cond))
+						return t.stepError(a, s, k)
+					}
+					gate = r.and(live, r.bind(ref, s.stmt.Pos()), s.stmt.Pos())
+				}
+			}
+			if s.update != nil {
+				next, err := r.update(s, k, acc, gate != "")
+				if err != nil {
+					return err
+				}
+				if gate != "" {
+					prev, ok := r.operandOf(acc)
+					if !ok {
+						s.at, s.why = s.update, "the value of "+a.acc+" before it is not lowered"
+						return t.stepError(a, s, k)
+					}
+					name := t.temp(s.update.Pos())
+					t.params[name] = r.accType()
+					r.lines = append(r.lines, fmt.Sprintf("let %s: %s = match %s { true => %s, false => %s, };", name, r.accType(), gate, next, prev))
+					next = name
+				}
+				acc = ast.NewIdent(next)
+			}
+			if s.branch == nil {
+				continue
+			}
+			if gate == "" {
+				if s.branch.Tok == token.BREAK {
+					running = "false"
+				}
+				break iteration
+			}
+			live = r.andNot(live, gate, s.branch.Pos())
+			if s.branch.Tok == token.BREAK {
+				running = live
+			}
+		}
+	}
+	ref, ok := r.operandOf(acc)
+	if !ok {
+		return t.errorAt(a.pos, "the value of %s after the loop is not lowered", a.acc)
+	}
+	r.lines = append(r.lines, fmt.Sprintf("let %s: %s = %s;", r.lowering.name, r.accType(), ref))
+	return nil
+}
+
+// update lowers the update of step s in iteration k to the value it gives
+// the accumulator, acc before it. A gated update is computed whether or
+// not the loop still runs, and must not divide.
+func (r *reduction) update(s loopStep, k int, acc ast.Expr, gated bool) (string, error) {
+	a, t := r.a, r.lowering.t
+	rhs, _ := updateValue(s.update, a.acc)
+	s.at = s.update
+	if gated && divides(rhs) {
+		s.why = "the update divides, which may fail, and in a loop whose break or continue is only known when the program runs it is computed in the iterations the loop skips as well"
+		return "", t.stepError(a, s, k)
+	}
+	step, ok := r.iteration(rhs, []int{k}, acc)
+	if !ok {
+		s.why = fmt.Sprintf("it uses %s other than to index an array", a.index)
+		return "", t.stepError(a, s, k)
+	}
+	if v, known := t.folder.Fold(step); known {
+		r.infer(v.Type)
+		return v.String(), nil
+	}
+	calls := len(r.lowering.calls)
+	ref, typ, ok := r.lowering.lower(step, false)
+	if !ok {
+		s.why = "the value it assigns is not lowered"
+		return "", t.stepError(a, s, k)
+	}
+	r.flush()
+	r.infer(typ)
+	if len(r.lowering.calls) > calls {
+		t.params[ref] = typ
+	}
+	return ref, nil
+}
+
+// divides reports whether expr divides or takes a remainder.
+func divides(expr ast.Expr) bool {
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if bin, ok := n.(*ast.BinaryExpr); ok && (bin.Op == token.QUO || bin.Op == token.REM) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// operandOf returns the SimplicityHL operand for acc, a value of the
+// accumulator.
+func (r *reduction) operandOf(acc ast.Expr) (string, bool) {
+	t := r.lowering.t
+	if v, ok := t.folder.Fold(acc); ok {
+		return v.String(), true
+	}
+	ref, err := t.expr.TranslateArg(acc)
+	return ref, err == nil && ref != placeholder
+}
+
+// accType returns the type of the accumulator, u32 when nothing gave it
+// one.
+func (r *reduction) accType() string {
+	if r.a.typ == "" {
+		r.a.typ = "u32"
+	}
+	return r.a.typ
+}
+
+// bind returns ref, a bool, as an operand that can be repeated, binding it
+// to a temporary for pos unless it is one.
+func (r *reduction) bind(ref string, pos token.Pos) string {
+	if simpleRef.MatchString(ref) || ref == "true" || ref == "false" {
+		return ref
+	}
+	name := r.lowering.t.temp(pos)
+	r.lines = append(r.lines, fmt.Sprintf("let %s: bool = %s;", name, ref))
+	return name
+}
+
+// and returns live && cond, "" standing for true.
+func (r *reduction) and(live, cond string, pos token.Pos) string {
+	if live == "" {
+		return cond
+	}
+	name := r.lowering.t.temp(pos)
+	r.lines = append(r.lines, fmt.Sprintf("let %s: bool = match %s { true => %s, false => false, };", name, live, cond))
+	return name
+}
+
+// andNot returns live && !gate, "" standing for true.
+func (r *reduction) andNot(live, gate string, pos token.Pos) string {
+	if live == gate {
+		return "false"
+	}
+	if live == "" {
+		live = "true"
+	}
+	name := r.lowering.t.temp(pos)
+	r.lines = append(r.lines, fmt.Sprintf("let %s: bool = match %s { true => false, false => %s, };", name, gate, live))
+	return name
+}
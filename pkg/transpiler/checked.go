@@ -0,0 +1,46 @@
+package transpiler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// checkedHelperPrefix starts the name of the SimplicityHL function a
+// division or remainder is computed with under checked arithmetic, such as
+// std_checked_divide_64.
+const checkedHelperPrefix = "std_checked_"
+
+// checkedDivision returns the helper that computes the divide or modulo
+// jet after asserting that the divisor is not zero, where the jet alone
+// would return 0 or the dividend.
+func (t *Transpiler) checkedDivision(jet string) string {
+	helper := checkedHelperPrefix + jet
+	t.helpers[helper] = true
+	return helper
+}
+
+// isCheckedDivision reports whether name is a helper of checkedDivision.
+func isCheckedDivision(name string) bool {
+	return strings.HasPrefix(name, checkedHelperPrefix+"divide_") || strings.HasPrefix(name, checkedHelperPrefix+"modulo_")
+}
+
+// checkedDivisionHelpers returns the definitions of the checked division
+// helpers the program calls, in name order.
+func (t *Transpiler) checkedDivisionHelpers() []string {
+	var names []string
+	for name := range t.helpers {
+		if isCheckedDivision(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	defs := make([]string, len(names))
+	for i, name := range names {
+		jet := strings.TrimPrefix(name, checkedHelperPrefix)
+		bits := jet[strings.LastIndex(jet, "_")+1:]
+		defs[i] = fmt.Sprintf("fn %s(a: u%s, b: u%s) -> u%s {\n    assert!(%s(0, b));\n    %s(a, b)\n}",
+			name, bits, bits, bits, jetRef("lt_"+bits), jetRef(jet))
+	}
+	return defs
+}
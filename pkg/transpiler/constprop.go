@@ -0,0 +1,103 @@
+package transpiler
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// forgetAssigned forgets every variable that the statements under n assign
+// to or take the address of, for statements whose effect the folder does
+// not model, such as loops and branches. A variable declared under n, as
+// by a := that shadows one outside, is not the outer variable, which keeps
+// its value.
+func (t *Transpiler) forgetAssigned(n ast.Node) {
+	forget := func(ident *ast.Ident) {
+		if ident.Obj == nil || ident.Obj.Pos() < n.Pos() || ident.Obj.Pos() >= n.End() {
+			t.folder.forget(ident.Name)
+		}
+	}
+	ast.Inspect(n, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range node.Lhs {
+				if ident, ok := assignedVar(lhs); ok {
+					forget(ident)
+				}
+			}
+		case *ast.IncDecStmt:
+			if ident, ok := assignedVar(node.X); ok {
+				forget(ident)
+			}
+		case *ast.UnaryExpr:
+			if ident, ok := node.X.(*ast.Ident); ok && node.Op == token.AND {
+				forget(ident)
+			}
+		}
+		return true
+	})
+}
+
+// assignedVar returns the variable an assignment to lhs changes: lhs
+// itself, or the array of an element, as in xs[0] = 1.
+func assignedVar(lhs ast.Expr) (*ast.Ident, bool) {
+	if index, ok := lhs.(*ast.IndexExpr); ok {
+		lhs = index.X
+	}
+	ident, ok := lhs.(*ast.Ident)
+	return ident, ok
+}
+
+// forgetBranchAssigned applies forgetAssigned to compound statements, whose
+// assignments may run conditionally or repeatedly.
+func (t *Transpiler) forgetBranchAssigned(stmt ast.Stmt) {
+	switch stmt.(type) {
+	case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt, *ast.BlockStmt, *ast.LabeledStmt:
+		t.forgetAssigned(stmt)
+	}
+}
+
+// trackAssign updates the environment for a single-variable assignment,
+// including op-assignments such as x += 1. It reports the folded value
+// and whether it depends on a witness, or ok=false after forgetting the
+// variable when the new value is unknown. A variable reassigned a value
+// the folder does not know keeps the type of the one it had, as a Go
+// variable does, so that var n uint16 followed by n += a adds 16-bit
+// words.
+func (t *Transpiler) trackAssign(s *ast.AssignStmt) (v Value, derived, ok bool) {
+	ident, isIdent := s.Lhs[0].(*ast.Ident)
+	if !isIdent || ident.Name == "_" {
+		return Value{}, false, false
+	}
+	rhs := s.Rhs[0]
+	if op, isOp := opAssignTokens[s.Tok]; isOp {
+		rhs = &ast.BinaryExpr{X: ident, Op: op, Y: rhs}
+	}
+	if v, ok := t.folder.Fold(rhs); ok {
+		t.folder.bind(ident.Name, v, false)
+		return v, false, true
+	}
+	if v, ok := t.folder.FoldDerived(rhs); ok {
+		t.folder.bind(ident.Name, v, true)
+		return v, true, true
+	}
+	if b, known := t.folder.lookup(ident.Name); known && s.Tok != token.DEFINE && isUIntType(b.value.Type) {
+		t.params[ident.Name] = b.value.Type
+	}
+	t.folder.forget(ident.Name)
+	return Value{}, false, false
+}
+
+// opAssignTokens maps op-assignment tokens to their binary operators.
+var opAssignTokens = map[token.Token]token.Token{
+	token.ADD_ASSIGN:     token.ADD,
+	token.SUB_ASSIGN:     token.SUB,
+	token.MUL_ASSIGN:     token.MUL,
+	token.QUO_ASSIGN:     token.QUO,
+	token.REM_ASSIGN:     token.REM,
+	token.AND_ASSIGN:     token.AND,
+	token.OR_ASSIGN:      token.OR,
+	token.XOR_ASSIGN:     token.XOR,
+	token.SHL_ASSIGN:     token.SHL,
+	token.SHR_ASSIGN:     token.SHR,
+	token.AND_NOT_ASSIGN: token.AND_NOT,
+}
@@ -0,0 +1,67 @@
+package transpiler
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/jets"
+)
+
+// A jet declared with jets.JetDecl is called by its Go name, jet.MyJet(x),
+// or by its SimplicityHL name through the generic jet.Custom:
+//
+//	ok := jet.Custom("my_jet", x, y)  →  let ok: bool = jet::my_jet(x, y);
+//
+// Before analysis each jet.Custom call is rewritten into a call by Go name,
+// so the rest of the transpiler sees an ordinary jet call.
+
+// CustomJet returns the jet that call, a call of jet.Custom, names in
+// registry. The name must be a string literal.
+func CustomJet(registry *jets.JetRegistry, call *ast.CallExpr) (jets.JetInfo, error) {
+	if len(call.Args) == 0 {
+		return jets.JetInfo{}, diag.UnsupportedSyntax.Errorf("jet.%s takes the SimplicityHL name of the jet, as in jet.%s(\"my_jet\", x)", jets.CustomGoName, jets.CustomGoName)
+	}
+	lit, ok := call.Args[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return jets.JetInfo{}, diag.UnsupportedSyntax.Errorf("the jet name jet.%s takes must be a string literal", jets.CustomGoName)
+	}
+	name, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return jets.JetInfo{}, diag.UnsupportedSyntax.Errorf("invalid jet name %s", lit.Value)
+	}
+	info, ok := registry.LookupJet(name)
+	if !ok {
+		return jets.JetInfo{}, diag.UnknownJet.Errorf("unknown jet: jet.%s(%q) names no jet of the table or of Config.ExtraJets", jets.CustomGoName, name)
+	}
+	return info, nil
+}
+
+// lowerCustomJets rewrites each jet.Custom call of file into a call of the
+// jet by its Go name.
+func (t *Transpiler) lowerCustomJets(file *ast.File) error {
+	var err error
+	ast.Inspect(file, func(n ast.Node) bool {
+		if err != nil {
+			return false
+		}
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != jets.CustomGoName || !t.isJet(sel.X) {
+			return true
+		}
+		info, lookupErr := CustomJet(t.jetRegistry, call)
+		if e, ok := lookupErr.(*diag.Error); ok {
+			err = e.Code.Wrap(t.errorAt(call.Pos(), "%v", e.Err))
+			return false
+		}
+		call.Fun = &ast.SelectorExpr{X: sel.X, Sel: &ast.Ident{NamePos: sel.Sel.NamePos, Name: info.GoName}}
+		call.Args = call.Args[1:]
+		return true
+	})
+	return err
+}
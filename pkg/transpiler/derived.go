@@ -0,0 +1,176 @@
+package transpiler
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// derivedBinding lowers name := expr in main, where expr computes a value
+// from witnesses, to let bindings that recompute it from its inputs. As a
+// witness of its own the value would be whatever the spender supplies,
+// whether or not it matches the inputs. It reports false for an expression
+// it cannot lower, which stays a witness; so does a value declared with
+// var, which is how a program marks one as the spender's to choose.
+func (t *Transpiler) derivedBinding(name string, s *ast.AssignStmt) bool {
+	l := derivedLowering{t: t, name: t.toSnakeCase(name), pos: s.Pos()}
+	if _, _, ok := l.lower(s.Rhs[0], true); !ok || len(l.calls) == 0 {
+		return false
+	}
+	// A struct is destructured, so that selectors such as p.Ok read locals
+	// like p_ok.
+	if last := &l.calls[len(l.calls)-1]; last.VarName == l.name {
+		if typeName := t.structResult(s.Rhs[0]); typeName != "" {
+			v := t.bindStruct(snakeCase(name), typeName)
+			t.structParams[name] = v
+			last.VarName = v.pattern()
+		}
+	}
+	t.jetCalls = append(t.jetCalls, l.calls...)
+	return true
+}
+
+// structResult returns the plain struct type expr, a call of a function of
+// the file, returns, or "".
+func (t *Transpiler) structResult(expr ast.Expr) string {
+	call, ok := ast.Unparen(expr).(*ast.CallExpr)
+	if !ok {
+		return ""
+	}
+	ident, ok := call.Fun.(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	decl, ok := t.funcDecls[ident.Name]
+	if !ok || decl.Type.Results == nil || len(decl.Type.Results.List) != 1 {
+		return ""
+	}
+	result, ok := decl.Type.Results.List[0].Type.(*ast.Ident)
+	if !ok || t.structDecls[result.Name] == nil || len(t.structFields[result.Name]) == 0 {
+		return ""
+	}
+	return result.Name
+}
+
+// derivedLowering flattens an arithmetic expression into jet calls, one per
+// operator, binding intermediate results to temporaries named after the
+// operator. An operand that is no operator, such as a call, is bound whole
+// to a JetCall without a JetName.
+type derivedLowering struct {
+	t     *Transpiler
+	name  string
+	pos   token.Pos
+	calls []JetCall
+}
+
+// lower returns the SimplicityHL operand for expr and its type, "" for an
+// untyped constant. The outermost operation, final, binds name itself.
+func (l *derivedLowering) lower(expr ast.Expr, final bool) (ref, typ string, ok bool) {
+	if v, ok := l.t.folder.Fold(expr); ok {
+		return v.String(), v.Type, true
+	}
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return l.lower(e.X, final)
+	case *ast.Ident, *ast.SelectorExpr:
+		ref, err := l.t.expr.TranslateArg(e)
+		if err != nil || !l.t.expr.isRuntime(e, ref) {
+			return "", "", false
+		}
+		typ := carryFree(l.t.expr.TypeOf(e))
+		if !isUIntType(typ) {
+			return "", "", false
+		}
+		return l.bind(ref, typ, final), typ, true
+	case *ast.BinaryExpr:
+		return l.binary(e, final)
+	}
+	return l.value(expr, final)
+}
+
+// value lowers expr, such as a call or a negation, that reads a runtime
+// value but is no operator of its own: it is translated whole.
+func (l *derivedLowering) value(expr ast.Expr, final bool) (string, string, bool) {
+	if !l.readsRuntime(expr) {
+		return "", "", false
+	}
+	ref, err := l.t.expr.TranslateArg(expr)
+	if err != nil {
+		return "", "", false
+	}
+	typ := l.t.convertedType(expr)
+	if !isUIntType(typ) && typ != "bool" && !strings.HasPrefix(typ, "[") && !strings.HasPrefix(typ, "(") {
+		return "", "", false
+	}
+	return l.bind(ref, typ, final), typ, true
+}
+
+// bind returns ref, binding it to name first when it is the outermost
+// operation.
+func (l *derivedLowering) bind(ref, typ string, final bool) string {
+	if !final {
+		return ref
+	}
+	l.calls = append(l.calls, JetCall{VarName: l.name, Args: ref, ReturnType: typ, Pos: l.pos})
+	return l.name
+}
+
+// readsRuntime reports whether expr reads a witness or a local of main.
+func (l *derivedLowering) readsRuntime(expr ast.Expr) bool {
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.Ident, *ast.SelectorExpr:
+			if sym, ok := l.t.expr.lookupPath(n.(ast.Expr)); ok && (sym.Kind == SymbolWitness || sym.Kind == SymbolLocal) {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+func (l *derivedLowering) binary(e *ast.BinaryExpr, final bool) (string, string, bool) {
+	left, leftType, ok := l.lower(e.X, false)
+	if !ok {
+		return "", "", false
+	}
+	right, rightType, ok := l.lower(e.Y, false)
+	if !ok {
+		return "", "", false
+	}
+	width := leftType
+	if width == "" {
+		width = rightType
+	}
+	if !isUIntType(width) {
+		return "", "", false
+	}
+	jet, swap := operatorToJetName(e.Op, width)
+	if jet == "" || width == "u256" && e.Op == token.MUL {
+		return "", "", false
+	}
+	if swap {
+		left, right = right, left
+	}
+	if _, constant := l.t.folder.Fold(e.Y); (e.Op == token.QUO || e.Op == token.REM) && !constant && l.t.expr.divisions != nil {
+		jet = l.t.expr.divisions(jet)
+	}
+	jc := JetCall{
+		VarName:    l.name,
+		JetName:    jet,
+		Args:       left + ", " + right,
+		ReturnType: operatorReturnType(e.Op, width),
+		Pos:        l.pos,
+	}
+	if !final {
+		jc.VarName = l.t.temp(e.OpPos)
+	}
+	typ := carryFree(jc.ReturnType)
+	if e.Op == token.MUL {
+		// Go's multiply wraps, so only the low half of the product counts.
+		jc.ReturnType, jc.Wrap, typ = width, true, width
+	}
+	l.calls = append(l.calls, jc)
+	return jc.VarName, typ, true
+}
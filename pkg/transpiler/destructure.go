@@ -0,0 +1,285 @@
+package transpiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// A tuple is destructured by a pattern wherever it is bound. The results
+// of a function returning several values are a tuple, bound by the let of
+// the assignment that receives them,
+//
+//	x, ok := Split(v)  →  let (x, ok): (u64, bool) = split(v);
+//
+// and a payload that is a tuple, the fields of a multi-field Left, a plain
+// struct that is the Value of an Option or the payload of a spend path, is
+// destructured by the pattern of its arm:
+//
+//	if q.IsSome {        →  match witness::Q {
+//		use(q.Value.Price)       Some((q_price, _): (u64, bool)) => { ... },
+//
+// A position the arm does not read is _, as is a name the Go discards. An
+// if whose init binds what its condition tests, as in
+//
+//	if p, found := Lookup(k); found {
+//		return p
+//	}
+//	return 0
+//
+// is, in a function, the let of its init followed by the match on its
+// condition that is the function's result, when both branches return; an
+// if without an init is the match alone.
+
+// resultType returns the SimplicityHL type results declares: "" for none,
+// the type of a single result, and the tuple of several.
+func (t *Transpiler) resultType(results *ast.FieldList) (string, error) {
+	types, err := t.resultTypes(results)
+	switch {
+	case err != nil || len(types) == 0:
+		return "", err
+	case len(types) == 1:
+		return types[0], nil
+	}
+	return tupleOf(types), nil
+}
+
+// resultTypes returns the type of each result results declares.
+func (t *Transpiler) resultTypes(results *ast.FieldList) ([]string, error) {
+	if results == nil {
+		return nil, nil
+	}
+	var types []string
+	for _, field := range results.List {
+		typ, err := t.mapType(field.Type)
+		if err != nil {
+			return nil, err
+		}
+		for range max(len(field.Names), 1) {
+			types = append(types, typ)
+		}
+	}
+	return types, nil
+}
+
+// callResults returns the result types of expr when it calls a function
+// of the file.
+func (t *Transpiler) callResults(expr ast.Expr) ([]string, bool) {
+	call, ok := ast.Unparen(expr).(*ast.CallExpr)
+	if !ok {
+		return nil, false
+	}
+	ident, ok := call.Fun.(*ast.Ident)
+	if !ok {
+		return nil, false
+	}
+	decl, ok := t.funcDecls[ident.Name]
+	if !ok {
+		return nil, false
+	}
+	types, err := t.resultTypes(decl.Type.Results)
+	return types, err == nil && len(types) > 0
+}
+
+// analyzeTupleAssign lowers stmt, an assignment of the results of one call
+// to several names, to the let of a tuple pattern, typed when the call is
+// to a function of the file. The names are recorded as locals of their
+// types.
+func (t *Transpiler) analyzeTupleAssign(stmt *ast.AssignStmt) (string, error) {
+	names := make([]string, len(stmt.Lhs))
+	for i, l := range stmt.Lhs {
+		ident, ok := l.(*ast.Ident)
+		if !ok {
+			return "", t.droppedStatement(stmt, "the assignment")
+		}
+		names[i] = "_"
+		if ident.Name != "_" {
+			names[i] = t.toSnakeCase(ident.Name)
+		}
+	}
+	rhs, err := t.expr.TranslateArg(stmt.Rhs[0])
+	if err != nil {
+		return "", err
+	}
+	types, ok := t.callResults(stmt.Rhs[0])
+	if !ok || len(types) != len(names) {
+		return fmt.Sprintf("let %s = %s;", tupleOf(names), rhs), nil
+	}
+	for i, l := range stmt.Lhs {
+		if name := l.(*ast.Ident).Name; name != "_" {
+			t.folder.forget(name)
+			t.params[name] = types[i]
+		}
+	}
+	return fmt.Sprintf("let %s: %s = %s;", tupleOf(names), tupleOf(types), rhs), nil
+}
+
+// lowerIfResult lowers s, an if statement that rest follows in a function
+// body, to the lines of the let of its init, if it has one, and the match on
+// its condition that is the function's result, when both branches return:
+// its body and the else, or rest, the statements after an if without one,
+// each lowered as a body of its own, so that a chain of guards nests. It
+// reports false for any other if.
+func (t *Transpiler) lowerIfResult(s *ast.IfStmt, rest []ast.Stmt) ([]string, bool, error) {
+	returns := func(body []ast.Stmt) bool {
+		if len(body) == 0 {
+			return false
+		}
+		ret, ok := body[len(body)-1].(*ast.ReturnStmt)
+		return ok && len(ret.Results) > 0
+	}
+	// if a { if b { return x } } is the guard if a && b { return x }.
+	if inner, ok := soleIf(s.Body.List); ok && s.Init == nil && s.Else == nil && inner.Init == nil && inner.Else == nil {
+		cond := &ast.BinaryExpr{X: s.Cond, OpPos: inner.If, Op: token.LAND, Y: inner.Cond}
+		return t.lowerIfResult(&ast.IfStmt{If: s.If, Cond: cond, Body: inner.Body}, rest)
+	}
+	var otherwise []ast.Stmt
+	switch e := s.Else.(type) {
+	case nil:
+		otherwise = rest
+	case *ast.BlockStmt:
+		otherwise = e.List
+		if len(rest) != 0 {
+			return nil, false, nil
+		}
+	default:
+		return nil, false, nil
+	}
+	if !returns(s.Body.List) || !returns(otherwise) {
+		return nil, false, nil
+	}
+
+	t.folder.push()
+	defer t.folder.pop()
+	var lines []string
+	if s.Init != nil {
+		init, err := t.analyzeStatement(s.Init)
+		if err != nil {
+			return nil, false, err
+		}
+		if init != "" {
+			lines = append(lines, init)
+		}
+	}
+	cond, err := t.expr.TranslateArg(s.Cond)
+	if err != nil {
+		return nil, false, err
+	}
+	arm := func(body []ast.Stmt) ([]string, error) {
+		text, _, err := t.analyzeFunctionBody(&ast.BlockStmt{List: body})
+		if err != nil || text == "" {
+			return nil, err
+		}
+		return []string{text}, nil
+	}
+	then, err := arm(s.Body.List)
+	if err != nil {
+		return nil, false, err
+	}
+	els, err := arm(otherwise)
+	if err != nil {
+		return nil, false, err
+	}
+	match := &MatchExpression{
+		Scrutinee:   cond,
+		Cases:       []MatchCase{{Pattern: "true", BodyStmts: then}, {Pattern: "false", BodyStmts: els}},
+		IsBoolMatch: true,
+	}
+	return append(lines, matchText(match)), true, nil
+}
+
+// soleIf returns the if statement that is all of body.
+func soleIf(body []ast.Stmt) (*ast.IfStmt, bool) {
+	if len(body) != 1 {
+		return nil, false
+	}
+	s, ok := body[0].(*ast.IfStmt)
+	return s, ok
+}
+
+// patternReading returns the pattern of the locals bound to v, with _ in
+// place of each that body, the arm the pattern binds, does not read. A
+// nested struct none of whose fields it reads is _ as a whole.
+func (v *structValue) patternReading(body []string) string {
+	refs := make([]string, len(v.fields))
+	for i, f := range v.fields {
+		switch {
+		case f.nested != nil:
+			refs[i] = f.nested.patternReading(body)
+			if strings.Trim(refs[i], "(_, )") == "" {
+				refs[i] = "_"
+			}
+		case readsLocal(body, f.ref):
+			refs[i] = f.ref
+		default:
+			refs[i] = "_"
+		}
+	}
+	return tupleOf(refs)
+}
+
+// tuplePattern returns the pattern that binds the elements of a tuple to
+// names, with _ in place of each that body does not read.
+func tuplePattern(names []string, body []string) string {
+	v := &structValue{}
+	for _, name := range names {
+		v.fields = append(v.fields, boundField{ref: name})
+	}
+	return v.patternReading(body)
+}
+
+// readsLocal reports whether a statement of body reads the local name.
+func readsLocal(body []string, name string) bool {
+	for _, stmt := range body {
+		for rest := stmt; ; {
+			i := strings.Index(rest, name)
+			if i < 0 {
+				break
+			}
+			end := i + len(name)
+			if (i == 0 || !isIdentByte(rest[i-1])) && (end == len(rest) || !isIdentByte(rest[end])) {
+				return true
+			}
+			rest = rest[end:]
+		}
+	}
+	return false
+}
+
+// optionStruct returns the plain struct that is the Value of the Option
+// witness varBase, or "".
+func (t *Transpiler) optionStruct(varBase string) string {
+	upperName := strings.ToUpper(t.toSnakeCase(varBase))
+	for _, w := range t.witnessValues {
+		if strings.ToUpper(w.Name) == upperName && w.GoTypeName != "" {
+			if value := t.optionStructs[w.GoTypeName]; len(t.structFields[value]) > 0 {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// bindOptionStruct binds the fields of the plain struct Value of the Option
+// witness varBase to locals named after their path, such as q_price for
+// q.Value.Price, for the analysis of its Some arm. It returns the fields,
+// nil for an Option of anything else, and the function that undoes the
+// binding.
+func (t *Transpiler) bindOptionStruct(varBase string) (*structValue, func()) {
+	value := t.optionStruct(varBase)
+	if value == "" {
+		return nil, func() {}
+	}
+	v := t.bindStruct(snakeCase(varBase), value)
+	field := boundField{structField: structField{Name: "Value", Type: t.customTypes[value], TypeName: value}, ref: v.pattern(), nested: v}
+	saved, had := t.structParams[varBase]
+	t.structParams[varBase] = &structValue{typeName: value, fields: []boundField{field}}
+	return v, func() {
+		if had {
+			t.structParams[varBase] = saved
+		} else {
+			delete(t.structParams, varBase)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package transpiler
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/jets"
+)
+
+// Dialect is a version of the SimplicityHL surface syntax that a downstream
+// compiler accepts. The transpiler always renders the latest; Rewrite turns
+// that into the dialect. Every difference in syntax between dialects is a
+// field here, so supporting another version is one more entry in dialects;
+// the jets a dialect names differently are recorded in the jet table, by
+// JetInfo.Dialects.
+type Dialect struct {
+	Name string
+	// ParamModule reports whether the dialect has the param module. Without
+	// it, each param::NAME reference is replaced by the value of NAME.
+	ParamModule bool
+	// Assert is the spelling of an assertion that a bool is true:
+	// "assert!" or a jet call such as "jet::verify".
+	Assert string
+	// Include is the directive that includes a module by its path, a
+	// format with one %q verb. It is empty for a dialect without one, in
+	// which an included module is copied into the program; no version of
+	// SimplicityHL has one yet.
+	Include string
+	// IntegerPatterns reports whether a match arm can be an integer, as in
+	// 0 => and _ =>. No version of SimplicityHL has them yet.
+	IntegerPatterns bool
+}
+
+// LatestDialect is the dialect the transpiler renders, and the default.
+const LatestDialect = "latest"
+
+// dialects is the table of supported dialects, newest first.
+var dialects = []*Dialect{
+	{Name: LatestDialect, ParamModule: true, Assert: "assert!"},
+	{Name: "simfony-0.3", Assert: "jet::verify"},
+}
+
+// integerPatterns reports whether every dialect has integer patterns, so
+// that the transpiler can match on an integer's value. Rewrite converts a
+// line at a time and cannot lower such a match to comparisons, so it is
+// rendered only when no dialect needs them.
+func integerPatterns() bool {
+	for _, d := range dialects {
+		if !d.IntegerPatterns {
+			return false
+		}
+	}
+	return true
+}
+
+// Dialects returns the names of the supported dialects, newest first.
+func Dialects() []string {
+	names := make([]string, len(dialects))
+	for i, d := range dialects {
+		names[i] = d.Name
+	}
+	return names
+}
+
+// LookupDialect returns the dialect called name; the empty name is
+// LatestDialect.
+func LookupDialect(name string) (*Dialect, error) {
+	if name == "" {
+		name = LatestDialect
+	}
+	for _, d := range dialects {
+		if d.Name == name {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown SimplicityHL dialect %s (want one of %s)", name, strings.Join(Dialects(), ", "))
+}
+
+var (
+	paramConst = regexp.MustCompile(`^\s*const ([A-Z0-9_]+): .* = (.*);$`)
+	paramRef   = regexp.MustCompile(`\bparam::([A-Z0-9_]+)\b`)
+	jetPattern = regexp.MustCompile(`\bjet::([a-z0-9_]+)\b`)
+)
+
+// Rewrite converts code, a program in the latest dialect, to d, naming
+// its jets as table records them for d. It returns the rewritten program
+// and, for each of its lines, the 1-based line of code it comes from, as
+// the optimization passes do.
+func (d *Dialect) Rewrite(code string, table *jets.JetRegistry) (string, []int) {
+	lines := strings.Split(code, "\n")
+	params := make(map[string]string)
+	out := make([]string, 0, len(lines))
+	origin := make([]int, 0, len(lines))
+	inParams := false
+	for i, line := range lines {
+		switch {
+		case !d.ParamModule && line == "mod param {":
+			inParams = true
+			continue
+		case inParams:
+			if m := paramConst.FindStringSubmatch(line); m != nil {
+				params[m[1]] = m[2]
+			}
+			inParams = line != "}"
+			continue
+		}
+		out = append(out, d.rewriteLine(line, params, table))
+		origin = append(origin, i+1)
+	}
+	return strings.Join(out, "\n"), origin
+}
+
+// rewriteLine converts one line outside the param module. Comments are
+// left alone.
+func (d *Dialect) rewriteLine(line string, params map[string]string, table *jets.JetRegistry) string {
+	if strings.HasPrefix(strings.TrimSpace(line), "//") {
+		return line
+	}
+	if len(params) > 0 {
+		line = paramRef.ReplaceAllStringFunc(line, func(ref string) string {
+			if value, ok := params[strings.TrimPrefix(ref, "param::")]; ok {
+				return value
+			}
+			return ref
+		})
+	}
+	if d.Assert != "assert!" {
+		line = strings.ReplaceAll(line, "assert!(", d.Assert+"(")
+	}
+	if d.Name != LatestDialect {
+		line = jetPattern.ReplaceAllStringFunc(line, func(ref string) string {
+			if info, ok := table.LookupJet(strings.TrimPrefix(ref, "jet::")); ok {
+				return "jet::" + info.Name(d.Name)
+			}
+			return ref
+		})
+	}
+	return line
+}
@@ -0,0 +1,266 @@
+package transpiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	gotypes "go/types"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	simtypes "github.com/0ceanslim/go-simplicity/pkg/types"
+)
+
+// SimplicityHL values are immutable, so an assignment to an element of an
+// array at a constant index,
+//
+//	var msg [4]byte
+//	msg[0] = 0x01
+//
+// gives the array a new value, the old one with that element replaced.
+// When the folder knows every element of the old value and the new element
+// folds, so does the new value, and the assignment is a let of the literal:
+//
+//	let msg: [u8; 4] = 0x01000000;
+//
+// Otherwise the old value is destructured, and the new one built from its
+// other elements and the new element:
+//
+//	let [_, msg_1, msg_2, msg_3]: [u8; 4] = msg;
+//	let msg: [u8; 4] = [x, msg_1, msg_2, msg_3];
+//
+// In main an array declared with var is a witness, and an assignment to
+// one of its elements, as test setup does, sets that element of the
+// witness's value. An index only known when the program runs is an error.
+
+// knownArray is an array whose every element is known at compile time.
+type knownArray struct {
+	typ   string  // SimplicityHL type of the array
+	elems []Value // Its elements, in order
+}
+
+// String returns the SimplicityHL literal for a: hex for a byte array.
+func (a knownArray) String() string {
+	if elem, _, _ := arrayType(a.typ); elem == "u8" {
+		b := make([]byte, len(a.elems))
+		for i, v := range a.elems {
+			b[i] = byte(v.Int.Uint64())
+		}
+		if s, err := simtypes.EncodeBytes(a.typ, b); err == nil {
+			return s
+		}
+	}
+	elems := make([]string, len(a.elems))
+	for i, v := range a.elems {
+		elems[i] = v.String()
+	}
+	return "[" + strings.Join(elems, ", ") + "]"
+}
+
+// with returns a with element k replaced by v.
+func (a knownArray) with(k int, v Value) knownArray {
+	elems := append([]Value(nil), a.elems...)
+	elems[k] = v
+	return knownArray{typ: a.typ, elems: elems}
+}
+
+// bindArray records that every element of the Go local name is known.
+func (f *Folder) bindArray(name string, a knownArray) {
+	if f.scope != nil {
+		f.scope.arrays[name] = a
+	}
+}
+
+// array returns the elements of the Go local name when they are all known.
+func (f *Folder) array(name string) (knownArray, bool) {
+	for s := f.scope; s != nil; s = s.parent {
+		if _, shadowed := s.vars[name]; shadowed {
+			return knownArray{}, false
+		}
+		if a, ok := s.arrays[name]; ok {
+			return a, true
+		}
+	}
+	return knownArray{}, false
+}
+
+// zeroArray returns the zero value of typ when it is an array of integers
+// or bools.
+func zeroArray(typ string) (knownArray, bool) {
+	elem, n, ok := arrayType(typ)
+	if !ok {
+		return knownArray{}, false
+	}
+	zero, ok := zeroFolded(elem)
+	if !ok {
+		return knownArray{}, false
+	}
+	a := knownArray{typ: typ, elems: make([]Value, n)}
+	for i := range a.elems {
+		a.elems[i] = zero
+	}
+	return a, true
+}
+
+// foldArray returns the value of expr, an array of type typ, when every
+// element is known: the zero value for a nil expr, or a composite literal
+// listing elements that fold, the ones it leaves out being zero.
+func (t *Transpiler) foldArray(typ string, expr ast.Expr) (knownArray, bool) {
+	a, ok := zeroArray(typ)
+	if !ok || expr == nil {
+		return a, ok
+	}
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok || len(lit.Elts) > len(a.elems) {
+		return knownArray{}, false
+	}
+	for i, elt := range lit.Elts {
+		v, ok := t.arrayElement(a, elt)
+		if !ok {
+			return knownArray{}, false
+		}
+		a.elems[i] = v
+	}
+	return a, true
+}
+
+// arrayElement folds expr as an element of a.
+func (t *Transpiler) arrayElement(a knownArray, expr ast.Expr) (Value, bool) {
+	elem, _, _ := arrayType(a.typ)
+	v, ok := t.folder.Fold(expr)
+	if !ok {
+		return Value{}, false
+	}
+	if v.Int != nil && v.Type == "" && isUIntType(elem) {
+		v.Type = elem
+		v, ok = checkWidth(v)
+	}
+	return v, ok && v.Type == elem
+}
+
+// trackArray records the value a declaration or := of the local name with
+// type typ, or the type of value when typ is "", gives it, when every
+// element is known.
+func (t *Transpiler) trackArray(name, typ string, value ast.Expr) {
+	if typ == "" {
+		lit, ok := value.(*ast.CompositeLit)
+		if !ok || lit.Type == nil {
+			return
+		}
+		var err error
+		if typ, err = t.mapType(lit.Type); err != nil {
+			return
+		}
+	}
+	if a, ok := t.foldArray(typ, value); ok {
+		t.folder.bindArray(name, a)
+	}
+}
+
+// elementAssignment returns the array and the index of stmt when it
+// assigns an element of an array variable, xs[k] = v. An index that is not
+// a constant is an error.
+func (t *Transpiler) elementAssignment(stmt *ast.AssignStmt) (*ast.Ident, int, bool, error) {
+	if stmt.Tok != token.ASSIGN || len(stmt.Lhs) != 1 || len(stmt.Rhs) != 1 {
+		return nil, 0, false, nil
+	}
+	index, ok := stmt.Lhs[0].(*ast.IndexExpr)
+	if !ok {
+		return nil, 0, false, nil
+	}
+	array, ok := index.X.(*ast.Ident)
+	if !ok {
+		return nil, 0, false, diag.UnsupportedSyntax.Wrap(t.errorAt(stmt.Pos(), "the assignment to %s is not supported: only an element of an array variable can be assigned", gotypes.ExprString(index)))
+	}
+	k, ok := t.constantInt(index.Index)
+	if !ok {
+		if v, folded := t.folder.Fold(index.Index); folded && v.Int != nil {
+			k, ok = v.Int, true
+		}
+	}
+	if !ok {
+		return nil, 0, false, diag.UnsupportedSyntax.Wrap(t.errorAt(index.Index.Pos(), "the assignment to %s is not supported: its index %s is only known when the program runs, and only an element at a constant index can be assigned", gotypes.ExprString(index), gotypes.ExprString(index.Index)))
+	}
+	if !k.IsInt64() || k.Sign() < 0 {
+		return nil, 0, false, t.errorAt(index.Index.Pos(), "the index %s of %s is out of range", k, array.Name)
+	}
+	return array, int(k.Int64()), true, nil
+}
+
+// lowerElementAssign lowers stmt, an assignment to element k of array in a
+// function body, to the let of the array's new value.
+func (t *Transpiler) lowerElementAssign(stmt *ast.AssignStmt, array *ast.Ident, k int) (string, error) {
+	value := stmt.Rhs[0]
+	name := t.toSnakeCase(array.Name)
+	if a, ok := t.folder.array(array.Name); ok && k < len(a.elems) {
+		if v, ok := t.arrayElement(a, value); ok {
+			a = a.with(k, v)
+			t.folder.bindArray(array.Name, a)
+			return fmt.Sprintf("let %s: %s = %s;", name, a.typ, a), nil
+		}
+	}
+	known, isKnown := t.folder.array(array.Name)
+	typ := known.typ
+	if !isKnown {
+		sym, ok := t.expr.symbols.Lookup(array.Name)
+		if !ok {
+			return "", t.errorAt(stmt.Pos(), "the assignment to %s[%d] cannot be lowered: the type of %s is not known", array.Name, k, array.Name)
+		}
+		typ = sym.Type
+	}
+	_, n, ok := arrayType(typ)
+	if !ok {
+		return "", t.errorAt(stmt.Pos(), "the assignment to %s[%d] cannot be lowered: %s is not an array", array.Name, k, array.Name)
+	}
+	if k >= n {
+		return "", t.errorAt(stmt.Lhs[0].(*ast.IndexExpr).Index.Pos(), "the index %d of %s is out of range for %s", k, array.Name, typ)
+	}
+	ref, err := t.expr.TranslateArg(value)
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	elems, names := make([]string, n), make([]string, n)
+	for i := range elems {
+		switch {
+		case i == k:
+			elems[i], names[i] = ref, "_"
+		case isKnown:
+			elems[i] = known.elems[i].String()
+		default:
+			elems[i] = fmt.Sprintf("%s_%d", name, i)
+			names[i] = elems[i]
+		}
+	}
+	if !isKnown {
+		lines = append(lines, fmt.Sprintf("let [%s]: %s = %s;", strings.Join(names, ", "), typ, name))
+	}
+	t.folder.forget(array.Name)
+	t.params[array.Name] = typ
+	lines = append(lines, fmt.Sprintf("let %s: %s = [%s];", name, typ, strings.Join(elems, ", ")))
+	return strings.Join(lines, "\n"), nil
+}
+
+// assignWitnessElement applies stmt, an assignment to element k of array in
+// main, to the value of the witness array is, and reports false when the
+// new value is not known at compile time.
+func (t *Transpiler) assignWitnessElement(stmt *ast.AssignStmt, array *ast.Ident, k int) bool {
+	a, ok := t.folder.array(array.Name)
+	if !ok || k >= len(a.elems) {
+		return false
+	}
+	v, ok := t.arrayElement(a, stmt.Rhs[0])
+	if !ok {
+		return false
+	}
+	upper := strings.ToUpper(t.toSnakeCase(array.Name))
+	for i := range t.witnessValues {
+		if w := &t.witnessValues[i]; strings.EqualFold(w.Name, upper) {
+			a = a.with(k, v)
+			t.folder.bindArray(array.Name, a)
+			w.Value = a.String()
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+package transpiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+)
+
+// Fallback is a place where the transpiler guessed instead of lowering the
+// Go it was given: an expression it compiled to the placeholder true, a
+// statement it left out, a witness whose type it inferred from the value,
+// or a main it made up from the names of the witnesses. Every one goes
+// through Transpiler.fallback, so this is also the inventory of the
+// heuristics that remain.
+type Fallback struct {
+	Pos    token.Pos
+	Reason string // What was guessed, and what the output does instead
+}
+
+// Fallbacks returns the fallbacks of the most recent ToSimplicityHL call,
+// in the order analysis met them. Under Options.Strict there is at most
+// one, the one that failed the call.
+func (t *Transpiler) Fallbacks() []Fallback {
+	return append([]Fallback(nil), t.fallbacks...)
+}
+
+// fallback records that the transpiler falls back on a guess at pos, and
+// fails in strict mode.
+func (t *Transpiler) fallback(reason string, pos token.Pos) error {
+	t.fallbacks = append(t.fallbacks, Fallback{Pos: pos, Reason: reason})
+	if t.strict {
+		return diag.SilentFallback.Wrap(t.errorAt(pos, "strict mode: %s", reason))
+	}
+	return nil
+}
+
+// droppedStatement records a statement of kind that the analysis of main
+// or a function body leaves out of the output.
+func (t *Transpiler) droppedStatement(stmt ast.Stmt, kind string) error {
+	return t.fallback(kind+" is not lowered and is left out of the program", stmt.Pos())
+}
+
+// mainFallbacks records the heuristics generateMainFunction applies to the
+// main analyzeMainFunction found in funcDecl: the statements of main a
+// shape of program leaves out, and the checks it makes up.
+func (t *Transpiler) mainFallbacks(funcDecl *ast.FuncDecl) error {
+	name := funcDecl.Name.Name
+	switch {
+	case t.hasMatchExpr && len(t.matchExprs) > 0:
+		boolMatch := false
+		for _, m := range t.matchExprs {
+			boolMatch = boolMatch || m.onValue()
+		}
+		if boolMatch {
+			for _, m := range t.matchExprs {
+				if !m.onValue() {
+					if err := t.fallback("the match on "+m.Scrutinee+" is left out of a main that matches on a bool", m.Pos); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}
+		if err := t.unnamedJetCalls(); err != nil {
+			return err
+		}
+		if len(t.matchExprs) > 1 {
+			return t.fallback(fmt.Sprintf("the %d matches of %s are taken to count valid signatures, and main asserts that at least 2 are", len(t.matchExprs), name), funcDecl.Pos())
+		}
+		return nil
+	case t.hasUnrolledLoop && len(t.unrolledLoops) > 0:
+		if err := t.unnamedJetCalls(); err != nil {
+			return err
+		}
+		for _, loop := range t.unrolledLoops {
+			if err := t.fallback(fmt.Sprintf("the iterations of the loop over %s are taken to count valid signatures, and main asserts that at least 2 are", loop.IndexVar), loop.Pos); err != nil {
+				return err
+			}
+		}
+		return nil
+	case len(t.jetCalls) > 0:
+		if result := t.resultBinding(); result != "" {
+			return t.fallback(fmt.Sprintf("%s checks nothing, so main asserts %s, whose name contains result", name, result), funcDecl.Pos())
+		}
+		return nil
+	}
+	for _, witness := range t.witnessValues {
+		if strings.Contains(strings.ToLower(witness.Name), "result") {
+			return t.fallback(fmt.Sprintf("%s checks nothing, so main asserts witness::%s, whose name contains result", name, strings.ToUpper(witness.Name)), funcDecl.Pos())
+		}
+	}
+	if len(t.functions) > 0 {
+		return t.fallback(fmt.Sprintf("%s checks nothing, so main calls %s, the last function declared, with the bool witnesses", name, t.functions[len(t.functions)-1].Name), funcDecl.Pos())
+	}
+	return t.fallback(name+" checks nothing, so main asserts true", funcDecl.Pos())
+}
+
+// unnamedJetCalls records the jet calls of main whose result is not bound,
+// such as a jet.Verify, which a main of matches or loops leaves out.
+func (t *Transpiler) unnamedJetCalls() error {
+	for _, jc := range t.jetCalls {
+		if jc.VarName == "" {
+			if err := t.fallback(fmt.Sprintf("the call of jet::%s is left out of a main of matches or loops", jc.JetName), jc.Pos); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// guessedTypes records the witnesses declared with the type "auto", whose
+// type DeclaredType infers from the value.
+func (t *Transpiler) guessedTypes() error {
+	for _, witness := range t.witnessValues {
+		if witness.Type != "auto" {
+			continue
+		}
+		if err := t.fallback(fmt.Sprintf("the type of witness::%s is guessed as %s from its value %s", strings.ToUpper(witness.Name), witness.DeclaredType(), witness.Value), witness.Pos); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statementKind describes stmt in a fallback.
+func statementKind(stmt ast.Stmt) string {
+	switch s := stmt.(type) {
+	case *ast.IfStmt:
+		return "the if statement"
+	case *ast.ForStmt, *ast.RangeStmt:
+		return "the loop"
+	case *ast.SwitchStmt, *ast.TypeSwitchStmt:
+		return "the switch"
+	case *ast.IncDecStmt:
+		return "the " + s.Tok.String() + " statement"
+	case *ast.BlockStmt:
+		return "the block"
+	case *ast.ExprStmt:
+		return "the expression statement"
+	case *ast.AssignStmt:
+		return "the assignment"
+	case *ast.BranchStmt:
+		return "the " + s.Tok.String()
+	}
+	return "the statement"
+}
+
+// returnsOnly reports whether each branch of s, an if chain without an
+// init, does nothing but return, so that as the last statement of main it
+// has no effect.
+func returnsOnly(s *ast.IfStmt) bool {
+	bare := func(body []ast.Stmt) bool {
+		for _, stmt := range body {
+			if ret, ok := stmt.(*ast.ReturnStmt); !ok || len(ret.Results) > 0 {
+				return false
+			}
+		}
+		return true
+	}
+	for {
+		if s.Init != nil || !bare(s.Body.List) {
+			return false
+		}
+		switch e := s.Else.(type) {
+		case nil:
+			return true
+		case *ast.BlockStmt:
+			return bare(e.List)
+		case *ast.IfStmt:
+			s = e
+		}
+	}
+}
@@ -0,0 +1,581 @@
+package transpiler
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+	"math/big"
+	"strings"
+
+	simtypes "github.com/0ceanslim/go-simplicity/pkg/types"
+)
+
+// Value is a value known at compile time: an integer of a SimplicityHL
+// width, an untyped integer literal, or a bool.
+type Value struct {
+	Type string   // "bool", "u8" … "u256", or "" for an untyped integer
+	Int  *big.Int // Integer value; nil for bools
+	Bool bool
+}
+
+// String returns the SimplicityHL literal for v.
+func (v Value) String() string {
+	if v.Int == nil {
+		if v.Bool {
+			return "true"
+		}
+		return "false"
+	}
+	if v.Type != "" {
+		// Integers wider than 64 bits are only written in hex.
+		if s, err := simtypes.EncodeInt(v.Type, v.Int); err == nil {
+			return s
+		}
+	}
+	return v.Int.String()
+}
+
+// binding is a local whose value is known. Derived bindings depend on a
+// witness: their value is what the witness placeholders produce, which is
+// fine for filling in derived witness values but must not replace the
+// witness reference in generated code, since the spender supplies the real
+// value.
+type binding struct {
+	value   Value
+	derived bool
+	gen     uint64 // Distinguishes this binding from every other, for the memo
+}
+
+// foldScope is the constant environment of one function body.
+type foldScope struct {
+	vars   map[string]binding
+	arrays map[string]knownArray // Arrays whose every element is known
+	parent *foldScope
+}
+
+// Folder evaluates Go expressions whose value is known at compile time. It
+// keeps an environment of locals with known values, and interprets calls of
+// the file's helpers when their arguments are known.
+type Folder struct {
+	types  *simtypes.TypeMapper
+	funcs  map[string]*ast.FuncDecl
+	scope  *foldScope
+	active map[*ast.FuncDecl]bool // Helpers whose calls are being folded
+	// noCalls leaves the calls of funcs unevaluated, as -O0 translates
+	// them, except inside withCalls.
+	noCalls bool
+	ctx     context.Context
+
+	// builtins folds calls of compiler-provided functions, which may
+	// depend on more than the folder sees, such as the file's constants.
+	builtins func(*ast.CallExpr) (Value, bool)
+
+	gen  uint64              // Last generation given to a binding
+	memo map[string]memoized // Results of Fold and FoldDerived, by memoKey
+	key  []byte              // Buffer for memoKey
+}
+
+// NewFolder returns a folder that maps conversion types with types and can
+// evaluate calls of funcs, keyed by Go name. Either may be nil.
+func NewFolder(types *simtypes.TypeMapper, funcs map[string]*ast.FuncDecl) *Folder {
+	if types == nil {
+		types = simtypes.NewTypeMapper()
+	}
+	return &Folder{types: types, funcs: funcs, ctx: context.Background()}
+}
+
+// Fold returns the value of expr if it is a compile-time constant that does
+// not depend on any witness.
+func (f *Folder) Fold(expr ast.Expr) (Value, bool) {
+	return f.foldMemoized(expr, false)
+}
+
+// FoldDerived is Fold with locals computed from witness placeholders
+// counting as known.
+func (f *Folder) FoldDerived(expr ast.Expr) (Value, bool) {
+	return f.foldMemoized(expr, true)
+}
+
+// Define binds the Go local name to v in the current scope.
+func (f *Folder) Define(name string, v Value) {
+	if f.scope == nil {
+		f.push()
+	}
+	f.bind(name, v, false)
+}
+
+// Local returns the value of a local that is a compile-time constant
+// independent of any witness.
+func (f *Folder) Local(name string) (Value, bool) {
+	b, ok := f.lookup(name)
+	if !ok || b.derived {
+		return Value{}, false
+	}
+	return b.value, true
+}
+
+func (f *Folder) push() {
+	f.scope = &foldScope{vars: make(map[string]binding), arrays: make(map[string]knownArray), parent: f.scope}
+}
+
+func (f *Folder) pop() {
+	f.scope = f.scope.parent
+}
+
+func (f *Folder) bind(name string, v Value, derived bool) {
+	if f.scope != nil {
+		f.scope.vars[name] = f.newBinding(v, derived)
+	}
+}
+
+// newBinding returns a binding of v in a generation of its own.
+func (f *Folder) newBinding(v Value, derived bool) binding {
+	f.gen++
+	return binding{value: v, derived: derived, gen: f.gen}
+}
+
+func (f *Folder) lookup(name string) (binding, bool) {
+	for s := f.scope; s != nil; s = s.parent {
+		if b, ok := s.vars[name]; ok {
+			return b, true
+		}
+	}
+	return binding{}, false
+}
+
+// forget drops name from every enclosing scope; an assignment the folder
+// cannot follow may have changed it.
+func (f *Folder) forget(name string) {
+	for s := f.scope; s != nil; s = s.parent {
+		delete(s.vars, name)
+		delete(s.arrays, name)
+	}
+}
+
+// set assigns to name in the innermost scope that declares it.
+func (f *Folder) set(name string, v Value) {
+	for s := f.scope; s != nil; s = s.parent {
+		if b, ok := s.vars[name]; ok {
+			s.vars[name] = f.newBinding(v, b.derived)
+			return
+		}
+	}
+	f.bind(name, v, false)
+}
+
+// fold evaluates expr if its value is known at compile time. With derived
+// set, locals computed from witness placeholders count as known.
+// Expressions whose typed result would overflow, or that divide by zero,
+// are left for the jets to evaluate.
+func (f *Folder) fold(expr ast.Expr, derived bool) (Value, bool) {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return f.fold(e.X, derived)
+	case *ast.BasicLit:
+		if e.Kind != token.INT {
+			return Value{}, false
+		}
+		n, ok := new(big.Int).SetString(e.Value, 0)
+		if !ok {
+			return Value{}, false
+		}
+		return Value{Int: n}, true
+	case *ast.Ident:
+		if b, ok := f.lookup(e.Name); ok && (derived || !b.derived) {
+			return b.value, true
+		}
+		if e.Name == "true" || e.Name == "false" {
+			return Value{Type: "bool", Bool: e.Name == "true"}, true
+		}
+	case *ast.SelectorExpr:
+		if name, ok := bitcoinMember(f.types, e); ok {
+			v, ok := bitcoinConstants[name]
+			return v, ok
+		}
+	case *ast.CallExpr:
+		if f.builtins != nil {
+			if v, ok := f.builtins(e); ok {
+				return v, true
+			}
+		}
+		if typ, ok := bitcoinConversion(f.types, e); ok {
+			v, ok := f.fold(e.Args[0], derived)
+			if !ok || v.Int == nil || !isUIntType(typ) {
+				return Value{}, false
+			}
+			return checkWidth(Value{Type: typ, Int: v.Int})
+		}
+		fun, ok := e.Fun.(*ast.Ident)
+		if !ok {
+			return Value{}, false
+		}
+		if decl, ok := f.funcs[fun.Name]; ok {
+			if f.noCalls {
+				return Value{}, false
+			}
+			return f.foldCall(decl, e.Args, derived)
+		}
+		// Conversions such as uint64(100)
+		if len(e.Args) != 1 {
+			return Value{}, false
+		}
+		typ, err := f.types.MapGoType(fun)
+		if err != nil || !isUIntType(typ) {
+			return Value{}, false
+		}
+		v, ok := f.fold(e.Args[0], derived)
+		if !ok || v.Int == nil {
+			return Value{}, false
+		}
+		return checkWidth(Value{Type: typ, Int: v.Int})
+	case *ast.UnaryExpr:
+		v, ok := f.fold(e.X, derived)
+		if ok && e.Op == token.NOT && v.Int == nil {
+			return Value{Type: "bool", Bool: !v.Bool}, true
+		}
+	case *ast.BinaryExpr:
+		l, okL := f.fold(e.X, derived)
+		if okL && l.Int == nil && (e.Op == token.LAND && !l.Bool || e.Op == token.LOR && l.Bool) {
+			return l, true // Go does not evaluate the right operand
+		}
+		r, okR := f.fold(e.Y, derived)
+		if okL && okR {
+			return foldBinary(e.Op, l, r)
+		}
+	}
+	return Value{}, false
+}
+
+// withCalls evaluates the calls of funcs until the returned function is
+// called, whatever noCalls says, for a value that must be a literal.
+func (f *Folder) withCalls() (restore func()) {
+	saved := f.noCalls
+	f.noCalls = false
+	return func() { f.noCalls = saved }
+}
+
+// foldCall evaluates a call of a helper whose arguments are known by
+// interpreting its body: assignments, constant declarations, if statements
+// and returns. Anything else, such as a jet call, leaves the call unfolded,
+// as does a call of a helper whose call is being folded: SimplicityHL has
+// no recursion, so neither does the folder.
+func (f *Folder) foldCall(decl *ast.FuncDecl, args []ast.Expr, derived bool) (Value, bool) {
+	if f.active[decl] || decl.Body == nil || decl.Type.Params.NumFields() != len(args) {
+		return Value{}, false
+	}
+	callee := &foldScope{vars: make(map[string]binding), arrays: make(map[string]knownArray)}
+	i := 0
+	for _, field := range decl.Type.Params.List {
+		for _, name := range field.Names {
+			v, ok := f.fold(args[i], derived)
+			if ok {
+				v, ok = f.convert(v, field.Type)
+			}
+			if !ok {
+				return Value{}, false
+			}
+			callee.vars[name.Name] = f.newBinding(v, false)
+			i++
+		}
+	}
+	results := decl.Type.Results
+	if results.NumFields() != 1 {
+		return Value{}, false
+	}
+
+	saved := f.scope
+	if f.active == nil {
+		f.active = make(map[*ast.FuncDecl]bool)
+	}
+	f.scope = callee
+	f.active[decl] = true
+	defer func() {
+		f.scope = saved
+		delete(f.active, decl)
+	}()
+	v, returned, ok := f.foldBlock(decl.Body.List, derived)
+	if !ok || !returned {
+		return Value{}, false
+	}
+	return f.convert(v, results.List[0].Type)
+}
+
+// foldBlock interprets stmts for foldCall in a new scope. returned reports
+// whether a return statement was reached.
+func (f *Folder) foldBlock(stmts []ast.Stmt, derived bool) (v Value, returned, ok bool) {
+	f.push()
+	defer f.pop()
+	for _, stmt := range stmts {
+		if f.ctx.Err() != nil {
+			return Value{}, false, false
+		}
+		switch s := stmt.(type) {
+		case *ast.ReturnStmt:
+			if len(s.Results) != 1 {
+				return Value{}, false, false
+			}
+			v, ok := f.fold(s.Results[0], derived)
+			return v, true, ok
+		case *ast.AssignStmt:
+			if len(s.Lhs) != 1 || len(s.Rhs) != 1 {
+				return Value{}, false, false
+			}
+			ident, isIdent := s.Lhs[0].(*ast.Ident)
+			if !isIdent {
+				return Value{}, false, false
+			}
+			rhs := s.Rhs[0]
+			if op, isOp := opAssignTokens[s.Tok]; isOp {
+				rhs = &ast.BinaryExpr{X: ident, Op: op, Y: rhs}
+			}
+			v, ok := f.fold(rhs, derived)
+			if !ok {
+				return Value{}, false, false
+			}
+			if s.Tok == token.DEFINE {
+				f.bind(ident.Name, v, false)
+			} else {
+				f.set(ident.Name, v)
+			}
+		case *ast.IncDecStmt:
+			ident, isIdent := s.X.(*ast.Ident)
+			if !isIdent {
+				return Value{}, false, false
+			}
+			v, ok := f.fold(incDecExpr(ident, s.Tok), derived)
+			if !ok {
+				return Value{}, false, false
+			}
+			f.set(ident.Name, v)
+		case *ast.DeclStmt:
+			if !f.foldDecl(s, derived) {
+				return Value{}, false, false
+			}
+		case *ast.IfStmt:
+			if s.Init != nil {
+				return Value{}, false, false
+			}
+			cond, ok := f.fold(s.Cond, derived)
+			if !ok || cond.Type != "bool" {
+				return Value{}, false, false
+			}
+			var branch []ast.Stmt
+			if cond.Bool {
+				branch = s.Body.List
+			} else if s.Else != nil {
+				branch = []ast.Stmt{s.Else}
+			}
+			v, returned, ok := f.foldBlock(branch, derived)
+			if !ok || returned {
+				return v, returned, ok
+			}
+		case *ast.BlockStmt:
+			v, returned, ok := f.foldBlock(s.List, derived)
+			if !ok || returned {
+				return v, returned, ok
+			}
+		case *ast.EmptyStmt:
+		default:
+			return Value{}, false, false
+		}
+	}
+	return Value{}, false, true
+}
+
+// foldDecl binds the constants and variables of a declaration inside a
+// helper being folded.
+func (f *Folder) foldDecl(s *ast.DeclStmt, derived bool) bool {
+	genDecl, ok := s.Decl.(*ast.GenDecl)
+	if !ok || (genDecl.Tok != token.CONST && genDecl.Tok != token.VAR) {
+		return false
+	}
+	for _, spec := range genDecl.Specs {
+		valueSpec, ok := spec.(*ast.ValueSpec)
+		if ok && len(valueSpec.Values) == 0 && valueSpec.Type != nil && genDecl.Tok == token.VAR {
+			// var n uint32 starts at zero
+			mapped, err := f.types.MapGoType(valueSpec.Type)
+			zero, isZero := zeroFolded(mapped)
+			if err != nil || !isZero {
+				return false
+			}
+			for _, name := range valueSpec.Names {
+				f.bind(name.Name, zero, false)
+			}
+			continue
+		}
+		if !ok || len(valueSpec.Values) != len(valueSpec.Names) {
+			return false
+		}
+		for i, name := range valueSpec.Names {
+			v, ok := f.fold(valueSpec.Values[i], derived)
+			if !ok {
+				return false
+			}
+			if valueSpec.Type != nil {
+				if v, ok = f.convert(v, valueSpec.Type); !ok {
+					return false
+				}
+			}
+			f.bind(name.Name, v, false)
+		}
+	}
+	return true
+}
+
+// convert gives an integer the type typ when it is assigned to a variable,
+// parameter or result of that type. Typed integers and bools must already
+// have it.
+func (f *Folder) convert(v Value, typ ast.Expr) (Value, bool) {
+	mapped, err := f.types.MapGoType(typ)
+	if err != nil {
+		return Value{}, false
+	}
+	if v.Int == nil || v.Type != "" {
+		return v, v.Type == mapped
+	}
+	if !isUIntType(mapped) {
+		return Value{}, false
+	}
+	return checkWidth(Value{Type: mapped, Int: v.Int})
+}
+
+// incDecExpr rewrites x++ and x-- as the binary expression they assign.
+func incDecExpr(x *ast.Ident, tok token.Token) *ast.BinaryExpr {
+	op := token.ADD
+	if tok == token.DEC {
+		op = token.SUB
+	}
+	return &ast.BinaryExpr{X: x, Op: op, Y: &ast.BasicLit{Kind: token.INT, Value: "1"}}
+}
+
+// foldBinary applies op to two known operands with Go's typing rules for
+// typed and untyped constants.
+func foldBinary(op token.Token, l, r Value) (Value, bool) {
+	if l.Int == nil || r.Int == nil {
+		if l.Int != nil || r.Int != nil {
+			return Value{}, false
+		}
+		switch op {
+		case token.LAND:
+			return Value{Type: "bool", Bool: l.Bool && r.Bool}, true
+		case token.LOR:
+			return Value{Type: "bool", Bool: l.Bool || r.Bool}, true
+		case token.EQL:
+			return Value{Type: "bool", Bool: l.Bool == r.Bool}, true
+		case token.NEQ:
+			return Value{Type: "bool", Bool: l.Bool != r.Bool}, true
+		}
+		return Value{}, false
+	}
+
+	typ := l.Type
+	if op != token.SHL && op != token.SHR {
+		switch {
+		case l.Type == "":
+			typ = r.Type
+		case r.Type != "" && r.Type != l.Type:
+			return Value{}, false
+		}
+	}
+
+	cmp := l.Int.Cmp(r.Int)
+	switch op {
+	case token.EQL:
+		return Value{Type: "bool", Bool: cmp == 0}, true
+	case token.NEQ:
+		return Value{Type: "bool", Bool: cmp != 0}, true
+	case token.LSS:
+		return Value{Type: "bool", Bool: cmp < 0}, true
+	case token.LEQ:
+		return Value{Type: "bool", Bool: cmp <= 0}, true
+	case token.GTR:
+		return Value{Type: "bool", Bool: cmp > 0}, true
+	case token.GEQ:
+		return Value{Type: "bool", Bool: cmp >= 0}, true
+	}
+
+	n := new(big.Int)
+	switch op {
+	case token.ADD:
+		n.Add(l.Int, r.Int)
+	case token.SUB:
+		n.Sub(l.Int, r.Int)
+	case token.MUL:
+		n.Mul(l.Int, r.Int)
+	case token.QUO, token.REM:
+		if r.Int.Sign() == 0 {
+			return Value{}, false
+		}
+		if op == token.QUO {
+			n.Quo(l.Int, r.Int)
+		} else {
+			n.Rem(l.Int, r.Int)
+		}
+	case token.AND:
+		n.And(l.Int, r.Int)
+	case token.OR:
+		n.Or(l.Int, r.Int)
+	case token.XOR:
+		n.Xor(l.Int, r.Int)
+	case token.AND_NOT:
+		n.AndNot(l.Int, r.Int)
+	case token.SHL, token.SHR:
+		if !r.Int.IsUint64() || r.Int.Uint64() > 256 {
+			return Value{}, false
+		}
+		if op == token.SHL {
+			n.Lsh(l.Int, uint(r.Int.Uint64()))
+		} else {
+			n.Rsh(l.Int, uint(r.Int.Uint64()))
+		}
+	default:
+		return Value{}, false
+	}
+	return checkWidth(Value{Type: typ, Int: n})
+}
+
+// checkWidth rejects integers that do not fit their type. Untyped values
+// only need to be non-negative, since every SimplicityHL integer is
+// unsigned.
+func checkWidth(v Value) (Value, bool) {
+	if v.Int.Sign() < 0 {
+		return Value{}, false
+	}
+	if v.Type != "" && v.Int.BitLen() > uintBits(v.Type) {
+		return Value{}, false
+	}
+	return v, true
+}
+
+// isUIntType reports whether typ is a SimplicityHL unsigned integer type.
+func isUIntType(typ string) bool {
+	return uintBits(typ) > 0
+}
+
+// uintBits returns the width of an unsigned integer type, or 0.
+func uintBits(typ string) int {
+	if !strings.HasPrefix(typ, "u") {
+		return 0
+	}
+	switch typ[1:] {
+	case "1":
+		return 1
+	case "2":
+		return 2
+	case "4":
+		return 4
+	case "8":
+		return 8
+	case "16":
+		return 16
+	case "32":
+		return 32
+	case "64":
+		return 64
+	case "128":
+		return 128
+	case "256":
+		return 256
+	}
+	return 0
+}
@@ -0,0 +1,128 @@
+package transpiler
+
+import (
+	"go/ast"
+	"strconv"
+)
+
+// Generated contracts repeat the same constant subexpressions, such as a
+// fee formula or a helper called with the same constants, once per input
+// or output. Fold and FoldDerived remember what each expression folded to
+// for the rest of the compile, keyed by its structure and by the bindings
+// its identifiers resolve to. Every binding the folder makes, whether a
+// declaration, a shadowing one or an assignment, is a generation of its
+// own, so a key only matches while each identifier still refers to the
+// binding it did when the result was stored.
+
+// memoized is a result of Fold or FoldDerived.
+type memoized struct {
+	value Value
+	ok    bool
+}
+
+// foldMemoized is fold for the entry points, through the memo. Only the
+// outermost call is memoized: inside a helper being folded a call of a
+// helper already being folded fails, where at the top it may fold.
+func (f *Folder) foldMemoized(expr ast.Expr, derived bool) (Value, bool) {
+	if len(f.active) > 0 || !compound(expr) {
+		return f.fold(expr, derived)
+	}
+	f.key = f.key[:0]
+	if derived {
+		f.key = append(f.key, 'D')
+	}
+	if f.noCalls {
+		f.key = append(f.key, 'N')
+	}
+	key, ok := f.memoKey(f.key, expr)
+	f.key = key
+	if !ok {
+		return f.fold(expr, derived)
+	}
+	if m, ok := f.memo[string(key)]; ok {
+		return m.value, m.ok
+	}
+	v, ok := f.fold(expr, derived)
+	if f.ctx.Err() != nil {
+		// Cancelled folding fails whatever the expression is.
+		return v, ok
+	}
+	if f.memo == nil {
+		f.memo = make(map[string]memoized)
+	}
+	f.memo[string(key)] = memoized{v, ok}
+	return v, ok
+}
+
+// compound reports whether expr is worth memoizing: a literal or an
+// identifier folds faster than its key is built.
+func compound(expr ast.Expr) bool {
+	switch expr.(type) {
+	case *ast.BinaryExpr, *ast.CallExpr, *ast.UnaryExpr, *ast.ParenExpr:
+		return true
+	}
+	return false
+}
+
+// memoKey appends the key of expr to key. Two expressions have the same
+// key when they have the same structure and their identifiers resolve to
+// the same bindings, or to none. Expressions the folder cannot evaluate,
+// or whose value may depend on more than that, such as composite literals
+// passed to a builtin, have no key.
+func (f *Folder) memoKey(key []byte, expr ast.Expr) ([]byte, bool) {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return f.memoKey(key, e.X)
+	case *ast.BasicLit:
+		key = append(key, 'L')
+		key = strconv.AppendInt(key, int64(e.Kind), 10)
+		key = append(key, ':')
+		key = strconv.AppendInt(key, int64(len(e.Value)), 10)
+		key = append(key, ':')
+		return append(key, e.Value...), true
+	case *ast.Ident:
+		key = append(key, 'I')
+		key = append(key, e.Name...)
+		if b, ok := f.lookup(e.Name); ok {
+			key = append(key, '#')
+			key = strconv.AppendUint(key, b.gen, 10)
+		}
+		return append(key, ';'), true
+	case *ast.SelectorExpr:
+		key = append(key, 'S')
+		key, ok := f.memoKey(key, e.X)
+		if !ok {
+			return key, false
+		}
+		key = append(key, e.Sel.Name...)
+		return append(key, ';'), true
+	case *ast.UnaryExpr:
+		key = append(key, 'U')
+		key = append(key, e.Op.String()...)
+		return f.memoKey(key, e.X)
+	case *ast.BinaryExpr:
+		key = append(key, 'B')
+		key = append(key, e.Op.String()...)
+		key, ok := f.memoKey(key, e.X)
+		if !ok {
+			return key, false
+		}
+		return f.memoKey(key, e.Y)
+	case *ast.CallExpr:
+		if e.Ellipsis.IsValid() {
+			return key, false
+		}
+		key = append(key, 'C')
+		key, ok := f.memoKey(key, e.Fun)
+		if !ok {
+			return key, false
+		}
+		for _, arg := range e.Args {
+			if key, ok = f.memoKey(key, arg); !ok {
+				return key, false
+			}
+		}
+		return append(key, ')'), true
+	}
+	return key, false
+}
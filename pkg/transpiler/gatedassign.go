@@ -0,0 +1,238 @@
+package transpiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	gotypes "go/types"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+)
+
+// An if or a switch whose branches reassign variables declared before it
+// gives each the value of the branch taken, chosen by the conditions the
+// program computes:
+//
+//	ok := true
+//	if a == 0 {
+//		ok = false
+//	}
+//
+// binds the condition, and shadows ok with its value after the if:
+//
+//	let t_8_5: bool = jet::eq_8(a, 0);
+//	let ok: bool = match t_8_5 { true => false, false => true, };
+//
+// An else if or a later case is taken when its condition holds and no
+// clause before it was, and an else or a default when none was. Only
+// branches that do nothing but reassign variables of known types are
+// lowered this way. The value of every branch is computed whether or not
+// it is taken, so a condition or a value that divides, which may fail, is
+// an error, as is one with no lowering: leaving the statement out would
+// keep the values from before it.
+
+// branchClause is a branch of an if or a switch: the condition that takes
+// it, nil for an else or a default, and its statements.
+type branchClause struct {
+	pos  token.Pos
+	cond ast.Expr
+	body []ast.Stmt
+}
+
+// branchClauses returns the clauses of stmt, an if chain or an expression
+// switch without an init, with the default last. It reports false for any
+// other statement.
+func branchClauses(stmt ast.Stmt) ([]branchClause, bool) {
+	switch s := stmt.(type) {
+	case *ast.IfStmt:
+		var clauses []branchClause
+		for {
+			if s.Init != nil {
+				return nil, false
+			}
+			clauses = append(clauses, branchClause{pos: s.Cond.Pos(), cond: s.Cond, body: s.Body.List})
+			switch e := s.Else.(type) {
+			case nil:
+				return clauses, true
+			case *ast.BlockStmt:
+				return append(clauses, branchClause{pos: e.Pos(), body: e.List}), true
+			case *ast.IfStmt:
+				s = e
+			}
+		}
+	case *ast.SwitchStmt:
+		if s.Init != nil {
+			return nil, false
+		}
+		var clauses []branchClause
+		var otherwise *branchClause
+		for _, stmt := range s.Body.List {
+			cc := stmt.(*ast.CaseClause)
+			if cc.List == nil {
+				otherwise = &branchClause{pos: cc.Pos(), body: cc.Body}
+				continue
+			}
+			var cond ast.Expr
+			for _, e := range cc.List {
+				if s.Tag != nil {
+					e = &ast.BinaryExpr{X: s.Tag, OpPos: e.Pos(), Op: token.EQL, Y: e}
+				}
+				if cond != nil {
+					e = &ast.BinaryExpr{X: cond, OpPos: e.Pos(), Op: token.LOR, Y: e}
+				}
+				cond = e
+			}
+			clauses = append(clauses, branchClause{pos: cc.Pos(), cond: cond, body: cc.Body})
+		}
+		if otherwise != nil {
+			clauses = append(clauses, *otherwise)
+		}
+		return clauses, true
+	}
+	return nil, false
+}
+
+// lowerBranchAssign lowers stmt, an if or a switch whose branches do
+// nothing but reassign variables of known types, to the lets of its
+// conditions and of the value of each variable after it, recording the
+// variables as locals of their types. It reports false for any other
+// statement, and an error for one whose conditions or values cannot be
+// lowered.
+func (t *Transpiler) lowerBranchAssign(stmt ast.Stmt) ([]JetCall, bool, error) {
+	switch stmt.(type) {
+	case *ast.IfStmt, *ast.SwitchStmt:
+	default:
+		return nil, false, nil
+	}
+	fail := func(n ast.Node, format string, args ...any) ([]JetCall, bool, error) {
+		return nil, false, diag.UnsupportedSyntax.Wrap(t.errorAt(n.Pos(), "%s, whose branches reassign variables, is not lowered: %s", statementKind(stmt), fmt.Sprintf(format, args...)))
+	}
+	clauses, ok := branchClauses(stmt)
+	if !ok {
+		return nil, false, nil
+	}
+	assigns := make([][]*ast.AssignStmt, len(clauses))
+	reassigns := false
+	for i, clause := range clauses {
+		for _, s := range clause.body {
+			if _, empty := s.(*ast.EmptyStmt); empty {
+				continue
+			}
+			assign, ok, err := t.reassignment(s)
+			if err != nil {
+				return nil, false, err
+			}
+			if !ok {
+				assign, ok = s.(*ast.AssignStmt)
+				ok = ok && assign.Tok == token.ASSIGN && len(assign.Lhs) == 1 && len(assign.Rhs) == 1
+			}
+			if !ok {
+				return nil, false, nil
+			}
+			ident, isIdent := assign.Lhs[0].(*ast.Ident)
+			if !isIdent || ident.Name == "_" || t.variableType(ident) == "" {
+				return nil, false, nil
+			}
+			assigns[i] = append(assigns[i], assign)
+			reassigns = true
+		}
+	}
+	if !reassigns {
+		return nil, false, nil
+	}
+
+	var calls []JetCall
+	bind := func(name, typ, value string, pos token.Pos) string {
+		calls = append(calls, JetCall{VarName: name, Args: value, ReturnType: typ, Pos: pos})
+		return name
+	}
+	taken := "" // Whether a clause before the current one was taken, "" before the first
+	for i, clause := range clauses {
+		gate := "true"
+		if clause.cond != nil {
+			if i > 0 && divides(clause.cond) {
+				return fail(clause.cond, "the condition %s divides, which may fail, and it is computed whether or not a clause before it is taken", gotypes.ExprString(clause.cond))
+			}
+			cond, ok := t.operand(clause.cond)
+			if !ok {
+				return fail(clause.cond, "its condition %s is not lowered", gotypes.ExprString(clause.cond))
+			}
+			gate = cond
+			if taken != "" {
+				cond = fmt.Sprintf("match %s { true => false, false => %s, }", taken, cond)
+			}
+			// A variable the branches reassign is read through a let
+			// of its value before them, as is any expression.
+			fixed := cond == "true" || cond == "false" || simpleRef.MatchString(cond) && strings.Contains(cond, "::")
+			if taken != "" || !fixed {
+				gate = bind(t.temp(clause.pos), "bool", cond, clause.pos)
+			}
+		} else if taken != "" {
+			gate = bind(t.temp(clause.pos), "bool", fmt.Sprintf("match %s { true => false, false => true, }", taken), clause.pos)
+		}
+		for _, assign := range assigns[i] {
+			ident := assign.Lhs[0].(*ast.Ident)
+			value := assign.Rhs[0]
+			if divides(value) {
+				return fail(assign, "the value %s divides, which may fail, and it is computed whether or not the branch is taken", gotypes.ExprString(value))
+			}
+			typ := t.variableType(ident)
+			old, ok := t.operand(ident)
+			if !ok {
+				return fail(assign, "the value of %s before it is not lowered", ident.Name)
+			}
+			next, ok := t.operand(value)
+			if !ok {
+				return fail(assign, "the value %s is not lowered", gotypes.ExprString(value))
+			}
+			t.folder.forget(ident.Name)
+			t.params[ident.Name] = typ
+			bind(t.toSnakeCase(ident.Name), typ, fmt.Sprintf("match %s { true => %s, false => %s, }", gate, next, old), assign.Pos())
+		}
+		if i+1 < len(clauses) {
+			switch {
+			case taken == "":
+				taken = gate
+			case clause.cond != nil:
+				taken = bind(t.temp(clause.pos), "bool", fmt.Sprintf("match %s { true => true, false => %s, }", taken, gate), clause.pos)
+			}
+		}
+	}
+	return calls, true, nil
+}
+
+// letLines renders calls, the lets of lowerBranchAssign, as lines of a
+// function body.
+func letLines(calls []JetCall) []string {
+	lines := make([]string, len(calls))
+	for i, jc := range calls {
+		lines[i] = letStatement(jc, jc.Args)
+	}
+	return lines
+}
+
+// operand returns the SimplicityHL operand for expr: its value when it
+// folds, else its translation.
+func (t *Transpiler) operand(expr ast.Expr) (string, bool) {
+	if v, ok := t.folder.Fold(expr); ok {
+		return v.String(), true
+	}
+	ref, err := t.expr.TranslateArg(expr)
+	return ref, err == nil && ref != placeholder
+}
+
+// variableType returns the type of the variable ident names, or "" when
+// it is not known.
+func (t *Transpiler) variableType(ident *ast.Ident) string {
+	if b, ok := t.folder.lookup(ident.Name); ok {
+		if b.value.Int == nil {
+			return "bool"
+		}
+		return b.value.Type
+	}
+	if sym, ok := t.expr.lookupPath(ident); ok {
+		return carryFree(sym.Type)
+	}
+	return ""
+}
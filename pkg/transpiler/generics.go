@@ -0,0 +1,238 @@
+package transpiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	gotypes "go/types"
+	"slices"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+)
+
+// genericFunc is a generic function of the contract or of an imported
+// package. It is monomorphized: each type argument it is called with gets
+// its own specialized SimplicityHL function, emitted on first use.
+type genericFunc struct {
+	decl      *ast.FuncDecl
+	typeParam string   // Name of the single type parameter
+	allowed   []string // Types admitted by a union constraint; nil for any or comparable
+	pkgPrefix string   // Package the function was declared in, as for Transpiler.pkgPrefix
+	pkgName   string
+	jetNames  map[string]bool // Names of the jet package in the declaring file
+}
+
+// errorAt formats an error positioned at pos, when positions are known.
+func (t *Transpiler) errorAt(pos token.Pos, format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	if t.fset == nil || !pos.IsValid() {
+		return fmt.Errorf("%s", msg)
+	}
+	return fmt.Errorf("%s: %s", t.fset.Position(pos), msg)
+}
+
+// declareGenerics records the generic functions of file. Only one type
+// parameter is supported, constrained by any, comparable or a union of
+// unsigned integer and byte array types.
+func (t *Transpiler) declareGenerics(file *ast.File) error {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || fn.Type.TypeParams == nil {
+			continue
+		}
+		params := fn.Type.TypeParams.List
+		if n := fn.Type.TypeParams.NumFields(); n != 1 {
+			return t.errorAt(fn.Pos(), "generic function %s has %d type parameters; only one is supported", fn.Name.Name, n)
+		}
+		allowed, err := t.constraintTypes(params[0].Type)
+		if err != nil {
+			return t.errorAt(params[0].Type.Pos(), "generic function %s: %v", fn.Name.Name, err)
+		}
+		t.generics[t.pkgPrefix+t.toSnakeCase(fn.Name.Name)] = &genericFunc{
+			decl:      fn,
+			typeParam: params[0].Names[0].Name,
+			allowed:   allowed,
+			pkgPrefix: t.pkgPrefix,
+			pkgName:   t.pkgName,
+			jetNames:  t.jetNames,
+		}
+	}
+	return nil
+}
+
+// constraintTypes lists the types a type parameter constraint admits, or
+// nil for any and comparable.
+func (t *Transpiler) constraintTypes(constraint ast.Expr) ([]string, error) {
+	if ident, ok := constraint.(*ast.Ident); ok && (ident.Name == "any" || ident.Name == "comparable") {
+		return nil, nil
+	}
+	var allowed []string
+	var terms func(ast.Expr) error
+	terms = func(e ast.Expr) error {
+		switch e := e.(type) {
+		case *ast.BinaryExpr:
+			if e.Op == token.OR {
+				if err := terms(e.X); err != nil {
+					return err
+				}
+				return terms(e.Y)
+			}
+		case *ast.UnaryExpr:
+			if e.Op == token.TILDE {
+				return terms(e.X)
+			}
+		case *ast.Ident, *ast.ArrayType:
+			typ, err := t.typeMapper.MapGoType(e)
+			if err == nil && instantiable(typ) {
+				allowed = append(allowed, typ)
+				return nil
+			}
+		}
+		return fmt.Errorf("constraint %s is not supported; use any, comparable or a union of unsigned integer and byte array types",
+			gotypes.ExprString(constraint))
+	}
+	if err := terms(constraint); err != nil {
+		return nil, err
+	}
+	return allowed, nil
+}
+
+// instantiable reports whether a generic function can be specialized at
+// typ: an unsigned integer or byte array type.
+func instantiable(typ string) bool {
+	parsed, err := shlparse.ParseType(typ)
+	if err != nil {
+		return false
+	}
+	switch parsed := parsed.(type) {
+	case *shlparse.UInt:
+		return true
+	case *shlparse.ArrayType:
+		u, ok := parsed.Elem.(*shlparse.UInt)
+		return ok && u.Bits == 8
+	}
+	return false
+}
+
+// instanceSuffix names the specialization of a generic function at typ:
+// eq_u64, or eq_bytes32 for [u8; 32].
+func instanceSuffix(typ string) string {
+	if parsed, err := shlparse.ParseType(typ); err == nil {
+		if arr, ok := parsed.(*shlparse.ArrayType); ok {
+			return fmt.Sprintf("bytes%d", arr.Len)
+		}
+	}
+	return typ
+}
+
+// callGeneric lowers a call of the generic function funcName to a call of
+// its specialization at the type argument, which is given explicitly as
+// typeArg or inferred from the arguments passed for parameters of the type
+// parameter's type.
+func (t *Transpiler) callGeneric(funcName string, g *genericFunc, typeArg ast.Expr, call *ast.CallExpr) (string, error) {
+	goName := g.decl.Name.Name
+	var typ string
+	if typeArg != nil {
+		mapped, err := t.typeMapper.MapGoType(typeArg)
+		if err != nil {
+			return "", t.errorAt(call.Pos(), "%s[%s]: %v", goName, gotypes.ExprString(typeArg), err)
+		}
+		typ = mapped
+	} else {
+		i := 0
+		for _, field := range g.decl.Type.Params.List {
+			ident, ofParam := field.Type.(*ast.Ident)
+			ofParam = ofParam && ident.Name == g.typeParam
+			for range max(len(field.Names), 1) {
+				if i < len(call.Args) && ofParam {
+					if argType, ok := t.operandType(call.Args[i]); ok {
+						if typ != "" && argType != typ {
+							return "", t.errorAt(call.Args[i].Pos(), "type %s of %s does not match inferred type %s for %s",
+								argType, gotypes.ExprString(call.Args[i]), typ, g.typeParam)
+						}
+						typ = argType
+					}
+				}
+				i++
+			}
+		}
+		if typ == "" {
+			return "", t.errorAt(call.Pos(), "cannot infer %s for %s; instantiate it explicitly, as in %s[uint64](...)", g.typeParam, goName, goName)
+		}
+	}
+	if !instantiable(typ) {
+		return "", t.errorAt(call.Pos(), "cannot instantiate %s with %s: generic functions take unsigned integer and byte array types only", goName, typ)
+	}
+	if g.allowed != nil && !slices.Contains(g.allowed, typ) {
+		return "", t.errorAt(call.Pos(), "%s does not satisfy the constraint of %s (%s)", typ, goName, strings.Join(g.allowed, " | "))
+	}
+
+	name := funcName + "_" + instanceSuffix(typ)
+	if !t.instances[name] {
+		t.instances[name] = true
+		if err := t.instantiate(g, typ, name); err != nil {
+			return "", t.errorAt(call.Pos(), "instantiating %s[%s]: %v", goName, typ, err)
+		}
+	}
+	args := make([]string, len(call.Args))
+	for i, arg := range call.Args {
+		v, err := t.expr.TranslateArg(arg)
+		if err != nil {
+			return "", err
+		}
+		args[i] = v
+	}
+	return fmt.Sprintf("%s(%s)", name, strings.Join(args, ", ")), nil
+}
+
+// instantiate emits the specialization name of g at typ. The function is
+// analyzed in the package it was declared in, with a folder of its own so
+// that none of the caller's locals leak into its body.
+func (t *Transpiler) instantiate(g *genericFunc, typ, name string) error {
+	mapper, folder, structParams, params := t.typeMapper, t.folder, t.structParams, t.params
+	pkgPrefix, pkgName, constants, library, jetNames := t.pkgPrefix, t.pkgName, t.constants, t.library, t.jetNames
+	defer func() {
+		t.typeMapper, t.folder, t.expr.folder, t.structParams, t.params = mapper, folder, folder, structParams, params
+		t.pkgPrefix, t.pkgName, t.constants, t.library, t.jetNames = pkgPrefix, pkgName, constants, library, jetNames
+	}()
+
+	t.typeMapper = t.typeMapper.WithTypeParams(map[string]string{g.typeParam: typ})
+	t.folder = NewFolder(t.typeMapper, t.funcDecls)
+	t.folder.ctx = t.ctx
+	t.folder.noCalls = t.noCallFolding
+	t.folder.builtins = t.foldBuiltinCall
+	t.expr.folder = t.folder
+	t.structParams, t.params = make(map[string]*structValue), make(map[string]string)
+	t.pkgPrefix, t.pkgName, t.jetNames = g.pkgPrefix, g.pkgName, g.jetNames
+	if g.pkgPrefix != "" {
+		t.constants, t.library = t.pkgConstants[strings.TrimSuffix(g.pkgPrefix, "_")], true
+	}
+
+	if err := t.analyzeFunction(g.decl); err != nil {
+		return err
+	}
+	fn := &t.functions[len(t.functions)-1]
+	fn.Name, fn.Called = name, true
+	return nil
+}
+
+// operandType returns the type of an argument from which a type argument
+// can be inferred: a typed name or field, a conversion, or a typed
+// constant. Untyped constants report false, as they would default to int.
+func (t *Transpiler) operandType(expr ast.Expr) (string, bool) {
+	if sym, ok := t.expr.lookupPath(expr); ok {
+		return carryFree(sym.Type), true
+	}
+	if call, ok := expr.(*ast.CallExpr); ok && len(call.Args) == 1 {
+		if ident, ok := call.Fun.(*ast.Ident); !ok || t.funcDecls[ident.Name] == nil {
+			if typ, err := t.typeMapper.MapGoType(call.Fun); err == nil && instantiable(typ) {
+				return typ, true
+			}
+		}
+	}
+	if v, ok := t.folder.Fold(expr); ok && v.Type != "" {
+		return v.Type, true
+	}
+	return "", false
+}
@@ -0,0 +1,284 @@
+package transpiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	gotypes "go/types"
+	"math/big"
+	"strings"
+)
+
+// A switch on an integer,
+//
+//	switch tier {
+//	case 0:
+//		jet.Verify(jet.Le64(1000, fee))
+//	case 1, 2:
+//		jet.Verify(jet.Le64(5000, fee))
+//	default:
+//		jet.Verify(jet.Le64(20000, fee))
+//	}
+//
+// is a match on its value where every dialect has integer patterns, each
+// value of a case an arm such as 1 =>, and the default _ =>. No version of
+// SimplicityHL has them yet, so the switch is lowered instead to the chain
+// of comparisons Go makes, one for each value in the order Go tries them,
+// each a match on whether the tag equals it with the next in its false arm:
+//
+//	match jet::eq_8(witness::TIER, 0) {
+//	    true => {
+//	        assert!(jet::le_64(1000, witness::FEE));
+//	    },
+//	    false => {
+//	        match jet::eq_8(witness::TIER, 1) {
+//	            ...
+//
+// A case listing several values repeats its body for each. The last false
+// arm is the default, or () for a switch without one, which does nothing
+// when no case matches. In a function the switch is the result when every
+// case returns; a switch without a default is then followed by the return
+// standing for it. A case that is not a constant of the tag's type, or
+// repeats one before it, is an error.
+
+// intCase is one value a case of an integer switch matches, or the
+// default, and the case's body.
+type intCase struct {
+	value string // Decimal value; "_" for the default
+	body  []ast.Stmt
+	pos   token.Pos
+}
+
+// intSwitchCases returns the tag of s translated, its type and the cases of
+// s, one for each value in the order Go tries them, the default last. It
+// reports false for a switch on anything but an integer of known type.
+func (t *Transpiler) intSwitchCases(s *ast.SwitchStmt) (string, string, []intCase, bool, error) {
+	if s.Tag == nil || s.Init != nil {
+		return "", "", nil, false, nil
+	}
+	sym, ok := t.expr.lookupPath(s.Tag)
+	if !ok || !isUIntType(carryFree(sym.Type)) {
+		return "", "", nil, false, nil
+	}
+	typ := carryFree(sym.Type)
+	ref, err := t.expr.TranslateArg(s.Tag)
+	if err != nil {
+		return "", "", nil, false, err
+	}
+	tag := gotypes.ExprString(s.Tag)
+	var cases []intCase
+	var def *intCase
+	seen := make(map[string]bool)
+	for _, stmt := range s.Body.List {
+		clause := stmt.(*ast.CaseClause)
+		if len(clause.List) == 0 {
+			def = &intCase{value: "_", body: clause.Body, pos: clause.Pos()}
+			continue
+		}
+		for _, expr := range clause.List {
+			v, ok := t.folder.Fold(expr)
+			if !ok {
+				// A package constant, such as one of an enumeration.
+				var n *big.Int
+				n, ok = t.constantInt(expr)
+				v = Value{Int: n}
+			}
+			if ok && v.Int != nil && v.Type == "" {
+				v.Type = typ
+				v, ok = checkWidth(v)
+			}
+			if !ok || v.Int == nil || v.Type != typ {
+				return "", "", nil, false, t.errorAt(expr.Pos(), "a case of a switch on the %s %s must be a constant %s, got %s", typ, tag, typ, gotypes.ExprString(expr))
+			}
+			value := v.Int.String()
+			if seen[value] {
+				return "", "", nil, false, t.errorAt(expr.Pos(), "duplicate case %s in switch on %s", value, tag)
+			}
+			seen[value] = true
+			cases = append(cases, intCase{value: value, body: clause.Body, pos: clause.Pos()})
+		}
+	}
+	if def != nil {
+		cases = append(cases, *def)
+	}
+	return ref, typ, cases, true, nil
+}
+
+// intSwitchMatch builds the match an integer switch on ref, of type typ,
+// lowers to, arm lowering the body of each of its cases. tail is the body
+// of the last arm when cases has no default. arm also returns the Go
+// statement each statement of a body was lowered from, when it knows them.
+func (t *Transpiler) intSwitchMatch(ref, typ string, cases []intCase, tail []string, arm func([]ast.Stmt) ([]string, []token.Pos, error)) (*MatchExpression, error) {
+	bodies := make([][]string, len(cases))
+	bodyPos := make([][]token.Pos, len(cases))
+	for i, c := range cases {
+		body, pos, err := arm(c.body)
+		if err != nil {
+			return nil, err
+		}
+		bodies[i], bodyPos[i] = body, pos
+	}
+	var tailPos []token.Pos
+	n := len(cases)
+	if n > 0 && cases[n-1].value == "_" {
+		tail, tailPos, n = bodies[n-1], bodyPos[n-1], n-1
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	if integerPatterns() {
+		match := &MatchExpression{Scrutinee: ref, IsIntMatch: true}
+		for i, c := range cases[:n] {
+			match.Cases = append(match.Cases, MatchCase{Pattern: c.value, BodyStmts: bodies[i], BodyPos: bodyPos[i]})
+		}
+		match.Cases = append(match.Cases, MatchCase{Pattern: "_", BodyStmts: tail, BodyPos: tailPos})
+		return match, nil
+	}
+	eq := jetRef(fmt.Sprintf("eq_%d", uintBits(typ)))
+	var next *MatchExpression
+	for i := n - 1; i >= 0; i-- {
+		otherwise := MatchCase{Pattern: "false", Nested: next}
+		if next == nil {
+			otherwise.BodyStmts, otherwise.BodyPos = tail, tailPos
+		}
+		next = &MatchExpression{
+			Scrutinee:   fmt.Sprintf("%s(%s, %s)", eq, ref, cases[i].value),
+			Cases:       []MatchCase{{Pattern: "true", BodyStmts: bodies[i], BodyPos: bodyPos[i]}, otherwise},
+			IsBoolMatch: true,
+			Pos:         cases[i].pos,
+		}
+	}
+	return next, nil
+}
+
+// analyzeIntSwitch lowers s, a statement of main, when it switches on an
+// integer. It returns nil for a switch on anything else.
+func (t *Transpiler) analyzeIntSwitch(s *ast.SwitchStmt) (*MatchExpression, error) {
+	ref, typ, cases, ok, err := t.intSwitchCases(s)
+	if !ok || err != nil {
+		return nil, err
+	}
+	return t.intSwitchMatch(ref, typ, cases, []string{"()"}, func(body []ast.Stmt) ([]string, []token.Pos, error) {
+		stmts, pos, err := t.analyzeArmBodyStmts(body)
+		if len(stmts) == 0 {
+			stmts = []string{"()"}
+		}
+		return stmts, pos, err
+	})
+}
+
+// lowerIntSwitchResult lowers s, a switch on an integer that rest follows
+// in a function body, to the match that is the function's result, when
+// every case of s returns and rest is the return after a switch without a
+// default. It reports false for any other switch.
+func (t *Transpiler) lowerIntSwitchResult(s *ast.SwitchStmt, rest []ast.Stmt) (string, bool, error) {
+	returns := func(body []ast.Stmt) bool {
+		if len(body) == 0 {
+			return false
+		}
+		ret, ok := body[len(body)-1].(*ast.ReturnStmt)
+		return ok && len(ret.Results) == 1
+	}
+	hasDefault := false
+	for _, stmt := range s.Body.List {
+		clause := stmt.(*ast.CaseClause)
+		if !returns(clause.Body) {
+			return "", false, nil
+		}
+		hasDefault = hasDefault || len(clause.List) == 0
+	}
+	if hasDefault && len(rest) != 0 || !hasDefault && (len(rest) != 1 || !returns(rest)) {
+		return "", false, nil
+	}
+	ref, typ, cases, ok, err := t.intSwitchCases(s)
+	if !ok || err != nil {
+		return "", false, err
+	}
+	arm := func(body []ast.Stmt) ([]string, []token.Pos, error) {
+		t.folder.push()
+		defer t.folder.pop()
+		var stmts []string
+		for _, stmt := range body {
+			text, err := t.analyzeStatement(stmt)
+			if err != nil {
+				return nil, nil, err
+			}
+			if text != "" {
+				stmts = append(stmts, text)
+			}
+		}
+		return stmts, nil, nil
+	}
+	var tail []string
+	if !hasDefault {
+		if tail, _, err = arm(rest); err != nil {
+			return "", false, err
+		}
+	}
+	match, err := t.intSwitchMatch(ref, typ, cases, tail, arm)
+	if match == nil || err != nil {
+		return "", false, err
+	}
+	return matchText(match), true, nil
+}
+
+// lowerBoolSwitchResult lowers s, a switch on a bool that rest follows in
+// a function body, to the match on its tag that is the function's result,
+// when every case of s returns. The arm no case matches is the default, or
+// rest after a switch without one. It reports false for any other switch.
+func (t *Transpiler) lowerBoolSwitchResult(s *ast.SwitchStmt, rest []ast.Stmt) (string, bool, error) {
+	returns := func(body []ast.Stmt) bool {
+		if len(body) == 0 {
+			return false
+		}
+		ret, ok := body[len(body)-1].(*ast.ReturnStmt)
+		return ok && len(ret.Results) == 1
+	}
+	for _, stmt := range s.Body.List {
+		if !returns(stmt.(*ast.CaseClause).Body) {
+			return "", false, nil
+		}
+	}
+	ref, arms, ok, err := t.boolSwitchArms(s)
+	if !ok || err != nil {
+		return "", false, err
+	}
+	match := &MatchExpression{Scrutinee: ref, IsBoolMatch: true}
+	for i, pattern := range []string{"true", "false"} {
+		body := arms[i].body
+		if !arms[i].set {
+			if !returns(rest) {
+				return "", false, nil
+			}
+			body = rest
+		}
+		text, _, err := t.analyzeFunctionBody(&ast.BlockStmt{List: body})
+		if err != nil || text == "" {
+			return "", false, err
+		}
+		match.Cases = append(match.Cases, MatchCase{Pattern: pattern, BodyStmts: []string{text}})
+	}
+	return matchText(match), true, nil
+}
+
+// matchText renders match as the result of a function body, in canonical
+// indentation, each arm's last item its value.
+func matchText(match *MatchExpression) string {
+	lines := []string{fmt.Sprintf("match %s {", match.Scrutinee)}
+	for i, mc := range match.Cases {
+		lines = append(lines, canonicalIndent+armPattern(mc)+" => {")
+		body := mc.BodyStmts
+		if mc.Nested != nil {
+			body = append(append([]string(nil), body...), matchText(mc.Nested))
+		}
+		for _, l := range strings.Split(strings.Join(body, "\n"), "\n") {
+			lines = append(lines, canonicalIndent+canonicalIndent+l)
+		}
+		if i < len(match.Cases)-1 {
+			lines = append(lines, canonicalIndent+"},")
+		} else {
+			lines = append(lines, canonicalIndent+"}")
+		}
+	}
+	return strings.Join(append(lines, "}"), "\n")
+}
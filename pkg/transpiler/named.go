@@ -0,0 +1,146 @@
+package transpiler
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+
+	"golang.org/x/tools/go/ast/astutil"
+
+	simtypes "github.com/0ceanslim/go-simplicity/pkg/types"
+)
+
+// A named type over another type, and the constants of an enumeration
+// declared with it,
+//
+//	type Kind uint8
+//
+//	const (
+//		Plain Kind = iota
+//		Fast
+//		Urgent
+//	)
+//
+// are their underlying type and values: Kind is a u8 wherever it is used,
+// and the constants are params of that type, 0, 1 and 2. Before analysis
+// each const group is rewritten so that every spec lists its own values,
+// with iota replaced by the index of the spec, as Go reads it.
+
+// namedTypes returns the Simplicity types of the named types file declares
+// over other types, mapped by mapper. A named type over a struct is left
+// to the struct layout, and one whose underlying type does not map is left
+// out.
+func namedTypes(file *ast.File, mapper *simtypes.TypeMapper) map[string]simtypes.Type {
+	specs := make(map[string]ast.Expr)
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			typeSpec, ok := spec.(*ast.TypeSpec)
+			if !ok || typeSpec.TypeParams != nil {
+				continue
+			}
+			switch typeSpec.Type.(type) {
+			case *ast.Ident, *ast.ArrayType, *ast.SelectorExpr:
+				specs[typeSpec.Name.Name] = typeSpec.Type
+			}
+		}
+	}
+	// A named type may be declared over another declared further down,
+	// so each pass resolves those whose underlying types the last did.
+	named := make(map[string]simtypes.Type)
+	for changed := true; changed; {
+		changed = false
+		scoped := mapper.WithNamedTypes(named)
+		for name, underlying := range specs {
+			typ, err := scoped.MapType(underlying)
+			if err != nil || !sized(typ) {
+				continue
+			}
+			named[name] = typ
+			delete(specs, name)
+			changed = true
+		}
+	}
+	return named
+}
+
+// sized reports whether typ is fully known, with no named type in it.
+func sized(typ simtypes.Type) bool {
+	_, ok := typ.BitSize()
+	return ok
+}
+
+// lowerIota rewrites each const group of file so that a spec without
+// values repeats the type and values of the last one with them, and iota
+// in a value is the index of its spec.
+func (t *Transpiler) lowerIota(file *ast.File) {
+	ast.Inspect(file, func(n ast.Node) bool {
+		genDecl, ok := n.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.CONST {
+			return true
+		}
+		var last *ast.ValueSpec
+		for i, spec := range genDecl.Specs {
+			valueSpec, ok := spec.(*ast.ValueSpec)
+			if !ok {
+				continue
+			}
+			values := valueSpec.Values
+			if len(values) == 0 && last != nil && valueSpec.Type == nil {
+				valueSpec.Type, values = last.Type, last.Values
+			} else if len(values) > 0 {
+				last = &ast.ValueSpec{Type: valueSpec.Type, Values: values}
+			}
+			valueSpec.Values = make([]ast.Expr, len(values))
+			for k, value := range values {
+				valueSpec.Values[k] = withIota(value, i)
+			}
+		}
+		return false
+	})
+}
+
+// withIota returns a copy of expr with iota replaced by the integer n.
+func withIota(expr ast.Expr, n int) ast.Expr {
+	copied := astutil.Apply(copyExpr(expr), nil, func(c *astutil.Cursor) bool {
+		if ident, ok := c.Node().(*ast.Ident); ok && ident.Name == "iota" {
+			c.Replace(&ast.BasicLit{ValuePos: ident.Pos(), Kind: token.INT, Value: strconv.Itoa(n)})
+		}
+		return true
+	})
+	return copied.(ast.Expr)
+}
+
+// copyExpr copies the operators and conversions of expr, the nodes iota
+// may sit under in a constant's value, so that replacing it leaves expr
+// as it is.
+func copyExpr(expr ast.Expr) ast.Expr {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		c := *e
+		return &c
+	case *ast.ParenExpr:
+		c := *e
+		c.X = copyExpr(e.X)
+		return &c
+	case *ast.UnaryExpr:
+		c := *e
+		c.X = copyExpr(e.X)
+		return &c
+	case *ast.BinaryExpr:
+		c := *e
+		c.X, c.Y = copyExpr(e.X), copyExpr(e.Y)
+		return &c
+	case *ast.CallExpr:
+		c := *e
+		c.Args = make([]ast.Expr, len(e.Args))
+		for i, arg := range e.Args {
+			c.Args[i] = copyExpr(arg)
+		}
+		return &c
+	}
+	return expr
+}
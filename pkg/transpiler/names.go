@@ -0,0 +1,136 @@
+package transpiler
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+	"unicode"
+
+	"github.com/0ceanslim/go-simplicity/pkg/gensym"
+)
+
+// snakeCase converts a Go identifier to snake_case. A name that is a
+// SimplicityHL keyword or module name gets a trailing underscore, so a Go
+// local named match becomes match_ wherever it is defined or used; so does
+// one of the form of a compiler temporary (see pkg/gensym), such as t_4_2.
+func snakeCase(name string) string {
+	snake := snakeWords(name)
+	if reservedWords[snake] || gensym.IsTemp(snake) {
+		return snake + "_"
+	}
+	return snake
+}
+
+// temp returns a new temporary named after the Go source at pos, for a
+// lowering that binds an intermediate result.
+func (t *Transpiler) temp(pos token.Pos) string {
+	if t.fset == nil || !pos.IsValid() {
+		return t.temps.At(0, int(pos))
+	}
+	p := t.fset.Position(pos)
+	return t.temps.At(p.Line, p.Column)
+}
+
+// reserveTemps takes the temporaries that identifiers of file convert to
+// before they are given a trailing underscore, so that no temporary is
+// spelled like a Go name of the program.
+func (t *Transpiler) reserveTemps(file *ast.File) {
+	ast.Inspect(file, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && gensym.IsTemp(snakeWords(ident.Name)) {
+			t.temps.Take(ident.Name)
+		}
+		return true
+	})
+}
+
+// snakeWords converts a Go identifier to snake_case as a part of a longer
+// name, which no reserved word can collide with. Letters with an ASCII
+// spelling are transliterated first. A word starts at an upper-case letter
+// after a lower-case one, or at the last of a run of capitals that begins
+// a capitalized word, so BIP340Key becomes bip340_key and an all-caps
+// constant such as MAX_FEE keeps its words as max_fee. Leading underscores
+// are kept.
+func snakeWords(name string) string {
+	ascii, _ := ASCIIName(name)
+	if !strings.ContainsFunc(ascii, unicode.IsUpper) {
+		// Already snake_case, as most locals are.
+		return ascii
+	}
+	runes := []rune(ascii)
+	var result strings.Builder
+	result.Grow(len(ascii) + len(ascii)/2)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || nextLower && (unicode.IsUpper(prev) || unicode.IsDigit(prev)) {
+				result.WriteByte('_')
+			}
+		}
+		result.WriteRune(unicode.ToLower(r))
+	}
+	return result.String()
+}
+
+// ASCIIName spells the Go identifier name in ASCII, as SimplicityHL
+// identifiers must be, transliterating accented Latin letters such as é
+// and ß. It reports false when some letter has no ASCII spelling; the
+// result then keeps that letter.
+func ASCIIName(name string) (string, bool) {
+	if !strings.ContainsFunc(name, func(r rune) bool { return r > unicode.MaxASCII }) {
+		return name, true
+	}
+	var result strings.Builder
+	ok := true
+	for _, r := range name {
+		if r <= unicode.MaxASCII {
+			result.WriteRune(r)
+			continue
+		}
+		if s, found := transliterations[r]; found {
+			result.WriteString(s)
+		} else if s, found := transliterations[unicode.ToUpper(r)]; found {
+			result.WriteString(strings.ToLower(s))
+		} else {
+			result.WriteRune(r)
+			ok = false
+		}
+	}
+	return result.String(), ok
+}
+
+// transliterations spells the upper-case Latin letters of Latin-1 and
+// Latin Extended-A in ASCII. Their lower-case forms are found through
+// unicode.ToUpper.
+var transliterations = map[rune]string{
+	'À': "A", 'Á': "A", 'Â': "A", 'Ã': "A", 'Ä': "A", 'Å': "A", 'Æ': "AE",
+	'Ç': "C", 'È': "E", 'É': "E", 'Ê': "E", 'Ë': "E", 'Ì': "I", 'Í': "I",
+	'Î': "I", 'Ï': "I", 'Ð': "D", 'Ñ': "N", 'Ò': "O", 'Ó': "O", 'Ô': "O",
+	'Õ': "O", 'Ö': "O", 'Ø': "O", 'Ù': "U", 'Ú': "U", 'Û': "U", 'Ü': "U",
+	'Ý': "Y", 'Þ': "TH", 'ß': "ss", 'Ÿ': "Y",
+	'Ā': "A", 'Ă': "A", 'Ą': "A", 'Ć': "C", 'Ĉ': "C", 'Ċ': "C", 'Č': "C",
+	'Ď': "D", 'Đ': "D", 'Ē': "E", 'Ĕ': "E", 'Ė': "E", 'Ę': "E", 'Ě': "E",
+	'Ĝ': "G", 'Ğ': "G", 'Ġ': "G", 'Ģ': "G", 'Ĥ': "H", 'Ħ': "H", 'Ĩ': "I",
+	'Ī': "I", 'Ĭ': "I", 'Į': "I", 'İ': "I", 'Ĳ': "IJ", 'Ĵ': "J", 'Ķ': "K",
+	'Ĺ': "L", 'Ļ': "L", 'Ľ': "L", 'Ŀ': "L", 'Ł': "L", 'Ń': "N", 'Ņ': "N",
+	'Ň': "N", 'Ŋ': "NG", 'Ō': "O", 'Ŏ': "O", 'Ő': "O", 'Œ': "OE", 'Ŕ': "R",
+	'Ŗ': "R", 'Ř': "R", 'Ś': "S", 'Ŝ': "S", 'Ş': "S", 'Š': "S", 'Ţ': "T",
+	'Ť': "T", 'Ŧ': "T", 'Ũ': "U", 'Ū': "U", 'Ŭ': "U", 'Ů': "U", 'Ű': "U",
+	'Ų': "U", 'Ŵ': "W", 'Ŷ': "Y", 'Ź': "Z", 'Ż': "Z", 'Ž': "Z",
+}
+
+// reservedWords are the names a SimplicityHL identifier cannot take: the
+// keywords, and the modules that programs refer to by path.
+var reservedWords = map[string]bool{
+	"const":   true,
+	"fn":      true,
+	"jet":     true,
+	"let":     true,
+	"match":   true,
+	"mod":     true,
+	"param":   true,
+	"pub":     true,
+	"type":    true,
+	"use":     true,
+	"witness": true,
+}
@@ -0,0 +1,75 @@
+package transpiler
+
+import (
+	"go/ast"
+	"go/token"
+	gotypes "go/types"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+)
+
+// An op-assignment or an increment abbreviates a reassignment,
+//
+//	total += fee  →  total = total + fee
+//	n++           →  n = n + 1
+//
+// and is lowered as the reassignment: a let that shadows the variable,
+// wherever a statement is, in main, a function body, a match arm or an
+// unrolled loop. One whose value has no lowering, such as the increment of
+// an untyped counter, is left out of the program as a fallback rather than
+// bound to the placeholder. SimplicityHL has no assignment to an element of
+// an array, so xs[i] += 1 is an error; only xs[k] = v, at a constant index,
+// gives an array a new value.
+
+// reassignment returns the reassignment stmt abbreviates when it is an
+// op-assignment or an increment or decrement. It reports false for any
+// other statement; one that updates anything but a variable is an error.
+func (t *Transpiler) reassignment(stmt ast.Stmt) (*ast.AssignStmt, bool, error) {
+	var target, rhs ast.Expr
+	var op, tok token.Token
+	var pos token.Pos
+	switch s := stmt.(type) {
+	case *ast.AssignStmt:
+		var isOp bool
+		if op, isOp = opAssignTokens[s.Tok]; !isOp || len(s.Lhs) != 1 || len(s.Rhs) != 1 {
+			return nil, false, nil
+		}
+		target, rhs, tok, pos = s.Lhs[0], s.Rhs[0], s.Tok, s.TokPos
+	case *ast.IncDecStmt:
+		e := incDecExpr(nil, s.Tok)
+		target, rhs, op, tok, pos = s.X, &ast.BasicLit{ValuePos: s.TokPos, Kind: token.INT, Value: "1"}, e.Op, s.Tok, s.TokPos
+	default:
+		return nil, false, nil
+	}
+	ident, ok := ast.Unparen(target).(*ast.Ident)
+	if !ok {
+		why := "only a variable can be reassigned"
+		if _, isIndex := ast.Unparen(target).(*ast.IndexExpr); isIndex {
+			why = "SimplicityHL has no assignment to an element of an array; write xs[k] = v with a constant index instead"
+		}
+		return nil, false, diag.UnsupportedSyntax.Wrap(t.errorAt(pos, "the %s of %s is not supported: %s", tok, gotypes.ExprString(target), why))
+	}
+	return &ast.AssignStmt{
+		Lhs:    []ast.Expr{ident},
+		TokPos: pos,
+		Tok:    token.ASSIGN,
+		Rhs:    []ast.Expr{&ast.BinaryExpr{X: &ast.Ident{NamePos: ident.Pos(), Name: ident.Name, Obj: ident.Obj}, OpPos: pos, Op: op, Y: rhs}},
+	}, true, nil
+}
+
+// lowersValue reports whether the value assign, a reassignment, gives its
+// variable has a lowering: it folds, or an operator jet computes it.
+func (t *Transpiler) lowersValue(assign *ast.AssignStmt) bool {
+	value, ok := assign.Rhs[0].(*ast.BinaryExpr)
+	if !ok {
+		return true
+	}
+	if _, ok := t.folder.Fold(value); ok {
+		return true
+	}
+	if _, ok := t.folder.FoldDerived(value); ok {
+		return true
+	}
+	_, ok = t.expr.OperatorCall(value)
+	return ok
+}
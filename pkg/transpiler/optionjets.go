@@ -0,0 +1,190 @@
+package transpiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/jets"
+)
+
+// Jets such as AnnexHash return Option<T>, which Go code sees as a *T that
+// is nil when there is no value. A program cannot hold the Option in a
+// local; it tests the call against nil in an if statement, which becomes a
+// match on the jet whose Some arm binds the value:
+//
+//	if annex := jet.AnnexHash(); annex != nil {  →  match jet::current_annex_hash() {
+//	    …                                               Some(annex: u256) => { … },
+//	} else {                                            None => { … },
+//	    …                                           }
+//	}
+//
+// Jets that take an input index, such as IssuanceAssetAmount, return
+// Option<Option<T>> in SimplicityHL: the outer Option is None when there is
+// no input at the index. The match unwraps it, failing the spend, and
+// tests the inner one. Issued amounts are confidential types; the Some arm
+// binds the explicit amount and fails on a blinded one.
+
+// confidentialPayloads are the confidential types behind the u64 that Go
+// code sees for the issued amounts.
+var confidentialPayloads = map[string]string{
+	"issuance_asset_amount": "Amount1",
+	"issuance_token_amount": "TokenAmount1",
+}
+
+// optionPayload returns T when returnType is Option<T>.
+func optionPayload(returnType string) (string, bool) {
+	if !strings.HasPrefix(returnType, "Option<") || !strings.HasSuffix(returnType, ">") {
+		return "", false
+	}
+	return returnType[len("Option<") : len(returnType)-1], true
+}
+
+// optionJet returns the Option-returning jet that expr calls.
+func (t *Transpiler) optionJet(expr ast.Expr) (jets.JetInfo, bool) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return jets.JetInfo{}, false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || !t.isJet(sel.X) {
+		return jets.JetInfo{}, false
+	}
+	info, ok := t.jetRegistry.Lookup(sel.Sel.Name)
+	if !ok {
+		return jets.JetInfo{}, false
+	}
+	_, ok = optionPayload(info.ReturnType)
+	return info, ok
+}
+
+// optionJetUse is the error for an Option-returning jet called anywhere but
+// in the test of an if statement.
+func (t *Transpiler) optionJetUse(pos token.Pos, info jets.JetInfo) error {
+	return t.errorAt(pos, "jet.%s returns %s, a pointer that is nil when there is no value; test it where it is called, as in if h := %s; h != nil { … }",
+		info.GoName, info.ReturnType, exampleCall(info))
+}
+
+// exampleCall is a call of the jet for messages, e.g. jet.InputPegin(i).
+func exampleCall(info jets.JetInfo) string {
+	if len(info.ParamTypes) > 0 {
+		return "jet." + info.GoName + "(i)"
+	}
+	return "jet." + info.GoName + "()"
+}
+
+// analyzeOptionJetIf lowers an if statement that tests an Option-returning
+// jet against nil, either bound by the init statement or called in the
+// condition itself. It returns nil for other if statements.
+func (t *Transpiler) analyzeOptionJetIf(ifStmt *ast.IfStmt) (*MatchExpression, error) {
+	var call ast.Expr
+	var bound string
+	if ifStmt.Init != nil {
+		assign, ok := ifStmt.Init.(*ast.AssignStmt)
+		if !ok || assign.Tok != token.DEFINE || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+			return nil, nil
+		}
+		ident, isIdent := assign.Lhs[0].(*ast.Ident)
+		if _, isJet := t.optionJet(assign.Rhs[0]); !isIdent || !isJet {
+			return nil, nil
+		}
+		call, bound = assign.Rhs[0], ident.Name
+	}
+
+	bin, ok := ifStmt.Cond.(*ast.BinaryExpr)
+	tested := ok && (bin.Op == token.EQL || bin.Op == token.NEQ) && (isNil(bin.X) || isNil(bin.Y))
+	if tested {
+		operand := bin.X
+		if isNil(operand) {
+			operand = bin.Y
+		}
+		if call == nil {
+			call = operand
+			if _, isJet := t.optionJet(call); !isJet {
+				return nil, nil
+			}
+		} else if ident, ok := operand.(*ast.Ident); !ok || ident.Name != bound {
+			tested = false
+		}
+	}
+	if call == nil {
+		return nil, nil
+	}
+	info, _ := t.optionJet(call)
+	if !tested {
+		return nil, t.errorAt(ifStmt.Cond.Pos(), "the condition must test %s against nil: if %s := %s; %s != nil { … }",
+			bound, bound, exampleCall(info), bound)
+	}
+
+	var elseBody []ast.Stmt
+	switch e := ifStmt.Else.(type) {
+	case *ast.BlockStmt:
+		elseBody = e.List
+	case *ast.IfStmt:
+		return nil, t.errorAt(e.Pos(), "an if testing jet.%s against nil cannot continue with else if; nest the if in an else block", info.GoName)
+	}
+	someBody, noneBody := ifStmt.Body.List, elseBody
+	if bin.Op == token.EQL {
+		someBody, noneBody = elseBody, ifStmt.Body.List
+	}
+
+	if bound == "" {
+		bound = info.GoName
+	}
+	scrutinee, err := t.optionJetScrutinee(call.(*ast.CallExpr), info)
+	if err != nil {
+		return nil, err
+	}
+	payload, _ := optionPayload(info.ReturnType)
+	varName := snakeCase(bound)
+	some := MatchCase{Pattern: "Some", VarName: varName, VarType: payload}
+	var prelude []string
+	if conf, ok := confidentialPayloads[info.SimplicityName]; ok {
+		some.VarName, some.VarType = t.temp(call.Pos()), conf
+		prelude = []string{fmt.Sprintf("let %s: %s = unwrap_right::<(u1, u256)>(%s);", varName, payload, some.VarName)}
+	}
+	none := MatchCase{Pattern: "None"}
+	for _, arm := range []struct {
+		mc   *MatchCase
+		body []ast.Stmt
+	}{{&some, someBody}, {&none, noneBody}} {
+		stmts, pos, err := t.analyzeArmBodyStmts(arm.body)
+		if err != nil {
+			return nil, err
+		}
+		if arm.mc == &some {
+			arm.mc.addBody(prelude, make([]token.Pos, len(prelude)))
+		}
+		arm.mc.addBody(stmts, pos)
+		if len(arm.mc.BodyStmts) == 0 {
+			arm.mc.BodyStmts = []string{"()"}
+		}
+	}
+	return &MatchExpression{
+		Scrutinee:  scrutinee,
+		Cases:      []MatchCase{some, none},
+		IsJetMatch: true,
+	}, nil
+}
+
+// optionJetScrutinee translates the call of an Option-returning jet,
+// unwrapping the outer Option of the jets that take an input index.
+func (t *Transpiler) optionJetScrutinee(call *ast.CallExpr, info jets.JetInfo) (string, error) {
+	if len(call.Args) != len(info.ParamTypes) {
+		return "", t.errorAt(call.Pos(), "jet.%s takes %d argument(s), got %d", info.GoName, len(info.ParamTypes), len(call.Args))
+	}
+	args := make([]string, len(call.Args))
+	for i, arg := range call.Args {
+		a, err := t.expr.TranslateArg(arg)
+		if err != nil {
+			return "", err
+		}
+		args[i] = a
+	}
+	scrutinee := t.jetCallExpr(info.SimplicityName, strings.Join(args, ", "))
+	if len(args) > 0 {
+		scrutinee = fmt.Sprintf("unwrap(%s)", scrutinee)
+	}
+	return scrutinee, nil
+}
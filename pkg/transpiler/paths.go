@@ -0,0 +1,381 @@
+package transpiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// A path struct lists the spend paths of a contract as pointer fields, of
+// which the spender sets exactly one:
+//
+//	type Spend struct {
+//		Claim  *ClaimData
+//		Refund *RefundData
+//		Cancel *[64]byte
+//	}
+//
+// SimplicityHL has no n-ary sums, so the paths nest to the right as
+// Either<Claim, Either<Refund, Cancel>>, and the if/else-if chain or
+// tagless switch that tests which field is set becomes nested matches
+// whose arms hold the checks of one path each.
+
+// pathArm is one spend path of a path struct.
+type pathArm struct {
+	Name     string // Go field name
+	Type     string // SimplicityHL type of the payload
+	TypeName string // Go name of the payload's struct type, "" for other payloads
+}
+
+// isPathStruct reports whether every field of structType is a named
+// pointer, and there are at least two.
+func isPathStruct(structType *ast.StructType) bool {
+	if structType.Fields == nil || structType.Fields.NumFields() < 2 {
+		return false
+	}
+	for _, field := range structType.Fields.List {
+		if _, ok := field.Type.(*ast.StarExpr); !ok || len(field.Names) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// layoutPaths maps each path struct to the nested Either of its payloads.
+// It runs once the plain structs the payloads may use are laid out.
+func (t *Transpiler) layoutPaths() error {
+	names := make([]string, 0, len(t.pathDecls))
+	for name := range t.pathDecls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var arms []pathArm
+		for _, field := range t.pathDecls[name].Fields.List {
+			payload := field.Type.(*ast.StarExpr).X
+			arm := pathArm{}
+			if ident, ok := payload.(*ast.Ident); ok && t.structDecls[ident.Name] != nil {
+				arm.Type, arm.TypeName = t.customTypes[ident.Name], ident.Name
+			} else {
+				typ, err := t.mapType(payload)
+				if err != nil {
+					return fmt.Errorf("path struct %s: %w", name, err)
+				}
+				arm.Type = typ
+			}
+			for _, n := range field.Names {
+				arm.Name = n.Name
+				arms = append(arms, arm)
+			}
+		}
+		t.paths[name] = arms
+		t.customTypes[name] = pathType(arms)
+	}
+	return nil
+}
+
+// pathType is the right-nested Either of the payloads of arms.
+func pathType(arms []pathArm) string {
+	if len(arms) == 1 {
+		return arms[0].Type
+	}
+	return fmt.Sprintf("Either<%s, %s>", arms[0].Type, pathType(arms[1:]))
+}
+
+// pathBranch is the chain of Either constructors that selects arm i of n,
+// outermost first, joined by dots: Left, Right.Left, Right.Right.
+func pathBranch(i, n int) string {
+	branch := strings.Repeat("Right.", i)
+	if i < n-1 {
+		return branch + "Left"
+	}
+	return strings.TrimSuffix(branch, ".")
+}
+
+// pathFields lists the payloads of arms for Witnesses: the fields of a
+// struct payload as path.field, any other payload under the path's name.
+func (t *Transpiler) pathFields(arms []pathArm) []WitnessField {
+	var fields []WitnessField
+	for i, arm := range arms {
+		branch := pathBranch(i, len(arms))
+		name := snakeCase(arm.Name)
+		if arm.TypeName == "" || len(t.structFields[arm.TypeName]) == 0 {
+			fields = append(fields, WitnessField{Name: name, Type: arm.Type, Branch: branch})
+			continue
+		}
+		for _, f := range t.structFields[arm.TypeName] {
+			fields = append(fields, WitnessField{Name: name + "." + snakeCase(f.Name), Type: f.Type, Branch: branch})
+		}
+	}
+	return fields
+}
+
+// pathWitness returns the Go name of the path struct type of the witness
+// named name, or "" if it is not declared with one.
+func (t *Transpiler) pathWitness(name string) string {
+	upper := strings.ToUpper(t.toSnakeCase(name))
+	for _, w := range t.witnessValues {
+		if strings.ToUpper(w.Name) == upper {
+			if _, ok := t.paths[w.GoTypeName]; ok {
+				return w.GoTypeName
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// pathCondition recognizes w.Path != nil (or nil != w.Path) on a witness
+// declared with a path struct, returning the witness and the path.
+func (t *Transpiler) pathCondition(cond ast.Expr) (witness, arm string, ok bool) {
+	witness, arm, op := t.pathTest(cond)
+	return witness, arm, op == token.NEQ
+}
+
+// pathTest returns the witness and path compared against nil by cond, and
+// the comparison, or token.ILLEGAL if cond is not such a test.
+func (t *Transpiler) pathTest(cond ast.Expr) (witness, arm string, op token.Token) {
+	bin, isBin := cond.(*ast.BinaryExpr)
+	if !isBin || (bin.Op != token.NEQ && bin.Op != token.EQL) {
+		return "", "", token.ILLEGAL
+	}
+	x, y := bin.X, bin.Y
+	if isNil(x) {
+		x, y = y, x
+	}
+	sel, isSel := x.(*ast.SelectorExpr)
+	if !isSel || !isNil(y) {
+		return "", "", token.ILLEGAL
+	}
+	ident, isIdent := sel.X.(*ast.Ident)
+	if !isIdent || t.pathWitness(ident.Name) == "" {
+		return "", "", token.ILLEGAL
+	}
+	return ident.Name, sel.Sel.Name, bin.Op
+}
+
+func isNil(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == "nil"
+}
+
+// pathClause is the body of one tested path, or of the final else or
+// default, which covers every path not tested.
+type pathClause struct {
+	arm  string // "" for else and default
+	body []ast.Stmt
+}
+
+// analyzePathIf lowers an if/else-if chain that tests which path of a
+// path struct witness is set. It returns nil if the chain does not start
+// with such a test.
+func (t *Transpiler) analyzePathIf(ifStmt *ast.IfStmt) (*MatchExpression, error) {
+	witness, arm, op := t.pathTest(ifStmt.Cond)
+	switch op {
+	case token.ILLEGAL:
+		return nil, nil
+	case token.EQL:
+		return nil, t.errorAt(ifStmt.Cond.Pos(), "select the paths of %s by the one that is set: %s.%s != nil", witness, witness, arm)
+	}
+	var clauses []pathClause
+	for stmt := ast.Stmt(ifStmt); stmt != nil; {
+		switch s := stmt.(type) {
+		case *ast.IfStmt:
+			if s.Init != nil {
+				return nil, t.errorAt(s.Pos(), "a condition selecting a path of %s cannot have an init statement", witness)
+			}
+			w, arm, ok := t.pathCondition(s.Cond)
+			if !ok || w != witness {
+				return nil, t.errorAt(s.Cond.Pos(), "every condition of a chain selecting a path of %s must test one of its fields against nil", witness)
+			}
+			clauses = append(clauses, pathClause{arm: arm, body: s.Body.List})
+			stmt = s.Else
+		case *ast.BlockStmt:
+			clauses = append(clauses, pathClause{body: s.List})
+			stmt = nil
+		}
+	}
+	return t.buildPathMatch(witness, clauses)
+}
+
+// analyzePathSwitch lowers a tagless switch whose cases test which path of
+// a path struct witness is set. It returns nil for any other switch.
+func (t *Transpiler) analyzePathSwitch(switchStmt *ast.SwitchStmt) (*MatchExpression, error) {
+	if switchStmt.Tag != nil || switchStmt.Init != nil {
+		return nil, nil
+	}
+	var witness string
+	for _, stmt := range switchStmt.Body.List {
+		clause := stmt.(*ast.CaseClause)
+		if len(clause.List) > 0 {
+			if w, _, ok := t.pathCondition(clause.List[0]); ok {
+				witness = w
+			}
+			break
+		}
+	}
+	if witness == "" {
+		return nil, nil
+	}
+	var clauses []pathClause
+	for _, stmt := range switchStmt.Body.List {
+		clause := stmt.(*ast.CaseClause)
+		if len(clause.List) == 0 {
+			clauses = append(clauses, pathClause{body: clause.Body})
+			continue
+		}
+		for _, cond := range clause.List {
+			w, arm, ok := t.pathCondition(cond)
+			if !ok || w != witness {
+				return nil, t.errorAt(cond.Pos(), "every case of a switch selecting a path of %s must test one of its fields against nil", witness)
+			}
+			clauses = append(clauses, pathClause{arm: arm, body: clause.Body})
+		}
+	}
+	match, err := t.buildPathMatch(witness, clauses)
+	if err != nil {
+		return nil, err
+	}
+	if missing := missingPaths(witness, t.paths[t.pathWitness(witness)], clauses); len(missing) > 0 {
+		return nil, t.errorAt(switchStmt.Pos(), "switch on the paths of %s has no case for %s and no default", witness, strings.Join(missing, ", "))
+	}
+	return match, nil
+}
+
+// missingPaths lists the paths of witness, such as w.Refund, that no
+// clause covers. An else or default covers them all.
+func missingPaths(witness string, arms []pathArm, clauses []pathClause) []string {
+	tested := make(map[string]bool, len(clauses))
+	for _, c := range clauses {
+		if c.arm == "" {
+			return nil
+		}
+		tested[c.arm] = true
+	}
+	var missing []string
+	for _, arm := range arms {
+		if !tested[arm.Name] {
+			missing = append(missing, witness+"."+arm.Name)
+		}
+	}
+	return missing
+}
+
+// buildPathMatch assigns the clauses to the paths of witness and nests one
+// match per path. In an if chain, a path that is neither tested nor
+// covered by an else runs no checks, as it does in Go; a switch must
+// cover every path.
+func (t *Transpiler) buildPathMatch(witness string, clauses []pathClause) (*MatchExpression, error) {
+	typeName := t.pathWitness(witness)
+	arms := t.paths[typeName]
+	bodies := make(map[string][]ast.Stmt, len(arms))
+	var rest []ast.Stmt
+	hasRest := false
+	for _, c := range clauses {
+		if c.arm == "" {
+			rest, hasRest = c.body, true
+			continue
+		}
+		known := false
+		for _, arm := range arms {
+			known = known || arm.Name == c.arm
+		}
+		if !known {
+			return nil, fmt.Errorf("%s.%s is not a path of %s", witness, c.arm, typeName)
+		}
+		if _, dup := bodies[c.arm]; dup {
+			return nil, fmt.Errorf("path %s of %s is selected twice", c.arm, witness)
+		}
+		bodies[c.arm] = c.body
+	}
+
+	cases := make([]MatchCase, len(arms))
+	for i, arm := range arms {
+		body, tested := bodies[arm.Name]
+		if !tested && hasRest {
+			body = rest
+		}
+		mc, err := t.pathCase(witness, arm, body)
+		if err != nil {
+			return nil, err
+		}
+		cases[i] = mc
+	}
+
+	// Either<A, Either<B, C>>: the Right arm of each level matches the rest
+	match := &MatchExpression{Scrutinee: t.resolveWitnessRef(witness)}
+	level := match
+	for i := range cases {
+		if i == len(cases)-2 {
+			cases[i].Pattern, cases[i+1].Pattern = "Left", "Right"
+			level.Cases = []MatchCase{cases[i], cases[i+1]}
+			break
+		}
+		cases[i].Pattern = "Left"
+		inner := &MatchExpression{Scrutinee: "rest"}
+		level.Cases = []MatchCase{cases[i], {Pattern: "Right", VarName: "rest", VarType: pathType(arms[i+1:]), Nested: inner}}
+		level = inner
+	}
+	return match, nil
+}
+
+// pathCase lowers the body of one path. The payload is bound to a local
+// named after the path; a struct payload is destructured by the arm's
+// pattern so that selectors such as w.Claim.Preimage read locals like
+// claim_preimage.
+func (t *Transpiler) pathCase(witness string, arm pathArm, body []ast.Stmt) (MatchCase, error) {
+	local := snakeCase(arm.Name)
+	mc := MatchCase{VarName: local, VarType: arm.Type}
+	if err := t.checkPathSelectors(witness, arm.Name, body); err != nil {
+		return mc, err
+	}
+
+	field := boundField{structField: structField{Name: arm.Name, Type: arm.Type, TypeName: arm.TypeName}, ref: local}
+	if arm.TypeName != "" && len(t.structFields[arm.TypeName]) > 0 {
+		field.nested = t.bindStruct(local, arm.TypeName)
+		field.ref = field.nested.pattern()
+	}
+	saved, had := t.structParams[witness]
+	t.structParams[witness] = &structValue{typeName: t.pathWitness(witness), fields: []boundField{field}}
+	defer func() {
+		if had {
+			t.structParams[witness] = saved
+		} else {
+			delete(t.structParams, witness)
+		}
+	}()
+
+	stmts, pos, err := t.analyzeArmStmts(body, "", "")
+	if err != nil {
+		return mc, err
+	}
+	mc.addBody(stmts, pos)
+	if field.nested != nil {
+		mc.VarName = field.nested.patternReading(mc.BodyStmts)
+	}
+	if len(mc.BodyStmts) == 0 {
+		mc.BodyStmts = []string{"()"}
+	}
+	return mc, nil
+}
+
+// checkPathSelectors rejects reads of a path other than arm in its body:
+// those pointers are nil there.
+func (t *Transpiler) checkPathSelectors(witness, arm string, body []ast.Stmt) error {
+	var err error
+	for _, stmt := range body {
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok || err != nil {
+				return err == nil
+			}
+			if ident, ok := sel.X.(*ast.Ident); ok && ident.Name == witness && sel.Sel.Name != arm {
+				err = t.errorAt(sel.Pos(), "%s.%s is nil on the %s path of %s", witness, sel.Sel.Name, arm, witness)
+			}
+			return true
+		})
+	}
+	return err
+}
@@ -3,6 +3,7 @@ package transpiler
 import (
 	"fmt"
 	"go/ast"
+	"go/token"
 	"strings"
 )
 
@@ -12,6 +13,24 @@ type MatchCase struct {
 	VarName   string   // The variable name bound in the case
 	VarType   string   // The type of the bound variable
 	BodyStmts []string // Statements in the case body
+
+	// BodyPos, when it has one entry for each of BodyStmts, holds the Go
+	// statement each was lowered from, or token.NoPos for one without.
+	BodyPos []token.Pos
+
+	// Nested, when set, follows BodyStmts as the arm's result: the match
+	// over the remaining paths of a path struct.
+	Nested *MatchExpression
+}
+
+// addBody appends stmts, lowered from the Go statements at pos, to the
+// body of mc.
+func (mc *MatchCase) addBody(stmts []string, pos []token.Pos) {
+	for len(mc.BodyPos) < len(mc.BodyStmts) {
+		mc.BodyPos = append(mc.BodyPos, token.NoPos)
+	}
+	mc.BodyStmts = append(mc.BodyStmts, stmts...)
+	mc.BodyPos = append(mc.BodyPos, pos...)
 }
 
 // MatchExpression represents a complete match/type-switch
@@ -20,6 +39,15 @@ type MatchExpression struct {
 	ScrutineeType string      // The type of the scrutinee (e.g., "Either<u256, [u8; 64]>")
 	Cases         []MatchCase // The cases
 	IsBoolMatch   bool        // true for boolean if/else (true/false patterns)
+	IsJetMatch    bool        // true for a match on an Option-returning jet call
+	IsIntMatch    bool        // true for a match on an integer's value (0 =>, _ =>)
+	Pos           token.Pos   // Go statement the match was lowered from
+}
+
+// onValue reports whether m matches on a value main computes, a bool, the
+// result of a jet or an integer, rather than on a witness of a sum type.
+func (m *MatchExpression) onValue() bool {
+	return m.IsBoolMatch || m.IsJetMatch || m.IsIntMatch
 }
 
 // analyzeTypeSwitchStmt extracts pattern matching info from a Go type switch
@@ -31,7 +59,7 @@ func (t *Transpiler) analyzeTypeSwitchStmt(stmt *ast.TypeSwitchStmt) (*MatchExpr
 	if assign, ok := stmt.Assign.(*ast.AssignStmt); ok {
 		if len(assign.Rhs) == 1 {
 			if typeAssert, ok := assign.Rhs[0].(*ast.TypeAssertExpr); ok {
-				scrutinee, err := t.evaluateExpression(typeAssert.X)
+				scrutinee, err := t.expr.Translate(typeAssert.X)
 				if err != nil {
 					return nil, err
 				}
@@ -52,6 +80,9 @@ func (t *Transpiler) analyzeTypeSwitchStmt(stmt *ast.TypeSwitchStmt) (*MatchExpr
 			}
 		}
 	}
+	if arm, ok := t.missingArm(match.Cases); ok {
+		return nil, t.errorAt(stmt.Pos(), "type switch has no case for %s and no default", arm)
+	}
 
 	return match, nil
 }
@@ -72,21 +103,24 @@ func (t *Transpiler) analyzeCaseClause(clause *ast.CaseClause) (*MatchCase, erro
 	}
 
 	// Process the body statements
-	for _, bodyStmt := range clause.Body {
-		stmtStr, err := t.analyzeStatement(bodyStmt)
-		if err != nil {
-			return nil, err
-		}
-		if stmtStr != "" {
-			mc.BodyStmts = append(mc.BodyStmts, stmtStr)
-		}
+	stmts, pos, err := t.analyzeArmStmts(clause.Body, "", "")
+	if err != nil {
+		return nil, err
 	}
+	mc.BodyStmts, mc.BodyPos = stmts, pos
 
 	return mc, nil
 }
 
 // analyzeStatement converts a Go statement to SimplicityHL
 func (t *Transpiler) analyzeStatement(stmt ast.Stmt) (string, error) {
+	if assign, ok, err := t.reassignment(stmt); err != nil {
+		return "", err
+	} else if ok && !t.lowersValue(assign) {
+		return "", t.droppedStatement(stmt, statementKind(stmt))
+	} else if ok {
+		stmt = assign
+	}
 	switch s := stmt.(type) {
 	case *ast.AssignStmt:
 		return t.analyzeAssignStmt(s)
@@ -96,55 +130,84 @@ func (t *Transpiler) analyzeStatement(stmt ast.Stmt) (string, error) {
 		return t.analyzeReturnStmt(s)
 	case *ast.DeclStmt:
 		return t.analyzeDeclStmt(s)
-	default:
+	case *ast.EmptyStmt:
 		return "", nil
+	case *ast.IfStmt, *ast.SwitchStmt:
+		calls, ok, err := t.lowerBranchAssign(stmt)
+		if err != nil || ok {
+			return strings.Join(letLines(calls), "\n"), err
+		}
 	}
+	return "", t.droppedStatement(stmt, statementKind(stmt))
 }
 
 // analyzeAssignStmt converts assignment statements
 func (t *Transpiler) analyzeAssignStmt(stmt *ast.AssignStmt) (string, error) {
+	if array, k, ok, err := t.elementAssignment(stmt); err != nil {
+		return "", err
+	} else if ok {
+		return t.lowerElementAssign(stmt, array, k)
+	}
 	if len(stmt.Lhs) == 1 && len(stmt.Rhs) == 1 {
 		lhs := ""
 		if ident, ok := stmt.Lhs[0].(*ast.Ident); ok {
 			lhs = t.toSnakeCase(ident.Name)
 		}
+		t.trackAssign(stmt)
+		if ident, ok := stmt.Lhs[0].(*ast.Ident); ok && (stmt.Tok == token.DEFINE || stmt.Tok == token.ASSIGN) {
+			t.trackArray(ident.Name, "", stmt.Rhs[0])
+		}
 
 		// Check if this is a jet call
 		if callExpr, ok := stmt.Rhs[0].(*ast.CallExpr); ok {
 			if sel, ok := callExpr.Fun.(*ast.SelectorExpr); ok {
-				if selIdent, ok := sel.X.(*ast.Ident); ok && selIdent.Name == "jet" {
+				if t.isJet(sel.X) {
 					jetCall, err := t.evaluateJetCall(sel.Sel.Name, callExpr.Args)
 					if err != nil {
 						return "", err
 					}
+					// The local has the jet's type, for a switch on it.
+					if info, found := t.jetRegistry.Lookup(sel.Sel.Name); found && info.ResultType == "" && lhs != "" && lhs != "_" {
+						t.params[stmt.Lhs[0].(*ast.Ident).Name] = info.ReturnType
+					}
 					return fmt.Sprintf("let %s = %s;", lhs, jetCall), nil
 				}
 			}
 		}
 
-		rhs, err := t.evaluateExpression(stmt.Rhs[0])
+		rhs, err := t.expr.TranslateArg(stmt.Rhs[0])
 		if err != nil {
 			return "", err
 		}
+		if ident, ok := stmt.Lhs[0].(*ast.Ident); ok && lhs != "_" {
+			t.trackLocalType(ident.Name, stmt.Rhs[0])
+		}
 		return fmt.Sprintf("let %s = %s;", lhs, rhs), nil
 	}
 
 	// Handle tuple destructuring: a, b := tuple
 	if len(stmt.Lhs) > 1 && len(stmt.Rhs) == 1 {
-		var names []string
-		for _, l := range stmt.Lhs {
-			if ident, ok := l.(*ast.Ident); ok {
-				names = append(names, t.toSnakeCase(ident.Name))
-			}
-		}
-		rhs, err := t.evaluateExpression(stmt.Rhs[0])
-		if err != nil {
-			return "", err
-		}
-		return fmt.Sprintf("let (%s) = %s;", strings.Join(names, ", "), rhs), nil
+		return t.analyzeTupleAssign(stmt)
 	}
 
-	return "", nil
+	return "", t.droppedStatement(stmt, "the assignment")
+}
+
+// trackLocalType records the type of the local name when value, the value
+// a let binds it to, is a copy of a typed name or an operator jet computes
+// it, so that a later reassignment such as name += 1 lowers to a jet over
+// it too. A Go variable keeps its type when reassigned, so one already
+// recorded stays for any other value.
+func (t *Transpiler) trackLocalType(name string, value ast.Expr) {
+	if sym, ok := t.expr.lookupPath(value); ok && sym.Type != "" {
+		t.params[name] = carryFree(sym.Type)
+		return
+	}
+	if bin, ok := ast.Unparen(value).(*ast.BinaryExpr); ok && isUIntType(t.expr.TypeOf(bin)) {
+		if _, ok := t.expr.OperatorCall(bin); ok {
+			t.params[name] = t.expr.TypeOf(bin)
+		}
+	}
 }
 
 // analyzeExprStmt converts expression statements (like jet calls)
@@ -152,7 +215,7 @@ func (t *Transpiler) analyzeExprStmt(stmt *ast.ExprStmt) (string, error) {
 	if callExpr, ok := stmt.X.(*ast.CallExpr); ok {
 		// jet.X(...) selector calls
 		if sel, ok := callExpr.Fun.(*ast.SelectorExpr); ok {
-			if selIdent, ok := sel.X.(*ast.Ident); ok && selIdent.Name == "jet" {
+			if t.isJet(sel.X) {
 				jetCall, err := t.evaluateJetCall(sel.Sel.Name, callExpr.Args)
 				if err != nil {
 					return "", err
@@ -164,69 +227,155 @@ func (t *Transpiler) analyzeExprStmt(stmt *ast.ExprStmt) (string, error) {
 		if _, ok := callExpr.Fun.(*ast.Ident); ok {
 			return t.evaluateCallExpr(callExpr)
 		}
+		// std helpers used as statements (e.g., std.RequireOutput(...))
+		if name, ok := t.stdFunc(callExpr); ok {
+			if name == "Assert" || name == "Verify" {
+				cond, err := t.assertArg(name, callExpr)
+				if err != nil {
+					return "", err
+				}
+				return formatJetCallExpr("verify", cond), nil
+			}
+			return t.evaluateCallExpr(callExpr)
+		}
 	}
-	return "", nil
+	return "", t.droppedStatement(stmt, "the expression statement")
 }
 
 // analyzeReturnStmt converts return statements
 func (t *Transpiler) analyzeReturnStmt(stmt *ast.ReturnStmt) (string, error) {
-	if len(stmt.Results) == 0 {
+	results := make([]string, len(stmt.Results))
+	for i, r := range stmt.Results {
+		result, err := t.expr.TranslateArg(r)
+		if err != nil {
+			return "", err
+		}
+		results[i] = result
+	}
+	switch len(results) {
+	case 0:
 		return "", nil
+	case 1:
+		return results[0], nil
 	}
-	result, err := t.evaluateExpression(stmt.Results[0])
-	if err != nil {
-		return "", err
+	// Several results are the tuple the function returns
+	return tupleOf(results), nil
+}
+
+// analyzeDeclStmt lowers the variables of a var declaration to lets. One
+// declared without a value starts at the zero of its type, which the
+// folder knows too, so that var n uint32 followed by return n + 5 folds,
+// and an array's elements are known for a later msg[0] = 0x01.
+func (t *Transpiler) analyzeDeclStmt(stmt *ast.DeclStmt) (string, error) {
+	genDecl, ok := stmt.Decl.(*ast.GenDecl)
+	if ok && genDecl.Tok == token.CONST {
+		return "", t.localConsts(genDecl)
+	}
+	if !ok || genDecl.Tok != token.VAR {
+		return "", nil
 	}
-	return result, nil
+	var lines []string
+	for _, spec := range genDecl.Specs {
+		valueSpec, ok := spec.(*ast.ValueSpec)
+		if !ok || valueSpec.Type == nil {
+			continue
+		}
+		typ, err := t.mapType(valueSpec.Type)
+		if err != nil {
+			return "", t.errorAt(valueSpec.Type.Pos(), "%v", err)
+		}
+		for i, name := range valueSpec.Names {
+			if name.Name == "_" {
+				continue
+			}
+			value := zeroValue(typ)
+			if i < len(valueSpec.Values) {
+				t.trackAssign(&ast.AssignStmt{Lhs: []ast.Expr{name}, Tok: token.DEFINE, Rhs: []ast.Expr{valueSpec.Values[i]}})
+				t.trackArray(name.Name, typ, valueSpec.Values[i])
+				if value, err = t.expr.TranslateArg(valueSpec.Values[i]); err != nil {
+					return "", err
+				}
+			} else if v, ok := zeroFolded(typ); ok {
+				t.folder.bind(name.Name, v, false)
+			} else {
+				t.trackArray(name.Name, typ, nil)
+			}
+			lines = append(lines, fmt.Sprintf("let %s: %s = %s;", t.toSnakeCase(name.Name), typ, value))
+		}
+	}
+	return strings.Join(lines, "\n"), nil
 }
 
-// analyzeDeclStmt handles variable declarations
-func (t *Transpiler) analyzeDeclStmt(_ *ast.DeclStmt) (string, error) {
-	// Skip declarations inside match arms — handled as witness values
-	return "", nil
+// localConsts gives the folder the values of the constants a function
+// body, main's too, declares, which are inlined where they are used.
+func (t *Transpiler) localConsts(decl *ast.GenDecl) error {
+	for _, spec := range decl.Specs {
+		valueSpec, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		for i, name := range valueSpec.Names {
+			if name.Name == "_" {
+				continue
+			}
+			if i >= len(valueSpec.Values) {
+				return t.errorAt(name.Pos(), "the constant %s has no value: a const of a function body must be given one", name.Name)
+			}
+			value := valueSpec.Values[i]
+			if valueSpec.Type != nil {
+				value = &ast.CallExpr{Fun: valueSpec.Type, Lparen: value.Pos(), Args: []ast.Expr{value}, Rparen: value.End()}
+			}
+			if _, _, known := t.trackAssign(&ast.AssignStmt{Lhs: []ast.Expr{name}, Tok: token.DEFINE, Rhs: []ast.Expr{value}}); !known {
+				return t.errorAt(valueSpec.Values[i].Pos(), "the value of the constant %s is not known at compile time", name.Name)
+			}
+		}
+	}
+	return nil
 }
 
-// generateMatchExpression generates SimplicityHL match expression
-func (t *Transpiler) generateMatchExpression(match *MatchExpression, indent string) string {
-	var sb strings.Builder
+// armBlock writes the body statements of mc at depth as printer.block
+// does, each at the Go statement it was lowered from when mc records them,
+// so that a failing check in an arm is reported at its own line.
+func (t *Transpiler) armBlock(depth int, mc MatchCase, result bool) {
+	if len(mc.BodyPos) != len(mc.BodyStmts) {
+		t.printer.block(depth, strings.Join(mc.BodyStmts, "\n"), result)
+		return
+	}
+	last := len(mc.BodyStmts) - 1
+	for last >= 0 && isComment(mc.BodyStmts[last]) {
+		last--
+	}
+	for i, stmt := range mc.BodyStmts {
+		if mc.BodyPos[i].IsValid() {
+			t.printer.at(mc.BodyPos[i])
+		}
+		t.printer.block(depth, stmt, result && i == last)
+	}
+}
 
-	sb.WriteString(fmt.Sprintf("%smatch %s {\n", indent, match.Scrutinee))
+// generateMatchExpression writes a SimplicityHL match expression at depth,
+// terminated as pos takes: a statement of main, or the result of an arm.
+func (t *Transpiler) generateMatchExpression(match *MatchExpression, depth int, pos position) {
+	t.printer.at(match.Pos)
+	t.emit(depth, fmt.Sprintf("match %s {", match.Scrutinee))
 
 	for i, mc := range match.Cases {
-		// Generate the pattern — Simfony requires type annotations in match arm
-		// bindings: Left(x: Type), Right(x: Type), Some(x: Type).
-		pattern := mc.Pattern
-		if mc.VarName != "" {
-			if mc.VarType != "" {
-				pattern = fmt.Sprintf("%s(%s: %s)", mc.Pattern, mc.VarName, mc.VarType)
-			} else {
-				pattern = fmt.Sprintf("%s(%s)", mc.Pattern, mc.VarName)
-			}
-		}
+		t.printer.at(match.Pos)
+		t.emit(depth+1, armPattern(mc)+" => {")
 
-		sb.WriteString(fmt.Sprintf("%s    %s => {\n", indent, pattern))
-
-		// Generate body statements (each entry may span multiple lines)
-		for _, bodyStmt := range mc.BodyStmts {
-			// Add ; to statements that don't already end with ; or }
-			trimmed := strings.TrimRight(bodyStmt, " \t\r\n")
-			if !strings.HasSuffix(trimmed, ";") && !strings.HasSuffix(trimmed, "}") {
-				bodyStmt = trimmed + ";"
-			}
-			for _, line := range strings.Split(bodyStmt, "\n") {
-				if strings.TrimSpace(line) != "" {
-					sb.WriteString(fmt.Sprintf("%s        %s\n", indent, line))
-				}
-			}
+		// Body statements, each of which may span lines; a nested match is
+		// the arm's result.
+		t.armBlock(depth+2, mc, false)
+		if mc.Nested != nil {
+			t.generateMatchExpression(mc.Nested, depth+2, resultPos)
 		}
 
-		sb.WriteString(fmt.Sprintf("%s    }", indent))
 		if i < len(match.Cases)-1 {
-			sb.WriteString(",")
+			t.emit(depth+1, "},")
+		} else {
+			t.emit(depth+1, "}")
 		}
-		sb.WriteString("\n")
 	}
 
-	sb.WriteString(fmt.Sprintf("%s}", indent))
-	return sb.String()
+	t.emit(depth, terminate("}", pos))
 }
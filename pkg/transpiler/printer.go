@@ -0,0 +1,407 @@
+package transpiler
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"io"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+)
+
+// Style controls the whitespace of generated SimplicityHL.
+type Style struct {
+	Indent                 string // One indentation level (e.g. "    " or "\t")
+	BlankLinesBetweenFuncs int    // Blank lines separating top-level items
+	TrailingNewline        bool   // Terminate the output with a newline
+}
+
+// DefaultStyle returns the formatting the transpiler has always produced:
+// four-space indentation, one blank line between functions and a trailing
+// newline.
+func DefaultStyle() Style {
+	return Style{
+		Indent:                 "    ",
+		BlankLinesBetweenFuncs: 1,
+		TrailingNewline:        true,
+	}
+}
+
+// canonicalIndent is the indentation unit used inside fragments that the
+// analysis phase renders ahead of time (match arms, unrolled iterations).
+// The printer folds each leading unit into the depth and re-indents with
+// Style.Indent, so whitespace is decided in exactly one place.
+const canonicalIndent = "    "
+
+// printer renders SimplicityHL to a writer. It is the only code that writes
+// indentation, line breaks, or blank lines into the output, and it decides
+// the terminator of each item of a block from the item's position: a
+// statement or the block's result.
+//
+// Each written line is attributed to the Go position most recently set with
+// at, which gives a line-level source map from output back to input.
+//
+// Output is buffered and flushed to the writer after each top-level item.
+// Line breaks are held back until more text follows, so separators written
+// after the last item never reach the writer and finish can apply the
+// trailing-newline policy. The first write error, or passing a non-zero
+// limit of bytes, stops all further output and is recorded in err. So does
+// an item that brings the estimated size of the program, counted as each
+// item is flushed, past a non-zero maxNodes; the item is not written.
+type printer struct {
+	style    Style
+	w        io.Writer
+	buf      bytes.Buffer
+	n        int // Bytes written, flushed or not
+	pending  int // Line breaks not yet written
+	pos      token.Pos
+	lines    []token.Pos
+	item     int    // Index in lines of the first line of the buffered item
+	name     string // Function the buffered item defines, if any
+	items    []Item // Items flushed
+	limit    int
+	nodes    int // Estimated size of the items flushed, by CountNodes
+	maxNodes int
+	fset     *token.FileSet
+	err      error
+}
+
+func newPrinter(style Style, limit, maxNodes int, fset *token.FileSet) *printer {
+	return &printer{style: style, limit: limit, maxNodes: maxNodes, fset: fset}
+}
+
+// reset prepares the printer to render a new program to w.
+func (p *printer) reset(w io.Writer) {
+	p.w = w
+	p.buf.Reset()
+	p.n, p.pending = 0, 0
+	p.pos = token.NoPos
+	p.lines = nil
+	p.item, p.nodes = 0, 0
+	p.name, p.items = "", nil
+	p.err = nil
+}
+
+// at attributes the lines written from now on to the Go source position pos.
+func (p *printer) at(pos token.Pos) {
+	p.pos = pos
+}
+
+// newline terminates the current output line and records its origin.
+func (p *printer) newline() {
+	p.pending++
+	p.lines = append(p.lines, p.pos)
+}
+
+// write appends text, indented depth levels, to the current line, first
+// emitting any line breaks held back by newline.
+func (p *printer) write(depth int, text string) {
+	for ; p.pending > 0; p.pending-- {
+		p.buf.WriteByte('\n')
+		p.n++
+	}
+	if depth == 0 && p.name == "" {
+		if rest, ok := strings.CutPrefix(text, "fn "); ok {
+			p.name, _, _ = strings.Cut(rest, "(")
+		}
+	}
+	for range depth {
+		p.buf.WriteString(p.style.Indent)
+	}
+	p.buf.WriteString(text)
+	p.n += depth*len(p.style.Indent) + len(text)
+	if p.limit > 0 && p.n > p.limit && p.err == nil {
+		p.err = diag.ProgramTooLarge.Errorf("generated output exceeds the limit of %d bytes", p.limit)
+	}
+}
+
+// line writes one or more lines at the given depth. Multi-line text is split
+// and each line is re-indented; leading canonical indentation within a line
+// is added to depth. Empty lines are written without indentation.
+func (p *printer) line(depth int, text string) {
+	if p.err != nil {
+		return
+	}
+	for rest, more := text, true; more; {
+		var l string
+		l, rest, more = strings.Cut(rest, "\n")
+		d := depth
+		for strings.HasPrefix(l, canonicalIndent) {
+			d++
+			l = l[len(canonicalIndent):]
+		}
+		l = strings.TrimSpace(l)
+		if l == "" {
+			p.newline()
+			continue
+		}
+		p.write(d, l)
+		p.newline()
+	}
+}
+
+// position is where an item of a block stands, which decides its
+// terminator: a statement ends with a semicolon, and the result of the
+// block, the expression that is its value, does not.
+type position int
+
+const (
+	statementPos position = iota
+	resultPos
+)
+
+// statement writes text, one statement of a block, at the given depth,
+// ending it with exactly one semicolon.
+func (p *printer) statement(depth int, text string) {
+	p.line(depth, terminate(text, statementPos))
+}
+
+// block writes body, the items of a block one after another, at the given
+// depth: each a statement but the last, which is the block's result when
+// result is set. Blank lines of body are left out.
+func (p *printer) block(depth int, body string, result bool) {
+	for _, l := range strings.Split(terminateBlock(body, result), "\n") {
+		if strings.TrimSpace(l) != "" {
+			p.line(depth, l)
+		}
+	}
+}
+
+// terminateBlock returns body, the items of a block, with the terminator
+// each takes: a semicolon after every statement, and none after the last
+// item when it is the block's result. The blocks of match arms within an
+// item are terminated the same way, each arm's last item being its result.
+func terminateBlock(body string, result bool) string {
+	items := blockItems(body)
+	last := len(items) - 1
+	for last >= 0 && isComment(items[last]) {
+		last--
+	}
+	for i, item := range items {
+		pos := statementPos
+		if i == last && result {
+			pos = resultPos
+		}
+		items[i] = terminate(terminateArms(item), pos)
+	}
+	return strings.Join(items, "\n")
+}
+
+// terminate returns item, a statement or the result of a block over one or
+// more lines, ending with the terminator pos takes. A let, an assert! or a
+// const is a statement wherever it stands; a comment takes no terminator.
+func terminate(item string, pos position) string {
+	if isComment(item) {
+		return item
+	}
+	item = strings.TrimRight(item, " \t\r\n")
+	for strings.HasSuffix(item, ";") {
+		item = strings.TrimRight(strings.TrimSuffix(item, ";"), " \t")
+	}
+	if item == "" {
+		return item
+	}
+	if pos == statementPos || isDeclaration(item) {
+		return item + ";"
+	}
+	return item
+}
+
+// isDeclaration reports whether item is a let, an assert! or a const,
+// which are never the value of a block.
+func isDeclaration(item string) bool {
+	item = strings.TrimSpace(item)
+	for _, prefix := range []string{"let ", "let(", "assert!(", "const "} {
+		if strings.HasPrefix(item, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isComment reports whether every line of item is a comment or blank.
+func isComment(item string) bool {
+	for _, l := range strings.Split(item, "\n") {
+		if l = strings.TrimSpace(l); l != "" && !strings.HasPrefix(l, "//") {
+			return false
+		}
+	}
+	return true
+}
+
+// armPattern returns the pattern of the arm mc as the latest dialect writes
+// it: a variant, with its binding typed as every dialect requires, as in
+// Left(x: Type) or Some(x: Type), true or false, an integer where the
+// dialects have integer patterns, or _.
+func armPattern(mc MatchCase) string {
+	switch {
+	case mc.VarName == "":
+		return mc.Pattern
+	case mc.VarType == "":
+		return fmt.Sprintf("%s(%s)", mc.Pattern, mc.VarName)
+	}
+	return fmt.Sprintf("%s(%s: %s)", mc.Pattern, mc.VarName, mc.VarType)
+}
+
+// blockItems splits body, the items of a block rendered one after
+// another, into the items. An item ends with the line that closes every
+// bracket it opens, unless that line ends with an operator and the item
+// goes on, as an accumulation of match results does. A comment line is an
+// item of its own.
+func blockItems(body string) []string {
+	var items []string
+	var item []string
+	depth := 0
+	for _, l := range strings.Split(body, "\n") {
+		code := strings.TrimSpace(l)
+		if code == "" && len(item) == 0 {
+			continue
+		}
+		if strings.HasPrefix(code, "//") && len(item) == 0 {
+			items = append(items, l)
+			continue
+		}
+		item = append(item, l)
+		if comment := strings.Index(code, "//"); comment >= 0 {
+			code = strings.TrimSpace(code[:comment])
+		}
+		depth += strings.Count(code, "(") + strings.Count(code, "[") + strings.Count(code, "{") -
+			strings.Count(code, ")") - strings.Count(code, "]") - strings.Count(code, "}")
+		if depth <= 0 && !continues(code) {
+			items = append(items, strings.Join(item, "\n"))
+			item, depth = nil, 0
+		}
+	}
+	if len(item) > 0 {
+		items = append(items, strings.Join(item, "\n"))
+	}
+	return items
+}
+
+// continues reports whether the line code ends with an operator, and the
+// expression it starts goes on on the next line.
+func continues(code string) bool {
+	for _, op := range []string{"=", "+", "-", "*", "|", "&", ",", "=>"} {
+		if strings.HasSuffix(code, op) && !strings.HasSuffix(code, "->") {
+			return true
+		}
+	}
+	return false
+}
+
+// terminateArms returns item with the body of each block an arm of a
+// match opens, as in Some(sig: [u8; 64]) => {, terminated as a block whose
+// last item is the arm's value.
+func terminateArms(item string) string {
+	lines := strings.Split(item, "\n")
+	var out []string
+	for i := 0; i < len(lines); i++ {
+		out = append(out, lines[i])
+		if !strings.HasSuffix(strings.TrimSpace(lines[i]), "=> {") {
+			continue
+		}
+		end, depth := i+1, 1
+		for ; end < len(lines); end++ {
+			code := strings.TrimSpace(lines[end])
+			depth += strings.Count(code, "(") + strings.Count(code, "[") + strings.Count(code, "{") -
+				strings.Count(code, ")") - strings.Count(code, "]") - strings.Count(code, "}")
+			if depth <= 0 {
+				break
+			}
+		}
+		if end >= len(lines) {
+			continue // Not closed within item: left as it is
+		}
+		if body := strings.Join(lines[i+1:end], "\n"); strings.TrimSpace(body) != "" {
+			out = append(out, strings.Split(terminateBlock(body, true), "\n")...)
+		}
+		i = end - 1
+	}
+	return strings.Join(out, "\n")
+}
+
+// comment writes text as // comments at the given depth, one per line.
+// Empty lines of text become bare // lines.
+func (p *printer) comment(depth int, text string) {
+	if p.err != nil || text == "" {
+		return
+	}
+	for _, l := range strings.Split(text, "\n") {
+		l = strings.TrimRight(l, " \t")
+		if l != "" {
+			l = " " + l
+		}
+		p.write(depth, "//"+l)
+		p.newline()
+	}
+}
+
+// blank writes a single empty line.
+func (p *printer) blank() {
+	p.newline()
+}
+
+// separator writes the configured number of blank lines between top-level
+// items and flushes the item just completed.
+func (p *printer) separator() {
+	for i := 0; i < p.style.BlankLinesBetweenFuncs; i++ {
+		p.newline()
+	}
+	p.flush()
+}
+
+// flush hands the buffered output to the writer.
+func (p *printer) flush() {
+	if p.err != nil || p.buf.Len() == 0 {
+		return
+	}
+	p.nodes += CountNodes(p.buf.String())
+	if p.maxNodes > 0 && p.nodes > p.maxNodes {
+		p.err = p.sizeError()
+		return
+	}
+	if _, err := p.w.Write(p.buf.Bytes()); err != nil {
+		p.err = err
+	}
+	p.buf.Reset()
+	p.items = append(p.items, Item{Name: p.name, Line: p.item + 1, EndLine: len(p.lines)})
+	p.item, p.name = len(p.lines), ""
+}
+
+// Item is a top-level item of a rendered program, written as a whole: a
+// function with the doc comment above it, or the witness and param modules.
+type Item struct {
+	Name    string // SimplicityHL name of the function; "" for the modules
+	Line    int    // First line of the item, from 1
+	EndLine int    // Last line of the item, including the blank lines after it
+}
+
+// sizeError reports the buffered item bringing the program past maxNodes,
+// at the Go position of its first line that has one.
+func (p *printer) sizeError() error {
+	what := "the program"
+	for _, line := range strings.Split(p.buf.String(), "\n") {
+		if strings.HasPrefix(line, "fn ") {
+			what, _, _ = strings.Cut(line, "(")
+			break
+		}
+	}
+	msg := fmt.Sprintf("%s brings the generated program to %d SimplicityHL nodes, above the limit of %d", what, p.nodes, p.maxNodes)
+	for _, pos := range p.lines[p.item:] {
+		if pos.IsValid() && p.fset != nil {
+			return diag.ProgramTooLarge.Errorf("%s: %s", p.fset.Position(pos), msg)
+		}
+	}
+	return diag.ProgramTooLarge.Errorf("%s", msg)
+}
+
+// finish ends the program, applying the trailing-newline policy, and
+// reports the first error seen while rendering.
+func (p *printer) finish() error {
+	if p.style.TrailingNewline && p.n > 0 && p.err == nil {
+		p.buf.WriteByte('\n')
+	}
+	p.flush()
+	return p.err
+}
@@ -0,0 +1,160 @@
+package transpiler
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// A range over an integer constant, Go 1.22's
+//
+//	for i := range 4 { ... }  →  for i := 0; i < 4; i++ { ... }
+//
+// is the counted loop it abbreviates. Before analysis each one is rewritten
+// into that for loop, so every lowering of counted loops, unrolling and
+// accumulation trees among them, applies to it as well. A named constant,
+// as in range N with const N = 4, is replaced by its value, the literal
+// bound those lowerings read, as is the named bound of a counted loop
+// written out, for i := 0; i < N; i++.
+
+// RangeIntLoop returns the for loop that stmt, a range over an integer
+// literal or a named integer constant, abbreviates, counting with counter
+// if stmt declares no variable of its own. It reports false for any other
+// range statement, such as one over an array or a variable, or one
+// assigning to an existing variable with =.
+func RangeIntLoop(stmt *ast.RangeStmt, counter string) (*ast.ForStmt, bool) {
+	lit, ok := LoopBound(stmt.X)
+	if !ok || stmt.Value != nil {
+		return nil, false
+	}
+	name := counter
+	switch key := stmt.Key.(type) {
+	case nil:
+	case *ast.Ident:
+		if stmt.Tok != token.DEFINE {
+			return nil, false
+		}
+		if key.Name != "_" {
+			name = key.Name
+		}
+	default:
+		return nil, false
+	}
+	at := stmt.For
+	if stmt.Key != nil {
+		at = stmt.Key.Pos()
+	}
+	counterIdent := func() *ast.Ident { return &ast.Ident{NamePos: at, Name: name} }
+	return &ast.ForStmt{
+		For:  stmt.For,
+		Init: &ast.AssignStmt{Lhs: []ast.Expr{counterIdent()}, TokPos: stmt.TokPos, Tok: token.DEFINE, Rhs: []ast.Expr{&ast.BasicLit{ValuePos: lit.Pos(), Kind: token.INT, Value: "0"}}},
+		Cond: &ast.BinaryExpr{X: counterIdent(), OpPos: lit.Pos(), Op: token.LSS, Y: lit},
+		Post: &ast.IncDecStmt{X: counterIdent(), TokPos: lit.End(), Tok: token.INC},
+		Body: stmt.Body,
+	}, true
+}
+
+// LoopBound returns x, the bound of a range or of a counted loop, as an
+// integer literal at the position of x: x itself when it is one, or the
+// value of the constant it names. The value of a constant may be written
+// with other constants, iota and integer arithmetic.
+func LoopBound(x ast.Expr) (*ast.BasicLit, bool) {
+	if lit, ok := x.(*ast.BasicLit); ok {
+		return lit, lit.Kind == token.INT
+	}
+	ident, ok := x.(*ast.Ident)
+	if !ok {
+		return nil, false
+	}
+	v := constantValue(ident, -1, 0)
+	if v.Kind() != constant.Int || constant.Sign(v) < 0 {
+		return nil, false
+	}
+	return &ast.BasicLit{ValuePos: ident.Pos(), Kind: token.INT, Value: v.ExactString()}, true
+}
+
+// constantValue evaluates expr, in the value of a constant declared with
+// the given iota, as Go does, following the constants it names to depth. It
+// returns an Unknown value for anything else, such as a variable.
+func constantValue(expr ast.Expr, iota, depth int) constant.Value {
+	unknown := constant.MakeUnknown()
+	if depth > 16 {
+		return unknown
+	}
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind == token.INT {
+			return constant.MakeFromLiteral(e.Value, e.Kind, 0)
+		}
+	case *ast.ParenExpr:
+		return constantValue(e.X, iota, depth)
+	case *ast.Ident:
+		if e.Name == "iota" && e.Obj == nil && iota >= 0 {
+			return constant.MakeInt64(int64(iota))
+		}
+		if e.Obj == nil || e.Obj.Kind != ast.Con {
+			return unknown
+		}
+		spec, ok := e.Obj.Decl.(*ast.ValueSpec)
+		if !ok {
+			return unknown
+		}
+		for i, name := range spec.Names {
+			if name.Name == e.Name && i < len(spec.Values) {
+				n, _ := e.Obj.Data.(int)
+				return constantValue(spec.Values[i], n, depth+1)
+			}
+		}
+	case *ast.BinaryExpr:
+		x, y := constantValue(e.X, iota, depth), constantValue(e.Y, iota, depth)
+		if x.Kind() != constant.Int || y.Kind() != constant.Int {
+			return unknown
+		}
+		switch e.Op {
+		case token.ADD, token.SUB, token.MUL:
+			return constant.BinaryOp(x, e.Op, y)
+		case token.QUO:
+			if constant.Sign(y) != 0 {
+				return constant.BinaryOp(x, token.QUO_ASSIGN, y)
+			}
+		case token.SHL:
+			if s, ok := constant.Uint64Val(y); ok && s < 64 {
+				return constant.Shift(x, token.SHL, uint(s))
+			}
+		}
+	}
+	return unknown
+}
+
+// lowerRangeInts rewrites each range over an integer constant in file into
+// the for loop it abbreviates, and the named constant bound of each counted
+// loop into its value. A loop without a variable of its own counts with a
+// temporary.
+func (t *Transpiler) lowerRangeInts(file *ast.File) {
+	astutil.Apply(file, nil, func(c *astutil.Cursor) bool {
+		if loop, ok := c.Node().(*ast.ForStmt); ok {
+			if cond, ok := loop.Cond.(*ast.BinaryExpr); ok && (cond.Op == token.LSS || cond.Op == token.LEQ) {
+				if _, named := cond.Y.(*ast.Ident); named {
+					if lit, ok := LoopBound(cond.Y); ok {
+						cond.Y = lit
+					}
+				}
+			}
+			return true
+		}
+		stmt, ok := c.Node().(*ast.RangeStmt)
+		if !ok {
+			return true
+		}
+		var counter string
+		if key, _ := stmt.Key.(*ast.Ident); key == nil || key.Name == "_" {
+			counter = t.temp(stmt.For)
+		}
+		if loop, ok := RangeIntLoop(stmt, counter); ok {
+			c.Replace(loop)
+		}
+		return true
+	})
+}
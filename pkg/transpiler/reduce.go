@@ -0,0 +1,732 @@
+package transpiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	gotypes "go/types"
+	"math/big"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+)
+
+// A function that folds the elements of an array into one accumulator
+// with an associative operator, over constant bounds, is a reduction:
+//
+//	var total uint64
+//	for i := 0; i < 4; i++ {
+//		total += xs[i]
+//	}
+//	return total
+//
+// SimplicityHL has neither loops nor mutable locals, so the array is
+// destructured once and its elements combined pairwise, each level of the
+// tree halving their number. The result is an expression of depth log2(n)
+// rather than the chain of n that unrolling each iteration would give:
+//
+//	let [xs_0, xs_1, xs_2, xs_3]: [u64; 4] = xs;
+//	let (_, t_9_3): (bool, u64) = jet::add_64(xs_0, xs_1);
+//	let (_, t_9_3_2): (bool, u64) = jet::add_64(xs_2, xs_3);
+//	let (_, total): (bool, u64) = jet::add_64(t_9_3, t_9_3_2);
+//	total
+//
+// The intermediate sums are temporaries named after the loop (see
+// pkg/gensym).
+//
+// Addition wraps like Go's, so regrouping it is exact, as it is for |, &
+// and ^, and for && and || over a bool, ok = ok && a[i] == b[i], combined
+// by matches as translateLogical combines them. Every operand is computed,
+// so one of && or || that divides, which Go may skip, is not regrouped.
+// Counting the elements that meet a condition, if flags[i] {
+// count++ }, adds one 0 or 1 per element the same way. Any other update of
+// a single accumulator, such as total = total*31 + xs[i], is unrolled
+// instead: one binding per iteration, each reading the one before.
+//
+// The loop may be a nest of counted loops, each the body of the one around
+// it, such as one over the rows and columns of a [4][8]byte:
+//
+//	for i := 0; i < 4; i++ {
+//		for j := 0; j < 8; j++ {
+//			diff |= a[i][j] ^ b[i][j]
+//		}
+//	}
+//
+// Its iterations are those of the innermost loop for every index of the
+// loops around it, in the order Go runs them. Each array is destructured
+// into its rows and each row, on its first use, into its elements, so
+// a[1][2] becomes a_1_2. Every iteration is unrolled, so the nest may take at
+// most MaxUnrollIterations of them, counting the product of its bounds.
+
+// accumulation is a recognised loop over constant bounds that updates a
+// single accumulator, together with the declaration before it.
+type accumulation struct {
+	loopBounds
+	acc    string       // Go name of the accumulator
+	typ    string       // SimplicityHL type of the accumulator, "" until inferred
+	init   ast.Expr     // Declared value, or nil for the zero value
+	inner  []loopBounds // Loops nested in the loop, outermost first
+	iters  [][]int      // Indices of the iterations that run the update, in order, one per loop
+	update ast.Stmt     // The innermost loop body's one statement
+	pos    token.Pos
+}
+
+// loopBounds is a counted loop, for index := from; index < to; index++.
+type loopBounds struct {
+	index string // Go name of the loop index
+	from  int    // First index
+	to    int    // Index the loop stops before
+}
+
+// MaxUnrollIterations bounds the iterations the transpiler unrolls a loop
+// of a function into, the product of the bounds for a loop nest.
+const MaxUnrollIterations = 1 << 10
+
+// associativeOps are the operators whose reductions are regrouped into a
+// tree.
+var associativeOps = map[token.Token]bool{token.ADD: true, token.OR: true, token.AND: true, token.XOR: true, token.LAND: true, token.LOR: true}
+
+// matchAccumulation recognises decl, the accumulator's declaration, and
+// loop, a for loop from one constant to another, or a nest of them, whose
+// body updates the accumulator alone. A loop it recognises that takes more
+// than MaxUnrollIterations iterations is an error.
+func (t *Transpiler) matchAccumulation(decl, loop ast.Stmt) (*accumulation, bool, error) {
+	a := &accumulation{pos: loop.Pos()}
+	if !t.accumulatorDecl(decl, a) {
+		return nil, false, nil
+	}
+	l, ok := loop.(*ast.ForStmt)
+	if !ok || !t.countingLoop(l, &a.loopBounds) || a.index == a.acc {
+		return nil, false, nil
+	}
+	body := l.Body.List
+	for len(body) == 1 {
+		nested, ok := body[0].(*ast.ForStmt)
+		if !ok {
+			break
+		}
+		var b loopBounds
+		if !t.countingLoop(nested, &b) || b.index == a.acc || slices.ContainsFunc(a.loops(), func(outer loopBounds) bool { return outer.index == b.index }) {
+			return nil, false, nil
+		}
+		a.inner = append(a.inner, b)
+		body = nested.Body.List
+	}
+	if len(body) != 1 || writes(body[0]) != a.acc {
+		return nil, false, nil
+	}
+	a.update = body[0]
+	if rhs, ok := updateValue(a.update, a.acc); a.typ == "bool" && !ok {
+		return nil, false, nil
+	} else if a.typ == "bool" && divides(rhs) {
+		return nil, false, diag.UnsupportedSyntax.Wrap(t.errorAt(a.pos, "the loop is not lowered: every operand of %s is computed, and it divides, which may fail where Go skips it", gotypes.ExprString(rhs)))
+	}
+	if err := t.checkIterations(a); err != nil {
+		return nil, false, err
+	}
+	a.iters = a.span()
+	return a, true, nil
+}
+
+// loops returns the loop and those nested in it, outermost first.
+func (a *accumulation) loops() []loopBounds {
+	return append([]loopBounds{a.loopBounds}, a.inner...)
+}
+
+// iterations returns the number of iterations of the loop nest, the
+// factors it multiplies, and whether it is at most MaxUnrollIterations.
+func (a *accumulation) iterations() (int, []string, bool) {
+	n, factors := 1, []string(nil)
+	for _, b := range a.loops() {
+		n *= b.to - b.from
+		factors = append(factors, strconv.Itoa(b.to-b.from))
+		if n > MaxUnrollIterations {
+			return n, factors, false
+		}
+	}
+	return n, factors, true
+}
+
+// checkIterations fails when unrolling a takes more than
+// MaxUnrollIterations iterations. It names the outer loop, where the error
+// is reported, and for a nest the product of the bounds that exceeds the
+// limit.
+func (t *Transpiler) checkIterations(a *accumulation) error {
+	n, factors, ok := a.iterations()
+	if ok {
+		return nil
+	}
+	what := "the loop over " + a.index
+	if len(a.inner) > 0 {
+		what += " and the loops nested in it"
+	}
+	count := strconv.Itoa(n)
+	if len(factors) > 1 {
+		count = strings.Join(factors, " × ") + " = " + count
+	}
+	return diag.ProgramTooLarge.Wrap(t.errorAt(a.pos, "unrolling %s takes %s iterations, above the limit of %d", what, count, MaxUnrollIterations))
+}
+
+// span returns the indices of every iteration of the loop nest, in the
+// order Go runs them.
+func (a *accumulation) span() [][]int {
+	iters := [][]int{nil}
+	for _, b := range a.loops() {
+		next := make([][]int, 0, len(iters)*(b.to-b.from))
+		for _, at := range iters {
+			for k := b.from; k < b.to; k++ {
+				next = append(next, append(slices.Clip(at), k))
+			}
+		}
+		iters = next
+	}
+	return iters
+}
+
+// accumulatorDecl matches var acc T, var acc T = v and acc := v.
+func (t *Transpiler) accumulatorDecl(stmt ast.Stmt, a *accumulation) bool {
+	switch s := stmt.(type) {
+	case *ast.AssignStmt:
+		if s.Tok != token.DEFINE || len(s.Lhs) != 1 || len(s.Rhs) != 1 {
+			return false
+		}
+		ident, ok := s.Lhs[0].(*ast.Ident)
+		if !ok {
+			return false
+		}
+		a.acc, a.init = ident.Name, s.Rhs[0]
+	case *ast.DeclStmt:
+		gen, ok := s.Decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.VAR || len(gen.Specs) != 1 {
+			return false
+		}
+		spec := gen.Specs[0].(*ast.ValueSpec)
+		if len(spec.Names) != 1 || len(spec.Values) > 1 || spec.Type == nil && len(spec.Values) == 0 {
+			return false
+		}
+		a.acc = spec.Names[0].Name
+		if len(spec.Values) == 1 {
+			a.init = spec.Values[0]
+		}
+		if spec.Type != nil {
+			typ, err := t.mapType(spec.Type)
+			if err != nil {
+				return false
+			}
+			a.typ = typ
+		}
+	default:
+		return false
+	}
+	if a.init != nil && a.typ == "" {
+		if v, ok := t.folder.Fold(a.init); ok {
+			a.typ = v.Type
+		} else {
+			a.typ = carryFree(t.expr.TypeOf(a.init))
+		}
+	}
+	return a.acc != "_" && (a.typ == "" || isUIntType(a.typ) || a.typ == "bool")
+}
+
+// countingLoop matches for i := from; i < to; i++, and i <= to-1 as the
+// condition.
+func (t *Transpiler) countingLoop(loop *ast.ForStmt, a *loopBounds) bool {
+	init, ok := loop.Init.(*ast.AssignStmt)
+	if !ok || init.Tok != token.DEFINE || len(init.Lhs) != 1 || len(init.Rhs) != 1 {
+		return false
+	}
+	index, ok := init.Lhs[0].(*ast.Ident)
+	if !ok {
+		return false
+	}
+	from, ok := t.constantInt(init.Rhs[0])
+	if !ok || !from.IsInt64() {
+		return false
+	}
+	cond, ok := loop.Cond.(*ast.BinaryExpr)
+	if !ok || !isIdentNamed(cond.X, index.Name) || cond.Op != token.LSS && cond.Op != token.LEQ {
+		return false
+	}
+	to, ok := t.constantInt(cond.Y)
+	if !ok || !to.IsInt64() {
+		return false
+	}
+	if cond.Op == token.LEQ {
+		to = new(big.Int).Add(to, big.NewInt(1))
+	}
+	post, ok := loop.Post.(*ast.IncDecStmt)
+	if !ok || post.Tok != token.INC || !isIdentNamed(post.X, index.Name) {
+		return false
+	}
+	a.index, a.from, a.to = index.Name, int(from.Int64()), int(to.Int64())
+	return a.from >= 0 && a.from <= a.to
+}
+
+// arrayTypePattern matches a SimplicityHL array type, [T; N].
+var arrayTypePattern = regexp.MustCompile(`^\[(.+); (\d+)\]$`)
+
+// arrayType returns the element type and length of an array type.
+func arrayType(typ string) (string, int, bool) {
+	m := arrayTypePattern.FindStringSubmatch(typ)
+	if m == nil {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(m[2])
+	return m[1], n, err == nil
+}
+
+// writes returns the one name stmt assigns, increments or, as the body of
+// an if statement without an else, conditionally increments; "" when it
+// assigns none or more than one.
+func writes(stmt ast.Stmt) string {
+	switch s := stmt.(type) {
+	case *ast.AssignStmt:
+		if ident, ok := s.Lhs[0].(*ast.Ident); ok && len(s.Lhs) == 1 && len(s.Rhs) == 1 && s.Tok != token.DEFINE {
+			return ident.Name
+		}
+	case *ast.IncDecStmt:
+		if ident, ok := s.X.(*ast.Ident); ok {
+			return ident.Name
+		}
+	case *ast.IfStmt:
+		if s.Init == nil && s.Else == nil && len(s.Body.List) == 1 {
+			return writes(s.Body.List[0])
+		}
+	}
+	return ""
+}
+
+// isIdentNamed reports whether expr is the identifier name.
+func isIdentNamed(expr ast.Expr, name string) bool {
+	ident, ok := ast.Unparen(expr).(*ast.Ident)
+	return ok && ident.Name == name
+}
+
+// term returns the operator and the operand the update combines with the
+// accumulator when it is a reduction step, acc op= e, acc = acc op e or
+// acc = e op acc for an associative op and an e that does not read acc.
+func (a *accumulation) term() (token.Token, ast.Expr, bool) {
+	s, ok := a.update.(*ast.AssignStmt)
+	if !ok {
+		return 0, nil, false
+	}
+	op, e := token.ILLEGAL, ast.Expr(nil)
+	if s.Tok == token.ASSIGN {
+		bin, ok := ast.Unparen(s.Rhs[0]).(*ast.BinaryExpr)
+		if !ok {
+			return 0, nil, false
+		}
+		switch {
+		case isIdentNamed(bin.X, a.acc):
+			op, e = bin.Op, bin.Y
+		case isIdentNamed(bin.Y, a.acc):
+			op, e = bin.Op, bin.X
+		default:
+			return 0, nil, false
+		}
+	} else {
+		op, e = opAssignTokens[s.Tok], s.Rhs[0]
+	}
+	return op, e, associativeOps[op] && !reads(e, a.acc)
+}
+
+// lowerAccumulation returns the statements that compute the accumulator of
+// a and bind it to its name, which the statements after the loop read. It
+// reports false for an update it cannot lower.
+func (t *Transpiler) lowerAccumulation(a *accumulation) ([]string, bool) {
+	l := &reduction{a: a, lowering: derivedLowering{t: t, name: t.toSnakeCase(a.acc), pos: a.pos}}
+	ok := false
+	if cond, isCount := incrementIf(a.update, a.acc); isCount {
+		ok = l.count(cond)
+	} else if op, e, isTerm := a.term(); isTerm {
+		ok = l.tree(op, e)
+	} else {
+		ok = l.chain()
+	}
+	if !ok {
+		return nil, false
+	}
+	if a.typ == "" {
+		a.typ = "u32"
+	}
+	t.params[a.acc] = a.typ
+	return append(l.destructures, l.lines...), true
+}
+
+// reduction lowers the iterations of an accumulation loop.
+type reduction struct {
+	a            *accumulation
+	lowering     derivedLowering
+	arrays       []string // Arrays the iterations index, in order of use
+	destructures []string // Their destructuring bindings
+	lines        []string // Bindings of the iterations
+	flushed      int      // Jet calls of lowering already in lines
+}
+
+// flush binds the results of the jet calls lowered since the last flush.
+func (r *reduction) flush() {
+	for _, jc := range r.lowering.calls[r.flushed:] {
+		r.lines = append(r.lines, letStatement(jc, r.lowering.t.boundExpr(jc)))
+	}
+	r.flushed = len(r.lowering.calls)
+}
+
+// iteration returns expr as the iteration at, the indices of the loops
+// outermost first, computes it: with each index replaced by its value, and
+// each element of an array indexed by one by a local bound to that
+// element. It reports false when expr still refers to an index afterwards.
+func (r *reduction) iteration(expr ast.Expr, at []int, acc ast.Expr) (ast.Expr, bool) {
+	a := r.a
+	loops := a.loops()
+	loopOf := func(e ast.Expr) int {
+		return slices.IndexFunc(loops, func(b loopBounds) bool { return isIdentNamed(e, b.index) })
+	}
+	var replace func(ast.Expr) (ast.Expr, bool)
+	replace = func(e ast.Expr) (ast.Expr, bool) {
+		switch e := e.(type) {
+		case *ast.Ident:
+			if d := loopOf(e); d >= 0 {
+				return &ast.BasicLit{ValuePos: e.Pos(), Kind: token.INT, Value: strconv.Itoa(at[d])}, true
+			}
+			if e.Name == a.acc && acc != nil {
+				return acc, true
+			}
+		case *ast.IndexExpr:
+			d := loopOf(e.Index)
+			if d < 0 {
+				break
+			}
+			// The rows of a nest's arrays are elements too: a[i][j] indexes
+			// the local bound to a[i].
+			array, ok := e.X.(*ast.Ident)
+			if row, isRow := e.X.(*ast.IndexExpr); isRow {
+				x, replaced := replace(row)
+				array, ok = x.(*ast.Ident)
+				ok = ok && replaced
+			}
+			if ok {
+				if _, n, isArray := arrayType(r.symbolType(array.Name)); isArray && loops[d].to <= n {
+					return r.element(array.Name, d, at[d])
+				}
+			}
+		}
+		return nil, false
+	}
+	out := substitute(expr, replace)
+	if out == nil || slices.ContainsFunc(loops, func(b loopBounds) bool { return reads(out, b.index) }) {
+		return nil, false
+	}
+	return out, true
+}
+
+// symbolType returns the SimplicityHL type of the Go name, or "".
+func (r *reduction) symbolType(name string) string {
+	sym, _ := r.lowering.t.expr.symbols.Lookup(name)
+	return sym.Type
+}
+
+// element returns the local bound to element k of array, which the index
+// of loop d indexes, destructuring the array on its first use.
+func (r *reduction) element(array string, d, k int) (ast.Expr, bool) {
+	t := r.lowering.t
+	sym, ok := t.expr.symbols.Lookup(array)
+	elemType, n, isArray := arrayType(sym.Type)
+	if !ok || !isArray || k >= n {
+		return nil, false
+	}
+	prefix := t.toSnakeCase(array)
+	name := func(i int) string { return fmt.Sprintf("%s_%d", prefix, i) }
+	if !contains(r.arrays, array) {
+		r.arrays = append(r.arrays, array)
+		names := make([]string, n)
+		for i := range names {
+			names[i] = "_"
+			if slices.ContainsFunc(r.a.iters, func(at []int) bool { return at[d] == i }) {
+				names[i] = name(i)
+				t.params[name(i)] = elemType
+			}
+		}
+		r.destructures = append(r.destructures, fmt.Sprintf("let [%s]: %s = %s;", strings.Join(names, ", "), sym.Type, sym.Ref))
+	}
+	return ast.NewIdent(name(k)), true
+}
+
+// contains reports whether names holds name.
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// tree lowers acc op= e to a balanced tree of op jet calls over the
+// declared value, when it is not op's identity, and e in each iteration.
+func (r *reduction) tree(op token.Token, e ast.Expr) bool {
+	var refs []string
+	if r.a.init != nil && !r.identity(op, r.a.init) {
+		ref, ok := r.operand(r.a.init)
+		if !ok {
+			return false
+		}
+		refs = append(refs, ref)
+	}
+	for _, at := range r.a.iters {
+		term, ok := r.iteration(e, at, nil)
+		if !ok {
+			return false
+		}
+		ref, ok := r.operand(term)
+		if !ok {
+			return false
+		}
+		refs = append(refs, ref)
+	}
+	return r.combine(op, refs)
+}
+
+// count lowers if cond { acc++ } to a tree of additions of 1 for each
+// iteration whose condition holds and 0 for the others.
+func (r *reduction) count(cond ast.Expr) bool {
+	var refs []string
+	if r.a.init != nil && !r.identity(token.ADD, r.a.init) {
+		ref, ok := r.operand(r.a.init)
+		if !ok {
+			return false
+		}
+		refs = append(refs, ref)
+	}
+	for _, at := range r.a.iters {
+		c, ok := r.iteration(cond, at, nil)
+		if !ok {
+			return false
+		}
+		ref, err := r.lowering.t.expr.TranslateArg(c)
+		if err != nil || ref == placeholder {
+			return false
+		}
+		refs = append(refs, fmt.Sprintf("match %s { true => 1, false => 0, }", ref))
+	}
+	return r.combine(token.ADD, refs)
+}
+
+// operand lowers expr to an operand of the tree.
+func (r *reduction) operand(expr ast.Expr) (string, bool) {
+	ref, typ, ok := r.lowering.lower(expr, false)
+	r.flush()
+	r.infer(typ)
+	return ref, ok
+}
+
+// identity reports whether init is the identity of op: 0, for & the
+// value with every bit set, and true for && and false for ||.
+func (r *reduction) identity(op token.Token, init ast.Expr) bool {
+	v, ok := r.lowering.t.folder.Fold(init)
+	switch {
+	case !ok:
+		return false
+	case v.Int == nil:
+		return op == token.LAND && v.Bool || op == token.LOR && !v.Bool
+	}
+	if op != token.AND {
+		return v.Int.Sign() == 0
+	}
+	bits := uintBits(r.a.typ)
+	return bits > 0 && v.Int.Cmp(new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(bits)), big.NewInt(1))) == 0
+}
+
+// infer takes the accumulator's type from an operand when its declaration
+// leaves it untyped.
+func (r *reduction) infer(typ string) {
+	if r.a.typ == "" && isUIntType(typ) {
+		r.a.typ = typ
+	}
+}
+
+// combine binds the accumulator to refs combined level by level with op,
+// each level pairing neighbours and carrying an odd one out up to the next.
+func (r *reduction) combine(op token.Token, refs []string) bool {
+	if r.a.typ == "" {
+		r.a.typ = "u32"
+	}
+	typ, l := r.a.typ, &r.lowering
+	jet, _ := operatorToJetName(op, typ)
+	switch len(refs) {
+	case 0:
+		r.lines = append(r.lines, fmt.Sprintf("let %s: %s = %s;", l.name, typ, r.zero()))
+		return true
+	case 1:
+		r.lines = append(r.lines, fmt.Sprintf("let %s: %s = %s;", l.name, typ, refs[0]))
+		return true
+	}
+	for len(refs) > 1 {
+		var next []string
+		for i := 0; i+1 < len(refs); i += 2 {
+			jc := JetCall{
+				VarName:    l.t.temp(l.pos),
+				JetName:    jet,
+				Args:       refs[i] + ", " + refs[i+1],
+				ReturnType: operatorReturnType(op, typ),
+				Pos:        l.pos,
+			}
+			switch op {
+			case token.LAND:
+				jc.JetName, jc.Args, jc.ReturnType = "", fmt.Sprintf("match %s { true => %s, false => false, }", refs[i], refs[i+1]), typ
+			case token.LOR:
+				jc.JetName, jc.Args, jc.ReturnType = "", fmt.Sprintf("match %s { true => true, false => %s, }", refs[i], refs[i+1]), typ
+			}
+			if len(refs) == 2 {
+				jc.VarName = l.name
+			}
+			l.calls = append(l.calls, jc)
+			next = append(next, jc.VarName)
+		}
+		if len(refs)%2 == 1 {
+			next = append(next, refs[len(refs)-1])
+		}
+		refs = next
+	}
+	r.flush()
+	return true
+}
+
+// zero returns the accumulator's declared value, or its zero value.
+func (r *reduction) zero() string {
+	if r.a.init != nil {
+		if ref, ok := r.operand(r.a.init); ok {
+			return ref
+		}
+	}
+	if r.a.typ == "bool" {
+		return "false"
+	}
+	return "0"
+}
+
+// chain unrolls an update that is not a reduction step, binding the value
+// of the accumulator after each iteration to a local the next reads.
+func (r *reduction) chain() bool {
+	a, t := r.a, r.lowering.t
+	rhs, ok := updateValue(a.update, a.acc)
+	if !ok {
+		return false
+	}
+	acc := a.start()
+	ref := ""
+	for n, at := range a.iters {
+		step, ok := r.iteration(rhs, at, acc)
+		if !ok {
+			return false
+		}
+		calls := len(r.lowering.calls)
+		var typ string
+		if ref, typ, ok = r.lowering.lower(step, n == len(a.iters)-1); !ok {
+			return false
+		}
+		r.flush()
+		r.infer(typ)
+		if len(r.lowering.calls) == calls {
+			// An operand: the next iteration reads it directly.
+			acc = step
+			continue
+		}
+		t.params[ref] = typ
+		acc = ast.NewIdent(ref)
+	}
+	if ref == r.lowering.name {
+		return true
+	}
+	if a.typ == "" {
+		a.typ = "u32"
+	}
+	if len(a.iters) == 0 {
+		ref = r.zero()
+	}
+	r.lines = append(r.lines, fmt.Sprintf("let %s: %s = %s;", r.lowering.name, a.typ, ref))
+	return true
+}
+
+// updateValue returns the value update, an assignment, op-assignment,
+// increment or decrement of acc, gives it.
+func updateValue(update ast.Stmt, acc string) (ast.Expr, bool) {
+	switch s := update.(type) {
+	case *ast.AssignStmt:
+		if op, isOp := opAssignTokens[s.Tok]; isOp {
+			return &ast.BinaryExpr{X: ast.NewIdent(acc), OpPos: s.TokPos, Op: op, Y: s.Rhs[0]}, true
+		}
+		return s.Rhs[0], s.Tok == token.ASSIGN
+	case *ast.IncDecStmt:
+		e := incDecExpr(ast.NewIdent(acc), s.Tok)
+		e.OpPos = s.TokPos
+		return e, true
+	}
+	return nil, false
+}
+
+// start returns the value of the accumulator before the first iteration:
+// its declared value, or the zero of its type.
+func (a *accumulation) start() ast.Expr {
+	switch {
+	case a.init != nil:
+		return a.init
+	case a.typ == "bool":
+		return ast.NewIdent("false")
+	}
+	return &ast.BasicLit{Kind: token.INT, Value: "0"}
+}
+
+// substitute returns a copy of expr in which replace has replaced each
+// subexpression it reports true for, or nil when expr holds a kind of
+// expression it does not copy.
+func substitute(expr ast.Expr, replace func(ast.Expr) (ast.Expr, bool)) ast.Expr {
+	if expr == nil {
+		return nil
+	}
+	if out, ok := replace(expr); ok {
+		return out
+	}
+	all := func(exprs []ast.Expr) ([]ast.Expr, bool) {
+		out := make([]ast.Expr, len(exprs))
+		for i, e := range exprs {
+			if out[i] = substitute(e, replace); out[i] == nil {
+				return nil, false
+			}
+		}
+		return out, true
+	}
+	switch e := expr.(type) {
+	case *ast.Ident, *ast.BasicLit:
+		return e
+	case *ast.ParenExpr:
+		if x := substitute(e.X, replace); x != nil {
+			return &ast.ParenExpr{Lparen: e.Lparen, X: x, Rparen: e.Rparen}
+		}
+	case *ast.UnaryExpr:
+		if x := substitute(e.X, replace); x != nil {
+			return &ast.UnaryExpr{OpPos: e.OpPos, Op: e.Op, X: x}
+		}
+	case *ast.BinaryExpr:
+		x, y := substitute(e.X, replace), substitute(e.Y, replace)
+		if x != nil && y != nil {
+			return &ast.BinaryExpr{X: x, OpPos: e.OpPos, Op: e.Op, Y: y}
+		}
+	case *ast.SelectorExpr:
+		if x := substitute(e.X, replace); x != nil {
+			return &ast.SelectorExpr{X: x, Sel: e.Sel}
+		}
+	case *ast.IndexExpr:
+		x, index := substitute(e.X, replace), substitute(e.Index, replace)
+		if x != nil && index != nil {
+			return &ast.IndexExpr{X: x, Lbrack: e.Lbrack, Index: index, Rbrack: e.Rbrack}
+		}
+	case *ast.CallExpr:
+		if args, ok := all(e.Args); ok {
+			return &ast.CallExpr{Fun: e.Fun, Lparen: e.Lparen, Args: args, Ellipsis: e.Ellipsis, Rparen: e.Rparen}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package transpiler
+
+import (
+	"go/token"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+)
+
+// DefaultMaxNodes is the limit on the estimated size of a program that the
+// compiler applies unless configured otherwise. It is far above any
+// contract in examples/, and low enough that a loop unrolled or a helper
+// inlined by mistake fails within seconds instead of writing hundreds of
+// megabytes.
+const DefaultMaxNodes = 1 << 20
+
+// CountNodes estimates the size of SimplicityHL code: the number of
+// expressions in the bodies of its functions. Options.MaxNodes limits this
+// estimate, and the compiler reports it for each function. Code that does
+// not parse counts as 0.
+func CountNodes(code string) int {
+	prog, err := shlparse.Parse(code)
+	if err != nil {
+		return 0
+	}
+	n := 0
+	for _, fn := range prog.Funcs {
+		shlparse.Inspect(fn.Body, func(shlparse.Expr) bool {
+			n++
+			return true
+		})
+	}
+	return n
+}
+
+// fragmentNodes is CountNodes for a statement or expression rendered
+// ahead of time for a function body, not counting the block around it.
+func fragmentNodes(code string) int {
+	if n := CountNodes("fn fragment() {\n" + code + "\n}"); n > 0 {
+		return n - 1
+	}
+	return 0
+}
+
+// checkSize fails when code, generated times over for the construct at
+// pos that what describes, has an estimated size of its own above the
+// limit. Analysis calls it where code multiplies, such as an unrolled loop
+// or an inlined body, so that a program too large fails before it is
+// rendered; the printer limits the program as a whole. Each expression
+// takes at least a byte, so code shorter than the limit is not counted.
+func (t *Transpiler) checkSize(pos token.Pos, what string, times int, code ...string) error {
+	limit := t.printer.maxNodes
+	size := 0
+	for _, c := range code {
+		size += len(c)
+	}
+	if limit == 0 || size*times <= limit {
+		return nil
+	}
+	nodes := 0
+	for _, c := range code {
+		nodes += fragmentNodes(c)
+	}
+	if nodes*times > limit {
+		return diag.ProgramTooLarge.Wrap(t.errorAt(pos, "%s generates %d SimplicityHL nodes, above the limit of %d", what, nodes*times, limit))
+	}
+	return nil
+}
+
+// Nodes returns the estimated size of the most recent program, as
+// CountNodes counts it, or of the part rendered before generation failed.
+func (t *Transpiler) Nodes() int {
+	return t.printer.nodes
+}
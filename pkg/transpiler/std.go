@@ -0,0 +1,1007 @@
+package transpiler
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"go/ast"
+	"go/token"
+	gotypes "go/types"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/secp256k1"
+	simtypes "github.com/0ceanslim/go-simplicity/pkg/types"
+)
+
+// StdImportPath is the import path of the compiler-provided std package.
+const StdImportPath = "github.com/0ceanslim/go-simplicity/std"
+
+// stdFunc returns the name of the std function call calls.
+func (t *Transpiler) stdFunc(call *ast.CallExpr) (string, bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return "", false
+	}
+	path, ok := t.typeMapper.ImportPath(ident.Name)
+	return sel.Sel.Name, ok && path == StdImportPath
+}
+
+// foldBuiltinCall lets the folder evaluate std calls that run at compile
+// time, so that their results are inlined like other constants.
+func (t *Transpiler) foldBuiltinCall(call *ast.CallExpr) (Value, bool) {
+	name, ok := t.stdFunc(call)
+	if !ok {
+		return Value{}, false
+	}
+	switch name {
+	case "KeyAggCoefficientless":
+		v, err := t.aggregateKeys(call)
+		return v, err == nil
+	case "DivCeil":
+		return t.foldDivCeil(call)
+	case "HashFromHexRaw", "HashFromHexDisplay":
+		v, err := t.hashFromHex(name, call)
+		return v, err == nil
+	}
+	return Value{}, false
+}
+
+// stdCall translates a call of the std package.
+func (t *Transpiler) stdCall(name string, call *ast.CallExpr) (string, error) {
+	switch name {
+	case "KeyAggCoefficientless":
+		v, err := t.aggregateKeys(call)
+		if err != nil {
+			return "", err
+		}
+		return v.String(), nil
+	case "HashFromHexRaw", "HashFromHexDisplay":
+		v, err := t.hashFromHex(name, call)
+		if err != nil {
+			return "", err
+		}
+		return v.String(), nil
+	case "RequireOutput":
+		args, err := t.requireOutputArgs(call)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s(%s)", requireOutputHelperName, args), nil
+	case "CheckSequenceAtLeast":
+		arg, err := t.checkSequenceArg(call)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s(%s)", checkSequenceHelperName, arg), nil
+	case "OutputValueAtLeast":
+		args, err := t.outputValueArgs(call)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s(%s)", outputValueHelperName, args), nil
+	case "FeeAtMost":
+		arg, err := t.feeArg(call)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s(%s)", feeHelperName, arg), nil
+	case "ExplicitOutputValue", "ExplicitInputValue":
+		helper, arg, err := t.explicitValueCall(name, call)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s(%s)", helper, arg), nil
+	case "DivCeil":
+		if v, ok := t.foldDivCeil(call); ok {
+			return v.String(), nil
+		}
+		args, err := t.divCeilArgs(call)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s(%s)", divCeilHelperName, args), nil
+	case "IsZero20", "IsZero32", "IsZero64":
+		helper, arg, err := t.isZeroArg(name, call)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s(%s)", helper, arg), nil
+	case "TaggedHash":
+		ctx, err := t.taggedHashContext(call)
+		if err != nil {
+			return "", err
+		}
+		return formatJetCallExpr("sha_256_ctx_8_finalize", ctx), nil
+	case "Threshold":
+		helper, args, err := t.thresholdCall(call)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s(%s)", helper, args), nil
+	case "Verify":
+		cond, err := t.assertArg(name, call)
+		if err != nil {
+			return "", err
+		}
+		t.helpers[verifyHelperName] = true
+		return fmt.Sprintf("%s(%s)", verifyHelperName, cond), nil
+	case "Unreachable":
+		if err := t.unreachableArgs(call); err != nil {
+			return "", err
+		}
+		return formatJetCallExpr("panic", ""), nil
+	case "Assert":
+		return "", t.errorAt(call.Pos(), "std.Assert has no value and can only be called as a statement; std.Verify returns the condition")
+	case "Concat":
+		return "", t.errorAt(call.Pos(), "std.Concat builds the message of a std.TaggedHash and can only be passed to it")
+	case "Uint16Bytes", "Uint32Bytes", "Uint64Bytes":
+		part, err := t.uintBytes(name, call)
+		if err != nil {
+			return "", err
+		}
+		if v, ok := t.folder.Fold(call.Args[0]); ok && v.Int != nil {
+			return simtypes.EncodeInt(fmt.Sprintf("[u8; %d]", part.size), v.Int)
+		}
+		return "", t.errorAt(call.Pos(), "std.%s of a value known only at spend time must be assigned to a local of the entry function and hashed with std.TaggedHash", name)
+	}
+	return "", t.errorAt(call.Pos(), "%s has no function %s", StdImportPath, name)
+}
+
+// stdStatement records a std call used as a statement of the entry
+// function. It reports false for other statements.
+func (t *Transpiler) stdStatement(s *ast.ExprStmt) (bool, error) {
+	call, ok := s.X.(*ast.CallExpr)
+	if !ok {
+		return false, nil
+	}
+	name, ok := t.stdFunc(call)
+	if !ok {
+		return false, nil
+	}
+	var helper, args string
+	var err error
+	switch name {
+	case "RequireOutput":
+		helper = requireOutputHelperName
+		args, err = t.requireOutputArgs(call)
+	case "CheckSequenceAtLeast":
+		helper = checkSequenceHelperName
+		args, err = t.checkSequenceArg(call)
+	case "OutputValueAtLeast":
+		helper = outputValueHelperName
+		args, err = t.outputValueArgs(call)
+	case "FeeAtMost":
+		helper = feeHelperName
+		args, err = t.feeArg(call)
+	case "Assert", "Verify":
+		helper = "verify"
+		args, err = t.assertArg(name, call)
+	case "Unreachable":
+		helper = "panic"
+		err = t.unreachableArgs(call)
+	default:
+		_, err = t.stdCall(name, call)
+		if err == nil {
+			err = t.errorAt(call.Pos(), "std.%s is evaluated but not used", name)
+		}
+		return false, err
+	}
+	if err != nil {
+		return false, err
+	}
+	t.jetCalls = append(t.jetCalls, JetCall{
+		JetName: helper,
+		Args:    args,
+		Pos:     s.Pos(),
+	})
+	return true, nil
+}
+
+// assertArg checks and translates the condition of std.Assert or
+// std.Verify.
+func (t *Transpiler) assertArg(name string, call *ast.CallExpr) (string, error) {
+	if len(call.Args) != 1 {
+		return "", t.errorAt(call.Pos(), "std.%s takes a condition, got %d arguments", name, len(call.Args))
+	}
+	return t.expr.TranslateArg(call.Args[0])
+}
+
+// unreachableArgs checks that std.Unreachable is called without
+// arguments.
+func (t *Transpiler) unreachableArgs(call *ast.CallExpr) error {
+	if len(call.Args) != 0 {
+		return t.errorAt(call.Pos(), "std.Unreachable takes no arguments, got %d", len(call.Args))
+	}
+	return nil
+}
+
+// verifyHelperName is the SimplicityHL function std.Verify calls where its
+// value is used.
+const verifyHelperName = "std_verify"
+
+// verifyHelper asserts cond and returns it.
+const verifyHelper = `fn std_verify(cond: bool) -> bool {
+    assert!(cond);
+    cond
+}`
+
+// requireOutputHelperName is the SimplicityHL function std.RequireOutput
+// calls.
+const requireOutputHelperName = "std_require_output"
+
+// requireOutputHelper asserts that an output of the spending transaction
+// exists, pays exactly value in explicit amounts and has the given script
+// hash. A confidential amount fails the unwrap_right.
+const requireOutputHelper = `fn std_require_output(index: u32, value: u64, script_hash: u256) {
+    let script: u256 = unwrap(jet::output_script_hash(index));
+    assert!(jet::eq_256(script, script_hash));
+    let (_, c_amount): (Asset1, Amount1) = unwrap(jet::output_amount(index));
+    let amount: u64 = unwrap_right::<(u1, u256)>(c_amount);
+    assert!(jet::eq_64(amount, value));
+}`
+
+// requireOutputArgs checks and translates the arguments of
+// std.RequireOutput(i, value, scriptHash).
+func (t *Transpiler) requireOutputArgs(call *ast.CallExpr) (string, error) {
+	if len(call.Args) != 3 {
+		return "", t.errorAt(call.Pos(), "std.RequireOutput takes an output index, a value and a script hash, got %d arguments", len(call.Args))
+	}
+	args := make([]string, len(call.Args))
+	for i, arg := range call.Args {
+		a, err := t.expr.TranslateArg(arg)
+		if err != nil {
+			return "", err
+		}
+		args[i] = a
+	}
+	t.helpers[requireOutputHelperName] = true
+	return strings.Join(args, ", "), nil
+}
+
+// checkSequenceHelperName is the SimplicityHL function
+// std.CheckSequenceAtLeast calls.
+const checkSequenceHelperName = "std_check_sequence_at_least"
+
+// checkSequenceHelper asserts that the current input's nSequence encodes a
+// BIP-68 relative lock of at least blocks blocks: the transaction version
+// enables relative locks, the disable flag (bit 31) and the type flag (bit
+// 22, set for 512-second units) are clear and the low 16 bits reach blocks.
+const checkSequenceHelper = `fn std_check_sequence_at_least(blocks: u16) {
+    assert!(jet::le_32(2, jet::version()));
+    let sequence: u32 = jet::current_sequence();
+    assert!(jet::eq_32(jet::and_32(sequence, 0x80400000), 0));
+    let (_, distance): (u16, u16) = <u32>::into(sequence);
+    assert!(jet::le_16(blocks, distance));
+}`
+
+// checkSequenceArg checks and translates the argument of
+// std.CheckSequenceAtLeast(blocks). A constant must fit the 16-bit
+// distance field; anything above it would set the type flag, the disable
+// flag or bits BIP-68 reserves.
+func (t *Transpiler) checkSequenceArg(call *ast.CallExpr) (string, error) {
+	if len(call.Args) != 1 {
+		return "", t.errorAt(call.Pos(), "std.CheckSequenceAtLeast takes a number of blocks, got %d arguments", len(call.Args))
+	}
+	if v, ok := t.constantInt(call.Args[0]); ok && (v.Sign() < 0 || v.BitLen() > 16) {
+		return "", t.errorAt(call.Args[0].Pos(), "std.CheckSequenceAtLeast(%s): %#x sets bits outside the 16-bit BIP-68 block count (bit 22 is the type flag, bit 31 the disable flag, the others are reserved)", gotypes.ExprString(call.Args[0]), v)
+	}
+	arg, err := t.expr.TranslateArg(call.Args[0])
+	if err != nil {
+		return "", err
+	}
+	t.helpers[checkSequenceHelperName] = true
+	return arg, nil
+}
+
+// outputValueHelperName is the SimplicityHL function
+// std.OutputValueAtLeast calls.
+const outputValueHelperName = "std_output_value_at_least"
+
+// outputValueHelper asserts that an output of the spending transaction
+// exists and pays at least min in an explicit amount. A missing output
+// fails the unwrap, a confidential amount the unwrap_right.
+const outputValueHelper = `fn std_output_value_at_least(index: u32, min: u64) {
+    let (_, c_amount): (Asset1, Amount1) = unwrap(jet::output_amount(index));
+    let amount: u64 = unwrap_right::<(u1, u256)>(c_amount);
+    assert!(jet::le_64(min, amount));
+}`
+
+// explicitOutputHelperName and explicitInputHelperName are the SimplicityHL
+// functions std.ExplicitOutputValue and std.ExplicitInputValue call.
+const (
+	explicitOutputHelperName = "std_explicit_output_value"
+	explicitInputHelperName  = "std_explicit_input_value"
+)
+
+// explicitOutputHelper returns the value of an output, failing the spend
+// when the output is missing or its amount is confidential.
+const explicitOutputHelper = `fn std_explicit_output_value(index: u32) -> u64 {
+    let (_, c_amount): (Asset1, Amount1) = unwrap(jet::output_amount(index));
+    unwrap_right::<(u1, u256)>(c_amount)
+}`
+
+// explicitInputHelper is explicitOutputHelper for the inputs.
+const explicitInputHelper = `fn std_explicit_input_value(index: u32) -> u64 {
+    let (_, c_amount): (Asset1, Amount1) = unwrap(jet::input_amount(index));
+    unwrap_right::<(u1, u256)>(c_amount)
+}`
+
+// explicitValueCall checks and translates std.ExplicitOutputValue(k) or
+// std.ExplicitInputValue(k), returning the helper it calls.
+func (t *Transpiler) explicitValueCall(name string, call *ast.CallExpr) (helper, arg string, err error) {
+	helper = explicitOutputHelperName
+	if name == "ExplicitInputValue" {
+		helper = explicitInputHelperName
+	}
+	if len(call.Args) != 1 {
+		return "", "", t.errorAt(call.Pos(), "std.%s takes an index, got %d arguments", name, len(call.Args))
+	}
+	arg, err = t.expr.TranslateArg(call.Args[0])
+	if err != nil {
+		return "", "", err
+	}
+	t.helpers[helper] = true
+	return helper, arg, nil
+}
+
+// feeHelperName is the SimplicityHL function std.FeeAtMost calls.
+const feeHelperName = "std_fee_at_most"
+
+// feeHelper asserts that the fee outputs of the spending transaction pay
+// at most max in the asset of the input being spent.
+const feeHelper = `fn std_fee_at_most(max: u64) {
+    let asset: u256 = unwrap_right::<(u1, u256)>(jet::current_asset());
+    assert!(jet::le_64(jet::total_fee(asset), max));
+}`
+
+// checkedSubtractHelperName is the SimplicityHL function an amount written
+// as a difference, such as value-MaxFee, is computed with.
+const checkedSubtractHelperName = "std_checked_subtract_64"
+
+// checkedSubtractHelper returns a - b and fails the spend when b is larger,
+// where jet::subtract_64 alone would wrap around below zero.
+const checkedSubtractHelper = `fn std_checked_subtract_64(a: u64, b: u64) -> u64 {
+    let (borrow, difference): (bool, u64) = jet::subtract_64(a, b);
+    unwrap_left::<()>(<bool>::into(borrow));
+    difference
+}`
+
+// outputValueArgs checks and translates the arguments of
+// std.OutputValueAtLeast(k, min).
+func (t *Transpiler) outputValueArgs(call *ast.CallExpr) (string, error) {
+	if len(call.Args) != 2 {
+		return "", t.errorAt(call.Pos(), "std.OutputValueAtLeast takes an output index and a minimum value, got %d arguments", len(call.Args))
+	}
+	index, err := t.expr.TranslateArg(call.Args[0])
+	if err != nil {
+		return "", err
+	}
+	min, err := t.amountArg("OutputValueAtLeast", call.Args[1])
+	if err != nil {
+		return "", err
+	}
+	t.helpers[outputValueHelperName] = true
+	return index + ", " + min, nil
+}
+
+// feeArg checks and translates the argument of std.FeeAtMost(max).
+func (t *Transpiler) feeArg(call *ast.CallExpr) (string, error) {
+	if len(call.Args) != 1 {
+		return "", t.errorAt(call.Pos(), "std.FeeAtMost takes a maximum fee, got %d arguments", len(call.Args))
+	}
+	arg, err := t.amountArg("FeeAtMost", call.Args[0])
+	if err != nil {
+		return "", err
+	}
+	t.helpers[feeHelperName] = true
+	return arg, nil
+}
+
+// amountArg translates an amount passed to the std helper name. A constant
+// must fit u64 and is computed at compile time; a difference known only at
+// spend time is computed with std_checked_subtract_64.
+func (t *Transpiler) amountArg(name string, arg ast.Expr) (string, error) {
+	if v, ok := t.constantInt(arg); ok {
+		if v.Sign() < 0 || v.BitLen() > 64 {
+			return "", t.errorAt(arg.Pos(), "std.%s: %s is %s, which is not a u64 amount", name, gotypes.ExprString(arg), v)
+		}
+		if _, named := arg.(*ast.Ident); !named {
+			return v.String(), nil
+		}
+		return t.expr.TranslateArg(arg)
+	}
+	expr := ast.Unparen(arg)
+	if bin, ok := expr.(*ast.BinaryExpr); ok && bin.Op == token.SUB {
+		a, err := t.amountArg(name, bin.X)
+		if err != nil {
+			return "", err
+		}
+		b, err := t.amountArg(name, bin.Y)
+		if err != nil {
+			return "", err
+		}
+		t.helpers[checkedSubtractHelperName] = true
+		return fmt.Sprintf("%s(%s, %s)", checkedSubtractHelperName, a, b), nil
+	}
+	return t.expr.TranslateArg(arg)
+}
+
+// divCeilHelperName is the SimplicityHL function std.DivCeil calls.
+const divCeilHelperName = "std_div_ceil_64"
+
+// divCeilHelper divides a by b and rounds the quotient up when there is a
+// remainder. The increment cannot carry: a remainder means b is at least
+// 2. jet::divide_64 returns 0 for a zero divisor, so that case is
+// asserted away, as Go's division panics.
+const divCeilHelper = `fn std_div_ceil_64(a: u64, b: u64) -> u64 {
+    assert!(jet::lt_64(0, b));
+    let quotient: u64 = jet::divide_64(a, b);
+    match jet::is_zero_64(jet::modulo_64(a, b)) {
+        true => quotient,
+        false => {
+            let (_, rounded): (bool, u64) = jet::add_64(quotient, 1);
+            rounded
+        },
+    }
+}`
+
+// divCeilArgs checks and translates the arguments of std.DivCeil(a, b)
+// when they are not both constants. A constant divisor must not be zero.
+func (t *Transpiler) divCeilArgs(call *ast.CallExpr) (string, error) {
+	if len(call.Args) != 2 {
+		return "", t.errorAt(call.Pos(), "std.DivCeil takes a dividend and a divisor, got %d arguments", len(call.Args))
+	}
+	if v, ok := t.constantInt(call.Args[1]); ok && v.Sign() == 0 {
+		return "", diag.DivisionByZero.Wrap(t.errorAt(call.Args[1].Pos(), "std.DivCeil(%s, %s) divides by zero", gotypes.ExprString(call.Args[0]), gotypes.ExprString(call.Args[1])))
+	}
+	args := make([]string, len(call.Args))
+	for i, arg := range call.Args {
+		a, err := t.expr.TranslateArg(arg)
+		if err != nil {
+			return "", err
+		}
+		args[i] = a
+	}
+	t.helpers[divCeilHelperName] = true
+	return strings.Join(args, ", "), nil
+}
+
+// isZeroSizes are the byte array lengths of std.IsZero20, IsZero32 and
+// IsZero64.
+var isZeroSizes = []int{20, 32, 64}
+
+// isZeroHelperName returns the SimplicityHL function std.IsZero<n> calls.
+func isZeroHelperName(n int) string {
+	return fmt.Sprintf("std_is_zero_%d", n)
+}
+
+// isZeroHelper defines std_is_zero_<n>, which compares an n-byte array
+// with zero in parts of at most 32 bytes, each a power of two long so that
+// it casts to an integer an eq jet compares: one u256 for 32 bytes, a u128
+// and a u32 for 20. A part is compared only when those before it are zero.
+func isZeroHelper(n int) string {
+	var parts []int
+	for rest := n; rest > 0; rest -= parts[len(parts)-1] {
+		size := 32
+		for size > rest {
+			size /= 2
+		}
+		parts = append(parts, size)
+	}
+	array := fmt.Sprintf("[u8; %d]", n)
+	lines := []string{fmt.Sprintf("fn %s(x: %s) -> bool {", isZeroHelperName(n), array)}
+	if len(parts) == 1 {
+		lines = append(lines, fmt.Sprintf("%s%s(<%s>::into(x), 0)", canonicalIndent, jetRef(fmt.Sprintf("eq_%d", 8*n)), array))
+		return strings.Join(append(lines, "}"), "\n")
+	}
+	bytes := make([]string, n)
+	for i := range bytes {
+		bytes[i] = fmt.Sprintf("x_%d", i)
+	}
+	lines = append(lines, fmt.Sprintf("%slet [%s]: %s = x;", canonicalIndent, strings.Join(bytes, ", "), array))
+	tests := make([]string, len(parts))
+	start := 0
+	for i, size := range parts {
+		lines = append(lines, fmt.Sprintf("%slet part_%d: u%d = <[u8; %d]>::into([%s]);", canonicalIndent, i, 8*size, size, strings.Join(bytes[start:start+size], ", ")))
+		tests[i] = fmt.Sprintf("%s(part_%d, 0)", jetRef(fmt.Sprintf("eq_%d", 8*size)), i)
+		start += size
+	}
+	result := []string{tests[len(tests)-1]}
+	for i := len(tests) - 2; i >= 0; i-- {
+		arm := []string{"true => " + result[0] + ","}
+		if len(result) > 1 {
+			arm = []string{"true => {"}
+			for _, line := range result {
+				arm = append(arm, canonicalIndent+line)
+			}
+			arm = append(arm, "},")
+		}
+		result = append([]string{fmt.Sprintf("match %s {", tests[i])}, indentLines(arm)...)
+		result = append(result, canonicalIndent+"false => false,", "}")
+	}
+	return strings.Join(append(append(lines, indentLines(result)...), "}"), "\n")
+}
+
+// indentLines indents each of lines one level.
+func indentLines(lines []string) []string {
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		out[i] = canonicalIndent + line
+	}
+	return out
+}
+
+// isZeroArg checks and translates the argument of std.IsZero<n>, marking
+// its helper as called.
+func (t *Transpiler) isZeroArg(name string, call *ast.CallExpr) (helper, arg string, err error) {
+	if len(call.Args) != 1 {
+		return "", "", t.errorAt(call.Pos(), "std.%s takes one byte array, got %d arguments", name, len(call.Args))
+	}
+	n, _ := strconv.Atoi(strings.TrimPrefix(name, "IsZero"))
+	if sym, ok := t.expr.lookupPath(call.Args[0]); ok && sym.Type != fmt.Sprintf("[u8; %d]", n) {
+		return "", "", t.errorAt(call.Args[0].Pos(), "std.%s takes a [%d]byte, but %s is %s", name, n, gotypes.ExprString(call.Args[0]), sym.Type)
+	}
+	if arg, err = t.expr.TranslateArg(call.Args[0]); err != nil {
+		return "", "", err
+	}
+	helper = isZeroHelperName(n)
+	t.helpers[helper] = true
+	return helper, arg, nil
+}
+
+// foldDivCeil computes std.DivCeil(a, b) when both operands are constants
+// that fit u64 and b is not zero.
+func (t *Transpiler) foldDivCeil(call *ast.CallExpr) (Value, bool) {
+	if len(call.Args) != 2 {
+		return Value{}, false
+	}
+	a, okA := t.constantInt(call.Args[0])
+	b, okB := t.constantInt(call.Args[1])
+	if !okA || !okB || b.Sign() == 0 {
+		return Value{}, false
+	}
+	for _, v := range []*big.Int{a, b} {
+		if v.Sign() < 0 || v.BitLen() > 64 {
+			return Value{}, false
+		}
+	}
+	q, r := new(big.Int).QuoRem(a, b, new(big.Int))
+	if r.Sign() != 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	return Value{Type: "u64", Int: q}, true
+}
+
+// inputIsIssuanceHelperName is the SimplicityHL function jet.InputIsIssuance
+// lowers to.
+const inputIsIssuanceHelperName = "std_input_is_issuance"
+
+// inputIsIssuanceHelper reports whether the input at index carries an
+// issuance or a reissuance. jet::issuance tells the two apart in its inner
+// Option, which is None when the input has neither; the outer Option is
+// None when there is no input at index, which fails the spend.
+const inputIsIssuanceHelper = `fn std_input_is_issuance(index: u32) -> bool {
+    match unwrap(jet::issuance(index)) {
+        Some(reissuance: bool) => true,
+        None => false,
+    }
+}`
+
+// jetHelpers are the compiler helpers registered as jets, in package jet
+// rather than std; they are emitted wherever the program calls them.
+var jetHelpers = []string{inputIsIssuanceHelperName}
+
+// markJetHelpers marks the jetHelpers that the jet calls, the match arms or
+// the functions of the program call.
+func (t *Transpiler) markJetHelpers() {
+	var texts []string
+	for _, jc := range t.jetCalls {
+		texts = append(texts, jc.JetName+"(", jc.Args)
+	}
+	var walk func(m *MatchExpression)
+	walk = func(m *MatchExpression) {
+		texts = append(texts, m.Scrutinee)
+		for _, c := range m.Cases {
+			texts = append(texts, c.BodyStmts...)
+			if c.Nested != nil {
+				walk(c.Nested)
+			}
+		}
+	}
+	for _, m := range t.matchExprs {
+		walk(m)
+	}
+	for _, function := range t.functions {
+		texts = append(texts, function.Body)
+	}
+	for _, name := range jetHelpers {
+		for _, text := range texts {
+			if strings.Contains(text, name+"(") {
+				t.helpers[name] = true
+				break
+			}
+		}
+	}
+}
+
+// compilerHelper is a SimplicityHL function that the compiler emits when the
+// program calls the std, std/bitcoin or jet helper behind it.
+type compilerHelper struct {
+	name string
+	def  string
+}
+
+// compilerHelpers are emitted in this order, ahead of the user functions.
+var compilerHelpers = []compilerHelper{
+	{xOnlyHelperName, xOnlyHelper},
+	{requireOutputHelperName, requireOutputHelper},
+	{outputValueHelperName, outputValueHelper},
+	{explicitOutputHelperName, explicitOutputHelper},
+	{explicitInputHelperName, explicitInputHelper},
+	{feeHelperName, feeHelper},
+	{checkedSubtractHelperName, checkedSubtractHelper},
+	{divCeilHelperName, divCeilHelper},
+	{isZeroHelperName(20), isZeroHelper(20)},
+	{isZeroHelperName(32), isZeroHelper(32)},
+	{isZeroHelperName(64), isZeroHelper(64)},
+	{checkSequenceHelperName, checkSequenceHelper},
+	{taggedHashHelperName, taggedHashHelper},
+	{inputIsIssuanceHelperName, inputIsIssuanceHelper},
+	{verifyHelperName, verifyHelper},
+}
+
+// isCompilerHelper reports whether name is one of compilerHelpers, which
+// are called like user functions rather than jets.
+func isCompilerHelper(name string) bool {
+	for _, h := range compilerHelpers {
+		if h.name == name {
+			return true
+		}
+	}
+	return strings.HasPrefix(name, thresholdHelperPrefix) || isCheckedDivision(name)
+}
+
+// emitCompilerHelpers emits the compiler helpers the program calls.
+func (t *Transpiler) emitCompilerHelpers() {
+	t.markJetHelpers()
+	for _, h := range compilerHelpers {
+		if t.helpers[h.name] {
+			t.emit(0, h.def)
+			t.printer.separator()
+		}
+	}
+	for _, def := range t.thresholdHelpers() {
+		t.emit(0, def)
+		t.printer.separator()
+	}
+	for _, def := range t.checkedDivisionHelpers() {
+		t.emit(0, def)
+		t.printer.separator()
+	}
+}
+
+// aggregateKeys computes std.KeyAggCoefficientless(a, b) at compile time.
+func (t *Transpiler) aggregateKeys(call *ast.CallExpr) (Value, error) {
+	if len(call.Args) != 2 {
+		return Value{}, t.errorAt(call.Pos(), "std.KeyAggCoefficientless takes two keys, got %d", len(call.Args))
+	}
+	keys := make([][]byte, len(call.Args))
+	for i, arg := range call.Args {
+		key, ok := t.constantKey(arg)
+		if !ok {
+			return Value{}, t.errorAt(arg.Pos(), "std.KeyAggCoefficientless: %s is not a compile-time constant 32-byte key; keys are aggregated by the compiler, not the program",
+				gotypes.ExprString(arg))
+		}
+		keys[i] = key
+	}
+	agg, err := secp256k1.KeyAggXOnly(keys)
+	if err != nil {
+		return Value{}, t.errorAt(call.Pos(), "std.KeyAggCoefficientless: %v", err)
+	}
+	return Value{Type: "u256", Int: new(big.Int).SetBytes(agg)}, nil
+}
+
+// hashFromHex decodes the hex string of std.HashFromHexRaw(s) or
+// std.HashFromHexDisplay(s), the latter reversing its bytes, at compile
+// time.
+func (t *Transpiler) hashFromHex(name string, call *ast.CallExpr) (Value, error) {
+	if len(call.Args) != 1 {
+		return Value{}, t.errorAt(call.Pos(), "std.%s takes one hex string, got %d arguments", name, len(call.Args))
+	}
+	b, ok := t.constantHexString(call.Args[0])
+	if !ok || len(b) != 32 {
+		return Value{}, t.errorAt(call.Args[0].Pos(), "std.%s: %s is not a string of 64 hex digits; the compiler decodes the hash, so it must be a string literal or a constant",
+			name, gotypes.ExprString(call.Args[0]))
+	}
+	if name == "HashFromHexDisplay" {
+		slices.Reverse(b)
+	}
+	return Value{Type: "u256", Int: new(big.Int).SetBytes(b)}, nil
+}
+
+// constantHexString returns the bytes expr spells when it is a hex string
+// literal or a constant whose value is one.
+func (t *Transpiler) constantHexString(expr ast.Expr) ([]byte, bool) {
+	if ident, ok := expr.(*ast.Ident); ok {
+		if _, local := t.folder.Local(ident.Name); !local {
+			if value, ok := t.constDecls[ident.Name]; ok {
+				return t.constantHexString(value)
+			}
+		}
+	}
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return nil, false
+	}
+	b, err := simtypes.DecodeHexString(lit.Value)
+	return b, err == nil
+}
+
+// constantInt returns the value of expr when it is an integer known at
+// compile time, looking through the program's constants, which are params
+// rather than folded values, and through sums, differences and products
+// of them.
+func (t *Transpiler) constantInt(expr ast.Expr) (*big.Int, bool) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if _, local := t.folder.Local(e.Name); !local {
+			if value, ok := t.constDecls[e.Name]; ok {
+				return t.constantInt(value)
+			}
+		}
+	case *ast.ParenExpr:
+		return t.constantInt(e.X)
+	case *ast.BinaryExpr:
+		if e.Op == token.ADD || e.Op == token.SUB || e.Op == token.MUL {
+			x, okX := t.constantInt(e.X)
+			y, okY := t.constantInt(e.Y)
+			if !okX || !okY {
+				return nil, false
+			}
+			switch e.Op {
+			case token.ADD:
+				return new(big.Int).Add(x, y), true
+			case token.SUB:
+				return new(big.Int).Sub(x, y), true
+			}
+			return new(big.Int).Mul(x, y), true
+		}
+	}
+	v, ok := t.folder.Fold(expr)
+	return v.Int, ok && v.Int != nil
+}
+
+// constantKey returns the 32 bytes of a key known at compile time: an
+// integer or hex string literal, a constant of the file or a local the
+// folder knows.
+func (t *Transpiler) constantKey(expr ast.Expr) ([]byte, bool) {
+	if ident, ok := expr.(*ast.Ident); ok {
+		if _, local := t.folder.Local(ident.Name); !local {
+			if value, ok := t.constDecls[ident.Name]; ok {
+				return t.constantKey(value)
+			}
+		}
+	}
+	if lit, ok := expr.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+		b, err := simtypes.DecodeHexString(lit.Value)
+		return b, err == nil && len(b) == 32
+	}
+	v, ok := t.folder.Fold(expr)
+	if !ok || v.Int == nil || v.Int.BitLen() > 256 {
+		return nil, false
+	}
+	return v.Int.FillBytes(make([]byte, 32)), true
+}
+
+// fileConstants returns the values of the constants file declares, by Go
+// name.
+func fileConstants(file *ast.File) map[string]ast.Expr {
+	consts := make(map[string]ast.Expr)
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			valueSpec := spec.(*ast.ValueSpec)
+			for i, name := range valueSpec.Names {
+				if i < len(valueSpec.Values) && !strings.HasPrefix(name.Name, "_") {
+					consts[name.Name] = valueSpec.Values[i]
+				}
+			}
+		}
+	}
+	return consts
+}
+
+// bytePart is one piece of a message hashed by std.TaggedHash: a
+// SimplicityHL value and its size in bytes.
+type bytePart struct {
+	value string
+	size  int
+}
+
+// uintBytesSizes are the sizes of the std.UintNBytes encodings.
+var uintBytesSizes = map[string]int{"Uint16Bytes": 2, "Uint32Bytes": 4, "Uint64Bytes": 8}
+
+// uintBytes translates std.Uint16Bytes, Uint32Bytes or Uint64Bytes. The
+// integer itself is the part: its bits are the big-endian bytes the
+// SHA-256 jets hash.
+func (t *Transpiler) uintBytes(name string, call *ast.CallExpr) (bytePart, error) {
+	if len(call.Args) != 1 {
+		return bytePart{}, t.errorAt(call.Pos(), "std.%s takes one integer, got %d arguments", name, len(call.Args))
+	}
+	value, err := t.expr.TranslateArg(call.Args[0])
+	if err != nil {
+		return bytePart{}, err
+	}
+	return bytePart{value: value, size: uintBytesSizes[name]}, nil
+}
+
+// stdBinding records name := std.UintNBytes(v) or a std call with a value,
+// such as std.TaggedHash(...), in the entry function. A UintNBytes local is
+// not emitted: slicing it into std.Concat hashes v. The others are bound
+// like a jet result.
+func (t *Transpiler) stdBinding(name string, s *ast.AssignStmt) (bool, error) {
+	call, ok := s.Rhs[0].(*ast.CallExpr)
+	if !ok {
+		return false, nil
+	}
+	fn, ok := t.stdFunc(call)
+	if !ok {
+		return false, nil
+	}
+	if _, sized := uintBytesSizes[fn]; sized {
+		part, err := t.uintBytes(fn, call)
+		if err != nil {
+			return false, err
+		}
+		t.byteParts[name] = part
+		return true, nil
+	}
+	var helper, args, returnType string
+	var err error
+	switch fn {
+	case "ExplicitOutputValue", "ExplicitInputValue":
+		helper, args, err = t.explicitValueCall(fn, call)
+		returnType = "u64"
+	case "DivCeil":
+		helper, returnType = divCeilHelperName, "u64"
+		args, err = t.divCeilArgs(call)
+	case "IsZero20", "IsZero32", "IsZero64":
+		helper, args, err = t.isZeroArg(fn, call)
+		returnType = "bool"
+	case "Threshold":
+		helper, args, err = t.thresholdCall(call)
+		returnType = "bool"
+	case "Verify":
+		args, err = t.assertArg(fn, call)
+		helper, returnType = verifyHelperName, "bool"
+		t.helpers[verifyHelperName] = true
+	}
+	if err != nil {
+		return false, err
+	}
+	if helper != "" {
+		t.jetCalls = append(t.jetCalls, JetCall{
+			VarName:    t.toSnakeCase(name),
+			JetName:    helper,
+			Args:       args,
+			ReturnType: returnType,
+			Pos:        s.Pos(),
+		})
+		return true, nil
+	}
+	if fn != "TaggedHash" {
+		return false, nil
+	}
+	ctx, err := t.taggedHashContext(call)
+	if err != nil {
+		return false, err
+	}
+	t.jetCalls = append(t.jetCalls, JetCall{
+		VarName:    t.toSnakeCase(name),
+		JetName:    "sha_256_ctx_8_finalize",
+		Args:       ctx,
+		ReturnType: "u256",
+		Pos:        s.Pos(),
+	})
+	return true, nil
+}
+
+// taggedHashHelperName is the SimplicityHL function that starts the
+// SHA-256 context of a std.TaggedHash.
+const taggedHashHelperName = "std_tagged_hash_init"
+
+// taggedHashHelper adds the hash of the tag twice, the 64-byte prefix of
+// every BIP-340 tagged hash.
+const taggedHashHelper = `fn std_tagged_hash_init(tag_hash: u256) -> Ctx8 {
+    let ctx: Ctx8 = jet::sha_256_ctx_8_add_32(jet::sha_256_ctx_8_init(), tag_hash);
+    jet::sha_256_ctx_8_add_32(ctx, tag_hash)
+}`
+
+// sha256AddSizes are the part sizes a sha_256_ctx_8_add_N jet exists for.
+var sha256AddSizes = map[int]bool{1: true, 2: true, 4: true, 8: true, 16: true, 32: true, 64: true, 128: true, 256: true, 512: true}
+
+// taggedHashContext translates std.TaggedHash(tag, msg) up to the final
+// sha_256_ctx_8_finalize: the tag is hashed here, and each part of msg is
+// added to the context with the jet of its size.
+func (t *Transpiler) taggedHashContext(call *ast.CallExpr) (string, error) {
+	if len(call.Args) != 2 {
+		return "", t.errorAt(call.Pos(), "std.TaggedHash takes a tag and a message, got %d arguments", len(call.Args))
+	}
+	lit, ok := call.Args[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", t.errorAt(call.Args[0].Pos(), "std.TaggedHash takes its tag as a string literal, which the compiler hashes")
+	}
+	tag, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return "", t.errorAt(lit.Pos(), "std.TaggedHash: invalid tag %s", lit.Value)
+	}
+	parts := []ast.Expr{call.Args[1]}
+	if concat, ok := call.Args[1].(*ast.CallExpr); ok {
+		if name, ok := t.stdFunc(concat); ok && name == "Concat" {
+			parts = concat.Args
+		}
+	}
+
+	tagHash := sha256.Sum256([]byte(tag))
+	ctx := fmt.Sprintf("%s(0x%x)", taggedHashHelperName, tagHash)
+	for _, expr := range parts {
+		part, err := t.messagePart(expr)
+		if err != nil {
+			return "", err
+		}
+		ctx = formatJetCallExpr(fmt.Sprintf("sha_256_ctx_8_add_%d", part.size), ctx+", "+part.value)
+	}
+	t.helpers[taggedHashHelperName] = true
+	return ctx, nil
+}
+
+// messagePart resolves one part of a std.TaggedHash message: a whole byte
+// array sliced with [:], either a local bound to std.UintNBytes or a value
+// of byte array or integer type.
+func (t *Transpiler) messagePart(expr ast.Expr) (bytePart, error) {
+	slice, ok := expr.(*ast.SliceExpr)
+	if !ok || slice.Low != nil || slice.High != nil || slice.Max != nil {
+		return bytePart{}, t.errorAt(expr.Pos(), "std.TaggedHash: %s is not a whole byte array such as data[:]", gotypes.ExprString(expr))
+	}
+	if ident, ok := slice.X.(*ast.Ident); ok {
+		if part, ok := t.byteParts[ident.Name]; ok {
+			return part, nil
+		}
+	}
+	size := byteSize(t.inferArgType(slice.X))
+	if size == 0 {
+		return bytePart{}, t.errorAt(expr.Pos(), "std.TaggedHash: cannot tell the size of %s", gotypes.ExprString(slice.X))
+	}
+	if !sha256AddSizes[size] {
+		return bytePart{}, t.errorAt(expr.Pos(), "std.TaggedHash: %s is %d bytes; parts must be 1, 2, 4, 8, 16, 32, 64, 128, 256 or 512 bytes, one SHA-256 jet each", gotypes.ExprString(slice.X), size)
+	}
+	value, err := t.expr.Translate(slice.X)
+	if err != nil {
+		return bytePart{}, err
+	}
+	return bytePart{value: value, size: size}, nil
+}
+
+// byteSize is the size in bytes of a byte array or integer type, or 0.
+func byteSize(simType string) int {
+	if n, ok := strings.CutPrefix(simType, "[u8; "); ok {
+		size, _ := strconv.Atoi(strings.TrimSuffix(n, "]"))
+		return size
+	}
+	if n, ok := strings.CutPrefix(simType, "u"); ok {
+		if bits, err := strconv.Atoi(n); err == nil && bits%8 == 0 {
+			return bits / 8
+		}
+	}
+	return 0
+}
@@ -0,0 +1,452 @@
+package transpiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// structField is a field of a plain struct type, which SimplicityHL
+// represents as a tuple of its fields in declaration order. The fields of
+// an embedded struct are flattened into the tuple in place of the
+// embedding.
+type structField struct {
+	Name     string   // Go field name
+	Type     string   // SimplicityHL type
+	TypeName string   // Go name of the field's struct type, for nested structs
+	Via      []string // Embedded structs the field is promoted through, outermost first
+}
+
+// analyzeTypes records the type declarations of file ahead of its functions,
+// so that signatures can use struct types declared further down.
+func (t *Transpiler) analyzeTypes(file *ast.File) error {
+	for _, decl := range file.Decls {
+		if genDecl, ok := decl.(*ast.GenDecl); ok && genDecl.Tok == token.TYPE {
+			if err := t.analyzeTypeDeclarations(genDecl); err != nil {
+				return err
+			}
+		}
+	}
+
+	names := make([]string, 0, len(t.structDecls))
+	for name := range t.structDecls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := t.structTuple(name, nil); err != nil {
+			return err
+		}
+	}
+	for name, value := range t.optionStructs {
+		if t.structDecls[value] != nil {
+			t.customTypes[name] = fmt.Sprintf("Option<%s>", t.customTypes[value])
+		}
+	}
+	return t.layoutPaths()
+}
+
+// structTuple returns the tuple type of the plain struct name, laying out
+// its fields on first use. visiting guards against structs that contain
+// themselves.
+func (t *Transpiler) structTuple(name string, visiting map[string]bool) (string, error) {
+	if typ, ok := t.customTypes[name]; ok {
+		return typ, nil
+	}
+	if visiting[name] {
+		return "", fmt.Errorf("struct %s contains itself", name)
+	}
+	if visiting == nil {
+		visiting = make(map[string]bool)
+	}
+	visiting[name] = true
+
+	var fields []structField
+	for _, field := range t.structDecls[name].Fields.List {
+		if len(field.Names) == 0 {
+			embedded, err := t.embeddedFields(name, field.Type, visiting)
+			if err != nil {
+				return "", err
+			}
+			fields = append(fields, embedded...)
+			continue
+		}
+		f, err := t.fieldOf(name, field.Type, visiting)
+		if err != nil {
+			return "", err
+		}
+		for _, n := range field.Names {
+			f.Name = n.Name
+			fields = append(fields, f)
+		}
+	}
+
+	types := make([]string, len(fields))
+	for i, f := range fields {
+		types[i] = f.Type
+	}
+	tuple := tupleOf(types)
+	t.structFields[name] = fields
+	t.customTypes[name] = tuple
+	return tuple, nil
+}
+
+// fieldOf lays out a field of type typ in the struct parent.
+func (t *Transpiler) fieldOf(parent string, typ ast.Expr, visiting map[string]bool) (structField, error) {
+	if ident, ok := typ.(*ast.Ident); ok && t.structDecls[ident.Name] != nil {
+		tuple, err := t.structTuple(ident.Name, visiting)
+		return structField{Type: tuple, TypeName: ident.Name}, err
+	}
+	mapped, err := t.mapType(typ)
+	if err != nil {
+		return structField{}, fmt.Errorf("struct %s: %w", parent, err)
+	}
+	return structField{Type: mapped}, nil
+}
+
+// embeddedFields lays out an embedded field of the struct parent. An
+// embedded struct contributes its own flattened fields; any other embedded
+// type is a field named after the type.
+func (t *Transpiler) embeddedFields(parent string, typ ast.Expr, visiting map[string]bool) ([]structField, error) {
+	ident, ok := typ.(*ast.Ident)
+	if !ok {
+		return nil, fmt.Errorf("struct %s: unsupported embedded field of type %T", parent, typ)
+	}
+	// Declared interfaces fail validation; the predeclared ones are caught here
+	if ident.Name == "error" || ident.Name == "any" {
+		return nil, fmt.Errorf("struct %s embeds interface %s, which has no SimplicityHL representation", parent, ident.Name)
+	}
+	if t.structDecls[ident.Name] == nil {
+		f, err := t.fieldOf(parent, typ, visiting)
+		f.Name = ident.Name
+		return []structField{f}, err
+	}
+	if _, err := t.structTuple(ident.Name, visiting); err != nil {
+		return nil, err
+	}
+	var fields []structField
+	for _, f := range t.structFields[ident.Name] {
+		f.Via = append([]string{ident.Name}, f.Via...)
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// mapType maps a Go type, resolving the sum and struct types declared in
+// the contract.
+func (t *Transpiler) mapType(expr ast.Expr) (string, error) {
+	if ident, ok := expr.(*ast.Ident); ok {
+		if typ, ok := t.customTypes[ident.Name]; ok {
+			return typ, nil
+		}
+	}
+	if arr, ok := expr.(*ast.ArrayType); ok && arr.Len != nil {
+		if ident, ok := arr.Elt.(*ast.Ident); ok && t.customTypes[ident.Name] != "" {
+			// An array of structs, such as [2]Bounds: the mapper keeps the
+			// element's Go name, which has no SimplicityHL declaration
+			mapped, err := t.typeMapper.MapGoType(expr)
+			if err != nil {
+				return "", err
+			}
+			return "[" + t.customTypes[ident.Name] + mapped[strings.LastIndex(mapped, ";"):], nil
+		}
+	}
+	key, isArray := arrayKeyOf(expr)
+	if typ, ok := t.mappedTypes[key]; isArray && ok {
+		return typ, nil
+	}
+	typ, err := t.typeMapper.MapGoType(expr)
+	if err == nil && isArray {
+		t.mappedTypes[key] = typ
+	}
+	return typ, err
+}
+
+// arrayKey identifies an array type of a predeclared element type and a
+// literal length, such as [32]byte, the types a contract spells most often.
+type arrayKey struct {
+	elem, len string
+}
+
+// arrayKeyOf returns the key of expr when it is such an array type. Other
+// element types may map differently from one file to the next.
+func arrayKeyOf(expr ast.Expr) (arrayKey, bool) {
+	arr, ok := expr.(*ast.ArrayType)
+	if !ok {
+		return arrayKey{}, false
+	}
+	elem, okElem := arr.Elt.(*ast.Ident)
+	n, okLen := arr.Len.(*ast.BasicLit)
+	if !okElem || !okLen || n.Kind != token.INT {
+		return arrayKey{}, false
+	}
+	switch elem.Name {
+	case "bool", "byte", "uint8", "uint16", "uint32", "uint64":
+		return arrayKey{elem.Name, n.Value}, true
+	}
+	return arrayKey{}, false
+}
+
+// tupleOf writes a tuple of elems, with the trailing comma a one-element
+// tuple needs.
+func tupleOf(elems []string) string {
+	if len(elems) == 1 {
+		return "(" + elems[0] + ",)"
+	}
+	return "(" + strings.Join(elems, ", ") + ")"
+}
+
+// structValue is a struct parameter destructured into one local per
+// flattened field.
+type structValue struct {
+	typeName string
+	fields   []boundField
+}
+
+// boundField is a flattened field and the local bound to it.
+type boundField struct {
+	structField
+	ref    string       // Local, or the destructuring pattern of a nested struct
+	nested *structValue // Value of a field of struct type
+}
+
+// destructureParam returns the let statement that binds the fields of the
+// struct parameter name, of Go type typeName, to locals named after their
+// path, such as p_bounds_min for p.Bounds.Min. Nested structs are
+// destructured recursively, and the parameter is registered so that field
+// selectors resolve to the locals.
+func (t *Transpiler) destructureParam(name, typeName string) string {
+	v := t.bindStruct(snakeCase(name), typeName)
+	t.structParams[name] = v
+	return fmt.Sprintf("let %s: %s = %s;", v.pattern(), t.customTypes[typeName], snakeCase(name))
+}
+
+// bindWitnessStruct destructures the witness name, declared with the plain
+// struct type typeName, at the top of main, so that selectors such as
+// w.Price read locals like w_price. Other witnesses are left alone.
+func (t *Transpiler) bindWitnessStruct(name, typeName string) {
+	if t.structDecls[typeName] == nil || len(t.structFields[typeName]) == 0 {
+		return
+	}
+	v := t.bindStruct(snakeCase(name), typeName)
+	t.structParams[name] = v
+	t.witnessStructs = append(t.witnessStructs, fmt.Sprintf("let %s: %s = witness::%s;", v.pattern(), t.customTypes[typeName], WitnessName(name)))
+}
+
+func (t *Transpiler) bindStruct(prefix, typeName string) *structValue {
+	v := &structValue{typeName: typeName}
+	for _, f := range t.structFields[typeName] {
+		local := prefix
+		for _, part := range append(f.Via, f.Name) {
+			local += "_" + snakeWords(part)
+		}
+		bf := boundField{structField: f, ref: local}
+		if f.TypeName != "" {
+			bf.nested = t.bindStruct(local, f.TypeName)
+			bf.ref = bf.nested.pattern()
+		}
+		v.fields = append(v.fields, bf)
+	}
+	return v
+}
+
+// pattern is the tuple of the locals bound to v.
+func (v *structValue) pattern() string {
+	refs := make([]string, len(v.fields))
+	for i, f := range v.fields {
+		refs[i] = f.ref
+	}
+	return tupleOf(refs)
+}
+
+// selectField resolves the selector sel on v by Go's promotion rules: the
+// shallowest field or embedded struct named sel wins, and two at the same
+// depth make the selector ambiguous. It returns the selected struct, or
+// the selected field when that is not a struct.
+func (v *structValue) selectField(sel string) (*structValue, *boundField, error) {
+	type candidate struct {
+		field    *boundField
+		embedded int // length of the embedding chain naming sel, or 0 for a field
+	}
+	depth := -1
+	found := make(map[string]candidate)
+	consider := func(d int, key string, c candidate) {
+		if depth < 0 || d < depth {
+			depth = d
+			found = make(map[string]candidate)
+		}
+		if d == depth {
+			found[key] = c
+		}
+	}
+	for i := range v.fields {
+		f := &v.fields[i]
+		for k, e := range f.Via {
+			if e == sel {
+				consider(k, strings.Join(f.Via[:k+1], "."), candidate{f, k + 1})
+			}
+		}
+		if f.Name == sel {
+			consider(len(f.Via), strings.Join(append(f.Via, f.Name), "."), candidate{f, 0})
+		}
+	}
+	if len(found) == 0 {
+		return nil, nil, fmt.Errorf("type %s has no field %s", v.typeName, sel)
+	}
+	if len(found) > 1 {
+		return nil, nil, fmt.Errorf("ambiguous selector %s", sel)
+	}
+
+	for key, c := range found {
+		if c.embedded == 0 {
+			return c.field.nested, c.field, nil
+		}
+		// An embedded struct: the fields promoted through it
+		sub := &structValue{typeName: sel}
+		for _, f := range v.fields {
+			if len(f.Via) >= c.embedded && strings.Join(f.Via[:c.embedded], ".") == key {
+				f.Via = f.Via[c.embedded:]
+				sub.fields = append(sub.fields, f)
+			}
+		}
+		return sub, nil, nil
+	}
+	return nil, nil, nil
+}
+
+// resolveField resolves a field path such as p.Bounds.Min rooted at a
+// destructured struct parameter. It reports false for paths rooted
+// elsewhere.
+func (t *Transpiler) resolveField(path string) (Symbol, bool, error) {
+	parts := strings.Split(path, ".")
+	v := t.structParams[parts[0]]
+	if v == nil || len(parts) < 2 {
+		return Symbol{}, false, nil
+	}
+	var field *boundField
+	for i, sel := range parts[1:] {
+		if v == nil {
+			return Symbol{}, true, fmt.Errorf("%s undefined (%s is not a struct)", path, strings.Join(parts[:i+1], "."))
+		}
+		var err error
+		if v, field, err = v.selectField(sel); err != nil {
+			return Symbol{}, true, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if v != nil {
+		return Symbol{Kind: SymbolLocal, Ref: v.pattern(), Type: t.customTypes[v.typeName]}, true, nil
+	}
+	return Symbol{Kind: SymbolLocal, Ref: field.ref, Type: field.Type}, true, nil
+}
+
+// checkFieldSelectors reports the first field selector in body that does
+// not resolve on its struct parameter.
+func (t *Transpiler) checkFieldSelectors(body *ast.BlockStmt) error {
+	var err error
+	ast.Inspect(body, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok || err != nil {
+			return err == nil
+		}
+		if path, ok := selectorPath(sel); ok {
+			_, _, err = t.resolveField(path)
+			return false
+		}
+		return true
+	})
+	return err
+}
+
+// structLiteral lowers a struct composite literal to a tuple in field
+// order. Fields left out get the zero value of their type.
+func (t *Transpiler) structLiteral(typeName string, lit *ast.CompositeLit) (string, bool, error) {
+	if _, ok := t.structFields[typeName]; !ok {
+		return "", false, nil
+	}
+	elems, err := t.structElems(typeName, lit)
+	return tupleOf(elems), true, err
+}
+
+// structElems returns the flattened tuple elements of a literal of the
+// struct typeName. An embedded struct is given as a literal of its own
+// type, whose elements are spliced in.
+func (t *Transpiler) structElems(typeName string, lit *ast.CompositeLit) ([]string, error) {
+	// The literal's members: declared fields, and embedded structs, each
+	// spanning the flattened fields promoted through it
+	type member struct {
+		name     string
+		embedded string
+		fields   []structField
+	}
+	var members []*member
+	for _, f := range t.structFields[typeName] {
+		name, embedded := f.Name, ""
+		if len(f.Via) > 0 {
+			name, embedded = f.Via[0], f.Via[0]
+		}
+		if n := len(members); n == 0 || members[n-1].name != name || embedded == "" {
+			members = append(members, &member{name: name, embedded: embedded})
+		}
+		members[len(members)-1].fields = append(members[len(members)-1].fields, f)
+	}
+
+	values := make([]ast.Expr, len(members))
+	for i, elt := range lit.Elts {
+		kv, keyed := elt.(*ast.KeyValueExpr)
+		if !keyed {
+			if i >= len(values) {
+				return nil, fmt.Errorf("too many values in %s literal", typeName)
+			}
+			values[i] = elt
+			continue
+		}
+		key, _ := kv.Key.(*ast.Ident)
+		if key == nil {
+			return nil, fmt.Errorf("invalid field key in %s literal", typeName)
+		}
+		found := false
+		for j, m := range members {
+			if m.name == key.Name {
+				values[j], found = kv.Value, true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown field %s in %s literal", key.Name, typeName)
+		}
+	}
+
+	var elems []string
+	for i, m := range members {
+		switch {
+		case values[i] == nil:
+			for _, f := range m.fields {
+				elems = append(elems, zeroValue(f.Type))
+			}
+		case m.embedded != "":
+			inner, _ := values[i].(*ast.CompositeLit)
+			if inner == nil || !isTypeName(inner.Type, m.embedded) {
+				return nil, fmt.Errorf("embedded %s in %s literal must be a %s literal", m.embedded, typeName, m.embedded)
+			}
+			sub, err := t.structElems(m.embedded, inner)
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, sub...)
+		default:
+			v, err := t.expr.Translate(values[i])
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, v)
+		}
+	}
+	return elems, nil
+}
+
+// isTypeName reports whether expr names the type name.
+func isTypeName(expr ast.Expr, name string) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == name
+}
@@ -0,0 +1,386 @@
+package transpiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// A function that counts which of n conditions hold and tests the count
+// against a constant k is a k-of-n threshold:
+//
+//	valid := 0
+//	if sigA { valid++ }
+//	if sigB { valid = valid + 1 }
+//	if sigC { valid += 1 }
+//	return valid >= 2
+//
+// SimplicityHL has no mutable counter, so the count is lowered to a
+// comparison tree instead: a match on each condition in turn, whose arms
+// need one fewer or the same number of the remaining conditions. Arms are
+// cut short once the threshold is met or can no longer be, so the tree is
+// the formula (a && b) || (a && c) || (b && c) factored by its first
+// condition:
+//
+//	match sig_a {
+//	    true => {
+//	        match sig_b {
+//	            true => true,
+//	            false => sig_c,
+//	        }
+//	    },
+//	    false => {
+//	        match sig_b {
+//	            true => sig_c,
+//	            false => false,
+//	        }
+//	    },
+//	}
+
+// thresholdCount is a recognised counting pattern at the end of a body.
+type thresholdCount struct {
+	start   int        // Index of the statement declaring the counter
+	counter string     // Go name of the counter
+	conds   []ast.Expr // Conditions counted, in source order
+	k       int        // Count the conditions must reach
+}
+
+// matchThresholdCount recognises the counting pattern in the statements
+// that end stmts: the counter's declaration with value zero, one if
+// statement per condition that increments it, and a return of the counter
+// compared with a constant.
+func (t *Transpiler) matchThresholdCount(stmts []ast.Stmt) (*thresholdCount, bool) {
+	if len(stmts) < 2 {
+		return nil, false
+	}
+	ret, ok := stmts[len(stmts)-1].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return nil, false
+	}
+	counter, k, ok := t.thresholdTest(ret.Results[0])
+	if !ok {
+		return nil, false
+	}
+	tc := &thresholdCount{counter: counter, k: k}
+	i := len(stmts) - 2
+	for ; i >= 0; i-- {
+		cond, ok := incrementIf(stmts[i], counter)
+		if !ok {
+			break
+		}
+		tc.conds = append([]ast.Expr{cond}, tc.conds...)
+	}
+	if i < 0 || len(tc.conds) == 0 || !t.zeroDecl(stmts[i], counter) {
+		return nil, false
+	}
+	tc.start = i
+	return tc, true
+}
+
+// thresholdTest matches counter >= k and the equivalent forms k <= counter,
+// counter > k-1 and k-1 < counter, for a constant k.
+func (t *Transpiler) thresholdTest(expr ast.Expr) (string, int, bool) {
+	bin, ok := ast.Unparen(expr).(*ast.BinaryExpr)
+	if !ok {
+		return "", 0, false
+	}
+	x, y, op := ast.Unparen(bin.X), ast.Unparen(bin.Y), bin.Op
+	if _, isIdent := x.(*ast.Ident); !isIdent {
+		x, y = y, x
+		switch op {
+		case token.LEQ:
+			op = token.GEQ
+		case token.LSS:
+			op = token.GTR
+		default:
+			return "", 0, false
+		}
+	}
+	ident, ok := x.(*ast.Ident)
+	if !ok {
+		return "", 0, false
+	}
+	v, ok := t.constantInt(y)
+	if !ok || !v.IsInt64() {
+		return "", 0, false
+	}
+	k := v.Int64()
+	switch op {
+	case token.GEQ:
+	case token.GTR:
+		k++
+	default:
+		return "", 0, false
+	}
+	if k < 0 || k > 1<<16 {
+		return "", 0, false
+	}
+	return ident.Name, int(k), true
+}
+
+// incrementIf matches if cond { counter++ }, with the increment also
+// written counter += 1, counter = counter + 1 or counter = 1 + counter,
+// and returns cond. The condition must not read the counter.
+func incrementIf(stmt ast.Stmt, counter string) (ast.Expr, bool) {
+	ifStmt, ok := stmt.(*ast.IfStmt)
+	if !ok || ifStmt.Init != nil || ifStmt.Else != nil || len(ifStmt.Body.List) != 1 || reads(ifStmt.Cond, counter) {
+		return nil, false
+	}
+	isCounter := func(e ast.Expr) bool {
+		ident, ok := ast.Unparen(e).(*ast.Ident)
+		return ok && ident.Name == counter
+	}
+	isOne := func(e ast.Expr) bool {
+		lit, ok := ast.Unparen(e).(*ast.BasicLit)
+		return ok && lit.Kind == token.INT && lit.Value == "1"
+	}
+	switch s := ifStmt.Body.List[0].(type) {
+	case *ast.IncDecStmt:
+		if s.Tok == token.INC && isCounter(s.X) {
+			return ifStmt.Cond, true
+		}
+	case *ast.AssignStmt:
+		if len(s.Lhs) != 1 || len(s.Rhs) != 1 || !isCounter(s.Lhs[0]) {
+			break
+		}
+		switch s.Tok {
+		case token.ADD_ASSIGN:
+			if isOne(s.Rhs[0]) {
+				return ifStmt.Cond, true
+			}
+		case token.ASSIGN:
+			sum, ok := ast.Unparen(s.Rhs[0]).(*ast.BinaryExpr)
+			if ok && sum.Op == token.ADD && (isCounter(sum.X) && isOne(sum.Y) || isOne(sum.X) && isCounter(sum.Y)) {
+				return ifStmt.Cond, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// zeroDecl matches counter := 0, var counter = 0 and var counter int,
+// with or without a conversion of the zero.
+func (t *Transpiler) zeroDecl(stmt ast.Stmt, counter string) bool {
+	var value ast.Expr
+	switch s := stmt.(type) {
+	case *ast.AssignStmt:
+		ident, ok := s.Lhs[0].(*ast.Ident)
+		if s.Tok != token.DEFINE || len(s.Lhs) != 1 || len(s.Rhs) != 1 || !ok || ident.Name != counter {
+			return false
+		}
+		value = s.Rhs[0]
+	case *ast.DeclStmt:
+		gen, ok := s.Decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.VAR || len(gen.Specs) != 1 {
+			return false
+		}
+		spec := gen.Specs[0].(*ast.ValueSpec)
+		if len(spec.Names) != 1 || spec.Names[0].Name != counter || len(spec.Values) > 1 {
+			return false
+		}
+		if len(spec.Values) == 0 {
+			return spec.Type != nil
+		}
+		value = spec.Values[0]
+	default:
+		return false
+	}
+	v, ok := t.folder.Fold(value)
+	return ok && v.Int != nil && v.Int.Sign() == 0
+}
+
+// reads reports whether expr refers to the Go name.
+func reads(expr ast.Expr, name string) bool {
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && ident.Name == name {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// simpleRef matches a translated operand that may be repeated in the tree
+// without evaluating anything twice: a name, possibly module-qualified or a
+// field of one.
+var simpleRef = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*((::|\.)[A-Za-z0-9_]+)*$`)
+
+// lowerThresholdCount returns the statements and the comparison tree for
+// tc. Conditions other than names are bound to locals first, in source
+// order, since the tree tests some of them on more than one path.
+func (t *Transpiler) lowerThresholdCount(tc *thresholdCount) ([]string, error) {
+	var lines []string
+	refs := make([]string, len(tc.conds))
+	for i, cond := range tc.conds {
+		ref, err := t.expr.Translate(cond)
+		if err != nil {
+			return nil, err
+		}
+		if !simpleRef.MatchString(ref) {
+			name := t.temp(cond.Pos())
+			lines = append(lines, fmt.Sprintf("let %s: bool = %s;", name, ref))
+			ref = name
+		}
+		refs[i] = ref
+	}
+	return append(lines, strings.Join(thresholdTree(refs, tc.k), "\n")), nil
+}
+
+// thresholdTree returns the expression that holds when at least k of conds
+// do, one line per element of the result.
+func thresholdTree(conds []string, k int) []string {
+	switch {
+	case k <= 0:
+		return []string{"true"}
+	case k > len(conds):
+		return []string{"false"}
+	case len(conds) == 1:
+		return []string{conds[0]}
+	}
+	met := thresholdTree(conds[1:], k-1)
+	unmet := thresholdTree(conds[1:], k)
+	if len(met) == 1 && len(unmet) == 1 && met[0] == "true" && unmet[0] == "false" {
+		return []string{conds[0]}
+	}
+	lines := []string{fmt.Sprintf("match %s {", conds[0])}
+	for _, arm := range []struct {
+		pattern string
+		body    []string
+	}{{"true", met}, {"false", unmet}} {
+		if len(arm.body) == 1 {
+			lines = append(lines, fmt.Sprintf("%s%s => %s,", canonicalIndent, arm.pattern, arm.body[0]))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s%s => {", canonicalIndent, arm.pattern))
+		for _, line := range arm.body {
+			lines = append(lines, strings.Repeat(canonicalIndent, 2)+line)
+		}
+		lines = append(lines, canonicalIndent+"},")
+	}
+	return append(lines, "}")
+}
+
+// maxThresholdConds bounds the conditions of a std.Threshold, which as a
+// circuit grows with the product of k and the number of conditions.
+const maxThresholdConds = 16
+
+// thresholdHelperPrefix starts the name of the SimplicityHL function that
+// a std.Threshold call lowers to, std_threshold_<k>_of_<n>.
+const thresholdHelperPrefix = "std_threshold_"
+
+// thresholdCall checks and translates std.Threshold(k, conds...), returning
+// the helper for its k and number of conditions and the arguments to pass.
+func (t *Transpiler) thresholdCall(call *ast.CallExpr) (helper, args string, err error) {
+	if call.Ellipsis.IsValid() {
+		return "", "", t.errorAt(call.Pos(), "std.Threshold takes its conditions as separate arguments, not a slice, so that their number is known when compiling")
+	}
+	if len(call.Args) == 0 {
+		return "", "", t.errorAt(call.Pos(), "std.Threshold takes k and the conditions, got no arguments")
+	}
+	k, ok := t.constantInt(call.Args[0])
+	if !ok {
+		return "", "", t.errorAt(call.Args[0].Pos(), "std.Threshold: k must be a compile-time constant")
+	}
+	conds := call.Args[1:]
+	switch {
+	case len(conds) > maxThresholdConds:
+		return "", "", t.errorAt(call.Pos(), "std.Threshold takes at most %d conditions, got %d", maxThresholdConds, len(conds))
+	case k.Sign() <= 0:
+		return "", "", t.errorAt(call.Args[0].Pos(), "std.Threshold: k is %s, but at least one condition must hold", k)
+	case k.Cmp(big.NewInt(int64(len(conds)))) > 0:
+		return "", "", t.errorAt(call.Args[0].Pos(), "std.Threshold: k is %s, more than the %d conditions", k, len(conds))
+	}
+	refs := make([]string, len(conds))
+	for i, cond := range conds {
+		if refs[i], err = t.expr.TranslateArg(cond); err != nil {
+			return "", "", err
+		}
+	}
+	helper = fmt.Sprintf("%s%d_of_%d", thresholdHelperPrefix, k.Int64(), len(conds))
+	t.helpers[helper] = true
+	return helper, strings.Join(refs, ", "), nil
+}
+
+// thresholdHelpers returns the definitions of the std.Threshold helpers
+// the program calls, ordered by number of conditions and then k.
+func (t *Transpiler) thresholdHelpers() []string {
+	type arity struct{ k, n int }
+	var arities []arity
+	for name := range t.helpers {
+		var a arity
+		if _, err := fmt.Sscanf(name, thresholdHelperPrefix+"%d_of_%d", &a.k, &a.n); err == nil {
+			arities = append(arities, a)
+		}
+	}
+	sort.Slice(arities, func(i, j int) bool {
+		if arities[i].n != arities[j].n {
+			return arities[i].n < arities[j].n
+		}
+		return arities[i].k < arities[j].k
+	})
+	defs := make([]string, len(arities))
+	for i, a := range arities {
+		defs[i] = thresholdHelper(a.k, a.n)
+	}
+	return defs
+}
+
+// thresholdHelper defines std_threshold_<k>_of_<n>, which reports whether
+// at least k of its n conditions hold. Where the counting pattern is
+// lowered to a tree, the helper is a circuit: at_least_<j>_from_<i> holds
+// when j of the conditions from c<i> on do, and each is a match on c<i>
+// between two of the next condition's, so the helper has at most k×n
+// matches rather than a tree's exponentially many.
+func thresholdHelper(k, n int) string {
+	cond := func(i int) string { return fmt.Sprintf("c%d", i) }
+	// atLeast is the expression for j of the conditions from c<i> on.
+	atLeast := func(i, j int) string {
+		switch {
+		case j <= 0:
+			return "true"
+		case j > n-i:
+			return "false"
+		case i == n-1:
+			return cond(i)
+		}
+		return fmt.Sprintf("at_least_%d_from_%d", j, i)
+	}
+	match := func(i, j int) []string {
+		return []string{
+			fmt.Sprintf("match %s {", cond(i)),
+			fmt.Sprintf("%strue => %s,", canonicalIndent, atLeast(i+1, j-1)),
+			fmt.Sprintf("%sfalse => %s,", canonicalIndent, atLeast(i+1, j)),
+			"}",
+		}
+	}
+
+	params := make([]string, n)
+	for i := range params {
+		params[i] = cond(i) + ": bool"
+	}
+	lines := []string{fmt.Sprintf("fn %s%d_of_%d(%s) -> bool {", thresholdHelperPrefix, k, n, strings.Join(params, ", "))}
+	for i := n - 2; i >= 1; i-- {
+		// After i conditions, between none and all of them have held.
+		for j := max(k-i, 1); j <= min(k, n-i); j++ {
+			m := match(i, j)
+			lines = append(lines, fmt.Sprintf("%slet %s: bool = %s", canonicalIndent, atLeast(i, j), m[0]))
+			for _, line := range m[1 : len(m)-1] {
+				lines = append(lines, canonicalIndent+line)
+			}
+			lines = append(lines, canonicalIndent+"};")
+		}
+	}
+	if n == 1 {
+		lines = append(lines, canonicalIndent+cond(0))
+	} else {
+		for _, line := range match(0, k) {
+			lines = append(lines, canonicalIndent+line)
+		}
+	}
+	return strings.Join(append(lines, "}"), "\n")
+}
@@ -0,0 +1,570 @@
+package transpiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	gotypes "go/types"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// SymbolKind classifies what a Go identifier refers to in generated code.
+type SymbolKind int
+
+const (
+	SymbolConstant SymbolKind = iota // A param module entry, or an inlined library constant
+	SymbolWitness                    // A witness module entry
+	SymbolLocal                      // A let binding from an earlier jet call
+)
+
+// Symbol is the SimplicityHL side of a Go identifier.
+type Symbol struct {
+	Kind SymbolKind
+	Ref  string // Expression that reads the symbol, e.g. witness::SIG
+	Type string // SimplicityHL type, or "" when unknown
+}
+
+// SymbolTable resolves the identifiers a Translator meets.
+type SymbolTable interface {
+	// Lookup resolves a local Go name, or a field path such as
+	// p.Bounds.Min.
+	Lookup(name string) (Symbol, bool)
+	// Qualified resolves a constant of an imported package, pkg.Name, to
+	// its value.
+	Qualified(pkg, name string) (string, bool)
+}
+
+// placeholder is emitted for residual expressions the translator cannot
+// lower to SimplicityHL.
+const placeholder = "true"
+
+// Translator lowers Go expressions to SimplicityHL. Constant subexpressions
+// are evaluated by its Folder; the rest become references to the symbols
+// they name and jet calls for the operators applied to them.
+type Translator struct {
+	symbols SymbolTable
+	folder  *Folder
+	// calls lowers call expressions, which need the jet registry and the
+	// file's helpers. Translators without it emit the placeholder.
+	calls func(*ast.CallExpr) (string, error)
+	// structs lowers a literal of the named struct type to a tuple, and
+	// reports false for types that are not structs.
+	structs func(typeName string, lit *ast.CompositeLit) (string, bool, error)
+	// divisions, when set, names the function a divide or modulo jet is
+	// called through when the divisor is a runtime value, as checked
+	// arithmetic asserts that it is not zero.
+	divisions func(jet string) string
+	// fallback, when set, is told of each expression the translator emits
+	// the placeholder for, and may refuse it with an error.
+	fallback func(reason string, pos token.Pos) error
+}
+
+// NewTranslator returns a translator that resolves identifiers through
+// symbols and folds constants with folder, which may be nil.
+func NewTranslator(symbols SymbolTable, folder *Folder) *Translator {
+	if folder == nil {
+		folder = NewFolder(nil, nil)
+	}
+	return &Translator{symbols: symbols, folder: folder}
+}
+
+// Translate returns the SimplicityHL expression for expr.
+func (tr *Translator) Translate(expr ast.Expr) (string, error) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind == token.INT && strings.HasPrefix(e.Value, "0x") {
+			return normalizeHex(e.Value)
+		}
+		return e.Value, nil
+	case *ast.ParenExpr:
+		if v, ok := tr.folder.Fold(e); ok {
+			return v.String(), nil
+		}
+		return tr.Translate(e.X)
+	case *ast.BinaryExpr:
+		return tr.translateBinary(e)
+	case *ast.CallExpr:
+		if v, ok := tr.folder.Fold(e); ok {
+			return v.String(), nil
+		}
+		if tr.calls == nil {
+			return tr.residual(e, "the call")
+		}
+		return tr.calls(e)
+	case *ast.UnaryExpr:
+		if e.Op == token.NOT {
+			return tr.translateNot(e)
+		}
+	case *ast.Ident:
+		if sym, ok := tr.symbols.Lookup(e.Name); ok {
+			return sym.Ref, nil
+		}
+		if v, ok := tr.folder.Local(e.Name); ok {
+			return v.String(), nil
+		}
+		// Parameters and locals without a value keep their own name
+		return snakeCase(e.Name), nil
+	case *ast.SelectorExpr:
+		if sym, ok := tr.lookupPath(e); ok {
+			return sym.Ref, nil
+		}
+		// Struct field access like w.Preimage or w.RecipientSig
+		if ident, ok := e.X.(*ast.Ident); ok {
+			fieldName := snakeCase(e.Sel.Name)
+			if sym, ok := tr.symbols.Lookup(ident.Name); ok && sym.Kind == SymbolWitness {
+				return sym.Ref + "." + fieldName, nil
+			}
+			if value, ok := tr.symbols.Qualified(ident.Name, e.Sel.Name); ok {
+				return value, nil
+			}
+			return snakeCase(ident.Name) + "." + fieldName, nil
+		}
+	case *ast.StarExpr:
+		// *w.Cancel on the Cancel path of a path struct is the bound payload
+		return tr.Translate(e.X)
+	case *ast.IndexExpr:
+		return tr.translateIndex(e)
+	case *ast.CompositeLit:
+		if ident, ok := e.Type.(*ast.Ident); ok && tr.structs != nil {
+			if tuple, isStruct, err := tr.structs(ident.Name, e); isStruct || err != nil {
+				return tuple, err
+			}
+		}
+		// Array literals like [3]u256{a, b, c}
+		var elements []string
+		for _, elt := range e.Elts {
+			elemStr, err := tr.Translate(elt)
+			if err != nil {
+				return "", err
+			}
+			elements = append(elements, elemStr)
+		}
+		return fmt.Sprintf("[%s]", strings.Join(elements, ", ")), nil
+	}
+	return tr.residual(expr, "the expression")
+}
+
+// residual returns the placeholder for expr, which the translator cannot
+// lower, described as what.
+func (tr *Translator) residual(expr ast.Expr, what string) (string, error) {
+	if tr.fallback != nil {
+		reason := fmt.Sprintf("%s %s has no lowering and compiles to %s", what, gotypes.ExprString(expr), placeholder)
+		if err := tr.fallback(reason, expr.Pos()); err != nil {
+			return "", err
+		}
+	}
+	return placeholder, nil
+}
+
+// lookupPath resolves an identifier or a field path such as p.Bounds.Min
+// through the symbol table.
+func (tr *Translator) lookupPath(expr ast.Expr) (Symbol, bool) {
+	path, ok := selectorPath(ast.Unparen(expr))
+	if !ok {
+		return Symbol{}, false
+	}
+	return tr.symbols.Lookup(path)
+}
+
+// selectorPath spells out a chain of field selections on an identifier.
+func selectorPath(expr ast.Expr) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name, true
+	case *ast.SelectorExpr:
+		base, ok := selectorPath(e.X)
+		return base + "." + e.Sel.Name, ok
+	}
+	return "", false
+}
+
+// TranslateArg is Translate for an operand of a jet call or operator: a
+// binary expression over runtime values becomes the jet call that computes
+// it instead of being folded.
+func (tr *Translator) TranslateArg(expr ast.Expr) (string, error) {
+	if e, ok := ast.Unparen(expr).(*ast.BinaryExpr); ok {
+		if call, ok := tr.OperatorCall(e); ok {
+			return tr.operatorValue(e, call), nil
+		}
+	}
+	return tr.Translate(expr)
+}
+
+// operatorValue is the SimplicityHL for the Go value of e, computed by the
+// jet call: the result of the jet without the carry of an add or subtract,
+// or the high half of a product, which Go's fixed-width arithmetic drops.
+func (tr *Translator) operatorValue(e *ast.BinaryExpr, call OperatorJet) string {
+	callExpr := formatJetCallExpr(call.Jet, call.Args)
+	switch e.Op {
+	case token.ADD:
+		return fmt.Sprintf("{ let (_, sum): %s = %s; sum }", call.ReturnType, callExpr)
+	case token.SUB:
+		return fmt.Sprintf("{ let (_, difference): %s = %s; difference }", call.ReturnType, callExpr)
+	case token.MUL:
+		width := tr.operandWidth(e.X, e.Y)
+		return fmt.Sprintf("{ let (_, product): (%s, %s) = <%s>::into(%s); product }", width, width, call.ReturnType, callExpr)
+	}
+	return callExpr
+}
+
+// translateBinary evaluates a binary expression that is not lowered to a
+// jet call. Operands that translate to integer literals, such as library
+// constants, are folded as untyped values.
+func (tr *Translator) translateBinary(e *ast.BinaryExpr) (string, error) {
+	if v, ok := tr.folder.Fold(e); ok {
+		return v.String(), nil
+	}
+	if e.Op == token.LAND || e.Op == token.LOR {
+		return tr.translateLogical(e)
+	}
+	left, err := tr.Translate(e.X)
+	if err != nil {
+		return "", err
+	}
+	right, err := tr.Translate(e.Y)
+	if err != nil {
+		return "", err
+	}
+	l, okL := new(big.Int).SetString(left, 10)
+	r, okR := new(big.Int).SetString(right, 10)
+	if okL && okR {
+		if v, ok := foldBinary(e.Op, Value{Int: l}, Value{Int: r}); ok {
+			return v.String(), nil
+		}
+	}
+	if e.Op == token.NEQ {
+		// a != b is the negation of a == b
+		if call, ok := tr.OperatorCall(&ast.BinaryExpr{X: e.X, OpPos: e.OpPos, Op: token.EQL, Y: e.Y}); ok {
+			return not(formatJetCallExpr(call.Jet, call.Args)), nil
+		}
+	}
+	return tr.residual(e, "the operation")
+}
+
+// translateLogical lowers && and || over runtime operands to a match on
+// the left one, which evaluates the right one only when Go would. A known
+// left operand is folded away: Go's short-circuit rules make "true && b"
+// b and "false || b" b, and the Folder has taken care of the rest. So is
+// a right operand that leaves the left one's value, as in "a && true".
+func (tr *Translator) translateLogical(e *ast.BinaryExpr) (string, error) {
+	left, err := tr.TranslateArg(e.X)
+	if err != nil {
+		return "", err
+	}
+	right, err := tr.TranslateArg(e.Y)
+	if err != nil {
+		return "", err
+	}
+	if left == "true" || left == "false" {
+		return right, nil
+	}
+	if e.Op == token.LAND && right == "true" || e.Op == token.LOR && right == "false" {
+		return left, nil
+	}
+	if e.Op == token.LAND {
+		return fmt.Sprintf("match %s { true => %s, false => false, }", left, right), nil
+	}
+	return fmt.Sprintf("match %s { true => true, false => %s, }", left, right), nil
+}
+
+// complements maps each comparison to the one that holds exactly when it
+// does not, for unsigned operands: !(a < b) is a >= b.
+var complements = map[token.Token]token.Token{
+	token.LSS: token.GEQ,
+	token.LEQ: token.GTR,
+	token.GTR: token.LEQ,
+	token.GEQ: token.LSS,
+	token.NEQ: token.EQL,
+}
+
+// translateNot lowers !x for any boolean x. Known operands fold; !!x is x
+// and a negated comparison is the complementary one, which reads better
+// than the general form, a match that swaps true and false.
+func (tr *Translator) translateNot(e *ast.UnaryExpr) (string, error) {
+	if v, ok := tr.folder.Fold(e); ok {
+		return v.String(), nil
+	}
+	switch x := ast.Unparen(e.X).(type) {
+	case *ast.UnaryExpr:
+		if x.Op == token.NOT {
+			return tr.TranslateArg(x.X)
+		}
+	case *ast.BinaryExpr:
+		if op, ok := complements[x.Op]; ok {
+			if call, ok := tr.OperatorCall(&ast.BinaryExpr{X: x.X, OpPos: x.OpPos, Op: op, Y: x.Y}); ok {
+				return formatJetCallExpr(call.Jet, call.Args), nil
+			}
+		}
+	}
+	operand, err := tr.TranslateArg(e.X)
+	if err != nil {
+		return "", err
+	}
+	return not(operand), nil
+}
+
+// not is the SimplicityHL negation of the boolean expression ref.
+func not(ref string) string {
+	switch ref {
+	case "true":
+		return "false"
+	case "false":
+		return "true"
+	}
+	return fmt.Sprintf("match %s { true => false, false => true, }", ref)
+}
+
+// translateIndex handles array indexing like arr[i] or arr[0]. SimplicityHL
+// has no index expression, so a constant index into an array of known type
+// is the element a block destructures the array to:
+//
+//	a[1]  →  { let [_, element, _]: [u8; 3] = a; element }
+func (tr *Translator) translateIndex(e *ast.IndexExpr) (string, error) {
+	arrayExpr, err := tr.Translate(e.X)
+	if err != nil {
+		return "", err
+	}
+	if elem, n, ok := arrayType(tr.arrayTypeOf(e.X)); ok {
+		if v, known := tr.folder.Fold(e.Index); known && v.Int != nil && v.Int.IsInt64() && v.Int.Int64() < int64(n) {
+			names := make([]string, n)
+			for i := range names {
+				names[i] = "_"
+			}
+			names[v.Int.Int64()] = "element"
+			return fmt.Sprintf("{ let [%s]: [%s; %d] = %s; element }", strings.Join(names, ", "), elem, n, arrayExpr), nil
+		}
+	}
+	indexExpr, err := tr.Translate(e.Index)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s[%s]", arrayExpr, indexExpr), nil
+}
+
+// arrayTypeOf returns the type of expr, a symbol or an element of one, or
+// "" when it is not known.
+func (tr *Translator) arrayTypeOf(expr ast.Expr) string {
+	if sym, ok := tr.lookupPath(expr); ok {
+		return sym.Type
+	}
+	if index, ok := ast.Unparen(expr).(*ast.IndexExpr); ok {
+		if elem, _, ok := arrayType(tr.arrayTypeOf(index.X)); ok {
+			return elem
+		}
+	}
+	return ""
+}
+
+// normalizeHex validates a hex literal and lowercases it.
+func normalizeHex(value string) (string, error) {
+	if !strings.HasPrefix(value, "0x") && !strings.HasPrefix(value, "0X") {
+		return "", fmt.Errorf("invalid hex literal: %s", value)
+	}
+	hexPart := value[2:]
+	if len(hexPart) == 0 {
+		return "", fmt.Errorf("empty hex literal")
+	}
+	for _, c := range hexPart {
+		if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')) {
+			return "", fmt.Errorf("invalid hex character in literal: %c", c)
+		}
+	}
+	return "0x" + strings.ToLower(hexPart), nil
+}
+
+// ─── Operator-to-jet mapping ─────────────────────────────────────────────────
+
+// OperatorJet is the jet call that computes a Go binary operation.
+type OperatorJet struct {
+	Jet        string // Jet name, e.g. lt_32
+	Args       string // Comma-separated SimplicityHL arguments
+	ReturnType string
+}
+
+// OperatorCall returns the jet call for expr when at least one operand is a
+// runtime value: a witness or param reference, or a local from a previous
+// jet call. It reports false when both sides are compile-time values, which
+// Translate folds instead, and for operators without a jet.
+func (tr *Translator) OperatorCall(expr *ast.BinaryExpr) (OperatorJet, bool) {
+	leftStr, _ := tr.TranslateArg(expr.X)
+	rightStr, _ := tr.TranslateArg(expr.Y)
+	if !tr.isRuntime(expr.X, leftStr) && !tr.isRuntime(expr.Y, rightStr) {
+		return OperatorJet{}, false
+	}
+
+	width := tr.operandWidth(expr.X, expr.Y)
+	jetName, swapArgs := operatorToJetName(expr.Op, width)
+	if jetName == "" {
+		return OperatorJet{}, false
+	}
+
+	if (expr.Op == token.QUO || expr.Op == token.REM) && tr.divisions != nil && tr.isRuntime(expr.Y, rightStr) {
+		jetName = tr.divisions(jetName)
+	}
+
+	args := leftStr + ", " + rightStr
+	if swapArgs {
+		args = rightStr + ", " + leftStr
+	}
+	return OperatorJet{Jet: jetName, Args: args, ReturnType: operatorReturnType(expr.Op, width)}, true
+}
+
+// isRuntime reports whether the operand expr, translated to ref, is only
+// known when the program runs.
+func (tr *Translator) isRuntime(expr ast.Expr, ref string) bool {
+	if strings.HasPrefix(ref, "witness::") || strings.HasPrefix(ref, "param::") {
+		return true
+	}
+	if _, ok := ast.Unparen(expr).(*ast.BinaryExpr); ok && (strings.HasPrefix(ref, "jet::") || strings.HasPrefix(ref, "{ let ")) {
+		return true // Computed by a jet, such as the product of a*b/c
+	}
+	sym, ok := tr.lookupPath(expr)
+	return ok && sym.Kind == SymbolLocal
+}
+
+// operandWidth picks u8/u16/u32/u64/u128/u256 from the types of two
+// operands. Defaults to u32 (the most common width for heights, sequences,
+// indices). u256 takes priority over everything (asset IDs, script hashes);
+// u128 is checked next (products of two u64 values). An untyped constant
+// takes the width of the other operand, as it does in Go, so b != 0 on a
+// byte compares bytes.
+func (tr *Translator) operandWidth(left, right ast.Expr) string {
+	lt := tr.TypeOf(left)
+	rt := tr.TypeOf(right)
+	if v, ok := tr.folder.Fold(left); ok && v.Type == "" && v.Int != nil {
+		lt = rt
+	} else if v, ok := tr.folder.Fold(right); ok && v.Type == "" && v.Int != nil {
+		rt = lt
+	}
+	if lt == "u256" || rt == "u256" {
+		return "u256"
+	}
+	if lt == "u128" || rt == "u128" {
+		return "u128"
+	}
+	if lt == "u64" || rt == "u64" {
+		return "u64"
+	}
+	if lt == "u16" && rt == "u16" {
+		return "u16"
+	}
+	if lt == "u8" && rt == "u8" {
+		return "u8"
+	}
+	return "u32"
+}
+
+// TypeOf returns the SimplicityHL type of an operand, defaulting to u32.
+func (tr *Translator) TypeOf(expr ast.Expr) string {
+	expr = ast.Unparen(expr)
+	if sym, ok := tr.lookupPath(expr); ok {
+		return carryFree(sym.Type)
+	}
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if b, ok := tr.folder.lookup(e.Name); ok && isUIntType(b.value.Type) {
+			return b.value.Type
+		}
+	case *ast.BinaryExpr:
+		switch e.Op {
+		case token.ADD, token.SUB, token.MUL, token.QUO, token.REM, token.AND, token.OR, token.XOR:
+			// Go's arithmetic wraps, so the result has the operands' width
+			return tr.operandWidth(e.X, e.Y)
+		}
+	case *ast.BasicLit:
+		if e.Kind == token.INT {
+			if v, err := strconv.ParseInt(e.Value, 0, 64); err == nil && v > 0x7FFFFFFF {
+				return "u64"
+			}
+		}
+	}
+	return "u32"
+}
+
+// carryFree strips the carry of a tuple such as "(bool, u64)" returned by
+// add_64: operators only care about the value.
+func carryFree(typ string) string {
+	if strings.HasPrefix(typ, "(bool, ") && strings.HasSuffix(typ, ")") {
+		return typ[7 : len(typ)-1]
+	}
+	return typ
+}
+
+// opJetSpec pairs a jet name prefix with whether the arguments must be swapped.
+// Swapping handles the GT/GEQ cases: a > b compiles as lt(b, a).
+type opJetSpec struct {
+	prefix   string
+	swapArgs bool
+}
+
+// opJetMap maps each supported Go binary operator to its jet prefix and swap flag.
+var opJetMap = map[token.Token]opJetSpec{
+	token.ADD: {"add_", false},
+	token.SUB: {"subtract_", false},
+	token.MUL: {"multiply_", false},
+	token.QUO: {"divide_", false},
+	token.REM: {"modulo_", false},
+	token.LSS: {"lt_", false}, // a < b
+	token.LEQ: {"le_", false}, // a <= b
+	token.GTR: {"lt_", true},  // a > b → lt(b, a)
+	token.GEQ: {"le_", true},  // a >= b → le(b, a)
+	token.EQL: {"eq_", false},
+	token.AND: {"and_", false},
+	token.OR:  {"or_", false},
+	token.XOR: {"xor_", false},
+}
+
+// operatorToJetName maps a Go binary operator and Simplicity integer width to
+// a jet name.  swapArgs=true means the caller should pass (right, left) instead
+// of (left, right) — used for > and >= which are expressed as < and <= with
+// swapped operands.
+func operatorToJetName(op token.Token, width string) (name string, swapArgs bool) {
+	suffix := "32"
+	switch width {
+	case "u8":
+		suffix = "8"
+	case "u16":
+		suffix = "16"
+	case "u64":
+		suffix = "64"
+	case "u128":
+		suffix = "128"
+	case "u256":
+		suffix = "256"
+	}
+	if spec, ok := opJetMap[op]; ok {
+		return spec.prefix + suffix, spec.swapArgs
+	}
+	return "", false
+}
+
+// operatorReturnType returns the Simplicity return type for the jet that
+// corresponds to a given Go binary operator and integer width.
+func operatorReturnType(op token.Token, width string) string {
+	switch op {
+	case token.ADD, token.SUB:
+		// add_N / subtract_N return a (carry/borrow, result) pair
+		return "(bool, " + width + ")"
+	case token.MUL:
+		// multiply_N returns a double-width integer
+		switch width {
+		case "u8":
+			return "u16"
+		case "u16":
+			return "u32"
+		case "u64":
+			return "u128"
+		case "u128":
+			return "u256"
+		default:
+			return "u64" // multiply_32 → u64
+		}
+	case token.LSS, token.LEQ, token.GTR, token.GEQ, token.EQL:
+		return "bool"
+	default:
+		// divide, modulo, and, or, xor return same width as input
+		return width
+	}
+}
@@ -2,20 +2,32 @@
 package transpiler
 
 import (
+	"context"
 	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/token"
+	gotypes "go/types"
+	"io"
+	"math/big"
 	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/gensym"
 	"github.com/0ceanslim/go-simplicity/pkg/jets"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
 	simtypes "github.com/0ceanslim/go-simplicity/pkg/types"
 )
 
 // EitherFieldInfo tracks field names for Either struct types
 type EitherFieldInfo struct {
+	Selector       string   // snake_case name of the IsLeft or IsRight field
 	LeftFieldNames []string // snake_case names of left branch fields
+	LeftFieldTypes []string // types of the left branch fields, in order
 	LeftType       string   // combined left type (tuple if multiple)
 	RightFieldName string   // snake_case name of right branch field
 	RightType      string   // right branch type
@@ -23,9 +35,10 @@ type EitherFieldInfo struct {
 
 // Transpiler converts Go AST to SimplicityHL.
 type Transpiler struct {
-	typeMapper       *simtypes.TypeMapper
+	baseMapper       *simtypes.TypeMapper // Mapper as configured
+	typeMapper       *simtypes.TypeMapper // baseMapper scoped to the current file's imports
 	jetRegistry      *jets.JetRegistry
-	output           strings.Builder
+	printer          *printer
 	witnessValues    []WitnessValue
 	constants        []Constant
 	functions        []Function
@@ -37,15 +50,97 @@ type Transpiler struct {
 	customTypes      map[string]string           // Map custom type names to Simplicity types
 	eitherFields     map[string]*EitherFieldInfo // Go struct name → field info for Either types
 	structFieldTypes map[string]string           // "StructName.FieldName" → Simplicity type (for SHA256Add auto-select)
+	structDecls      map[string]*ast.StructType  // Plain struct types, represented as tuples
+	structFields     map[string][]structField    // Plain struct name → tuple layout
+	structParams     map[string]*structValue     // Struct parameters of the function being analyzed
+	optionStructs    map[string]string           // Option struct name → the plain struct its Value is
+	pathDecls        map[string]*ast.StructType  // Path struct types, laid out by layoutPaths
+	paths            map[string][]pathArm        // Path struct name → its spend paths
+	witnessStructs   []string                    // Destructuring of struct witnesses, emitted at the top of main
+	byteParts        map[string]bytePart         // Locals bound to std.UintNBytes, by Go name
+	params           map[string]string           // Go parameter or bound local name → type, for the function being analyzed
+	generics         map[string]*genericFunc     // Generic functions by SimplicityHL base name
+	instances        map[string]bool             // Specialized names of the instantiated generics
+	fset             *token.FileSet              // Positions for errors; may be nil
+	entry            string                      // Go function analyzed as the program root
+	entryCall        string                      // Call asserted by main when the entry is a predicate
+	entryPos         token.Pos                   // Position of the entry function declaration
+	library          bool                        // Emit only fn definitions
+	noThresholdTrees bool                        // Leave counting patterns to the statement lowering
+	noComments       bool                        // Drop Go doc comments from the output
+	noInline         bool                        // Call helpers instead of inlining their bodies
+	noCallFolding    bool                        // Call helpers instead of evaluating calls with known arguments
+	onGenerate       func()                      // Called between analysis and code generation
+	checked          bool                        // Assert divisors are nonzero before dividing
+	strict           bool                        // Fail on the first fallback instead of guessing
+	fallbacks        []Fallback                  // Heuristics the current call fell back to
+	entryDoc         string                      // Doc comment of the entry function
+	helpers          map[string]bool             // Compiler helper functions the program calls
+	imports          []Import                    // User packages the contract imports
+	pkgAliases       map[string]string           // Local import name → mangling prefix
+	jetNames         map[string]bool             // Local names of the jet package in the file being analyzed
+	pkgConstants     map[string][]Constant       // Mangling prefix → the package's constants
+	pkgPrefix        string                      // Prefix of the package being analyzed
+	pkgName          string                      // Go name of the package being analyzed
+	overrides        map[string]string           // Witness values supplied by the caller
+	ctx              context.Context             // Cancellation for the current ToSimplicityHL call
+	funcDecls        map[string]*ast.FuncDecl    // Helpers of the file, for folding calls with known arguments
+	constDecls       map[string]ast.Expr         // Values of the file's constants, for compile-time std calls
+	folder           *Folder                     // Locals with known values in the body being analyzed
+	expr             *Translator                 // Lowers expressions against the symbols collected so far
+	snakeNames       map[string]string           // Go name → toSnakeCase, for the current call
+	temps            gensym.Names                // Temporaries introduced in the current call
+	paramPatterns    map[string]*regexp.Regexp   // Parameter name → its word-boundary pattern, for inlining
+	mappedTypes      map[arrayKey]string         // Go array types already mapped, interned
+}
+
+// JetImportPath is the import path of the compiler-provided jet package.
+const JetImportPath = "simplicity/jet"
+
+// JetPackageNames returns the names under which file imports the jet
+// package: jet, or the alias of import j "simplicity/jet". Blank and dot
+// imports bind no name. The import paths of stub packages, which declare
+// jets of JetDecl.Package, are jet packages as well.
+func JetPackageNames(file *ast.File, stubs ...string) map[string]bool {
+	names := make(map[string]bool)
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || path != JetImportPath && !slices.Contains(stubs, path) {
+			continue
+		}
+		switch {
+		case spec.Name == nil:
+			names[path[strings.LastIndex(path, "/")+1:]] = true
+		case spec.Name.Name != "_" && spec.Name.Name != ".":
+			names[spec.Name.Name] = true
+		}
+	}
+	return names
+}
+
+// isJet reports whether x names the jet package in the file being
+// analyzed, so that x.Sel is a jet.
+func (t *Transpiler) isJet(x ast.Expr) bool {
+	ident, ok := x.(*ast.Ident)
+	return ok && t.jetNames[ident.Name]
+}
+
+// Import is a user package whose functions are compiled into the program.
+type Import struct {
+	Path  string      // Import path as written in the contract
+	Name  string      // Package name, used as the mangling prefix
+	Files []*ast.File // Parsed source files
 }
 
 // JetCall represents a jet function call in the code.
 type JetCall struct {
-	VarName    string // Variable name being assigned (empty if inline)
-	JetName    string // Simplicity jet name
-	Args       string // Comma-separated arguments
-	ReturnType string // Return type from jet registry
-	IsWitness  bool   // True if argument should come from witness
+	VarName    string    // Variable name being assigned (empty if inline)
+	JetName    string    // Simplicity jet name; empty for a let that binds Args itself
+	Args       string    // Comma-separated arguments
+	ReturnType string    // Return type from jet registry
+	IsWitness  bool      // True if argument should come from witness
+	Wrap       bool      // Keep the low half, of type ReturnType, of a double-width product
+	Pos        token.Pos // Go statement the call was lowered from
 }
 
 // WitnessValue represents a witness variable declaration.
@@ -54,6 +149,78 @@ type WitnessValue struct {
 	Type       string
 	Value      string
 	GoTypeName string // Original Go struct type name, for Either field lookup
+	// Selector and Fields describe a witness declared with an Either
+	// struct: the snake_case name of its IsLeft or IsRight field, and the
+	// fields each arm carries. A path struct has Fields but no Selector.
+	// Both are empty for other witnesses.
+	Selector string
+	Fields   []WitnessField
+	Doc      string // Go doc comment of the declaration, without comment markers
+	Pos      token.Pos
+	// Constant reports a value the Go code computes from constants alone,
+	// which could be a param instead. A literal initializer is taken for
+	// the placeholder of a value the spender sets.
+	Constant bool
+}
+
+// fromConstants reports whether expr, the initializer of a witness, is
+// computed from constants alone: it folds, or reads nothing but the file's
+// constants, which are params, through operators and conversions. A
+// literal is not: it is the placeholder of a value the spender sets.
+func (t *Transpiler) fromConstants(expr ast.Expr) bool {
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.BasicLit:
+		return false
+	case *ast.Ident:
+		if e.Name == "true" || e.Name == "false" {
+			return false
+		}
+	}
+	if _, ok := t.folder.Fold(expr); ok {
+		return true
+	}
+	constant := true
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Ident:
+			if n.Obj != nil && n.Obj.Kind != ast.Con {
+				constant = false
+			}
+		case *ast.CallExpr:
+			fun, isIdent := n.Fun.(*ast.Ident)
+			if !isIdent || fun.Obj != nil || len(n.Args) != 1 {
+				constant = false
+			} else if _, err := t.typeMapper.MapGoType(fun); err != nil {
+				constant = false
+			}
+		case *ast.SelectorExpr, *ast.IndexExpr, *ast.CompositeLit, *ast.FuncLit:
+			constant = false
+		}
+		return constant
+	})
+	return constant
+}
+
+// WitnessField is a struct field carried by one arm of an Either witness.
+type WitnessField struct {
+	Name   string // snake_case field name, path.field on a path struct
+	Type   string
+	Branch string // "Left" or "Right", a chain such as "Right.Left" on a path struct
+}
+
+// DeclaredType returns the type the witness is declared with in the
+// witness module, inferring it from the value for "auto" entries.
+func (witness WitnessValue) DeclaredType() string {
+	if witness.Type != "auto" {
+		return witness.Type
+	}
+	if witness.Value == "true" || witness.Value == "false" {
+		return "bool"
+	}
+	if _, err := strconv.ParseInt(witness.Value, 10, 64); err == nil {
+		return "u64"
+	}
+	return "bool" // default to bool
 }
 
 // Constant represents a Go const declaration mapped to a param module entry.
@@ -61,14 +228,27 @@ type Constant struct {
 	Name  string
 	Type  string
 	Value string
+	Doc   string // Go doc comment, without comment markers
 }
 
 // Function represents a user-defined helper function.
 type Function struct {
 	Name       string
+	GoName     string // Go declaration name, package-qualified for imports
 	Parameters []Parameter
 	ReturnType string
 	Body       string
+	Pos        token.Pos // Go function declaration
+	Doc        string    // Go doc comment, without comment markers
+	// Called reports that call sites call the function instead of inlining
+	// its body: a body that destructures struct parameters or that returns
+	// a value after statements does not inline, and instantiations of
+	// generic functions are shared.
+	Called bool
+	// Stub reports a Go declaration without a body: the function is
+	// defined outside the program, by a module included with it, and is
+	// called but not emitted.
+	Stub bool
 }
 
 // Parameter represents a function parameter.
@@ -77,18 +257,135 @@ type Parameter struct {
 	Type string
 }
 
-// New creates a new transpiler instance
+// Options configures a Transpiler.
+type Options struct {
+	// Style controls output formatting. The zero value selects DefaultStyle.
+	Style Style
+	// Entry names the Go function compiled as the SimplicityHL main. The
+	// empty string selects main().
+	Entry string
+	// Library emits every function and nothing else: no witness or param
+	// module and no main. Constants are inlined at their use sites.
+	Library bool
+	// WitnessValues replaces the values of witness constants, keyed by
+	// their emitted name. Each value is SimplicityHL literal syntax and is
+	// checked against the witness's declared type.
+	WitnessValues map[string]string
+	// MaxOutputBytes stops generation once the program grows past this many
+	// bytes. Zero means no limit.
+	MaxOutputBytes int
+	// MaxNodes stops generation once the estimated size of the program, as
+	// CountNodes counts it, grows past this many expression nodes. Zero
+	// means no limit.
+	MaxNodes int
+	// TypeMapper supplies the Go → Simplicity type mappings, including any
+	// registered by the caller. Nil selects a default mapper.
+	TypeMapper *simtypes.TypeMapper
+	// Jets is the jet table calls are looked up in, typically extended with
+	// JetRegistry.Declare for jets the builtin table lacks. Nil selects
+	// jets.NewRegistry().
+	Jets *jets.JetRegistry
+	// FileSet positions the source in errors that point at a declaration
+	// or call site. Nil leaves positions out.
+	FileSet *token.FileSet
+	// NoThresholdTrees keeps a function that counts the conditions that
+	// hold and compares the count with a constant from being lowered to a
+	// comparison tree.
+	NoThresholdTrees bool
+	// NoComments leaves the doc comments of functions, constants and
+	// witness declarations out of the output.
+	NoComments bool
+	// CheckedArithmetic computes a division or remainder by a value known
+	// only at spend time with a helper that fails the spend when the
+	// divisor is zero.
+	CheckedArithmetic bool
+	// NoInline calls every helper instead of substituting its body at the
+	// call site. Calls with known arguments are still folded, unless
+	// NoCallFolding is set.
+	NoInline bool
+	// NoCallFolding keeps a call of a helper whose arguments are known,
+	// rather than evaluating the helper's body and using its result.
+	NoCallFolding bool
+	// OnGenerate, if set, is called when analysis is done and code
+	// generation starts, for callers that time the two.
+	OnGenerate func()
+	// Strict fails on the first construct the transpiler would otherwise
+	// guess at, such as an expression it cannot lower or a witness whose
+	// type it infers from the value: see Fallbacks.
+	Strict bool
+}
+
+// New creates a new transpiler instance with default options.
 func New() *Transpiler {
+	return NewWithOptions(Options{})
+}
+
+// NewWithOptions creates a new transpiler instance with the given options.
+func NewWithOptions(opts Options) *Transpiler {
+	style := opts.Style
+	if style == (Style{}) {
+		style = DefaultStyle()
+	}
+	entry := opts.Entry
+	if entry == "" {
+		entry = "main"
+	}
+	mapper := opts.TypeMapper
+	if mapper == nil {
+		mapper = simtypes.NewTypeMapper()
+	}
+	registry := opts.Jets
+	if registry == nil {
+		registry = jets.NewRegistry()
+	}
 	return &Transpiler{
-		typeMapper:   simtypes.NewTypeMapper(),
-		jetRegistry:  jets.NewRegistry(),
-		eitherFields: make(map[string]*EitherFieldInfo),
+		baseMapper:       mapper,
+		typeMapper:       mapper,
+		jetRegistry:      registry,
+		printer:          newPrinter(style, opts.MaxOutputBytes, opts.MaxNodes, opts.FileSet),
+		eitherFields:     make(map[string]*EitherFieldInfo),
+		entry:            entry,
+		library:          opts.Library,
+		noThresholdTrees: opts.NoThresholdTrees,
+		noComments:       opts.NoComments,
+		noInline:         opts.NoInline,
+		noCallFolding:    opts.NoCallFolding,
+		onGenerate:       opts.OnGenerate,
+		checked:          opts.CheckedArithmetic,
+		strict:           opts.Strict,
+		overrides:        opts.WitnessValues,
+		fset:             opts.FileSet,
+		ctx:              context.Background(),
+		snakeNames:       make(map[string]string),
+		paramPatterns:    make(map[string]*regexp.Regexp),
+		mappedTypes:      make(map[arrayKey]string),
 	}
 }
 
 // ToSimplicityHL transpiles Go AST to SimplicityHL code.
 func (t *Transpiler) ToSimplicityHL(file *ast.File) (string, error) {
-	t.output.Reset()
+	return t.ToSimplicityHLContext(context.Background(), file)
+}
+
+// ToSimplicityHLContext is ToSimplicityHL with cancellation. The context is
+// checked between declarations and statements and on every unrolled loop
+// iteration; an expired context fails with an error wrapping ctx.Err().
+func (t *Transpiler) ToSimplicityHLContext(ctx context.Context, file *ast.File) (string, error) {
+	var out strings.Builder
+	// Generated code is about as long as its source.
+	out.Grow(int(file.FileEnd - file.FileStart))
+	if err := t.WriteSimplicityHL(ctx, file, &out); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// WriteSimplicityHL transpiles file and streams the result to w, one
+// top-level item at a time. Analysis completes before anything is written;
+// an error during generation may leave partial output in w.
+func (t *Transpiler) WriteSimplicityHL(ctx context.Context, file *ast.File, w io.Writer) error {
+	t.ctx = ctx
+	t.printer.reset(w)
 	t.witnessValues = nil
 	t.constants = nil
 	t.functions = nil
@@ -100,57 +397,559 @@ func (t *Transpiler) ToSimplicityHL(file *ast.File) (string, error) {
 	t.customTypes = make(map[string]string)
 	t.eitherFields = make(map[string]*EitherFieldInfo)
 	t.structFieldTypes = make(map[string]string)
+	t.structDecls = make(map[string]*ast.StructType)
+	t.structFields = make(map[string][]structField)
+	t.structParams = make(map[string]*structValue)
+	t.optionStructs = make(map[string]string)
+	t.pathDecls = make(map[string]*ast.StructType)
+	t.paths = make(map[string][]pathArm)
+	t.witnessStructs = nil
+	t.byteParts = make(map[string]bytePart)
+	t.params = make(map[string]string)
+	t.generics = make(map[string]*genericFunc)
+	t.instances = make(map[string]bool)
+	t.entryCall = ""
+	t.entryPos = token.NoPos
+	t.entryDoc = ""
+	t.pkgAliases = make(map[string]string)
+	t.pkgConstants = make(map[string][]Constant)
+	t.funcDecls = make(map[string]*ast.FuncDecl)
+	t.helpers = make(map[string]bool)
+	t.snakeNames = make(map[string]string)
+	t.temps.Reset()
+	t.reserveTemps(file)
+	t.fallbacks = nil
+	t.paramPatterns = make(map[string]*regexp.Regexp)
+	t.mappedTypes = make(map[arrayKey]string)
 
 	// Phase 1: Analyze the code and extract all computable values
 	if err := t.analyzeCode(file); err != nil {
-		return "", fmt.Errorf("code analysis failed: %w", err)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("compile canceled during analysis: %w", ctxErr)
+		}
+		return fmt.Errorf("code analysis failed: %w", diag.UnsupportedSyntax.Wrap(err))
+	}
+	if err := t.encodeWitnesses(); err != nil {
+		return err
+	}
+	if err := t.applyWitnessValues(); err != nil {
+		return err
+	}
+	if err := t.guessedTypes(); err != nil {
+		return err
 	}
 
 	// Phase 2: Generate SimplicityHL code
+	if t.onGenerate != nil {
+		t.onGenerate()
+	}
 	t.generateCode()
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("compile canceled during code generation: %w", err)
+	}
+	return t.printer.finish()
+}
 
-	return t.output.String(), nil
+// Items returns the top-level items of the most recent ToSimplicityHL
+// output, in order, or of the part rendered before generation failed.
+func (t *Transpiler) Items() []Item {
+	return t.printer.items
+}
+
+// SourceMap returns, for each line of the most recent ToSimplicityHL output,
+// the position of the Go code it was generated from. Index i describes line
+// i+1; lines without a Go origin, such as module headers, hold token.NoPos.
+func (t *Transpiler) SourceMap() []token.Pos {
+	return append([]token.Pos(nil), t.printer.lines...)
+}
+
+// Witnesses returns the witness entries declared by the most recent
+// ToSimplicityHL call, in emission order.
+func (t *Transpiler) Witnesses() []WitnessValue {
+	witnesses := append([]WitnessValue(nil), t.witnessValues...)
+	for i, w := range witnesses {
+		if arms, ok := t.paths[w.GoTypeName]; ok {
+			witnesses[i].Fields = t.pathFields(arms)
+			continue
+		}
+		info, ok := t.eitherFields[w.GoTypeName]
+		if !ok {
+			continue
+		}
+		witnesses[i].Selector = info.Selector
+		for j, name := range info.LeftFieldNames {
+			witnesses[i].Fields = append(witnesses[i].Fields, WitnessField{Name: name, Type: info.LeftFieldTypes[j], Branch: "Left"})
+		}
+		witnesses[i].Fields = append(witnesses[i].Fields, WitnessField{Name: info.RightFieldName, Type: info.RightType, Branch: "Right"})
+	}
+	return witnesses
+}
+
+// Functions returns the helper functions emitted by the most recent
+// ToSimplicityHL call, in emission order. A main() entry is not included.
+func (t *Transpiler) Functions() []Function {
+	return append([]Function(nil), t.functions...)
+}
+
+// Constants returns the param module entries of the most recent
+// ToSimplicityHL call.
+func (t *Transpiler) Constants() []Constant {
+	return append([]Constant(nil), t.constants...)
+}
+
+// SetImports registers the user packages available to the next
+// ToSimplicityHL call. Their functions are emitted with a package prefix.
+func (t *Transpiler) SetImports(imports []Import) {
+	t.imports = imports
+}
+
+// QualifiedName mangles a package-qualified identifier into a SimplicityHL
+// function name: checks.MinAmount becomes checks_min_amount.
+func (t *Transpiler) QualifiedName(pkg, name string) string {
+	return t.toSnakeCase(pkg) + "_" + t.toSnakeCase(name)
 }
 
 func (t *Transpiler) analyzeCode(file *ast.File) error {
-	// Find the main function and extract witness values
+	t.typeMapper = t.baseMapper.WithImports(t.localImports(file))
+	t.typeMapper = t.typeMapper.WithNamedTypes(namedTypes(file, t.typeMapper))
+	t.jetNames = JetPackageNames(file, t.jetRegistry.Packages()...)
+	if err := t.lowerCustomJets(file); err != nil {
+		return err
+	}
+	t.lowerRangeInts(file)
+	t.lowerIota(file)
 	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name != t.entry && fn.Type.TypeParams == nil {
+			t.funcDecls[fn.Name.Name] = fn
+		}
+	}
+	t.constDecls = fileConstants(file)
+	t.folder = NewFolder(t.typeMapper, t.funcDecls)
+	t.folder.ctx = t.ctx
+	t.folder.noCalls = t.noCallFolding
+	t.folder.builtins = t.foldBuiltinCall
+	t.expr = NewTranslator(transpilerSymbols{t}, t.folder)
+	t.expr.calls = t.evaluateCallExpr
+	t.expr.structs = t.structLiteral
+	t.expr.fallback = t.fallback
+	if t.checked {
+		t.expr.divisions = t.checkedDivision
+	}
+	if err := t.analyzeImports(file); err != nil {
+		return err
+	}
+	if err := t.checkKeyTypes(file); err != nil {
+		return err
+	}
+	if t.library {
+		return t.analyzeLibrary(file)
+	}
+	if err := t.analyzeTypes(file); err != nil {
+		return err
+	}
+	if err := t.declareGenerics(file); err != nil {
+		return err
+	}
+
+	// With an explicit entry, only the helpers it reaches belong to the
+	// program; sibling entry points in the same file are left out.
+	var reachable map[string]bool
+	if t.entry != "main" {
+		if !hasFunc(file, t.entry) {
+			return diag.EntryNotFound.Errorf("entry function %s not found", t.entry)
+		}
+		reachable = reachableFuncs(file, t.entry)
+	}
+
+	// Find the entry function and extract witness values
+	for _, decl := range file.Decls {
+		if err := t.ctx.Err(); err != nil {
+			return err
+		}
 		if funcDecl, ok := decl.(*ast.FuncDecl); ok {
-			if funcDecl.Name.Name == "main" {
-				if err := t.analyzeMainFunction(funcDecl); err != nil {
+			if funcDecl.Name.Name == t.entry && funcDecl.Recv == nil {
+				if funcDecl.Type.TypeParams != nil {
+					return diag.EntrySignature.Wrap(t.errorAt(funcDecl.Pos(), "entry function %s must not be generic", t.entry))
+				}
+				t.entryPos = funcDecl.Pos()
+				t.entryDoc = docText(funcDecl.Doc)
+				if funcDecl.Type.Results != nil && len(funcDecl.Type.Results.List) > 0 {
+					if err := t.analyzeEntryPredicate(funcDecl); err != nil {
+						return err
+					}
+				} else if err := t.analyzeEntryBody(funcDecl); err != nil {
 					return err
 				}
+			} else if (reachable != nil && !reachable[funcDecl.Name.Name]) || funcDecl.Type.TypeParams != nil {
+				continue
 			} else {
 				if err := t.analyzeFunction(funcDecl); err != nil {
 					return err
 				}
 			}
 		}
-		if genDecl, ok := decl.(*ast.GenDecl); ok {
-			if genDecl.Tok == token.CONST {
-				if err := t.analyzeConstants(genDecl); err != nil {
+		if genDecl, ok := decl.(*ast.GenDecl); ok && (genDecl.Tok == token.CONST || genDecl.Tok == token.VAR) {
+			if err := t.analyzeConstants(genDecl); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// analyzeImports compiles the functions of every imported user package ahead
+// of the contract so that qualified calls can be inlined. Package constants
+// are kept apart from the contract's param module and inlined at use sites.
+func (t *Transpiler) analyzeImports(file *ast.File) error {
+	byPath := make(map[string]Import)
+	for _, imp := range t.imports {
+		byPath[imp.Path] = imp
+	}
+	prefixes := make(map[string]string)
+
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		imp, ok := byPath[path]
+		if !ok {
+			continue
+		}
+		prefix := t.toSnakeCase(imp.Name)
+		if other, dup := prefixes[prefix]; dup && other != path {
+			return fmt.Errorf("imported packages %s and %s both use the name %s", other, path, imp.Name)
+		}
+		prefixes[prefix] = path
+
+		local := imp.Name
+		if spec.Name != nil {
+			local = spec.Name.Name
+		}
+		if local == "_" {
+			continue
+		}
+		t.pkgAliases[local] = prefix
+		if _, done := t.pkgConstants[prefix]; done {
+			continue
+		}
+
+		saved, savedLibrary, savedJets := t.constants, t.library, t.jetNames
+		t.constants, t.library, t.pkgPrefix, t.pkgName = nil, true, prefix+"_", imp.Name
+		for _, f := range imp.Files {
+			t.jetNames = JetPackageNames(f, t.jetRegistry.Packages()...)
+			err := t.lowerCustomJets(f)
+			if err == nil {
+				t.lowerRangeInts(f)
+				t.lowerIota(f)
+				err = t.analyzeLibrary(f)
+			}
+			if err != nil {
+				t.constants, t.library, t.pkgPrefix, t.pkgName, t.jetNames = saved, savedLibrary, "", "", savedJets
+				return fmt.Errorf("package %s: %w", path, err)
+			}
+		}
+		t.pkgConstants[prefix] = t.constants
+		t.constants, t.library, t.pkgPrefix, t.pkgName, t.jetNames = saved, savedLibrary, "", "", savedJets
+	}
+	return nil
+}
+
+// localImports maps each local package name in file to its import path, so
+// the type mapper can resolve qualified types registered by package.
+func (t *Transpiler) localImports(file *ast.File) map[string]string {
+	names := make(map[string]string)
+	for _, imp := range t.imports {
+		names[imp.Path] = imp.Name
+	}
+	locals := make(map[string]string)
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		local, ok := names[path]
+		if !ok {
+			local = path[strings.LastIndex(path, "/")+1:]
+		}
+		if spec.Name != nil {
+			local = spec.Name.Name
+		}
+		locals[local] = path
+	}
+	return locals
+}
+
+// packageConstant resolves a qualified constant such as checks.MinAmount to
+// its inlined value.
+func (t *Transpiler) packageConstant(pkg, name string) (string, bool) {
+	if path, ok := t.typeMapper.ImportPath(pkg); ok && path == simtypes.BitcoinImportPath {
+		v, ok := bitcoinConstants[name]
+		return v.String(), ok
+	}
+	prefix, ok := t.pkgAliases[pkg]
+	if !ok {
+		return "", false
+	}
+	want := strings.ToUpper(t.toSnakeCase(name))
+	for _, c := range t.pkgConstants[prefix] {
+		if c.Name == want {
+			return c.Value, true
+		}
+	}
+	return "", false
+}
+
+// analyzeLibrary collects constants, types, and every function except main.
+// Nothing is pruned: a library's callers are not known at compile time.
+func (t *Transpiler) analyzeLibrary(file *ast.File) error {
+	if err := t.analyzeTypes(file); err != nil {
+		return err
+	}
+	if err := t.declareGenerics(file); err != nil {
+		return err
+	}
+	for _, decl := range file.Decls {
+		if err := t.ctx.Err(); err != nil {
+			return err
+		}
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			// Generic functions are emitted per instantiation, at call sites
+			if d.Recv != nil || d.Name.Name == "main" || d.Type.TypeParams != nil {
+				continue
+			}
+			if err := t.analyzeFunction(d); err != nil {
+				return err
+			}
+		case *ast.GenDecl:
+			if d.Tok == token.CONST || d.Tok == token.VAR {
+				if err := t.analyzeConstants(d); err != nil {
 					return err
 				}
 			}
-			if genDecl.Tok == token.TYPE {
-				if err := t.analyzeTypeDeclarations(genDecl); err != nil {
-					return err
+		}
+	}
+	return nil
+}
+
+// analyzeEntryPredicate handles an entry function that returns a result. The
+// function is emitted as a regular fn and main asserts its return value.
+func (t *Transpiler) analyzeEntryPredicate(funcDecl *ast.FuncDecl) error {
+	if funcDecl.Name.Name == "main" {
+		return diag.MainSignature.Errorf("main must not return a value")
+	}
+	if err := t.analyzeFunction(funcDecl); err != nil {
+		return err
+	}
+	function := t.functions[len(t.functions)-1]
+	if function.ReturnType != "bool" {
+		return diag.EntrySignature.Errorf("entry function %s must return bool, got %s", funcDecl.Name.Name, function.ReturnType)
+	}
+
+	// Parameters are registered only after the body is analyzed so that
+	// references inside the fn stay bound to its parameters.
+	params, err := t.entryParamWitnesses(funcDecl)
+	if err != nil {
+		return err
+	}
+	var args []string
+	for _, p := range params {
+		if err := t.checkWitnessCollision(funcDecl.Name.Name, p.Name); err != nil {
+			return err
+		}
+		t.witnessValues = append(t.witnessValues, p)
+		args = append(args, fmt.Sprintf("witness::%s", p.Name))
+	}
+	t.entryCall = fmt.Sprintf("%s(%s)", function.Name, strings.Join(args, ", "))
+	return nil
+}
+
+// analyzeEntryBody handles an entry function without a result, whose body
+// becomes the SimplicityHL main. Its parameters are declared as witnesses
+// before the body is analyzed so that references resolve to witness::NAME.
+func (t *Transpiler) analyzeEntryBody(funcDecl *ast.FuncDecl) error {
+	params, err := t.entryParamWitnesses(funcDecl)
+	if err != nil {
+		return err
+	}
+	t.witnessValues = append(t.witnessValues, params...)
+	for _, field := range funcDecl.Type.Params.List {
+		if ident, ok := field.Type.(*ast.Ident); ok {
+			for _, name := range field.Names {
+				t.bindWitnessStruct(name.Name, ident.Name)
+			}
+		}
+	}
+	if err := t.analyzeMainFunction(funcDecl); err != nil {
+		return err
+	}
+	for _, p := range params {
+		count := 0
+		for _, w := range t.witnessValues {
+			if strings.EqualFold(w.Name, p.Name) {
+				count++
+			}
+		}
+		if count > 1 {
+			return diag.DuplicateWitness.Errorf("parameter of %s becomes witness %s, which is already declared", funcDecl.Name.Name, p.Name)
+		}
+	}
+	return nil
+}
+
+// entryParamWitnesses maps the parameters of an entry function to witness
+// entries. Values arrive at spend time, so each entry gets the type's
+// placeholder value.
+func (t *Transpiler) entryParamWitnesses(funcDecl *ast.FuncDecl) ([]WitnessValue, error) {
+	var params []WitnessValue
+	if funcDecl.Type.Params == nil {
+		return nil, nil
+	}
+	for _, field := range funcDecl.Type.Params.List {
+		var simplicityType, goTypeName string
+		if ident, ok := field.Type.(*ast.Ident); ok {
+			if customType, found := t.customTypes[ident.Name]; found {
+				simplicityType = customType
+				goTypeName = ident.Name
+			}
+		}
+		if simplicityType == "" {
+			var err error
+			simplicityType, err = t.typeMapper.MapGoType(field.Type)
+			if err != nil {
+				return nil, fmt.Errorf("entry function %s: %w", funcDecl.Name.Name, err)
+			}
+		}
+		for _, name := range field.Names {
+			if name.Name == "_" {
+				return nil, diag.EntrySignature.Errorf("entry function %s: parameters must be named to become witnesses", funcDecl.Name.Name)
+			}
+			if err := t.checkConstantCollision(name); err != nil {
+				return nil, err
+			}
+			witnessName := strings.ToUpper(t.toSnakeCase(name.Name))
+			for _, p := range params {
+				if p.Name == witnessName {
+					return nil, diag.DuplicateWitness.Errorf("parameter of %s becomes witness %s, which is already declared", funcDecl.Name.Name, witnessName)
 				}
 			}
+			params = append(params, WitnessValue{
+				Name:       witnessName,
+				Type:       simplicityType,
+				Value:      zeroValue(simplicityType),
+				GoTypeName: goTypeName,
+				Pos:        name.Pos(),
+			})
 		}
 	}
+	return params, nil
+}
+
+// checkConstantCollision reports an error if the witness declared as name
+// would share its SimplicityHL name with a constant, such as maxFee with
+// MAX_FEE: references to it would read the constant.
+func (t *Transpiler) checkConstantCollision(name *ast.Ident) error {
+	witness := WitnessName(name.Name)
+	for _, c := range t.constants {
+		if c.Name == witness {
+			return diag.DuplicateWitness.Wrap(t.errorAt(name.Pos(), "%s becomes witness::%s, which is also the name of param::%s; rename one of them", name.Name, witness, c.Name))
+		}
+	}
+	return nil
+}
 
+// checkWitnessCollision reports an error if a witness named name already
+// exists.
+func (t *Transpiler) checkWitnessCollision(funcName, name string) error {
+	for _, w := range t.witnessValues {
+		if strings.EqualFold(w.Name, name) {
+			return diag.DuplicateWitness.Errorf("parameter of %s becomes witness %s, which is already declared", funcName, name)
+		}
+	}
 	return nil
 }
 
+// hasFunc reports whether file declares a top-level function named name.
+func hasFunc(file *ast.File, name string) bool {
+	for _, decl := range file.Decls {
+		if funcDecl, ok := decl.(*ast.FuncDecl); ok && funcDecl.Recv == nil && funcDecl.Name.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// reachableFuncs returns the top-level functions called, directly or
+// transitively, from the function named root.
+func reachableFuncs(file *ast.File, root string) map[string]bool {
+	decls := make(map[string]*ast.FuncDecl)
+	for _, decl := range file.Decls {
+		if funcDecl, ok := decl.(*ast.FuncDecl); ok && funcDecl.Recv == nil {
+			decls[funcDecl.Name.Name] = funcDecl
+		}
+	}
+
+	seen := map[string]bool{root: true}
+	queue := []string{root}
+	for len(queue) > 0 {
+		funcDecl := decls[queue[0]]
+		queue = queue[1:]
+		if funcDecl == nil || funcDecl.Body == nil {
+			continue
+		}
+		ast.Inspect(funcDecl.Body, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok {
+				if ident, ok := call.Fun.(*ast.Ident); ok && decls[ident.Name] != nil && !seen[ident.Name] {
+					seen[ident.Name] = true
+					queue = append(queue, ident.Name)
+				}
+			}
+			return true
+		})
+	}
+	return seen
+}
+
 func (t *Transpiler) analyzeMainFunction(funcDecl *ast.FuncDecl) error {
+	t.folder.push()
+	defer t.folder.pop()
+
 	// Extract variable declarations and their computed values
-	for _, stmt := range funcDecl.Body.List {
+	for i, stmt := range funcDecl.Body.List {
+		if err := t.ctx.Err(); err != nil {
+			return err
+		}
+		if calls, ok, err := t.lowerBranchAssign(stmt); err != nil {
+			return err
+		} else if ok {
+			t.jetCalls = append(t.jetCalls, calls...)
+			continue
+		}
+		t.forgetBranchAssigned(stmt)
+		if assign, ok, err := t.reassignment(stmt); err != nil {
+			return err
+		} else if ok && !t.lowersValue(assign) {
+			if err := t.droppedStatement(stmt, statementKind(stmt)); err != nil {
+				return err
+			}
+			continue
+		} else if ok {
+			stmt = assign
+		}
 		switch s := stmt.(type) {
 		case *ast.DeclStmt:
+			if genDecl, ok := s.Decl.(*ast.GenDecl); ok && genDecl.Tok == token.CONST {
+				if err := t.localConsts(genDecl); err != nil {
+					return err
+				}
+				continue
+			}
 			if genDecl, ok := s.Decl.(*ast.GenDecl); ok && genDecl.Tok == token.VAR {
 				for _, spec := range genDecl.Specs {
 					if valueSpec, ok := spec.(*ast.ValueSpec); ok {
+						doc := specDoc(genDecl, valueSpec)
 						for i, name := range valueSpec.Names {
 							// Check if this is a witness variable (array type with no value)
 							if valueSpec.Type != nil && len(valueSpec.Values) == 0 {
@@ -169,24 +968,31 @@ func (t *Transpiler) analyzeMainFunction(funcDecl *ast.FuncDecl) error {
 								// If not a custom type, use the type mapper
 								if simplicityType == "" {
 									var err error
-									simplicityType, err = t.typeMapper.MapGoType(valueSpec.Type)
+									simplicityType, err = t.mapType(valueSpec.Type)
 									if err != nil {
 										return err
 									}
 								}
 
+								if err := t.checkConstantCollision(name); err != nil {
+									return err
+								}
 								t.witnessValues = append(t.witnessValues, WitnessValue{
 									Name:       strings.ToUpper(t.toSnakeCase(name.Name)),
 									Type:       simplicityType,
-									Value:      generateWitnessPlaceholder(simplicityType),
+									Value:      zeroValue(simplicityType),
 									GoTypeName: goTypeName,
+									Doc:        doc,
+									Pos:        name.Pos(),
 								})
+								t.bindWitnessStruct(name.Name, goTypeName)
+								t.trackArray(name.Name, simplicityType, nil)
 								continue
 							}
 
 							if i < len(valueSpec.Values) {
 								// Try to evaluate the expression at compile time
-								value, err := t.evaluateExpression(valueSpec.Values[i])
+								value, err := t.expr.Translate(valueSpec.Values[i])
 								if err != nil {
 									return fmt.Errorf("failed to evaluate expression for %s: %w", name.Name, err)
 								}
@@ -200,10 +1006,36 @@ func (t *Transpiler) analyzeMainFunction(funcDecl *ast.FuncDecl) error {
 									typ = simplicityType
 								}
 
+								// The witness placeholder of a computed initializer is
+								// the value the computation yields.
+								t.folder.forget(name.Name)
+								constant := t.fromConstants(valueSpec.Values[i])
+								restore := t.folder.withCalls()
+								v, ok := t.folder.FoldDerived(valueSpec.Values[i])
+								restore()
+								if ok {
+									if v.Int != nil && v.Type == "" && isUIntType(typ) {
+										v.Type = typ
+										v, ok = checkWidth(v)
+									}
+									if ok && v.Type == typ {
+										t.folder.bind(name.Name, v, true)
+										if _, lit := valueSpec.Values[i].(*ast.BasicLit); !lit {
+											value = v.String()
+										}
+									}
+								}
+
+								if err := t.checkConstantCollision(name); err != nil {
+									return err
+								}
 								t.witnessValues = append(t.witnessValues, WitnessValue{
-									Name:  t.toSnakeCase(name.Name),
-									Type:  typ,
-									Value: value,
+									Name:     t.toSnakeCase(name.Name),
+									Type:     typ,
+									Value:    value,
+									Doc:      doc,
+									Pos:      name.Pos(),
+									Constant: constant,
 								})
 							}
 						}
@@ -211,17 +1043,38 @@ func (t *Transpiler) analyzeMainFunction(funcDecl *ast.FuncDecl) error {
 				}
 			}
 		case *ast.AssignStmt:
+			// An element assignment sets that element of a witness
+			// whose value is known, such as test setup's msg[0] = 0x01.
+			// Main has no lets of arrays to rebuild for any other.
+			if array, k, ok, err := t.elementAssignment(s); err != nil {
+				return err
+			} else if ok {
+				if !t.assignWitnessElement(s, array, k) {
+					return diag.UnsupportedSyntax.Wrap(t.errorAt(s.Pos(), "the assignment to %s is not supported in %s: %s is not an array witness whose elements are known at compile time", gotypes.ExprString(s.Lhs[0]), funcDecl.Name.Name, array.Name))
+				}
+				continue
+			}
 			// Handle := assignments
+			if _, ok := s.Lhs[0].(*ast.Ident); !ok || len(s.Lhs) != 1 || len(s.Rhs) != 1 {
+				if err := t.droppedStatement(s, "the assignment"); err != nil {
+					return err
+				}
+				continue
+			}
 			if len(s.Lhs) == 1 && len(s.Rhs) == 1 {
 				if ident, ok := s.Lhs[0].(*ast.Ident); ok {
 					// Skip blank identifier assignments: _ = someVar
 					if ident.Name == "_" {
 						continue
 					}
+					// A witness's value is a literal, whatever calls fold.
+					restore := t.folder.withCalls()
+					folded, derived, known := t.trackAssign(s)
+					restore()
 					// Check if RHS is a jet call
 					if callExpr, ok := s.Rhs[0].(*ast.CallExpr); ok {
 						if sel, ok := callExpr.Fun.(*ast.SelectorExpr); ok {
-							if selIdent, ok := sel.X.(*ast.Ident); ok && selIdent.Name == "jet" {
+							if t.isJet(sel.X) {
 								// This is a jet call assignment: varName := jet.X()
 								jetName := sel.Sel.Name
 								// SHA256Add auto-select: resolve before registry lookup
@@ -230,15 +1083,18 @@ func (t *Transpiler) analyzeMainFunction(funcDecl *ast.FuncDecl) error {
 								}
 								jetInfo, found := t.jetRegistry.Lookup(jetName)
 								if !found {
-									return fmt.Errorf("unknown jet function: jet.%s", jetName)
+									return diag.UnknownJet.Errorf("unknown jet function: jet.%s", jetName)
+								}
+								if _, ok := optionPayload(jetInfo.ReturnType); ok {
+									return t.optionJetUse(s.Pos(), jetInfo)
 								}
 
-								// Evaluate arguments using evaluateJetArg so that
+								// Evaluate arguments with TranslateArg so that
 								// inline binary expressions (e.g. a + b as a jet arg)
-								// are handled correctly via binaryExprToJetCall.
+								// become operator jet calls.
 								var argStrs []string
 								for _, arg := range callExpr.Args {
-									argStr, err := t.evaluateJetArg(arg)
+									argStr, err := t.expr.TranslateArg(arg)
 									if err != nil {
 										return err
 									}
@@ -251,56 +1107,107 @@ func (t *Transpiler) analyzeMainFunction(funcDecl *ast.FuncDecl) error {
 									JetName:    jetInfo.SimplicityName,
 									Args:       strings.Join(argStrs, ", "),
 									ReturnType: jetInfo.ReturnType,
+									Pos:        s.Pos(),
 								})
 								continue
 							}
 						}
 					}
 
+					bound, err := t.xOnlyBinding(ident.Name, s)
+					if err != nil {
+						return err
+					}
+					if bound {
+						continue
+					}
+					bound, err = t.stdBinding(ident.Name, s)
+					if err != nil {
+						return err
+					}
+					if bound {
+						continue
+					}
+
 					// Handle binary expression assignments: result := a + b, result := a < b, etc.
-					// Check before the evaluateExpression fallback so runtime operations
+					// Check before the Translate fallback so runtime operations
 					// map to jet calls rather than always resolving to "true".
 					if binExpr, ok := s.Rhs[0].(*ast.BinaryExpr); ok {
 						if jc, matched := t.binaryExprToJetCall(ident.Name, binExpr); matched {
+							jc.Pos = s.Pos()
 							t.jetCalls = append(t.jetCalls, *jc)
 							continue
 						}
 					}
 
+					if (derived || !known) && t.derivedBinding(ident.Name, s) {
+						continue
+					}
+
 					// Regular assignment - but skip counter initializations and local variables
-					value, err := t.evaluateExpression(s.Rhs[0])
+					value, err := t.expr.Translate(s.Rhs[0])
 					if err != nil {
 						return fmt.Errorf("failed to evaluate assignment for %s: %w", ident.Name, err)
 					}
 
-					// Skip simple numeric literals (these are local counters, not witnesses)
-					if _, parseErr := strconv.Atoi(value); parseErr == nil {
+					typ := "auto" // will be inferred
+					if sym, ok := t.expr.lookupPath(s.Rhs[0]); ok && !known {
+						typ = carryFree(sym.Type) // A copy, such as r := rate
+					}
+					if known {
+						// Integer constants are inlined where they are used
+						if !derived && folded.Int != nil {
+							continue
+						}
+						value = folded.String()
+						if folded.Type != "" {
+							typ = folded.Type
+						}
+						// The local becomes a witness, which the spender may set.
+						t.folder.bind(ident.Name, folded, true)
+					} else if _, parseErr := strconv.Atoi(value); parseErr == nil {
+						// Skip simple numeric literals (these are local counters, not witnesses)
+						if err := t.fallback(fmt.Sprintf("%s is taken for a local counter and left out of the witnesses", ident.Name), ident.Pos()); err != nil {
+							return err
+						}
 						continue // Skip counter initialization like validCount := 0
 					}
 
 					t.witnessValues = append(t.witnessValues, WitnessValue{
-						Name:  t.toSnakeCase(ident.Name),
-						Type:  "auto", // will be inferred
-						Value: value,
+						Name:     t.toSnakeCase(ident.Name),
+						Type:     typ,
+						Value:    value,
+						Pos:      ident.Pos(),
+						Constant: t.fromConstants(s.Rhs[0]),
 					})
 				}
 			}
 		case *ast.ExprStmt:
+			recorded, err := t.stdStatement(s)
+			if err != nil {
+				return err
+			}
+			if recorded {
+				continue
+			}
 			// Handle standalone jet calls like jet.BIP340Verify(...)
 			if callExpr, ok := s.X.(*ast.CallExpr); ok {
 				if sel, ok := callExpr.Fun.(*ast.SelectorExpr); ok {
-					if selIdent, ok := sel.X.(*ast.Ident); ok && selIdent.Name == "jet" {
+					if t.isJet(sel.X) {
 						// This is a standalone jet call: jet.X(...)
 						jetName := sel.Sel.Name
 						jetInfo, found := t.jetRegistry.Lookup(jetName)
 						if !found {
-							return fmt.Errorf("unknown jet function: jet.%s", jetName)
+							return diag.UnknownJet.Errorf("unknown jet function: jet.%s", jetName)
+						}
+						if _, ok := optionPayload(jetInfo.ReturnType); ok {
+							return t.optionJetUse(s.Pos(), jetInfo)
 						}
 
 						// Evaluate arguments
 						var argStrs []string
 						for _, arg := range callExpr.Args {
-							argStr, err := t.evaluateJetArg(arg)
+							argStr, err := t.expr.TranslateArg(arg)
 							if err != nil {
 								return err
 							}
@@ -313,10 +1220,15 @@ func (t *Transpiler) analyzeMainFunction(funcDecl *ast.FuncDecl) error {
 							JetName:    jetInfo.SimplicityName,
 							Args:       strings.Join(argStrs, ", "),
 							ReturnType: jetInfo.ReturnType,
+							Pos:        s.Pos(),
 						})
+						continue
 					}
 				}
 			}
+			if err := t.droppedStatement(s, "the expression statement"); err != nil {
+				return err
+			}
 
 		case *ast.IfStmt:
 			// Check if this is a sum type pattern match (if w.IsLeft { ... } else { ... })
@@ -324,10 +1236,18 @@ func (t *Transpiler) analyzeMainFunction(funcDecl *ast.FuncDecl) error {
 			if err != nil {
 				return err
 			}
-			if matchExpr != nil {
-				t.matchExprs = append(t.matchExprs, matchExpr)
-				t.hasMatchExpr = true
+			if matchExpr == nil {
+				if i == len(funcDecl.Body.List)-1 && returnsOnly(s) {
+					continue // main returns whichever branch is taken
+				}
+				if err := t.droppedStatement(s, "the if statement"); err != nil {
+					return err
+				}
+				continue
 			}
+			matchExpr.Pos = s.Pos()
+			t.matchExprs = append(t.matchExprs, matchExpr)
+			t.hasMatchExpr = true
 
 		case *ast.TypeSwitchStmt:
 			// Handle type switch: switch v := expr.(type) { case Left: ... case Right: ... }
@@ -335,10 +1255,15 @@ func (t *Transpiler) analyzeMainFunction(funcDecl *ast.FuncDecl) error {
 			if err != nil {
 				return err
 			}
-			if matchExpr != nil {
-				t.matchExprs = append(t.matchExprs, matchExpr)
-				t.hasMatchExpr = true
+			if matchExpr == nil {
+				if err := t.droppedStatement(s, "the switch"); err != nil {
+					return err
+				}
+				continue
 			}
+			matchExpr.Pos = s.Pos()
+			t.matchExprs = append(t.matchExprs, matchExpr)
+			t.hasMatchExpr = true
 
 		case *ast.SwitchStmt:
 			// Handle switch on sum type tag: switch { case w.IsLeft: ... case !w.IsLeft: ... }
@@ -346,10 +1271,15 @@ func (t *Transpiler) analyzeMainFunction(funcDecl *ast.FuncDecl) error {
 			if err != nil {
 				return err
 			}
-			if matchExpr != nil {
-				t.matchExprs = append(t.matchExprs, matchExpr)
-				t.hasMatchExpr = true
+			if matchExpr == nil {
+				if err := t.droppedStatement(s, "the switch"); err != nil {
+					return err
+				}
+				continue
 			}
+			matchExpr.Pos = s.Pos()
+			t.matchExprs = append(t.matchExprs, matchExpr)
+			t.hasMatchExpr = true
 
 		case *ast.ForStmt:
 			// Handle bounded for loops by unrolling them
@@ -357,19 +1287,45 @@ func (t *Transpiler) analyzeMainFunction(funcDecl *ast.FuncDecl) error {
 			if err != nil {
 				return err
 			}
-			if unrolled != nil {
-				t.unrolledLoops = append(t.unrolledLoops, unrolled)
-				t.hasUnrolledLoop = true
+			if unrolled == nil {
+				if err := t.droppedStatement(s, "the loop"); err != nil {
+					return err
+				}
+				continue
+			}
+			t.unrolledLoops = append(t.unrolledLoops, unrolled)
+			t.hasUnrolledLoop = true
+
+		case *ast.EmptyStmt:
+
+		case *ast.ReturnStmt:
+			// A bare return ending main returns as falling off its end does
+			if i < len(funcDecl.Body.List)-1 {
+				if err := t.droppedStatement(s, "the return"); err != nil {
+					return err
+				}
+			}
+
+		default:
+			if err := t.droppedStatement(s, statementKind(s)); err != nil {
+				return err
 			}
 		}
 	}
 
-	return nil
+	return t.mainFallbacks(funcDecl)
 }
 
 // analyzeIfAsMatch checks if an if statement represents sum type pattern matching
 // or a boolean if/else (if boolVar { ... } else { ... }).
 func (t *Transpiler) analyzeIfAsMatch(ifStmt *ast.IfStmt) (*MatchExpression, error) {
+	if match, err := t.analyzePathIf(ifStmt); match != nil || err != nil {
+		return match, err
+	}
+	if match, err := t.analyzeOptionJetIf(ifStmt); match != nil || err != nil {
+		return match, err
+	}
+
 	// Check for boolean if/else: if boolVar { ... } else { ... }
 	// where boolVar is a local jet call variable with ReturnType == "bool"
 	if ident, ok := ifStmt.Cond.(*ast.Ident); ok {
@@ -417,24 +1373,8 @@ func (t *Transpiler) analyzeIfAsMatch(ifStmt *ast.IfStmt) (*MatchExpression, err
 	}
 
 	// Process body statements with bound variable substitution
-	for _, stmt := range ifStmt.Body.List {
-		stmtStr, err := t.analyzeStatementWithVarBinding(stmt, varBase, thenCase.VarName)
-		if err != nil {
-			return nil, err
-		}
-		if stmtStr != "" {
-			thenCase.BodyStmts = append(thenCase.BodyStmts, stmtStr)
-		}
-	}
-
-	// For multi-field Left arm, prepend a destructuring statement
-	if pattern == "Left" {
-		fieldInfo := t.getEitherFieldInfo(varBase)
-		if fieldInfo != nil && len(fieldInfo.LeftFieldNames) > 1 {
-			names := "(" + strings.Join(fieldInfo.LeftFieldNames, ", ") + ")"
-			destructure := fmt.Sprintf("let %s: %s = data;", names, fieldInfo.LeftType)
-			thenCase.BodyStmts = append([]string{destructure}, thenCase.BodyStmts...)
-		}
+	if err := t.sumArm(&thenCase, varBase, ifStmt.Body.List); err != nil {
+		return nil, err
 	}
 
 	match.Cases = append(match.Cases, thenCase)
@@ -461,29 +1401,17 @@ func (t *Transpiler) analyzeIfAsMatch(ifStmt *ast.IfStmt) (*MatchExpression, err
 			}
 		}
 
+		var elseList []ast.Stmt
 		switch e := ifStmt.Else.(type) {
 		case *ast.BlockStmt:
-			for _, stmt := range e.List {
-				// Pass varBase so Right arm can substitute witness field accesses
-				stmtStr, err := t.analyzeStatementWithVarBinding(stmt, varBase, elseCase.VarName)
-				if err != nil {
-					return nil, err
-				}
-				if stmtStr != "" {
-					elseCase.BodyStmts = append(elseCase.BodyStmts, stmtStr)
-				}
-			}
+			elseList = e.List
 		case *ast.IfStmt:
 			// Nested if-else chain - recurse
-			for _, stmt := range e.Body.List {
-				stmtStr, err := t.analyzeStatementWithVarBinding(stmt, varBase, elseCase.VarName)
-				if err != nil {
-					return nil, err
-				}
-				if stmtStr != "" {
-					elseCase.BodyStmts = append(elseCase.BodyStmts, stmtStr)
-				}
-			}
+			elseList = e.Body.List
+		}
+		// Pass varBase so Right arm can substitute witness field accesses
+		if err := t.sumArm(&elseCase, varBase, elseList); err != nil {
+			return nil, err
 		}
 		match.Cases = append(match.Cases, elseCase)
 	} else if pattern == "Some" {
@@ -506,13 +1434,14 @@ func (t *Transpiler) analyzeBoolIfElse(ifStmt *ast.IfStmt, varName string) (*Mat
 	}
 
 	// True branch
-	trueStmts, err := t.analyzeArmBodyStmts(ifStmt.Body.List)
+	trueStmts, truePos, err := t.analyzeArmBodyStmts(ifStmt.Body.List)
 	if err != nil {
 		return nil, err
 	}
 	match.Cases = append(match.Cases, MatchCase{
 		Pattern:   "true",
 		BodyStmts: trueStmts,
+		BodyPos:   truePos,
 	})
 
 	// False branch
@@ -524,13 +1453,14 @@ func (t *Transpiler) analyzeBoolIfElse(ifStmt *ast.IfStmt, varName string) (*Mat
 		case *ast.IfStmt:
 			elseList = e.Body.List
 		}
-		falseStmts, err := t.analyzeArmBodyStmts(elseList)
+		falseStmts, falsePos, err := t.analyzeArmBodyStmts(elseList)
 		if err != nil {
 			return nil, err
 		}
 		match.Cases = append(match.Cases, MatchCase{
 			Pattern:   "false",
 			BodyStmts: falseStmts,
+			BodyPos:   falsePos,
 		})
 	} else {
 		match.Cases = append(match.Cases, MatchCase{
@@ -544,22 +1474,28 @@ func (t *Transpiler) analyzeBoolIfElse(ifStmt *ast.IfStmt, varName string) (*Mat
 
 // analyzeArmBodyStmts processes a slice of statements from a boolean if/else arm.
 // Jet call and binary expression assignments are added temporarily to t.jetCalls
-// so that subsequent arm statements can reference them, then removed on return.
-func (t *Transpiler) analyzeArmBodyStmts(stmts []ast.Stmt) ([]string, error) {
+// so that subsequent arm statements can reference them, then removed on return,
+// and the arm's locals are folded in a scope of their own, as analyzeArmStmts
+// does.
+func (t *Transpiler) analyzeArmBodyStmts(stmts []ast.Stmt) ([]string, []token.Pos, error) {
+	t.folder.push()
+	defer t.folder.pop()
 	savedLen := len(t.jetCalls)
 	var result []string
+	var pos []token.Pos
 	for _, stmt := range stmts {
 		s, err := t.analyzeArmBodyStmt(stmt)
 		if err != nil {
 			t.jetCalls = t.jetCalls[:savedLen]
-			return nil, err
+			return nil, nil, err
 		}
 		if s != "" {
 			result = append(result, s)
+			pos = append(pos, stmt.Pos())
 		}
 	}
 	t.jetCalls = t.jetCalls[:savedLen]
-	return result, nil
+	return result, pos, nil
 }
 
 // analyzeArmBodyStmt converts a single statement inside a boolean arm.
@@ -567,6 +1503,11 @@ func (t *Transpiler) analyzeArmBodyStmts(stmts []ast.Stmt) ([]string, error) {
 // the resulting variable is added to t.jetCalls so later arm stmts can reference it.
 func (t *Transpiler) analyzeArmBodyStmt(stmt ast.Stmt) (string, error) {
 	s, ok := stmt.(*ast.AssignStmt)
+	if assign, isOp, err := t.reassignment(stmt); err != nil {
+		return "", err
+	} else if isOp {
+		s, ok = assign, true
+	}
 	if !ok {
 		return t.analyzeStatement(stmt)
 	}
@@ -582,15 +1523,18 @@ func (t *Transpiler) analyzeArmBodyStmt(stmt ast.Stmt) (string, error) {
 	// Jet call assignment: varName := jet.X(args)
 	if callExpr, ok := s.Rhs[0].(*ast.CallExpr); ok {
 		if sel, ok := callExpr.Fun.(*ast.SelectorExpr); ok {
-			if selIdent, ok := sel.X.(*ast.Ident); ok && selIdent.Name == "jet" {
+			if t.isJet(sel.X) {
 				jetName := sel.Sel.Name
 				jetInfo, found := t.jetRegistry.Lookup(jetName)
 				if !found {
-					return "", fmt.Errorf("unknown jet function: jet.%s", jetName)
+					return "", diag.UnknownJet.Errorf("unknown jet function: jet.%s", jetName)
+				}
+				if _, ok := optionPayload(jetInfo.ReturnType); ok {
+					return "", t.optionJetUse(s.Pos(), jetInfo)
 				}
 				var argStrs []string
 				for _, arg := range callExpr.Args {
-					argStr, err := t.evaluateJetArg(arg)
+					argStr, err := t.expr.TranslateArg(arg)
 					if err != nil {
 						return "", err
 					}
@@ -603,9 +1547,9 @@ func (t *Transpiler) analyzeArmBodyStmt(stmt ast.Stmt) (string, error) {
 					ReturnType: jetInfo.ReturnType,
 				}
 				t.jetCalls = append(t.jetCalls, jc)
-				callStr := formatJetCallExpr(jc.JetName, jc.Args)
+				callStr := t.jetCallExpr(jc.JetName, jc.Args)
 				if kind := liquidKind(jc.JetName); kind != noLiquidUnwrap {
-					return strings.Join(buildLiquidJetLines(varName, callStr, kind), "\n"), nil
+					return strings.Join(buildLiquidJetLines(varName, t.temp(callExpr.Pos()), callStr, kind), "\n"), nil
 				}
 				if strings.HasPrefix(jc.ReturnType, "(bool,") {
 					return fmt.Sprintf("let (_, %s): %s = %s;", varName, jc.ReturnType, callStr), nil
@@ -619,7 +1563,7 @@ func (t *Transpiler) analyzeArmBodyStmt(stmt ast.Stmt) (string, error) {
 	if binExpr, ok := s.Rhs[0].(*ast.BinaryExpr); ok {
 		if jc, matched := t.binaryExprToJetCall(varName, binExpr); matched {
 			t.jetCalls = append(t.jetCalls, *jc)
-			callStr := formatJetCallExpr(jc.JetName, jc.Args)
+			callStr := t.jetCallExpr(jc.JetName, jc.Args)
 			if strings.HasPrefix(jc.ReturnType, "(bool,") {
 				return fmt.Sprintf("let (_, %s): %s = %s;", varName, jc.ReturnType, callStr), nil
 			}
@@ -631,13 +1575,66 @@ func (t *Transpiler) analyzeArmBodyStmt(stmt ast.Stmt) (string, error) {
 	return t.analyzeStatement(stmt)
 }
 
+// analyzeArmStmts analyzes the statements of a match arm with
+// analyzeStatementWithVarBinding. The folder sees them in a scope of their
+// own: a local the arm declares with :=, or assigns, is the arm's alone, as
+// a variable declared in a Go branch is, and is not seen by the other arms
+// or after the match. Each statement returned is paired with the position
+// of the Go statement it was lowered from.
+func (t *Transpiler) analyzeArmStmts(stmts []ast.Stmt, varBase, boundVar string) ([]string, []token.Pos, error) {
+	t.folder.push()
+	defer t.folder.pop()
+	var result []string
+	var pos []token.Pos
+	for _, stmt := range stmts {
+		stmtStr, err := t.analyzeStatementWithVarBinding(stmt, varBase, boundVar)
+		if err != nil {
+			return nil, nil, err
+		}
+		if stmtStr != "" {
+			result = append(result, stmtStr)
+			pos = append(pos, stmt.Pos())
+		}
+	}
+	return result, pos, nil
+}
+
+// sumArm lowers body, the statements of mc, the arm of the sum type witness
+// varBase, binding a payload that is a tuple with a pattern: the fields of
+// a multi-field Left, or of the plain struct Value of an Option.
+func (t *Transpiler) sumArm(mc *MatchCase, varBase string, body []ast.Stmt) error {
+	var payload *structValue
+	restore := func() {}
+	if mc.Pattern == "Some" {
+		payload, restore = t.bindOptionStruct(varBase)
+	}
+	stmts, pos, err := t.analyzeArmStmts(body, varBase, mc.VarName)
+	restore()
+	if err != nil {
+		return err
+	}
+	mc.addBody(stmts, pos)
+	switch info := t.getEitherFieldInfo(varBase); {
+	case payload != nil:
+		mc.VarName = payload.patternReading(mc.BodyStmts)
+	case mc.Pattern == "Left" && info != nil && len(info.LeftFieldNames) > 1:
+		mc.VarName = tuplePattern(info.LeftFieldNames, mc.BodyStmts)
+	}
+	return nil
+}
+
 // analyzeStatementWithVarBinding analyzes a statement replacing witness field accesses
 // with the appropriate bound variable or destructured field name.
 func (t *Transpiler) analyzeStatementWithVarBinding(stmt ast.Stmt, varBase string, boundVar string) (string, error) {
-	stmtStr, err := t.analyzeStatement(stmt)
+	stmtStr, lowered, err := t.liquidArmBinding(stmt)
 	if err != nil {
 		return "", err
 	}
+	if !lowered {
+		if stmtStr, err = t.analyzeStatement(stmt); err != nil {
+			return "", err
+		}
+	}
 
 	if varBase != "" && boundVar != "" {
 		upperBase := strings.ToUpper(t.toSnakeCase(varBase))
@@ -661,9 +1658,47 @@ func (t *Transpiler) analyzeStatementWithVarBinding(stmt ast.Stmt, varBase strin
 	return stmtStr, nil
 }
 
+// liquidArmBinding lowers v := jet.X(...) inside a match arm when X is a
+// Liquid introspection jet, unwrapping its Option or confidential result the
+// same way top-level bindings do. It reports false for any other statement.
+func (t *Transpiler) liquidArmBinding(stmt ast.Stmt) (string, bool, error) {
+	s, ok := stmt.(*ast.AssignStmt)
+	if !ok || len(s.Lhs) != 1 || len(s.Rhs) != 1 {
+		return "", false, nil
+	}
+	ident, ok := s.Lhs[0].(*ast.Ident)
+	if !ok {
+		return "", false, nil
+	}
+	call, ok := s.Rhs[0].(*ast.CallExpr)
+	if !ok {
+		return "", false, nil
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", false, nil
+	}
+	if !t.isJet(sel.X) {
+		return "", false, nil
+	}
+	info, found := t.jetRegistry.Lookup(sel.Sel.Name)
+	if !found {
+		return "", false, nil
+	}
+	kind := liquidKind(info.SimplicityName)
+	if kind == noLiquidUnwrap {
+		return "", false, nil
+	}
+	callStr, err := t.evaluateJetCall(sel.Sel.Name, call.Args)
+	if err != nil {
+		return "", false, err
+	}
+	return strings.Join(buildLiquidJetLines(t.toSnakeCase(ident.Name), t.temp(call.Pos()), callStr, kind), "\n"), true, nil
+}
+
 // extractSumTypeCondition extracts scrutinee, pattern, and variable base name from a condition
 func (t *Transpiler) extractSumTypeCondition(cond ast.Expr) (scrutinee, pattern, varBase string) {
-	switch c := cond.(type) {
+	switch c := ast.Unparen(cond).(type) {
 	case *ast.SelectorExpr:
 		// w.IsLeft or opt.IsSome
 		if ident, ok := c.X.(*ast.Ident); ok {
@@ -683,7 +1718,7 @@ func (t *Transpiler) extractSumTypeCondition(cond ast.Expr) (scrutinee, pattern,
 	case *ast.UnaryExpr:
 		// !w.IsLeft means Right
 		if c.Op == token.NOT {
-			if sel, ok := c.X.(*ast.SelectorExpr); ok {
+			if sel, ok := ast.Unparen(c.X).(*ast.SelectorExpr); ok {
 				if ident, ok := sel.X.(*ast.Ident); ok {
 					varBase = ident.Name
 					scrutinee = t.resolveWitnessRef(ident.Name)
@@ -821,19 +1856,32 @@ func (t *Transpiler) getOppositePattern(pattern string) string {
 		return "None"
 	case "None":
 		return "Some"
+	case "true":
+		return "false"
+	case "false":
+		return "true"
 	default:
 		return "_"
 	}
 }
 
 // analyzeSwitchAsMatch analyzes a switch statement as a pattern match.
-// Handles tagless switch { case w.IsLeft: ... case !w.IsLeft: ... } patterns.
+// Handles tagless switch { case w.IsLeft: ... case !w.IsLeft: ... } patterns,
+// and a switch on a bool or an integer.
 func (t *Transpiler) analyzeSwitchAsMatch(switchStmt *ast.SwitchStmt) (*MatchExpression, error) {
 	if switchStmt.Tag != nil {
-		return nil, nil // tagged switch deferred
+		if match, err := t.analyzeBoolSwitch(switchStmt); match != nil || err != nil {
+			return match, err
+		}
+		return t.analyzeIntSwitch(switchStmt)
+	}
+	if match, err := t.analyzePathSwitch(switchStmt); match != nil || err != nil {
+		return match, err
 	}
 
 	var match *MatchExpression
+	var defaultClause *ast.CaseClause
+	var varBase string
 
 	for _, stmt := range switchStmt.Body.List {
 		caseClause, ok := stmt.(*ast.CaseClause)
@@ -841,208 +1889,534 @@ func (t *Transpiler) analyzeSwitchAsMatch(switchStmt *ast.SwitchStmt) (*MatchExp
 			continue
 		}
 		if len(caseClause.List) == 0 {
-			continue // skip default:
+			defaultClause = caseClause
+			continue
 		}
 
-		scrutinee, pattern, varBase := t.extractSumTypeCondition(caseClause.List[0])
+		scrutinee, pattern, base := t.extractSumTypeCondition(caseClause.List[0])
 		if scrutinee == "" {
 			continue
 		}
 
 		if match == nil {
 			match = &MatchExpression{Scrutinee: scrutinee}
+			varBase = base
 		}
 
-		mc := MatchCase{Pattern: pattern}
-		caseFieldInfo := t.getEitherFieldInfo(varBase)
-		switch pattern {
-		case "Left":
-			mc.VarName = "data"
-			if caseFieldInfo != nil && caseFieldInfo.LeftType != "" {
-				mc.VarType = caseFieldInfo.LeftType
-			} else if armType := t.resolveArmVarType(varBase, "Left"); armType != "" {
-				mc.VarType = armType
-			}
-		case "Right":
-			mc.VarName = "sig"
-			if caseFieldInfo != nil && caseFieldInfo.RightType != "" {
-				mc.VarType = caseFieldInfo.RightType
-			} else if armType := t.resolveArmVarType(varBase, "Right"); armType != "" {
-				mc.VarType = armType
-			}
-		case "Some":
-			mc.VarName = "sig"
-			if caseFieldInfo != nil && caseFieldInfo.RightType != "" {
-				mc.VarType = caseFieldInfo.RightType
-			} else if armType := t.resolveArmVarType(varBase, "Some"); armType != "" {
-				mc.VarType = armType
-			}
+		mc, err := t.switchArm(pattern, base, caseClause.Body)
+		if err != nil {
+			return nil, err
 		}
+		match.Cases = append(match.Cases, mc)
+	}
 
-		for _, s := range caseClause.Body {
-			stmtStr, err := t.analyzeStatementWithVarBinding(s, varBase, mc.VarName)
-			if err != nil {
-				return nil, err
-			}
-			if stmtStr != "" {
-				mc.BodyStmts = append(mc.BodyStmts, stmtStr)
-			}
+	// A match covers both variants, so a default next to a single case is
+	// the other one. A default of std.Unreachable() fails the spend there.
+	if match != nil && len(match.Cases) == 1 && defaultClause != nil {
+		mc, err := t.switchArm(t.getOppositePattern(match.Cases[0].Pattern), varBase, defaultClause.Body)
+		if err != nil {
+			return nil, err
 		}
+		match.Cases = append(match.Cases, mc)
+	}
 
-		// multi-field Left arm: prepend destructuring (same as analyzeIfAsMatch)
-		if pattern == "Left" {
-			fieldInfo := t.getEitherFieldInfo(varBase)
-			if fieldInfo != nil && len(fieldInfo.LeftFieldNames) > 1 {
-				names := "(" + strings.Join(fieldInfo.LeftFieldNames, ", ") + ")"
-				destructure := fmt.Sprintf("let %s: %s = data;", names, fieldInfo.LeftType)
-				mc.BodyStmts = append([]string{destructure}, mc.BodyStmts...)
-			}
+	if match != nil {
+		if arm, ok := t.missingArm(match.Cases); ok {
+			return nil, t.errorAt(switchStmt.Pos(), "switch on %s has no case for its %s arm, %s, and no default", varBase, arm, armCondition(varBase, arm))
 		}
+	}
+	return match, nil
+}
 
-		match.Cases = append(match.Cases, mc)
+// missingArm returns the arm of a two-variant type, such as Right or
+// false, that cases do not match. A wildcard matches every arm.
+func (t *Transpiler) missingArm(cases []MatchCase) (string, bool) {
+	if len(cases) == 0 {
+		return "", false
+	}
+	have := make(map[string]bool, len(cases))
+	for _, mc := range cases {
+		if mc.Pattern == "_" {
+			return "", false
+		}
+		have[mc.Pattern] = true
+	}
+	other := t.getOppositePattern(cases[0].Pattern)
+	if other == "_" || have[other] {
+		return "", false
 	}
+	return other, true
+}
 
-	return match, nil
+// armCondition is the Go condition that selects arm of the sum type
+// witness varBase.
+func armCondition(varBase, arm string) string {
+	switch arm {
+	case "Left":
+		return varBase + ".IsLeft"
+	case "Right":
+		return "!" + varBase + ".IsLeft"
+	case "Some":
+		return varBase + ".IsSome"
+	case "None":
+		return "!" + varBase + ".IsSome"
+	}
+	return arm
 }
 
-// evaluateJetArg evaluates an argument to a jet call, handling various cases
-func (t *Transpiler) evaluateJetArg(arg ast.Expr) (string, error) {
-	switch a := arg.(type) {
-	case *ast.Ident:
-		// Check if it's a known constant
-		for _, c := range t.constants {
-			if strings.EqualFold(c.Name, strings.ToUpper(t.toSnakeCase(a.Name))) {
-				return fmt.Sprintf("param::%s", strings.ToUpper(t.toSnakeCase(a.Name))), nil
-			}
+// boolArm is the body of the arm of a switch on a bool for true or false,
+// set when a case or the default of the switch matches it.
+type boolArm struct {
+	body []ast.Stmt
+	set  bool
+}
+
+// boolSwitchArms returns the tag of s translated and the arms for true
+// and false of its match, in that order. It reports false for a switch on
+// anything but a bool.
+func (t *Transpiler) boolSwitchArms(s *ast.SwitchStmt) (string, [2]boolArm, bool, error) {
+	var arms [2]boolArm
+	if s.Tag == nil || s.Init != nil {
+		return "", arms, false, nil
+	}
+	if sym, ok := t.expr.lookupPath(s.Tag); !ok || carryFree(sym.Type) != "bool" {
+		return "", arms, false, nil
+	}
+	ref, err := t.expr.TranslateArg(s.Tag)
+	if err != nil {
+		return "", arms, false, err
+	}
+	tag := gotypes.ExprString(s.Tag)
+	var rest []ast.Stmt
+	hasDefault := false
+	for _, stmt := range s.Body.List {
+		clause := stmt.(*ast.CaseClause)
+		if len(clause.List) == 0 {
+			rest, hasDefault = clause.Body, true
+			continue
 		}
-		// Check if it's a witness value
-		for _, w := range t.witnessValues {
-			if strings.EqualFold(strings.ToUpper(w.Name), strings.ToUpper(t.toSnakeCase(a.Name))) {
-				return fmt.Sprintf("witness::%s", strings.ToUpper(t.toSnakeCase(a.Name))), nil
+		for _, expr := range clause.List {
+			v, ok := t.folder.Fold(expr)
+			if !ok || v.Type != "bool" {
+				return "", arms, false, t.errorAt(expr.Pos(), "a case of a switch on the bool %s must be true or false, got %s", tag, gotypes.ExprString(expr))
 			}
-		}
-		// Check if it's a local variable from a jet call
-		for _, jc := range t.jetCalls {
-			if jc.VarName == t.toSnakeCase(a.Name) {
-				return jc.VarName, nil
+			i := 0
+			if !v.Bool {
+				i = 1
+			}
+			if arms[i].set {
+				return "", arms, false, t.errorAt(expr.Pos(), "duplicate case %t in switch on %s", v.Bool, tag)
 			}
+			arms[i] = boolArm{body: clause.Body, set: true}
 		}
-		// Return as-is (might be a parameter name or local var)
-		return t.toSnakeCase(a.Name), nil
-	case *ast.BasicLit:
-		if a.Kind == token.INT && strings.HasPrefix(a.Value, "0x") {
-			return t.evaluateHexLiteral(a.Value)
+	}
+	for i := range arms {
+		if !arms[i].set && hasDefault {
+			arms[i] = boolArm{body: rest, set: true}
+		}
+	}
+	return ref, arms, true, nil
+}
+
+// analyzeBoolSwitch lowers a switch on a bool, switch ok { case true: ...
+// case false: ... }, to a match. It returns nil for a switch on anything
+// else.
+func (t *Transpiler) analyzeBoolSwitch(switchStmt *ast.SwitchStmt) (*MatchExpression, error) {
+	ref, arms, ok, err := t.boolSwitchArms(switchStmt)
+	if !ok || err != nil {
+		return nil, err
+	}
+	match := &MatchExpression{Scrutinee: ref, IsBoolMatch: true}
+	for i, pattern := range []string{"true", "false"} {
+		if !arms[i].set {
+			return nil, t.errorAt(switchStmt.Pos(), "switch on %s has no case for %s and no default", gotypes.ExprString(switchStmt.Tag), pattern)
+		}
+		stmts, pos, err := t.analyzeArmBodyStmts(arms[i].body)
+		if err != nil {
+			return nil, err
+		}
+		if len(stmts) == 0 {
+			stmts = []string{"()"}
+		}
+		match.Cases = append(match.Cases, MatchCase{Pattern: pattern, BodyStmts: stmts, BodyPos: pos})
+	}
+	return match, nil
+}
+
+// switchArm lowers the body of the switch case that matches pattern of the
+// sum type witness varBase.
+func (t *Transpiler) switchArm(pattern, varBase string, body []ast.Stmt) (MatchCase, error) {
+	mc := MatchCase{Pattern: pattern}
+	caseFieldInfo := t.getEitherFieldInfo(varBase)
+	switch pattern {
+	case "Left":
+		mc.VarName = "data"
+		if caseFieldInfo != nil && caseFieldInfo.LeftType != "" {
+			mc.VarType = caseFieldInfo.LeftType
+		} else if armType := t.resolveArmVarType(varBase, "Left"); armType != "" {
+			mc.VarType = armType
+		}
+	case "Right":
+		mc.VarName = "sig"
+		if caseFieldInfo != nil && caseFieldInfo.RightType != "" {
+			mc.VarType = caseFieldInfo.RightType
+		} else if armType := t.resolveArmVarType(varBase, "Right"); armType != "" {
+			mc.VarType = armType
+		}
+	case "Some":
+		mc.VarName = "sig"
+		if caseFieldInfo != nil && caseFieldInfo.RightType != "" {
+			mc.VarType = caseFieldInfo.RightType
+		} else if armType := t.resolveArmVarType(varBase, "Some"); armType != "" {
+			mc.VarType = armType
+		}
+	}
+
+	return mc, t.sumArm(&mc, varBase, body)
+}
+
+// constantRef returns the expression that reads constant c. Libraries have no
+// param module, so their constants are inlined as literal values.
+func (t *Transpiler) constantRef(c Constant) string {
+	if t.library {
+		return c.Value
+	}
+	return fmt.Sprintf("param::%s", c.Name)
+}
+
+// binaryExprToJetCall converts a Go binary expression over runtime values
+// into a JetCall bound to varName; see Translator.OperatorCall.
+func (t *Transpiler) binaryExprToJetCall(varName string, expr *ast.BinaryExpr) (*JetCall, bool) {
+	for _, operand := range []ast.Expr{expr.X, expr.Y} {
+		if _, nested := ast.Unparen(operand).(*ast.BinaryExpr); nested {
+			return nil, false // Flattened by derivedBinding, a let per operator
+		}
+	}
+	call, ok := t.expr.OperatorCall(expr)
+	if !ok {
+		return nil, false
+	}
+	return &JetCall{
+		VarName:    t.toSnakeCase(varName),
+		JetName:    call.Jet,
+		Args:       call.Args,
+		ReturnType: call.ReturnType,
+	}, true
+}
+
+// transpilerSymbols resolves identifiers against the constants, witnesses
+// and jet call results collected so far.
+type transpilerSymbols struct {
+	t *Transpiler
+}
+
+func (s transpilerSymbols) Lookup(name string) (Symbol, bool) {
+	if sym, ok, err := s.t.resolveField(name); ok && err == nil {
+		return sym, true
+	}
+	if typ, ok := s.t.params[name]; ok {
+		return Symbol{Kind: SymbolLocal, Ref: s.t.toSnakeCase(name), Type: typ}, true
+	}
+	snake := s.t.toSnakeCase(name)
+	upper := strings.ToUpper(snake)
+	for _, c := range s.t.constants {
+		if strings.EqualFold(c.Name, upper) {
+			return Symbol{Kind: SymbolConstant, Ref: s.t.constantRef(c), Type: c.Type}, true
 		}
-		return a.Value, nil
-	case *ast.SelectorExpr:
-		// Handle struct field access like w.Preimage or w.RecipientSig
-		if ident, ok := a.X.(*ast.Ident); ok {
-			varName := t.toSnakeCase(ident.Name)
-			fieldName := t.toSnakeCase(a.Sel.Name)
-			// Check if base is a witness value
-			for _, w := range t.witnessValues {
-				if strings.EqualFold(strings.ToUpper(w.Name), strings.ToUpper(varName)) {
-					return fmt.Sprintf("witness::%s.%s", strings.ToUpper(varName), fieldName), nil
-				}
-			}
-			return fmt.Sprintf("%s.%s", varName, fieldName), nil
+	}
+	// A let of main shadows the witness of the same name it reassigns.
+	for _, jc := range s.t.jetCalls {
+		if jc.VarName == snake {
+			return Symbol{Kind: SymbolLocal, Ref: jc.VarName, Type: jc.ReturnType}, true
 		}
-		return t.evaluateExpression(arg)
-	case *ast.BinaryExpr:
-		// Binary expression used inline as a jet argument (e.g. jet.Verify(x == y)).
-		// Attempt to generate a runtime jet call for the comparison/operation.
-		// A synthetic var name is used since this result is used inline, not bound.
-		if jc, ok := t.binaryExprToJetCall("i_inline", a); ok {
-			return formatJetCallExpr(jc.JetName, jc.Args), nil
+	}
+	for _, w := range s.t.witnessValues {
+		if strings.EqualFold(w.Name, upper) {
+			return Symbol{Kind: SymbolWitness, Ref: "witness::" + upper, Type: w.Type}, true
 		}
-		return t.evaluateExpression(arg)
-	case *ast.CallExpr:
-		// Handle nested jet calls like jet.SHA256Init()
-		return t.evaluateCallExpr(a)
-	default:
-		return t.evaluateExpression(arg)
 	}
+	return Symbol{}, false
+}
+
+func (s transpilerSymbols) Qualified(pkg, name string) (string, bool) {
+	return s.t.packageConstant(pkg, name)
 }
 
 func (t *Transpiler) analyzeFunction(funcDecl *ast.FuncDecl) error {
 	// Convert Go functions to pure pattern-matching functions
 	function := Function{
-		Name: t.toSnakeCase(funcDecl.Name.Name),
-	}
-
-	// Extract parameters
+		Name:   t.pkgPrefix + t.toSnakeCase(funcDecl.Name.Name),
+		GoName: funcDecl.Name.Name,
+		Pos:    funcDecl.Pos(),
+		Doc:    docText(funcDecl.Doc),
+	}
+	if t.pkgName != "" {
+		function.GoName = t.pkgName + "." + funcDecl.Name.Name
+	}
+
+	// Extract parameters. Struct parameters are tuples, destructured at the
+	// top of the body so that field accesses read named locals.
+	var destructure []string
+	clear(t.structParams)
+	clear(t.params)
+	defer clear(t.structParams)
+	defer clear(t.params)
 	if funcDecl.Type.Params != nil {
 		for _, field := range funcDecl.Type.Params.List {
-			simplicityType, err := t.typeMapper.MapGoType(field.Type)
+			simplicityType, err := t.mapType(field.Type)
 			if err != nil {
 				return err
 			}
+			ident, _ := field.Type.(*ast.Ident)
 
 			for _, name := range field.Names {
 				function.Parameters = append(function.Parameters, Parameter{
 					Name: t.toSnakeCase(name.Name),
 					Type: simplicityType,
 				})
+				t.params[name.Name] = simplicityType
+				if ident != nil && len(t.structFields[ident.Name]) > 0 && name.Name != "_" {
+					destructure = append(destructure, t.destructureParam(name.Name, ident.Name))
+				}
 			}
 		}
 	}
 
-	// Extract return type
-	if funcDecl.Type.Results != nil && len(funcDecl.Type.Results.List) > 0 {
-		rt, err := t.typeMapper.MapGoType(funcDecl.Type.Results.List[0].Type)
-		if err != nil {
-			return err
-		}
-		function.ReturnType = rt
+	// Extract return type: several results are a tuple
+	rt, err := t.resultType(funcDecl.Type.Results)
+	if err != nil {
+		return err
+	}
+	function.ReturnType = rt
+	if funcDecl.Body == nil {
+		function.Stub, function.Called = true, true
+		t.functions = append(t.functions, function)
+		return nil
+	}
+
+	if err := t.checkFieldSelectors(funcDecl.Body); err != nil {
+		return err
 	}
 
 	// Analyze function body to create pattern matching logic
-	body, err := t.analyzeFunctionBody(funcDecl.Body)
+	body, statements, err := t.analyzeFunctionBody(funcDecl.Body)
 	if err != nil {
 		return err
 	}
+	if body == "" && function.ReturnType != "" {
+		if err := t.fallback(fmt.Sprintf("%s computes no result the transpiler can lower, and returns %s", funcDecl.Name.Name, placeholder), funcDecl.Body.Rbrace); err != nil {
+			return err
+		}
+		body = placeholder
+	}
+	if err := t.checkSize(funcDecl.Pos(), "the body of "+funcDecl.Name.Name, 1, body); err != nil {
+		return err
+	}
+	if statements && function.ReturnType != "" {
+		// The value of a call is an expression, into which statements
+		// such as asserts and lets cannot be inlined.
+		function.Called = true
+	}
+	if len(destructure) > 0 {
+		body = strings.Join(destructure, "\n") + "\n" + body
+		function.Called = true
+	}
+	if t.noInline {
+		function.Called = true
+	}
 	function.Body = body
 
 	t.functions = append(t.functions, function)
 	return nil
 }
 
-func (t *Transpiler) analyzeFunctionBody(block *ast.BlockStmt) (string, error) {
+// analyzeFunctionBody returns the SimplicityHL body of a function, empty
+// when it lowers to nothing, and whether statements precede its result.
+func (t *Transpiler) analyzeFunctionBody(block *ast.BlockStmt) (string, bool, error) {
 	// NOTE: t.constants must be populated before this runs.
 	// Place constants before helper functions in source to guarantee ordering.
+	t.folder.push()
+	defer t.folder.pop()
+	stmts := block.List
+	count, isCount := t.matchThresholdCount(stmts)
+	if isCount && !t.noThresholdTrees {
+		stmts = stmts[:count.start]
+	}
 	var lines []string
-	for _, stmt := range block.List {
+	for i := 0; i < len(stmts); i++ {
+		stmt := stmts[i]
+		if err := t.ctx.Err(); err != nil {
+			return "", false, err
+		}
+		if i+2 < len(stmts) {
+			result, ok, err := t.lowerZeroFlagLoop(stmt, stmts[i+1], stmts[i+2])
+			if err != nil {
+				return "", false, err
+			}
+			if ok {
+				lines = append(lines, result...)
+				i += 2
+				continue
+			}
+		}
+		if i+1 < len(stmts) {
+			if reduced, ok, err := t.lowerBranchingLoop(stmt, stmts[i+1]); err != nil {
+				return "", false, err
+			} else if ok {
+				lines = append(lines, reduced...)
+				i++
+				continue
+			}
+		}
+		if reduced, ok, err := t.lowerBranchingLoop(nil, stmt); err != nil {
+			return "", false, err
+		} else if ok {
+			lines = append(lines, reduced...)
+			continue
+		}
+		if i+1 < len(stmts) {
+			if acc, ok, err := t.matchAccumulation(stmt, stmts[i+1]); err != nil {
+				return "", false, err
+			} else if ok {
+				reduced, ok := t.lowerAccumulation(acc)
+				if !ok {
+					return "", false, diag.UnsupportedSyntax.Wrap(t.errorAt(acc.pos, "the loop is not lowered: its update of %s has no lowering", acc.acc))
+				}
+				lines = append(lines, reduced...)
+				i++
+				continue
+			}
+			result, ok, err := t.lowerZeroLoop(stmt, stmts[i+1])
+			if err != nil {
+				return "", false, err
+			}
+			if ok {
+				lines = append(lines, result...)
+				i++
+				continue
+			}
+		}
+		if sw, ok := stmt.(*ast.SwitchStmt); ok {
+			result, ok, err := t.lowerBoolSwitchResult(sw, stmts[i+1:])
+			if err != nil {
+				return "", false, err
+			}
+			if ok {
+				lines = append(lines, result)
+				break
+			}
+			result, ok, err = t.lowerIntSwitchResult(sw, stmts[i+1:])
+			if err != nil {
+				return "", false, err
+			}
+			if ok {
+				lines = append(lines, result)
+				break
+			}
+		}
+		if ifStmt, ok := stmt.(*ast.IfStmt); ok {
+			result, ok, err := t.lowerIfResult(ifStmt, stmts[i+1:])
+			if err != nil {
+				return "", false, err
+			}
+			if ok {
+				lines = append(lines, result...)
+				break
+			}
+		}
+		if calls, ok, err := t.lowerBranchAssign(stmt); err != nil {
+			return "", false, err
+		} else if ok {
+			lines = append(lines, letLines(calls)...)
+			continue
+		}
+		t.forgetBranchAssigned(stmt)
 		stmtStr, err := t.analyzeStatement(stmt)
 		if err != nil {
-			return "", err
+			return "", false, err
 		}
 		if stmtStr != "" {
 			lines = append(lines, stmtStr)
 		}
 	}
+	if isCount && !t.noThresholdTrees {
+		tree, err := t.lowerThresholdCount(count)
+		if err != nil {
+			return "", false, err
+		}
+		lines = append(lines, tree...)
+	}
 	if len(lines) == 0 {
-		return "true", nil
+		return "", false, nil
 	}
-	return strings.Join(lines, "\n"), nil
+	// Every item but the result is a statement, such as an assert!; the
+	// printer decides the result's terminator, as it knows the return type.
+	return terminateBlock(strings.Join(lines, "\n"), true), len(lines) > 1, nil
 }
 
+// analyzeConstants records the constants of a package-level declaration
+// as params. A var declared without a value is one too, of the zero of its
+// type: nothing but main declares witnesses.
 func (t *Transpiler) analyzeConstants(genDecl *ast.GenDecl) error {
 	for _, spec := range genDecl.Specs {
 		if valueSpec, ok := spec.(*ast.ValueSpec); ok {
+			doc := specDoc(genDecl, valueSpec)
+			if genDecl.Tok == token.VAR {
+				if len(valueSpec.Values) > 0 || valueSpec.Type == nil {
+					continue
+				}
+				typ, err := t.mapType(valueSpec.Type)
+				if err != nil {
+					return t.errorAt(valueSpec.Type.Pos(), "%v", err)
+				}
+				for _, name := range valueSpec.Names {
+					if err := t.addConstant(name, Constant{Type: typ, Value: zeroValue(typ), Doc: doc}); err != nil {
+						return err
+					}
+				}
+				continue
+			}
 			for i, name := range valueSpec.Names {
 				if i < len(valueSpec.Values) {
-					value, err := t.evaluateExpression(valueSpec.Values[i])
+					// A hex string decodes to the byte array it spells
+					if lit, ok := valueSpec.Values[i].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+						c, err := hexStringConstant(name.Name, lit)
+						if err != nil {
+							return t.errorAt(lit.Pos(), "%v", err)
+						}
+						c.Doc = doc
+						t.constants = append(t.constants, c)
+						continue
+					}
+					value, err := t.expr.Translate(valueSpec.Values[i])
 					if err != nil {
 						return err
 					}
+					// A constant expression, such as Amount * Rate / 10000
+					// or false, is its value; a literal keeps its spelling.
+					folded, folds := t.folder.Fold(valueSpec.Values[i])
+					if c := constantValue(valueSpec.Values[i], -1, 0); !folds && c.Kind() == constant.Int && constant.Sign(c) >= 0 {
+						folded.Int, folds = new(big.Int).SetString(c.ExactString(), 10)
+					}
+					if _, isLit := valueSpec.Values[i].(*ast.BasicLit); folds && !isLit {
+						value = folded.String()
+					}
 
 					typ := "u64"
+					if folds && folded.Int == nil {
+						typ = "bool"
+					}
 					if valueSpec.Type != nil {
 						simplicityType, err := t.typeMapper.MapGoType(valueSpec.Type)
 						if err != nil {
 							return err
 						}
 						typ = simplicityType
+						if v, ok := t.folder.Fold(valueSpec.Values[i]); ok && v.Int != nil && isUIntType(typ) {
+							if _, err := simtypes.EncodeInt(typ, v.Int); err != nil {
+								return diag.ValueOverflow.Wrap(t.errorAt(valueSpec.Values[i].Pos(), "constant %s: %v", name.Name, err))
+							}
+						}
 					} else {
 						// Infer type from hex literals
 						if strings.HasPrefix(value, "0x") {
@@ -1050,11 +2424,9 @@ func (t *Transpiler) analyzeConstants(genDecl *ast.GenDecl) error {
 						}
 					}
 
-					t.constants = append(t.constants, Constant{
-						Name:  strings.ToUpper(t.toSnakeCase(name.Name)),
-						Type:  typ,
-						Value: value,
-					})
+					if err := t.addConstant(name, Constant{Type: typ, Value: value, Doc: doc}); err != nil {
+						return err
+					}
 				}
 			}
 		}
@@ -1062,12 +2434,59 @@ func (t *Transpiler) analyzeConstants(genDecl *ast.GenDecl) error {
 	return nil
 }
 
+// addConstant records c as the param of the Go constant name.
+func (t *Transpiler) addConstant(name *ast.Ident, c Constant) error {
+	c.Name = strings.ToUpper(t.toSnakeCase(name.Name))
+	for _, other := range t.constants {
+		if other.Name == c.Name {
+			return t.errorAt(name.Pos(), "constant %s becomes param::%s, which is already declared", name.Name, c.Name)
+		}
+	}
+	t.constants = append(t.constants, c)
+	return nil
+}
+
+// hexStringConstant decodes the constant name = "0x02ab…" to a byte array.
+func hexStringConstant(name string, lit *ast.BasicLit) (Constant, error) {
+	b, err := simtypes.DecodeHexString(lit.Value)
+	if err != nil {
+		return Constant{}, fmt.Errorf("constant %s: %w", name, err)
+	}
+	typ := fmt.Sprintf("[u8; %d]", len(b))
+	value, err := simtypes.EncodeBytes(typ, b)
+	if err != nil {
+		return Constant{}, err
+	}
+	return Constant{Name: strings.ToUpper(snakeCase(name)), Type: typ, Value: value}, nil
+}
+
+// docText returns the text of the doc comment doc without its comment
+// markers, or "" for none.
+func docText(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	return strings.TrimSpace(doc.Text())
+}
+
+// specDoc returns the doc comment of spec, or of its declaration when spec
+// is the only one, as in a const or var without parentheses.
+func specDoc(genDecl *ast.GenDecl, spec *ast.ValueSpec) string {
+	if spec.Doc == nil && len(genDecl.Specs) == 1 {
+		return docText(genDecl.Doc)
+	}
+	return docText(spec.Doc)
+}
+
 // analyzeTypeDeclarations processes type declarations to detect Option/Either patterns
 // and record individual field types for SHA256Add auto-select.
 func (t *Transpiler) analyzeTypeDeclarations(genDecl *ast.GenDecl) error {
 	for _, spec := range genDecl.Specs {
 		if typeSpec, ok := spec.(*ast.TypeSpec); ok {
 			typeName := typeSpec.Name.Name
+			if typeSpec.TypeParams != nil {
+				return t.errorAt(typeSpec.Pos(), "type %s has type parameters; only functions may be generic", typeName)
+			}
 
 			// Check if this is a struct type
 			if structType, ok := typeSpec.Type.(*ast.StructType); ok {
@@ -1079,6 +2498,11 @@ func (t *Transpiler) analyzeTypeDeclarations(genDecl *ast.GenDecl) error {
 				// Pattern: { IsSome bool; Value T }
 				if optionType := t.detectOptionPattern(structType); optionType != "" {
 					t.customTypes[typeName] = fmt.Sprintf("Option<%s>", optionType)
+					// A plain struct Value is a tuple, laid out by analyzeTypes
+					// once every struct is declared.
+					if ident, ok := optionValueType(structType).(*ast.Ident); ok {
+						t.optionStructs[typeName] = ident.Name
+					}
 					continue
 				}
 
@@ -1089,6 +2513,17 @@ func (t *Transpiler) analyzeTypeDeclarations(genDecl *ast.GenDecl) error {
 					t.eitherFields[typeName] = info
 					continue
 				}
+
+				// A struct of pointers selects one of several spend paths
+				if isPathStruct(structType) {
+					t.pathDecls[typeName] = structType
+					continue
+				}
+
+				// Any other struct is a tuple of its fields
+				if structType.Fields != nil {
+					t.structDecls[typeName] = structType
+				}
 			}
 		}
 	}
@@ -1144,369 +2579,77 @@ func (t *Transpiler) detectOptionPattern(structType *ast.StructType) string {
 	return ""
 }
 
-// detectEitherPattern checks if a struct has the pattern { IsLeft bool; ...fields... }
-// Convention: the last non-discriminator field is the Right branch; all others are Left.
-// Returns nil if the struct does not match the Either pattern.
-func (t *Transpiler) detectEitherPattern(structType *ast.StructType) *EitherFieldInfo {
-	if structType.Fields == nil || len(structType.Fields.List) < 2 {
-		return nil
-	}
-
-	type fieldEntry struct {
-		snakeName string
-		simType   string
-	}
-
-	hasIsLeft := false
-	var nonDiscrim []fieldEntry
-
+// optionValueType returns the type of the Value field of an Option struct.
+func optionValueType(structType *ast.StructType) ast.Expr {
 	for _, field := range structType.Fields.List {
 		for _, name := range field.Names {
-			if name.Name == "IsLeft" || name.Name == "IsRight" {
-				if ident, ok := field.Type.(*ast.Ident); ok && ident.Name == "bool" {
-					hasIsLeft = true
-				}
-				continue
-			}
-			typ, err := t.typeMapper.MapGoType(field.Type)
-			if err == nil {
-				nonDiscrim = append(nonDiscrim, fieldEntry{
-					snakeName: t.toSnakeCase(name.Name),
-					simType:   typ,
-				})
-			}
-		}
-	}
-
-	if !hasIsLeft || len(nonDiscrim) < 2 {
-		return nil
-	}
-
-	// Last field → Right; everything before → Left
-	rightField := nonDiscrim[len(nonDiscrim)-1]
-	leftFields := nonDiscrim[:len(nonDiscrim)-1]
-
-	var leftType string
-	var leftFieldNames []string
-	for _, f := range leftFields {
-		leftFieldNames = append(leftFieldNames, f.snakeName)
-	}
-	if len(leftFields) == 1 {
-		leftType = leftFields[0].simType
-	} else {
-		var types []string
-		for _, f := range leftFields {
-			types = append(types, f.simType)
-		}
-		leftType = fmt.Sprintf("(%s)", strings.Join(types, ", "))
-	}
-
-	return &EitherFieldInfo{
-		LeftFieldNames: leftFieldNames,
-		LeftType:       leftType,
-		RightFieldName: rightField.snakeName,
-		RightType:      rightField.simType,
-	}
-}
-
-func (t *Transpiler) evaluateExpression(expr ast.Expr) (string, error) {
-	switch e := expr.(type) {
-	case *ast.BasicLit:
-		// Check for hex literals
-		if e.Kind == token.INT && strings.HasPrefix(e.Value, "0x") {
-			return t.evaluateHexLiteral(e.Value)
-		}
-		return e.Value, nil
-	case *ast.BinaryExpr:
-		return t.evaluateBinaryExpr(e)
-	case *ast.CallExpr:
-		return t.evaluateCallExpr(e)
-	case *ast.UnaryExpr:
-		if e.Op == token.NOT {
-			operand, err := t.evaluateExpression(e.X)
-			if err != nil {
-				return "", err
-			}
-			if operand == "true" {
-				return "false", nil
-			}
-			return "true", nil
-		}
-	case *ast.Ident:
-		// Check if it's a known constant
-		for _, c := range t.constants {
-			if strings.EqualFold(c.Name, strings.ToUpper(t.toSnakeCase(e.Name))) {
-				return fmt.Sprintf("param::%s", strings.ToUpper(t.toSnakeCase(e.Name))), nil
-			}
-		}
-		// Check if it's a witness value
-		for _, w := range t.witnessValues {
-			if strings.EqualFold(strings.ToUpper(w.Name), strings.ToUpper(t.toSnakeCase(e.Name))) {
-				return fmt.Sprintf("witness::%s", strings.ToUpper(t.toSnakeCase(e.Name))), nil
-			}
-		}
-		// Check if it's a local variable from a jet call
-		for _, jc := range t.jetCalls {
-			if jc.VarName == t.toSnakeCase(e.Name) {
-				return jc.VarName, nil
-			}
-		}
-		// Return placeholder for unknown identifiers
-		return t.toSnakeCase(e.Name), nil
-	case *ast.SelectorExpr:
-		// Handle struct field access like w.Preimage or w.RecipientSig
-		if ident, ok := e.X.(*ast.Ident); ok {
-			varName := t.toSnakeCase(ident.Name)
-			fieldName := t.toSnakeCase(e.Sel.Name)
-			// Check if base is a witness value
-			for _, w := range t.witnessValues {
-				if strings.EqualFold(strings.ToUpper(w.Name), strings.ToUpper(varName)) {
-					return fmt.Sprintf("witness::%s.%s", strings.ToUpper(varName), fieldName), nil
-				}
+			if name.Name == "Value" {
+				return field.Type
 			}
-			return fmt.Sprintf("%s.%s", varName, fieldName), nil
-		}
-	case *ast.IndexExpr:
-		// Handle array indexing like arr[0] or arr[i]
-		return t.evaluateIndexExpr(e)
-	case *ast.CompositeLit:
-		// Handle array literals like [3]u256{a, b, c}
-		return t.evaluateCompositeLit(e)
-	}
-
-	// If we can't evaluate it, return a default
-	return "true", nil
-}
-
-// evaluateCompositeLit handles composite literals like array literals
-func (t *Transpiler) evaluateCompositeLit(lit *ast.CompositeLit) (string, error) {
-	var elements []string
-	for _, elt := range lit.Elts {
-		elemStr, err := t.evaluateExpression(elt)
-		if err != nil {
-			return "", err
-		}
-		elements = append(elements, elemStr)
-	}
-	return fmt.Sprintf("[%s]", strings.Join(elements, ", ")), nil
-}
-
-// evaluateHexLiteral processes hex literals and normalizes them
-func (t *Transpiler) evaluateHexLiteral(value string) (string, error) {
-	// Validate hex literal
-	if !strings.HasPrefix(value, "0x") && !strings.HasPrefix(value, "0X") {
-		return "", fmt.Errorf("invalid hex literal: %s", value)
-	}
-
-	// Remove the 0x prefix for validation
-	hexPart := value[2:]
-	if len(hexPart) == 0 {
-		return "", fmt.Errorf("empty hex literal")
-	}
-
-	// Validate hex characters
-	for _, c := range hexPart {
-		if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')) {
-			return "", fmt.Errorf("invalid hex character in literal: %c", c)
-		}
-	}
-
-	// Normalize to lowercase 0x prefix
-	return "0x" + strings.ToLower(hexPart), nil
-}
-
-// ─── Phase 5: operator-to-jet mapping ────────────────────────────────────────
-
-// binaryExprToJetCall converts a Go binary expression into a JetCall when at
-// least one operand is a runtime value (witness:: or param:: reference, or a
-// local variable from a previous jet call).
-//
-// Returns (jc, true) on success; (nil, false) when both sides are compile-time
-// constants and the expression should be folded by evaluateBinaryExpr instead.
-func (t *Transpiler) binaryExprToJetCall(varName string, expr *ast.BinaryExpr) (*JetCall, bool) {
-	leftStr, _ := t.evaluateJetArg(expr.X)
-	rightStr, _ := t.evaluateJetArg(expr.Y)
-
-	leftRuntime := t.isRuntimeRef(leftStr)
-	rightRuntime := t.isRuntimeRef(rightStr)
-
-	if !leftRuntime && !rightRuntime {
-		return nil, false
-	}
-
-	width := t.inferOperandTypeWidth(expr.X, expr.Y)
-	jetName, swapArgs := t.operatorToJetName(expr.Op, width)
-	if jetName == "" {
-		return nil, false
-	}
-
-	var args string
-	if swapArgs {
-		args = rightStr + ", " + leftStr
-	} else {
-		args = leftStr + ", " + rightStr
-	}
-
-	return &JetCall{
-		VarName:    t.toSnakeCase(varName),
-		JetName:    jetName,
-		Args:       args,
-		ReturnType: t.operatorReturnType(expr.Op, width),
-	}, true
-}
-
-// isRuntimeRef returns true if s is a witness::, param::, or local jet variable
-// reference (i.e., not a compile-time numeric or hex literal).
-func (t *Transpiler) isRuntimeRef(s string) bool {
-	if strings.HasPrefix(s, "witness::") || strings.HasPrefix(s, "param::") {
-		return true
-	}
-	// Also treat local variables from previous jet calls as runtime
-	for _, jc := range t.jetCalls {
-		if jc.VarName == s {
-			return true
 		}
 	}
-	return false
+	return nil
 }
 
-// inferOperandTypeWidth picks u8/u16/u32/u64/u128/u256 from the types of two
-// operands. Defaults to u32 (the most common width for heights, sequences,
-// indices). u256 takes priority over everything (asset IDs, script hashes);
-// u128 is checked next (products of two u64 values).
-func (t *Transpiler) inferOperandTypeWidth(left, right ast.Expr) string {
-	lt := t.inferExprType(left)
-	rt := t.inferExprType(right)
-	if lt == "u256" || rt == "u256" {
-		return "u256"
-	}
-	if lt == "u128" || rt == "u128" {
-		return "u128"
-	}
-	if lt == "u64" || rt == "u64" {
-		return "u64"
-	}
-	if lt == "u16" && rt == "u16" {
-		return "u16"
+// detectEitherPattern checks if a struct has the pattern { IsLeft bool; ...fields... }
+// Convention: the last non-discriminator field is the Right branch; all others are Left.
+// Returns nil if the struct does not match the Either pattern.
+func (t *Transpiler) detectEitherPattern(structType *ast.StructType) *EitherFieldInfo {
+	if structType.Fields == nil || len(structType.Fields.List) < 2 {
+		return nil
 	}
-	if lt == "u8" && rt == "u8" {
-		return "u8"
+
+	type fieldEntry struct {
+		snakeName string
+		simType   string
 	}
-	return "u32"
-}
 
-// inferExprType returns the Simplicity type of a Go expression by consulting
-// the transpiler's known constants, witnesses, and jet call results.
-func (t *Transpiler) inferExprType(expr ast.Expr) string {
-	switch e := expr.(type) {
-	case *ast.Ident:
-		name := strings.ToUpper(t.toSnakeCase(e.Name))
-		for _, c := range t.constants {
-			if strings.ToUpper(c.Name) == name {
-				return c.Type
-			}
-		}
-		for _, w := range t.witnessValues {
-			if strings.ToUpper(w.Name) == name {
-				return w.Type
-			}
-		}
-		for _, jc := range t.jetCalls {
-			if jc.VarName == t.toSnakeCase(e.Name) {
-				// If the return type is a carry-tuple like "(bool, u64)", extract
-				// the actual numeric type — callers of inferExprType (e.g.
-				// inferOperandTypeWidth) only care about the unwrapped value type.
-				rt := jc.ReturnType
-				if strings.HasPrefix(rt, "(bool, ") && strings.HasSuffix(rt, ")") {
-					rt = rt[7 : len(rt)-1]
+	var selector string
+	var nonDiscrim []fieldEntry
+
+	for _, field := range structType.Fields.List {
+		for _, name := range field.Names {
+			if name.Name == "IsLeft" || name.Name == "IsRight" {
+				if ident, ok := field.Type.(*ast.Ident); ok && ident.Name == "bool" {
+					selector = t.toSnakeCase(name.Name)
 				}
-				return rt
+				continue
+			}
+			typ, err := t.typeMapper.MapGoType(field.Type)
+			if err == nil {
+				nonDiscrim = append(nonDiscrim, fieldEntry{
+					snakeName: t.toSnakeCase(name.Name),
+					simType:   typ,
+				})
 			}
 		}
-	case *ast.BasicLit:
-		if e.Kind == token.INT {
-			if v, err := strconv.ParseInt(e.Value, 0, 64); err == nil && v > 0x7FFFFFFF {
-				return "u64"
-			}
-		}
-	}
-	return "u32"
-}
-
-// operatorToJetName maps a Go binary operator and Simplicity integer width to
-// a jet name.  swapArgs=true means the caller should pass (right, left) instead
-// of (left, right) — used for > and >= which are expressed as < and <= with
-// swapped operands.
-// opJetSpec pairs a jet name prefix with whether the arguments must be swapped.
-// Swapping handles the GT/GEQ cases: a > b compiles as lt(b, a).
-type opJetSpec struct {
-	prefix   string
-	swapArgs bool
-}
-
-// opJetMap maps each supported Go binary operator to its jet prefix and swap flag.
-var opJetMap = map[token.Token]opJetSpec{
-	token.ADD: {"add_", false},
-	token.SUB: {"subtract_", false},
-	token.MUL: {"multiply_", false},
-	token.QUO: {"divide_", false},
-	token.REM: {"modulo_", false},
-	token.LSS: {"lt_", false}, // a < b
-	token.LEQ: {"le_", false}, // a <= b
-	token.GTR: {"lt_", true},  // a > b → lt(b, a)
-	token.GEQ: {"le_", true},  // a >= b → le(b, a)
-	token.EQL: {"eq_", false},
-	token.AND: {"and_", false},
-	token.OR:  {"or_", false},
-	token.XOR: {"xor_", false},
-}
-
-func (t *Transpiler) operatorToJetName(op token.Token, width string) (name string, swapArgs bool) {
-	suffix := "32"
-	switch width {
-	case "u8":
-		suffix = "8"
-	case "u16":
-		suffix = "16"
-	case "u64":
-		suffix = "64"
-	case "u128":
-		suffix = "128"
-	case "u256":
-		suffix = "256"
-	}
-	if spec, ok := opJetMap[op]; ok {
-		return spec.prefix + suffix, spec.swapArgs
 	}
-	return "", false
-}
 
-// operatorReturnType returns the Simplicity return type for the jet that
-// corresponds to a given Go binary operator and integer width.
-func (t *Transpiler) operatorReturnType(op token.Token, width string) string {
-	switch op {
-	case token.ADD, token.SUB:
-		// add_N / subtract_N return a (carry/borrow, result) pair
-		return "(bool, " + width + ")"
-	case token.MUL:
-		// multiply_N returns a double-width integer
-		switch width {
-		case "u8":
-			return "u16"
-		case "u16":
-			return "u32"
-		case "u64":
-			return "u128"
-		case "u128":
-			return "u256"
-		default:
-			return "u64" // multiply_32 → u64
-		}
-	case token.LSS, token.LEQ, token.GTR, token.GEQ, token.EQL:
-		return "bool"
-	default:
-		// divide, modulo, and, or, xor return same width as input
-		return width
+	if selector == "" || len(nonDiscrim) < 2 {
+		return nil
+	}
+
+	// Last field → Right; everything before → Left
+	rightField := nonDiscrim[len(nonDiscrim)-1]
+	leftFields := nonDiscrim[:len(nonDiscrim)-1]
+
+	var leftFieldNames, leftFieldTypes []string
+	for _, f := range leftFields {
+		leftFieldNames = append(leftFieldNames, f.snakeName)
+		leftFieldTypes = append(leftFieldTypes, f.simType)
+	}
+	leftType := leftFieldTypes[0]
+	if len(leftFields) > 1 {
+		leftType = fmt.Sprintf("(%s)", strings.Join(leftFieldTypes, ", "))
+	}
+
+	return &EitherFieldInfo{
+		Selector:       selector,
+		LeftFieldNames: leftFieldNames,
+		LeftFieldTypes: leftFieldTypes,
+		LeftType:       leftType,
+		RightFieldName: rightField.snakeName,
+		RightType:      rightField.simType,
 	}
 }
 
@@ -1516,13 +2659,12 @@ func (t *Transpiler) operatorReturnType(op token.Token, width string) string {
 type liquidJetKind int
 
 const (
-	noLiquidUnwrap    liquidJetKind = iota
-	amountPairNoOpt                 // current_amount → (Asset1, Amount1); extract u64
-	amountPairOpt                   // input_amount / output_amount → Option<(Asset1, Amount1)>
-	assetNoOpt                      // current_asset → Asset1; extract u256
-	assetOpt                        // input_asset / output_asset → Option<Asset1>
-	optScalarU256                   // output_script_hash / input_script_hash → Option<u256>
-	issuanceAmountOpt               // issuance_asset_amount → Option<Option<Amount1>>
+	noLiquidUnwrap  liquidJetKind = iota
+	amountPairNoOpt               // current_amount → (Asset1, Amount1); extract u64
+	amountPairOpt                 // input_amount / output_amount → Option<(Asset1, Amount1)>
+	assetNoOpt                    // current_asset → Asset1; extract u256
+	assetOpt                      // input_asset / output_asset → Option<Asset1>
+	optScalarU256                 // output_script_hash / input_script_hash → Option<u256>
 )
 
 func liquidKind(simName string) liquidJetKind {
@@ -1537,8 +2679,6 @@ func liquidKind(simName string) liquidJetKind {
 		return assetOpt
 	case "output_script_hash", "input_script_hash":
 		return optScalarU256
-	case "issuance_asset_amount":
-		return issuanceAmountOpt
 	}
 	return noLiquidUnwrap
 }
@@ -1550,11 +2690,9 @@ const (
 
 // buildLiquidJetLines returns the Simfony lines (without leading indent) that
 // bind the explicit scalar from a Liquid introspection jet call.
-// varName is the final Go-level variable; jetCall is the full "jet::foo(args)" expression.
-func buildLiquidJetLines(varName, jetCall string, kind liquidJetKind) []string {
-	// SimplicityHL 0.3.0 identifiers must start with ASCII_ALPHA (not underscore).
-	// Use "c_" prefix for intermediate conf variables.
-	confVar := "c_" + varName
+// varName is the final Go-level variable; jetCall is the full "jet::foo(args)" expression;
+// confVar is the temporary bound to the possibly confidential value.
+func buildLiquidJetLines(varName, confVar, jetCall string, kind liquidJetKind) []string {
 	switch kind {
 	case amountPairNoOpt:
 		// jet::current_amount() → (Asset1, Amount1)
@@ -1591,15 +2729,6 @@ func buildLiquidJetLines(varName, jetCall string, kind liquidJetKind) []string {
 		return []string{
 			fmt.Sprintf("let %s: u256 = unwrap(%s);", varName, jetCall),
 		}
-	case issuanceAmountOpt:
-		// jet::issuance_asset_amount(n) → Option<Option<Amount1>>
-		// Two unwrap() calls then unwrap_right for the inner Either.
-		optVar := "o_" + varName
-		return []string{
-			fmt.Sprintf("let %s: Option<Amount1> = unwrap(%s);", optVar, jetCall),
-			fmt.Sprintf("let %s: Amount1 = unwrap(%s);", confVar, optVar),
-			fmt.Sprintf("let %s: u64 = unwrap_right::<(u1, u256)>(%s);", varName, confVar),
-		}
 	}
 	return nil
 }
@@ -1627,12 +2756,13 @@ func findTopLevelComma(s string) int {
 // used in a top-level verify context.
 //
 // op is "le_128", "lt_128", or "eq_128"; argStr is the full "op(exprA, exprB)" string.
+// temp names the temporaries, temp_a_hi, temp_b_hi and so on.
 //
 // For le_128(a, b) — assert a ≤ b: subtract b−a; the borrow flag must be false.
 // For lt_128(a, b) — assert a < b: subtract b−a−1 (init borrow=true); must be false.
 // For eq_128(a, b) — assert a == b: both u64 halves must be equal (two assert! calls).
-func expandBorrow128Verify(op, argStr string) []string {
-	// Strip "op(" prefix and ")" suffix to get "exprA, exprB".
+func expandBorrow128Verify(op, argStr, temp string) []string {
+	// Strip "op(" temp and ")" suffix to get "exprA, exprB".
 	inner := argStr[len(op)+1 : len(argStr)-1]
 	comma := findTopLevelComma(inner)
 	if comma < 0 {
@@ -1645,27 +2775,24 @@ func expandBorrow128Verify(op, argStr string) []string {
 	// eq_128: assert both u64 halves are equal — no borrow-arithmetic needed.
 	if op == "eq_128" {
 		return []string{
-			fmt.Sprintf("let (eq_a_hi, eq_a_lo): (u64, u64) = <u128>::into(%s);", exprA),
-			fmt.Sprintf("let (eq_b_hi, eq_b_lo): (u64, u64) = <u128>::into(%s);", exprB),
-			"assert!(jet::eq_64(eq_a_hi, eq_b_hi));",
-			"assert!(jet::eq_64(eq_a_lo, eq_b_lo));",
+			fmt.Sprintf("let (%s_a_hi, %s_a_lo): (u64, u64) = <u128>::into(%s);", temp, temp, exprA),
+			fmt.Sprintf("let (%s_b_hi, %s_b_lo): (u64, u64) = <u128>::into(%s);", temp, temp, exprB),
+			fmt.Sprintf("assert!(jet::eq_64(%s_a_hi, %s_b_hi));", temp, temp),
+			fmt.Sprintf("assert!(jet::eq_64(%s_a_lo, %s_b_lo));", temp, temp),
 		}
 	}
 
-	// Variable name prefix: "le" or "lt" to avoid collisions.
-	prefix := op[:2]
-
 	initBorrow := "false"
 	if op == "lt_128" {
 		initBorrow = "true"
 	}
 
 	return []string{
-		fmt.Sprintf("let (%s_a_hi, %s_a_lo): (u64, u64) = <u128>::into(%s);", prefix, prefix, exprA),
-		fmt.Sprintf("let (%s_b_hi, %s_b_lo): (u64, u64) = <u128>::into(%s);", prefix, prefix, exprB),
-		fmt.Sprintf("let (%s_borrow_lo, _): (bool, u64) = jet::full_subtract_64(%s, %s_b_lo, %s_a_lo);", prefix, initBorrow, prefix, prefix),
-		fmt.Sprintf("let (%s_borrow_hi, _): (bool, u64) = jet::full_subtract_64(%s_borrow_lo, %s_b_hi, %s_a_hi);", prefix, prefix, prefix, prefix),
-		fmt.Sprintf("unwrap_left::<()>(<bool>::into(%s_borrow_hi));", prefix),
+		fmt.Sprintf("let (%s_a_hi, %s_a_lo): (u64, u64) = <u128>::into(%s);", temp, temp, exprA),
+		fmt.Sprintf("let (%s_b_hi, %s_b_lo): (u64, u64) = <u128>::into(%s);", temp, temp, exprB),
+		fmt.Sprintf("let (%s_borrow_lo, _): (bool, u64) = jet::full_subtract_64(%s, %s_b_lo, %s_a_lo);", temp, initBorrow, temp, temp),
+		fmt.Sprintf("let (%s_borrow_hi, _): (bool, u64) = jet::full_subtract_64(%s_borrow_lo, %s_b_hi, %s_a_hi);", temp, temp, temp, temp),
+		fmt.Sprintf("unwrap_left::<()>(<bool>::into(%s_borrow_hi));", temp),
 	}
 }
 
@@ -1702,7 +2829,8 @@ func splitTopLevelArgs(s string) []string {
 //
 // argStr is the full "fee_adjusted_le_128(r0, newR0, feeNum, feeDiff, feeDen, newR1, r1)" string.
 // Intermediate products are asserted to fit in u64 (holds for all practical pool sizes).
-func expandFeeAdjustedLe128Verify(argStr string) []string {
+// temp names the temporaries, temp_t1, temp_lhs and so on.
+func expandFeeAdjustedLe128Verify(argStr, temp string) []string {
 	const prefix = "fee_adjusted_le_128("
 	inner := argStr[len(prefix) : len(argStr)-1]
 	parts := splitTopLevelArgs(inner)
@@ -1710,25 +2838,26 @@ func expandFeeAdjustedLe128Verify(argStr string) []string {
 		return []string{fmt.Sprintf("assert!(%s);", argStr)}
 	}
 	r0, newR0, feeNum, feeDiff, feeDen, newR1, r1 := parts[0], parts[1], parts[2], parts[3], parts[4], parts[5], parts[6]
+	named := func(line string) string { return strings.ReplaceAll(line, "fa_", temp+"_") }
 	return []string{
-		fmt.Sprintf("let fa_t1: u128 = jet::multiply_64(%s, %s);", r0, feeDiff),
-		fmt.Sprintf("let fa_t2: u128 = jet::multiply_64(%s, %s);", newR0, feeNum),
-		"let (fa_t1_hi, fa_t1_lo): (u64, u64) = <u128>::into(fa_t1);",
-		"let (fa_t2_hi, fa_t2_lo): (u64, u64) = <u128>::into(fa_t2);",
-		"assert!(jet::eq_64(fa_t1_hi, 0));",
-		"assert!(jet::eq_64(fa_t2_hi, 0));",
-		"let (fa_carry, fa_adj_lo): (bool, u64) = jet::add_64(fa_t1_lo, fa_t2_lo);",
-		"unwrap_left::<()>(<bool>::into(fa_carry));",
-		fmt.Sprintf("let fa_lhs: u128 = jet::multiply_64(fa_adj_lo, %s);", newR1),
-		fmt.Sprintf("let fa_r0d: u128 = jet::multiply_64(%s, %s);", r0, feeDen),
-		"let (fa_r0d_hi, fa_r0d_lo): (u64, u64) = <u128>::into(fa_r0d);",
-		"assert!(jet::eq_64(fa_r0d_hi, 0));",
-		fmt.Sprintf("let fa_rhs: u128 = jet::multiply_64(fa_r0d_lo, %s);", r1),
-		"let (fa_lhs_hi, fa_lhs_lo): (u64, u64) = <u128>::into(fa_lhs);",
-		"let (fa_rhs_hi, fa_rhs_lo): (u64, u64) = <u128>::into(fa_rhs);",
-		"let (fa_borrow_lo, _): (bool, u64) = jet::full_subtract_64(false, fa_lhs_lo, fa_rhs_lo);",
-		"let (fa_borrow_hi, _): (bool, u64) = jet::full_subtract_64(fa_borrow_lo, fa_lhs_hi, fa_rhs_hi);",
-		"unwrap_left::<()>(<bool>::into(fa_borrow_hi));",
+		fmt.Sprintf(named("let fa_t1: u128 = jet::multiply_64(%s, %s);"), r0, feeDiff),
+		fmt.Sprintf(named("let fa_t2: u128 = jet::multiply_64(%s, %s);"), newR0, feeNum),
+		named("let (fa_t1_hi, fa_t1_lo): (u64, u64) = <u128>::into(fa_t1);"),
+		named("let (fa_t2_hi, fa_t2_lo): (u64, u64) = <u128>::into(fa_t2);"),
+		named("assert!(jet::eq_64(fa_t1_hi, 0));"),
+		named("assert!(jet::eq_64(fa_t2_hi, 0));"),
+		named("let (fa_carry, fa_adj_lo): (bool, u64) = jet::add_64(fa_t1_lo, fa_t2_lo);"),
+		named("unwrap_left::<()>(<bool>::into(fa_carry));"),
+		fmt.Sprintf(named("let fa_lhs: u128 = jet::multiply_64(fa_adj_lo, %s);"), newR1),
+		fmt.Sprintf(named("let fa_r0d: u128 = jet::multiply_64(%s, %s);"), r0, feeDen),
+		named("let (fa_r0d_hi, fa_r0d_lo): (u64, u64) = <u128>::into(fa_r0d);"),
+		named("assert!(jet::eq_64(fa_r0d_hi, 0));"),
+		fmt.Sprintf(named("let fa_rhs: u128 = jet::multiply_64(fa_r0d_lo, %s);"), r1),
+		named("let (fa_lhs_hi, fa_lhs_lo): (u64, u64) = <u128>::into(fa_lhs);"),
+		named("let (fa_rhs_hi, fa_rhs_lo): (u64, u64) = <u128>::into(fa_rhs);"),
+		named("let (fa_borrow_lo, _): (bool, u64) = jet::full_subtract_64(false, fa_lhs_lo, fa_rhs_lo);"),
+		named("let (fa_borrow_hi, _): (bool, u64) = jet::full_subtract_64(fa_borrow_lo, fa_lhs_hi, fa_rhs_hi);"),
+		named("unwrap_left::<()>(<bool>::into(fa_borrow_hi));"),
 	}
 }
 
@@ -1737,42 +2866,63 @@ func expandFeeAdjustedLe128Verify(argStr string) []string {
 // comparison jets are emitted as user-defined helper function calls.
 func formatJetCallExpr(jetName, args string) string {
 	if jetName == "verify" {
-		return fmt.Sprintf("assert!(%s)", args)
+		return "assert!(" + args + ")"
 	}
-	if u128CompareJets[jetName] {
+	if jetName == "panic" {
+		// std.Unreachable(), the fail combinator
+		return "panic!()"
+	}
+	if u128CompareJets[jetName] || isCompilerHelper(jetName) {
 		// Call as user-defined function, not jet
-		return fmt.Sprintf("%s(%s)", jetName, args)
+		return jetName + "(" + args + ")"
 	}
 	// Synthetic jets are always inlined — format without jet:: prefix so that
 	// the verify dispatch can detect them by prefix match.
 	if jetName == "fee_adjusted_le_128" {
-		return fmt.Sprintf("fee_adjusted_le_128(%s)", args)
+		return "fee_adjusted_le_128(" + args + ")"
+	}
+	return jetRef(jetName) + "(" + args + ")"
+}
+
+// jetCallExpr is formatJetCallExpr for a jet of t's registry, which
+// may be one declared beyond the jet table.
+func (t *Transpiler) jetCallExpr(jetName, args string) string {
+	if info, ok := t.jetRegistry.LookupJet(jetName); ok && info.Custom {
+		return "jet::" + jetName + "(" + args + ")"
 	}
-	if args == "" {
-		return fmt.Sprintf("jet::%s()", jetName)
+	return formatJetCallExpr(jetName, args)
+}
+
+// jetTable is the jet table that emitted jet names are looked up in.
+var jetTable = jets.NewRegistry()
+
+// jetRef returns the SimplicityHL reference to the jet name, which must be
+// in the jet table: emitting a jet that is not is a transpiler bug.
+func jetRef(name string) string {
+	if _, ok := jetTable.LookupJet(name); !ok {
+		panic("transpiler: jet " + name + " is not in the jet table")
 	}
-	return fmt.Sprintf("jet::%s(%s)", jetName, args)
+	return "jet::" + name
 }
 
 // u128HelperFunctions returns SimplicityHL helper function definitions for
-// 128-bit comparisons that are needed by the current program.
-func u128HelperFunctions(needed map[string]bool) string {
-	var sb strings.Builder
+// 128-bit comparisons that are needed by the current program, in a fixed
+// order. Each definition is rendered in canonical indentation.
+func u128HelperFunctions(needed map[string]bool) []string {
+	var helpers []string
 
 	if needed["eq_128"] {
-		sb.WriteString(`fn eq_128(a: u128, b: u128) -> bool {
+		helpers = append(helpers, `fn eq_128(a: u128, b: u128) -> bool {
     let (ah, al): (u64, u64) = <u128>::into(a);
     let (bh, bl): (u64, u64) = <u128>::into(b);
     match jet::eq_64(ah, bh) {
         true => jet::eq_64(al, bl),
         false => false,
     }
-}
-
-`)
+}`)
 	}
 	if needed["le_128"] {
-		sb.WriteString(`fn le_128(a: u128, b: u128) -> bool {
+		helpers = append(helpers, `fn le_128(a: u128, b: u128) -> bool {
     let (ah, al): (u64, u64) = <u128>::into(a);
     let (bh, bl): (u64, u64) = <u128>::into(b);
     match jet::lt_64(ah, bh) {
@@ -1784,12 +2934,10 @@ func u128HelperFunctions(needed map[string]bool) string {
             }
         }
     }
-}
-
-`)
+}`)
 	}
 	if needed["lt_128"] {
-		sb.WriteString(`fn lt_128(a: u128, b: u128) -> bool {
+		helpers = append(helpers, `fn lt_128(a: u128, b: u128) -> bool {
     let (ah, al): (u64, u64) = <u128>::into(a);
     let (bh, bl): (u64, u64) = <u128>::into(b);
     match jet::lt_64(ah, bh) {
@@ -1801,11 +2949,25 @@ func u128HelperFunctions(needed map[string]bool) string {
             }
         }
     }
+}`)
+	}
+	return helpers
 }
 
-`)
+// resultBinding returns the bool binding of main whose name contains
+// result, which main asserts when it checks nothing else, as it asserts a
+// witness named so. It returns "" when main checks something.
+func (t *Transpiler) resultBinding() string {
+	result := ""
+	for _, jc := range t.jetCalls {
+		if jc.VarName == "" {
+			return ""
+		}
+		if result == "" && jc.ReturnType == "bool" && strings.Contains(jc.VarName, "result") {
+			result = jc.VarName
+		}
 	}
-	return sb.String()
+	return result
 }
 
 // writeLetBinding emits a `let` statement for a JetCall that has a variable
@@ -1813,101 +2975,204 @@ func u128HelperFunctions(needed map[string]bool) string {
 // carry is discarded with the `(_, varName)` destructuring pattern.
 // Liquid introspection jets (amount/asset) are expanded into multi-line
 // Either-unwrapping code so the final variable holds a plain u64 or u256.
-func (t *Transpiler) writeLetBinding(indent string, jc JetCall) {
-	callExpr := formatJetCallExpr(jc.JetName, jc.Args)
+func (t *Transpiler) writeLetBinding(depth int, jc JetCall) {
+	t.printer.at(jc.Pos)
+	callExpr := t.boundExpr(jc)
 
 	kind := liquidKind(jc.JetName)
 	if kind != noLiquidUnwrap {
-		for _, line := range buildLiquidJetLines(jc.VarName, callExpr, kind) {
-			t.writeLine(indent + line)
+		for _, line := range buildLiquidJetLines(jc.VarName, t.temp(jc.Pos), callExpr, kind) {
+			t.emit(depth, line)
 		}
 		return
 	}
 
-	var stmt string
-	if strings.HasPrefix(jc.ReturnType, "(bool,") {
+	t.emit(depth, letStatement(jc, callExpr))
+}
+
+// boundExpr renders the expression the let of jc binds: the jet call, or
+// the Args of a JetCall without a JetName.
+func (t *Transpiler) boundExpr(jc JetCall) string {
+	if jc.JetName == "" {
+		return jc.Args
+	}
+	return t.jetCallExpr(jc.JetName, jc.Args)
+}
+
+// letStatement binds the result of the jet call jc, rendered as callExpr.
+func letStatement(jc JetCall, callExpr string) string {
+	if jc.Wrap {
+		product := operatorReturnType(token.MUL, jc.ReturnType)
+		return "let (_, " + jc.VarName + "): (" + jc.ReturnType + ", " + jc.ReturnType + ") = <" + product + ">::into(" + callExpr + ");"
+	}
+	if jc.JetName != "" && strings.HasPrefix(jc.ReturnType, "(bool,") {
 		// Discard the carry/borrow flag — the caller only wants the numeric result.
-		stmt = fmt.Sprintf("%slet (_, %s): %s = %s;", indent, jc.VarName, jc.ReturnType, callExpr)
-	} else {
-		stmt = fmt.Sprintf("%slet %s: %s = %s;", indent, jc.VarName, jc.ReturnType, callExpr)
+		return "let (_, " + jc.VarName + "): " + jc.ReturnType + " = " + callExpr + ";"
 	}
-	t.writeLine(stmt)
+	return "let " + jc.VarName + ": " + jc.ReturnType + " = " + callExpr + ";"
 }
 
 // ─────────────────────────────────────────────────────────────────────────────
 
-func (t *Transpiler) evaluateBinaryExpr(expr *ast.BinaryExpr) (string, error) {
-	// Try to evaluate both sides
-	left, leftErr := t.evaluateExpression(expr.X)
-	right, rightErr := t.evaluateExpression(expr.Y)
-
-	// If both are literals, we can compute the result
-	if leftErr == nil && rightErr == nil {
-		leftVal, err1 := strconv.ParseInt(left, 10, 64)
-		rightVal, err2 := strconv.ParseInt(right, 10, 64)
-
-		if err1 == nil && err2 == nil {
-			switch expr.Op {
-			case token.ADD:
-				return strconv.FormatInt(leftVal+rightVal, 10), nil
-			case token.SUB:
-				return strconv.FormatInt(leftVal-rightVal, 10), nil
-			case token.MUL:
-				return strconv.FormatInt(leftVal*rightVal, 10), nil
-			case token.QUO:
-				if rightVal != 0 {
-					return strconv.FormatInt(leftVal/rightVal, 10), nil
+func (t *Transpiler) evaluateCallExpr(expr *ast.CallExpr) (string, error) {
+	// Check for jet.X() calls (SelectorExpr)
+	if sel, ok := expr.Fun.(*ast.SelectorExpr); ok {
+		if t.isJet(sel.X) {
+			return t.evaluateJetCall(sel.Sel.Name, expr.Args)
+		}
+	}
+
+	// A generic function may be instantiated explicitly: Eq[uint64](a, b)
+	fun, typeArg := expr.Fun, ast.Expr(nil)
+	switch ix := fun.(type) {
+	case *ast.IndexExpr:
+		fun, typeArg = ix.X, ix.Index
+	case *ast.IndexListExpr:
+		return "", t.errorAt(expr.Pos(), "%s takes one type argument, got %d", gotypes.ExprString(ix.X), len(ix.Indices))
+	}
+
+	// Qualified calls into an imported package: checks.MinAmount(x)
+	if sel, ok := fun.(*ast.SelectorExpr); ok {
+		if ident, ok := sel.X.(*ast.Ident); ok {
+			if prefix, ok := t.pkgAliases[ident.Name]; ok {
+				return t.userCall(prefix+"_"+t.toSnakeCase(sel.Sel.Name), typeArg, expr)
+			}
+		}
+		if name, ok := t.stdFunc(expr); ok {
+			return t.stdCall(name, expr)
+		}
+		if name, ok := bitcoinMember(t.typeMapper, sel); ok {
+			if name == "XOnly" {
+				return t.xOnlyKey(expr)
+			}
+			typ, ok := bitcoinConversion(t.typeMapper, expr)
+			if !ok {
+				return "", t.errorAt(expr.Pos(), "%s has no function %s(...); it provides NewAmount, XOnly and conversions to its types", simtypes.BitcoinImportPath, name)
+			}
+			if v, ok := t.folder.Fold(expr.Args[0]); ok && v.Int != nil {
+				if _, err := simtypes.EncodeInt(typ, v.Int); err != nil {
+					return "", t.errorAt(expr.Pos(), "bitcoin.%s: %v", name, err)
 				}
-			case token.GTR:
-				return strconv.FormatBool(leftVal > rightVal), nil
-			case token.LSS:
-				return strconv.FormatBool(leftVal < rightVal), nil
-			case token.GEQ:
-				return strconv.FormatBool(leftVal >= rightVal), nil
-			case token.LEQ:
-				return strconv.FormatBool(leftVal <= rightVal), nil
-			case token.EQL:
-				return strconv.FormatBool(leftVal == rightVal), nil
 			}
+			return t.expr.Translate(expr.Args[0])
+		}
+	}
+
+	// A conversion of a runtime value to a bool or an unsigned integer
+	if ident, ok := fun.(*ast.Ident); ok && len(expr.Args) == 1 && t.funcDecls[ident.Name] == nil {
+		if typ, err := t.typeMapper.MapGoType(ident); err == nil && (typ == "bool" || isUIntType(typ)) {
+			return t.conversion(typ, expr)
 		}
 	}
 
-	// If we can't evaluate it completely, create a boolean result
-	// This should become a witness value
-	return "true", nil
+	// User-defined function calls: look up in t.functions and inline the body
+	if ident, ok := fun.(*ast.Ident); ok {
+		return t.userCall(t.pkgPrefix+t.toSnakeCase(ident.Name), typeArg, expr)
+	}
+	return t.expr.residual(expr, "the call")
 }
 
-func (t *Transpiler) evaluateCallExpr(expr *ast.CallExpr) (string, error) {
-	// Check for jet.X() calls (SelectorExpr)
-	if sel, ok := expr.Fun.(*ast.SelectorExpr); ok {
-		if ident, ok := sel.X.(*ast.Ident); ok && ident.Name == "jet" {
-			return t.evaluateJetCall(sel.Sel.Name, expr.Args)
+// conversion lowers expr, the conversion of a runtime value to typ. One to
+// the type the value has, such as bool(ok) or uint64(amount) of a uint64,
+// or of a signed integer to the unsigned one of its width, which SIM0307
+// already warns of, is the value itself. One to a wider unsigned integer
+// pairs the value with zero high halves until it has the width of typ:
+//
+//	uint32(n) of a uint16  →  <(u16, u16)>::into((0, n))
+//
+// Any other conversion, such as one that narrows, has no lowering.
+func (t *Transpiler) conversion(typ string, expr *ast.CallExpr) (string, error) {
+	from := t.convertedType(expr.Args[0])
+	bits, fromBits := uintBits(typ), uintBits(from)
+	switch {
+	case from == typ || bits > 0 && from == "int"+typ[1:]:
+		return t.expr.TranslateArg(expr.Args[0])
+	case fromBits > 0 && fromBits < bits:
+		value, err := t.expr.TranslateArg(expr.Args[0])
+		if err != nil {
+			return "", err
 		}
+		for ; fromBits < bits; fromBits *= 2 {
+			value = fmt.Sprintf("<(u%d, u%d)>::into((0, %s))", fromBits, fromBits, value)
+		}
+		return value, nil
 	}
+	return t.expr.residual(expr, "the conversion")
+}
 
-	// User-defined function calls: look up in t.functions and inline the body
-	if ident, ok := expr.Fun.(*ast.Ident); ok {
-		funcName := t.toSnakeCase(ident.Name)
-		for _, fn := range t.functions {
-			if fn.Name == funcName && len(fn.Parameters) == len(expr.Args) {
-				// Evaluate call-site arguments
-				var argStrs []string
-				for _, arg := range expr.Args {
-					argStr, _ := t.evaluateJetArg(arg)
-					argStrs = append(argStrs, argStr)
+// convertedType returns the type of the operand of a conversion: that of a
+// typed name, field or conversion, the result of a jet or of a function of
+// the file, bool for a comparison or a logical operator, and otherwise the
+// type TypeOf infers.
+func (t *Transpiler) convertedType(expr ast.Expr) string {
+	expr = ast.Unparen(expr)
+	if typ, ok := t.operandType(expr); ok {
+		return typ
+	}
+	switch e := expr.(type) {
+	case *ast.CallExpr:
+		if sel, ok := e.Fun.(*ast.SelectorExpr); ok && t.isJet(sel.X) {
+			if info, ok := t.jetRegistry.Lookup(sel.Sel.Name); ok {
+				return carryFree(info.Result())
+			}
+		}
+		if types, ok := t.callResults(e); ok && len(types) == 1 {
+			return types[0]
+		}
+	case *ast.BinaryExpr:
+		switch e.Op {
+		case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ, token.LAND, token.LOR:
+			return "bool"
+		}
+	case *ast.UnaryExpr:
+		if e.Op == token.NOT {
+			return "bool"
+		}
+	}
+	return t.expr.TypeOf(expr)
+}
+
+// userCall lowers a call of the user function funcName, instantiating it
+// first when it is generic.
+func (t *Transpiler) userCall(funcName string, typeArg ast.Expr, expr *ast.CallExpr) (string, error) {
+	if g, ok := t.generics[funcName]; ok {
+		return t.callGeneric(funcName, g, typeArg, expr)
+	}
+	return t.inlineCall(funcName, expr)
+}
+
+// inlineCall substitutes the arguments of call into the body of the
+// function named funcName.
+func (t *Transpiler) inlineCall(funcName string, call *ast.CallExpr) (string, error) {
+	args := call.Args
+	for _, fn := range t.functions {
+		if fn.Name == funcName && len(fn.Parameters) == len(args) {
+			// Evaluate call-site arguments
+			var argStrs []string
+			for _, arg := range args {
+				argStr, err := t.expr.TranslateArg(arg)
+				if err != nil {
+					return "", err
 				}
-				// Substitute parameters into the function body using word-boundary replacement
-				body := fn.Body
-				for i, param := range fn.Parameters {
-					re := regexp.MustCompile(`\b` + regexp.QuoteMeta(param.Name) + `\b`)
-					body = re.ReplaceAllString(body, argStrs[i])
+				argStrs = append(argStrs, argStr)
+			}
+			if fn.Called {
+				return fmt.Sprintf("%s(%s)", funcName, strings.Join(argStrs, ", ")), nil
+			}
+			// Substitute parameters into the function body using word-boundary replacement
+			body := fn.Body
+			for i, param := range fn.Parameters {
+				re, ok := t.paramPatterns[param.Name]
+				if !ok {
+					re = regexp.MustCompile(`\b` + regexp.QuoteMeta(param.Name) + `\b`)
+					t.paramPatterns[param.Name] = re
 				}
-				return body, nil
+				body = re.ReplaceAllLiteralString(body, argStrs[i])
 			}
+			return body, nil
 		}
-		return "true", nil
 	}
-	return "true", nil
+	return t.expr.residual(call, "the call")
 }
 
 // byteWidthFromType returns the byte count for a Simplicity type used as SHA-256 input.
@@ -1982,7 +3247,7 @@ func (t *Transpiler) inferArgType(arg ast.Expr) string {
 	case *ast.CallExpr:
 		// jet.SHA256Finalize(...) returns u256 (= 32 bytes for the next round)
 		if sel, ok := a.Fun.(*ast.SelectorExpr); ok {
-			if selIdent, ok := sel.X.(*ast.Ident); ok && selIdent.Name == "jet" {
+			if t.isJet(sel.X) {
 				if jetInfo, found := t.jetRegistry.Lookup(sel.Sel.Name); found {
 					return jetInfo.ReturnType
 				}
@@ -2002,13 +3267,16 @@ func (t *Transpiler) evaluateJetCall(jetName string, args []ast.Expr) (string, e
 	// Look up the jet in the registry
 	jetInfo, found := t.jetRegistry.Lookup(jetName)
 	if !found {
-		return "", fmt.Errorf("unknown jet function: jet.%s", jetName)
+		return "", diag.UnknownJet.Errorf("unknown jet function: jet.%s", jetName)
+	}
+	if _, ok := optionPayload(jetInfo.ReturnType); ok {
+		return "", t.optionJetUse(token.NoPos, jetInfo)
 	}
 
 	// Evaluate arguments
 	var argStrs []string
 	for _, arg := range args {
-		argStr, err := t.evaluateJetArg(arg)
+		argStr, err := t.expr.TranslateArg(arg)
 		if err != nil {
 			return "", fmt.Errorf("failed to evaluate jet argument: %w", err)
 		}
@@ -2017,7 +3285,7 @@ func (t *Transpiler) evaluateJetCall(jetName string, args []ast.Expr) (string, e
 
 	// Return the jet call syntax for SimplicityHL
 	if len(argStrs) == 0 {
-		return formatJetCallExpr(jetInfo.SimplicityName, ""), nil
+		return t.jetCallExpr(jetInfo.SimplicityName, ""), nil
 	}
 
 	// BIP340Verify requires special tuple formatting: ((pubkey, msg), sig)
@@ -2025,39 +3293,33 @@ func (t *Transpiler) evaluateJetCall(jetName string, args []ast.Expr) (string, e
 		return fmt.Sprintf("jet::%s((%s, %s), %s)", jetInfo.SimplicityName, argStrs[0], argStrs[1], argStrs[2]), nil
 	}
 
-	return formatJetCallExpr(jetInfo.SimplicityName, strings.Join(argStrs, ", ")), nil
+	return t.jetCallExpr(jetInfo.SimplicityName, strings.Join(argStrs, ", ")), nil
 }
 
 func (t *Transpiler) generateCode() {
+	if t.library {
+		t.generateLibrary()
+		return
+	}
+
 	// Generate witness module
-	t.writeLine("mod witness {")
+	t.emit(0, "mod witness {")
 	for _, witness := range t.witnessValues {
-		// Fix type inference
-		witnessType := witness.Type
-		if witnessType == "auto" {
-			// Infer type from value
-			if witness.Value == "true" || witness.Value == "false" {
-				witnessType = "bool"
-			} else if _, err := strconv.ParseInt(witness.Value, 10, 64); err == nil {
-				witnessType = "u64"
-			} else {
-				witnessType = "bool" // default to bool
-			}
-		}
-
-		t.writeLine(fmt.Sprintf("    const %s: %s = %s;",
-			strings.ToUpper(witness.Name), witnessType, witness.Value))
+		t.emitDoc(1, witness.Doc)
+		t.emit(1, fmt.Sprintf("const %s: %s = %s;",
+			strings.ToUpper(witness.Name), witness.DeclaredType(), witness.Value))
 	}
-	t.writeLine("}")
+	t.emit(0, "}")
 
 	// Generate param module
-	t.writeLine("mod param {")
+	t.emit(0, "mod param {")
 	for _, constant := range t.constants {
-		t.writeLine(fmt.Sprintf("    const %s: %s = %s;",
+		t.emitDoc(1, constant.Doc)
+		t.emit(1, fmt.Sprintf("const %s: %s = %s;",
 			constant.Name, constant.Type, constant.Value))
 	}
-	t.writeLine("}")
-	t.writeLine("")
+	t.emit(0, "}")
+	t.printer.separator()
 
 	// Detect which u128 helper functions are needed by scanning jet calls
 	// and match expression body statements for u128 compare references.
@@ -2097,16 +3359,17 @@ func (t *Transpiler) generateCode() {
 	}
 
 	// Emit u128 helper functions before user functions and main
-	if len(neededU128) > 0 {
-		helpers := u128HelperFunctions(neededU128)
-		for _, line := range strings.Split(strings.TrimRight(helpers, "\n"), "\n") {
-			t.writeLine(line)
-		}
-		t.writeLine("")
+	for _, helper := range u128HelperFunctions(neededU128) {
+		t.emit(0, helper)
+		t.printer.separator()
 	}
+	t.emitCompilerHelpers()
 
 	// Generate functions
 	for _, function := range t.functions {
+		if t.ctx.Err() != nil {
+			return
+		}
 		t.generateFunction(function)
 	}
 
@@ -2114,7 +3377,34 @@ func (t *Transpiler) generateCode() {
 	t.generateMainFunction()
 }
 
+// generateLibrary emits the u128 helpers the functions need followed by the
+// functions themselves.
+func (t *Transpiler) generateLibrary() {
+	needed := make(map[string]bool)
+	for _, function := range t.functions {
+		for name := range u128CompareJets {
+			if strings.Contains(function.Body, name+"(") {
+				needed[name] = true
+			}
+		}
+	}
+	for _, helper := range u128HelperFunctions(needed) {
+		t.emit(0, helper)
+		t.printer.separator()
+	}
+	t.emitCompilerHelpers()
+	for _, function := range t.functions {
+		if t.ctx.Err() != nil {
+			return
+		}
+		t.generateFunction(function)
+	}
+}
+
 func (t *Transpiler) generateFunction(function Function) {
+	if function.Stub {
+		return
+	}
 	// Build parameter list
 	var params []string
 	for _, param := range function.Parameters {
@@ -2127,43 +3417,54 @@ func (t *Transpiler) generateFunction(function Function) {
 		sig += fmt.Sprintf(" -> %s", function.ReturnType)
 	}
 
-	t.writeLine(fmt.Sprintf("fn %s%s {", function.Name, sig))
-	for _, line := range strings.Split(function.Body, "\n") {
-		if strings.TrimSpace(line) != "" {
-			t.writeLine(fmt.Sprintf("    %s", line))
-		}
-	}
-	t.writeLine("}")
-	t.writeLine("")
+	t.printer.at(function.Pos)
+	t.emitDoc(0, function.Doc)
+	t.emit(0, fmt.Sprintf("fn %s%s {", function.Name, sig))
+	// A function without results returns no value, so its last item is a
+	// statement too.
+	t.printer.block(1, function.Body, function.ReturnType != "")
+	t.emit(0, "}")
+	t.printer.separator()
+	t.printer.at(token.NoPos)
 }
 
 // deduplicateWitnessRefs scans jet calls and match arm bodies for witness
 // references. Any witness referenced more than once is bound to a local
 // variable (emitted as a let statement), and all occurrences in jet call args
 // and match arm bodies are replaced with the local variable name.
+//
+// A reference is the whole name, so witness::C1 is no reference to C1 in
+// witness::C10. Both passes are linear in the size of main, which for a
+// generated contract may reference thousands of witnesses.
 func (t *Transpiler) deduplicateWitnessRefs() {
 	// Count witness references across all jet calls and match arm bodies
-	witnessCounts := make(map[string]int) // "witness::NAME" → count
+	witnessCounts := make(map[string]int, len(t.witnessValues)) // "witness::NAME" → count
+	for _, w := range t.witnessValues {
+		witnessCounts["witness::"+strings.ToUpper(w.Name)] = 0
+	}
+	count := func(text string) {
+		forWitnessRefs(text, func(_ int, ref string) {
+			if _, ok := witnessCounts[ref]; ok {
+				witnessCounts[ref]++
+			}
+		})
+	}
 	for _, jc := range t.jetCalls {
-		for _, w := range t.witnessValues {
-			ref := fmt.Sprintf("witness::%s", strings.ToUpper(w.Name))
-			witnessCounts[ref] += strings.Count(jc.Args, ref)
-		}
+		count(jc.Args)
 	}
 	for _, m := range t.matchExprs {
 		for _, c := range m.Cases {
 			for _, stmt := range c.BodyStmts {
-				for _, w := range t.witnessValues {
-					ref := fmt.Sprintf("witness::%s", strings.ToUpper(w.Name))
-					witnessCounts[ref] += strings.Count(stmt, ref)
-				}
+				count(stmt)
 			}
 		}
 	}
 
-	// For each witness used more than once, emit a let binding and replace refs
+	// For each witness used more than once, emit a let binding; one replacer
+	// then rewrites the references to all of them.
+	locals := make(map[string]string)
 	for _, w := range t.witnessValues {
-		ref := fmt.Sprintf("witness::%s", strings.ToUpper(w.Name))
+		ref := "witness::" + strings.ToUpper(w.Name)
 		if witnessCounts[ref] <= 1 {
 			continue
 		}
@@ -2177,74 +3478,139 @@ func (t *Transpiler) deduplicateWitnessRefs() {
 			}
 		}
 		localVar := strings.ToLower(w.Name)
-		t.writeLine(fmt.Sprintf("    let %s: %s = witness::%s;", localVar, witnessType, strings.ToUpper(w.Name)))
+		t.emit(1, "let "+localVar+": "+witnessType+" = "+ref+";")
+		locals[ref] = localVar
+	}
+	if len(locals) == 0 {
+		return
+	}
 
-		// Replace all witness::NAME references with the local variable
-		for i := range t.jetCalls {
-			t.jetCalls[i].Args = strings.ReplaceAll(t.jetCalls[i].Args, ref, localVar)
+	// Replace all witness::NAME references with the local variables.
+	replace := func(text string) string {
+		var b strings.Builder
+		last := 0
+		forWitnessRefs(text, func(at int, ref string) {
+			if local, ok := locals[ref]; ok {
+				b.WriteString(text[last:at])
+				b.WriteString(local)
+				last = at + len(ref)
+			}
+		})
+		if last == 0 {
+			return text
 		}
-		for i := range t.matchExprs {
-			for j := range t.matchExprs[i].Cases {
-				for k := range t.matchExprs[i].Cases[j].BodyStmts {
-					t.matchExprs[i].Cases[j].BodyStmts[k] = strings.ReplaceAll(
-						t.matchExprs[i].Cases[j].BodyStmts[k], ref, localVar)
-				}
+		b.WriteString(text[last:])
+		return b.String()
+	}
+	for i := range t.jetCalls {
+		t.jetCalls[i].Args = replace(t.jetCalls[i].Args)
+	}
+	for i := range t.matchExprs {
+		for j := range t.matchExprs[i].Cases {
+			for k := range t.matchExprs[i].Cases[j].BodyStmts {
+				t.matchExprs[i].Cases[j].BodyStmts[k] = replace(t.matchExprs[i].Cases[j].BodyStmts[k])
 			}
 		}
 	}
 }
 
+// forWitnessRefs calls f with each witness::NAME reference of text and its
+// offset, in order.
+func forWitnessRefs(text string, f func(at int, ref string)) {
+	for at := 0; ; {
+		i := strings.Index(text[at:], "witness::")
+		if i < 0 {
+			return
+		}
+		at += i
+		end := at + len("witness::")
+		for end < len(text) && isIdentByte(text[end]) {
+			end++
+		}
+		f(at, text[at:end])
+		at = end
+	}
+}
+
+// isIdentByte reports whether c may appear in a SimplicityHL identifier.
+func isIdentByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
 func (t *Transpiler) generateMainFunction() {
-	t.writeLine("fn main() {")
+	t.printer.at(t.entryPos)
+	t.emitDoc(0, t.entryDoc)
+	t.emit(0, "fn main() {")
+
+	// A predicate entry point is satisfied when it returns true.
+	if t.entryCall != "" {
+		t.printer.statement(1, fmt.Sprintf("assert!(%s)", t.entryCall))
+		t.emit(0, "}")
+		return
+	}
 
 	// Deduplicate witness references: in SimplicityHL, each witness value can
 	// only be consumed once (linear typing). If a witness is referenced more
 	// than once in jet calls or match arms, bind it to a local variable first.
 	t.deduplicateWitnessRefs()
+	for _, stmt := range t.witnessStructs {
+		t.emit(1, stmt)
+	}
 
 	// If we have match expressions, generate them
 	if t.hasMatchExpr && len(t.matchExprs) > 0 {
 		// Check if any match is a boolean if/else match
 		hasBoolMatch := false
 		for _, m := range t.matchExprs {
-			if m.IsBoolMatch {
+			if m.onValue() {
 				hasBoolMatch = true
 				break
 			}
 		}
 
 		if hasBoolMatch {
-			// Boolean if/else path: emit all top-level jet calls (named + standalone)
-			// in declaration order, then emit the boolean match expression.
+			// Boolean if/else path, also taken by matches on jet calls: emit
+			// all top-level jet calls (named + standalone) in declaration
+			// order, then emit the match expression.
 			for _, jc := range t.jetCalls {
 				if jc.VarName != "" {
-					t.writeLetBinding("    ", jc)
+					t.writeLetBinding(1, jc)
 				} else {
+					t.printer.at(jc.Pos)
 					args := jc.Args
 					if jc.JetName == "bip_0340_verify" {
 						args = jc.formatBIP340Args()
 					}
 					if jc.JetName == "verify" && strings.HasPrefix(args, "fee_adjusted_le_128(") {
-						for _, line := range expandFeeAdjustedLe128Verify(args) {
-							t.writeLine("    " + line)
+						for _, line := range expandFeeAdjustedLe128Verify(args, t.temp(jc.Pos)) {
+							t.emit(1, line)
 						}
 					} else if jc.JetName == "verify" && (strings.HasPrefix(args, "le_128(") || strings.HasPrefix(args, "lt_128(") || strings.HasPrefix(args, "eq_128(")) {
 						op := args[:strings.Index(args, "(")]
-						for _, line := range expandBorrow128Verify(op, args) {
-							t.writeLine("    " + line)
+						for _, line := range expandBorrow128Verify(op, args, t.temp(jc.Pos)) {
+							t.emit(1, line)
 						}
 					} else {
-						t.writeLine(fmt.Sprintf("    %s;", formatJetCallExpr(jc.JetName, args)))
+						t.printer.statement(1, t.jetCallExpr(jc.JetName, args))
 					}
 				}
 			}
+			// The last match is main's result; one before it is a
+			// statement.
+			var matches []*MatchExpression
 			for _, match := range t.matchExprs {
-				if match.IsBoolMatch {
-					matchCode := t.generateMatchExpression(match, "    ")
-					t.writeLine(matchCode)
+				if match.onValue() {
+					matches = append(matches, match)
 				}
 			}
-			t.writeLine("}")
+			for i, match := range matches {
+				pos := statementPos
+				if i == len(matches)-1 {
+					pos = resultPos
+				}
+				t.generateMatchExpression(match, 1, pos)
+			}
+			t.emit(0, "}")
 			return
 		}
 
@@ -2252,7 +3618,7 @@ func (t *Transpiler) generateMainFunction() {
 		// First, generate any jet calls that need to happen before the matches
 		for _, jc := range t.jetCalls {
 			if jc.VarName != "" {
-				t.writeLetBinding("    ", jc)
+				t.writeLetBinding(1, jc)
 			}
 		}
 
@@ -2263,18 +3629,17 @@ func (t *Transpiler) generateMainFunction() {
 		} else {
 			// Single match expression
 			for _, match := range t.matchExprs {
-				matchCode := t.generateMatchExpression(match, "    ")
-				t.writeLine(matchCode)
+				t.generateMatchExpression(match, 1, resultPos)
 			}
 		}
-		t.writeLine("}")
+		t.emit(0, "}")
 		return
 	}
 
 	// If we have unrolled loops, generate them
 	if t.hasUnrolledLoop && len(t.unrolledLoops) > 0 {
 		t.generateUnrolledLoopCode()
-		t.writeLine("}")
+		t.emit(0, "}")
 		return
 	}
 
@@ -2282,28 +3647,32 @@ func (t *Transpiler) generateMainFunction() {
 	if len(t.jetCalls) > 0 {
 		for _, jc := range t.jetCalls {
 			if jc.VarName != "" {
-				t.writeLetBinding("    ", jc)
+				t.writeLetBinding(1, jc)
 			} else {
 				// This is a standalone call (like BIP340Verify)
+				t.printer.at(jc.Pos)
 				args := jc.Args
 				if jc.JetName == "bip_0340_verify" {
 					args = jc.formatBIP340Args()
 				}
 				if jc.JetName == "verify" && strings.HasPrefix(args, "fee_adjusted_le_128(") {
-					for _, line := range expandFeeAdjustedLe128Verify(args) {
-						t.writeLine("    " + line)
+					for _, line := range expandFeeAdjustedLe128Verify(args, t.temp(jc.Pos)) {
+						t.emit(1, line)
 					}
 				} else if jc.JetName == "verify" && (strings.HasPrefix(args, "le_128(") || strings.HasPrefix(args, "lt_128(") || strings.HasPrefix(args, "eq_128(")) {
 					op := args[:strings.Index(args, "(")]
-					for _, line := range expandBorrow128Verify(op, args) {
-						t.writeLine("    " + line)
+					for _, line := range expandBorrow128Verify(op, args, t.temp(jc.Pos)) {
+						t.emit(1, line)
 					}
 				} else {
-					t.writeLine(fmt.Sprintf("    %s;", formatJetCallExpr(jc.JetName, args)))
+					t.printer.statement(1, t.jetCallExpr(jc.JetName, args))
 				}
 			}
 		}
-		t.writeLine("}")
+		if result := t.resultBinding(); result != "" {
+			t.printer.statement(1, fmt.Sprintf("assert!(%s)", result))
+		}
+		t.emit(0, "}")
 		return
 	}
 
@@ -2319,7 +3688,7 @@ func (t *Transpiler) generateMainFunction() {
 
 	// If we found a result witness, use it
 	if resultWitness != "" {
-		t.writeLine(fmt.Sprintf("    assert!(%s);", resultWitness))
+		t.printer.statement(1, fmt.Sprintf("assert!(%s)", resultWitness))
 	} else if len(t.functions) > 0 {
 		// Otherwise, call the main business logic function with appropriate witness values
 		mainFunc := t.functions[len(t.functions)-1] // Assume the last function is the main logic
@@ -2343,33 +3712,32 @@ func (t *Transpiler) generateMainFunction() {
 			}
 		}
 
-		if len(args) == paramCount {
-			t.writeLine(fmt.Sprintf("    assert!(%s(%s));", mainFunc.Name, strings.Join(args, ", ")))
+		if len(args) == paramCount && mainFunc.ReturnType == "bool" {
+			t.printer.statement(1, fmt.Sprintf("assert!(%s(%s))", mainFunc.Name, strings.Join(args, ", ")))
 		} else {
-			t.writeLine("    assert!(true);")
+			t.emit(1, "assert!(true);")
 		}
 	} else {
-		t.writeLine("    assert!(true);")
+		t.emit(1, "assert!(true);")
 	}
 
-	t.writeLine("}")
+	t.emit(0, "}")
 }
 
 // generateMultisigMatchCode generates code for multiple Option match expressions with counter accumulation
 func (t *Transpiler) generateMultisigMatchCode() {
-	t.writeLine("")
-	t.writeLine("    // Signature verification with counter accumulation")
+	t.printer.blank()
+	t.emit(1, "// Signature verification with counter accumulation")
 
-	for i, match := range t.matchExprs {
-		// Generate counter assignment with match expression
-		if i == 0 {
-			t.writeLine(fmt.Sprintf("    let count_%d: u32 =", i))
-		} else {
-			t.writeLine(fmt.Sprintf("    let count_%d: u32 = count_%d +", i, i-1))
-		}
+	var count string
+	for _, match := range t.matchExprs {
+		// Bind the 1 or 0 of the signature, then add it to the count
+		t.printer.at(match.Pos)
+		prev, term := count, t.temp(match.Pos)
+		t.emit(1, fmt.Sprintf("let %s: u32 =", term))
 
 		// Generate match expression inline
-		t.writeLine(fmt.Sprintf("        match %s {", match.Scrutinee))
+		t.emit(2, fmt.Sprintf("match %s {", match.Scrutinee))
 
 		for _, mc := range match.Cases {
 			pattern := mc.Pattern
@@ -2384,30 +3752,42 @@ func (t *Transpiler) generateMultisigMatchCode() {
 			switch mc.Pattern {
 			case "None":
 				// None arm: no block braces, just the value
-				t.writeLine("            None => 0,")
+				t.emit(3, "None => 0,")
 			case "Some":
-				t.writeLine(fmt.Sprintf("            %s => {", pattern))
+				t.emit(3, fmt.Sprintf("%s => {", pattern))
 				// Jet calls are statements; they need semicolons before the return value
 				for _, stmt := range mc.BodyStmts {
-					t.writeLine(fmt.Sprintf("                %s;", stmt))
+					t.printer.statement(4, stmt)
 				}
-				t.writeLine("                1")
-				t.writeLine("            },")
+				t.emit(4, "1")
+				t.emit(3, "},")
 			default:
-				t.writeLine(fmt.Sprintf("            %s => {", pattern))
-				for _, stmt := range mc.BodyStmts {
-					t.writeLine(fmt.Sprintf("                %s", stmt))
-				}
-				t.writeLine("            },")
+				t.emit(3, fmt.Sprintf("%s => {", pattern))
+				t.armBlock(4, mc, true)
+				t.emit(3, "},")
 			}
 		}
-		t.writeLine("        };")
+		t.emit(2, "};")
+		count = t.addCount(match.Pos, prev, term)
 	}
 
 	// Final verification - require at least 2 signatures
-	t.writeLine("")
-	t.writeLine("    // Require at least 2 valid signatures")
-	t.writeLine(fmt.Sprintf("    assert!(jet::le_32(2, count_%d))", len(t.matchExprs)-1))
+	t.printer.at(t.entryPos)
+	t.printer.blank()
+	t.emit(1, "// Require at least 2 valid signatures")
+	t.printer.statement(1, fmt.Sprintf("assert!(%s(2, %s))", jetRef("le_32"), count))
+}
+
+// addCount returns the count of signatures after one of them, term, has
+// been counted: term itself for the first, when prev is "", else prev plus
+// term, bound to a temporary at pos.
+func (t *Transpiler) addCount(pos token.Pos, prev, term string) string {
+	if prev == "" {
+		return term
+	}
+	count := t.temp(pos)
+	t.printer.statement(1, fmt.Sprintf("let (_, %s): (bool, u32) = %s(%s, %s)", count, jetRef("add_32"), prev, term))
+	return count
 }
 
 // formatBIP340Args formats arguments for BIP340Verify with proper tuple syntax
@@ -2422,28 +3802,39 @@ func (jc *JetCall) formatBIP340Args() string {
 	return fmt.Sprintf("(%s, %s), %s", args[0], args[1], args[2])
 }
 
+// toSnakeCase is snakeCase, remembered for the names of the current call:
+// a generated contract converts the same few thousand names over and over.
+// A temporary the call introduced, which a lowering may hand back as a Go
+// name, is its own SimplicityHL name.
 func (t *Transpiler) toSnakeCase(name string) string {
-	if name == "" {
+	if t.temps.Issued(name) {
 		return name
 	}
-
-	var result strings.Builder
-	for i, r := range name {
-		if i > 0 && r >= 'A' && r <= 'Z' {
-			result.WriteByte('_')
-		}
-		if r >= 'A' && r <= 'Z' {
-			result.WriteRune(r - 'A' + 'a')
-		} else {
-			result.WriteRune(r)
-		}
+	snake, ok := t.snakeNames[name]
+	if !ok {
+		snake = snakeCase(name)
+		t.snakeNames[name] = snake
 	}
-	return result.String()
+	return snake
+}
+
+// WitnessName returns the witness module name for a Go identifier, e.g.
+// aliceSig becomes ALICE_SIG.
+func WitnessName(name string) string {
+	return strings.ToUpper(snakeCase(name))
+}
+
+// emit writes a line of output at the given nesting depth through the printer.
+func (t *Transpiler) emit(depth int, line string) {
+	t.printer.line(depth, line)
 }
 
-func (t *Transpiler) writeLine(line string) {
-	t.output.WriteString(line)
-	t.output.WriteString("\n")
+// emitDoc writes doc, the text of a Go doc comment, as // comments at the
+// given depth, unless comments are turned off.
+func (t *Transpiler) emitDoc(depth int, doc string) {
+	if !t.noComments {
+		t.printer.comment(depth, doc)
+	}
 }
 
 // generateUnrolledLoopCode generates code for unrolled loops with counter accumulation
@@ -2451,89 +3842,154 @@ func (t *Transpiler) generateUnrolledLoopCode() {
 	// First, generate any jet calls that happen before the loop
 	for _, jc := range t.jetCalls {
 		if jc.VarName != "" {
-			t.writeLetBinding("    ", jc)
+			t.writeLetBinding(1, jc)
 		}
 	}
 
 	// Generate unrolled loop code
 	for _, loop := range t.unrolledLoops {
-		t.writeLine(fmt.Sprintf("    // Unrolled loop (originally: for %s := 0; %s < %d; %s++)",
+		t.emit(1, fmt.Sprintf("// Unrolled loop (originally: for %s := 0; %s < %d; %s++)",
 			loop.IndexVar, loop.IndexVar, loop.Iterations, loop.IndexVar))
 
 		// Generate counter accumulation
-		for i := 0; i < loop.Iterations; i++ {
+		var count string
+		for _, body := range loop.BodyStmts {
 			// Generate a check_sig call and accumulate
-			if i == 0 {
-				t.writeLine(fmt.Sprintf("    let count_%d: u32 =", i))
-			} else {
-				t.writeLine(fmt.Sprintf("    let count_%d: u32 = count_%d +", i, i-1))
-			}
+			prev, term := count, t.temp(loop.Pos)
+			t.emit(1, fmt.Sprintf("let %s: u32 =", term))
 
 			// Generate the body for this iteration
-			for _, stmt := range loop.BodyStmts[i] {
-				t.writeLine(fmt.Sprintf("        %s", stmt))
+			for j, stmt := range body {
+				if j == len(body)-1 {
+					stmt += ";"
+				}
+				t.emit(2, stmt)
 			}
+			count = t.addCount(loop.Pos, prev, term)
 		}
 
 		// Final verification
-		t.writeLine(fmt.Sprintf("    assert!(jet::le_32(2, count_%d))", loop.Iterations-1))
+		t.printer.statement(1, fmt.Sprintf("assert!(%s(2, %s))", jetRef("le_32"), count))
 	}
 }
 
-// generateWitnessPlaceholder returns a syntactically valid SimplicityHL zero-value
-// for the given Simplicity type. Used when the actual witness data is not known at
-// compile time (runtime witnesses).
-func generateWitnessPlaceholder(simType string) string {
-	simType = strings.TrimSpace(simType)
+// encodeWitnesses rewrites the computed value of each integer and byte
+// array witness in the literal syntax of its type: byte arrays and
+// integers wider than 64 bits as hex of the exact width. Values that are
+// not literals, such as the result of a jet call, are only known at spend
+// time and get the type's placeholder.
+func (t *Transpiler) encodeWitnesses() error {
+	for i := range t.witnessValues {
+		w := &t.witnessValues[i]
+		if w.Type == "auto" && strings.HasPrefix(w.Value, "0x") {
+			w.Type = t.typeMapper.InferHexType(w.Value)
+		}
+		witnessType := w.DeclaredType()
+		if alias, ok := t.customTypes[witnessType]; ok {
+			witnessType = alias
+		}
+		typ, err := shlparse.ParseType(witnessType)
+		if err != nil {
+			continue
+		}
+		var wide bool
+		var byteLen int
+		switch typ := typ.(type) {
+		case *shlparse.UInt:
+			wide = typ.Bits > 64
+		case *shlparse.ArrayType:
+			if u, ok := typ.Elem.(*shlparse.UInt); !ok || u.Bits != 8 {
+				continue
+			}
+			wide, byteLen = true, typ.Len
+		default:
+			continue
+		}
 
-	// Fixed-size byte arrays: [u8; N]
-	if strings.HasPrefix(simType, "[u8; ") && strings.HasSuffix(simType, "]") {
-		nStr := strings.TrimSpace(simType[5 : len(simType)-1])
-		if n, err := strconv.Atoi(nStr); err == nil {
-			return "0x" + strings.Repeat("00", n)
-		}
-	}
-
-	switch simType {
-	case "u256":
-		return "0x" + strings.Repeat("0", 64)
-	case "u128":
-		return "0x" + strings.Repeat("0", 32)
-	case "u64":
-		return "0x0000000000000000"
-	case "u32":
-		return "0x00000000"
-	case "u16":
-		return "0x0000"
-	case "u8":
-		return "0x00"
-	case "bool":
-		return "false"
+		expr, err := shlparse.ParseExpr(w.Value)
+		if err == nil && !wide && shlparse.CheckValue(expr, typ) == nil {
+			continue
+		}
+		if arr, ok := expr.(*shlparse.Array); ok && byteLen > 0 {
+			// [N]byte{...}: elements not listed are zero
+			if len(arr.Elems) > byteLen {
+				return diag.ValueOverflow.Errorf("witness %s: %d elements do not fit in %s", strings.ToUpper(w.Name), len(arr.Elems), witnessType)
+			}
+			buf := make([]byte, byteLen)
+			for j, elem := range arr.Elems {
+				var n *big.Int
+				if lit, ok := elem.(*shlparse.Literal); ok {
+					n, _ = simtypes.ParseIntLiteral(lit.Text)
+				}
+				if n == nil || n.BitLen() > 8 {
+					return diag.ValueOverflow.Errorf("witness %s: element %d is not a byte", strings.ToUpper(w.Name), j)
+				}
+				buf[j] = byte(n.Uint64())
+			}
+			if w.Value, err = simtypes.EncodeBytes(witnessType, buf); err != nil {
+				return diag.ValueOverflow.Errorf("witness %s: %w", strings.ToUpper(w.Name), err)
+			}
+			continue
+		}
+		n, ok := simtypes.ParseIntLiteral(w.Value)
+		if !ok {
+			w.Value = zeroValue(witnessType)
+			continue
+		}
+		if w.Value, err = simtypes.EncodeInt(witnessType, n); err != nil {
+			return diag.ValueOverflow.Errorf("witness %s: %w", strings.ToUpper(w.Name), err)
+		}
 	}
+	return nil
+}
 
-	// Option<T> → None
-	if strings.HasPrefix(simType, "Option<") && strings.HasSuffix(simType, ">") {
-		return "None"
+// applyWitnessValues substitutes the caller's witness values, checking
+// each one against the declared type. Witnesses without an entry keep the
+// value computed from the source.
+func (t *Transpiler) applyWitnessValues() error {
+	if len(t.overrides) == 0 {
+		return nil
 	}
-
-	// Either<L, R> → Left(zero_L)
-	if strings.HasPrefix(simType, "Either<") && strings.HasSuffix(simType, ">") {
-		if st, err := simtypes.ParseSumType(simType); err == nil {
-			return fmt.Sprintf("Left(%s)", generateWitnessPlaceholder(st.LeftType))
-		}
+	index := make(map[string]int, len(t.witnessValues))
+	declared := make([]string, 0, len(t.witnessValues))
+	for i, w := range t.witnessValues {
+		name := strings.ToUpper(w.Name)
+		index[name] = i
+		declared = append(declared, name)
 	}
 
-	// Tuple (A, B, ...) → (zero_A, zero_B, ...)
-	if strings.HasPrefix(simType, "(") && strings.HasSuffix(simType, ")") {
-		if tt, err := simtypes.ParseTupleType(simType); err == nil && len(tt.Elements) > 0 {
-			var vals []string
-			for _, elem := range tt.Elements {
-				vals = append(vals, generateWitnessPlaceholder(strings.TrimSpace(elem)))
+	names := make([]string, 0, len(t.overrides))
+	for name := range t.overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		i, ok := index[name]
+		if !ok {
+			if len(declared) == 0 {
+				return diag.UnknownWitness.Errorf("unknown witness %s: program declares no witnesses", name)
 			}
-			return "(" + strings.Join(vals, ", ") + ")"
+			return diag.UnknownWitness.Errorf("unknown witness %s (declared: %s)", name, strings.Join(declared, ", "))
 		}
+		w := &t.witnessValues[i]
+		witnessType := w.DeclaredType()
+		if alias, ok := t.customTypes[witnessType]; ok {
+			witnessType = alias
+		}
+		typ, err := shlparse.ParseType(witnessType)
+		if err != nil {
+			return diag.UnknownWitness.Errorf("witness %s: cannot check type %s: %w", name, witnessType, err)
+		}
+		value := strings.TrimSpace(t.overrides[name])
+		expr, err := shlparse.ParseExpr(value)
+		if err != nil {
+			return diag.UnknownWitness.Errorf("witness %s: expected %s: %w", name, witnessType, err)
+		}
+		if err := shlparse.CheckValue(expr, typ); err != nil {
+			return diag.UnknownWitness.Errorf("witness %s: %w", name, err)
+		}
+		w.Type = w.DeclaredType()
+		w.Value = value
 	}
-
-	// Fallback: u256-sized zero
-	return "0x" + strings.Repeat("0", 64)
+	return nil
 }
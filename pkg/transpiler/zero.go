@@ -0,0 +1,66 @@
+package transpiler
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+)
+
+// zeroValue writes the value of simType that Go gives a variable declared
+// without one: 0 as hex of the type's width, false, an array or tuple of
+// zeros, None, and Left of the zero of an Either's left side. It is the
+// placeholder of a witness the spender has not set as well as the value of
+// a local or struct field left out. A type it cannot read, such as Ctx8,
+// gets the zero of a u256.
+func zeroValue(simType string) string {
+	typ, err := shlparse.ParseType(strings.TrimSpace(simType))
+	if err != nil {
+		return zeroOf(&shlparse.UInt{Bits: 256})
+	}
+	return zeroOf(typ)
+}
+
+func zeroOf(typ shlparse.Type) string {
+	switch t := typ.(type) {
+	case *shlparse.UInt:
+		if t.Bits < 8 {
+			return "0"
+		}
+		return "0x" + strings.Repeat("0", t.Bits/4)
+	case *shlparse.Bool:
+		return "false"
+	case *shlparse.ArrayType:
+		if u, ok := t.Elem.(*shlparse.UInt); ok && u.Bits == 8 {
+			return "0x" + strings.Repeat("00", t.Len)
+		}
+		elems := make([]string, t.Len)
+		for i := range elems {
+			elems[i] = zeroOf(t.Elem)
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case *shlparse.TupleType:
+		elems := make([]string, len(t.Elems))
+		for i, elem := range t.Elems {
+			elems[i] = zeroOf(elem)
+		}
+		return tupleOf(elems)
+	case *shlparse.Option:
+		return "None"
+	case *shlparse.Either:
+		return "Left(" + zeroOf(t.Left) + ")"
+	}
+	return zeroOf(&shlparse.UInt{Bits: 256})
+}
+
+// zeroFolded returns the zero of simType as a folded value, for the types
+// the Folder computes with: unsigned integers and bool.
+func zeroFolded(simType string) (Value, bool) {
+	switch {
+	case simType == "bool":
+		return Value{Type: "bool"}, true
+	case isUIntType(simType):
+		return Value{Type: simType, Int: new(big.Int)}, true
+	}
+	return Value{}, false
+}
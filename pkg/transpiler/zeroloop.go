@@ -0,0 +1,199 @@
+package transpiler
+
+import (
+	"go/ast"
+	"go/token"
+	"slices"
+)
+
+// A loop that returns as soon as it finds a nonzero byte tests whether an
+// array is all zeros:
+//
+//	for i := 0; i < 32; i++ {
+//		if k[i] != 0 {
+//			return false
+//		}
+//	}
+//	return true
+//
+// std.IsZero32(k) makes the same test in one comparison, and the loop
+// followed by its return is lowered to that call. One over a whole byte
+// array of another length, such as a [4]byte, which no helper takes, is
+// unrolled as the accumulation zero = zero && k[i] == 0 over its elements.
+// A loop of this shape over part of an array is an error that names the
+// helpers rather than a function body quietly dropped.
+
+// A loop that sets a flag and breaks on a nonzero byte makes the same test,
+//
+//	zero := true
+//	for i := 0; i < 32; i++ {
+//		if k[i] != 0 {
+//			zero = false
+//			break
+//		}
+//	}
+//	return zero
+//
+// and is lowered the same way.
+
+// zeroLoopHint is the alternative offered for a loop testing for zeros.
+const zeroLoopHint = "std.IsZero20, std.IsZero32 and std.IsZero64 test a whole [20]byte, [32]byte or [64]byte in one comparison"
+
+// lowerZeroLoop returns the lines of the result for loop followed by next,
+// the function's return statement, when they test an array for zeros: the
+// result expression, after the lets it reads when the loop is unrolled.
+func (t *Transpiler) lowerZeroLoop(loop, next ast.Stmt) ([]string, bool, error) {
+	forStmt, ok := loop.(*ast.ForStmt)
+	if !ok || forStmt.Body == nil || len(forStmt.Body.List) != 1 {
+		return nil, false, nil
+	}
+	var bounds loopBounds
+	if !t.countingLoop(forStmt, &bounds) {
+		return nil, false, nil
+	}
+	ifStmt, ok := forStmt.Body.List[0].(*ast.IfStmt)
+	if !ok || ifStmt.Init != nil || ifStmt.Else != nil || len(ifStmt.Body.List) != 1 {
+		return nil, false, nil
+	}
+	array, ok := t.nonzeroElement(ifStmt.Cond, bounds.index)
+	if !ok {
+		return nil, false, nil
+	}
+	found, ok := boolReturn(ifStmt.Body.List[0])
+	if !ok {
+		return nil, false, nil
+	}
+	if otherwise, ok := boolReturn(next); !ok || otherwise == found {
+		return nil, false, nil
+	}
+	sym, ok := t.expr.lookupPath(array)
+	if !ok {
+		return nil, false, nil
+	}
+	elem, n, ok := arrayType(sym.Type)
+	if !ok || elem != "u8" || bounds.from != 0 || bounds.to != n {
+		return nil, false, t.errorAt(loop.Pos(), "this loop tests whether %s is all zeros, which is not lowered: %s", array.Name, zeroLoopHint)
+	}
+	if !slices.Contains(isZeroSizes, n) {
+		return t.unrollZeroLoop(forStmt, ast.Unparen(ifStmt.Cond).(*ast.BinaryExpr), found)
+	}
+	ref, err := t.expr.TranslateArg(array)
+	if err != nil {
+		return nil, false, err
+	}
+	helper := isZeroHelperName(n)
+	t.helpers[helper] = true
+	if found {
+		// The loop returns true on a nonzero byte: the array is not zero.
+		return []string{"match " + helper + "(" + ref + ") { true => false, false => true, }"}, true, nil
+	}
+	return []string{helper + "(" + ref + ")"}, true, nil
+}
+
+// unrollZeroLoop returns the lets of the accumulation that tests every
+// element of the array of forStmt for 0, followed by the result of the
+// loop, whose body returns found when nonzero, an element that is not 0,
+// holds.
+func (t *Transpiler) unrollZeroLoop(forStmt *ast.ForStmt, nonzero *ast.BinaryExpr, found bool) ([]string, bool, error) {
+	at := forStmt.For
+	flag := func() *ast.Ident { return &ast.Ident{NamePos: at, Name: t.temp(at)} }
+	zero := flag()
+	decl := &ast.AssignStmt{Lhs: []ast.Expr{zero}, TokPos: at, Tok: token.DEFINE, Rhs: []ast.Expr{&ast.Ident{NamePos: at, Name: "true"}}}
+	isZero := &ast.BinaryExpr{X: nonzero.X, OpPos: nonzero.OpPos, Op: token.EQL, Y: nonzero.Y}
+	update := &ast.AssignStmt{Lhs: []ast.Expr{zero}, TokPos: nonzero.Pos(), Tok: token.ASSIGN, Rhs: []ast.Expr{
+		&ast.BinaryExpr{X: &ast.Ident{NamePos: at, Name: zero.Name}, OpPos: nonzero.OpPos, Op: token.LAND, Y: isZero},
+	}}
+	loop := &ast.ForStmt{For: at, Init: forStmt.Init, Cond: forStmt.Cond, Post: forStmt.Post, Body: &ast.BlockStmt{List: []ast.Stmt{update}}}
+	a, ok, err := t.matchAccumulation(decl, loop)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	lines, ok := t.lowerAccumulation(a)
+	if !ok {
+		return nil, false, nil
+	}
+	result := t.toSnakeCase(zero.Name)
+	if found {
+		// The loop returns true on a nonzero byte: the array is not zero.
+		result = "match " + result + " { true => false, false => true, }"
+	}
+	return append(lines, result), true, nil
+}
+
+// lowerZeroFlagLoop returns the lines of the result for decl, loop and next
+// when they test an array for zeros with a flag: decl declares the flag, the
+// loop sets it on a nonzero byte and breaks, and next returns it.
+func (t *Transpiler) lowerZeroFlagLoop(decl, loop, next ast.Stmt) ([]string, bool, error) {
+	var a accumulation
+	forStmt, ok := loop.(*ast.ForStmt)
+	if !ok || forStmt.Body == nil || len(forStmt.Body.List) != 1 || !t.accumulatorDecl(decl, &a) || a.typ != "bool" {
+		return nil, false, nil
+	}
+	initial, ok := boolLit(a.init)
+	if ret, isReturn := next.(*ast.ReturnStmt); !ok || !isReturn || len(ret.Results) != 1 || !isIdentNamed(ret.Results[0], a.acc) {
+		return nil, false, nil
+	}
+	ifStmt, ok := forStmt.Body.List[0].(*ast.IfStmt)
+	if !ok || len(ifStmt.Body.List) != 2 {
+		return nil, false, nil
+	}
+	set, ok := ifStmt.Body.List[0].(*ast.AssignStmt)
+	if branch, isBranch := ifStmt.Body.List[1].(*ast.BranchStmt); !ok || !isBranch || branch.Tok != token.BREAK || branch.Label != nil {
+		return nil, false, nil
+	}
+	if set.Tok != token.ASSIGN || len(set.Lhs) != 1 || len(set.Rhs) != 1 || !isIdentNamed(set.Lhs[0], a.acc) {
+		return nil, false, nil
+	}
+	found, ok := boolLit(set.Rhs[0])
+	if !ok || found == initial {
+		return nil, false, nil
+	}
+	// The flag form is the return form with the flag's values returned.
+	returns := &ast.ForStmt{For: forStmt.For, Init: forStmt.Init, Cond: forStmt.Cond, Post: forStmt.Post, Body: &ast.BlockStmt{List: []ast.Stmt{
+		&ast.IfStmt{If: ifStmt.If, Init: ifStmt.Init, Cond: ifStmt.Cond, Body: &ast.BlockStmt{List: []ast.Stmt{
+			&ast.ReturnStmt{Return: set.Pos(), Results: []ast.Expr{set.Rhs[0]}},
+		}}, Else: ifStmt.Else},
+	}}}
+	return t.lowerZeroLoop(returns, &ast.ReturnStmt{Return: next.Pos(), Results: []ast.Expr{a.init}})
+}
+
+// boolLit returns the value of expr when it is true or false.
+func boolLit(expr ast.Expr) (bool, bool) {
+	ident, ok := expr.(*ast.Ident)
+	if !ok || ident.Name != "true" && ident.Name != "false" {
+		return false, false
+	}
+	return ident.Name == "true", true
+}
+
+// nonzeroElement returns the array of cond when it is arr[index] != 0 or
+// 0 != arr[index].
+func (t *Transpiler) nonzeroElement(cond ast.Expr, index string) (*ast.Ident, bool) {
+	bin, ok := ast.Unparen(cond).(*ast.BinaryExpr)
+	if !ok || bin.Op != token.NEQ {
+		return nil, false
+	}
+	elem, zero := bin.X, bin.Y
+	if c, isConst := t.constantInt(elem); isConst && c.Sign() == 0 {
+		elem, zero = zero, elem
+	}
+	if c, isConst := t.constantInt(zero); !isConst || c.Sign() != 0 {
+		return nil, false
+	}
+	ix, ok := ast.Unparen(elem).(*ast.IndexExpr)
+	if !ok || !isIdentNamed(ix.Index, index) {
+		return nil, false
+	}
+	array, ok := ix.X.(*ast.Ident)
+	return array, ok
+}
+
+// boolReturn returns the value of stmt when it is return true or return
+// false.
+func boolReturn(stmt ast.Stmt) (bool, bool) {
+	ret, ok := stmt.(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return false, false
+	}
+	return boolLit(ret.Results[0])
+}
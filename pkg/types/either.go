@@ -24,54 +24,19 @@ const (
 
 // ParseSumType parses a string like "Either<u256, [u8; 64]>" or "Option<[u8; 64]>"
 func ParseSumType(typeStr string) (*SumType, error) {
-	typeStr = strings.TrimSpace(typeStr)
-
-	// Check for Either<L, R>
-	if strings.HasPrefix(typeStr, "Either<") && strings.HasSuffix(typeStr, ">") {
-		inner := typeStr[7 : len(typeStr)-1] // Remove "Either<" and ">"
-		left, right, err := splitTypeParams(inner)
-		if err != nil {
-			return nil, fmt.Errorf("invalid Either type: %w", err)
-		}
-		return &SumType{
-			Kind:      SumTypeEither,
-			LeftType:  left,
-			RightType: right,
-		}, nil
+	t, err := Parse(typeStr)
+	if err != nil {
+		return nil, fmt.Errorf("not a sum type: %s", typeStr)
 	}
-
-	// Check for Option<T>
-	if strings.HasPrefix(typeStr, "Option<") && strings.HasSuffix(typeStr, ">") {
-		inner := typeStr[7 : len(typeStr)-1] // Remove "Option<" and ">"
-		return &SumType{
-			Kind:     SumTypeOption,
-			LeftType: strings.TrimSpace(inner),
-		}, nil
+	switch t := t.(type) {
+	case *Either:
+		return &SumType{Kind: SumTypeEither, LeftType: t.Left.String(), RightType: t.Right.String()}, nil
+	case *Option:
+		return &SumType{Kind: SumTypeOption, LeftType: t.Elem.String()}, nil
 	}
-
 	return nil, fmt.Errorf("not a sum type: %s", typeStr)
 }
 
-// splitTypeParams splits "A, B" handling nested brackets
-func splitTypeParams(params string) (string, string, error) {
-	depth := 0
-	for i, c := range params {
-		switch c {
-		case '<', '(', '[':
-			depth++
-		case '>', ')', ']':
-			depth--
-		case ',':
-			if depth == 0 {
-				left := strings.TrimSpace(params[:i])
-				right := strings.TrimSpace(params[i+1:])
-				return left, right, nil
-			}
-		}
-	}
-	return "", "", fmt.Errorf("could not split type parameters: %s", params)
-}
-
 // ToSimplicityHL returns the SimplicityHL representation of the sum type
 func (st *SumType) ToSimplicityHL() string {
 	switch st.Kind {
@@ -147,52 +112,21 @@ type TupleType struct {
 
 // ParseTupleType parses a string like "(u256, [u8; 64])"
 func ParseTupleType(typeStr string) (*TupleType, error) {
-	typeStr = strings.TrimSpace(typeStr)
-
-	if !strings.HasPrefix(typeStr, "(") || !strings.HasSuffix(typeStr, ")") {
+	t, err := Parse(typeStr)
+	if err != nil {
 		return nil, fmt.Errorf("not a tuple type: %s", typeStr)
 	}
-
-	inner := typeStr[1 : len(typeStr)-1] // Remove "(" and ")"
-	if inner == "" {
+	switch t := t.(type) {
+	case *Unit:
 		return &TupleType{Elements: []string{}}, nil
-	}
-
-	elements, err := splitTupleElements(inner)
-	if err != nil {
-		return nil, err
-	}
-
-	return &TupleType{Elements: elements}, nil
-}
-
-// splitTupleElements splits tuple elements handling nested brackets
-func splitTupleElements(params string) ([]string, error) {
-	var elements []string
-	depth := 0
-	start := 0
-
-	for i, c := range params {
-		switch c {
-		case '<', '(', '[':
-			depth++
-		case '>', ')', ']':
-			depth--
-		case ',':
-			if depth == 0 {
-				elements = append(elements, strings.TrimSpace(params[start:i]))
-				start = i + 1
-			}
+	case *Tuple:
+		elements := make([]string, len(t.Elems))
+		for i, elem := range t.Elems {
+			elements[i] = elem.String()
 		}
+		return &TupleType{Elements: elements}, nil
 	}
-
-	// Add the last element
-	last := strings.TrimSpace(params[start:])
-	if last != "" {
-		elements = append(elements, last)
-	}
-
-	return elements, nil
+	return nil, fmt.Errorf("not a tuple type: %s", typeStr)
 }
 
 // ToSimplicityHL returns the SimplicityHL representation
@@ -0,0 +1,96 @@
+package types
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ParseIntLiteral parses a decimal, hex, octal or binary integer literal as
+// written in Go or SimplicityHL source, with optional underscores.
+func ParseIntLiteral(s string) (*big.Int, bool) {
+	n, ok := new(big.Int).SetString(strings.ReplaceAll(strings.TrimSpace(s), "_", ""), 0)
+	if !ok || n.Sign() < 0 {
+		return nil, false
+	}
+	return n, true
+}
+
+// EncodeInt renders n as a value of simType, an unsigned integer or byte
+// array type. Integers of up to 64 bits are written in decimal; wider
+// integers and byte arrays as 0x-prefixed hex of exactly the type's width,
+// zero-padded on the left, which is the only form SimplicityHL accepts for
+// them. A value wider than the type is an error.
+func EncodeInt(simType string, n *big.Int) (string, error) {
+	bits, array, err := intWidth(simType)
+	if err != nil {
+		return "", err
+	}
+	hex := array || bits > 64
+	if n.Sign() < 0 || n.BitLen() > bits {
+		shown := n.String()
+		if hex {
+			shown = fmt.Sprintf("%#x", n)
+		}
+		return "", fmt.Errorf("%s does not fit in %s", shown, simType)
+	}
+	if !hex {
+		return n.String(), nil
+	}
+	return fmt.Sprintf("0x%0*x", bits/4, n), nil
+}
+
+// EncodeBytes renders b as a value of the byte array type simType, which
+// must hold exactly len(b) bytes.
+func EncodeBytes(simType string, b []byte) (string, error) {
+	bits, array, err := intWidth(simType)
+	if err != nil {
+		return "", err
+	}
+	if !array || bits != 8*len(b) {
+		return "", fmt.Errorf("%d bytes are not a value of %s", len(b), simType)
+	}
+	return fmt.Sprintf("0x%x", b), nil
+}
+
+// IsHexString reports whether the quoted Go string literal lit holds hex
+// digits, optionally 0x-prefixed, for a whole number of bytes.
+func IsHexString(lit string) bool {
+	_, err := DecodeHexString(lit)
+	return err == nil
+}
+
+// DecodeHexString decodes the quoted Go string literal lit, such as
+// "0x02ab", as hex to the bytes it spells.
+func DecodeHexString(lit string) ([]byte, error) {
+	s, err := strconv.Unquote(lit)
+	if err != nil {
+		return nil, err
+	}
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	b, err := hex.DecodeString(digits)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("%s is not a hex string", lit)
+	}
+	return b, nil
+}
+
+// intWidth returns the width in bits of an unsigned integer or byte array
+// type, and whether it is an array.
+func intWidth(simType string) (bits int, array bool, err error) {
+	t, err := Parse(simType)
+	if err != nil {
+		return 0, false, err
+	}
+	switch t := t.(type) {
+	case *UInt:
+		return t.Bits, false, nil
+	case *Array:
+		if u, ok := t.Elem.(*UInt); ok && u.Bits == 8 {
+			return 8 * t.Len, true, nil
+		}
+	}
+	return 0, false, fmt.Errorf("%s is not an integer or byte array type", simType)
+}
@@ -0,0 +1,122 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RegisterType maps the Go type goName, as written unqualified in contract
+// source, to simplicityType. The type must parse as SimplicityHL and have a
+// known bit size. Registering a builtin name such as uint64, or a name that
+// is already registered with a different type, is an error; use
+// OverrideType to replace such a mapping deliberately.
+func (tm *TypeMapper) RegisterType(goName, simplicityType string) error {
+	return tm.register(goName, simplicityType, false)
+}
+
+// OverrideType is RegisterType without the conflict check: it replaces a
+// builtin or earlier mapping of goName.
+func (tm *TypeMapper) OverrideType(goName, simplicityType string) error {
+	return tm.register(goName, simplicityType, true)
+}
+
+// RegisterPackageType maps the type name declared in the package with import
+// path pkgPath. Contracts refer to it qualified, as pkg.Name, under whatever
+// local name they import the package with.
+func (tm *TypeMapper) RegisterPackageType(pkgPath, name, simplicityType string) error {
+	if pkgPath == "" {
+		return fmt.Errorf("register %s: empty package path", name)
+	}
+	t, err := checkRegisteredType(simplicityType)
+	if err != nil {
+		return fmt.Errorf("register %s.%s: %w", pkgPath, name, err)
+	}
+	key := pkgPath + "." + name
+	if prev, ok := tm.packageTypes[key]; ok && !prev.Equal(t) {
+		return fmt.Errorf("register %s.%s: already registered as %s", pkgPath, name, prev)
+	}
+	tm.packageTypes[key] = t
+	return nil
+}
+
+// ProvidesPackage reports whether types have been registered for the package
+// with import path pkgPath. Such packages describe types only and are not
+// loaded from disk.
+func (tm *TypeMapper) ProvidesPackage(pkgPath string) bool {
+	prefix := pkgPath + "."
+	for key := range tm.packageTypes {
+		if strings.HasPrefix(key, prefix) && !strings.Contains(key[len(prefix):], ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportPath returns the import path of the package known by the local
+// name in the file tm was scoped to with WithImports.
+func (tm *TypeMapper) ImportPath(name string) (string, bool) {
+	path, ok := tm.imports[name]
+	return path, ok
+}
+
+// WithImports returns a mapper that shares tm's mappings and resolves
+// qualified references through imports, which gives the import path each
+// local package name of the file being mapped stands for. tm itself is not
+// modified, so one configured mapper can serve concurrent compilations.
+func (tm *TypeMapper) WithImports(imports map[string]string) *TypeMapper {
+	scoped := *tm
+	scoped.imports = imports
+	return &scoped
+}
+
+// WithTypeParams returns a mapper that shares tm's mappings and maps the
+// type parameters of a generic function instantiation, keyed by name, to
+// their SimplicityHL type arguments. Like WithImports it leaves tm as is.
+func (tm *TypeMapper) WithTypeParams(params map[string]string) *TypeMapper {
+	scoped := *tm
+	scoped.typeParams = params
+	return &scoped
+}
+
+// WithNamedTypes returns a mapper that shares tm's mappings and maps the
+// named types a file declares over other types, such as type Kind uint8,
+// keyed by name, to the Simplicity types of their underlying types. Like
+// WithImports it leaves tm as is.
+func (tm *TypeMapper) WithNamedTypes(named map[string]Type) *TypeMapper {
+	scoped := *tm
+	scoped.named = named
+	return &scoped
+}
+
+func (tm *TypeMapper) register(goName, simplicityType string, override bool) error {
+	if goName == "" {
+		return fmt.Errorf("register: empty Go type name")
+	}
+	t, err := checkRegisteredType(simplicityType)
+	if err != nil {
+		return fmt.Errorf("register %s: %w", goName, err)
+	}
+	if !override {
+		if builtin, ok := tm.builtinTypes[goName]; ok {
+			return fmt.Errorf("register %s: conflicts with the builtin mapping to %s", goName, builtin)
+		}
+		if prev, ok := tm.registered[goName]; ok && !prev.Equal(t) {
+			return fmt.Errorf("register %s: already registered as %s", goName, prev)
+		}
+	}
+	tm.registered[goName] = t
+	return nil
+}
+
+// checkRegisteredType parses a registered type, which must have a fully
+// known size: named aliases cannot be resolved at registration time.
+func checkRegisteredType(simplicityType string) (Type, error) {
+	t, err := Parse(simplicityType)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := t.BitSize(); !ok {
+		return nil, fmt.Errorf("type %s has no known bit size", simplicityType)
+	}
+	return t, nil
+}
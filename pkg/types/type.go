@@ -0,0 +1,186 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+)
+
+// Type is a SimplicityHL type, as Parse reads it and MapType builds it from
+// Go. String renders it the way the compiler writes it in generated code,
+// so Parse(t.String()) is Equal to t.
+type Type interface {
+	String() string
+	// BitSize returns the number of bits a value of the type occupies,
+	// and false for a type of unknown size, such as Ctx8.
+	BitSize() (int, bool)
+	// Equal reports whether the types are the same. Named types are equal
+	// when their names are: aliases are not resolved.
+	Equal(Type) bool
+}
+
+// Unit is (), the type with one value.
+type Unit struct{}
+
+// Bool is bool.
+type Bool struct{}
+
+// UInt is an unsigned integer of 1, 2, 4, 8, ... or 256 bits.
+type UInt struct {
+	Bits int
+}
+
+// Array is [Elem; Len].
+type Array struct {
+	Elem Type
+	Len  int
+}
+
+// Tuple is a tuple of at least one element; the empty tuple is Unit.
+type Tuple struct {
+	Elems []Type
+}
+
+// Option is Option<Elem>.
+type Option struct {
+	Elem Type
+}
+
+// Either is Either<Left, Right>.
+type Either struct {
+	Left, Right Type
+}
+
+// Named is a type known by name only: a builtin such as Ctx8, an alias, or
+// a Go struct the transpiler declares.
+type Named struct {
+	Name string
+}
+
+// Parse reads a SimplicityHL type such as "(u64, Option<[u8; 32]>)".
+func Parse(s string) (Type, error) {
+	t, err := shlparse.ParseType(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SimplicityHL type %q: %w", s, err)
+	}
+	return fromSHL(t), nil
+}
+
+// fromSHL converts a type of the SimplicityHL parser.
+func fromSHL(t shlparse.Type) Type {
+	switch t := t.(type) {
+	case *shlparse.Bool:
+		return &Bool{}
+	case *shlparse.UInt:
+		return &UInt{Bits: t.Bits}
+	case *shlparse.ArrayType:
+		return &Array{Elem: fromSHL(t.Elem), Len: t.Len}
+	case *shlparse.TupleType:
+		if len(t.Elems) == 0 {
+			return &Unit{}
+		}
+		elems := make([]Type, len(t.Elems))
+		for i, elem := range t.Elems {
+			elems[i] = fromSHL(elem)
+		}
+		return &Tuple{Elems: elems}
+	case *shlparse.Option:
+		return &Option{Elem: fromSHL(t.Elem)}
+	case *shlparse.Either:
+		return &Either{Left: fromSHL(t.Left), Right: fromSHL(t.Right)}
+	case *shlparse.Named:
+		return &Named{Name: t.Name}
+	}
+	return &Named{Name: t.String()}
+}
+
+func (*Unit) String() string   { return "()" }
+func (*Bool) String() string   { return "bool" }
+func (t *UInt) String() string { return fmt.Sprintf("u%d", t.Bits) }
+func (t *Array) String() string {
+	return fmt.Sprintf("[%s; %d]", t.Elem, t.Len)
+}
+func (t *Tuple) String() string {
+	if len(t.Elems) == 1 {
+		return fmt.Sprintf("(%s,)", t.Elems[0])
+	}
+	elems := make([]string, len(t.Elems))
+	for i, elem := range t.Elems {
+		elems[i] = elem.String()
+	}
+	return "(" + strings.Join(elems, ", ") + ")"
+}
+func (t *Option) String() string { return fmt.Sprintf("Option<%s>", t.Elem) }
+func (t *Either) String() string { return fmt.Sprintf("Either<%s, %s>", t.Left, t.Right) }
+func (t *Named) String() string  { return t.Name }
+
+func (*Unit) BitSize() (int, bool)   { return 0, true }
+func (*Bool) BitSize() (int, bool)   { return 1, true }
+func (t *UInt) BitSize() (int, bool) { return t.Bits, true }
+func (t *Array) BitSize() (int, bool) {
+	n, ok := t.Elem.BitSize()
+	return n * t.Len, ok
+}
+func (t *Tuple) BitSize() (int, bool) {
+	total := 0
+	for _, elem := range t.Elems {
+		n, ok := elem.BitSize()
+		if !ok {
+			return 0, false
+		}
+		total += n
+	}
+	return total, true
+}
+func (t *Option) BitSize() (int, bool) {
+	n, ok := t.Elem.BitSize()
+	return 1 + n, ok
+}
+func (t *Either) BitSize() (int, bool) {
+	l, okL := t.Left.BitSize()
+	r, okR := t.Right.BitSize()
+	return 1 + max(l, r), okL && okR
+}
+func (*Named) BitSize() (int, bool) { return 0, false }
+
+func (*Unit) Equal(u Type) bool {
+	_, ok := u.(*Unit)
+	return ok
+}
+func (*Bool) Equal(u Type) bool {
+	_, ok := u.(*Bool)
+	return ok
+}
+func (t *UInt) Equal(u Type) bool {
+	v, ok := u.(*UInt)
+	return ok && v.Bits == t.Bits
+}
+func (t *Array) Equal(u Type) bool {
+	v, ok := u.(*Array)
+	return ok && v.Len == t.Len && t.Elem.Equal(v.Elem)
+}
+func (t *Tuple) Equal(u Type) bool {
+	v, ok := u.(*Tuple)
+	if !ok || len(v.Elems) != len(t.Elems) {
+		return false
+	}
+	for i, elem := range t.Elems {
+		if !elem.Equal(v.Elems[i]) {
+			return false
+		}
+	}
+	return true
+}
+func (t *Option) Equal(u Type) bool {
+	v, ok := u.(*Option)
+	return ok && t.Elem.Equal(v.Elem)
+}
+func (t *Either) Equal(u Type) bool {
+	v, ok := u.(*Either)
+	return ok && t.Left.Equal(v.Left) && t.Right.Equal(v.Right)
+}
+func (t *Named) Equal(u Type) bool {
+	v, ok := u.(*Named)
+	return ok && v.Name == t.Name
+}
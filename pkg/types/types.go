@@ -6,42 +6,69 @@ import (
 	"fmt"
 	"go/ast"
 	"go/token"
+	"sort"
 	"strconv"
-	"strings"
 )
 
+// BitcoinImportPath is the import path of the compiler-provided package of
+// Bitcoin value types.
+const BitcoinImportPath = "github.com/0ceanslim/go-simplicity/std/bitcoin"
+
 // TypeMapper maps Go types to Simplicity types
 type TypeMapper struct {
-	builtinTypes map[string]string
+	builtinTypes map[string]Type
+	registered   map[string]Type   // Unqualified Go name → Simplicity type (RegisterType)
+	packageTypes map[string]Type   // "pkgPath.Name" → Simplicity type (RegisterPackageType)
+	imports      map[string]string // Local package name → import path
+	typeParams   map[string]string // Type parameter → type argument of a generic instantiation
+	named        map[string]Type   // Named type of the file → Simplicity type of its underlying type
 }
 
 // NewTypeMapper creates a new type mapper
 func NewTypeMapper() *TypeMapper {
+	u256 := &UInt{Bits: 256}
 	return &TypeMapper{
-		builtinTypes: map[string]string{
+		builtinTypes: map[string]Type{
 			// Go basic types -> Simplicity types
-			"bool":   "bool",
-			"uint8":  "u8",
-			"uint16": "u16",
-			"uint32": "u32",
-			"uint64": "u64",
-			"byte":   "u8",
+			"bool":   &Bool{},
+			"uint8":  &UInt{Bits: 8},
+			"uint16": &UInt{Bits: 16},
+			"uint32": &UInt{Bits: 32},
+			"uint64": &UInt{Bits: 64},
+			"byte":   &UInt{Bits: 8},
 
 			// Bitcoin-specific types (when imported)
-			"Hash":      "u256",
-			"Address":   "u256",
-			"Pubkey":    "u256",
-			"Signature": "[u8; 64]",
+			"Hash":      u256,
+			"Address":   u256,
+			"Pubkey":    u256,
+			"Signature": signature,
 
 			// Simplicity-specific types
-			"Ctx8": "Ctx8", // SHA-256 context
-			"u256": "u256", // Explicit 256-bit type
+			"Ctx8": &Named{Name: "Ctx8"}, // SHA-256 context
+			"u256": u256,                 // Explicit 256-bit type
 		},
+		registered:   make(map[string]Type),
+		packageTypes: make(map[string]Type),
 	}
 }
 
-// MapGoType converts a Go type to its Simplicity equivalent
+// signature is the type of a BIP-340 signature.
+var signature = &Array{Elem: &UInt{Bits: 8}, Len: 64}
+
+// MapGoType converts a Go type to its Simplicity equivalent, as MapType
+// does, rendered as SimplicityHL.
 func (tm *TypeMapper) MapGoType(goType ast.Expr) (string, error) {
+	t, err := tm.MapType(goType)
+	if err != nil {
+		return "", err
+	}
+	return t.String(), nil
+}
+
+// MapType converts a Go type to its Simplicity equivalent. A name the
+// mapper does not know, such as a struct the transpiler declares, is a
+// Named type.
+func (tm *TypeMapper) MapType(goType ast.Expr) (Type, error) {
 	switch t := goType.(type) {
 	case *ast.Ident:
 		return tm.mapIdentType(t)
@@ -58,50 +85,50 @@ func (tm *TypeMapper) MapGoType(goType ast.Expr) (string, error) {
 		// Handle generic types with multiple params like Either[L, R]
 		return tm.mapMultiGenericType(t)
 	default:
-		return "", fmt.Errorf("unsupported Go type: %T", goType)
+		return nil, fmt.Errorf("unsupported Go type: %T", goType)
 	}
 }
 
 // mapGenericType handles single-parameter generics like Option[T]
-func (tm *TypeMapper) mapGenericType(indexExpr *ast.IndexExpr) (string, error) {
+func (tm *TypeMapper) mapGenericType(indexExpr *ast.IndexExpr) (Type, error) {
 	// Get the base type name
 	baseName := ""
 	if ident, ok := indexExpr.X.(*ast.Ident); ok {
 		baseName = ident.Name
 	} else {
-		return "", fmt.Errorf("unsupported generic base type: %T", indexExpr.X)
+		return nil, fmt.Errorf("unsupported generic base type: %T", indexExpr.X)
 	}
 
 	// Get the type parameter
-	paramType, err := tm.MapGoType(indexExpr.Index)
+	paramType, err := tm.MapType(indexExpr.Index)
 	if err != nil {
-		return "", fmt.Errorf("failed to map generic parameter: %w", err)
+		return nil, fmt.Errorf("failed to map generic parameter: %w", err)
 	}
 
 	switch baseName {
 	case "Option":
-		return fmt.Sprintf("Option<%s>", paramType), nil
+		return &Option{Elem: paramType}, nil
 	default:
-		return "", fmt.Errorf("unsupported generic type: %s", baseName)
+		return nil, fmt.Errorf("unsupported generic type: %s", baseName)
 	}
 }
 
 // mapMultiGenericType handles multi-parameter generics like Either[L, R]
-func (tm *TypeMapper) mapMultiGenericType(indexListExpr *ast.IndexListExpr) (string, error) {
+func (tm *TypeMapper) mapMultiGenericType(indexListExpr *ast.IndexListExpr) (Type, error) {
 	// Get the base type name
 	baseName := ""
 	if ident, ok := indexListExpr.X.(*ast.Ident); ok {
 		baseName = ident.Name
 	} else {
-		return "", fmt.Errorf("unsupported generic base type: %T", indexListExpr.X)
+		return nil, fmt.Errorf("unsupported generic base type: %T", indexListExpr.X)
 	}
 
 	// Get all type parameters
-	var paramTypes []string
+	var paramTypes []Type
 	for _, idx := range indexListExpr.Indices {
-		paramType, err := tm.MapGoType(idx)
+		paramType, err := tm.MapType(idx)
 		if err != nil {
-			return "", fmt.Errorf("failed to map generic parameter: %w", err)
+			return nil, fmt.Errorf("failed to map generic parameter: %w", err)
 		}
 		paramTypes = append(paramTypes, paramType)
 	}
@@ -109,54 +136,66 @@ func (tm *TypeMapper) mapMultiGenericType(indexListExpr *ast.IndexListExpr) (str
 	switch baseName {
 	case "Either":
 		if len(paramTypes) != 2 {
-			return "", fmt.Errorf("Either requires exactly 2 type parameters, got %d", len(paramTypes))
+			return nil, fmt.Errorf("Either requires exactly 2 type parameters, got %d", len(paramTypes))
 		}
-		return fmt.Sprintf("Either<%s, %s>", paramTypes[0], paramTypes[1]), nil
+		return &Either{Left: paramTypes[0], Right: paramTypes[1]}, nil
 	default:
-		return "", fmt.Errorf("unsupported generic type: %s", baseName)
+		return nil, fmt.Errorf("unsupported generic type: %s", baseName)
 	}
 }
 
-func (tm *TypeMapper) mapIdentType(ident *ast.Ident) (string, error) {
+func (tm *TypeMapper) mapIdentType(ident *ast.Ident) (Type, error) {
+	if simplicityType, exists := tm.typeParams[ident.Name]; exists {
+		return Parse(simplicityType)
+	}
+	if simplicityType, exists := tm.registered[ident.Name]; exists {
+		return simplicityType, nil
+	}
+	if simplicityType, exists := tm.named[ident.Name]; exists {
+		return simplicityType, nil
+	}
 	if simplicityType, exists := tm.builtinTypes[ident.Name]; exists {
 		return simplicityType, nil
 	}
+	if ident.Name == "string" {
+		return nil, fmt.Errorf("string is not supported in Simplicity; use a fixed-size byte array such as [32]byte")
+	}
 
 	// For custom types, return as-is (they should be defined elsewhere)
-	return ident.Name, nil
+	return &Named{Name: ident.Name}, nil
 }
 
-func (tm *TypeMapper) mapArrayType(arrayType *ast.ArrayType) (string, error) {
+func (tm *TypeMapper) mapArrayType(arrayType *ast.ArrayType) (Type, error) {
 	// Get element type
-	elemType, err := tm.MapGoType(arrayType.Elt)
+	elemType, err := tm.MapType(arrayType.Elt)
 	if err != nil {
-		return "", fmt.Errorf("failed to map array element type: %w", err)
+		return nil, fmt.Errorf("failed to map array element type: %w", err)
 	}
 
 	// Get array length
 	if arrayType.Len == nil {
-		return "", fmt.Errorf("slices are not supported, use fixed-size arrays")
+		return nil, fmt.Errorf("slices are not supported, use fixed-size arrays")
 	}
 
 	length, err := tm.evaluateArrayLength(arrayType.Len)
 	if err != nil {
-		return "", fmt.Errorf("failed to evaluate array length: %w", err)
+		return nil, fmt.Errorf("failed to evaluate array length: %w", err)
 	}
 
-	return fmt.Sprintf("[%s; %d]", elemType, length), nil
+	return &Array{Elem: elemType, Len: length}, nil
 }
 
-func (tm *TypeMapper) mapStructType(structType *ast.StructType) (string, error) {
+func (tm *TypeMapper) mapStructType(structType *ast.StructType) (Type, error) {
 	// Simplicity doesn't have structs, so we convert to tuples
 	if structType.Fields == nil || len(structType.Fields.List) == 0 {
-		return "()", nil
+		return &Unit{}, nil
 	}
 
-	var fieldTypes []string
+	var fieldTypes []Type
 	for _, field := range structType.Fields.List {
-		fieldType, err := tm.MapGoType(field.Type)
+		fieldType, err := tm.MapType(field.Type)
 		if err != nil {
-			return "", fmt.Errorf("failed to map struct field type: %w", err)
+			return nil, fmt.Errorf("failed to map struct field type: %w", err)
 		}
 
 		// If field has multiple names, add the type for each
@@ -170,40 +209,44 @@ func (tm *TypeMapper) mapStructType(structType *ast.StructType) (string, error)
 		}
 	}
 
-	if len(fieldTypes) == 1 {
-		return fmt.Sprintf("(%s,)", fieldTypes[0]), nil // Single-element tuple
-	}
-
-	return fmt.Sprintf("(%s)", strings.Join(fieldTypes, ", ")), nil
+	return &Tuple{Elems: fieldTypes}, nil
 }
 
-func (tm *TypeMapper) mapSelectorType(sel *ast.SelectorExpr) (string, error) {
+func (tm *TypeMapper) mapSelectorType(sel *ast.SelectorExpr) (Type, error) {
 	// Handle qualified types like bitcoin.Hash
 	if ident, ok := sel.X.(*ast.Ident); ok {
 		qualifiedName := fmt.Sprintf("%s.%s", ident.Name, sel.Sel.Name)
 
-		// Handle bitcoin package types
-		if ident.Name == "bitcoin" {
+		if path, ok := tm.imports[ident.Name]; ok {
+			if simplicityType, ok := tm.packageTypes[path+"."+sel.Sel.Name]; ok {
+				return simplicityType, nil
+			}
+		}
+
+		// Types of the std/bitcoin package, under whatever name it is imported
+		if tm.imports[ident.Name] == BitcoinImportPath {
 			switch sel.Sel.Name {
 			case "Hash":
-				return "u256", nil
+				return &UInt{Bits: 256}, nil
 			case "Address":
-				return "u256", nil
-			case "Pubkey":
-				return "u256", nil
+				return &UInt{Bits: 256}, nil
+			case "Pubkey", "XOnlyPubkey":
+				return &UInt{Bits: 256}, nil
+			case "CompressedPubkey":
+				return &Tuple{Elems: []Type{&UInt{Bits: 8}, &UInt{Bits: 256}}}, nil
 			case "Signature":
-				return "[u8; 64]", nil
+				return signature, nil
 			case "Amount":
-				return "u64", nil
+				return &UInt{Bits: 64}, nil
 			default:
-				return "", fmt.Errorf("unsupported bitcoin type: %s", sel.Sel.Name)
+				return nil, fmt.Errorf("unsupported bitcoin type: %s", sel.Sel.Name)
 			}
 		}
 
-		return "", fmt.Errorf("unsupported qualified type: %s", qualifiedName)
+		return nil, fmt.Errorf("unsupported qualified type: %s", qualifiedName)
 	}
 
-	return "", fmt.Errorf("unsupported selector expression")
+	return nil, fmt.Errorf("unsupported selector expression")
 }
 
 func (tm *TypeMapper) evaluateArrayLength(expr ast.Expr) (int, error) {
@@ -220,52 +263,40 @@ func (tm *TypeMapper) evaluateArrayLength(expr ast.Expr) (int, error) {
 	return 0, fmt.Errorf("unsupported array length expression: %T", expr)
 }
 
-// GetBitSize returns the bit size for a Simplicity type
+// BitSize returns the number of bits a value of a SimplicityHL type
+// occupies: the sum of the sizes of a tuple's elements, N times the element
+// size of an array, one bit more than the payload for an Option and than
+// the larger side for an Either. A string that is not a type, or names one
+// of unknown size such as Ctx8, is an error.
+func (tm *TypeMapper) BitSize(simplicityType string) (int, error) {
+	t, err := Parse(simplicityType)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := t.BitSize()
+	if !ok {
+		return 0, fmt.Errorf("type %s has no known bit size", simplicityType)
+	}
+	return n, nil
+}
+
+// GetBitSize returns the bit size for a Simplicity type, as BitSize does,
+// or 0 for a type BitSize rejects.
 func (tm *TypeMapper) GetBitSize(simplicityType string) int {
-	switch simplicityType {
-	case "bool", "u1":
-		return 1
-	case "u2":
-		return 2
-	case "u4":
-		return 4
-	case "u8":
-		return 8
-	case "u16":
-		return 16
-	case "u32":
-		return 32
-	case "u64":
-		return 64
-	case "u128":
-		return 128
-	case "u256":
-		return 256
-	case "()":
+	n, err := tm.BitSize(simplicityType)
+	if err != nil {
 		return 0
-	default:
-		// For arrays like [u8; 32], we need to parse
-		if strings.HasPrefix(simplicityType, "[") && strings.Contains(simplicityType, ";") {
-			// Extract element type and count
-			parts := strings.Split(simplicityType[1:len(simplicityType)-1], ";")
-			if len(parts) == 2 {
-				elemType := strings.TrimSpace(parts[0])
-				count, err := strconv.Atoi(strings.TrimSpace(parts[1]))
-				if err == nil {
-					return tm.GetBitSize(elemType) * count
-				}
-			}
-		}
-		return 0 // Unknown
 	}
+	return n
 }
 
-// SupportedTypes returns a list of all supported Go types
+// SupportedTypes returns a list of all supported Go types, sorted
 func (tm *TypeMapper) SupportedTypes() []string {
 	var types []string
 	for goType := range tm.builtinTypes {
 		types = append(types, goType)
 	}
+	sort.Strings(types)
 	return types
 }
 
@@ -0,0 +1,264 @@
+package value
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/0ceanslim/go-simplicity/pkg/types"
+)
+
+// Encode writes v, a value of t, in the compact bit encoding of Simplicity
+// values, the form witness data is serialized in: nothing for (), a bit for
+// a bool (1 for true), an integer's bits from the most significant, the
+// elements of a tuple or array one after the other, and for a sum a bit for
+// the side, 0 for Left and None, followed by the value on that side. The
+// bits are packed into bytes from the most significant bit of the first,
+// and the last byte is padded with zeros.
+//
+// The encoding of a sum holds only the side it has, so a value may take
+// fewer bits than types.Type.BitSize, which counts those of the larger
+// side.
+func Encode(t types.Type, v Value) ([]byte, error) {
+	var w bitWriter
+	if err := w.value(t, v); err != nil {
+		return nil, err
+	}
+	return w.bytes(), nil
+}
+
+// Decode reads a value of t encoded by Encode. Data left over after the
+// value, other than the zero padding of its last byte, is an error.
+func Decode(t types.Type, data []byte) (Value, error) {
+	r := &bitReader{data: data}
+	v, err := r.value(t)
+	if err != nil {
+		return nil, err
+	}
+	if r.pos+7 < 8*len(data) {
+		return nil, fmt.Errorf("%d bytes left after a value of %s", len(data)-(r.pos+7)/8, t)
+	}
+	for r.pos < 8*len(data) {
+		if r.bit() {
+			return nil, fmt.Errorf("nonzero padding after a value of %s", t)
+		}
+	}
+	return v, nil
+}
+
+type bitWriter struct {
+	buf  bytes.Buffer
+	cur  byte
+	bits int // Written to cur
+}
+
+func (w *bitWriter) bit(b bool) {
+	w.cur <<= 1
+	if b {
+		w.cur |= 1
+	}
+	if w.bits++; w.bits == 8 {
+		w.buf.WriteByte(w.cur)
+		w.cur, w.bits = 0, 0
+	}
+}
+
+// word writes the low n bits of x, the most significant first.
+func (w *bitWriter) word(x *big.Int, n int) {
+	for i := n - 1; i >= 0; i-- {
+		w.bit(x.Bit(i) == 1)
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	if w.bits > 0 {
+		w.buf.WriteByte(w.cur << (8 - w.bits))
+	}
+	return w.buf.Bytes()
+}
+
+func (w *bitWriter) value(t types.Type, v Value) error {
+	mismatch := func() error {
+		return fmt.Errorf("expected a value of %s, got %T", t, v)
+	}
+	switch t := t.(type) {
+	case *types.Unit:
+		if _, ok := v.(Unit); !ok {
+			return mismatch()
+		}
+	case *types.Bool:
+		b, ok := v.(bool)
+		if !ok {
+			return mismatch()
+		}
+		w.bit(b)
+	case *types.UInt:
+		n, ok := v.(*big.Int)
+		if !ok {
+			return mismatch()
+		}
+		if n.Sign() < 0 || n.BitLen() > t.Bits {
+			return fmt.Errorf("%s does not fit in %s", showInt(n, t.Bits > 64), t)
+		}
+		w.word(n, t.Bits)
+	case *types.Array:
+		if isBytes(t) {
+			switch b := v.(type) {
+			case []byte:
+				if len(b) != t.Len {
+					return fmt.Errorf("expected %d bytes for %s, got %d", t.Len, t, len(b))
+				}
+				w.word(new(big.Int).SetBytes(b), 8*t.Len)
+				return nil
+			case *big.Int:
+				if b.Sign() < 0 || b.BitLen() > 8*t.Len {
+					return fmt.Errorf("%s does not fit in %s", showInt(b, true), t)
+				}
+				w.word(b, 8*t.Len)
+				return nil
+			}
+		}
+		elems, ok := v.(Array)
+		if !ok {
+			return mismatch()
+		}
+		if len(elems) != t.Len {
+			return fmt.Errorf("expected %d elements of %s, got %d", t.Len, t.Elem, len(elems))
+		}
+		for i, elem := range elems {
+			if err := w.value(t.Elem, elem); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+	case *types.Tuple:
+		elems, ok := v.(Tuple)
+		if !ok {
+			return mismatch()
+		}
+		if len(elems) != len(t.Elems) {
+			return fmt.Errorf("expected a tuple of %d elements, got %d", len(t.Elems), len(elems))
+		}
+		for i, elem := range elems {
+			if err := w.value(t.Elems[i], elem); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+	case *types.Option:
+		s, ok := v.(Sum)
+		if !ok {
+			return mismatch()
+		}
+		w.bit(s.Right)
+		if s.Right {
+			return w.value(t.Elem, s.V)
+		}
+		return w.value(&types.Unit{}, s.V)
+	case *types.Either:
+		s, ok := v.(Sum)
+		if !ok {
+			return mismatch()
+		}
+		w.bit(s.Right)
+		if s.Right {
+			return w.value(t.Right, s.V)
+		}
+		return w.value(t.Left, s.V)
+	default:
+		return fmt.Errorf("type %s has no known encoding", t)
+	}
+	return nil
+}
+
+type bitReader struct {
+	data []byte
+	pos  int // In bits
+}
+
+func (r *bitReader) bit() bool {
+	b := r.data[r.pos/8]>>(7-r.pos%8)&1 == 1
+	r.pos++
+	return b
+}
+
+func (r *bitReader) need(n int, t types.Type) error {
+	if r.pos+n > 8*len(r.data) {
+		return fmt.Errorf("data ends inside a value of %s", t)
+	}
+	return nil
+}
+
+func (r *bitReader) word(n int) *big.Int {
+	x := new(big.Int)
+	for i := 0; i < n; i++ {
+		x.Lsh(x, 1)
+		if r.bit() {
+			x.SetBit(x, 0, 1)
+		}
+	}
+	return x
+}
+
+func (r *bitReader) value(t types.Type) (Value, error) {
+	switch t := t.(type) {
+	case *types.Unit:
+		return Unit{}, nil
+	case *types.Bool:
+		if err := r.need(1, t); err != nil {
+			return nil, err
+		}
+		return r.bit(), nil
+	case *types.UInt:
+		if err := r.need(t.Bits, t); err != nil {
+			return nil, err
+		}
+		return r.word(t.Bits), nil
+	case *types.Array:
+		if isBytes(t) {
+			if err := r.need(8*t.Len, t); err != nil {
+				return nil, err
+			}
+			return r.word(8 * t.Len).FillBytes(make([]byte, t.Len)), nil
+		}
+		elems := make(Array, t.Len)
+		for i := range elems {
+			v, err := r.value(t.Elem)
+			if err != nil {
+				return nil, err
+			}
+			elems[i] = v
+		}
+		return elems, nil
+	case *types.Tuple:
+		elems := make(Tuple, len(t.Elems))
+		for i, elem := range t.Elems {
+			v, err := r.value(elem)
+			if err != nil {
+				return nil, err
+			}
+			elems[i] = v
+		}
+		return elems, nil
+	case *types.Option:
+		return r.sum(t, &types.Unit{}, t.Elem)
+	case *types.Either:
+		return r.sum(t, t.Left, t.Right)
+	}
+	return nil, fmt.Errorf("type %s has no known encoding", t)
+}
+
+func (r *bitReader) sum(t, left, right types.Type) (Value, error) {
+	if err := r.need(1, t); err != nil {
+		return nil, err
+	}
+	s := Sum{Right: r.bit()}
+	side := left
+	if s.Right {
+		side = right
+	}
+	v, err := r.value(side)
+	if err != nil {
+		return nil, err
+	}
+	s.V = v
+	return s, nil
+}
@@ -0,0 +1,134 @@
+package value
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/types"
+)
+
+// Format renders v, a value of t, for people reading debug output, reports
+// and errors: integers of up to 64 bits in decimal, wider ones and byte
+// arrays in hex with a 0x prefix and every digit of the width, true and
+// false, Some(x) and None, Left(x) and Right(x), tuples as (a, b) and other
+// arrays as [a, b]. A value that does not match t is rendered as Go would
+// print it. Format output parses back with Parse.
+func Format(t types.Type, v Value) string {
+	var b strings.Builder
+	format(&b, t, v)
+	return b.String()
+}
+
+// Display parses the literal src as a value of the SimplicityHL type typ
+// and renders it with Format. It returns src unchanged if either does not
+// parse, so callers can show whatever they have.
+func Display(typ, src string) string {
+	t, err := types.Parse(typ)
+	if err != nil {
+		return src
+	}
+	v, err := Parse(t, src)
+	if err != nil {
+		return src
+	}
+	return Format(t, v)
+}
+
+func format(b *strings.Builder, t types.Type, v Value) {
+	switch t := t.(type) {
+	case *types.Unit:
+		if _, ok := v.(Unit); ok {
+			b.WriteString("()")
+			return
+		}
+	case *types.Bool:
+		if x, ok := v.(bool); ok {
+			fmt.Fprint(b, x)
+			return
+		}
+	case *types.UInt:
+		if n, ok := v.(*big.Int); ok {
+			if t.Bits <= 64 {
+				b.WriteString(n.String())
+			} else {
+				hexWord(b, n, t.Bits)
+			}
+			return
+		}
+	case *types.Array:
+		if isBytes(t) {
+			switch x := v.(type) {
+			case []byte:
+				fmt.Fprintf(b, "0x%x", x)
+				return
+			case *big.Int:
+				hexWord(b, x, 8*t.Len)
+				return
+			}
+		}
+		if elems, ok := v.(Array); ok {
+			b.WriteByte('[')
+			for i, elem := range elems {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				format(b, t.Elem, elem)
+			}
+			b.WriteByte(']')
+			return
+		}
+	case *types.Tuple:
+		if elems, ok := v.(Tuple); ok && len(elems) == len(t.Elems) {
+			b.WriteByte('(')
+			for i, elem := range elems {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				format(b, t.Elems[i], elem)
+			}
+			if len(elems) == 1 {
+				b.WriteByte(',')
+			}
+			b.WriteByte(')')
+			return
+		}
+	case *types.Option:
+		if s, ok := v.(Sum); ok {
+			if !s.Right {
+				b.WriteString("None")
+				return
+			}
+			b.WriteString("Some(")
+			format(b, t.Elem, s.V)
+			b.WriteByte(')')
+			return
+		}
+	case *types.Either:
+		if s, ok := v.(Sum); ok {
+			side, name := t.Left, "Left("
+			if s.Right {
+				side, name = t.Right, "Right("
+			}
+			b.WriteString(name)
+			format(b, side, s.V)
+			b.WriteByte(')')
+			return
+		}
+	}
+	fmt.Fprint(b, v)
+}
+
+// hexWord writes n in hex with all the digits of an n-bit word.
+func hexWord(b *strings.Builder, n *big.Int, bits int) {
+	fmt.Fprintf(b, "0x%0*x", (bits+3)/4, n)
+}
+
+// showInt renders n, which may not fit the type an error is about, the way
+// Format renders integers of that type.
+func showInt(n *big.Int, hex bool) string {
+	if hex {
+		return fmt.Sprintf("%#x", n)
+	}
+	return n.String()
+}
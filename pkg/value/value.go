@@ -0,0 +1,193 @@
+// Package value holds SimplicityHL values of a known type, as a witness or
+// param module declares them, and encodes them as Simplicity serializes
+// values: the bits of the value, most significant first, packed into bytes.
+package value
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+	"github.com/0ceanslim/go-simplicity/pkg/types"
+)
+
+// Value is a value of a types.Type:
+//
+//   - Unit for ()
+//   - bool for bool
+//   - *big.Int for an unsigned integer
+//   - []byte for a byte array [u8; N], of N bytes
+//   - Tuple for a tuple, an element per element of the type
+//   - Array for any other array
+//   - Sum for an Either, and for an Option: None is Left(()), Some(x)
+//     Right(x)
+//
+// A *big.Int is also accepted for a byte array, as its big-endian number.
+type Value interface{}
+
+// Unit is the value of ().
+type Unit struct{}
+
+// Tuple is the value of a tuple type.
+type Tuple []Value
+
+// Array is the value of an array type other than a byte array.
+type Array []Value
+
+// Sum is the value of an Either or Option.
+type Sum struct {
+	Right bool
+	V     Value
+}
+
+// None is the value of an Option without one.
+var None = Sum{V: Unit{}}
+
+// Some returns the value of an Option holding v.
+func Some(v Value) Sum { return Sum{Right: true, V: v} }
+
+// Parse reads the SimplicityHL literal src, such as a value of the witness
+// module, as a value of t.
+func Parse(t types.Type, src string) (Value, error) {
+	e, err := shlparse.ParseExpr(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q: %w", src, err)
+	}
+	return fromExpr(t, e)
+}
+
+func fromExpr(t types.Type, e shlparse.Expr) (Value, error) {
+	mismatch := func() error {
+		return fmt.Errorf("expected a value of %s, got %s", t, describe(e))
+	}
+	switch t := t.(type) {
+	case *types.Unit:
+		if tuple, ok := e.(*shlparse.Tuple); ok && len(tuple.Elems) == 0 {
+			return Unit{}, nil
+		}
+	case *types.Bool:
+		if b, ok := e.(*shlparse.BoolLit); ok {
+			return b.Value, nil
+		}
+	case *types.UInt:
+		if lit, ok := e.(*shlparse.Literal); ok {
+			return parseInt(lit.Text, t.Bits, t)
+		}
+	case *types.Array:
+		if isBytes(t) {
+			if lit, ok := e.(*shlparse.Literal); ok {
+				n, err := parseInt(lit.Text, 8*t.Len, t)
+				if err != nil {
+					return nil, err
+				}
+				return n.FillBytes(make([]byte, t.Len)), nil
+			}
+		}
+		array, ok := e.(*shlparse.Array)
+		if !ok {
+			break
+		}
+		if len(array.Elems) != t.Len {
+			return nil, fmt.Errorf("expected %d elements of %s, got %d", t.Len, t.Elem, len(array.Elems))
+		}
+		elems := make([]Value, t.Len)
+		for i, elem := range array.Elems {
+			v, err := fromExpr(t.Elem, elem)
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+			elems[i] = v
+		}
+		if isBytes(t) {
+			bytes := make([]byte, t.Len)
+			for i, elem := range elems {
+				bytes[i] = byte(elem.(*big.Int).Uint64())
+			}
+			return bytes, nil
+		}
+		return Array(elems), nil
+	case *types.Tuple:
+		tuple, ok := e.(*shlparse.Tuple)
+		if !ok {
+			break
+		}
+		if len(tuple.Elems) != len(t.Elems) {
+			return nil, fmt.Errorf("expected a tuple of %d elements, got %d", len(t.Elems), len(tuple.Elems))
+		}
+		elems := make(Tuple, len(t.Elems))
+		for i, elem := range tuple.Elems {
+			v, err := fromExpr(t.Elems[i], elem)
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+			elems[i] = v
+		}
+		return elems, nil
+	case *types.Option:
+		if id, ok := e.(*shlparse.Ident); ok && id.Name == "None" {
+			return None, nil
+		}
+		if call, ok := e.(*shlparse.Call); ok && call.Func == "Some" && len(call.Args) == 1 {
+			v, err := fromExpr(t.Elem, call.Args[0])
+			if err != nil {
+				return nil, err
+			}
+			return Some(v), nil
+		}
+	case *types.Either:
+		if call, ok := e.(*shlparse.Call); ok && len(call.Args) == 1 && (call.Func == "Left" || call.Func == "Right") {
+			side := t.Left
+			if call.Func == "Right" {
+				side = t.Right
+			}
+			v, err := fromExpr(side, call.Args[0])
+			if err != nil {
+				return nil, err
+			}
+			return Sum{Right: call.Func == "Right", V: v}, nil
+		}
+	case *types.Named:
+		return nil, fmt.Errorf("type %s has no known values", t)
+	}
+	return nil, mismatch()
+}
+
+// parseInt reads an integer literal of at most bits bits.
+func parseInt(text string, bits int, t types.Type) (*big.Int, error) {
+	n, ok := types.ParseIntLiteral(text)
+	if !ok {
+		return nil, fmt.Errorf("invalid integer literal %s", text)
+	}
+	if n.BitLen() > bits {
+		return nil, fmt.Errorf("%s does not fit in %s", text, t)
+	}
+	return n, nil
+}
+
+// isBytes reports whether t is a byte array.
+func isBytes(t *types.Array) bool {
+	u, ok := t.Elem.(*types.UInt)
+	return ok && u.Bits == 8
+}
+
+// describe names the kind of expression e, for errors.
+func describe(e shlparse.Expr) string {
+	switch e := e.(type) {
+	case *shlparse.Literal:
+		return "the integer " + e.Text
+	case *shlparse.BoolLit:
+		return fmt.Sprint(e.Value)
+	case *shlparse.Tuple:
+		if len(e.Elems) == 0 {
+			return "()"
+		}
+		return fmt.Sprintf("a tuple of %d elements", len(e.Elems))
+	case *shlparse.Array:
+		return fmt.Sprintf("an array of %d elements", len(e.Elems))
+	case *shlparse.Call:
+		return e.Func + "(...)"
+	case *shlparse.Ident:
+		return e.Name
+	}
+	return "an expression"
+}
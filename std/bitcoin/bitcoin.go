@@ -0,0 +1,53 @@
+// Package bitcoin declares the Bitcoin value types used by contracts, so
+// that contract source importing it type-checks with the go tool and in
+// editors. The compiler provides the package itself: its types map to
+// SimplicityHL types, its constants are inlined and its constructors are
+// conversions, so nothing here is compiled into a program.
+package bitcoin
+
+// Hash is a 32-byte digest, such as a sighash or a script hash (u256).
+type Hash [32]byte
+
+// Address is a 32-byte witness program (u256).
+type Address [32]byte
+
+// XOnlyPubkey is a 32-byte x-only public key, the key BIP-340 signatures
+// are verified against (u256).
+type XOnlyPubkey [32]byte
+
+// Pubkey is an XOnlyPubkey.
+type Pubkey = XOnlyPubkey
+
+// CompressedPubkey is a 33-byte SEC compressed public key: a parity byte,
+// 0x02 or 0x03, followed by the x coordinate ((u8, u256)). Some oracle
+// formats publish keys this way.
+type CompressedPubkey [33]byte
+
+// Signature is a 64-byte BIP-340 Schnorr signature ([u8; 64]).
+type Signature [64]byte
+
+// Amount is a quantity of satoshis (u64).
+type Amount uint64
+
+const (
+	// DustLimit is the smallest output amount relayed by default, in
+	// satoshis.
+	DustLimit Amount = 546
+
+	// MaxMoney is the most satoshis that can ever exist: 21 million
+	// bitcoin.
+	MaxMoney Amount = 21_000_000 * 100_000_000
+)
+
+// NewAmount returns sats as an Amount.
+func NewAmount(sats uint64) Amount {
+	return Amount(sats)
+}
+
+// XOnly drops the parity byte of a compressed key, leaving the x-only key
+// that BIP-340 verification takes.
+func XOnly(key CompressedPubkey) XOnlyPubkey {
+	var x XOnlyPubkey
+	copy(x[:], key[1:])
+	return x
+}
@@ -0,0 +1,9 @@
+package jets
+
+// Custom calls the jet called name in SimplicityHL, a jet of the table or
+// one declared with compiler.Config.ExtraJets, with args:
+// jet.Custom("my_jet", x, y) compiles to jet::my_jet(x, y), whose result
+// has the declared return type. The name must be a string literal.
+func Custom(name string, args ...any) any {
+	panic(unavailable)
+}
@@ -0,0 +1,15 @@
+// Package jets holds a Go stub of every jet the compiler knows, generated
+// from the jet table of pkg/jets, for tools that want the jets as Go
+// declarations: their names and the Go types contracts pass them. The
+// compiler lowers calls itself; a stub called outside a compiled program
+// panics.
+package jets
+
+//go:generate go run gen.go
+
+// Ctx8 is a SHA-256 hashing context, jet::sha_256_ctx_8_init's result.
+type Ctx8 struct {
+	_ [0]func() // Opaque
+}
+
+const unavailable = "jets: a jet runs only in a compiled program"
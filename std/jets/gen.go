@@ -0,0 +1,23 @@
+//go:build ignore
+
+// gen.go writes stubs.go from the jet table of pkg/jets. Run it with go
+// generate.
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+
+	"github.com/0ceanslim/go-simplicity/pkg/jets"
+)
+
+func main() {
+	var b bytes.Buffer
+	if err := jets.WriteStubs(&b); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("stubs.go", b.Bytes(), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
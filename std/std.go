@@ -0,0 +1,233 @@
+// Package std provides helpers for contracts that the compiler lowers
+// itself rather than compiling their Go bodies. Where a helper does not
+// depend on the spending transaction, its Go implementation gives the same
+// result, so contracts can be unit tested with the go tool.
+package std
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+
+	"github.com/0ceanslim/go-simplicity/pkg/secp256k1"
+	"github.com/0ceanslim/go-simplicity/std/bitcoin"
+)
+
+// KeyAggCoefficientless returns the BIP-327 (MuSig2) aggregate of the x-only
+// keys a and b, in that order, for a cooperative path that verifies one
+// aggregate signature instead of two. The compiler aggregates the keys at
+// build time, so both must be compile-time constants and the program
+// carries only the aggregate key, with no coefficients or curve arithmetic.
+// It panics if either key is not on the curve.
+func KeyAggCoefficientless(a, b bitcoin.XOnlyPubkey) bitcoin.XOnlyPubkey {
+	agg, err := secp256k1.KeyAggXOnly([][]byte{a[:], b[:]})
+	if err != nil {
+		panic("std.KeyAggCoefficientless: " + err.Error())
+	}
+	return bitcoin.XOnlyPubkey(agg)
+}
+
+// RequireOutput asserts that output i of the spending transaction exists,
+// pays exactly value with an explicit amount and has the script hash
+// scriptHash, the commitment a covenant makes to where funds may go. The
+// compiler expands it into the output introspection jets and their
+// asserts. There is no spending transaction outside a program, so the Go
+// function panics.
+func RequireOutput(i uint32, value uint64, scriptHash [32]byte) {
+	panic("std.RequireOutput needs the spending transaction; it only runs in a compiled program")
+}
+
+// OutputValueAtLeast asserts that output k of the spending transaction
+// exists and pays at least min with an explicit amount, the usual
+// covenant bound of "the input value less the fee". A min written as a
+// difference, such as value-MaxFee, is subtracted with a check that fails
+// the spend instead of wrapping around. There is no spending transaction
+// outside a program, so the Go function panics.
+func OutputValueAtLeast(k uint32, min uint64) {
+	panic("std.OutputValueAtLeast needs the spending transaction; it only runs in a compiled program")
+}
+
+// ExplicitOutputValue returns the value of output k of the spending
+// transaction and asserts that it is explicit. On Elements an output
+// amount may be confidential, which jet.OutputAmount leaves to the
+// program; this helper states that a blinded amount, like a missing
+// output, fails the spend. There is no spending transaction outside a
+// program, so the Go function panics.
+func ExplicitOutputValue(k uint32) uint64 {
+	panic("std.ExplicitOutputValue needs the spending transaction; it only runs in a compiled program")
+}
+
+// ExplicitInputValue is ExplicitOutputValue for input k.
+func ExplicitInputValue(k uint32) uint64 {
+	panic("std.ExplicitInputValue needs the spending transaction; it only runs in a compiled program")
+}
+
+// FeeAtMost asserts that the fee outputs of the spending transaction pay
+// at most max in the asset of the input being spent. The compiler expands
+// it into the current_asset and total_fee jets and a comparison. There is
+// no spending transaction outside a program, so the Go function panics.
+func FeeAtMost(max uint64) {
+	panic("std.FeeAtMost needs the spending transaction; it only runs in a compiled program")
+}
+
+// CheckSequenceAtLeast asserts that the input being spent has a BIP-68
+// relative lock of at least blocks blocks: the transaction version is 2 or
+// more and the input's nSequence has the disable and type flags clear and a
+// block count of at least blocks. The compiler expands it into the version
+// and current_sequence jets and their asserts, and rejects a constant that
+// does not fit the 16-bit count. There is no spending transaction outside
+// a program, so the Go function panics.
+func CheckSequenceAtLeast(blocks uint16) {
+	panic("std.CheckSequenceAtLeast needs the spending transaction; it only runs in a compiled program")
+}
+
+// DivCeil returns a / b rounded up, where Go's a / b rounds down: the
+// choice a fee or share computation makes explicit so that the rounding
+// favours the right party. The compiler lowers it to the divide and modulo
+// jets, or computes it when both operands are constants. Like a / b, it
+// panics when b is zero, and the compiled program fails the spend.
+func DivCeil(a, b uint64) uint64 {
+	q := a / b
+	if a%b != 0 {
+		q++
+	}
+	return q
+}
+
+// IsZero32 reports whether every byte of x is zero, as an unset hash or
+// key is. The compiler compares x, read as one 256-bit integer, with zero
+// through jet::eq_256, where a loop over the bytes would compare them one
+// at a time.
+func IsZero32(x [32]byte) bool {
+	return x == [32]byte{}
+}
+
+// IsZero20 is IsZero32 for a 20-byte value such as a HASH160, compared as
+// a 128-bit and a 32-bit integer.
+func IsZero20(x [20]byte) bool {
+	return x == [20]byte{}
+}
+
+// IsZero64 is IsZero32 for a 64-byte value such as a signature, compared
+// as two 256-bit integers.
+func IsZero64(x [64]byte) bool {
+	return x == [64]byte{}
+}
+
+// Uint16Bytes returns the big-endian encoding of v, the byte order in which
+// SimplicityHL hashes integers.
+func Uint16Bytes(v uint16) [2]byte {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	return b
+}
+
+// Uint32Bytes returns the big-endian encoding of v.
+func Uint32Bytes(v uint32) [4]byte {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	return b
+}
+
+// Uint64Bytes returns the big-endian encoding of v.
+func Uint64Bytes(v uint64) [8]byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	return b
+}
+
+// Concat returns the parts one after another, the message block of a
+// std.TaggedHash. Programs have no byte strings, so the compiler only
+// accepts it as that argument, with each part a whole byte array of 1, 2,
+// 4, 8, 16, 32, 64, 128, 256 or 512 bytes, such as price[:].
+func Concat(parts ...[]byte) []byte {
+	var msg []byte
+	for _, p := range parts {
+		msg = append(msg, p...)
+	}
+	return msg
+}
+
+// TaggedHash returns the BIP-340 tagged hash of msg,
+// SHA256(SHA256(tag) || SHA256(tag) || msg), the digest an oracle signs
+// to attest to a message in its own domain. The tag must be a string
+// literal: the compiler hashes it at build time, and the program adds
+// that hash twice and then each part of msg to a SHA-256 context.
+func TaggedHash(tag string, msg []byte) [32]byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	h.Write(msg)
+	var digest [32]byte
+	copy(digest[:], h.Sum(nil))
+	return digest
+}
+
+// HashFromHexRaw returns the hash whose bytes s spells in hex, in raw
+// order: the order SHA-256 produces a digest in and the jets compare it
+// in. It is the order of a hashlock computed with sha256sum. The compiler
+// decodes the hash at build time, so s must be a string literal or a
+// constant of exactly 64 hex digits, with an optional 0x prefix; the Go
+// function panics on anything else.
+func HashFromHexRaw(s string) bitcoin.Hash {
+	b, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"))
+	if err != nil || len(b) != len(bitcoin.Hash{}) {
+		panic("std.HashFromHexRaw: " + s + " is not 64 hex digits")
+	}
+	return bitcoin.Hash(b)
+}
+
+// HashFromHexDisplay is HashFromHexRaw for s in display order, the order
+// block explorers and bitcoind RPCs show txids and block hashes in: the
+// raw digest byte-reversed. It returns the raw digest, so that a txid
+// copied from an explorer compares equal to the one a program computes.
+func HashFromHexDisplay(s string) bitcoin.Hash {
+	h := HashFromHexRaw(s)
+	for i, j := 0, len(h)-1; i < j; i, j = i+1, j-1 {
+		h[i], h[j] = h[j], h[i]
+	}
+	return h
+}
+
+// Threshold reports whether at least k of conds hold, the check behind a
+// k-of-n multisig with one condition per signer. The compiler expands it
+// into a boolean circuit over the conditions, so k and the number of
+// conditions must be compile-time constants: k at least 1 and at most
+// len(conds), and at most 16 conditions.
+func Threshold(k uint8, conds ...bool) bool {
+	n := 0
+	for _, c := range conds {
+		if c {
+			n++
+		}
+	}
+	return n >= int(k)
+}
+
+// Assert fails the spend unless cond holds. It may be called anywhere in
+// a function body, and the compiler lowers it to an assert! at that point,
+// in order with the bindings around it. The Go function panics when cond
+// is false.
+func Assert(cond bool) {
+	if !cond {
+		panic("std.Assert: condition does not hold")
+	}
+}
+
+// Verify is Assert that also returns cond, for a function whose result is
+// a condition it requires rather than one its caller may reject.
+func Verify(cond bool) bool {
+	Assert(cond)
+	return cond
+}
+
+// Unreachable marks a branch that cannot run, such as the default of a
+// switch whose cases cover every variant, and fails the spend if it ever
+// does: the compiler lowers it to panic!(), the fail combinator. It
+// returns a bool so that a predicate can return it instead of a made-up
+// result; the Go function panics and never returns.
+func Unreachable() bool {
+	panic("std.Unreachable: reached a branch marked unreachable")
+}
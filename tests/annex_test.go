@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+	"github.com/0ceanslim/go-simplicity/pkg/testkeys"
+)
+
+func TestAnnexFreeGolden(t *testing.T) {
+	result := compileGolden(t, "annex_free")
+	if !strings.Contains(result, "match jet::current_annex_hash() {") {
+		t.Errorf("expected a match on the annex hash:\n%s", result)
+	}
+}
+
+func TestAnnexFreeSpendPaths(t *testing.T) {
+	prog, err := shlparse.Parse(compileGolden(t, "annex_free"))
+	if err != nil {
+		t.Fatalf("generated program does not parse: %v", err)
+	}
+	txJSON := string(readExample(t, "annex_free.tx.json"))
+	witness, err := eval.ParseWitnessJSON(readExample(t, "annex_free.witness.json"))
+	if err != nil {
+		t.Fatalf("ParseWitnessJSON: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		edit   func(string) string
+		reject string // "" to accept
+	}{
+		{"owner spends", nil, ""},
+		{"input carries an annex", func(s string) string {
+			return strings.Replace(s, `"value": 25000,`, `"value": 25000, "annex_hash": "0x5df6e0e2761359d30a8275058e299fcc0381534545f55cf43e41983f5d4c9456",`, 1)
+		}, "assertion failed"},
+		{"tapscript leaf", func(s string) string {
+			return strings.Replace(s, `"tapleaf_version": 190`, `"tapleaf_version": 192`, 1)
+		}, "assertion failed"},
+		{"signature for another transaction", func(s string) string {
+			return strings.Replace(s, strings.ToLower(testkeys.Vector1Msg), testkeys.Vector0Msg, 1)
+		}, "bip_0340_verify"},
+	}
+	for _, tt := range tests {
+		data := txJSON
+		if tt.edit != nil {
+			data = tt.edit(data)
+		}
+		tx, err := eval.ParseTx([]byte(data))
+		if err != nil {
+			t.Fatalf("%s: ParseTx: %v", tt.name, err)
+		}
+		err = eval.Run(prog, eval.Options{Witness: witness, Tx: tx})
+		if tt.reject == "" {
+			if err != nil {
+				t.Errorf("%s: expected accept, got %v", tt.name, err)
+			}
+			continue
+		}
+		var rejection *eval.Rejection
+		if !errors.As(err, &rejection) || !strings.Contains(rejection.Reason, tt.reject) {
+			t.Errorf("%s: expected %s to reject, got %v", tt.name, tt.reject, err)
+		}
+	}
+}
+
+func compileAnnex(t *testing.T, config compiler.Config, body string) (string, error) {
+	t.Helper()
+	source := `package main
+
+import "simplicity/jet"
+
+const Expected = 0x5df6e0e2761359d30a8275058e299fcc0381534545f55cf43e41983f5d4c9456
+
+func main() {
+	` + body + `
+}
+`
+	config.Target = "simplicityhl"
+	return compiler.New(config).Compile(source, "contract.go")
+}
+
+func TestOptionJetIf(t *testing.T) {
+	tests := []struct {
+		name, body string
+		want       []string
+	}{
+		{"bound and present", `if annex := jet.AnnexHash(); annex != nil {
+		jet.Verify(jet.Eq256(*annex, Expected))
+	} else {
+		jet.Verify(false)
+	}`, []string{
+			"match jet::current_annex_hash() {",
+			"Some(annex: u256) => {\n            assert!(jet::eq_256(annex, param::EXPECTED));",
+			"None => {\n            assert!(false);",
+		}},
+		{"called in the condition, no else", `if jet.AnnexHash() == nil {
+		jet.Verify(jet.Eq32(jet.NumInputs(), 1))
+	}`, []string{
+			"Some(annex_hash: u256) => {\n            ()",
+			"None => {\n            assert!(jet::eq_32(jet::num_inputs(), 1));",
+		}},
+		{"nil on the left", `if annex := jet.AnnexHash(); nil != annex {
+		jet.Verify(jet.Eq256(*annex, Expected))
+	}`, []string{
+			"Some(annex: u256) => {\n            assert!(jet::eq_256(annex, param::EXPECTED));",
+			"None => {\n            ()",
+		}},
+	}
+	for _, tt := range tests {
+		result, err := compileAnnex(t, compiler.Config{}, tt.body)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(result, want) {
+				t.Errorf("%s: missing %q in\n%s", tt.name, want, result)
+			}
+		}
+		if _, err := shlparse.Parse(result); err != nil {
+			t.Errorf("%s: generated program does not parse: %v", tt.name, err)
+		}
+	}
+}
+
+func TestOptionJetErrors(t *testing.T) {
+	tests := []struct {
+		body, want string
+	}{
+		{"h := jet.AnnexHash()\n\tjet.Verify(h == nil)", "contract.go:8:2: jet.AnnexHash returns Option<u256>, a pointer that is nil when there is no value; test it where it is called"},
+		{"if annex := jet.AnnexHash(); jet.Eq32(jet.NumInputs(), 1) {\n\t}", "the condition must test annex against nil"},
+		{"if annex := jet.AnnexHash(); annex != nil {\n\t} else if jet.Eq32(jet.NumInputs(), 1) {\n\t}", "an if testing jet.AnnexHash against nil cannot continue with else if"},
+	}
+	for _, tt := range tests {
+		_, err := compileAnnex(t, compiler.Config{}, tt.body)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.body, tt.want, err)
+		}
+	}
+}
+
+func TestChainJets(t *testing.T) {
+	introspection := "msg := jet.SigAllHash()\n\tjet.Verify(jet.Eq256(msg, Expected))"
+	if _, err := compileAnnex(t, compiler.Config{}, introspection); err != nil {
+		t.Errorf("elements is the default chain: %v", err)
+	}
+	if _, err := compileAnnex(t, compiler.Config{Chain: "elements"}, introspection); err != nil {
+		t.Errorf("chain elements: %v", err)
+	}
+
+	_, err := compileAnnex(t, compiler.Config{Chain: "bitcoin"}, introspection)
+	want := "contract.go:8:9: jet.SigAllHash (jet::sig_all_hash) reads the transaction environment, which SimplicityHL defines only for chain elements"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected error containing %q, got %v", want, err)
+	}
+
+	// The core jets exist on every chain.
+	if _, err := compileAnnex(t, compiler.Config{Chain: "bitcoin", AllowTrivialMain: true}, "jet.Verify(jet.Le32(1, 2))"); err != nil {
+		t.Errorf("core jets on chain bitcoin: %v", err)
+	}
+
+	_, err = compileAnnex(t, compiler.Config{Chain: "litecoin"}, "jet.Verify(jet.Le32(1, 2))")
+	if err == nil || !strings.Contains(err.Error(), "unsupported chain: litecoin (want elements or bitcoin)") {
+		t.Errorf("expected an unsupported chain error, got %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+)
+
+const assertSource = `package main
+
+import (
+	"simplicity/jet"
+
+	"github.com/0ceanslim/go-simplicity/std"
+)
+
+func Check(a uint32, b uint32) bool {
+	std.Assert(jet.Le32(5, a))
+	c := jet.And32(a, b)
+	std.Assert(jet.Lt32(c, 100))
+	d := jet.Or32(a, b)
+	std.Assert(jet.Le32(d, 60))
+	return std.Verify(jet.Lt32(a, 90))
+}
+
+func main() {
+	var a, b uint32
+	std.Assert(jet.Le32(1, b))
+	x := jet.Xor32(a, b)
+	std.Verify(jet.Lt32(x, 64))
+	ok := std.Verify(Check(a, b))
+	jet.Verify(ok)
+}
+`
+
+func TestStdAssertInterleaved(t *testing.T) {
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(assertSource, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	// The asserts keep their places between the bindings, both in a
+	// helper and in main.
+	for _, want := range []string{
+		"fn std_verify(cond: bool) -> bool {\n    assert!(cond);\n    cond\n}",
+		"fn check(a: u32, b: u32) -> bool {\n" +
+			"    assert!(jet::le_32(5, a));\n" +
+			"    let c = jet::and_32(a, b);\n" +
+			"    assert!(jet::lt_32(c, 100));\n" +
+			"    let d = jet::or_32(a, b);\n" +
+			"    assert!(jet::le_32(d, 60));\n" +
+			"    std_verify(jet::lt_32(a, 90))\n}",
+		"    assert!(jet::le_32(1, b));\n" +
+			"    let x: u32 = jet::xor_32(a, b);\n" +
+			"    assert!(jet::lt_32(x, 64));\n" +
+			"    let ok: bool = std_verify(check(a, b));\n" +
+			"    assert!(ok);\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+
+	prog, err := shlparse.Parse(result)
+	if err != nil {
+		t.Fatalf("generated program does not parse: %v\n%s", err, result)
+	}
+	tests := []struct {
+		name, a, b string
+		reject     bool
+	}{
+		{"all hold", "10", "40", false},
+		{"first assert of main", "10", "0", true},
+		{"assert after a binding in main", "10", "100", true},
+		{"first assert of the helper", "3", "4", true},
+		{"assert after a binding in the helper", "12", "51", true},
+		{"at the bounds", "59", "1", false},
+	}
+	for _, tt := range tests {
+		err := eval.Run(prog, eval.Options{Witness: map[string]string{"A": tt.a, "B": tt.b}})
+		var rejection *eval.Rejection
+		switch {
+		case !tt.reject && err != nil:
+			t.Errorf("%s: expected accept, got %v", tt.name, err)
+		case tt.reject && !errors.As(err, &rejection):
+			t.Errorf("%s: expected reject, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestStdAssertErrors(t *testing.T) {
+	for _, tt := range []struct {
+		body, want string
+	}{
+		{"jet.Verify(std.Verify(jet.Le32(1, a), true))", "std.Verify takes a condition, got 2 arguments"},
+		{"std.Assert()", "std.Assert takes a condition, got 0 arguments"},
+		{"ok := std.Verify()\n\tjet.Verify(ok)", "std.Verify takes a condition, got 0 arguments"},
+		{"jet.Verify(std.Assert(jet.Le32(1, a)))", "std.Assert has no value and can only be called as a statement"},
+	} {
+		source := "package main\n\nimport (\n\t\"simplicity/jet\"\n\n\t\"github.com/0ceanslim/go-simplicity/std\"\n)\n\nfunc main() {\n\tvar a uint32\n\t" + tt.body + "\n}\n"
+		_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "contract.go")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q, got %v", tt.body, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+)
+
+// largeContract generates a machine-written contract of about lines lines:
+// straight-line checks over many witnesses, each with a constant, a helper
+// and a fee derived from the witness, as a generator that unrolls its
+// loops would write them.
+func largeContract(lines int) string {
+	n := lines / 9
+	var b strings.Builder
+	b.WriteString("package main\n\nimport \"simplicity/jet\"\n\nconst (\n")
+	for i := range n {
+		fmt.Fprintf(&b, "\tLimitAmount%d uint64 = %d\n", i, 1000+i)
+	}
+	b.WriteString(")\n")
+	for i := range n {
+		fmt.Fprintf(&b, "\n// WithinLimit%d checks input %d.\nfunc WithinLimit%d(amountValue uint64, feeValue uint64) bool {\n\treturn jet.Le64(amountValue+feeValue, LimitAmount%d)\n}\n", i, i, i, i)
+	}
+	b.WriteString("\nfunc main() {\n")
+	for i := range n {
+		fmt.Fprintf(&b, "\tvar inputAmount%d uint64\n", i)
+		fmt.Fprintf(&b, "\tinputFee%d := inputAmount%d*3 + %d\n", i, i, i)
+		fmt.Fprintf(&b, "\tjet.Verify(WithinLimit%d(inputAmount%d, inputFee%d))\n", i, i, i)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// TestCompileLargeAllocs bounds the allocations of compiling a generated
+// contract per line of source. Counting each witness reference against
+// every witness, recompiling the substitution pattern of each inlined
+// parameter and formatting every line took several hundred per line.
+func TestCompileLargeAllocs(t *testing.T) {
+	source := largeContract(5000)
+	allocs := testing.AllocsPerRun(1, func() {
+		if _, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "large.go"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if perLine := allocs / 5000; perLine > 100 {
+		t.Errorf("%.0f allocations per line of source, want at most 100", perLine)
+	}
+}
+
+// BenchmarkCompileLarge compiles a 5,000-line generated contract.
+func BenchmarkCompileLarge(b *testing.B) {
+	source := largeContract(5000)
+	b.ReportAllocs()
+	for range b.N {
+		if _, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "large.go"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// repeatedFeeContract checks each of n inputs against the same fee
+// formula, a helper called with the same known arguments every time.
+func repeatedFeeContract(n int) string {
+	var b strings.Builder
+	b.WriteString("package main\n\nimport \"simplicity/jet\"\n\n")
+	b.WriteString("func Fee(rate uint64, size uint64) uint64 {\n\tfee := rate * size\n")
+	for range 16 {
+		b.WriteString("\tfee = fee + fee/16\n")
+	}
+	b.WriteString("\tif fee > 0xffffffff {\n\t\tfee = 0xffffffff\n\t}\n\treturn fee + 0x1000\n}\n\n")
+	b.WriteString("func main() {\n\trate := uint64(25)\n\tsize := uint64(0x1f4)\n")
+	for i := range n {
+		fmt.Fprintf(&b, "\tvar inputAmount%d uint64\n", i)
+		fmt.Fprintf(&b, "\tjet.Verify(jet.Le64(Fee(rate, size)*3+0x200, inputAmount%d))\n", i)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// BenchmarkFoldRepeated compiles a contract that folds the same call of a
+// helper 500 times.
+func BenchmarkFoldRepeated(b *testing.B) {
+	source := repeatedFeeContract(500)
+	b.ReportAllocs()
+	for range b.N {
+		if _, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "fees.go"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+)
+
+func TestBitcoinPackage(t *testing.T) {
+	source := `package main
+
+import (
+	"simplicity/jet"
+
+	btc "github.com/0ceanslim/go-simplicity/std/bitcoin"
+)
+
+func aboveDust(amount btc.Amount) bool {
+	return jet.Le64(btc.DustLimit, amount)
+}
+
+func main() {
+	var sig btc.Signature
+	var key btc.Pubkey
+	fee := btc.NewAmount(5000)
+	jet.Verify(jet.Le64(fee, jet.CurrentAmount()))
+	jet.Verify(jet.Le64(jet.CurrentAmount(), btc.MaxMoney))
+	jet.Verify(aboveDust(btc.Amount(jet.CurrentAmount())))
+	jet.BIP340Verify(key, jet.SigAllHash(), sig)
+}
+`
+	c := compiler.New(compiler.Config{Target: "simplicityhl"})
+	result, err := c.Compile(source, "refund.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"const SIG: [u8; 64]",
+		"const KEY: u256",
+		"fn above_dust(amount: u64) -> bool {\n    jet::le_64(546, amount)\n}",
+		"jet::le_64(jet::current_amount(), 2100000000000000)",
+		"jet::le_64(5000, jet::current_amount())",
+		"jet::le_64(546, jet::current_amount())",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q\n%s", want, result)
+		}
+	}
+}
+
+func TestBitcoinPackageErrors(t *testing.T) {
+	tests := []struct {
+		name, source, want string
+	}{
+		{"not imported", "package main\n\nfunc main() {\n\tvar h bitcoin.Hash\n\t_ = h\n}\n", "unsupported qualified type: bitcoin.Hash"},
+		{"unknown type", "package main\n\nimport \"github.com/0ceanslim/go-simplicity/std/bitcoin\"\n\nfunc main() {\n\tvar s bitcoin.Script\n\t_ = s\n}\n", "unsupported bitcoin type"},
+		{"unknown function", "package main\n\nimport (\n\t\"simplicity/jet\"\n\n\t\"github.com/0ceanslim/go-simplicity/std/bitcoin\"\n)\n\nfunc main() {\n\tjet.Verify(jet.Le64(bitcoin.ParseAmount(1), jet.CurrentAmount()))\n}\n", "has no function ParseAmount"},
+		{"overflow", "package main\n\nimport (\n\t\"simplicity/jet\"\n\n\t\"github.com/0ceanslim/go-simplicity/std/bitcoin\"\n)\n\nfunc main() {\n\tjet.Verify(jet.Le64(bitcoin.NewAmount(18446744073709551616), jet.CurrentAmount()))\n}\n", "does not fit in u64"},
+	}
+	for _, tt := range tests {
+		c := compiler.New(compiler.Config{Target: "simplicityhl"})
+		_, err := c.Compile(tt.source, tt.name+".go")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestCompressedPubkey(t *testing.T) {
+	source := `package main
+
+import (
+	"simplicity/jet"
+
+	"github.com/0ceanslim/go-simplicity/std/bitcoin"
+)
+
+func checkOracle(oracleKey bitcoin.CompressedPubkey, sig bitcoin.Signature) {
+	jet.BIP340Verify(bitcoin.XOnly(oracleKey), jet.SigAllHash(), sig)
+}
+
+func main() {
+	var oracleKey bitcoin.CompressedPubkey
+	var sig bitcoin.Signature
+	key := bitcoin.XOnly(oracleKey)
+	jet.BIP340Verify(key, jet.SigAllHash(), sig)
+}
+`
+	c := compiler.New(compiler.Config{Target: "simplicityhl"})
+	result, err := c.Compile(source, "oracle.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"const ORACLE_KEY: (u8, u256)",
+		"fn bitcoin_x_only(key: (u8, u256)) -> u256 {\n    let (_, x): (u8, u256) = key;\n    x\n}",
+		"fn check_oracle(oracle_key: (u8, u256), sig: [u8; 64]) {",
+		"jet::bip_0340_verify((bitcoin_x_only(oracle_key), jet::sig_all_hash()), sig)",
+		"let key: u256 = bitcoin_x_only(witness::ORACLE_KEY);",
+		"jet::bip_0340_verify((key, jet::sig_all_hash()), witness::SIG);",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q\n%s", want, result)
+		}
+	}
+	if n := strings.Count(result, "fn bitcoin_x_only"); n != 1 {
+		t.Errorf("bitcoin_x_only emitted %d times\n%s", n, result)
+	}
+
+	tests := []struct {
+		name, body, want string
+	}{
+		{
+			"compressed key",
+			"var key bitcoin.CompressedPubkey\n\tjet.BIP340Verify(key, jet.SigAllHash(), sig)",
+			"key.go:12:19: jet.BIP340Verify takes a bitcoin.XOnlyPubkey, but key is a bitcoin.CompressedPubkey; drop its parity byte with bitcoin.XOnly(key)",
+		},
+		{
+			"hash as key",
+			"var key bitcoin.Hash\n\tjet.BIP340Verify(key, jet.SigAllHash(), sig)",
+			"jet.BIP340Verify takes a bitcoin.XOnlyPubkey, but key is a bitcoin.Hash",
+		},
+		{
+			"x-only twice",
+			"var key bitcoin.XOnlyPubkey\n\tjet.BIP340Verify(bitcoin.XOnly(key), jet.SigAllHash(), sig)",
+			"bitcoin.XOnly takes a bitcoin.CompressedPubkey, but key is a bitcoin.XOnlyPubkey",
+		},
+	}
+	for _, tt := range tests {
+		source := "package main\n\nimport (\n\t\"simplicity/jet\"\n\n\t\"github.com/0ceanslim/go-simplicity/std/bitcoin\"\n)\n\nfunc main() {\n\tvar sig bitcoin.Signature\n\t" + tt.body + "\n}\n"
+		_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "key.go")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/types"
+)
+
+func TestBitSizeErrors(t *testing.T) {
+	tm := types.NewTypeMapper()
+	for _, typ := range []string{"", "u7", "(u8, bool", "Option<u8", "Either<u8>", "[u8; x]", "Ctx8", "(u8, Ctx8)"} {
+		if n, err := tm.BitSize(typ); err == nil {
+			t.Errorf("BitSize(%q) = %d, want an error", typ, n)
+		}
+		if n := tm.GetBitSize(typ); n != 0 {
+			t.Errorf("GetBitSize(%q) = %d, want 0", typ, n)
+		}
+	}
+}
+
+// typeGen builds a random well-formed type string out of fuzz input, along
+// with the size the Simplicity rules give it.
+type typeGen struct {
+	data []byte
+}
+
+func (g *typeGen) next() int {
+	if len(g.data) == 0 {
+		return 0
+	}
+	b := g.data[0]
+	g.data = g.data[1:]
+	return int(b)
+}
+
+func (g *typeGen) typ(depth int) (string, int) {
+	kind := g.next() % 6
+	if depth >= 4 {
+		kind = 0
+	}
+	switch kind {
+	case 1:
+		n := g.next() % 4
+		elems := make([]string, n)
+		size := 0
+		for i := range elems {
+			elem, bits := g.typ(depth + 1)
+			elems[i] = elem
+			size += bits
+		}
+		return "(" + strings.Join(elems, ", ") + ")", size
+	case 2:
+		elem, bits := g.typ(depth + 1)
+		n := g.next() % 5
+		return fmt.Sprintf("[%s; %d]", elem, n), bits * n
+	case 3:
+		elem, bits := g.typ(depth + 1)
+		return "Option<" + elem + ">", 1 + bits
+	case 4:
+		left, l := g.typ(depth + 1)
+		right, r := g.typ(depth + 1)
+		return "Either<" + left + ", " + right + ">", 1 + max(l, r)
+	case 5:
+		return "bool", 1
+	}
+	bits := 1 << (g.next() % 9) // u1 to u256
+	return fmt.Sprintf("u%d", bits), bits
+}
+
+func FuzzBitSize(f *testing.F) {
+	for _, seed := range []string{"", "\x01\x02\x05\x03", "\x04\x02\x01\x08\x03\x00", "\x01\x03\x02\x04\x00\x05\x01\x00\x03"} {
+		f.Add([]byte(seed))
+	}
+	tm := types.NewTypeMapper()
+	f.Fuzz(func(t *testing.T, data []byte) {
+		typ, want := (&typeGen{data: data}).typ(0)
+		got, err := tm.BitSize(typ)
+		if err != nil {
+			t.Fatalf("BitSize(%q): %v", typ, err)
+		}
+		if got != want {
+			t.Errorf("BitSize(%q) = %d, want %d", typ, got, want)
+		}
+		parsed, err := types.Parse(typ)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", typ, err)
+		}
+		if again, err := types.Parse(parsed.String()); err != nil || !again.Equal(parsed) {
+			t.Errorf("Parse(%q) renders as %q, which reads back as %v (%v)", typ, parsed, again, err)
+		}
+	})
+}
@@ -0,0 +1,206 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/equiv"
+)
+
+const loopBranchSource = `package main
+
+import "simplicity/jet"
+
+func Sum(xs [8]uint64) uint64 {
+	var total uint64
+	for i := 0; i < 8; i++ {
+		if i%2 == 1 {
+			continue
+		}
+		if i == 6 {
+			break
+		}
+		total += xs[i]
+	}
+	return total
+}
+
+func Zero(k [32]byte) bool {
+	zero := true
+	for i := 0; i < 32; i++ {
+		if k[i] != 0 {
+			zero = false
+			break
+		}
+	}
+	return zero
+}
+
+func Steps(x uint8, limit uint8) bool {
+	var total uint8
+	for i := 0; i < 4; i++ {
+		if total >= limit {
+			break
+		}
+		if x == 3 {
+			continue
+		}
+		total += x
+	}
+	return total <= 200
+}
+
+func Found(x uint8, y uint8) bool {
+	found := false
+	for i := 0; i < 3; i++ {
+		if x == y {
+			found = true
+			break
+		}
+	}
+	return found
+}
+
+func main() {
+	var xs [8]uint64
+	var k [32]byte
+	jet.Verify(jet.Le64(10, Sum(xs)))
+	jet.Verify(Zero(k))
+}
+`
+
+func TestLoopBranches(t *testing.T) {
+	out, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(loopBranchSource, "branch.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		// Iterations 0, 2 and 4 remain, and are summed as a tree
+		"let [xs_0, _, xs_2, _, xs_4, _, _, _]: [u64; 8] = xs;",
+		"let (_, total): (bool, u64) = jet::add_64(t_7_2, xs_4);",
+		// The flag form of a zero check
+		"fn zero(k: [u8; 32]) -> bool {\n    std_is_zero_32(k)\n}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in\n%s", want, out)
+		}
+	}
+}
+
+// TestLoopBranchEquivalence runs loops that break and continue on values
+// only known when the program runs against their Go.
+func TestLoopBranchEquivalence(t *testing.T) {
+	for _, entry := range []string{"Steps", "Found"} {
+		report, err := equiv.Check(loopBranchSource, "branch.go", compiler.Config{Entry: entry}, equiv.Options{})
+		if err != nil {
+			t.Fatalf("%s: %v", entry, err)
+		}
+		if report.Cases == 0 || len(report.Mismatches) != 0 {
+			t.Errorf("%s: %d cases, mismatches %v; want all to agree", entry, report.Cases, report.Mismatches)
+		}
+	}
+}
+
+func TestMainLoopBranches(t *testing.T) {
+	src := `package main
+
+type OptionalSig struct {
+	IsSome bool
+	Value  [64]byte
+}
+
+func main() {
+	var sigs [4]OptionalSig
+	var validCount uint32
+	for i := 0; i < 4; i++ {
+		if i == 1 {
+			continue
+		}
+		if i == 3 {
+			break
+		}
+		if sigs[i].IsSome {
+			validCount++
+		}
+	}
+}
+`
+	out, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(src, "main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	taken := func(k int) bool {
+		pattern := make([]string, 4)
+		for i := range pattern {
+			pattern[i] = "_"
+		}
+		pattern[k] = "element"
+		return strings.Contains(out, "let ["+strings.Join(pattern, ", ")+"]")
+	}
+	if !taken(0) || !taken(2) || taken(1) || taken(3) {
+		t.Errorf("expected iterations 0 and 2 alone:\n%s", out)
+	}
+}
+
+func TestLoopBranchErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name, body, want string
+	}{
+		{"two accumulators", `var total uint64
+	var n uint64
+	for i := 0; i < 4; i++ {
+		if xs[i] == 0 {
+			break
+		}
+		total += xs[i]
+		n++
+	}
+	return total + n`, "main.go:12:3: the assignment in iteration i = 0 cannot be lowered: a loop that breaks or continues is lowered when each of its other statements updates n, the accumulator declared just before it"},
+		{"else", `var total uint64
+	for i := 0; i < 4; i++ {
+		if xs[i] != 0 {
+			total += xs[i]
+		} else {
+			break
+		}
+	}
+	return total`, "main.go:11:4: the break in iteration i = 0 cannot be lowered: an if statement in a loop that breaks or continues is lowered without an else"},
+		{"nested loop", `var total uint64
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 2; j++ {
+			continue
+		}
+		if i == 2 {
+			break
+		}
+		total += xs[i]
+	}
+	return total`, "main.go:8:3: the loop in iteration i = 0 cannot be lowered"},
+		{"reached late", `var total uint64
+	for i := 0; i < 4; i++ {
+		if i < 2 {
+			continue
+		}
+		if xs[i] == 0 {
+			break
+		}
+		jet.Verify(jet.Le64(1, xs[i]))
+	}
+	return total`, "main.go:14:3: the expression statement in iteration i = 2 cannot be lowered"},
+	} {
+		src := "package main\n\nimport \"simplicity/jet\"\n\nfunc Sum(xs [4]uint64) uint64 {\n\t" + tt.body + "\n}\n\nfunc main() {\n\tvar xs [4]uint64\n\tjet.Verify(jet.Le64(10, Sum(xs)))\n}\n"
+		_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(src, "main.go")
+		if code, _ := diag.CodeOf(err); code != diag.LoopBranch || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected a loop branch error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	src := "package main\n\ntype OptionalSig struct {\n\tIsSome bool\n\tValue  [64]byte\n}\n\nfunc main() {\n\tvar sigs [4]OptionalSig\n\tvar validCount uint32\n\tfor i := 0; i < 4; i++ {\n\t\tif !sigs[i].IsSome {\n\t\t\tbreak\n\t\t}\n\t\tvalidCount++\n\t}\n}\n"
+	_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(src, "main.go")
+	want := "main.go:13:4: the break in iteration i = 0 cannot be lowered: its condition !sigs[i].IsSome is only known when the program runs"
+	if code, _ := diag.CodeOf(err); code != diag.LoopBranch || !strings.Contains(err.Error(), want) {
+		t.Errorf("main: expected a loop branch error containing %q, got %v", want, err)
+	}
+}
@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/gen"
+)
+
+func TestMatchBuildTags(t *testing.T) {
+	tests := []struct {
+		header string
+		tags   []string
+		want   bool
+	}{
+		{"", nil, true},
+		{"// Package comment, not a constraint.\n", nil, true},
+		{"//go:build ignore\n", nil, false},
+		{"//go:build ignore\n", []string{"ignore"}, true},
+		{"//go:build oracle\n", nil, false},
+		{"//go:build oracle\n", []string{"oracle"}, true},
+		{"//go:build oracle && !testnet\n", []string{"oracle", "testnet"}, false},
+		{"//go:build oracle || testnet\n", []string{"testnet"}, true},
+		{"// +build oracle\n", []string{"oracle"}, true},
+		{"// +build oracle\n", nil, false},
+		// GOOS, GOARCH and release tags never decide.
+		{"//go:build linux\n", nil, true},
+		{"//go:build !linux && !darwin\n", nil, true},
+		{"//go:build windows && arm64\n", nil, true},
+		{"//go:build go1.99\n", nil, true},
+		{"//go:build linux && oracle\n", nil, false},
+		{"//go:build linux && oracle\n", []string{"oracle"}, true},
+		{"//go:build linux && !linux\n", nil, false},
+	}
+	for _, tt := range tests {
+		src := tt.header + "\npackage main\n\nfunc main() {}\n"
+		got, err := compiler.MatchBuildTags([]byte(src), tt.tags)
+		if err != nil {
+			t.Errorf("%q with %v: %v", tt.header, tt.tags, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q with %v = %v, want %v", tt.header, tt.tags, got, tt.want)
+		}
+	}
+
+	if _, err := compiler.MatchBuildTags([]byte("//go:build (oracle\n\npackage main\n"), nil); err == nil {
+		t.Error("expected an error for a malformed constraint")
+	}
+}
+
+func TestSingleFileBuildConstraintWarning(t *testing.T) {
+	const body = "\npackage main\n\nimport \"simplicity/jet\"\n\nfunc main() {\n\tjet.Verify(jet.Le64(1, 2))\n}\n"
+	tests := []struct {
+		header string
+		tags   []string
+		warn   string // "" for no warning
+	}{
+		{"", nil, ""},
+		{"//go:build ignore\n", nil, ""},
+		{"//go:build linux\n", nil, ""},
+		{"//go:build oracle\n", []string{"oracle"}, ""},
+		{"//go:build oracle\n", nil, "oracle.go: //go:build oracle excludes this file with no tags; compiling it anyway [SIM0305]"},
+		{"//go:build !testnet\n", []string{"testnet", "x"}, "oracle.go: //go:build !testnet excludes this file with tags testnet,x; compiling it anyway [SIM0305]"},
+	}
+	for _, tt := range tests {
+		c := compiler.New(compiler.Config{Target: "simplicityhl", BuildTags: tt.tags, AllowTrivialMain: true})
+		out, err := c.Compile(tt.header+body, "oracle.go")
+		if err != nil {
+			t.Fatalf("%q: compilation failed: %v", tt.header, err)
+		}
+		if !strings.Contains(out, "fn main()") {
+			t.Errorf("%q: missing main in\n%s", tt.header, out)
+		}
+		warnings := c.Warnings()
+		switch {
+		case tt.warn == "" && len(warnings) > 0:
+			t.Errorf("%q: unexpected warnings %v", tt.header, warnings)
+		case tt.warn != "" && (len(warnings) != 1 || warnings[0] != tt.warn):
+			t.Errorf("%q: warnings %v, want %q", tt.header, warnings, tt.warn)
+		}
+	}
+}
+
+func TestDiscoverBuildTags(t *testing.T) {
+	dir := t.TempDir()
+	for name, header := range map[string]string{
+		"plain.go":   "",
+		"ignored.go": "//go:build ignore\n",
+		"oracle.go":  "//go:build oracle\n",
+		"host.go":    "//go:build windows && 386\n",
+	} {
+		src := header + "\n//simplicity:contract\npackage contracts\n\nfunc main() {}\n"
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		tags []string
+		want string
+	}{
+		{nil, "host,plain"},
+		{[]string{"oracle"}, "host,oracle,plain"},
+		{[]string{"oracle", "ignore"}, "host,ignored,oracle,plain"},
+	}
+	for _, tt := range tests {
+		contracts, err := gen.Discover(dir, tt.tags)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.tags, err)
+		}
+		var names []string
+		for _, c := range contracts {
+			names = append(names, c.Name)
+		}
+		if got := strings.Join(names, ","); got != tt.want {
+			t.Errorf("tags %v: discovered %s, want %s", tt.tags, got, tt.want)
+		}
+	}
+}
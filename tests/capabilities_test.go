@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+)
+
+const capabilitySource = `package main
+
+import "simplicity/jet"
+
+func main() {
+	var amount uint64
+	jet.Verify(jet.Le64(1000, amount))
+}
+`
+
+func TestCapabilities(t *testing.T) {
+	elements := compiler.Capabilities(compiler.Config{Target: "simplicityhl"})
+	if !elements.Supported || elements.Chain != "elements" || elements.MaxWordBits != 256 || !elements.Witnesses || elements.CMR {
+		t.Errorf("unexpected elements capability: %+v", elements)
+	}
+	bitcoin := compiler.Capabilities(compiler.Config{Target: "simplicityhl", Chain: "bitcoin"})
+	for _, jet := range []string{"sha_256_ctx_8_init", "bip_0340_verify", "le_64"} {
+		if !elements.HasJet(jet) || !bitcoin.HasJet(jet) {
+			t.Errorf("expected core jet %s on both chains", jet)
+		}
+	}
+	for _, jet := range []string{"issuance", "current_index", "sig_all_hash"} {
+		if !elements.HasJet(jet) || bitcoin.HasJet(jet) {
+			t.Errorf("expected %s on elements alone", jet)
+		}
+	}
+	if library := compiler.Capabilities(compiler.Config{Target: "simplicityhl", Mode: "library"}); library.Witnesses {
+		t.Error("library mode should have no witness module")
+	}
+
+	// Compile fails with the error of the capability, for each way a
+	// configuration can be unsupported.
+	for _, config := range []compiler.Config{
+		{Target: "simplicity"},
+		{Target: "wasm"},
+		{Target: "simplicityhl", Chain: "litecoin"},
+	} {
+		caps := compiler.Capabilities(config)
+		if caps.Supported || caps.Err() == nil {
+			t.Errorf("%s on %s: expected no support, got %+v", config.Target, caps.Chain, caps)
+			continue
+		}
+		_, err := compiler.New(config).Compile(capabilitySource, "main.go")
+		if code, _ := diag.CodeOf(err); err == nil || err.Error() != caps.Err().Error() || code != diag.InvalidConfig {
+			t.Errorf("%s on %s: Compile = %v, want %v", config.Target, caps.Chain, err, caps.Err())
+		}
+	}
+
+	source := strings.Replace(capabilitySource, "func main() {", "func main() {\n\tjet.Verify(jet.Eq32(jet.CurrentIndex(), 0))", 1)
+	_, err := compiler.New(compiler.Config{Target: "simplicityhl", Chain: "bitcoin"}).Compile(source, "main.go")
+	if err == nil || !strings.Contains(err.Error(), "jet::current_index") {
+		t.Errorf("expected current_index to be rejected on bitcoin, got %v", err)
+	}
+
+	matrix := compiler.CapabilityMatrix()
+	if len(matrix) != 4 || matrix[0].Target != "simplicityhl" || matrix[3].Supported {
+		t.Errorf("unexpected matrix: %+v", matrix)
+	}
+}
@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+)
+
+const commentedSource = `package main
+
+import "simplicity/jet"
+
+// OwnerKey is the key that may spend alone.
+//
+// It is Alice's.
+const OwnerKey = 0x79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798
+
+const (
+	// MinHeight is when the coins unlock.
+	MinHeight uint32 = 800000
+	MaxFee    uint64 = 500 // Not a doc comment
+)
+
+/* Covers reports whether fee is within the limit. */
+func Covers(fee uint64) bool {
+	return jet.Le64(fee, MaxFee)
+}
+
+// main checks the owner's signature after the lock.
+func main() {
+	// sig is the owner's signature of the spending transaction.
+	var sig [64]byte
+	var fee uint64 // The fee the spender claims
+	jet.CheckLockHeight(MinHeight)
+	jet.Verify(Covers(fee))
+	jet.BIP340Verify(OwnerKey, jet.SigAllHash(), sig)
+}
+`
+
+func TestDocComments(t *testing.T) {
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl", SelfCheck: true}).Compile(commentedSource, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"mod witness {\n    // sig is the owner's signature of the spending transaction.\n    const SIG: [u8; 64] = ",
+		"mod param {\n    // OwnerKey is the key that may spend alone.\n    //\n    // It is Alice's.\n    const OWNER_KEY: u256 = ",
+		"    // MinHeight is when the coins unlock.\n    const MIN_HEIGHT: u32 = 800000;\n    const MAX_FEE: u64 = 500;\n",
+		"// Covers reports whether fee is within the limit.\nfn covers(fee: u64) -> bool {",
+		"// main checks the owner's signature after the lock.\nfn main() {",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+	// Trailing comments are dropped.
+	for _, unwanted := range []string{"Not a doc comment", "The fee the spender claims"} {
+		if strings.Contains(result, unwanted) {
+			t.Errorf("unexpected %q in\n%s", unwanted, result)
+		}
+	}
+	if _, err := shlparse.Parse(result); err != nil {
+		t.Errorf("generated program does not parse: %v", err)
+	}
+
+	bare, err := compiler.New(compiler.Config{Target: "simplicityhl", NoComments: true}).Compile(commentedSource, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	if strings.Contains(bare, "//") {
+		t.Errorf("NoComments output has comments:\n%s", bare)
+	}
+	if stripped := stripComments(result); stripped != bare {
+		t.Errorf("comments changed the code:\n%s\nwant:\n%s", stripped, bare)
+	}
+}
+
+// stripComments removes the lines of program that are // comments.
+func stripComments(program string) string {
+	var lines []string
+	for _, line := range strings.Split(program, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "//") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
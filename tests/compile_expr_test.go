@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+)
+
+func TestCompileExpr(t *testing.T) {
+	tests := []struct {
+		expr   string
+		vars   map[string]string
+		want   string
+		params string // name:type of each param, guessed ones marked ?
+		result string
+	}{
+		{"jet.Le64(1000, amount)", nil, "jet::le_64(1000, amount)", "amount:uint64?", "bool"},
+		{"amount >= 1000", map[string]string{"amount": "uint64"}, "jet::le_64(1000, amount)", "amount:uint64", "bool"},
+		{"height < timeout", map[string]string{"timeout": "uint16"}, "jet::lt_16(height, timeout)", "height:uint16? timeout:uint16", "bool"},
+		{"x + y", nil, "{ let (_, sum): (bool, u32) = jet::add_32(x, y); sum }", "x:uint32? y:uint32?", "uint32"},
+		{"jet.Eq256(jet.SigAllHash(), h)", nil, "jet::eq_256(jet::sig_all_hash(), h)", "h:[32]byte?", "bool"},
+		{"(2 + 3) * 4 > 10", nil, "true", "", "bool"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := compiler.CompileExpr(compiler.Config{Target: "simplicityhl"}, tt.expr, tt.vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.Code != tt.want {
+				t.Errorf("code %q, want %q", e.Code, tt.want)
+			}
+			var params []string
+			for _, p := range e.Params {
+				param := p.Name + ":" + p.Type
+				if p.Guessed {
+					param += "?"
+				}
+				params = append(params, param)
+			}
+			if got := strings.Join(params, " "); got != tt.params {
+				t.Errorf("params %q, want %q", got, tt.params)
+			}
+			if e.Result != tt.result {
+				t.Errorf("result %s, want %s", e.Result, tt.result)
+			}
+			if len(e.Warnings) > 0 {
+				t.Errorf("warnings %v", e.Warnings)
+			}
+		})
+	}
+}
+
+func TestCompileExprErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		vars map[string]string
+		want string
+		code diag.Code
+	}{
+		{"amount >= ", nil, "expr:1:11: expected operand", diag.SyntaxError},
+		{"a > 1 && chek(a)", nil, "expr:1:10: call of undefined function chek", diag.UndefinedName},
+		{"a / 0 > 1", nil, "expr:1:5: 0 divides by zero", diag.DivisionByZero},
+		{"a >\n\tb / 0", nil, "expr:2:6: 0 divides by zero", diag.DivisionByZero},
+		{"s == 1", map[string]string{"s": "string"}, "expr: s has type string", diag.String},
+		{"a", map[string]string{"a": "uint64)"}, `type "uint64)" of a is not a Go type`, diag.InvalidConfig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := compiler.CompileExpr(compiler.Config{Target: "simplicityhl"}, tt.expr, tt.vars)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected %q, got %v", tt.want, err)
+			}
+			if codes := diag.Find(err.Error()); len(codes) == 0 || codes[0] != tt.code {
+				t.Errorf("codes %v, want %s: %v", codes, tt.code, err)
+			}
+		})
+	}
+}
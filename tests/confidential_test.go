@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+)
+
+const confidentialSource = `package main
+
+import (
+	"simplicity/jet"
+
+	"github.com/0ceanslim/go-simplicity/std"
+)
+
+func main() {
+	` + "BODY" + `
+}
+`
+
+func TestConfidentialAmountWarnings(t *testing.T) {
+	tests := []struct {
+		name, body string
+		warn       []string // A substring of each warning, in order
+	}{
+		{"compared output amount", "jet.Verify(jet.Le64(1000, jet.OutputAmount(0)))",
+			[]string{"contract.go:10:28: jet.OutputAmount(0) is compared at contract.go:10:13, but the output amount may be confidential, which fails the spend as a missing value would; use std.ExplicitOutputValue(0), which asserts explicitness"}},
+		{"compared input amount", "v := jet.InputAmount(1)\n\tjet.Verify(v >= 1000)",
+			[]string{"jet.InputAmount(1) is compared at contract.go:11:15, but the input amount may be confidential, which fails the spend as a missing value would; use std.ExplicitInputValue(1)"}},
+		{"through arithmetic", "a := jet.OutputAmount(0)\n\tb := a * 2\n\tvar c uint64 = b\n\tjet.Verify(jet.Eq64(c, 10))",
+			[]string{"jet.OutputAmount(0) is compared at contract.go:13:13"}},
+		{"compound assignment", "total := jet.CurrentAmount()\n\ttotal += jet.OutputAmount(1)\n\tjet.Verify(jet.Lt64(total, 10))",
+			[]string{"jet.OutputAmount(1) is compared"}},
+		{"std bound", "std.OutputValueAtLeast(0, jet.InputAmount(0))",
+			[]string{"jet.InputAmount(0) is compared at contract.go:10:2"}},
+		{"each call", "jet.Verify(jet.Eq64(jet.OutputAmount(0), jet.OutputAmount(1)))",
+			[]string{"jet.OutputAmount(0)", "jet.OutputAmount(1)"}},
+		{"explicit helpers", "jet.Verify(jet.Le64(std.ExplicitInputValue(0), std.ExplicitOutputValue(0)))", nil},
+		{"reassigned", "v := jet.OutputAmount(0)\n\tv = 5\n\tjet.Verify(jet.Eq64(v, 5))", nil},
+		{"not compared", "a := jet.OutputAmount(0)\n\tb := a + 1\n\t_ = b\n\tjet.Verify(jet.Eq64(1, 1))", nil},
+	}
+	for _, tt := range tests {
+		c := compiler.New(compiler.Config{Target: "simplicityhl"})
+		if _, err := c.Compile(strings.Replace(confidentialSource, "BODY", tt.body, 1), "contract.go"); err != nil {
+			t.Errorf("%s: compile: %v", tt.name, err)
+			continue
+		}
+		warnings := c.Warnings()
+		if len(warnings) != len(tt.warn) {
+			t.Errorf("%s: warnings %v, want %d", tt.name, warnings, len(tt.warn))
+			continue
+		}
+		for i, want := range tt.warn {
+			if !strings.Contains(warnings[i], want) {
+				t.Errorf("%s: warning %q lacks %q", tt.name, warnings[i], want)
+			}
+		}
+	}
+}
+
+func TestExplicitValueHelpers(t *testing.T) {
+	body := "out := std.ExplicitOutputValue(0)\n\tjet.Verify(jet.Le64(std.ExplicitInputValue(0), out))"
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(strings.Replace(confidentialSource, "BODY", body, 1), "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"fn std_explicit_output_value(index: u32) -> u64 {\n    let (_, c_amount): (Asset1, Amount1) = unwrap(jet::output_amount(index));\n    unwrap_right::<(u1, u256)>(c_amount)\n}",
+		"fn std_explicit_input_value(index: u32) -> u64 {\n    let (_, c_amount): (Asset1, Amount1) = unwrap(jet::input_amount(index));",
+		"let out: u64 = std_explicit_output_value(0);",
+		"assert!(jet::le_64(std_explicit_input_value(0), out));",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+
+	prog, err := shlparse.Parse(result)
+	if err != nil {
+		t.Fatalf("generated program does not parse: %v", err)
+	}
+	tests := []struct {
+		name, edit, reject string // "" to accept
+	}{
+		{"explicit", "", ""},
+		{"output short of the input", `"value": 900, "script_hash"`, "assertion failed"},
+		{"confidential output", `"value": 1000, "confidential": true, "script_hash"`, "unwrap_right"},
+	}
+	for _, tt := range tests {
+		data := `{
+  "chain": "liquid",
+  "inputs": [{"prev_txid": "0x` + strings.Repeat("11", 32) + `", "prev_vout": 0, "value": 1000, "script_hash": "0x` + strings.Repeat("22", 32) + `"}],
+  "outputs": [{"value": 1000, "script_hash": "0x` + strings.Repeat("33", 32) + `"}]
+}`
+		if tt.edit != "" {
+			data = strings.Replace(data, `"value": 1000, "script_hash": "0x33`, strings.Replace(tt.edit, `"script_hash"`, `"script_hash": "0x33`, 1), 1)
+		}
+		tx, err := eval.ParseTx([]byte(data))
+		if err != nil {
+			t.Fatalf("%s: ParseTx: %v", tt.name, err)
+		}
+		err = eval.Run(prog, eval.Options{Tx: tx})
+		if tt.reject == "" {
+			if err != nil {
+				t.Errorf("%s: expected accept, got %v", tt.name, err)
+			}
+			continue
+		}
+		var rejection *eval.Rejection
+		if !errors.As(err, &rejection) || !strings.Contains(rejection.Reason, tt.reject) {
+			t.Errorf("%s: expected %s to reject, got %v", tt.name, tt.reject, err)
+		}
+	}
+
+	_, err = compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(strings.Replace(confidentialSource, "BODY", "jet.Verify(jet.Le64(std.ExplicitOutputValue(), 1))", 1), "contract.go")
+	if err == nil || !strings.Contains(err.Error(), "std.ExplicitOutputValue takes an index, got 0 arguments") {
+		t.Errorf("expected an argument count error, got %v", err)
+	}
+}
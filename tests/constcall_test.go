@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+)
+
+const constCallSource = `package main
+
+import "simplicity/jet"
+
+// MinPayment is the smallest payment accepted on network.
+func MinPayment(network uint8, base uint64) uint64 {
+	if network == 1 {
+		return base * 10
+	}
+	if network == 2 {
+		return base * 2
+	}
+	return base
+}
+
+func main() {
+	var paid uint64
+	jet.Verify(jet.Le64(MinPayment(1, 100), paid))
+}
+`
+
+func TestConstantCall(t *testing.T) {
+	tests := []struct {
+		config compiler.Config
+		has    []string
+		hasNot []string
+	}{
+		// The branch network == 1 takes is the one left of MinPayment.
+		{compiler.Config{}, []string{"assert!(jet::le_64(1000, witness::PAID));"}, nil},
+		{compiler.Config{NoInline: true}, []string{"assert!(jet::le_64(1000, witness::PAID));"}, nil},
+		// No call is left, so DCE drops the helper.
+		{compiler.Config{OptLevel: compiler.O1}, []string{"assert!(jet::le_64(1000, witness::PAID));"}, []string{"fn min_payment("}},
+		{compiler.Config{OptLevel: compiler.O2}, []string{"assert!(jet::le_64(1000, witness::PAID));"}, []string{"fn min_payment("}},
+		// -O0 calls the helper as the Go does.
+		{compiler.Config{OptLevel: compiler.O0}, []string{"fn min_payment(", "assert!(jet::le_64(min_payment(1, 100), witness::PAID));"}, nil},
+		{compiler.Config{OptLevel: compiler.O1, NoCallFolding: true}, []string{"assert!(jet::le_64(min_payment(1, 100), witness::PAID));"}, nil},
+	}
+	for _, tt := range tests {
+		tt.config.Target = "simplicityhl"
+		result, err := compiler.New(tt.config).Compile(constCallSource, "main.go")
+		if err != nil {
+			t.Fatalf("%+v: %v", tt.config, err)
+		}
+		for _, s := range tt.has {
+			if !strings.Contains(result, s) {
+				t.Errorf("%+v: expected %q in\n%s", tt.config, s, result)
+			}
+		}
+		for _, s := range tt.hasNot {
+			if strings.Contains(result, s) {
+				t.Errorf("%+v: unexpected %q in\n%s", tt.config, s, result)
+			}
+		}
+	}
+}
+
+func TestConstantCallRuntimeArgument(t *testing.T) {
+	// network is a witness, so the call is kept even though base is known.
+	src := strings.Replace(constCallSource, "MinPayment(1, 100)", "MinPayment(network, 100)", 1)
+	src = strings.Replace(src, "var paid uint64", "var paid uint64\n\tvar network uint8", 1)
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl", NoInline: true}).Compile(src, "main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "assert!(jet::le_64(min_payment(witness::NETWORK, 100), witness::PAID));"; !strings.Contains(result, want) {
+		t.Errorf("expected %q in\n%s", want, result)
+	}
+}
@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+)
+
+// TestConstantPropagationBasicSwap checks that the fee chain of
+// basic_swap.go recomputes the fee from the witnesses it derives from.
+func TestConstantPropagationBasicSwap(t *testing.T) {
+	out := compileExample(t, filepath.Join("..", "examples", "basic_swap.go"))
+	for _, want := range []string{
+		// amount is a witness, so the comparison stays a runtime check.
+		"let amount_valid: bool = jet::lt_64(0, amount);",
+		"    let (_, t_32_27): (u64, u64) = <u128>::into(jet::multiply_64(amount, witness::RATE));\n" +
+			"    let calculated_fee: u64 = jet::divide_64(t_32_27, 10000);\n" +
+			"    let fee_valid: bool = jet::le_64(witness::MIN_FEE, calculated_fee);\n",
+		// result is computed from them too, the guard on amount_valid
+		// the match of BasicSwap, and is what main asserts.
+		"    let result: bool = match match amount_valid { true => false, false => true, } {\n",
+		"    };\n    assert!(result);\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "CALCULATED_FEE") {
+		t.Errorf("calculated_fee is derived from witnesses and should not be one:\n%s", out)
+	}
+}
+
+func TestConstantPropagationLocals(t *testing.T) {
+	source := `
+package main
+
+import "simplicity/jet"
+
+func main() {
+	fee := jet.TxLockHeight()
+	minFee := uint32(100)
+	ok := fee >= minFee
+	jet.Verify(ok)
+
+	limit := uint32(10)
+	limit += 5
+	jet.Verify(jet.Le32(fee, limit))
+}
+`
+	out, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "fee.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"let ok: bool = jet::le_32(100, fee);",
+		"assert!(jet::le_32(fee, 15));",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "MIN_FEE") || strings.Contains(out, "LIMIT") {
+		t.Errorf("integer constants should be inlined, not emitted as witnesses:\n%s", out)
+	}
+
+	// Reassigned under a condition, so its value is the match on it.
+	source = `
+package main
+
+import "simplicity/jet"
+
+func main() {
+	fee := jet.TxLockHeight()
+	step := uint32(1)
+	if jet.Le32(fee, 7) {
+		step = 2
+	}
+	jet.Verify(jet.Le32(step, fee))
+}
+`
+	out, err = compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "fee.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	want := "    let t_9_5: bool = jet::le_32(fee, 7);\n    let step: u32 = match t_9_5 { true => 2, false => 1, };\n    assert!(jet::le_32(step, fee));\n"
+	if !strings.Contains(out, want) {
+		t.Errorf("missing %q in\n%s", want, out)
+	}
+}
+
+func TestConstantPropagationHelpers(t *testing.T) {
+	source := `
+package main
+
+func countValid(a bool, b bool, c bool) uint32 {
+	n := uint32(0)
+	if a {
+		n++
+	}
+	if b {
+		n = n + 1
+	}
+	if c {
+		n += 1
+	}
+	return n
+}
+
+func main() {
+	signed := countValid(true, false, true) >= 2
+	unsigned := countValid(false, false, true) >= 2
+}
+`
+	out, err := compiler.New(compiler.Config{Target: "simplicityhl", AllowTrivialMain: true}).Compile(source, "count.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{"const SIGNED: bool = true;", "const UNSIGNED: bool = false;"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+}
+
+// TestConstantPropagationMemo checks that fee*3+1, folded once, is folded
+// again for each binding of fee: in a helper, shadowed in main and after
+// an assignment.
+func TestConstantPropagationMemo(t *testing.T) {
+	source := `
+package main
+
+import "simplicity/jet"
+
+func Check(amount uint64) bool {
+	fee := uint64(100)
+	return jet.Le64(amount, fee*3+1)
+}
+
+func Double(x uint64) uint64 { return x * 2 }
+
+func main() {
+	var amount uint64
+	jet.Verify(Check(amount))
+	fee := uint64(7)
+	jet.Verify(jet.Le64(amount, fee*3+1))
+	fee = 200
+	jet.Verify(jet.Le64(amount, fee*3+1))
+	jet.Verify(jet.Le64(amount, Double(fee*3+1)))
+}
+`
+	out, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "fee.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	want := "    assert!(check(amount));\n" +
+		"    assert!(jet::le_64(amount, 22));\n" +
+		"    assert!(jet::le_64(amount, 601));\n" +
+		"    assert!(jet::le_64(amount, 1202));\n"
+	if !strings.Contains(out, "    jet::le_64(amount, 301)\n") || !strings.Contains(out, want) {
+		t.Errorf("missing %q in\n%s", want, out)
+	}
+}
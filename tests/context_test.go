@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+)
+
+const longLoop = `
+package main
+
+type OptionalSig struct {
+	IsSome bool
+	Value  [64]byte
+}
+
+func main() {
+	var sigs [100000]OptionalSig
+	var count uint32
+	for i := 0; i < 100000; i++ {
+		if sigs[i].IsSome {
+			count++
+		}
+	}
+}
+`
+
+// countdownContext is canceled after Err has been called n times, which
+// lands the cancellation inside a phase without relying on timing.
+type countdownContext struct {
+	context.Context
+	n int
+}
+
+func (c *countdownContext) Err() error {
+	if c.n <= 0 {
+		return context.Canceled
+	}
+	c.n--
+	return nil
+}
+
+func TestCompileContextCanceledBeforeStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).CompileContext(ctx, longLoop, "loop.go")
+	if !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), "during parsing") {
+		t.Errorf("expected cancellation during parsing, got %v", err)
+	}
+}
+
+func TestCompileContextCanceledWhileUnrolling(t *testing.T) {
+	ctx := &countdownContext{Context: context.Background(), n: 100}
+	_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).CompileContext(ctx, longLoop, "loop.go")
+	if !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), "compile canceled during analysis") {
+		t.Errorf("expected cancellation during analysis, got %v", err)
+	}
+}
+
+func TestMaxOutputBytes(t *testing.T) {
+	c := compiler.New(compiler.Config{Target: "simplicityhl", MaxOutputBytes: 4096})
+	_, err := c.Compile(longLoop, "loop.go")
+	if err == nil || !strings.Contains(err.Error(), "exceeds the output limit of 4096 bytes") {
+		t.Errorf("expected the unroll to hit the limit, got %v", err)
+	}
+
+	c = compiler.New(compiler.Config{Target: "simplicityhl", Entry: "AmountOk", MaxOutputBytes: 64})
+	_, err = c.Compile(amountPredicate, "amount.go")
+	if err == nil || !strings.Contains(err.Error(), "generated output exceeds the limit of 64 bytes") {
+		t.Errorf("expected generation to hit the limit, got %v", err)
+	}
+
+	c = compiler.New(compiler.Config{Target: "simplicityhl", Entry: "AmountOk", MaxOutputBytes: 1 << 20})
+	if _, err := c.Compile(amountPredicate, "amount.go"); err != nil {
+		t.Errorf("output under the limit: %v", err)
+	}
+}
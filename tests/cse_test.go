@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+	"github.com/0ceanslim/go-simplicity/pkg/optimize"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+)
+
+// TestOptimizeHTLC compiles htlc.go with Config.Optimize: both arms sign
+// jet::sig_all_hash(), which is computed once before the match.
+func TestOptimizeHTLC(t *testing.T) {
+	c := compiler.New(compiler.Config{Target: "simplicityhl", Optimize: true})
+	result, err := c.Compile(string(readExample(t, "htlc.go")), "htlc.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	golden := `fn main() {
+    let t_63_3: u256 = jet::sig_all_hash();
+    match witness::W {
+        Left((preimage, recipient_sig): ([u8; 32], [u8; 64])) => {
+            let hash = jet::sha_256_ctx_8_finalize(jet::sha_256_ctx_8_add_32(jet::sha_256_ctx_8_init(), preimage));
+            assert!(jet::eq_256(hash, param::HASH_LOCK));
+            let msg = t_63_3;
+            jet::bip_0340_verify((param::RECIPIENT_PUBKEY, msg), recipient_sig);
+        },
+        Right(sig: [u8; 64]) => {
+            jet::check_lock_height(param::REFUND_HEIGHT);
+            let msg = t_63_3;
+            jet::bip_0340_verify((param::SENDER_PUBKEY, msg), sig);
+        }
+    }
+}
+`
+	if !strings.HasSuffix(result, golden) {
+		t.Errorf("expected main to end the program as\n%s\ngot:\n%s", golden, result)
+	}
+
+	// The binding maps to the Go source of the first call it replaces.
+	plain := compiler.New(compiler.Config{Target: "simplicityhl"})
+	unoptimized, err := plain.Compile(string(readExample(t, "htlc.go")), "htlc.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	lineOf := func(code, text string) int {
+		return strings.Count(code[:strings.Index(code, text)], "\n") + 1
+	}
+	want, _ := plain.SourcePosition(lineOf(unoptimized, "let msg"))
+	if got, ok := c.SourcePosition(lineOf(result, "let t_63_3")); !ok || got != want {
+		t.Errorf("binding maps to %v, %v, want %v", got, ok, want)
+	}
+
+	prog, err := shlparse.Parse(result)
+	if err != nil {
+		t.Fatalf("generated program does not parse: %v", err)
+	}
+	tx, err := eval.ParseTx(readExample(t, "htlc.tx.json"))
+	if err != nil {
+		t.Fatalf("ParseTx: %v", err)
+	}
+	claim, err := eval.ParseWitnessJSON(readExample(t, "htlc.witness.json"))
+	if err != nil {
+		t.Fatalf("ParseWitnessJSON: %v", err)
+	}
+	if err := eval.Run(prog, eval.Options{Witness: claim, Tx: tx}); err != nil {
+		t.Errorf("expected the claim to be accepted, got %v", err)
+	}
+	wrong := map[string]string{"W": strings.Replace(claim["W"], "0x4242", "0x4243", 1)}
+	var rejection *eval.Rejection
+	if err := eval.Run(prog, eval.Options{Witness: wrong, Tx: tx}); !errors.As(err, &rejection) {
+		t.Errorf("expected the wrong preimage to be rejected, got %v", err)
+	}
+}
+
+func TestCSE(t *testing.T) {
+	tests := []struct {
+		name, code, want string
+		origin           []int // nil when unchanged
+	}{
+		{
+			"repeated call",
+			`fn main() {
+    let x: u32 = 1;
+    let a: u32 = jet::and_32(x, 2);
+    let b: u32 = jet::and_32(x, 2);
+    assert!(jet::eq_32(a, b));
+}
+`,
+			`fn main() {
+    let x: u32 = 1;
+    let t_3_18: u32 = jet::and_32(x, 2);
+    let a: u32 = t_3_18;
+    let b: u32 = t_3_18;
+    assert!(jet::eq_32(a, b));
+}
+`,
+			[]int{1, 2, 3, 3, 4, 5, 6, 7},
+		},
+		{
+			// The second x is another binding: the calls differ.
+			"shadowed argument",
+			`fn main() {
+    let x: u32 = 1;
+    let a: u32 = jet::and_32(x, 2);
+    let x: u32 = 3;
+    let b: u32 = jet::and_32(x, 2);
+    assert!(jet::eq_32(a, b));
+}
+`,
+			"",
+			nil,
+		},
+		{
+			// An arm that is an expression has nowhere to put a binding,
+			// and before the match x is not in scope.
+			"expression arm",
+			`fn main() {
+    match witness::W {
+        Left(x: u32) => assert!(jet::eq_32(jet::and_32(x, 2), jet::and_32(x, 2))),
+        Right(y: u32) => assert!(jet::eq_32(y, 3)),
+    }
+}
+`,
+			"",
+			nil,
+		},
+		{
+			// A temporary the program binds is not reused.
+			"taken name",
+			`fn main() {
+    let t_2_35: u32 = jet::xor_32(jet::and_32(7, 2), jet::and_32(7, 2));
+    assert!(jet::eq_32(t_2_35, 0));
+}
+`,
+			`fn main() {
+    let t_2_35_2: u32 = jet::and_32(7, 2);
+    let t_2_35: u32 = jet::xor_32(t_2_35_2, t_2_35_2);
+    assert!(jet::eq_32(t_2_35, 0));
+}
+`,
+			[]int{1, 2, 2, 3, 4, 5},
+		},
+	}
+	for _, tt := range tests {
+		want := tt.want
+		if want == "" {
+			want = tt.code
+		}
+		got, origin := optimize.CSE(tt.code)
+		if got != want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, want)
+			continue
+		}
+		if tt.origin == nil {
+			tt.origin = make([]int, strings.Count(tt.code, "\n")+1)
+			for i := range tt.origin {
+				tt.origin[i] = i + 1
+			}
+		}
+		if len(origin) != len(tt.origin) {
+			t.Errorf("%s: origin = %v, want %v", tt.name, origin, tt.origin)
+			continue
+		}
+		for i := range origin {
+			if origin[i] != tt.origin[i] {
+				t.Errorf("%s: origin = %v, want %v", tt.name, origin, tt.origin)
+				break
+			}
+		}
+	}
+}
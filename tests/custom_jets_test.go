@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/jets"
+)
+
+// proposedJets declares jets the table does not have, as a researcher
+// experimenting with them would.
+var proposedJets = []jets.JetDecl{
+	{Name: "fold_sum_32", ParamTypes: []string{"u32", "u32"}, ReturnType: "u32"},
+	{Name: "is_cool", GoName: "Cool", Package: "example.com/lab/labjets", ParamTypes: []string{"u256"}, ReturnType: "bool",
+		Dialects: map[string]string{"simfony-0.3": "is_cool_v0"}},
+}
+
+const customJetSource = `package main
+
+import (
+	"simplicity/jet"
+
+	"example.com/lab/labjets"
+)
+
+func main() {
+	var a uint32
+	var b uint32
+	sum := jet.Custom("fold_sum_32", a, b)
+	twice := jet.FoldSum32(sum, sum)
+	jet.Verify(twice == 10)
+	jet.Verify(labjets.Cool(jet.SigAllHash()))
+}
+`
+
+func TestExtraJets(t *testing.T) {
+	config := compiler.Config{Target: "simplicityhl", ExtraJets: proposedJets, SelfCheck: true}
+	c := compiler.New(config)
+	out, err := c.Compile(customJetSource, "custom.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"jet::fold_sum_32(witness::A, witness::B)", "jet::fold_sum_32(sum, sum)", "jet::is_cool(jet::sig_all_hash())"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in\n%s", want, out)
+		}
+	}
+	var required []string
+	for _, fn := range c.Result().Functions {
+		required = append(required, fn.Jets...)
+	}
+	if got := strings.Join(required, " "); !strings.Contains(got, "fold_sum_32") || !strings.Contains(got, "is_cool") {
+		t.Errorf("required jets %s lack the declared ones", got)
+	}
+	caps := compiler.Capabilities(config)
+	if !caps.HasJet("fold_sum_32") || !caps.HasJet("is_cool") || !caps.HasJet("sig_all_hash") {
+		t.Errorf("capabilities lack the declared jets: %v", caps.Jets)
+	}
+
+	config.SHLDialect = "simfony-0.3"
+	out, err = compiler.New(config).Compile(customJetSource, "custom.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "jet::is_cool_v0(") {
+		t.Errorf("expected the simfony-0.3 name of is_cool in\n%s", out)
+	}
+
+	// The declared parameter types are checked in the generated code.
+	mistyped := []jets.JetDecl{{Name: "fold_sum_32", ParamTypes: []string{"u64", "u64"}, ReturnType: "u32"}, proposedJets[1]}
+	_, err = compiler.New(compiler.Config{Target: "simplicityhl", ExtraJets: mistyped}).Compile(customJetSource, "custom.go")
+	if code, _ := diag.CodeOf(err); code != diag.TypeMismatch {
+		t.Errorf("expected a type mismatch for u32 arguments of a u64 jet, got %v", err)
+	}
+}
+
+func TestExtraJetErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		decls []jets.JetDecl
+		want  string
+	}{
+		{"builtin name", []jets.JetDecl{{Name: "sig_all_hash", GoName: "MyHash", ReturnType: "u256"}}, "conflicts with the builtin jet SigAllHash"},
+		{"builtin Go name", []jets.JetDecl{{Name: "my_hash", GoName: "SigAllHash", ReturnType: "u256"}}, "conflicts with the builtin jet SigAllHash"},
+		{"declared twice", append(proposedJets, proposedJets[0]), "conflicts with the declared jet FoldSum32"},
+		{"bad type", []jets.JetDecl{{Name: "my_jet", ParamTypes: []string{"u33"}, ReturnType: "u32"}}, "parameter 0"},
+		{"bad name", []jets.JetDecl{{Name: "MyJet", ReturnType: "u32"}}, "invalid SimplicityName"},
+		{"generic Go name", []jets.JetDecl{{Name: "custom", ReturnType: "u32"}}, "invalid Go name"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := compiler.Config{Target: "simplicityhl", ExtraJets: tc.decls}
+			if caps := compiler.Capabilities(config); caps.Supported {
+				t.Errorf("capabilities accept invalid ExtraJets")
+			}
+			_, err := compiler.New(config).Compile(customJetSource, "custom.go")
+			if code, _ := diag.CodeOf(err); code != diag.InvalidConfig || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected an invalid config error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+
+	unknown := strings.Replace(customJetSource, `"fold_sum_32"`, `"fold_sum_64"`, 1)
+	_, err := compiler.New(compiler.Config{Target: "simplicityhl", ExtraJets: proposedJets}).Compile(unknown, "custom.go")
+	if err == nil || !strings.Contains(err.Error(), `jet.Custom("fold_sum_64") names no jet of the table or of Config.ExtraJets [SIM0103]`) {
+		t.Errorf("expected an unknown jet error, got %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+)
+
+const derivedSource = `package main
+
+import "simplicity/jet"
+
+func main() {
+	var amount uint64 = 1000
+	var rate uint64 = 1500
+	fee := (amount*rate)/10000 + 1
+	var bonus uint64 = amount * 2
+	jet.Verify(jet.Le64(100, fee))
+	jet.Verify(jet.Le64(bonus, 5000))
+}
+`
+
+func TestDerivedValuesAreRecomputed(t *testing.T) {
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(derivedSource, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		// Intermediate results are temporaries named after their operator.
+		"let (_, t_8_16): (u64, u64) = <u128>::into(jet::multiply_64(witness::AMOUNT, witness::RATE));",
+		"let t_8_22: u64 = jet::divide_64(t_8_16, 10000);",
+		"let (_, fee): (bool, u64) = jet::add_64(t_8_22, 1);",
+		// Declared with var, so the spender supplies it.
+		"const BONUS: u64 = 2000;",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+	if strings.Contains(result, "const FEE") {
+		t.Errorf("fee is derived from witnesses and should not be one:\n%s", result)
+	}
+
+	for _, tt := range []struct {
+		name, amount string
+		accept       bool
+	}{
+		{"enough", "1000", true},
+		{"too little", "600", false},
+		// 3 × amount wraps to 1, so the fee is 1, as it is in Go.
+		{"overflow", "12297829382473034411", false},
+	} {
+		witness := map[string]string{"AMOUNT": tt.amount, "RATE": "1500", "BONUS": "10"}
+		_, err := runSource(t, compiler.Config{}, derivedSource, witness)
+		var rejection *eval.Rejection
+		if tt.accept && err != nil || !tt.accept && !errors.As(err, &rejection) {
+			t.Errorf("%s: accept %v, got %v", tt.name, tt.accept, err)
+		}
+	}
+}
+
+const derivedCallSource = `package main
+
+import "simplicity/jet"
+
+func dbl(x uint64) uint64 {
+	return x * 2
+}
+
+func main() {
+	var x uint64
+	var ok bool
+	m := dbl(x)
+	n := !ok
+	jet.Verify(jet.Le64(m, 100))
+	jet.Verify(n)
+}
+`
+
+func TestDerivedCallIsRecomputed(t *testing.T) {
+	for _, level := range []compiler.OptLevel{compiler.O0, compiler.O2} {
+		result, err := compiler.New(compiler.Config{Target: "simplicityhl", OptLevel: level, SelfCheck: true}).Compile(derivedCallSource, "contract.go")
+		if err != nil {
+			t.Fatalf("%v: %v", level, err)
+		}
+		// A call or a negation of witnesses is bound in main, not made a
+		// witness whose value reads others.
+		for _, want := range []string{"    let m: u64 = ", "    let n: bool = match witness::OK {"} {
+			if !strings.Contains(result, want) {
+				t.Errorf("%v: missing %q in\n%s", level, want, result)
+			}
+		}
+		if strings.Contains(result, "const M") || strings.Contains(result, "const N") {
+			t.Errorf("%v: m and n are derived from witnesses and should not be ones:\n%s", level, result)
+		}
+
+		for _, tt := range []struct {
+			x, ok  string
+			accept bool
+		}{
+			{"50", "false", true},
+			{"51", "false", false},
+			{"50", "true", false},
+		} {
+			_, err := runSource(t, compiler.Config{OptLevel: level}, derivedCallSource, map[string]string{"X": tt.x, "OK": tt.ok})
+			var rejection *eval.Rejection
+			if tt.accept && err != nil || !tt.accept && !errors.As(err, &rejection) {
+				t.Errorf("%v: x %s, ok %s: accept %v, got %v", level, tt.x, tt.ok, tt.accept, err)
+			}
+		}
+	}
+}
+
+const derivedAggregateSource = `package main
+
+import "simplicity/jet"
+
+type Pair struct {
+	Ok bool
+	K  uint8
+}
+
+func order(k uint8, b uint8) [2]uint8 {
+	if jet.Le8(k, b) {
+		return [2]uint8{k, b}
+	}
+	return [2]uint8{b, k}
+}
+
+func pair(k uint8, b uint8) Pair {
+	return Pair{Ok: jet.Le8(k, b), K: k}
+}
+
+func same(a [2]uint8, b [2]uint8) bool {
+	ok := true
+	for i := 0; i < 2; i++ {
+		ok = ok && a[i] == b[i]
+	}
+	return ok
+}
+
+func main() {
+	var k uint8
+	var b uint8
+	var sorted [2]uint8
+	r := order(k, b)
+	p := pair(k, b)
+	jet.Verify(same(r, sorted))
+	jet.Verify(p.Ok)
+}
+`
+
+func TestDerivedAggregateIsRecomputed(t *testing.T) {
+	for _, level := range []compiler.OptLevel{compiler.O0, compiler.O2} {
+		result, err := compiler.New(compiler.Config{Target: "simplicityhl", OptLevel: level, SelfCheck: true, Strict: true}).Compile(derivedAggregateSource, "contract.go")
+		if err != nil {
+			t.Fatalf("%v: %v", level, err)
+		}
+		// An array is bound with its type, and a struct is destructured
+		// into the locals its selectors read.
+		for _, want := range []string{"    let r: [u8; 2] = ", "    let (p_ok, p_k): (bool, u8) = ", "assert!(p_ok);"} {
+			if !strings.Contains(result, want) {
+				t.Errorf("%v: missing %q in\n%s", level, want, result)
+			}
+		}
+		if strings.Contains(result, "const R") || strings.Contains(result, "const P") {
+			t.Errorf("%v: r and p are derived from witnesses and should not be ones:\n%s", level, result)
+		}
+
+		for _, tt := range []struct {
+			k, b, sorted string
+			accept       bool
+		}{
+			{"3", "7", "0x0307", true},
+			{"7", "3", "0x0307", false},
+			{"3", "7", "0x0703", false},
+		} {
+			_, err := runSource(t, compiler.Config{OptLevel: level}, derivedAggregateSource, map[string]string{"K": tt.k, "B": tt.b, "SORTED": tt.sorted})
+			var rejection *eval.Rejection
+			if tt.accept && err != nil || !tt.accept && !errors.As(err, &rejection) {
+				t.Errorf("%v: k %s, b %s, sorted %s: accept %v, got %v", level, tt.k, tt.b, tt.sorted, tt.accept, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/equiv"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+)
+
+const destructureSource = `package main
+
+import "simplicity/jet"
+
+func Split(v uint64) (uint64, bool) {
+	return v / 2, v%2 == 0
+}
+
+func Lookup(k uint64) (uint64, bool) {
+	half, even := Split(k)
+	return half, even
+}
+
+func Half(k uint64) uint64 {
+	if p, found := Lookup(k); found {
+		return p
+	}
+	return 0
+}
+
+func Odd(k uint64) bool {
+	_, even := Split(k)
+	return !even
+}
+
+func main() {
+	var k uint64
+	jet.Verify(jet.Eq64(Half(k), 21))
+}
+`
+
+func TestDestructureLet(t *testing.T) {
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl", OptLevel: compiler.O0}).Compile(destructureSource, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"fn split(v: u64) -> (u64, bool) {\n    (jet::divide_64(v, 2), jet::eq_64(jet::modulo_64(v, 2), 0))\n}",
+		"    let (half, even): (u64, bool) = split(k);\n    (half, even)\n",
+		// The init of the if is its let, the condition its match.
+		"    let (p, found): (u64, bool) = lookup(k);\n" +
+			"    match found {\n" +
+			"        true => {\n" +
+			"            p\n" +
+			"        },\n" +
+			"        false => {\n" +
+			"            0\n" +
+			"        }\n" +
+			"    }\n",
+		"    let (_, even): (u64, bool) = split(k);\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+}
+
+func TestDestructureLetEquivalence(t *testing.T) {
+	for _, level := range []compiler.OptLevel{compiler.O0, compiler.O2} {
+		for _, k := range []uint64{0, 21, 42, 43, 84} {
+			_, err := runSource(t, compiler.Config{OptLevel: level}, destructureSource, map[string]string{"K": fmt.Sprint(k)})
+			var rejection *eval.Rejection
+			switch {
+			case k == 42 && err != nil:
+				t.Errorf("%v: k %d was rejected: %v", level, k, err)
+			case k != 42 && !errors.As(err, &rejection):
+				t.Errorf("%v: k %d was accepted: %v", level, k, err)
+			}
+		}
+	}
+}
+
+const destructureOptionSource = `package main
+
+import "simplicity/jet"
+
+type Quote struct {
+	Price uint64
+	Fresh bool
+}
+
+type OptQuote struct {
+	IsSome bool
+	Value  Quote
+}
+
+func main() {
+	var q OptQuote
+	if q.IsSome {
+		jet.Verify(jet.Le64(1000, q.Value.Price))
+	}
+}
+`
+
+func TestDestructureOption(t *testing.T) {
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(destructureOptionSource, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	// The Value is a tuple, the field the arm does not read _.
+	for _, want := range []string{
+		"const Q: Option<(u64, bool)> = None;",
+		"        Some((q_price, _): (u64, bool)) => {\n            assert!(jet::le_64(1000, q_price));\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+
+	tests := []struct {
+		witness string
+		accept  bool
+	}{
+		{"None", true},
+		{"Some((1000, false))", true},
+		{"Some((5000, true))", true},
+		{"Some((999, true))", false},
+	}
+	for _, tt := range tests {
+		_, err := runSource(t, compiler.Config{}, destructureOptionSource, map[string]string{"Q": tt.witness})
+		var rejection *eval.Rejection
+		switch {
+		case tt.accept && err != nil:
+			t.Errorf("%s was rejected: %v", tt.witness, err)
+		case !tt.accept && !errors.As(err, &rejection):
+			t.Errorf("%s was accepted: %v", tt.witness, err)
+		}
+	}
+}
+
+const ifResultSource = `package main
+
+import "simplicity/jet"
+
+func Guard(a uint8, r uint8) bool {
+	if r == 0 {
+		return false
+	}
+	return jet.Le8(a, 10)
+}
+
+func Pick(a uint8, r uint8) bool {
+	if r == 0 {
+		return false
+	} else {
+		return jet.Le8(a, 10)
+	}
+}
+
+func main() {
+	var a uint8
+	var r uint8
+	jet.Verify(Guard(a, r))
+	jet.Verify(Pick(a, r))
+}
+`
+
+// TestIfResultWithoutInit checks that an if without an init whose branches
+// return is the match on its condition, and agrees with the Go.
+func TestIfResultWithoutInit(t *testing.T) {
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl", OptLevel: compiler.O0}).Compile(ifResultSource, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	want := "    match jet::eq_8(r, 0) {\n" +
+		"        true => {\n" +
+		"            false\n" +
+		"        },\n" +
+		"        false => {\n" +
+		"            jet::le_8(a, 10)\n" +
+		"        }\n" +
+		"    }\n"
+	for _, fn := range []string{"fn guard(a: u8, r: u8) -> bool {\n", "fn pick(a: u8, r: u8) -> bool {\n"} {
+		if !strings.Contains(result, fn+want) {
+			t.Errorf("missing %q in\n%s", fn+want, result)
+		}
+	}
+
+	for _, entry := range []string{"Guard", "Pick"} {
+		report, err := equiv.Check(ifResultSource, "contract.go", compiler.Config{Entry: entry}, equiv.Options{})
+		if err != nil {
+			t.Fatalf("%s: Check: %v", entry, err)
+		}
+		if len(report.Mismatches) != 0 {
+			t.Errorf("%s: mismatches %v in %d cases; want all to agree", entry, report.Mismatches, report.Cases)
+		}
+	}
+}
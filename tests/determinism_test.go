@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/types"
+)
+
+const determinismRuns = 50
+
+// TestDeterministicOutput compiles every example repeatedly, with fresh
+// compilers running concurrently and with one compiler reused across calls,
+// and requires byte-identical output. CI runs it under -race.
+func TestDeterministicOutput(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("..", "examples", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testable, err := filepath.Glob(filepath.Join("..", "examples", "testable", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths = append(paths, testable...)
+	if len(paths) == 0 {
+		t.Fatal("no examples found")
+	}
+
+	for _, path := range paths {
+		for _, level := range []compiler.OptLevel{compiler.O0, compiler.O2} {
+			t.Run(filepath.Base(path)+"/"+level.String(), func(t *testing.T) {
+				src := loadExample(t, path)
+				config := exampleConfig(path)
+				config.OptLevel = level
+				want, err := compiler.New(config).Compile(src, path)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				outputs := make([]string, determinismRuns)
+				var wg sync.WaitGroup
+				for i := range outputs {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						out, err := compiler.New(config).Compile(src, path)
+						if err != nil {
+							t.Errorf("run %d: %v", i, err)
+						}
+						outputs[i] = out
+					}(i)
+				}
+				wg.Wait()
+				for i, out := range outputs {
+					if out != want {
+						t.Fatalf("run %d differs from the first compile:\n%s\nwant:\n%s", i, out, want)
+					}
+				}
+
+				// State left behind by one Compile must not leak into the next.
+				c := compiler.New(config)
+				for i := 0; i < determinismRuns; i++ {
+					out, err := c.Compile(src, path)
+					if err != nil {
+						t.Fatalf("reused compiler, run %d: %v", i, err)
+					}
+					if out != want {
+						t.Fatalf("reused compiler, run %d differs from a fresh compile:\n%s\nwant:\n%s", i, out, want)
+					}
+				}
+			})
+		}
+	}
+}
+
+// TestDeterministicTemporaries requires the temporaries of a function not
+// to depend on the rest of the program: adding a function with temporaries
+// of its own, which a counter would number first, leaves those of main and
+// their names as they were, with and without CSE.
+func TestDeterministicTemporaries(t *testing.T) {
+	const program = `package main
+
+import "simplicity/jet"
+
+func main() {
+	var amount uint64
+	var rate uint64
+	fee := (amount*rate)/10000 + 1
+	jet.Verify(jet.Le64(100, fee))
+	jet.Verify(jet.Eq256(jet.SigAllHash(), jet.SigAllHash()))
+}
+`
+	const helper = `
+func scaled(a uint64, b uint64) bool {
+	c := (a*b)/100 + a
+	return jet.Eq256(jet.SigAllHash(), jet.SigAllHash()) && jet.Le64(c, 5)
+}
+`
+	mainOf := func(out string) string {
+		_, body, _ := strings.Cut(out, "fn main() {")
+		return body
+	}
+	for _, level := range []compiler.OptLevel{compiler.O0, compiler.O2} {
+		config := compiler.Config{Target: "simplicityhl", OptLevel: level, NoDCE: true, NoInline: true}
+		alone, err := compiler.New(config).Compile(program, "fee.go")
+		if err != nil {
+			t.Fatal(err)
+		}
+		with, err := compiler.New(config).Compile(program+helper, "fee.go")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(with, "fn scaled(") {
+			t.Fatalf("%v: expected the helper in\n%s", level, with)
+		}
+		if mainOf(alone) != mainOf(with) {
+			t.Errorf("%v: main changed with another function:\n%s\nwas:\n%s", level, with, alone)
+		}
+		if !strings.Contains(alone, "let t_8_22: u64 = jet::divide_64(t_8_16, 10000);") {
+			t.Errorf("%v: expected temporaries named after their operators in\n%s", level, alone)
+		}
+	}
+}
+
+func TestSupportedTypesSorted(t *testing.T) {
+	supported := types.NewTypeMapper().SupportedTypes()
+	for i := 1; i < len(supported); i++ {
+		if supported[i-1] >= supported[i] {
+			t.Fatalf("SupportedTypes is not sorted: %v", supported)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+)
+
+// TestDiagnosticCodesGolden locks the registry: a code keeps its meaning
+// once released, so adding a code means adding a line to
+// tests/testdata/diag_codes.golden, never changing one.
+func TestDiagnosticCodesGolden(t *testing.T) {
+	golden, err := os.ReadFile(filepath.Join("testdata", "diag_codes.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got strings.Builder
+	for _, e := range diag.All() {
+		fmt.Fprintf(&got, "%s  %s\n", e.Code, e.Title)
+		if !strings.Contains(e.Text, "\n    ") && e.Code != diag.SyntaxError && e.Code != diag.PackageLoad {
+			t.Errorf("%s: explanation has no example", e.Code)
+		}
+	}
+	if got.String() != string(golden) {
+		t.Errorf("registry differs from tests/testdata/diag_codes.golden; a released code must keep its meaning, so only add lines, with\n\tgo run ./cmd/simgo explain > tests/testdata/diag_codes.golden\ngot:\n%s", got.String())
+	}
+
+	if e, ok := diag.Lookup("sim0102"); !ok || e.Code != diag.ValueOverflow {
+		t.Errorf("Lookup(sim0102) = %v, %v", e.Code, ok)
+	}
+	if _, ok := diag.Lookup("SIM9999"); ok {
+		t.Error("Lookup(SIM9999) found a code")
+	}
+}
+
+const diagSource = `package main
+
+import "simplicity/jet"
+
+const Limit uint32 = 10
+
+func main() {
+	var a uint32
+	BODY
+}
+`
+
+func TestDiagnosticCodes(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string // BODY of diagSource, or a whole file starting with package
+		config compiler.Config
+		want   diag.Code
+	}{
+		{"unbounded loop", "for {}", compiler.Config{}, diag.UnboundedLoop},
+		{"range loop", "var b [2]uint32\n\tfor i := range b { jet.Verify(jet.Le32(a, b[i])) }", compiler.Config{}, diag.RangeLoop},
+		{"map", "var m map[uint32]uint32\n\tjet.Verify(jet.Le32(a, m[0]))", compiler.Config{}, diag.Map},
+		{"string", "var s string\n\t_ = s", compiler.Config{}, diag.String},
+		{"syntax", "package main\n\nfunc main() {", compiler.Config{}, diag.SyntaxError},
+		{"unknown jet", "jet.Verify(jet.Nope(a))", compiler.Config{}, diag.UnknownJet},
+		{"division by zero", "jet.Verify(jet.Le32(a/0, Limit))", compiler.Config{}, diag.DivisionByZero},
+		{"constant overflow", "package main\n\nimport \"simplicity/jet\"\n\nconst Fee uint8 = 300\n\nfunc main() {\n\tvar a uint8\n\tjet.Verify(jet.Le8(a, Fee))\n}\n", compiler.Config{}, diag.ValueOverflow},
+		{"witness overflow", "var b uint8 = 300\n\tjet.Verify(jet.Le8(b, 3))", compiler.Config{}, diag.ValueOverflow},
+		{"no main", "package main\n\nfunc helper(a uint32) uint32 { return a }\n", compiler.Config{}, diag.NoMain},
+		{"entry not found", "jet.Verify(jet.Le32(a, Limit))", compiler.Config{Entry: "Spend"}, diag.EntryNotFound},
+		{"unknown witness", "jet.Verify(jet.Le32(a, Limit))", compiler.Config{WitnessValues: map[string]string{"B": "1"}}, diag.UnknownWitness},
+		{"trivial main", "jet.Verify(Limit > 5)", compiler.Config{}, diag.TrivialMain},
+		{"stub without include", "package main\n\nimport \"simplicity/jet\"\n\nfunc check(a uint32) bool\n\nfunc main() {\n\tvar a uint32\n\tjet.Verify(check(a))\n}\n", compiler.Config{}, diag.IncludeMismatch},
+		{"invalid mode", "jet.Verify(jet.Le32(a, Limit))", compiler.Config{Mode: "lib"}, diag.InvalidConfig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := tt.source
+			if !strings.HasPrefix(source, "package ") {
+				source = strings.Replace(diagSource, "BODY", tt.source, 1)
+			}
+			tt.config.Target = "simplicityhl"
+			_, err := compiler.New(tt.config).Compile(source, "contract.go")
+			if err == nil {
+				t.Fatal("compiled")
+			}
+			if codes := diag.Find(err.Error()); len(codes) == 0 || codes[0] != tt.want {
+				t.Errorf("codes %v, want %s: %v", codes, tt.want, err)
+			}
+		})
+	}
+
+	c := compiler.New(compiler.Config{Target: "simplicityhl", AllowTrivialMain: true})
+	body := "if Limit > 5 {\n\t\tjet.Verify(jet.Le32(a, Limit))\n\t} else {\n\t\tjet.Verify(jet.Le32(Limit, a))\n\t}"
+	if _, err := c.Compile(strings.Replace(diagSource, "BODY", body, 1), "contract.go"); err != nil {
+		t.Fatal(err)
+	}
+	if w := c.Warnings(); len(w) != 1 || !strings.HasSuffix(w[0], " ["+string(diag.Unreachable)+"]") {
+		t.Errorf("warnings %v, want one ending in [%s]", w, diag.Unreachable)
+	}
+}
+
+func TestSuggestions(t *testing.T) {
+	candidates := []string{"amount", "Amount", "minAmount", "checkAmount", "fee"}
+	for _, tt := range []struct {
+		name string
+		want []string
+	}{
+		{"amout", []string{"Amount", "amount"}},
+		{"AMOUNT", []string{"Amount", "amount"}},
+		{"MinAmont", []string{"minAmount"}},
+		{"fe", []string{"fee"}},
+		{"sig", nil},
+	} {
+		if got := diag.Suggest(tt.name, candidates); !slices.Equal(got, tt.want) {
+			t.Errorf("Suggest(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if got, want := diag.DidYouMean("amout", candidates), "; did you mean Amount or amount?"; got != want {
+		t.Errorf("DidYouMean = %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		name, body string
+		want       string
+	}{
+		{"slice of bytes", "var data []byte\n\t_ = data", "slices are not supported: declare a fixed-size array like [32]byte [SIM0006]"},
+		{"slice", "var keys []uint32\n\tjet.Verify(jet.Le32(a, keys[0]))", "declare a fixed-size array like [4]uint32, sized for the most elements the contract needs"},
+		{"no condition", "for {\n\t}", "is unrolled automatically, but the bound of this one is not constant because it has no condition [SIM0001]"},
+		{"variable bound", "for i := 0; i < a; i++ {\n\t}", "not constant because its bound a is a variable, not an integer constant such as 4 or N with const N = 4"},
+		{"condition", "for i := 0; i != 3; i++ {\n\t}", "not constant because its condition i != 3 does not compare the counter with < or <="},
+		{"identifier", "jet.Verify(jet.Le32(a, Limt))", "contract.go:9:25: undefined: Limt; did you mean Limit? [SIM0016]"},
+		{"local", "total := a\n\tjet.Verify(jet.Le32(totl, Limit))", "undefined: totl; did you mean total?"},
+		{"no suggestion", "jet.Verify(jet.Le32(a, zzz))", "undefined: zzz [SIM0016]"},
+		{"function", "jet.Verify(withinLimt(a))", "contract.go:9:13: call of undefined function withinLimt; did you mean withinLimit? [SIM0016]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := strings.Replace(diagSource, "BODY", tt.body, 1) + "\nfunc withinLimit(a uint32) bool { return jet.Le32(a, Limit) }\n"
+			_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "contract.go")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/transpiler"
+)
+
+// TestDialectGolden compiles the examples under tests/testdata/dialects in
+// the dialect each directory is named for.
+func TestDialectGolden(t *testing.T) {
+	for _, dialect := range transpiler.Dialects() {
+		paths, err := filepath.Glob(filepath.Join("testdata", "dialects", dialect, "*.simf"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(paths) < 2 {
+			t.Errorf("dialect %s has %d golden outputs, want at least 2", dialect, len(paths))
+		}
+		for _, golden := range paths {
+			name := strings.TrimSuffix(filepath.Base(golden), ".simf")
+			t.Run(dialect+"/"+name, func(t *testing.T) {
+				path := filepath.Join("..", "examples", name+".go")
+				config := exampleConfig(path)
+				config.SHLDialect = dialect
+				result, err := compiler.New(config).Compile(loadExample(t, path), path)
+				if err != nil {
+					t.Fatalf("compile: %v", err)
+				}
+				data, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, want, _ := strings.Cut(string(data), "\n")
+				if result != want {
+					t.Errorf("output differs from %[1]s; regenerate it with\n\tgo run ./cmd/simgo -dialect %[2]s -input examples/%[3]s.go -output tests/%[1]s\ngot:\n%[4]s", filepath.ToSlash(golden), dialect, name, result)
+				}
+			})
+		}
+	}
+}
+
+func TestDialects(t *testing.T) {
+	source := string(readExample(t, "amount_check.go"))
+	c := compiler.New(compiler.Config{Target: "simplicityhl", SHLDialect: "simfony-0.3"})
+	out, err := c.Compile(source, "amount_check.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, "param::") || strings.Contains(out, "assert!") {
+		t.Errorf("expected params inlined and assertions as jet::verify, got\n%s", out)
+	}
+	// Positions survive the rewrite: the line of jet::verify(index_ok)
+	// maps to the Go it was generated from, as the latest line does.
+	latest := compiler.New(compiler.Config{Target: "simplicityhl"})
+	latestOut, err := latest.Compile(source, "amount_check.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := c.SourcePosition(lineOf(out, "jet::verify(index_ok)"))
+	want, _ := latest.SourcePosition(lineOf(latestOut, "assert!(index_ok)"))
+	if !ok || got != want {
+		t.Errorf("jet::verify(index_ok) maps to %v, want %v", got, want)
+	}
+
+	_, err = compiler.New(compiler.Config{Target: "simplicityhl", SHLDialect: "simfony-0.1"}).Compile(source, "amount_check.go")
+	if code, _ := diag.CodeOf(err); code != diag.InvalidConfig || !strings.Contains(err.Error(), "latest, simfony-0.3") {
+		t.Errorf("expected an invalid config error listing the dialects, got %v", err)
+	}
+}
+
+// lineOf returns the 1-based line of out that contains text, or 0.
+func lineOf(out, text string) int {
+	for i, line := range strings.Split(out, "\n") {
+		if strings.Contains(line, text) {
+			return i + 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+)
+
+const discardedSource = `package main
+
+import (
+	"simplicity/jet"
+
+	"github.com/0ceanslim/go-simplicity/std"
+)
+
+func Validate(amount uint64) bool {
+	return amount >= 1000
+}
+
+func Split(amount uint64) (uint64, uint64) {
+	return amount / 2, amount - amount/2
+}
+
+func Require(amount uint64) {
+	std.Assert(amount > 0)
+}
+
+func Check(amount uint64) bool {
+	Validate(amount)
+	jet.Le64(amount, 5000)
+	Split(amount)
+	return Validate(amount)
+}
+
+func main() {
+	var amount uint64
+	Require(amount)
+	std.Assert(Validate(amount))
+	jet.Verify(Check(amount))
+}
+`
+
+func TestDiscardedValue(t *testing.T) {
+	c := compiler.New(compiler.Config{Target: "simplicityhl"})
+	if _, err := c.Compile(discardedSource, "main.go"); err != nil {
+		t.Fatal(err)
+	}
+	// Require returns nothing, and std.Assert and jet.Verify are not
+	// reported either.
+	want := []string{
+		"main.go:22:2: the bool that Validate returns is discarded; write std.Assert(Validate(amount)) to require it, or bind it and use it [SIM0311]",
+		"main.go:23:2: the bool that jet.Le64 returns is discarded; write std.Assert(jet.Le64(amount, 5000)) to require it, or bind it and use it [SIM0311]",
+		"main.go:24:2: the values (uint64, uint64) that Split returns are discarded; bind them and use them [SIM0311]",
+	}
+	var got []string
+	for _, w := range c.Warnings() {
+		if strings.Contains(w, "[SIM0311]") {
+			got = append(got, w)
+		}
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("warnings:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestDiscardedValueStrict(t *testing.T) {
+	_, err := compiler.New(compiler.Config{Target: "simplicityhl", Strict: true}).Compile(discardedSource, "main.go")
+	want := "main.go:22:2: the bool that Validate returns is discarded"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("expected an error containing %q, got %v", want, err)
+	}
+	if codes := diag.Find(err.Error()); len(codes) != 3 || codes[0] != diag.DiscardedValue {
+		t.Errorf("expected three SIM0311 errors, got %v", err)
+	}
+}
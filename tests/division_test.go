@@ -0,0 +1,197 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+)
+
+const divisionSource = `package main
+
+import (
+	"simplicity/jet"
+
+	"github.com/0ceanslim/go-simplicity/std"
+)
+
+const (
+	Amount uint64 = 1234567
+	Rate   uint64 = 30
+)
+
+func main() {
+	var fee uint64
+	BODY
+}
+`
+
+func TestTruncationWarning(t *testing.T) {
+	tests := []struct {
+		name, body string
+		want       string // "" for no warning
+	}{
+		{"inexact", "jet.Verify(jet.Le64(fee, Amount*Rate/10000))",
+			"contract.go:16:38: Amount * Rate / 10000 truncates: 37037010 / 10000 is 3703 with a remainder of 7010, which is dropped; std.DivCeil rounds up instead [SIM0302]"},
+		{"exact", "jet.Verify(jet.Le64(fee, Amount*Rate/10))", ""},
+		{"witness", "jet.Verify(jet.Le64(Amount, fee/3))", ""},
+		{"rounded up", "jet.Verify(jet.Le64(fee, std.DivCeil(Amount*Rate, 10000)))", ""},
+	}
+	for _, tt := range tests {
+		c := compiler.New(compiler.Config{Target: "simplicityhl"})
+		if _, err := c.Compile(strings.Replace(divisionSource, "BODY", tt.body, 1), "contract.go"); err != nil {
+			t.Errorf("%s: compile: %v", tt.name, err)
+			continue
+		}
+		warnings := c.Warnings()
+		if tt.want == "" && len(warnings) != 0 {
+			t.Errorf("%s: unexpected warnings %v", tt.name, warnings)
+		}
+		if tt.want != "" && (len(warnings) != 1 || warnings[0] != tt.want) {
+			t.Errorf("%s: warnings %v, want %q", tt.name, warnings, tt.want)
+		}
+	}
+}
+
+func TestTruncationWarningInConstant(t *testing.T) {
+	source := strings.Replace(divisionSource, "BODY", "jet.Verify(jet.Le64(fee, Fee))", 1) + "\nconst Fee = Amount * Rate / 10000\n"
+	c := compiler.New(compiler.Config{Target: "simplicityhl"})
+	if _, err := c.Compile(source, "contract.go"); err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	if warnings := c.Warnings(); len(warnings) != 1 || !strings.Contains(warnings[0], "contract.go:19:27: Amount * Rate / 10000 truncates") {
+		t.Errorf("warnings %v", warnings)
+	}
+}
+
+func TestDivCeil(t *testing.T) {
+	// Both operands constant: the quotient is computed at compile time.
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(
+		strings.Replace(divisionSource, "BODY", "jet.Verify(jet.Le64(fee, std.DivCeil(Amount*Rate, 10000)))", 1), "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	if want := "assert!(jet::le_64(witness::FEE, 3704));"; !strings.Contains(result, want) {
+		t.Errorf("missing %q in\n%s", want, result)
+	}
+	if strings.Contains(result, "std_div_ceil_64") {
+		t.Errorf("constant std.DivCeil emitted its helper:\n%s", result)
+	}
+
+	// A witness operand, in main and in a helper.
+	source := strings.NewReplacer(
+		"BODY", "share := std.DivCeil(fee, 3)\n\tjet.Verify(jet.Le64(share, 2))\n\tjet.Verify(AtLeastOne(fee))",
+		"func main", "func AtLeastOne(fee uint64) bool {\n\treturn jet.Le64(1, std.DivCeil(fee, Rate))\n}\n\nfunc main",
+	).Replace(divisionSource)
+	for _, tt := range []struct {
+		fee    string
+		accept bool
+	}{{"6", true}, {"4", true}, {"7", false}, {"0", false}} {
+		_, err := runSource(t, compiler.Config{}, source, map[string]string{"FEE": tt.fee})
+		var rejection *eval.Rejection
+		if tt.accept && err != nil || !tt.accept && !errors.As(err, &rejection) {
+			t.Errorf("fee = %s: accept %v, got %v", tt.fee, tt.accept, err)
+		}
+	}
+
+	_, err = compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(
+		strings.Replace(divisionSource, "BODY", "jet.Verify(jet.Le64(std.DivCeil(fee, Rate-30), 2))", 1), "contract.go")
+	if want := "contract.go:16:39: std.DivCeil(fee, Rate - 30) divides by zero"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected %q, got %v", want, err)
+	}
+}
+
+const divisorSource = `package main
+
+import "simplicity/jet"
+
+func main() {
+	var fee, rate uint64
+	BODY
+}
+`
+
+// withShare declares Share, which divides by its parameter n, ahead of
+// main in source.
+func withShare(source string) string {
+	return strings.Replace(source, "func main", "func Share(a, n uint64) uint64 {\n\treturn a / n\n}\n\nfunc main", 1)
+}
+
+func TestDivisorWarnings(t *testing.T) {
+	tests := []struct {
+		name, body string
+		want       string // "" for no warning
+	}{
+		{"unchecked", "jet.Verify(jet.Le64(fee/rate, 10))",
+			"contract.go:7:26: rate may be zero at spend time, and the divide jet then returns 0 where Go would panic; require rate > 0 before dividing, or compile with -checked-arithmetic"},
+		{"remainder", "jet.Verify(jet.Eq64(fee%rate, 0))",
+			"contract.go:7:26: rate may be zero at spend time, and the modulo jet then returns the dividend where Go would panic"},
+		{"compared with 0", "jet.Verify(jet.Le64(0, rate))\n\tjet.Verify(jet.Le64(fee/rate, 10))",
+			"contract.go:8:26: rate may be zero at spend time: jet.Le64(0, rate) always holds for an unsigned integer, so it does not rule zero out"},
+		{"checked", "jet.Verify(jet.Lt64(0, rate))\n\tjet.Verify(jet.Le64(fee/rate, 10))", ""},
+		{"checked copy", "r := rate\n\tjet.Verify(rate != 0)\n\tjet.Verify(jet.Le64(fee/r, 10))", ""},
+		{"copy unchecked", "r := rate\n\tjet.Verify(jet.Le64(fee/r, 10))", "contract.go:8:26: r may be zero at spend time"},
+		{"constant argument", "jet.Verify(jet.Le64(Share(fee, 4), 10))", ""},
+		{"witness argument", "jet.Verify(jet.Le64(Share(fee, rate), 10))", "contract.go:6:13: n may be zero at spend time"},
+	}
+	for _, tt := range tests {
+		c := compiler.New(compiler.Config{Target: "simplicityhl"})
+		source := strings.Replace(divisorSource, "BODY", tt.body, 1)
+		if strings.Contains(tt.body, "Share") {
+			source = withShare(source)
+		}
+		if _, err := c.Compile(source, "contract.go"); err != nil {
+			t.Errorf("%s: compile: %v", tt.name, err)
+			continue
+		}
+		warnings := c.Warnings()
+		if tt.want == "" && len(warnings) != 0 {
+			t.Errorf("%s: unexpected warnings %v", tt.name, warnings)
+		}
+		if tt.want != "" && (len(warnings) != 1 || !strings.HasPrefix(warnings[0], tt.want)) {
+			t.Errorf("%s: warnings %v, want %q", tt.name, warnings, tt.want)
+		}
+	}
+
+	_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(
+		strings.Replace(divisorSource, "BODY", "jet.Verify(jet.Le64(fee/(Rate-30), 10))", 1)+"\nconst Rate = 30\n", "contract.go")
+	if want := "contract.go:7:26: (Rate - 30) divides by zero"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected %q, got %v", want, err)
+	}
+}
+
+func TestCheckedArithmetic(t *testing.T) {
+	source := withShare(strings.Replace(divisorSource, "BODY", "share := fee / rate\n\tjet.Verify(jet.Le64(share, 10))\n\tjet.Verify(jet.Le64(Share(fee, rate), 10))", 1))
+	c := compiler.New(compiler.Config{Target: "simplicityhl", CheckedArithmetic: true})
+	result, err := c.Compile(source, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	if warnings := c.Warnings(); len(warnings) != 0 {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+	for _, want := range []string{
+		"fn std_checked_divide_64(a: u64, b: u64) -> u64 {\n    assert!(jet::lt_64(0, b));\n    jet::divide_64(a, b)\n}",
+		"    std_checked_divide_64(a, n)\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+	for _, tt := range []struct {
+		rate   string
+		accept bool
+	}{{"2", true}, {"1", false}, {"0", false}} {
+		_, err := runSource(t, compiler.Config{CheckedArithmetic: true}, source, map[string]string{"FEE": "20", "RATE": tt.rate})
+		var rejection *eval.Rejection
+		if tt.accept && err != nil || !tt.accept && !errors.As(err, &rejection) {
+			t.Errorf("rate = %s: accept %v, got %v", tt.rate, tt.accept, err)
+		}
+	}
+	// Without the check, the divide jet makes fee / 0 zero, which passes.
+	if _, err := runSource(t, compiler.Config{}, source, map[string]string{"FEE": "20", "RATE": "0"}); err != nil {
+		t.Errorf("unchecked rate = 0: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+)
+
+const elementAssignSource = `package main
+
+import "simplicity/jet"
+
+func Message() [4]byte {
+	var msg [4]byte
+	msg[0] = 0x01
+	msg[3] = 2 + 3
+	return msg
+}
+
+func Sum(xs [3]uint32, v uint32) uint32 {
+	xs[1] = v
+	var total uint32
+	for i := 0; i < 3; i++ {
+		total += xs[i]
+	}
+	return total
+}
+
+func main() {
+	var key [4]byte
+	key[0] = 0x02
+	var xs [3]uint32
+	var v, sum uint32
+	jet.Verify(jet.Eq32(Sum(xs, v), sum))
+}
+`
+
+func TestElementAssign(t *testing.T) {
+	out, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(elementAssignSource, "main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		// Every element of msg is known, so each new value folds.
+		"    let msg: [u8; 4] = 0x00000000;\n" +
+			"    let msg: [u8; 4] = 0x01000000;\n" +
+			"    let msg: [u8; 4] = 0x01000005;\n",
+		// A parameter's new value is built from its other elements.
+		"    let [xs_0, _, xs_2]: [u32; 3] = xs;\n" +
+			"    let xs: [u32; 3] = [xs_0, v, xs_2];\n",
+		// Test setup in main sets the element of the witness's value.
+		"const KEY: [u8; 4] = 0x02000000;",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+}
+
+func TestElementAssignEquivalence(t *testing.T) {
+	witness := map[string]string{"XS": "[1, 2, 3]", "V": "10", "SUM": "14"}
+	if _, err := runSource(t, compiler.Config{}, elementAssignSource, witness); err != nil {
+		t.Fatalf("expected accept, got %v", err)
+	}
+	// The sum over the old value is wrong.
+	witness["SUM"] = "6"
+	var rejection *eval.Rejection
+	if _, err := runSource(t, compiler.Config{}, elementAssignSource, witness); !errors.As(err, &rejection) {
+		t.Fatalf("the sum over the old array was accepted: %v", err)
+	}
+}
+
+func TestElementAssignDynamicIndex(t *testing.T) {
+	src := `package main
+
+import "simplicity/jet"
+
+func Set(xs [4]uint8, i uint8) uint8 {
+	xs[i] = 1
+	return xs[0]
+}
+
+func main() {
+	var xs [4]uint8
+	var i uint8
+	jet.Verify(jet.Eq8(Set(xs, i), 0))
+}
+`
+	_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(src, "main.go")
+	want := "main.go:6:5: the assignment to xs[i] is not supported: its index i is only known when the program runs"
+	if code, _ := diag.CodeOf(err); code != diag.UnsupportedSyntax || err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected an error containing %q, got %v", want, err)
+	}
+}
+
+func TestElementAssignMainLocal(t *testing.T) {
+	src := `package main
+
+import "simplicity/jet"
+
+func build(x uint64) [2]uint64 {
+	return [2]uint64{x, x}
+}
+
+func main() {
+	var x uint64
+	m := build(x)
+	m[1] = 5
+	jet.Verify(jet.Le64(m[0], m[1]))
+}
+`
+	for _, level := range []compiler.OptLevel{compiler.O0, compiler.O2} {
+		_, err := compiler.New(compiler.Config{Target: "simplicityhl", OptLevel: level, SelfCheck: true}).Compile(src, "main.go")
+		want := "main.go:12:2: the assignment to m[1] is not supported in main: m is not an array witness whose elements are known at compile time"
+		if code, _ := diag.CodeOf(err); code != diag.UnsupportedSyntax || err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%v: expected an error containing %q, got %v", level, want, err)
+		}
+	}
+}
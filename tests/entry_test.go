@@ -0,0 +1,227 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+)
+
+const channelSource = `
+package main
+
+import "simplicity/jet"
+
+const AlicePubkey = 0x9bef8d556d80e43ae7e0becb3f7de6b4e5e4f7e8d9a0b1c2d3e4f5a6b7c8d9e0
+const RefundHeight = 1000
+
+func CooperativeClose() {
+	var closeSig [64]byte
+	jet.BIP340Verify(AlicePubkey, jet.SigAllHash(), closeSig)
+}
+
+func TimeoutRefund() {
+	var refundSig [64]byte
+	jet.CheckLockHeight(RefundHeight)
+	jet.BIP340Verify(AlicePubkey, jet.SigAllHash(), refundSig)
+}
+
+func Unlocked() bool {
+	return true
+}
+
+func main() {
+	var unused [64]byte
+	jet.BIP340Verify(AlicePubkey, jet.SigAllHash(), unused)
+}
+`
+
+func compileEntry(t *testing.T, entry string) (string, error) {
+	t.Helper()
+	c := compiler.New(compiler.Config{Target: "simplicityhl", Entry: entry})
+	return c.Compile(channelSource, "channel.go")
+}
+
+func TestEntrySelectsSpendPath(t *testing.T) {
+	result, err := compileEntry(t, "TimeoutRefund")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"const REFUND_SIG: [u8; 64]",
+		"jet::check_lock_height(param::REFUND_HEIGHT);",
+		"witness::REFUND_SIG",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q\n%s", want, result)
+		}
+	}
+	// Other spend paths and the Go main() are not part of this program.
+	for _, unwanted := range []string{"CLOSE_SIG", "UNUSED", "fn cooperative_close", "fn unlocked"} {
+		if strings.Contains(result, unwanted) {
+			t.Errorf("unexpected %q\n%s", unwanted, result)
+		}
+	}
+}
+
+func TestEntryPredicateIsAsserted(t *testing.T) {
+	// Unlocked always holds, so the program must opt in to accepting
+	// unconditionally.
+	c := compiler.New(compiler.Config{Target: "simplicityhl", Entry: "Unlocked", AllowTrivialMain: true})
+	result, err := c.Compile(channelSource, "channel.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	if !strings.Contains(result, "fn unlocked() -> bool {") {
+		t.Errorf("entry predicate should be emitted as a function\n%s", result)
+	}
+	if !strings.Contains(result, "fn main() {\n    assert!(unlocked());\n}") {
+		t.Errorf("main should assert the entry predicate\n%s", result)
+	}
+}
+
+func TestEntryNotFound(t *testing.T) {
+	_, err := compileEntry(t, "Penalty")
+	if err == nil || !strings.Contains(err.Error(), "entry function Penalty not found") {
+		t.Errorf("expected missing entry error, got %v", err)
+	}
+}
+
+func TestExportedFunctions(t *testing.T) {
+	names, err := compiler.ExportedFunctions(channelSource, "channel.go")
+	if err != nil {
+		t.Fatalf("ExportedFunctions: %v", err)
+	}
+	want := []string{"CooperativeClose", "TimeoutRefund", "Unlocked"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", names, want)
+	}
+}
+
+func TestEntryParametersBecomeWitnesses(t *testing.T) {
+	source := `
+package main
+
+import "simplicity/jet"
+
+const AlicePubkey = 0x9bef8d556d80e43ae7e0becb3f7de6b4e5e4f7e8d9a0b1c2d3e4f5a6b7c8d9e0
+
+func Spend(aliceSig [64]byte) {
+	jet.BIP340Verify(AlicePubkey, jet.SigAllHash(), aliceSig)
+}
+
+func Check(amountOk bool, keyOk bool) bool {
+	return amountOk
+}
+`
+	c := compiler.New(compiler.Config{Target: "simplicityhl", Entry: "Spend"})
+	result, err := c.Compile(source, "params.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{"const ALICE_SIG: [u8; 64] = 0x", "witness::ALICE_SIG);"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q\n%s", want, result)
+		}
+	}
+
+	c = compiler.New(compiler.Config{Target: "simplicityhl", Entry: "Check"})
+	result, err = c.Compile(source, "params.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"const AMOUNT_OK: bool = false;",
+		"const KEY_OK: bool = false;",
+		"fn check(amount_ok: bool, key_ok: bool) -> bool {\n    amount_ok\n}",
+		"assert!(check(witness::AMOUNT_OK, witness::KEY_OK));",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q\n%s", want, result)
+		}
+	}
+}
+
+func TestMainWithParameters(t *testing.T) {
+	source := `
+package main
+
+import "simplicity/jet"
+
+const AlicePubkey = 0x9bef8d556d80e43ae7e0becb3f7de6b4e5e4f7e8d9a0b1c2d3e4f5a6b7c8d9e0
+
+func main(sig [64]byte) {
+	jet.BIP340Verify(AlicePubkey, jet.SigAllHash(), sig)
+}
+`
+	c := compiler.New(compiler.Config{Target: "simplicityhl"})
+	_, err := c.Compile(source, "main_params.go")
+	if err == nil || !strings.HasPrefix(err.Error(), "main_params.go:8:1: main must take no parameters, has 1") {
+		t.Errorf("expected a positioned error about main's parameters, got %v", err)
+	}
+
+	source = strings.Replace(source, "func main(sig [64]byte) {", "func main() bool {", 1)
+	_, err = c.Compile(source, "main_params.go")
+	if err == nil || !strings.HasPrefix(err.Error(), "main_params.go:8:1: main must not return values") {
+		t.Errorf("expected a positioned error about main's results, got %v", err)
+	}
+}
+
+func TestMissingMain(t *testing.T) {
+	source := `
+package main
+
+import "simplicity/jet"
+
+func checkHeight(height uint32) bool {
+	return jet.Le32(800000, height)
+}
+
+func Spend(height uint32) bool {
+	return checkHeight(height)
+}
+
+func Refund(height uint32) bool {
+	return jet.Le32(900000, height)
+}
+`
+	_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "paths.go")
+	if err == nil {
+		t.Fatal("expected an error for a program without main")
+	}
+	for _, want := range []string{"no main function found", "exported functions: Spend, Refund", "-entry <name>", "-mode library"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in %v", want, err)
+		}
+	}
+
+	_, err = compiler.New(compiler.Config{Target: "simplicityhl"}).Compile("package main\n\nconst Limit uint32 = 5\n", "empty.go")
+	if err == nil || !strings.Contains(err.Error(), "a program needs func main()") {
+		t.Errorf("expected an error for a file without functions, got %v", err)
+	}
+
+	if _, err := compiler.New(compiler.Config{Target: "simplicityhl", Mode: "library"}).Compile(source, "paths.go"); err != nil {
+		t.Errorf("library mode needs no main: %v", err)
+	}
+}
+
+func TestEntryParameterWitnessCollision(t *testing.T) {
+	source := `
+package main
+
+import "simplicity/jet"
+
+const AlicePubkey = 0x9bef8d556d80e43ae7e0becb3f7de6b4e5e4f7e8d9a0b1c2d3e4f5a6b7c8d9e0
+
+func Spend(aliceSig [64]byte) {
+	var AliceSig [64]byte
+	jet.BIP340Verify(AlicePubkey, jet.SigAllHash(), AliceSig)
+}
+`
+	c := compiler.New(compiler.Config{Target: "simplicityhl", Entry: "Spend"})
+	_, err := c.Compile(source, "collision.go")
+	if err == nil || !strings.Contains(err.Error(), "witness ALICE_SIG, which is already declared") {
+		t.Errorf("expected witness collision error, got %v", err)
+	}
+}
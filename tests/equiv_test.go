@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/equiv"
+)
+
+const equivSource = `package main
+
+import "simplicity/jet"
+
+const Limit uint32 = 1000
+
+func below(x uint32) bool {
+	return x < Limit
+}
+
+func InRange(lo uint8, hi uint8, amount uint32, ok bool) bool {
+	jet.Verify(jet.Le8(lo, hi))
+	jet.Verify(below(amount))
+	jet.Verify(ok)
+	return amount >= 20
+}
+
+func Small(x uint8, flag bool) bool {
+	jet.Verify(flag)
+	return jet.Le8(x, 200)
+}
+
+func Index(n uint32) bool {
+	return jet.Le32(jet.CurrentIndex(), n)
+}
+
+func Hash(h [32]byte) bool {
+	return true
+}
+`
+
+func TestEquivalence(t *testing.T) {
+	report, err := equiv.Check(equivSource, "range.go", compiler.Config{Entry: "Small"}, equiv.Options{})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if !report.Exhaustive || report.Cases != 512 || len(report.Mismatches) != 0 {
+		t.Errorf("Small: %d cases, exhaustive %v, mismatches %v; want all 512 to agree", report.Cases, report.Exhaustive, report.Mismatches)
+	}
+
+	// Too many inputs to run them all: the boundaries and Limit are sampled.
+	report, err = equiv.Check(equivSource, "range.go", compiler.Config{Entry: "InRange"}, equiv.Options{MaxCases: 500})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if report.Exhaustive || report.Cases != 500 || len(report.Mismatches) != 0 {
+		t.Errorf("InRange: %d cases, exhaustive %v, mismatches %v; want a sample of 500 to agree", report.Cases, report.Exhaustive, report.Mismatches)
+	}
+
+	// A compile that moves the limit disagrees with the Go at the limit.
+	lowerLimit := func(file *ast.File, _ *token.FileSet) error {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if lit, ok := n.(*ast.BasicLit); ok && lit.Value == "1000" {
+				lit.Value = "999"
+			}
+			return true
+		})
+		return nil
+	}
+	config := compiler.Config{Entry: "InRange", PreTransforms: []func(*ast.File, *token.FileSet) error{lowerLimit}}
+	report, err = equiv.Check(equivSource, "range.go", config, equiv.Options{})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(report.Mismatches) == 0 {
+		t.Fatal("expected the moved limit to be found")
+	}
+	for _, m := range report.Mismatches {
+		if m.Inputs["AMOUNT"] != "999" || !m.GoAccepts || m.ProgramAccepts {
+			t.Errorf("unexpected mismatch %v", m)
+		}
+	}
+	if s := report.Mismatches[0].String(); !strings.Contains(s, "AMOUNT=999 HI=") || !strings.Contains(s, "Go accepts, program rejects (line ") {
+		t.Errorf("mismatch reads %q", s)
+	}
+
+	for entry, want := range map[string]string{
+		"Index": "range.go:24:18: jet.CurrentIndex is not supported",
+		"Hash":  "range.go:27:13: parameter of unsupported type",
+	} {
+		if _, err := equiv.Check(equivSource, "range.go", compiler.Config{Entry: entry, AllowTrivialMain: true}, equiv.Options{}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected %q, got %v", entry, want, err)
+		}
+	}
+}
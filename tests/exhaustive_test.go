@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/equiv"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+)
+
+func TestSwitchExhaustiveness(t *testing.T) {
+	const eitherSource = `package main
+
+import "simplicity/jet"
+
+type SpendWitness struct {
+	IsLeft bool
+	Owner  uint32
+	Other  uint32
+}
+
+func main() {
+	var w SpendWitness
+	switch {
+	CASES
+	}
+}
+`
+	const boolSource = `package main
+
+import "simplicity/jet"
+
+func main() {
+	var a uint32
+	ok := jet.Le32(3, a)
+	switch ok {
+	CASES
+	}
+}
+`
+	// The three-way Spend of spendPathsSource, selected by a switch.
+	pathSource := strings.NewReplacer(
+		"if w.Claim != nil {", "switch {\n\tcase w.Claim != nil:",
+		"} else if w.Refund != nil {", "case w.Refund != nil:",
+		"} else {", "case w.Cancel != nil:",
+	).Replace(spendPathsSource)
+
+	tests := []struct {
+		name, source, from, to string
+		want                   string // "" to compile
+	}{
+		{"either, both arms", eitherSource, "CASES", "case w.IsLeft:\n\t\tjet.Verify(jet.Le32(1, w.Owner))\n\tcase !w.IsLeft:\n\t\tjet.Verify(jet.Le32(2, w.Other))", ""},
+		{"either, a default", eitherSource, "CASES", "case !w.IsLeft:\n\t\tjet.Verify(jet.Le32(2, w.Other))\n\tdefault:\n\t\tjet.Verify(jet.Le32(1, w.Owner))", ""},
+		{"either, no Right", eitherSource, "CASES", "case w.IsLeft:\n\t\tjet.Verify(jet.Le32(1, w.Owner))",
+			"contract.go:13:2: switch on w has no case for its Right arm, !w.IsLeft, and no default"},
+		{"either, no Left", eitherSource, "CASES", "case !w.IsLeft:\n\t\tjet.Verify(jet.Le32(2, w.Other))",
+			"contract.go:13:2: switch on w has no case for its Left arm, w.IsLeft, and no default"},
+		{"paths, all cases", pathSource, "", "", ""},
+		{"paths, a default", pathSource, "case w.Cancel != nil:", "default:", ""},
+		{"paths, two missing", pathSource, "case w.Refund != nil:\n\t\tjet.CheckLockHeight(800000)\n\t\tjet.BIP340Verify(BobKey, jet.SigAllHash(), w.Refund.Sig)\n\tcase w.Cancel != nil:\n\t\tjet.BIP340Verify(AliceKey, jet.SigAllHash(), *w.Cancel)\n", "",
+			"contract.go:26:2: switch on the paths of w has no case for w.Refund, w.Cancel and no default"},
+		{"bool, both arms", boolSource, "CASES", "case true:\n\t\tjet.Verify(jet.Le32(1, a))\n\tcase false:\n\t\tjet.Verify(jet.Le32(a, 1))", ""},
+		{"bool, a default", boolSource, "CASES", "case false:\n\t\tjet.Verify(jet.Le32(a, 1))\n\tdefault:\n\t\tjet.Verify(jet.Le32(1, a))", ""},
+		{"bool, no false", boolSource, "CASES", "case true:\n\t\tjet.Verify(jet.Le32(1, a))",
+			"contract.go:8:2: switch on ok has no case for false and no default"},
+		{"bool, no true", boolSource, "CASES", "case false:\n\t\tjet.Verify(jet.Le32(a, 1))",
+			"contract.go:8:2: switch on ok has no case for true and no default"},
+		{"bool, not a constant", boolSource, "CASES", "case a > 1:\n\t\tjet.Verify(jet.Le32(a, 1))",
+			"contract.go:9:7: a case of a switch on the bool ok must be true or false, got a > 1"},
+		{"bool, a duplicate", boolSource, "CASES", "case true:\n\tcase !false:\n\t\tjet.Verify(jet.Le32(a, 1))",
+			"contract.go:10:7: duplicate case true in switch on ok"},
+	}
+	for _, tt := range tests {
+		source := strings.Replace(tt.source, tt.from, tt.to, 1)
+		result, err := compiler.New(compiler.Config{Target: "simplicityhl", SelfCheck: true}).Compile(source, "contract.go")
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%s: compile: %v", tt.name, err)
+			} else if !strings.Contains(result, "match ") {
+				t.Errorf("%s: no match in\n%s", tt.name, result)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestBoolSwitch(t *testing.T) {
+	source := "package main\n\nimport \"simplicity/jet\"\n\nfunc main() {\n\tvar a uint32\n\tok := jet.Le32(3, a)\n\tswitch ok {\n\tcase true:\n\t\tjet.Verify(jet.Le32(a, 9))\n\tdefault:\n\t\tjet.Verify(jet.Eq32(a, 1))\n\t}\n}\n"
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	if want := "    match ok {\n        true => {\n            assert!(jet::le_32(a, 9));\n        },\n        false => {\n            assert!(jet::eq_32(a, 1));\n        }\n    }"; !strings.Contains(result, want) {
+		t.Errorf("missing %q in\n%s", want, result)
+	}
+	prog, err := shlparse.Parse(result)
+	if err != nil {
+		t.Fatalf("generated program does not parse: %v", err)
+	}
+	for _, tt := range []struct {
+		a      string
+		accept bool
+	}{{"5", true}, {"10", false}, {"1", true}, {"2", false}} {
+		err := eval.Run(prog, eval.Options{Witness: map[string]string{"A": tt.a}})
+		var rejection *eval.Rejection
+		if tt.accept && err != nil || !tt.accept && !errors.As(err, &rejection) {
+			t.Errorf("a = %s: accept %v, got %v", tt.a, tt.accept, err)
+		}
+	}
+}
+
+// TestBoolSwitchResult checks that a switch on a bool whose cases return
+// is a helper's result, the return after it or the default the arm no
+// case matches, and agrees with the Go.
+func TestBoolSwitchResult(t *testing.T) {
+	source := `package main
+
+import "simplicity/jet"
+
+func One(a uint8, r bool) bool {
+	switch r {
+	case true:
+		return jet.Le8(a, 9)
+	}
+	return false
+}
+
+func Other(a uint8, r bool) bool {
+	b := jet.Le8(a, 5)
+	switch b {
+	case false:
+		return r
+	default:
+		return jet.Eq8(a, 1)
+	}
+}
+
+func main() {
+	var a uint8
+	var r bool
+	jet.Verify(One(a, r))
+	jet.Verify(Other(a, r))
+}
+`
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl", OptLevel: compiler.O0, Strict: true}).Compile(source, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"fn one(a: u8, r: bool) -> bool {\n    match r {\n        true => {\n            jet::le_8(a, 9)\n        },\n        false => {\n            false\n        }\n    }\n}",
+		"    match b {\n        true => {\n            jet::eq_8(a, 1)\n        },\n        false => {\n            r\n        }\n    }\n}",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+	for _, entry := range []string{"One", "Other"} {
+		report, err := equiv.Check(source, "contract.go", compiler.Config{Entry: entry}, equiv.Options{})
+		if err != nil {
+			t.Fatalf("%s: Check: %v", entry, err)
+		}
+		if len(report.Mismatches) != 0 {
+			t.Errorf("%s: mismatches %v in %d cases; want all to agree", entry, report.Mismatches, report.Cases)
+		}
+	}
+}
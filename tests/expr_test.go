@@ -0,0 +1,209 @@
+package tests
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/transpiler"
+)
+
+// fixtureSymbols is a SymbolTable over fixed symbols and package constants
+// keyed "pkg.Name".
+type fixtureSymbols struct {
+	symbols   map[string]transpiler.Symbol
+	qualified map[string]string
+}
+
+func (s fixtureSymbols) Lookup(name string) (transpiler.Symbol, bool) {
+	sym, ok := s.symbols[name]
+	return sym, ok
+}
+
+func (s fixtureSymbols) Qualified(pkg, name string) (string, bool) {
+	v, ok := s.qualified[pkg+"."+name]
+	return v, ok
+}
+
+var exprSymbols = fixtureSymbols{
+	symbols: map[string]transpiler.Symbol{
+		"sig":     {Kind: transpiler.SymbolWitness, Ref: "witness::SIG", Type: "[u8; 64]"},
+		"w":       {Kind: transpiler.SymbolWitness, Ref: "witness::W", Type: "Spend"},
+		"amount":  {Kind: transpiler.SymbolWitness, Ref: "witness::AMOUNT", Type: "u64"},
+		"height":  {Kind: transpiler.SymbolLocal, Ref: "height", Type: "u32"},
+		"sum":     {Kind: transpiler.SymbolLocal, Ref: "sum", Type: "(bool, u64)"},
+		"timeout": {Kind: transpiler.SymbolConstant, Ref: "param::TIMEOUT", Type: "u32"},
+	},
+	qualified: map[string]string{"checks.MinAmount": "1000"},
+}
+
+// exprHelpers are the helpers the folder may evaluate.
+const exprHelpers = `
+package main
+
+func double(n uint32) uint32 {
+	return n * 2
+}
+
+func clamp(n uint32) uint32 {
+	if n > 10 {
+		return 10
+	}
+	return n
+}
+
+func depth(n uint32) uint32 {
+	if n == 0 {
+		return 0
+	}
+	return depth(n-1) + 1
+}
+
+func capped(n uint32) bool {
+	return jet.Le32(n, 10)
+}
+`
+
+func newFolder(t *testing.T) *transpiler.Folder {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "helpers.go", exprHelpers, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	funcs := make(map[string]*ast.FuncDecl)
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok {
+			funcs[fn.Name.Name] = fn
+		}
+	}
+	f := transpiler.NewFolder(nil, funcs)
+	f.Define("limit", transpiler.Value{Type: "u32", Int: big.NewInt(15)})
+	return f
+}
+
+func parseExpr(t *testing.T, src string) ast.Expr {
+	t.Helper()
+	expr, err := parser.ParseExpr(src)
+	if err != nil {
+		t.Fatalf("parse %q: %v", src, err)
+	}
+	return expr
+}
+
+func TestFolder(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string // "" when the expression is not constant
+		typ  string
+	}{
+		{"1 + 2", "3", ""},
+		{"(7 - 2) * 3", "15", ""},
+		{"uint64(1) << 40", "1099511627776", "u64"},
+		{"uint8(200) + uint8(100)", "", ""}, // overflows u8
+		{"uint8(1) + uint16(1)", "", ""},    // mismatched types
+		{"2 - 5", "", ""},                   // negative
+		{"10 / 0", "", ""},
+		{"!(3 < 4)", "false", "bool"},
+		{"true && !false", "true", "bool"},
+		{"limit * 2", "30", "u32"},
+		{"double(21)", "42", "u32"},
+		{"clamp(double(limit))", "10", "u32"},
+		{"clamp(height)", "", ""},
+		{"depth(0)", "0", "u32"}, // The recursive call is not reached
+		{"depth(3)", "", ""},     // SimplicityHL has no recursion
+		{"capped(3)", "", ""},    // Jets run at spend time
+		{"amount + 1", "", ""},
+		{"jet.Le32(1, 2)", "", ""},
+	}
+	f := newFolder(t)
+	for _, tt := range tests {
+		v, ok := f.Fold(parseExpr(t, tt.expr))
+		if tt.want == "" {
+			if ok {
+				t.Errorf("Fold(%s) = %s, want not constant", tt.expr, v)
+			}
+			continue
+		}
+		if !ok || v.String() != tt.want || v.Type != tt.typ {
+			t.Errorf("Fold(%s) = %s (%q), %v; want %s (%q)", tt.expr, v, v.Type, ok, tt.want, tt.typ)
+		}
+	}
+
+	// Redefining limit refolds expressions that read it, however often
+	// they were folded before.
+	f.Define("limit", transpiler.Value{Type: "u32", Int: big.NewInt(4)})
+	if v, ok := f.Fold(parseExpr(t, "clamp(double(limit))")); !ok || v.String() != "8" {
+		t.Errorf("Fold(clamp(double(limit))) after redefining limit = %s, %v; want 8", v, ok)
+	}
+}
+
+func TestTranslator(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"sig", "witness::SIG"},
+		{"(sig)", "witness::SIG"},
+		{"height", "height"},
+		{"timeout", "param::TIMEOUT"},
+		{"recipientKey", "recipient_key"},
+		{"limit", "15"},
+		{"0xABcd", "0xabcd"},
+		{"w.Preimage", "witness::W.preimage"},
+		{"checks.MinAmount", "1000"},
+		{"checks.MinAmount * 2", "2000"},
+		// SimplicityHL has no index expression; a block destructures the array.
+		{"sig[3]", "{ let [_, _, _, element" + strings.Repeat(", _", 60) + "]: [u8; 64] = witness::SIG; element }"},
+		{"sig[limit - 14]", "{ let [_, element" + strings.Repeat(", _", 62) + "]: [u8; 64] = witness::SIG; element }"},
+		{"[2]uint32{limit, height}", "[15, height]"},
+		{"double(4) + 1", "9"},
+		{"!true", "false"},
+	}
+	tr := transpiler.NewTranslator(exprSymbols, newFolder(t))
+	for _, tt := range tests {
+		got, err := tr.Translate(parseExpr(t, tt.expr))
+		if err != nil {
+			t.Errorf("Translate(%s): %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Translate(%s) = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestTranslatorOperators(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string // "" when no jet is needed
+	}{
+		{"amount + 1", "{ let (_, sum): (bool, u64) = jet::add_64(witness::AMOUNT, 1); sum }"},
+		{"height > timeout", "jet::lt_32(param::TIMEOUT, height)"},
+		{"height >= limit", "jet::le_32(15, height)"},
+		{"sum == amount", "jet::eq_64(sum, witness::AMOUNT)"},
+		{"limit + 1", ""},
+		{"height != 1", ""}, // no jet for !=
+	}
+	tr := transpiler.NewTranslator(exprSymbols, newFolder(t))
+	for _, tt := range tests {
+		expr := parseExpr(t, tt.expr).(*ast.BinaryExpr)
+		call, ok := tr.OperatorCall(expr)
+		if tt.want == "" {
+			if ok {
+				t.Errorf("OperatorCall(%s) = %+v, want none", tt.expr, call)
+			}
+			continue
+		}
+		got, err := tr.TranslateArg(expr)
+		if !ok || err != nil || got != tt.want {
+			t.Errorf("TranslateArg(%s) = %s, %v; want %s", tt.expr, got, err, tt.want)
+		}
+	}
+
+	if typ := tr.TypeOf(parseExpr(t, "sum")); typ != "u64" {
+		t.Errorf("TypeOf(sum) = %s, want the carry-free u64", typ)
+	}
+}
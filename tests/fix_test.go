@@ -0,0 +1,163 @@
+package tests
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/fix"
+)
+
+// TestFixes applies each kind of fix-it and compiles the result.
+func TestFixes(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		safe   bool
+		code   diag.Code
+		want   []string // In the fixed source
+		absent []string // Not in the fixed source
+	}{
+		{
+			name: "fmt output",
+			source: `package main
+
+import (
+	"fmt"
+
+	"simplicity/jet"
+)
+
+func main() {
+	var amount uint64
+	fmt.Println("amount", amount)
+	jet.Verify(jet.Le64(amount, 100))
+}
+`,
+			safe:   true,
+			want:   []string{"import \"simplicity/jet\"\n\nfunc main", "\tvar amount uint64\n\tjet.Verify"},
+			absent: []string{"fmt"},
+		},
+		{
+			name: "byte slice parameter",
+			source: `package main
+
+import "simplicity/jet"
+
+func isZero(h []byte) bool {
+	return jet.Eq8(h[0], 1)
+}
+
+func main() {
+	var digest [32]byte
+	jet.Verify(isZero(digest[:]))
+}
+`,
+			safe: true,
+			code: diag.Slice,
+			want: []string{"func isZero(h [32]byte) bool", "jet.Verify(isZero(digest))"},
+		},
+		{
+			name: "signed int",
+			source: `package main
+
+import "simplicity/jet"
+
+func main() {
+	var count int
+	jet.Verify(jet.Le64(uint64(count), 3))
+}
+`,
+			code: diag.SignedInt,
+			want: []string{"var count uint64"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixes, err := fix.Find("contract.go", []byte(tt.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(fixes) == 0 {
+				t.Fatal("no fixes")
+			}
+			for _, f := range fixes {
+				if f.Safe != tt.safe || f.Code != tt.code {
+					t.Errorf("fix %q: safe %v, code %q; want %v, %q", f.Message, f.Safe, f.Code, tt.safe, tt.code)
+				}
+			}
+			out, err := fix.Apply([]byte(tt.source), fixes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fixed := string(out)
+			for _, want := range tt.want {
+				if !strings.Contains(fixed, want) {
+					t.Errorf("fixed source does not contain %q:\n%s", want, fixed)
+				}
+			}
+			for _, absent := range tt.absent {
+				if strings.Contains(fixed, absent) {
+					t.Errorf("fixed source contains %q:\n%s", absent, fixed)
+				}
+			}
+			if _, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(fixed, "contract.go"); err != nil {
+				t.Errorf("fixed source does not compile: %v\n%s", err, fixed)
+			}
+			if again, err := fix.Find("contract.go", out); err != nil || len(again) > 0 {
+				t.Errorf("fixed source has fixes %v, %v", again, err)
+			}
+		})
+	}
+}
+
+func TestFixesLeaveOtherCallsAlone(t *testing.T) {
+	// A call passing a whole slice, or an array of another size, keeps the
+	// parameter a slice.
+	source := `package main
+
+import "simplicity/jet"
+
+func first(h []byte) bool {
+	return jet.Eq8(h[0], 0)
+}
+
+func main() {
+	var a [32]byte
+	var b [64]byte
+	jet.Verify(first(a[:]))
+	jet.Verify(first(b[:]))
+}
+`
+	fixes, err := fix.Find("contract.go", []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixes) != 0 {
+		t.Errorf("fixes %v, want none", fixes)
+	}
+}
+
+func TestFixJSON(t *testing.T) {
+	source := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(1)\n}\n"
+	fixes, err := fix.Find("contract.go", []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(fixes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"file":"contract.go"`, `"pos":{"offset":43,"line":6,"column":2}`, `"safe":true`, `"new_text":""`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("JSON does not contain %s:\n%s", want, data)
+		}
+	}
+
+	_, err = fix.Find("contract.go", []byte("package main\n\nfunc main() {"))
+	if code, _ := diag.CodeOf(err); code != diag.SyntaxError {
+		t.Errorf("parse error %v, want %s", err, diag.SyntaxError)
+	}
+}
@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/equiv"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+)
+
+const branchAssignSource = `package main
+
+import "simplicity/jet"
+
+func Clamp(a uint8, b uint8) bool {
+	ok := true
+	x := uint8(0)
+	if a == 0 {
+		ok = false
+	}
+	if b > 3 {
+		x = b
+	}
+	return ok && jet.Le8(x, 5)
+}
+
+func Tier(a uint8, b uint8) bool {
+	fee := uint8(1)
+	if a < 10 {
+		fee = 3
+	} else if a < 100 {
+		fee = 2
+		fee += b
+	} else {
+		fee = a
+	}
+	return jet.Le8(fee, 120)
+}
+
+func Pick(a uint8, b uint8) bool {
+	var n uint8
+	switch a {
+	case 1, 2:
+		n = 7
+	case 3:
+		n = b
+	default:
+		n++
+	}
+	return jet.Le8(n, 3)
+}
+
+func Guards(a uint8, b uint8) bool {
+	if a > 0 {
+		if b == 0 {
+			return false
+		}
+	}
+	if a == 7 {
+		return false
+	}
+	return jet.Le8(b, 200)
+}
+
+func main() {
+	var a uint8
+	var b uint8
+	jet.Verify(Clamp(a, b))
+	jet.Verify(Tier(a, b))
+	jet.Verify(Pick(a, b))
+	jet.Verify(Guards(a, b))
+}
+`
+
+// TestBranchAssign checks that the reassignments of the branches of an if
+// chain or a switch are the matches on their conditions, and agree with
+// the Go, as do guards after an if that falls through.
+func TestBranchAssign(t *testing.T) {
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl", OptLevel: compiler.O0}).Compile(branchAssignSource, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"    let t_8_5: bool = jet::eq_8(a, 0);\n    let ok: bool = match t_8_5 { true => false, false => true, };\n",
+		"    let x: u8 = match t_11_5 { true => b, false => 0, };\n",
+		"    let t_24_9: bool = match t_21_12_2 { true => false, false => true, };\n    let fee: u8 = match t_24_9 { true => a, false => fee, };\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+
+	for _, entry := range []string{"Clamp", "Tier", "Pick", "Guards"} {
+		report, err := equiv.Check(branchAssignSource, "contract.go", compiler.Config{Entry: entry}, equiv.Options{})
+		if err != nil {
+			t.Fatalf("%s: Check: %v", entry, err)
+		}
+		if len(report.Mismatches) != 0 {
+			t.Errorf("%s: mismatches %v in %d cases; want all to agree", entry, report.Mismatches, report.Cases)
+		}
+	}
+}
+
+// TestBranchAssignInMain checks the reassignments of the branches of an
+// if in main, which strict mode accepts.
+func TestBranchAssignInMain(t *testing.T) {
+	source := `package main
+
+import "simplicity/jet"
+
+func main() {
+	var a uint8
+	var b uint8
+	ok := true
+	x := uint8(0)
+	if a == 0 {
+		ok = false
+	}
+	if b > 3 {
+		x = b
+	}
+	jet.Verify(ok)
+	jet.Verify(jet.Le8(x, 5))
+}
+`
+	for _, tt := range []struct {
+		a, b   string
+		accept bool
+	}{
+		{"1", "2", true},
+		{"1", "5", true},
+		{"0", "2", false},
+		{"1", "6", false},
+	} {
+		_, err := runSource(t, compiler.Config{Strict: true}, source, map[string]string{"A": tt.a, "B": tt.b})
+		var rejection *eval.Rejection
+		switch {
+		case tt.accept && err != nil:
+			t.Errorf("a %s, b %s was rejected: %v", tt.a, tt.b, err)
+		case !tt.accept && !errors.As(err, &rejection):
+			t.Errorf("a %s, b %s was accepted: %v", tt.a, tt.b, err)
+		}
+	}
+}
+
+// TestBranchAssignErrors checks that a branch whose reassignment cannot
+// be computed whether or not it is taken is an error, not left out.
+func TestBranchAssignErrors(t *testing.T) {
+	source := `package main
+
+import "simplicity/jet"
+
+func Share(a uint8, b uint8) bool {
+	x := uint8(0)
+	if a > 0 {
+		x = b / a
+	}
+	return jet.Le8(x, 5)
+}
+
+func main() {
+	var a uint8
+	var b uint8
+	jet.Verify(Share(a, b))
+}
+`
+	_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "share.go")
+	want := "share.go:8:3: the if statement, whose branches reassign variables, is not lowered: the value b / a divides"
+	if code, _ := diag.CodeOf(err); code != diag.UnsupportedSyntax || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected an error containing %q, got %v", want, err)
+	}
+}
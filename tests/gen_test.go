@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/gen"
+)
+
+// writeFixture creates a package directory containing the given files.
+func writeFixture(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+const genFileContract = `//simplicity:contract
+package contracts
+
+import "simplicity/jet"
+
+const OwnerPubkey = 0x9bef8d556d80e43ae7e0becb3f7de6b4e5e4f7e8d9a0b1c2d3e4f5a6b7c8d9e0
+
+func main() {
+	var sig [64]byte
+	jet.BIP340Verify(OwnerPubkey, jet.SigAllHash(), sig)
+}
+`
+
+const genFuncContracts = `package contracts
+
+import "simplicity/jet"
+
+const BobPubkey = 0xe37d58a1aae4ba05c9b2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4
+
+// Refund lets Bob reclaim the coins after a timeout.
+//
+//simplicity:contract
+func Refund() {
+	var bobSig [64]byte
+	jet.CheckLockHeight(1000)
+	jet.BIP340Verify(BobPubkey, jet.SigAllHash(), bobSig)
+}
+
+//simplicity:contract cooperative
+func CooperativeClose() {
+	var closeSig [64]byte
+	jet.BIP340Verify(BobPubkey, jet.SigAllHash(), closeSig)
+}
+`
+
+func TestGenDiscover(t *testing.T) {
+	dir := writeFixture(t, map[string]string{
+		"p2pk.go":    genFileContract,
+		"channel.go": genFuncContracts,
+		"plain.go":   "package contracts\n\nfunc Unrelated() {}\n",
+		"ignored.go": "//go:build ignore\n\n//simplicity:contract\npackage contracts\n\nfunc main() {}\n",
+	})
+
+	contracts, err := gen.Discover(dir, nil)
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+
+	want := map[string]string{"Refund": "Refund", "cooperative": "CooperativeClose", "p2pk": "main"}
+	if len(contracts) != len(want) {
+		t.Fatalf("expected %d contracts, got %+v", len(want), contracts)
+	}
+	for _, c := range contracts {
+		if want[c.Name] != c.Entry {
+			t.Errorf("contract %s: entry %q, want %q", c.Name, c.Entry, want[c.Name])
+		}
+	}
+
+	// Build tags select otherwise excluded files.
+	tagged, err := gen.Discover(dir, []string{"ignore"})
+	if err != nil {
+		t.Fatalf("Discover with tags: %v", err)
+	}
+	if len(tagged) != len(want)+1 {
+		t.Errorf("expected the ignore-tagged file to be included, got %+v", tagged)
+	}
+}
+
+func TestGenCollision(t *testing.T) {
+	dir := writeFixture(t, map[string]string{
+		"a.go": "package contracts\n\n//simplicity:contract\nfunc Refund() {}\n",
+		"b.go": "package contracts\n\n//simplicity:contract refund\nfunc Other() {}\n",
+	})
+
+	_, err := gen.Discover(dir, nil)
+	if err == nil {
+		t.Fatal("expected a collision error")
+	}
+	if !strings.Contains(err.Error(), "collides") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestGenGenerate(t *testing.T) {
+	dir := writeFixture(t, map[string]string{
+		"p2pk.go":    genFileContract,
+		"channel.go": genFuncContracts,
+	})
+	outDir := filepath.Join(dir, "out")
+
+	results, err := gen.Generate(dir, gen.Options{OutDir: outDir})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+
+	refund, err := os.ReadFile(filepath.Join(outDir, "Refund.simf"))
+	if err != nil {
+		t.Fatalf("read Refund.simf: %v", err)
+	}
+	for _, want := range []string{"const BOB_SIG: [u8; 64]", "jet::check_lock_height(1000);", "fn main() {"} {
+		if !strings.Contains(string(refund), want) {
+			t.Errorf("Refund.simf missing %q\n%s", want, refund)
+		}
+	}
+	// Each contract is rooted at its own function.
+	if strings.Contains(string(refund), "CLOSE_SIG") || strings.Contains(string(refund), "fn cooperative_close") {
+		t.Errorf("Refund.simf should not include the cooperative path\n%s", refund)
+	}
+
+	p2pk, err := os.ReadFile(filepath.Join(outDir, "p2pk.simf"))
+	if err != nil {
+		t.Fatalf("read p2pk.simf: %v", err)
+	}
+	if !strings.Contains(string(p2pk), "param::OWNER_PUBKEY") {
+		t.Errorf("p2pk.simf missing owner key\n%s", p2pk)
+	}
+}
@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+)
+
+const genericHelpers = `
+package main
+
+import "simplicity/jet"
+
+func Eq[T comparable](a, b T) bool {
+	return a == b
+}
+
+func Below[T ~uint32 | ~uint64](a, limit T) bool {
+	return a < limit
+}
+
+func First[T any](a, b T) T {
+	return a
+}
+
+func main() {
+	var amount uint64 = 5
+	var lock u256 = 7
+	var tag [32]byte
+	jet.Verify(Eq(amount, 5))
+	jet.Verify(Eq(5, amount))
+	jet.Verify(Eq[u256](lock, 7))
+	jet.Verify(Below(uint32(3), 4))
+	jet.Verify(jet.Eq256(First(lock, lock), lock))
+	jet.Verify(jet.Eq256(jet.SHA256Finalize(jet.SHA256Add32(jet.SHA256Init(), First(tag, tag))), lock))
+}
+`
+
+func TestGenericInstantiation(t *testing.T) {
+	c := compiler.New(compiler.Config{Target: "simplicityhl"})
+	out, err := c.Compile(genericHelpers, "generics.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"fn eq_u64(a: u64, b: u64) -> bool {\n    jet::eq_64(a, b)\n}",
+		"fn eq_u256(a: u256, b: u256) -> bool {\n    jet::eq_256(a, b)\n}",
+		"fn below_u32(a: u32, limit: u32) -> bool {\n    jet::lt_32(a, limit)\n}",
+		"fn first_u256(a: u256, b: u256) -> u256 {",
+		"fn first_bytes32(a: [u8; 32], b: [u8; 32]) -> [u8; 32] {",
+		"eq_u64(amount, 5)",
+		"eq_u64(5, amount)",
+		"eq_u256(lock, 7)",
+		"below_u32(3, 4)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+	// One specialization per type argument, however often it is called
+	if n := strings.Count(out, "fn eq_u64("); n != 1 {
+		t.Errorf("eq_u64 emitted %d times", n)
+	}
+	// The generic declaration itself is not emitted
+	if strings.Contains(out, "fn eq(") || strings.Contains(out, "fn first(") {
+		t.Errorf("generic declaration emitted:\n%s", out)
+	}
+}
+
+func TestGenericErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			"two type parameters",
+			"func Same[A, B comparable](a A, b B) bool {\n\treturn true\n}\n",
+			"generic.go:5:1: generic function Same has 2 type parameters; only one is supported",
+		},
+		{
+			"type parameters on a type",
+			"type Pair[T any] struct {\n\tA, B T\n}\n",
+			"generic.go:5:6: type Pair has type parameters; only functions may be generic",
+		},
+		{
+			"unsupported constraint",
+			"type Number interface {\n\t~uint64\n}\n\nfunc Eq[T Number](a, b T) bool {\n\treturn a == b\n}\n",
+			"interfaces are not supported",
+		},
+		{
+			"constraint of signed integers",
+			"func Eq[T ~int | ~uint64](a, b T) bool {\n\treturn a == b\n}\n",
+			"generic.go:5:11: generic function Eq: constraint ~int | ~uint64 is not supported",
+		},
+		{
+			"untyped arguments",
+			"func Eq[T comparable](a, b T) bool {\n\treturn a == b\n}\n\nfunc main() {\n\tjet.Verify(Eq(1, 2))\n}\n",
+			"generic.go:10:13: cannot infer T for Eq; instantiate it explicitly, as in Eq[uint64](...)",
+		},
+		{
+			"mismatched arguments",
+			"func Eq[T comparable](a, b T) bool {\n\treturn a == b\n}\n\nfunc main() {\n\tjet.Verify(Eq(uint64(1), uint32(2)))\n}\n",
+			"generic.go:10:27: type u32 of uint32(2) does not match inferred type u64 for T",
+		},
+		{
+			"unsupported type argument",
+			"func Eq[T comparable](a, b T) bool {\n\treturn a == b\n}\n\nfunc main() {\n\tjet.Verify(Eq[bool](true, true))\n}\n",
+			"generic.go:10:13: cannot instantiate Eq with bool",
+		},
+		{
+			"constraint not satisfied",
+			"func Below[T ~uint32 | ~uint64](a, limit T) bool {\n\treturn a < limit\n}\n\nfunc main() {\n\tjet.Verify(Below(uint8(1), 2))\n}\n",
+			"generic.go:10:13: u8 does not satisfy the constraint of Below (u32 | u64)",
+		},
+		{
+			"uncalled generic",
+			"func Spend[T any](a T) bool {\n\treturn true\n}\n\nfunc main() {}\n",
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "package main\n\nimport \"simplicity/jet\"\n\n" + tt.src
+			if !strings.Contains(src, "func main") {
+				src += "\nfunc main() {\n\tjet.Verify(true)\n}\n"
+			}
+			_, err := compiler.New(compiler.Config{Target: "simplicityhl", AllowTrivialMain: true}).Compile(src, "generic.go")
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	src := "package main\n\nfunc Spend[T any](a T) bool {\n\treturn true\n}\n"
+	_, err := compiler.New(compiler.Config{Target: "simplicityhl", Entry: "Spend"}).Compile(src, "entry.go")
+	if err == nil || !strings.Contains(err.Error(), "entry.go:3:1: entry function Spend must not be generic") {
+		t.Errorf("expected a generic entry error, got %v", err)
+	}
+}
+
+func TestImportedGeneric(t *testing.T) {
+	source := `
+package main
+
+import (
+	"simplicity/jet"
+
+	"example.com/contracts/checks"
+)
+
+func main() {
+	amount := jet.CurrentAmount()
+	jet.Verify(checks.AtLeast(amount, checks.MinAmount))
+	jet.Verify(checks.AtLeast[uint32](jet.CurrentIndex(), 1))
+}
+`
+	result, err := compileFixture(t, "generic.go", source)
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"fn checks_at_least_u64(v: u64, floor: u64) -> bool {\n    jet::le_64(floor, v)\n}",
+		"fn checks_at_least_u32(v: u32, floor: u32) -> bool {\n    jet::le_32(floor, v)\n}",
+		"checks_at_least_u64(amount, 1000)",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q\n%s", want, result)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package tests
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/std"
+)
+
+// The coinbase transaction of the genesis block, and its txid as block
+// explorers display it and as SHA-256 produces it.
+const (
+	genesisCoinbase = "01000000010000000000000000000000000000000000000000000000000000000000000000ffffffff4d04ffff001d0104455468652054696d65732030332f4a616e2f32303039204368616e63656c6c6f72206f6e206272696e6b206f66207365636f6e64206261696c6f757420666f722062616e6b73ffffffff0100f2052a01000000434104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac00000000"
+	genesisDisplay  = "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"
+	genesisRaw      = "3ba3edfd7a7b12b27ac72c3e67768f617fc81bc3888a51323a9fb8aa4b1e5e4a"
+)
+
+func TestHashFromHexGo(t *testing.T) {
+	tx, err := hex.DecodeString(genesisCoinbase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := sha256.Sum256(tx)
+	txid := sha256.Sum256(first[:])
+	if got := std.HashFromHexDisplay(genesisDisplay); got != txid {
+		t.Errorf("HashFromHexDisplay(%s) = %x, want the digest %x", genesisDisplay, got, txid)
+	}
+	if got := std.HashFromHexRaw("0x" + genesisRaw); got != txid {
+		t.Errorf("HashFromHexRaw(%s) = %x, want the digest %x", genesisRaw, got, txid)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("HashFromHexRaw accepted 62 hex digits")
+		}
+	}()
+	std.HashFromHexRaw(genesisRaw[2:])
+}
+
+const hashFromHexSource = `package main
+
+import (
+	"simplicity/jet"
+
+	"github.com/0ceanslim/go-simplicity/std"
+	"github.com/0ceanslim/go-simplicity/std/bitcoin"
+)
+
+const GenesisTxid = "` + genesisDisplay + `"
+
+func Spends(prev bitcoin.Hash) bool {
+	want := std.HashFromHexDisplay(GenesisTxid)
+	return jet.Eq256(prev, want)
+}
+
+func main() {
+	var prev bitcoin.Hash
+	jet.Verify(Spends(prev))
+	jet.Verify(jet.Eq256(prev, std.HashFromHexRaw("0x` + genesisRaw + `")))
+}
+`
+
+func TestHashFromHex(t *testing.T) {
+	c := compiler.New(compiler.Config{Target: "simplicityhl"})
+	result, err := c.Compile(hashFromHexSource, "main.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	// Both spellings of the txid fold to the digest in raw order.
+	for _, want := range []string{
+		"    let want = 0x" + genesisRaw + ";\n",
+		"    assert!(jet::eq_256(prev, 0x" + genesisRaw + "));\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+	for _, w := range c.Warnings() {
+		if strings.Contains(w, string(diag.HashByteOrder)) {
+			t.Errorf("unexpected warning: %s", w)
+		}
+	}
+}
+
+func TestHashFromHexErrors(t *testing.T) {
+	tests := []struct {
+		name, call, want string
+	}{
+		{"short", `std.HashFromHexRaw("` + genesisRaw[2:] + `")`, `main.go:11:48: std.HashFromHexRaw: "` + genesisRaw[2:] + `" is not a string of 64 hex digits`},
+		{"not hex", `std.HashFromHexDisplay("txid")`, `main.go:11:52: std.HashFromHexDisplay: "txid" is not a string of 64 hex digits`},
+		{"runtime", `std.HashFromHexRaw(prev)`, "main.go:11:48: std.HashFromHexRaw: prev is not a string of 64 hex digits"},
+		{"arguments", `std.HashFromHexRaw()`, "main.go:11:29: std.HashFromHexRaw takes one hex string, got 0 arguments"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "package main\n\nimport (\n\t\"simplicity/jet\"\n\n\t\"github.com/0ceanslim/go-simplicity/std\"\n)\n\nfunc main() {\n\tvar prev [32]byte\n\tjet.Verify(jet.Eq256(prev, " + tt.call + "))\n}\n"
+			_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(src, "main.go")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+const hashOrderSource = `package main
+
+import (
+	"simplicity/jet"
+
+	"github.com/0ceanslim/go-simplicity/std"
+	"github.com/0ceanslim/go-simplicity/std/bitcoin"
+)
+
+const Txid = 0x` + genesisDisplay + `
+
+const TxidHex = "` + genesisDisplay + `"
+
+const Key = 0x79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798
+
+func Spends(prev bitcoin.Hash) bool {
+	return jet.Eq256(prev, std.HashFromHexDisplay("` + genesisDisplay + `"))
+}
+
+func Prev() bitcoin.Hash {
+	return Txid
+}
+
+func main() {
+	var prev bitcoin.Hash = TxidHex
+	var sig [64]byte
+	jet.Verify(Spends(Txid))
+	jet.Verify(Spends(prev))
+	jet.Verify(Spends(bitcoin.Hash(0x` + genesisDisplay + `)))
+	jet.Verify(jet.Eq256(Prev(), Key))
+	jet.BIP340Verify(Key, jet.SigAllHash(), sig)
+}
+`
+
+func TestHashByteOrder(t *testing.T) {
+	c := compiler.New(compiler.Config{Target: "simplicityhl"})
+	if _, err := c.Compile(hashOrderSource, "main.go"); err != nil {
+		t.Fatal(err)
+	}
+	// Key is a key, never given to a bitcoin.Hash, and a bare hex compared
+	// with a hash is not given to one either.
+	want := []string{
+		"main.go:21:9: constant Txid is given to the result of Prev, a bitcoin.Hash, without its byte order: ",
+		"main.go:25:26: constant TxidHex is given to prev, a bitcoin.Hash, without its byte order: ",
+		"main.go:27:20: constant Txid is given to parameter prev of Spends, a bitcoin.Hash, without its byte order: ",
+		"main.go:29:33: the hex 4a5e…a33b is given to the conversion, a bitcoin.Hash, without its byte order: ",
+	}
+	var got []string
+	for _, w := range c.Warnings() {
+		if strings.Contains(w, "["+string(diag.HashByteOrder)+"]") {
+			got = append(got, w)
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d byte order warnings, got %q", len(want), got)
+	}
+	for i, w := range got {
+		if !strings.HasPrefix(w, want[i]) {
+			t.Errorf("warning %d: expected a prefix %q, got %q", i, want[i], w)
+		}
+	}
+	fix := `Write std.HashFromHexRaw("` + genesisDisplay + `") for a raw digest, or std.HashFromHexDisplay("` + genesisDisplay + `") for a txid or block hash in display order`
+	if !strings.Contains(got[0], "display txids and block hashes byte-reversed from the raw digest") || !strings.Contains(got[0], fix) {
+		t.Errorf("the warning does not explain the byte orders: %s", got[0])
+	}
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+	"github.com/0ceanslim/go-simplicity/pkg/report"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+	"github.com/0ceanslim/go-simplicity/pkg/testkeys"
+)
+
+func TestHTLCSpendPaths(t *testing.T) {
+	prog, err := shlparse.Parse(compileGolden(t, "htlc"))
+	if err != nil {
+		t.Fatalf("generated program does not parse: %v", err)
+	}
+	txJSON := string(readExample(t, "htlc.tx.json"))
+	claim, err := eval.ParseWitnessJSON(readExample(t, "htlc.witness.json"))
+	if err != nil {
+		t.Fatalf("ParseWitnessJSON: %v", err)
+	}
+	wrongPreimage := map[string]string{"W": strings.Replace(claim["W"], "0x4242", "0x4243", 1)}
+	refund := map[string]string{"W": "Right(0x" + strings.ToLower(testkeys.Vector0Sig) + ")"}
+	refundTx := func(locktime string) func(string) string {
+		return func(s string) string {
+			s = strings.Replace(s, `"locktime": 0`, `"locktime": `+locktime, 1)
+			return strings.Replace(s, strings.ToLower(testkeys.Vector1Msg), testkeys.Vector0Msg, 1)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		witness map[string]string
+		edit    func(string) string
+		reject  string // "" to accept
+	}{
+		{"claim", claim, nil, ""},
+		{"claim with the wrong preimage", wrongPreimage, nil, "assertion failed"},
+		{"claim signed for another transaction", claim, refundTx("0"), "bip_0340_verify"},
+		{"refund at the lock height", refund, refundTx("800000"), ""},
+		{"refund before the lock height", refund, refundTx("799999"), "check_lock_height"},
+	}
+	for _, tt := range tests {
+		data := txJSON
+		if tt.edit != nil {
+			data = tt.edit(data)
+		}
+		tx, err := eval.ParseTx([]byte(data))
+		if err != nil {
+			t.Fatalf("%s: ParseTx: %v", tt.name, err)
+		}
+		err = eval.Run(prog, eval.Options{Witness: tt.witness, Tx: tx})
+		if tt.reject == "" {
+			if err != nil {
+				t.Errorf("%s: expected accept, got %v", tt.name, err)
+			}
+			continue
+		}
+		var rejection *eval.Rejection
+		if !errors.As(err, &rejection) || !strings.Contains(rejection.Reason, tt.reject) {
+			t.Errorf("%s: expected %s to reject, got %v", tt.name, tt.reject, err)
+		}
+	}
+}
+
+func TestHTLCReportWitnessLayout(t *testing.T) {
+	c := compiler.New(compiler.Config{Target: "simplicityhl"})
+	if _, err := c.Compile(string(readExample(t, "htlc.go")), "htlc.go"); err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := report.New("htlc.go", c.Result()).Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	r, err := report.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	witnesses := r.Programs[0].Witnesses
+	if len(witnesses) != 1 || witnesses[0].Name != "W" {
+		t.Fatalf("witnesses = %+v, want the single Either W", witnesses)
+	}
+	w := witnesses[0]
+	if w.Selector != "is_left" {
+		t.Errorf("selector = %q, want is_left", w.Selector)
+	}
+	want := []report.Field{
+		{Name: "preimage", Type: "[u8; 32]", Branch: "Left"},
+		{Name: "recipient_sig", Type: "[u8; 64]", Branch: "Left"},
+		{Name: "sender_sig", Type: "[u8; 64]", Branch: "Right"},
+	}
+	if len(w.Fields) != len(want) {
+		t.Fatalf("fields = %+v, want %+v", w.Fields, want)
+	}
+	for i := range want {
+		if w.Fields[i] != want[i] {
+			t.Errorf("field %d = %+v, want %+v", i, w.Fields[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+)
+
+// compileFixture compiles source as if it lived in the testdata/imports module.
+func compileFixture(t *testing.T, name, source string) (string, error) {
+	t.Helper()
+	c := compiler.New(compiler.Config{Target: "simplicityhl"})
+	return c.Compile(source, filepath.Join("testdata", "imports", name))
+}
+
+func TestCrossPackageImport(t *testing.T) {
+	path := filepath.Join("testdata", "imports", "payout.go")
+	source, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	result, err := compileFixture(t, "payout.go", string(source))
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"fn checks_amount_ok(amount: u64) -> bool {\n    jet::le_64(1000, amount)\n}",
+		"fn checks_same_key(a: [u8; 32], b: [u8; 32]) -> bool {",
+		"assert!(jet::le_64(1000, amount));",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q\n%s", want, result)
+		}
+	}
+	// Package constants are inlined rather than added to mod param.
+	if strings.Contains(result, "MIN_AMOUNT") {
+		t.Errorf("imported constant leaked into the param module\n%s", result)
+	}
+}
+
+func TestCrossPackageImportAlias(t *testing.T) {
+	source := `
+package main
+
+import (
+	"simplicity/jet"
+
+	c "example.com/contracts/checks"
+)
+
+func main() {
+	amount := jet.CurrentAmount()
+	jet.Verify(c.AmountOk(amount))
+}
+`
+	result, err := compileFixture(t, "alias.go", source)
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	if !strings.Contains(result, "fn checks_amount_ok(") || !strings.Contains(result, "assert!(jet::le_64(1000, amount));") {
+		t.Errorf("aliased import should resolve to the checks package\n%s", result)
+	}
+}
+
+func TestCrossPackageImportMustBePure(t *testing.T) {
+	source := `
+package main
+
+import "example.com/contracts/impure"
+
+func main() {
+	impure.Bump()
+}
+`
+	_, err := compileFixture(t, "bad.go", source)
+	if err == nil {
+		t.Fatal("expected purity error")
+	}
+	if !strings.Contains(err.Error(), "imported package example.com/contracts/impure is not pure") ||
+		!strings.Contains(err.Error(), "package-level variables") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// Problems of the package's own files are positioned in them
+	if !strings.Contains(err.Error(), "impure.go:13:20: slices are not supported") {
+		t.Errorf("slice parameter not reported in impure.go: %v", err)
+	}
+}
+
+func TestJetImportAlias(t *testing.T) {
+	plain := `
+package main
+
+import "simplicity/jet"
+
+func main() {
+	var pubkey [32]byte
+	var sig [64]byte
+	msg := jet.SigAllHash()
+	jet.BIP340Verify(pubkey, msg, sig)
+	amount := jet.CurrentAmount()
+	jet.Verify(jet.Le64(1000, amount))
+}
+`
+	aliased := strings.NewReplacer(`import "simplicity/jet"`, `import j "simplicity/jet"`, "jet.", "j.").Replace(plain)
+	want, err := compileFixture(t, "plain.go", plain)
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	got, err := compileFixture(t, "alias.go", aliased)
+	if err != nil {
+		t.Fatalf("Compilation with an aliased jet import failed: %v", err)
+	}
+	if got != want {
+		t.Errorf("aliased jet import compiled differently:\n%s\nwant:\n%s", got, want)
+	}
+
+	// The alias, not the spelling jet, names the package. A user package
+	// may take the freed name.
+	shadowed := `
+package main
+
+import (
+	j "simplicity/jet"
+
+	jet "example.com/contracts/checks"
+)
+
+func main() {
+	amount := j.CurrentAmount()
+	j.Verify(jet.AmountOk(amount))
+}
+`
+	result, err := compileFixture(t, "shadowed.go", shadowed)
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	if !strings.Contains(result, "fn checks_amount_ok(") || !strings.Contains(result, "assert!(jet::le_64(1000, amount));") {
+		t.Errorf("jet should name the checks package\n%s", result)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		name, imports, body, want string
+	}{
+		{"dot jet", `. "simplicity/jet"`, "Verify(true)", `dot.go:4:2: dot import of simplicity/jet is not supported: it makes selector resolution ambiguous; import the package by name, as in import j "simplicity/jet"`},
+		{"dot package", `. "example.com/contracts/checks"`, "_ = AmountOk(5)", "dot import of example.com/contracts/checks is not supported"},
+		{"unknown builtin", `"simplicity/hash"`, "", "dot.go:4:2: unknown compiler package simplicity/hash; the compiler provides github.com/0ceanslim/go-simplicity/std, github.com/0ceanslim/go-simplicity/std/bitcoin, simplicity/jet"},
+		{"jet not imported", `j "simplicity/jet"`, "jet.Verify(j.Le64(1, 2))", "dot.go:8:2: jet.Verify: the file does not import simplicity/jet under the name jet"},
+	}
+	for _, tt := range tests {
+		source := "package main\n\nimport (\n\t" + tt.imports + "\n)\n\nfunc main() {\n\t" + tt.body + "\n}\n"
+		_, err := compileFixture(t, "dot.go", source)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestBlankImports(t *testing.T) {
+	source := `
+package main
+
+import (
+	"simplicity/jet"
+
+	_ "example.com/contracts/impure"
+)
+
+func main() {
+	var a uint64
+	jet.Verify(jet.Le64(1, a))
+}
+`
+	result, err := compileFixture(t, "blank.go", source)
+	if err != nil {
+		t.Fatalf("a blank import should not be loaded or compiled: %v", err)
+	}
+	if strings.Contains(result, "impure_") {
+		t.Errorf("blank import emitted functions\n%s", result)
+	}
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+)
+
+// sharedChecks is a library compiled once and included by programs.
+const sharedChecks = `package checks
+
+import "simplicity/jet"
+
+const Floor uint64 = 1000
+
+func MinAmount(x uint64) bool {
+	return jet.Le64(Floor, x)
+}
+
+func Double(x uint32) uint32 {
+	return x + x
+}
+`
+
+const includingProgram = `package main
+
+import "simplicity/jet"
+
+// minAmount is defined by the included library.
+func minAmount(x uint64) bool
+
+func double(x uint32) uint32
+
+func main() {
+	var amount uint64
+	var n uint32
+	jet.Verify(minAmount(amount))
+	jet.Verify(jet.Eq32(double(n), 8))
+}
+`
+
+// compileLibrary compiles sharedChecks in library mode, as simgo -mode
+// library writes it.
+func compileLibrary(t *testing.T) compiler.Include {
+	t.Helper()
+	lib, err := compiler.New(compiler.Config{Target: "simplicityhl", Mode: "library"}).Compile(sharedChecks, "checks.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return compiler.Include{Path: "checks.simf", Source: "// Code generated by simgo from checks.go. DO NOT EDIT.\n" + lib}
+}
+
+func TestInclude(t *testing.T) {
+	lib := compileLibrary(t)
+	for _, level := range []compiler.OptLevel{compiler.O0, compiler.O1, compiler.O2} {
+		t.Run(level.String(), func(t *testing.T) {
+			c := compiler.New(compiler.Config{Target: "simplicityhl", OptLevel: level, SelfCheck: true, Includes: []compiler.Include{lib}})
+			out, err := c.Compile(includingProgram, "main.go")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range []string{"// Included from checks.simf\nfn min_amount(x: u64) -> bool {", "fn double(x: u32) -> u32", "assert!(min_amount(witness::AMOUNT));", "jet::eq_32(double(witness::N), 8)"} {
+				if !strings.Contains(out, want) {
+					t.Errorf("expected %q in\n%s", want, out)
+				}
+			}
+			if strings.Contains(out, "DO NOT EDIT") || strings.Count(out, "fn double") != 1 {
+				t.Errorf("expected the library once, without its header, in\n%s", out)
+			}
+			// Lines of main still map to the Go they come from.
+			if pos, ok := c.SourcePosition(lineOf(out, "assert!(min_amount")); !ok || pos.Line != 13 {
+				t.Errorf("assert!(min_amount(...)) maps to %v, want main.go:13", pos)
+			}
+		})
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+	lib := compileLibrary(t)
+	tests := []struct {
+		name     string
+		source   string
+		includes []compiler.Include
+		code     diag.Code
+		want     string
+	}{
+		{"signature mismatch", strings.Replace(includingProgram, "func double(x uint32) uint32", "func double(x uint64) uint32", 1), []compiler.Include{lib},
+			diag.IncludeMismatch, "main.go:8:1: double is declared as fn double(u64) -> u32, but checks.simf defines fn double(u32) -> u32"},
+		{"result mismatch", strings.Replace(includingProgram, "func minAmount(x uint64) bool", "func minAmount(x uint64)", 1), []compiler.Include{lib},
+			diag.IncludeMismatch, "minAmount is declared as fn min_amount(u64), but checks.simf defines fn min_amount(u64) -> bool"},
+		{"not included", includingProgram, nil,
+			diag.IncludeMismatch, "minAmount has no body, and no included module defines fn min_amount"},
+		{"defined twice", strings.Replace(includingProgram, "func double(x uint32) uint32", "func double(x uint32) uint32 { return x + x }", 1), []compiler.Include{lib},
+			diag.IncludeMismatch, "double compiles to fn double, which checks.simf defines as well"},
+		{"program included", includingProgram, []compiler.Include{{Path: "p2pk.simf", Source: "fn main() {\n    assert!(true);\n}\n"}},
+			diag.InvalidConfig, "include p2pk.simf: defines main"},
+		{"included twice", includingProgram, []compiler.Include{lib, lib},
+			diag.InvalidConfig, "fn min_amount is defined by checks.simf as well"},
+		{"unparsable", includingProgram, []compiler.Include{{Path: "bad.simf", Source: "fn ("}},
+			diag.InvalidConfig, "include bad.simf:"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := compiler.New(compiler.Config{Target: "simplicityhl", Includes: tc.includes}).Compile(tc.source, "main.go")
+			if code, _ := diag.CodeOf(err); code != tc.code || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected %s error containing %q, got %v", tc.code, tc.want, err)
+			}
+		})
+	}
+}
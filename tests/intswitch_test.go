@@ -0,0 +1,193 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+)
+
+const intSwitchSource = `package main
+
+import "simplicity/jet"
+
+func MinFee(tier uint8) uint64 {
+	switch tier {
+	case 1:
+		return 1000
+	case 2, 3:
+		return 5000
+	}
+	return 20000
+}
+
+func main() {
+	var tier uint8
+	var fee uint64
+	switch tier {
+	case 0:
+		jet.Verify(jet.Eq64(fee, 0))
+	case 4:
+	default:
+		jet.Verify(jet.Le64(MinFee(tier), fee))
+	}
+}
+`
+
+func TestIntSwitch(t *testing.T) {
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl", OptLevel: compiler.O0}).Compile(intSwitchSource, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		// Each value is compared in the order Go tries them, the next in
+		// the false arm, and the return after the switch is the last.
+		"    match jet::eq_8(tier, 1) {\n" +
+			"        true => {\n" +
+			"            1000\n" +
+			"        },\n" +
+			"        false => {\n" +
+			"            match jet::eq_8(tier, 2) {\n",
+		"                    match jet::eq_8(tier, 3) {\n" +
+			"                        true => {\n" +
+			"                            5000\n" +
+			"                        },\n" +
+			"                        false => {\n" +
+			"                            20000\n" +
+			"                        }\n",
+		// An empty case does nothing.
+		"            match jet::eq_8(witness::TIER, 4) {\n" +
+			"                true => {\n" +
+			"                    ();\n" +
+			"                },\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+}
+
+func TestIntSwitchEquivalence(t *testing.T) {
+	minFee := func(tier int) uint64 {
+		switch tier {
+		case 1:
+			return 1000
+		case 2, 3:
+			return 5000
+		}
+		return 20000
+	}
+	for _, level := range []compiler.OptLevel{compiler.O0, compiler.O2} {
+		for tier := 0; tier < 6; tier++ {
+			for _, fee := range []uint64{0, 999, 1000, 5000, 20000} {
+				accept := tier == 4 || tier == 0 && fee == 0 || tier != 0 && fee >= minFee(tier)
+				witness := map[string]string{"TIER": fmt.Sprint(tier), "FEE": fmt.Sprint(fee)}
+				_, err := runSource(t, compiler.Config{OptLevel: level}, intSwitchSource, witness)
+				var rejection *eval.Rejection
+				switch {
+				case accept && err != nil:
+					t.Errorf("%v: tier %d with fee %d was rejected: %v", level, tier, fee, err)
+				case !accept && !errors.As(err, &rejection):
+					t.Errorf("%v: tier %d with fee %d was accepted: %v", level, tier, fee, err)
+				}
+			}
+		}
+	}
+}
+
+const namedSwitchSource = `package main
+
+import "simplicity/jet"
+
+type Kind uint8
+
+const (
+	Plain Kind = iota
+	Fast
+	Urgent
+)
+
+func Known(k Kind) bool {
+	switch k {
+	case Plain:
+		return false
+	case Fast, Urgent:
+		return true
+	}
+	return false
+}
+
+func main() {
+	var kind Kind
+	var fee uint64
+	jet.Verify(Known(kind))
+	switch kind {
+	case Fast:
+		jet.Verify(jet.Le64(1000, fee))
+	case Urgent:
+		jet.Verify(jet.Le64(5000, fee))
+	}
+}
+`
+
+func TestIntSwitchNamedType(t *testing.T) {
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl", OptLevel: compiler.O0}).Compile(namedSwitchSource, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	// Kind is its underlying u8, and the constants of the enumeration
+	// are the values iota gives them.
+	for _, want := range []string{
+		"const KIND: u8 = 0x00;",
+		"const PLAIN: u8 = 0;\n    const FAST: u8 = 1;\n    const URGENT: u8 = 2;",
+		"fn known(k: u8) -> bool {\n    match jet::eq_8(k, 0) {",
+		"match jet::eq_8(witness::KIND, 1) {",
+		"match jet::eq_8(witness::KIND, 2) {",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+	if strings.Contains(result, "Kind") {
+		t.Errorf("the named type is left in\n%s", result)
+	}
+
+	for _, level := range []compiler.OptLevel{compiler.O0, compiler.O2} {
+		for kind := 0; kind < 4; kind++ {
+			for _, fee := range []uint64{0, 1000, 5000} {
+				accept := kind == 1 && fee >= 1000 || kind == 2 && fee >= 5000
+				witness := map[string]string{"KIND": fmt.Sprint(kind), "FEE": fmt.Sprint(fee)}
+				_, err := runSource(t, compiler.Config{OptLevel: level}, namedSwitchSource, witness)
+				var rejection *eval.Rejection
+				switch {
+				case accept && err != nil:
+					t.Errorf("%v: kind %d with fee %d was rejected: %v", level, kind, fee, err)
+				case !accept && !errors.As(err, &rejection):
+					t.Errorf("%v: kind %d with fee %d was accepted: %v", level, kind, fee, err)
+				}
+			}
+		}
+	}
+}
+
+func TestIntSwitchErrors(t *testing.T) {
+	tests := []struct {
+		name, cases, want string
+	}{
+		{"not constant", "case fee:", "main.go:9:7: a case of a switch on the u8 tier must be a constant u8, got fee"},
+		{"too wide", "case 256:", "main.go:9:7: a case of a switch on the u8 tier must be a constant u8, got 256"},
+		{"duplicate", "case 1, 2, 1:", "main.go:9:13: duplicate case 1 in switch on tier"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "package main\n\nimport \"simplicity/jet\"\n\nfunc main() {\n\tvar tier uint8\n\tvar fee uint8\n\tswitch tier {\n\t" + tt.cases + "\n\t\tjet.Verify(jet.Eq8(fee, 0))\n\t}\n}\n"
+			_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(src, "main.go")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+)
+
+const isZeroSource = `package main
+
+import (
+	"simplicity/jet"
+
+	"github.com/0ceanslim/go-simplicity/std"
+)
+
+func NonZero(k [32]byte) bool {
+	for i := 0; i < 32; i++ {
+		if k[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func Zero4(tag [4]byte) bool {
+	for i := 0; i < 4; i++ {
+		if tag[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func main() {
+	var hash [20]byte
+	var key [32]byte
+	var sig [64]byte
+	var tag [4]byte
+	jet.Verify(std.IsZero20(hash))
+	jet.Verify(NonZero(key))
+	jet.Verify(Zero4(tag))
+	zero := std.IsZero64(sig)
+	jet.Verify(zero)
+}
+`
+
+func TestIsZero(t *testing.T) {
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(isZeroSource, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"fn std_is_zero_32(x: [u8; 32]) -> bool {\n    jet::eq_256(<[u8; 32]>::into(x), 0)\n}",
+		// 20 bytes split into a u128 and a u32.
+		"    let part_1: u32 = <[u8; 4]>::into([x_16, x_17, x_18, x_19]);\n" +
+			"    match jet::eq_128(part_0, 0) {\n" +
+			"        true => jet::eq_32(part_1, 0),\n" +
+			"        false => false,\n" +
+			"    }\n",
+		"    match std_is_zero_32(k) { true => false, false => true, }\n",
+		"    let zero: bool = std_is_zero_64(witness::SIG);\n",
+		// No helper takes 4 bytes, so the loop is unrolled.
+		"    let [tag_0, tag_1, tag_2, tag_3]: [u8; 4] = tag;\n",
+		"jet::eq_8(tag_3, 0)",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+
+	zeros := func(n int) string { return "0x" + strings.Repeat("00", n) }
+	last := func(n int) string { return "0x" + strings.Repeat("00", n-1) + "01" }
+	first := func(n int) string { return "0x80" + strings.Repeat("00", n-1) }
+	for _, tt := range []struct {
+		name                string
+		hash, key, sig, tag string
+		accept              bool
+	}{
+		{"all hold", zeros(20), last(32), zeros(64), zeros(4), true},
+		{"hash low part", last(20), last(32), zeros(64), zeros(4), false},
+		{"hash high part", first(20), last(32), zeros(64), zeros(4), false},
+		{"key zero", zeros(20), zeros(32), zeros(64), zeros(4), false},
+		{"key high byte", zeros(20), first(32), zeros(64), zeros(4), true},
+		{"sig low half", zeros(20), last(32), last(64), zeros(4), false},
+		{"sig high half", zeros(20), last(32), first(64), zeros(4), false},
+		{"tag last byte", zeros(20), last(32), zeros(64), last(4), false},
+		{"tag first byte", zeros(20), last(32), zeros(64), first(4), false},
+		{"tag middle byte", zeros(20), last(32), zeros(64), "0x00100000", false},
+	} {
+		_, err := runSource(t, compiler.Config{}, isZeroSource, map[string]string{"HASH": tt.hash, "KEY": tt.key, "SIG": tt.sig, "TAG": tt.tag})
+		var rejection *eval.Rejection
+		if tt.accept && err != nil || !tt.accept && !errors.As(err, &rejection) {
+			t.Errorf("%s: accept %v, got %v", tt.name, tt.accept, err)
+		}
+	}
+}
+
+func TestZeroLoopHints(t *testing.T) {
+	for _, tt := range []struct {
+		name, loop, want string
+	}{
+		{"partial", "for i := 0; i < 16; i++ {\n\t\tif k[i] != 0 {\n\t\t\treturn false\n\t\t}\n\t}\n\treturn true",
+			"contract.go:6:2: this loop tests whether k is all zeros, which is not lowered: std.IsZero20, std.IsZero32 and std.IsZero64"},
+		{"range", "for _, b := range k {\n\t\tif b != 0 {\n\t\t\treturn false\n\t\t}\n\t}\n\treturn true",
+			"but this one ranges over k; to test whether a byte array is all zeros, std.IsZero20"},
+	} {
+		source := "package main\n\nimport \"simplicity/jet\"\n\nfunc Zero(k [32]byte) bool {\n\t" + tt.loop + "\n}\n\nfunc main() {\n\tvar k [32]byte\n\tjet.Verify(Zero(k))\n}\n"
+		_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "contract.go")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/jets"
+	"github.com/0ceanslim/go-simplicity/pkg/types"
+)
+
+// TestJetStubs checks that std/jets is what go generate writes from the jet
+// table, with a stub for every jet and a jet for every stub.
+func TestJetStubs(t *testing.T) {
+	path := filepath.Join("..", "std", "jets", "stubs.go")
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want bytes.Buffer
+	if err := jets.WriteStubs(&want); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want.Bytes()) {
+		t.Errorf("std/jets/stubs.go is out of date; regenerate it with go generate ./std/jets")
+	}
+
+	file, err := parser.ParseFile(token.NewFileSet(), path, got, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := jets.NewRegistry()
+	stubs := make(map[string]bool)
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		stubs[fn.Name.Name] = true
+		info, ok := registry.Lookup(fn.Name.Name)
+		if !ok {
+			t.Errorf("stub %s has no entry in the jet table", fn.Name.Name)
+			continue
+		}
+		if n := fn.Type.Params.NumFields(); n != len(info.ParamTypes) {
+			t.Errorf("stub %s takes %d parameters, the table %d", fn.Name.Name, n, len(info.ParamTypes))
+		}
+	}
+	for name := range registry.AllJets() {
+		if !stubs[name] {
+			t.Errorf("jet %s has no stub in std/jets", name)
+		}
+	}
+}
+
+func TestJetTable(t *testing.T) {
+	registry := jets.NewRegistry()
+	info, ok := registry.LookupJet("sha_256_ctx_8_add_32")
+	if !ok || info.GoName != "SHA256Add32" {
+		t.Fatalf("LookupJet(sha_256_ctx_8_add_32) = %+v, %v", info, ok)
+	}
+	source, target := info.Signature()
+	if want, _ := types.Parse("(Ctx8, [u8; 32])"); !source.Equal(want) || target.String() != "Ctx8" {
+		t.Errorf("signature %s -> %s", source, target)
+	}
+	info, _ = registry.Lookup("SigAllHash")
+	if source, target := info.Signature(); source.String() != "()" || target.String() != "u256" {
+		t.Errorf("SigAllHash signature %s -> %s", source, target)
+	}
+	info, _ = registry.Lookup("OutputAmount")
+	if _, target := info.Signature(); target.String() != "Option<(Asset1, Amount1)>" {
+		t.Errorf("OutputAmount result %s, want the Option the jet returns", target)
+	}
+	info, _ = registry.Lookup("BIP340Verify")
+	if info.Name("latest") != "bip_0340_verify" || info.Name("simfony-0.3") != "bip0340_verify" {
+		t.Errorf("BIP340Verify names: %s, %s", info.Name("latest"), info.Name("simfony-0.3"))
+	}
+}
+
+// TestEmittedJetsInTable checks that every jet the golden outputs call,
+// including those of the helpers the transpiler writes, is in the jet table.
+func TestEmittedJetsInTable(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "O0", "*.simf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := jets.NewRegistry()
+	ref := regexp.MustCompile(`\bjet::([a-z0-9_]+)`)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range ref.FindAllStringSubmatch(string(data), -1) {
+			if _, ok := registry.LookupJet(m[1]); !ok {
+				t.Errorf("%s calls jet::%s, which is not in the jet table", filepath.Base(path), m[1])
+			}
+		}
+	}
+}
@@ -74,8 +74,9 @@ func main() {
 `
 
 	c := compiler.New(compiler.Config{
-		Target: "simplicityhl",
-		Debug:  false,
+		AllowTrivialMain: true,
+		Target:           "simplicityhl",
+		Debug:            false,
 	})
 
 	result, err := c.Compile(source, "test.go")
@@ -344,6 +345,7 @@ func TestPhase5TxIntrospectionJetRegistry(t *testing.T) {
 		{"TapleafVersion", "tapleaf_version"},
 		{"Tappath", "tappath"},
 		{"ScriptCmr", "script_cmr"},
+		{"AnnexHash", "current_annex_hash"},
 	}
 
 	for _, tc := range testCases {
@@ -390,7 +392,7 @@ func main() {
 	result := jet.Add32(100, 200)
 }
 `
-	c := compiler.New(compiler.Config{Target: "simplicityhl"})
+	c := compiler.New(compiler.Config{Target: "simplicityhl", AllowTrivialMain: true})
 	out, err := c.Compile(source, "test.go")
 	if err != nil {
 		t.Fatalf("compile failed: %v", err)
@@ -412,7 +414,7 @@ func main() {
 	jet.Verify(ok)
 }
 `
-	c := compiler.New(compiler.Config{Target: "simplicityhl"})
+	c := compiler.New(compiler.Config{Target: "simplicityhl", AllowTrivialMain: true})
 	out, err := c.Compile(source, "test.go")
 	if err != nil {
 		t.Fatalf("compile failed: %v", err)
@@ -547,9 +549,14 @@ func TestElementsAmountJetRegistry(t *testing.T) {
 		{"CurrentAsset", "current_asset", "u256"},
 		{"CurrentAmount", "current_amount", "u64"},
 		// Issuance jets
-		{"IssuanceAssetAmount", "issuance_asset_amount", "u64"},
-		{"IssuanceTokenAmount", "issuance_token_amount", "u64"},
-		{"NewIssuanceContract", "new_issuance_contract", "u256"},
+		{"InputIsIssuance", "std_input_is_issuance", "bool"},
+		{"IssuanceAssetAmount", "issuance_asset_amount", "Option<u64>"},
+		{"IssuanceTokenAmount", "issuance_token_amount", "Option<u64>"},
+		{"NewIssuanceContract", "new_issuance_contract", "Option<u256>"},
+		{"ReissuanceBlinding", "reissuance_blinding", "Option<u256>"},
+		{"ReissuanceEntropy", "reissuance_entropy", "Option<u256>"},
+		{"InputPegin", "input_pegin", "Option<u256>"},
+		{"CurrentPegin", "current_pegin", "Option<u256>"},
 	}
 
 	for _, tc := range testCases {
@@ -604,8 +611,9 @@ package main
 import "simplicity/jet"
 
 func main() {
-	lpMinted := jet.IssuanceAssetAmount(0)
-	_ = lpMinted
+	if lpMinted := jet.IssuanceAssetAmount(0); lpMinted != nil {
+		jet.Verify(jet.Le64(*lpMinted, 1000))
+	}
 }
 `
 	c := compiler.New(compiler.Config{Target: "simplicityhl"})
@@ -613,8 +621,15 @@ func main() {
 	if err != nil {
 		t.Fatalf("compile failed: %v", err)
 	}
-	if !strings.Contains(out, "jet::issuance_asset_amount") {
-		t.Errorf("expected jet::issuance_asset_amount in output, got:\n%s", out)
+	for _, want := range []string{
+		"match unwrap(jet::issuance_asset_amount(0)) {",
+		"Some(t_7_17: Amount1) => {",
+		"let lp_minted: u64 = unwrap_right::<(u1, u256)>(t_7_17);",
+		"assert!(jet::le_64(lp_minted, 1000));",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, out)
+		}
 	}
 }
 
@@ -692,7 +707,7 @@ func main() {
 	_ = sum
 }
 `
-	c := compiler.New(compiler.Config{Target: "simplicityhl"})
+	c := compiler.New(compiler.Config{Target: "simplicityhl", AllowTrivialMain: true})
 	out, err := c.Compile(source, "test.go")
 	if err != nil {
 		t.Fatalf("compile failed: %v", err)
@@ -792,7 +807,7 @@ func main() {
 	_ = product
 }
 `
-	c := compiler.New(compiler.Config{Target: "simplicityhl"})
+	c := compiler.New(compiler.Config{Target: "simplicityhl", AllowTrivialMain: true})
 	out, err := c.Compile(source, "test.go")
 	if err != nil {
 		t.Fatalf("compile failed: %v", err)
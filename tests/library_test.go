@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+)
+
+const checksLibrary = `
+package checks
+
+import "simplicity/jet"
+
+const MinAmount = 1000
+
+// AmountOk reports whether an amount clears the minimum.
+func AmountOk(amount uint64) bool {
+	return jet.Le64(MinAmount, amount)
+}
+
+func sameKey(a [32]byte, b [32]byte) bool {
+	return jet.Eq256(a, b)
+}
+`
+
+func TestLibraryMode(t *testing.T) {
+	c := compiler.New(compiler.Config{Target: "simplicityhl", Mode: "library"})
+	result, err := c.Compile(checksLibrary, "checks.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+
+	want := "// AmountOk reports whether an amount clears the minimum.\n" +
+		"fn amount_ok(amount: u64) -> bool {\n" +
+		"    jet::le_64(1000, amount)\n" +
+		"}\n" +
+		"\n" +
+		"fn same_key(a: [u8; 32], b: [u8; 32]) -> bool {\n" +
+		"    jet::eq_256(a, b)\n" +
+		"}\n"
+	if result != want {
+		t.Errorf("library output mismatch\ngot:\n%s\nwant:\n%s", result, want)
+	}
+}
+
+func TestLibraryModeSkipsMain(t *testing.T) {
+	source := checksLibrary + "\nfunc main() {\n\tvar sig [64]byte\n}\n"
+	c := compiler.New(compiler.Config{Target: "simplicityhl", Mode: "library"})
+	result, err := c.Compile(source, "checks.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, unwanted := range []string{"mod witness", "mod param", "fn main"} {
+		if strings.Contains(result, unwanted) {
+			t.Errorf("library output should not contain %q\n%s", unwanted, result)
+		}
+	}
+}
+
+func TestUnsupportedMode(t *testing.T) {
+	c := compiler.New(compiler.Config{Target: "simplicityhl", Mode: "plugin"})
+	if _, err := c.Compile(checksLibrary, "checks.go"); err == nil || !strings.Contains(err.Error(), "unsupported mode") {
+		t.Errorf("expected unsupported mode error, got %v", err)
+	}
+}
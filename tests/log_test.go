@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+)
+
+// recordingLogger keeps the messages logged to it, prefixed with their
+// level.
+type recordingLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *recordingLogger) Logf(level compiler.LogLevel, format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, level.String()+": "+fmt.Sprintf(format, args...))
+}
+
+func TestLogLevels(t *testing.T) {
+	compile := func(config compiler.Config) string {
+		t.Helper()
+		logger := &recordingLogger{}
+		config.Target, config.Logger = "simplicityhl", logger
+		if _, err := compiler.New(config).Compile(string(readExample(t, "simple_multisig.go")), "simple_multisig.go"); err != nil {
+			t.Fatalf("Compilation failed: %v", err)
+		}
+		return strings.Join(logger.messages, "\n")
+	}
+
+	if log := compile(compiler.Config{}); log != "" {
+		t.Errorf("expected nothing logged by default, got\n%s", log)
+	}
+
+	debug := compile(compiler.Config{LogLevel: compiler.LogDebug, OptLevel: compiler.O2})
+	for _, want := range []string{
+		"debug: parsed simple_multisig.go: ",
+		"debug: parsing took ",
+		"debug: validation took ",
+		"debug: transpiled 1 functions, 3 witnesses, ",
+		"debug: analysis took ",
+		"debug: rendering took ",
+		"debug: compile took ",
+		"debug: optimization took ",
+		"debug: checking took ",
+	} {
+		if !strings.Contains(debug, want) {
+			t.Errorf("expected %q in\n%s", want, debug)
+		}
+	}
+	if strings.Contains(debug, "AST") {
+		t.Errorf("expected no AST at LogDebug, got\n%s", debug)
+	}
+
+	trace := compile(compiler.Config{LogLevel: compiler.LogTrace})
+	if !strings.Contains(trace, "trace: AST of simple_multisig.go:\n") || !strings.Contains(trace, "*ast.FuncDecl") {
+		t.Errorf("expected the AST at LogTrace, got\n%.500s", trace)
+	}
+
+	// The deprecated Debug option logs as LogDebug does.
+	if log := compile(compiler.Config{Debug: true}); !strings.Contains(log, "debug: parsing took ") || strings.Contains(log, "AST") {
+		t.Errorf("expected Debug to log at LogDebug, got\n%s", log)
+	}
+
+	// A compile that fails logs the phase it failed in.
+	logger := &recordingLogger{}
+	_, err := compiler.New(compiler.Config{Target: "simplicityhl", LogLevel: compiler.LogDebug, Logger: logger}).Compile("package main\n\nfunc main() {\n\tfor {}\n}\n", "loop.go")
+	if err == nil || len(logger.messages) == 0 || logger.messages[len(logger.messages)-1] != "debug: validation failed" {
+		t.Errorf("expected the failing phase logged last, got %v, %q", err, logger.messages)
+	}
+}
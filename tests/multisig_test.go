@@ -120,8 +120,9 @@ func main() {
 `
 
 	c := compiler.New(compiler.Config{
-		Target: "simplicityhl",
-		Debug:  false,
+		AllowTrivialMain: true,
+		Target:           "simplicityhl",
+		Debug:            false,
 	})
 
 	result, err := c.Compile(source, "test.go")
@@ -146,8 +147,9 @@ func main() {
 `
 
 	c := compiler.New(compiler.Config{
-		Target: "simplicityhl",
-		Debug:  false,
+		AllowTrivialMain: true,
+		Target:           "simplicityhl",
+		Debug:            false,
 	})
 
 	result, err := c.Compile(source, "test.go")
@@ -176,8 +178,9 @@ func main() {
 `
 
 	c := compiler.New(compiler.Config{
-		Target: "simplicityhl",
-		Debug:  false,
+		AllowTrivialMain: true,
+		Target:           "simplicityhl",
+		Debug:            false,
 	})
 
 	result, err := c.Compile(source, "test.go")
@@ -204,10 +207,18 @@ func TestBoundedForLoopAllowed(t *testing.T) {
 	source := `
 package main
 
+type OptionalSig struct {
+	IsSome bool
+	Value  [64]byte
+}
+
 func main() {
+	var sigs [3]OptionalSig
 	var count int
 	for i := 0; i < 3; i++ {
-		count++
+		if sigs[i].IsSome {
+			count++
+		}
 	}
 	_ = count
 }
@@ -224,6 +235,44 @@ func main() {
 	}
 }
 
+// TestNamedBoundLoop checks that a loop of main bounded by a named
+// constant, in a range or a counted loop, is unrolled by its value.
+func TestNamedBoundLoop(t *testing.T) {
+	source := `package main
+
+import "simplicity/jet"
+
+const Signers = 3
+
+type OptionalSig struct {
+	IsSome bool
+	Value  [64]byte
+}
+
+func main() {
+	var sigs [3]OptionalSig
+	var count uint32
+	LOOP {
+		if sigs[i].IsSome {
+			count++
+		}
+	}
+	jet.Verify(jet.Le32(2, count))
+}
+`
+	for _, loop := range []string{"for i := 0; i < Signers; i++", "for i := range Signers"} {
+		result, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(strings.Replace(source, "LOOP", loop, 1), "test.go")
+		if err != nil {
+			t.Fatalf("%s: %v", loop, err)
+		}
+		for _, want := range []string{"// Unrolled loop (originally: for i := 0; i < 3; i++)", "let [_, _, element]: [Option<[u8; 64]>; 3] = witness::SIGS; element"} {
+			if !strings.Contains(result, want) {
+				t.Errorf("%s: missing %q in\n%s", loop, want, result)
+			}
+		}
+	}
+}
+
 func TestArrayIndexing(t *testing.T) {
 	source := `
 package main
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/secp256k1"
+	"github.com/0ceanslim/go-simplicity/std"
+	"github.com/0ceanslim/go-simplicity/std/bitcoin"
+)
+
+// TestKeyAggVectors checks key aggregation against the BIP-327
+// key_agg_vectors.json test cases.
+func TestKeyAggVectors(t *testing.T) {
+	pubkeys := []string{
+		"02F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9",
+		"03DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		"023590A94E768F8E1815C2F24B4D80A8E3149316C3518CE7B7AD338368D038CA66",
+	}
+	tests := []struct {
+		indices []int
+		want    string
+	}{
+		{[]int{0, 1, 2}, "90539EEDE565F5D054F32CC0C220126889ED1E5D193BAF15AEF344FE59D4610C"},
+		{[]int{2, 1, 0}, "6204DE8B083426DC6EAF9502D27024D53FC826BF7D2012148A0575435DF54B2B"},
+		{[]int{0, 0, 0}, "B436E3BAD62B8CD409969A224731C193D051162D8C5AE8B109306127DA3AA935"},
+		{[]int{0, 0, 1, 1}, "69BC22BFA5D106306E48A20679DE1D7389386124D07571D0D872686028C26A3E"},
+	}
+	for _, tt := range tests {
+		var keys [][]byte
+		for _, i := range tt.indices {
+			key, err := hex.DecodeString(pubkeys[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+			keys = append(keys, key)
+		}
+		got, err := secp256k1.KeyAgg(keys)
+		if err != nil {
+			t.Errorf("%v: %v", tt.indices, err)
+			continue
+		}
+		if strings.ToUpper(hex.EncodeToString(got)) != tt.want {
+			t.Errorf("%v: got %X, want %s", tt.indices, got, tt.want)
+		}
+	}
+
+	invalid, _ := hex.DecodeString("020000000000000000000000000000000000000000000000000000000000000005")
+	if _, err := secp256k1.KeyAgg([][]byte{invalid}); err == nil {
+		t.Error("expected an error for a key that is not on the curve")
+	}
+}
+
+func TestKeyAggCoefficientless(t *testing.T) {
+	out := compileExample(t, "../examples/musig_cooperative.go")
+
+	var alice, bob bitcoin.XOnlyPubkey
+	hex.Decode(alice[:], []byte("f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9"))
+	hex.Decode(bob[:], []byte("dff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659"))
+	agg := std.KeyAggCoefficientless(alice, bob)
+	want := "jet::bip_0340_verify((0x" + hex.EncodeToString(agg[:]) + ", msg), data);"
+	if !strings.Contains(out, want) {
+		t.Errorf("musig_cooperative: missing %q\n%s", want, out)
+	}
+	for _, leak := range []string{"COOP_KEY", "KeyAgg", "key_agg"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("musig_cooperative: key aggregation leaked into the program as %q\n%s", leak, out)
+		}
+	}
+
+	tests := []struct {
+		name, body, want string
+	}{
+		{
+			"witness key",
+			"var bob bitcoin.XOnlyPubkey\n\tjet.BIP340Verify(std.KeyAggCoefficientless(Alice, bob), jet.SigAllHash(), sig)",
+			"std.KeyAggCoefficientless: bob is not a compile-time constant 32-byte key",
+		},
+		{
+			"off the curve",
+			"jet.BIP340Verify(std.KeyAggCoefficientless(Alice, 5), jet.SigAllHash(), sig)",
+			"std.KeyAggCoefficientless: 0000000000000000000000000000000000000000000000000000000000000005 is not on the curve",
+		},
+		{
+			"unknown helper",
+			"jet.BIP340Verify(std.KeyAgg(Alice, Alice), jet.SigAllHash(), sig)",
+			"has no function KeyAgg",
+		},
+	}
+	for _, tt := range tests {
+		source := "package main\n\nimport (\n\t\"simplicity/jet\"\n\n\t\"github.com/0ceanslim/go-simplicity/std\"\n\t\"github.com/0ceanslim/go-simplicity/std/bitcoin\"\n)\n\n" +
+			"const Alice = 0xf9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9\n\nfunc main() {\n\tvar sig bitcoin.Signature\n\t" + tt.body + "\n}\n"
+		_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "coop.go")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+)
+
+const unicodeSource = `package main
+
+import "simplicity/jet"
+
+const PlafondÉlevé uint64 = 9000
+
+func main() {
+	var prix uint64
+	var amountÉlevé uint64
+	jet.Verify(jet.Le64(prix, amountÉlevé))
+	jet.Verify(jet.Le64(amountÉlevé, PlafondÉlevé))
+}
+`
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl", SelfCheck: true}).Compile(unicodeSource, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"const PRIX: u64 = 0x0000000000000000;",
+		"const AMOUNT_ELEVE: u64 = 0x0000000000000000;",
+		"const PLAFOND_ELEVE: u64 = 9000;",
+		"let amount_eleve: u64 = witness::AMOUNT_ELEVE;",
+		"assert!(jet::le_64(amount_eleve, param::PLAFOND_ELEVE));",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+
+	for _, tt := range []struct {
+		name, source string
+		config       compiler.Config
+		want         string
+	}{
+		{"no transliteration", unicodeSource, compiler.Config{NoTransliteration: true},
+			"contract.go:5:7: identifier PlafondÉlevé is not ASCII; rename it, e.g. to PlafondEleve, or allow transliteration"},
+		{"no ASCII spelling", strings.ReplaceAll(unicodeSource, "prix", "価格"), compiler.Config{},
+			"contract.go:8:6: identifier 価格 has a letter with no ASCII spelling; SimplicityHL identifiers are ASCII"},
+	} {
+		tt.config.Target = "simplicityhl"
+		_, err := compiler.New(tt.config).Compile(tt.source, "contract.go")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestIdentifierCase(t *testing.T) {
+	const source = `package main
+
+import "simplicity/jet"
+
+const MAX_FEE uint64 = 500
+const BIP340Key = 0x79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798
+
+func main() {
+	var _fee uint64
+	var sig [64]byte
+	txHeight := jet.TxLockHeight()
+	jet.Verify(jet.Le64(_fee, MAX_FEE))
+	jet.Verify(jet.Le32(txHeight, 100))
+	jet.BIP340Verify(BIP340Key, jet.SigAllHash(), sig)
+}
+`
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl", SelfCheck: true}).Compile(source, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	// All-caps constants keep their words, and a leading underscore stays.
+	for _, want := range []string{
+		"const MAX_FEE: u64 = 500;",
+		"const BIP340_KEY: u256 = ",
+		"const _FEE: u64 = 0x0000000000000000;",
+		"assert!(jet::le_64(witness::_FEE, param::MAX_FEE));",
+		"let tx_height: u32 = jet::tx_lock_height();",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+
+	for _, tt := range []struct {
+		from, to, want string
+	}{
+		{"_fee", "maxFee", "contract.go:9:6: maxFee becomes witness::MAX_FEE, which is also the name of param::MAX_FEE; rename one of them"},
+		{"const BIP340Key", "const MaxFee uint64 = 7\nconst BIP340Key", "contract.go:6:7: constant MaxFee becomes param::MAX_FEE, which is already declared"},
+	} {
+		_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(strings.ReplaceAll(source, tt.from, tt.to), "contract.go")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q, got %v", tt.to, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"errors"
+	"go/parser"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+	"github.com/0ceanslim/go-simplicity/pkg/transpiler"
+)
+
+const negationSource = `package main
+
+import "simplicity/jet"
+
+func check(p bool, q bool, x uint32, y uint32) bool {
+	return EXPR
+}
+
+func main() {
+	var p, q bool
+	var x, y uint32
+	jet.Verify(EXPR)
+	jet.Verify(check(p, q, x, y))
+}
+`
+
+func TestNegationLowering(t *testing.T) {
+	tests := []struct {
+		expr, want string
+	}{
+		{"!!p", "    p\n"},
+		{"!(x > y)", "    jet::le_32(x, y)\n"},
+		{"!((x <= y))", "    jet::lt_32(y, x)\n"},
+		{"!(x != y)", "    jet::eq_32(x, y)\n"},
+		{"x != y", "    match jet::eq_32(x, y) { true => false, false => true, }\n"},
+		{"!(p && q)", "    match match p { true => q, false => false, } { true => false, false => true, }\n"},
+		{"p || !q", "    match p { true => true, false => match q { true => false, false => true, }, }\n"},
+		{"(true && p) || false", "    p\n"},
+		{"false && p", "TRIVIAL"},
+	}
+	for _, tt := range tests {
+		source := strings.ReplaceAll(negationSource, "EXPR", tt.expr)
+		result, err := compiler.New(compiler.Config{Target: "simplicityhl", OptLevel: compiler.O0, SelfCheck: true}).Compile(source, "contract.go")
+		if tt.want == "TRIVIAL" {
+			// Go never evaluates p, so the program can never be satisfied
+			if err != nil || !strings.Contains(result, "fn check(p: bool, q: bool, x: u32, y: u32) -> bool {\n    false\n}") {
+				t.Errorf("%s: got %v\n%s", tt.expr, err, result)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		if want := "fn check(p: bool, q: bool, x: u32, y: u32) -> bool {\n" + tt.want + "}"; !strings.Contains(result, want) {
+			t.Errorf("%s: missing %q in\n%s", tt.expr, want, result)
+		}
+	}
+}
+
+// boolExpr is a random boolean expression over the bools p and q and the
+// uint32s x and y.
+type boolExpr struct {
+	op   string // "!", "&&", "||", a comparison, or "" for p or q
+	name string
+	x, y *boolExpr
+}
+
+var comparisons = []string{"<", "<=", ">", ">=", "==", "!="}
+
+func randomBoolExpr(rng *rand.Rand, depth int) *boolExpr {
+	n := rng.Intn(6)
+	if depth == 0 {
+		n = rng.Intn(2)
+	}
+	switch n {
+	case 0:
+		return &boolExpr{name: []string{"p", "q"}[rng.Intn(2)]}
+	case 1:
+		return &boolExpr{op: comparisons[rng.Intn(len(comparisons))]}
+	case 2, 3:
+		return &boolExpr{op: "!", x: randomBoolExpr(rng, depth-1)}
+	default:
+		return &boolExpr{op: []string{"&&", "||"}[rng.Intn(2)], x: randomBoolExpr(rng, depth-1), y: randomBoolExpr(rng, depth-1)}
+	}
+}
+
+// format writes e as Go, with value naming each free variable and extra
+// parentheses where parens is set.
+func (e *boolExpr) format(value func(string) string, parens bool) string {
+	var s string
+	switch e.op {
+	case "":
+		s = value(e.name)
+	case "!":
+		s = "!" + e.x.format(value, parens)
+	case "&&", "||":
+		s = "(" + e.x.format(value, parens) + " " + e.op + " " + e.y.format(value, parens) + ")"
+	default:
+		s = "(" + value("x") + " " + e.op + " " + value("y") + ")"
+	}
+	if parens && e.op == "" {
+		s = "(" + s + ")"
+	}
+	return s
+}
+
+// TestNegationTruthTable compiles random boolean expressions and checks
+// that the program accepts exactly the witnesses for which the Folder
+// evaluates the expression, with the witness values written in, to true.
+func TestNegationTruthTable(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	folder := transpiler.NewFolder(nil, nil)
+	numbers := [][2]uint32{{1, 2}, {2, 2}, {3, 2}}
+	for run := 0; run < 150; run++ {
+		e := randomBoolExpr(rng, 4)
+		expr := e.format(func(name string) string { return name }, run%2 == 1)
+		source := strings.ReplaceAll(negationSource, "EXPR", expr)
+		result, err := compiler.New(compiler.Config{Target: "simplicityhl", OptLevel: compiler.O0, AllowTrivialMain: true}).Compile(source, "contract.go")
+		if err != nil {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		prog, err := shlparse.Parse(result)
+		if err != nil {
+			t.Fatalf("%s: generated program does not parse: %v\n%s", expr, err, result)
+		}
+		for _, p := range []bool{false, true} {
+			for _, q := range []bool{false, true} {
+				for _, xy := range numbers {
+					values := map[string]string{
+						"p": strconv.FormatBool(p),
+						"q": strconv.FormatBool(q),
+						"x": strconv.FormatUint(uint64(xy[0]), 10),
+						"y": strconv.FormatUint(uint64(xy[1]), 10),
+					}
+					literal := e.format(func(name string) string { return values[name] }, false)
+					parsed, err := parser.ParseExpr(literal)
+					if err != nil {
+						t.Fatalf("%s: %v", literal, err)
+					}
+					want, ok := folder.Fold(parsed)
+					if !ok {
+						t.Fatalf("%s does not fold", literal)
+					}
+					witness := map[string]string{"P": values["p"], "Q": values["q"], "X": values["x"], "Y": values["y"]}
+					err = eval.Run(prog, eval.Options{Witness: witness})
+					var rejection *eval.Rejection
+					switch {
+					case want.Bool && err != nil:
+						t.Fatalf("%s with %v: expected accept, got %v\n%s", expr, witness, err, result)
+					case !want.Bool && !errors.As(err, &rejection):
+						t.Fatalf("%s with %v: expected reject, got %v\n%s", expr, witness, err, result)
+					}
+				}
+			}
+		}
+	}
+}
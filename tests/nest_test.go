@@ -0,0 +1,225 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+)
+
+const loopNestSource = `package main
+
+import "simplicity/jet"
+
+func Sum(m [4][4]uint64) uint64 {
+	var total uint64
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			total += m[i][j]
+		}
+	}
+	return total
+}
+
+func Same(a [4][8]byte, b [4][8]byte) bool {
+	var diff uint8
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 8; j++ {
+			diff |= a[i][j] ^ b[i][j]
+		}
+	}
+	return diff == 0
+}
+
+func main() {
+	var m [4][4]uint64
+	var a, b [4][8]byte
+	var sum uint64
+	jet.Verify(jet.Eq64(Sum(m), sum))
+	jet.Verify(Same(a, b))
+}
+`
+
+func TestLoopNest(t *testing.T) {
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(loopNestSource, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		// The rows are destructured, then each row into its elements.
+		"    let [m_0, m_1, m_2, m_3]: [[u64; 4]; 4] = m;\n" +
+			"    let [m_0_0, m_0_1, m_0_2, m_0_3]: [u64; 4] = m_0;\n",
+		"    let [m_3_0, m_3_1, m_3_2, m_3_3]: [u64; 4] = m_3;\n",
+		// Sixteen elements, in the order Go adds them, pair up in a tree.
+		"    let (_, t_7_2): (bool, u64) = jet::add_64(m_0_0, m_0_1);\n",
+		"    let (_, t_7_2_8): (bool, u64) = jet::add_64(m_3_2, m_3_3);\n",
+		"    let (_, total): (bool, u64) = jet::add_64(t_7_2_13, t_7_2_14);\n",
+		// The inner index selects the element of the row the outer one
+		// selects, in both arrays.
+		"jet::xor_8(a_2_5, b_2_5);",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+}
+
+func TestLoopNestEquivalence(t *testing.T) {
+	rows := func(n int, cell func(i, j int) string) string {
+		var out []string
+		for i := 0; i < 4; i++ {
+			var row []string
+			for j := 0; j < n; j++ {
+				row = append(row, cell(i, j))
+			}
+			out = append(out, "["+strings.Join(row, ", ")+"]")
+		}
+		return "[" + strings.Join(out, ", ") + "]"
+	}
+	rng := rand.New(rand.NewSource(1))
+	for run := 0; run < 10; run++ {
+		var m [4][4]uint64
+		var a, b [4][8]byte
+		var sum uint64
+		for i := range m {
+			for j := range m[i] {
+				m[i][j] = rng.Uint64() >> 8
+				sum += m[i][j]
+			}
+		}
+		for i := range a {
+			for j := range a[i] {
+				a[i][j] = byte(rng.Intn(256))
+			}
+		}
+		b = a
+		if run%2 == 1 {
+			b[run%4][run%8] ^= 1
+		}
+		witness := map[string]string{
+			"M":   rows(4, func(i, j int) string { return strconv.FormatUint(m[i][j], 10) }),
+			"A":   rows(8, func(i, j int) string { return strconv.Itoa(int(a[i][j])) }),
+			"B":   rows(8, func(i, j int) string { return strconv.Itoa(int(b[i][j])) }),
+			"SUM": strconv.FormatUint(sum, 10),
+		}
+		_, err := runSource(t, compiler.Config{}, loopNestSource, witness)
+		if run%2 == 0 && err != nil {
+			t.Fatalf("run %d: %v\nwitness %v", run, err, witness)
+		}
+		var rejection *eval.Rejection
+		if run%2 == 1 && !errors.As(err, &rejection) {
+			t.Fatalf("run %d: different arrays were accepted: %v", run, err)
+		}
+		witness["B"] = witness["A"]
+		witness["SUM"] = fmt.Sprint(sum + 1)
+		if _, err := runSource(t, compiler.Config{}, loopNestSource, witness); !errors.As(err, &rejection) {
+			t.Fatalf("run %d: a wrong sum was accepted: %v", run, err)
+		}
+	}
+}
+
+const boolNestSource = `package main
+
+import "simplicity/jet"
+
+func Equal(a [4][4]uint8, b [4][4]uint8) bool {
+	ok := true
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			ok = ok && a[i][j] == b[i][j]
+		}
+	}
+	return ok
+}
+
+func main() {
+	var a, b [4][4]uint8
+	jet.Verify(Equal(a, b))
+}
+`
+
+// TestLoopNestBool checks that && over a nest of loops is the tree of
+// matches over every element, accepting equal arrays and rejecting ones
+// that differ in any element.
+func TestLoopNestBool(t *testing.T) {
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl", Strict: true}).Compile(boolNestSource, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"jet::eq_8(a_3_3, b_3_3);",
+		"    let ok: bool = match t_7_2_13 { true => t_7_2_14, false => false, };\n    ok\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+
+	rows := func(m [4][4]uint8) string {
+		var out []string
+		for i := range m {
+			var row []string
+			for j := range m[i] {
+				row = append(row, strconv.Itoa(int(m[i][j])))
+			}
+			out = append(out, "["+strings.Join(row, ", ")+"]")
+		}
+		return "[" + strings.Join(out, ", ") + "]"
+	}
+	rng := rand.New(rand.NewSource(1))
+	var a [4][4]uint8
+	for i := range a {
+		for j := range a[i] {
+			a[i][j] = uint8(rng.Intn(256))
+		}
+	}
+	// Equal arrays, then ones that differ in each element in turn.
+	for k := -1; k < 16; k++ {
+		b := a
+		if k >= 0 {
+			b[k/4][k%4] ^= 1 << (k % 8)
+		}
+		_, err := runSource(t, compiler.Config{Strict: true}, boolNestSource, map[string]string{"A": rows(a), "B": rows(b)})
+		var rejection *eval.Rejection
+		switch {
+		case k < 0 && err != nil:
+			t.Fatalf("equal arrays were rejected: %v", err)
+		case k >= 0 && !errors.As(err, &rejection):
+			t.Fatalf("arrays that differ in element %d, %d were accepted: %v", k/4, k%4, err)
+		}
+	}
+}
+
+func TestLoopNestTooLarge(t *testing.T) {
+	src := `package main
+
+import "simplicity/jet"
+
+func Sum(m [64][32]uint8) uint8 {
+	var total uint8
+	for i := 0; i < 64; i++ {
+		for j := 0; j < 32; j++ {
+			total += m[i][j]
+		}
+	}
+	return total
+}
+
+func main() {
+	var m [64][32]uint8
+	jet.Verify(jet.Eq8(Sum(m), 0))
+}
+`
+	// Each bound is below the limit; their product is not.
+	_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(src, "main.go")
+	want := "main.go:7:2: unrolling the loop over i and the loops nested in it takes 64 × 32 = 2048 iterations, above the limit of 1024"
+	if code, _ := diag.CodeOf(err); code != diag.ProgramTooLarge || err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected an error containing %q, got %v", want, err)
+	}
+}
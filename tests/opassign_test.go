@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/equiv"
+)
+
+const opAssignSource = `package main
+
+import "simplicity/jet"
+
+func Fee(amount uint8, fee uint8, limit uint8) bool {
+	total := amount
+	total += fee
+	total -= 1
+	total *= 3
+	total &= 0xf7
+	total |= 1
+	total ^= fee
+	return total <= limit
+}
+
+func Steps(n uint16) bool {
+	n++
+	n++
+	n--
+	return n == 8
+}
+
+func Sum(xs [4]uint32) uint32 {
+	var total uint32
+	for i := 0; i < 4; i++ {
+		total += xs[i]
+	}
+	return total
+}
+
+func Wide(a uint16, b uint16) bool {
+	var total uint16
+	total += a
+	total -= b
+	return jet.Le16(total, 300)
+}
+
+func Narrow(a uint8, b uint8) bool {
+	total := uint8(0)
+	total = total + a
+	total = total * b
+	return jet.Le8(total, 40)
+}
+
+func main() {
+	var amount, fee, limit uint8
+	var n uint16
+	var xs [4]uint32
+	var sum uint32
+	jet.Verify(Fee(amount, fee, limit))
+	jet.Verify(Steps(n))
+	jet.Verify(jet.Eq32(Sum(xs), sum))
+	jet.Verify(Wide(n, n))
+	jet.Verify(Narrow(amount, fee))
+}
+`
+
+func TestOpAssign(t *testing.T) {
+	out, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(opAssignSource, "main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		// Each op-assignment shadows the local with the jet of its operator.
+		"    let total = amount;\n    let total = { let (_, sum): (bool, u8) = jet::add_8(total, fee); sum };\n",
+		"jet::subtract_8(total, 1)",
+		"    let total = jet::xor_8(total, fee);\n    jet::le_8(total, limit)\n",
+		"    let n = { let (_, sum): (bool, u16) = jet::add_16(n, 1); sum };\n",
+		"jet::subtract_16(n, 1)",
+		// A loop accumulator is still reduced to a tree.
+		"    let (_, total): (bool, u32) = jet::add_32(t_25_2, t_25_2_2);\n",
+		// A local keeps the width it was declared or converted to.
+		"    let total: u16 = 0x0000;\n    let total = { let (_, sum): (bool, u16) = jet::add_16(total, a); sum };\n",
+		"jet::subtract_16(total, b)",
+		"    let total = { let (_, sum): (bool, u8) = jet::add_8(total, a); sum };\n",
+		"jet::multiply_8(total, b)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+	for _, entry := range []string{"Fee", "Steps", "Wide", "Narrow"} {
+		report, err := equiv.Check(opAssignSource, "main.go", compiler.Config{Entry: entry}, equiv.Options{})
+		if err != nil {
+			t.Fatalf("%s: %v", entry, err)
+		}
+		if report.Cases == 0 || len(report.Mismatches) != 0 {
+			t.Errorf("%s: %d cases, mismatches %v; want all to agree", entry, report.Cases, report.Mismatches)
+		}
+	}
+}
+
+func TestOpAssignElement(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		stmt string
+		want string
+	}{
+		{"op-assignment", "xs[1] += 2", "main.go:6:8: the += of xs[1] is not supported: SimplicityHL has no assignment to an element of an array"},
+		{"increment", "xs[i]++", "main.go:6:7: the ++ of xs[i] is not supported"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			src := `package main
+
+import "simplicity/jet"
+
+func Bump(xs [3]uint64, i uint32) uint64 {
+	` + tc.stmt + `
+	return xs[1]
+}
+
+func main() {
+	var xs [3]uint64
+	jet.Verify(jet.Eq64(Bump(xs, 1), 2))
+}
+`
+			_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(src, "main.go")
+			if code, _ := diag.CodeOf(err); code != diag.UnsupportedSyntax || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected an error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/optimize"
+)
+
+// TestO0Golden compiles every example at -O0 and checks the result against
+// tests/testdata/O0, so that a change to the straight translation shows up
+// in review.
+func TestO0Golden(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("..", "examples", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testable, err := filepath.Glob(filepath.Join("..", "examples", "testable", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range append(paths, testable...) {
+		name := strings.TrimSuffix(filepath.Base(path), ".go")
+		t.Run(name, func(t *testing.T) {
+			config := exampleConfig(path)
+			config.OptLevel = compiler.O0
+			result, err := compiler.New(config).Compile(loadExample(t, path), path)
+			if err != nil {
+				t.Fatalf("compile: %v", err)
+			}
+			golden, err := os.ReadFile(filepath.Join("testdata", "O0", name+".simf"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			header, want, _ := strings.Cut(string(golden), "\n")
+			if header != "// Code generated by simgo from "+name+".go. DO NOT EDIT." || result != want {
+				t.Errorf("output differs from tests/testdata/O0/%[1]s.simf; regenerate it with\n\tgo run ./cmd/simgo -O0 -input %[2]s -output tests/testdata/O0/%[1]s.simf\ngot:\n%[3]s", name, strings.TrimPrefix(filepath.ToSlash(path), "../"), result)
+			}
+		})
+	}
+}
+
+func TestOptLevels(t *testing.T) {
+	compile := func(t *testing.T, config compiler.Config, name string) (*compiler.Compiler, string) {
+		t.Helper()
+		config.Target = "simplicityhl"
+		c := compiler.New(config)
+		result, err := c.Compile(string(readExample(t, name+".go")), name+".go")
+		if err != nil {
+			t.Fatalf("%s at %v: %v", name, config.OptLevel, err)
+		}
+		return c, result
+	}
+
+	tests := []struct {
+		name   string
+		config compiler.Config
+		has    []string
+		hasNot []string
+	}{
+		// -O0 calls the helper and keeps the one std.Threshold is
+		// lowered to.
+		{"simple_multisig", compiler.Config{OptLevel: compiler.O0},
+			[]string{"fn multi_sig_validation(", "assert!(multi_sig_validation(witness::SIG1, witness::SIG2, witness::SIG3));"}, nil},
+		// -O1 drops no function that main calls.
+		{"simple_multisig", compiler.Config{OptLevel: compiler.O1},
+			[]string{"fn multi_sig_validation(", "fn std_threshold_2_of_3("}, nil},
+		// -O2 inlines it and drops it, being called nowhere.
+		{"simple_multisig", compiler.Config{OptLevel: compiler.O2},
+			[]string{"assert!(std_threshold_2_of_3(witness::SIG1, witness::SIG2, witness::SIG3));"},
+			[]string{"MultiSigValidation", "fn multi_sig_validation("}},
+		{"simple_multisig", compiler.Config{OptLevel: compiler.O2, NoDCE: true},
+			[]string{"fn multi_sig_validation("}, nil},
+		{"simple_multisig", compiler.Config{OptLevel: compiler.O2, NoInline: true},
+			[]string{"assert!(multi_sig_validation("}, nil},
+		// Helpers whose calls fold to constants are dead at -O1; the
+		// result main asserts still calls basic_swap.
+		{"basic_swap", compiler.Config{OptLevel: compiler.O0},
+			[]string{"fn validate_amount(", "fn basic_swap("}, nil},
+		{"basic_swap", compiler.Config{OptLevel: compiler.O1},
+			[]string{"let result: bool = basic_swap(amount_valid, fee_valid);"}, []string{"fn validate_amount(", "fn validate_fee("}},
+		// -O2 computes the sighash of both spend paths once.
+		{"htlc", compiler.Config{OptLevel: compiler.O2},
+			[]string{"let t_63_3: u256 = jet::sig_all_hash();"}, nil},
+		{"htlc", compiler.Config{OptLevel: compiler.O0, Optimize: true},
+			[]string{"let t_63_3: u256 = jet::sig_all_hash();"}, nil},
+		{"htlc", compiler.Config{OptLevel: compiler.O1},
+			nil, []string{"t_63_3"}},
+		// Without a level, as before levels: inlined, nothing dropped.
+		{"simple_multisig", compiler.Config{},
+			[]string{"fn multi_sig_validation(", "assert!(std_threshold_2_of_3("}, nil},
+	}
+	for _, tt := range tests {
+		_, result := compile(t, tt.config, tt.name)
+		for _, s := range tt.has {
+			if !strings.Contains(result, s) {
+				t.Errorf("%s at %v %+v: expected %q in\n%s", tt.name, tt.config.OptLevel, tt.config, s, result)
+			}
+		}
+		for _, s := range tt.hasNot {
+			if strings.Contains(result, s) {
+				t.Errorf("%s at %v %+v: unexpected %q in\n%s", tt.name, tt.config.OptLevel, tt.config, s, result)
+			}
+		}
+	}
+
+	// The k-of-n recognizer runs at -O2 only. Below it the increments of
+	// the untyped counter are left out, so nothing is checked.
+	source := thresholdSource(3, "count >= 2")
+	for level, counts := range map[compiler.OptLevel]bool{compiler.O0: true, compiler.O1: true, compiler.O2: false} {
+		result, err := compiler.New(compiler.Config{Target: "simplicityhl", Entry: "Threshold", OptLevel: level, AllowTrivialMain: counts}).Compile(source, "threshold.go")
+		if err != nil {
+			t.Fatalf("threshold at %v: %v", level, err)
+		}
+		if strings.Contains(result, "count") != counts {
+			t.Errorf("threshold at %v: counter kept is %v, want %v\n%s", level, !counts, counts, result)
+		}
+	}
+
+	// Functions DCE drops are not reported, and lines after them still
+	// map to their Go source.
+	c, result := compile(t, compiler.Config{OptLevel: compiler.O2}, "simple_multisig")
+	for _, fn := range c.Result().Functions {
+		if fn.Name == "multi_sig_validation" {
+			t.Errorf("reported %s, which -O2 leaves out", fn.Name)
+		}
+	}
+	line := strings.Count(result[:strings.Index(result, "fn main")], "\n") + 1
+	if pos, ok := c.SourcePosition(line); !ok || pos.Line != 19 {
+		t.Errorf("fn main maps to %v, %v, want simple_multisig.go:19", pos, ok)
+	}
+
+	if _, err := compiler.New(compiler.Config{Target: "simplicityhl", OptLevel: 7}).Compile(string(readExample(t, "htlc.go")), "htlc.go"); err == nil || !strings.Contains(err.Error(), "unsupported optimization level: OptLevel(7)") {
+		t.Errorf("expected OptLevel 7 to be rejected, got %v", err)
+	}
+}
+
+func TestDCE(t *testing.T) {
+	code := `mod witness {
+    const X: u32 = 1;
+}
+
+// Dead calls live but is called by no one.
+fn dead(x: u32) -> bool {
+    live(x)
+}
+
+fn live(x: u32) -> bool {
+    jet::le_32(x, 9)
+}
+
+fn main() {
+    assert!(live(witness::X));
+}
+`
+	want := `mod witness {
+    const X: u32 = 1;
+}
+
+fn live(x: u32) -> bool {
+    jet::le_32(x, 9)
+}
+
+fn main() {
+    assert!(live(witness::X));
+}
+`
+	got, origin := optimize.DCE(code)
+	if got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+	wantOrigin := []int{1, 2, 3, 4, 10, 11, 12, 13, 14, 15, 16, 17}
+	if fmt.Sprint(origin) != fmt.Sprint(wantOrigin) {
+		t.Errorf("origin = %v, want %v", origin, wantOrigin)
+	}
+
+	// A library has no main to reach its functions from.
+	library := "fn dead(x: u32) -> u32 {\n    x\n}\n"
+	if got, _ := optimize.DCE(library); got != library {
+		t.Errorf("library changed to\n%s", got)
+	}
+}
@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+	"github.com/0ceanslim/go-simplicity/pkg/secp256k1"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+	"github.com/0ceanslim/go-simplicity/pkg/testkeys"
+	"github.com/0ceanslim/go-simplicity/std"
+)
+
+// Oracle signatures by the Vector2 key over the "simgo/oracle-price" tagged
+// hash of other observations.
+const (
+	oracleSigBelowStrike    = "85e8a7d094b1793302e51dff14fac5e7ec67006d5cb363f88f7ce934fd5711bbbe2f2c2b946e5eed34f2267af0fa4fbaaf4f0676753dd745c68750a728f9f0ae" // 49999 at 1767225600
+	oracleSigBeforeMaturity = "6adca8ce3b86edcf33050377a6ef505a8d84c75eb3a8e7131e2f2380b0ac7831c81de9e9c72629277485fe34dffcfd35d0095a13635293f37c5100f1f9359e20" // 52000 at 1767225599
+)
+
+func TestOracleAttestationGolden(t *testing.T) {
+	result := compileGolden(t, "oracle_attestation")
+	if n := strings.Count(result, "fn std_tagged_hash_init("); n != 1 {
+		t.Errorf("std_tagged_hash_init defined %d times", n)
+	}
+}
+
+func TestOracleAttestationSpendPaths(t *testing.T) {
+	prog, err := shlparse.Parse(compileGolden(t, "oracle_attestation"))
+	if err != nil {
+		t.Fatalf("generated program does not parse: %v", err)
+	}
+	txJSON := string(readExample(t, "oracle_attestation.tx.json"))
+	exercise, err := eval.ParseWitnessJSON(readExample(t, "oracle_attestation.witness.json"))
+	if err != nil {
+		t.Fatalf("ParseWitnessJSON: %v", err)
+	}
+	holderSig := "0x" + strings.ToLower(testkeys.Vector1Sig)
+	witness := func(price, timestamp, oracleSig string) map[string]string {
+		return map[string]string{"W": "(" + price + ", " + timestamp + ", 0x" + oracleSig + ", " + holderSig + ")"}
+	}
+
+	tests := []struct {
+		name    string
+		witness map[string]string
+		edit    func(string) string
+		reject  string // "" to accept
+	}{
+		{"exercise", exercise, nil, ""},
+		{"forged price", map[string]string{"W": strings.Replace(exercise["W"], "(52000,", "(60000,", 1)}, nil, "bip_0340_verify"},
+		{"attested below the strike", witness("49999", "1767225600", oracleSigBelowStrike), nil, "assertion failed"},
+		{"attested before maturity", witness("52000", "1767225599", oracleSigBeforeMaturity), nil, "assertion failed"},
+		{"holder signed another transaction", exercise, func(s string) string {
+			return strings.Replace(s, strings.ToLower(testkeys.Vector1Msg), testkeys.Vector0Msg, 1)
+		}, "bip_0340_verify"},
+	}
+	for _, tt := range tests {
+		data := txJSON
+		if tt.edit != nil {
+			data = tt.edit(data)
+		}
+		tx, err := eval.ParseTx([]byte(data))
+		if err != nil {
+			t.Fatalf("%s: ParseTx: %v", tt.name, err)
+		}
+		err = eval.Run(prog, eval.Options{Witness: tt.witness, Tx: tx})
+		if tt.reject == "" {
+			if err != nil {
+				t.Errorf("%s: expected accept, got %v", tt.name, err)
+			}
+			continue
+		}
+		var rejection *eval.Rejection
+		if !errors.As(err, &rejection) || !strings.Contains(rejection.Reason, tt.reject) {
+			t.Errorf("%s: expected %s to reject, got %v", tt.name, tt.reject, err)
+		}
+	}
+}
+
+// The Go implementation of the std helpers hashes the message the compiled
+// program does, so an oracle can sign with it.
+func TestTaggedHashGo(t *testing.T) {
+	price := std.Uint64Bytes(52000)
+	stamp := std.Uint32Bytes(1767225600)
+	if got := hex.EncodeToString(std.Concat(price[:], stamp[:])); got != "000000000000cb206955b900" {
+		t.Errorf("message = %s", got)
+	}
+	digest := std.TaggedHash("simgo/oracle-price", std.Concat(price[:], stamp[:]))
+	pubkey, _ := hex.DecodeString(testkeys.Vector2Pubkey)
+	sig, _ := hex.DecodeString("ce6734ed6a60703b844fb59a33795e693cdf09a7c869aff3e8fd476cd7b562e3b30e79095a4a130a2571098340e5643497c74d70ea64e18f492478441c03ead9")
+	if !secp256k1.VerifySchnorr(pubkey, digest[:], sig) {
+		t.Errorf("oracle signature does not verify over %x", digest)
+	}
+	if got := std.Uint16Bytes(0x0102); got != [2]byte{1, 2} {
+		t.Errorf("Uint16Bytes = %x", got)
+	}
+}
+
+func TestTaggedHashErrors(t *testing.T) {
+	tests := []struct {
+		body, want string
+	}{
+		{`h := std.TaggedHash(Tag, std.Concat(data[:]))`, "std.TaggedHash takes its tag as a string literal"},
+		{`h := std.TaggedHash("t", std.Concat(odd[:]))`, "std.TaggedHash: odd is 3 bytes; parts must be 1, 2, 4, 8, 16, 32, 64, 128, 256 or 512 bytes"},
+		{`h := std.TaggedHash("t", std.Concat(data[1:]))`, "std.TaggedHash: data[1:] is not a whole byte array such as data[:]"},
+		{`h := jet.SHA256Finalize(jet.SHA256Add8(jet.SHA256Init(), std.Uint64Bytes(amount)))`, "std.Uint64Bytes of a value known only at spend time must be assigned to a local of the entry function"},
+		{`h := jet.SHA256Finalize(jet.SHA256Add32(jet.SHA256Init(), std.Concat(data[:])))`, "std.Concat builds the message of a std.TaggedHash and can only be passed to it"},
+	}
+	for _, tt := range tests {
+		source := `package main
+
+import (
+	"simplicity/jet"
+
+	"github.com/0ceanslim/go-simplicity/std"
+)
+
+const Tag = "0x01"
+
+func main() {
+	var data [32]byte
+	var odd [3]byte
+	var amount uint64
+	` + tt.body + `
+	jet.Verify(jet.Eq256(h, h))
+}
+`
+		_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "contract.go")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.body, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+)
+
+// variantCorpus returns n contracts that import the same helper package and
+// differ only in a threshold, like the variants of one contract in a batch.
+func variantCorpus(n int) []string {
+	sources := make([]string, n)
+	for i := range sources {
+		sources[i] = fmt.Sprintf(`package main
+
+import (
+	"simplicity/jet"
+
+	"example.com/contracts/checks"
+)
+
+func main() {
+	amount := jet.CurrentAmount()
+	jet.Verify(checks.AmountOk(amount))
+	jet.Verify(checks.AtLeast(amount, %d))
+}
+`, 1000+i)
+	}
+	return sources
+}
+
+// compileCorpus compiles sources as files of the testdata/imports module,
+// concurrently when config shares a package cache.
+func compileCorpus(config compiler.Config, sources []string) ([]string, []error) {
+	outputs := make([]string, len(sources))
+	errs := make([]error, len(sources))
+	compile := func(i int) {
+		name := filepath.Join("testdata", "imports", fmt.Sprintf("variant_%d.go", i))
+		outputs[i], errs[i] = compiler.New(config).Compile(sources[i], name)
+	}
+	if config.Packages == nil {
+		for i := range sources {
+			compile(i)
+		}
+		return outputs, errs
+	}
+
+	slots := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+	for i := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			compile(i)
+		}()
+	}
+	wg.Wait()
+	return outputs, errs
+}
+
+func TestPackageCacheShared(t *testing.T) {
+	sources := variantCorpus(12)
+	want, errs := compileCorpus(compiler.Config{Target: "simplicityhl"}, sources)
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("variant %d without a cache: %v", i, err)
+		}
+	}
+
+	config := compiler.Config{Target: "simplicityhl", Packages: compiler.NewPackageCache()}
+	got, errs := compileCorpus(config, sources)
+	for i := range sources {
+		if errs[i] != nil {
+			t.Fatalf("variant %d with a cache: %v", i, errs[i])
+		}
+		if got[i] != want[i] {
+			t.Errorf("variant %d differs with a shared cache:\n%s\nwant:\n%s", i, got[i], want[i])
+		}
+	}
+	if !strings.Contains(got[3], "checks_at_least_u64(amount, 1003)") {
+		t.Errorf("variant 3 lost its threshold:\n%s", got[3])
+	}
+
+	// A package that fails its checks fails every compile that imports it.
+	impure := `package main
+
+import "example.com/contracts/impure"
+
+func main() {
+	_ = impure.Bump()
+}
+`
+	_, errs = compileCorpus(config, []string{impure, impure, impure})
+	for i, err := range errs {
+		if err == nil || !strings.Contains(err.Error(), "imported package example.com/contracts/impure is not pure") {
+			t.Errorf("impure import %d: got %v", i, err)
+		}
+	}
+}
+
+// BenchmarkBatchCompile compiles a 100-file corpus one file at a time with
+// a fresh package load each, as separate simgo runs would, and concurrently
+// with a shared package cache, as simgo build does for a glob -input.
+func BenchmarkBatchCompile(b *testing.B) {
+	sources := variantCorpus(100)
+	b.Run("sequential", func(b *testing.B) {
+		for range b.N {
+			if _, errs := compileCorpus(compiler.Config{Target: "simplicityhl"}, sources); errs[0] != nil {
+				b.Fatal(errs[0])
+			}
+		}
+	})
+	b.Run("parallel-cached", func(b *testing.B) {
+		for range b.N {
+			config := compiler.Config{Target: "simplicityhl", Packages: compiler.NewPackageCache()}
+			if _, errs := compileCorpus(config, sources); errs[0] != nil {
+				b.Fatal(errs[0])
+			}
+		}
+	})
+}
@@ -21,8 +21,9 @@ func main() {
 `
 
 	c := compiler.New(compiler.Config{
-		Target: "simplicityhl",
-		Debug:  false,
+		AllowTrivialMain: true,
+		Target:           "simplicityhl",
+		Debug:            false,
 	})
 
 	result, err := c.Compile(source, "test.go")
@@ -170,6 +171,135 @@ type Reader interface {
 	}
 }
 
+func TestDynamicTypingRejected(t *testing.T) {
+	testCases := []struct {
+		name   string
+		body   string
+		errors []string
+	}{
+		{
+			name:   "Assertion",
+			body:   "n := v.(uint64)\n\t_ = n",
+			errors: []string{"dyn.go:6:7: type assertion v.(uint64) is not supported"},
+		},
+		{
+			name:   "Comma-ok assertion",
+			body:   "n, ok := v.(uint64)\n\t_, _ = n, ok",
+			errors: []string{"dyn.go:6:11: type assertion v.(uint64) is not supported"},
+		},
+		{
+			name:   "Assertion as call argument",
+			body:   "jet.Verify(jet.Le64(v.(uint64), 10))",
+			errors: []string{"dyn.go:6:22: type assertion v.(uint64) is not supported"},
+		},
+		{
+			name:   "Assertion in a range loop",
+			body:   "for range [3]bool{} {\n\t\t_ = v.(bool)\n\t}",
+			errors: []string{"range loops are not supported", "dyn.go:7:7: type assertion v.(bool) is not supported"},
+		},
+		{
+			name:   "Type switch",
+			body:   "switch n := v.(type) {\n\tcase uint64:\n\t\t_ = n\n\t}",
+			errors: []string{"dyn.go:6:2: type switch on v is not supported"},
+		},
+		{
+			name: "Assertion in a type switch case",
+			body: "switch v.(type) {\n\tcase bool:\n\t\t_ = v.(bool)\n\t}",
+			errors: []string{
+				"dyn.go:6:2: type switch on v is not supported",
+				"dyn.go:8:7: type assertion v.(bool) is not supported",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			source := "package main\n\nimport \"simplicity/jet\"\n\nfunc check(v any) {\n\t" + tc.body + "\n}\n"
+			_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "dyn.go")
+			if err == nil {
+				t.Fatal("Expected compilation to fail")
+			}
+			for _, want := range tc.errors {
+				if !contains(err.Error(), want) {
+					t.Errorf("Expected error containing '%s', got: %v", want, err)
+				}
+			}
+			// The x.(type) guard belongs to the switch error
+			if contains(err.Error(), "v.(type)") {
+				t.Errorf("Type switch guard reported as an assertion: %v", err)
+			}
+			if !contains(err.Error(), "sum type") {
+				t.Errorf("Expected a sum type suggestion, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestStringsRejected(t *testing.T) {
+	testCases := []struct {
+		name  string
+		decls string
+		want  string
+	}{
+		{"Typed variable", "func main() {\n\tvar memo string\n\t_ = memo\n}", "str.go:4:6: memo has type string"},
+		{"Inferred variable", "func main() {\n\tmemo := \"hi\"\n\t_ = memo\n}", `str.go:4:10: string literal "hi" is not supported`},
+		{"Parameter", "func label(a uint64, s string) bool {\n\treturn true\n}\n\nfunc main() {}", "str.go:3:24: s has type string"},
+		{"Result", "func label() string {\n\treturn \"\"\n}\n\nfunc main() {}", "str.go:3:14: unnamed field has type string"},
+		{"Struct field", "type Order struct {\n\tID, Memo string\n}\n\nfunc main() {}", "str.go:4:11: ID, Memo has type string"},
+		{"Array of strings", "func main() {\n\tvar tags [2]string\n\t_ = tags\n}", "str.go:4:14: type string is not supported"},
+		{"Typed constant", "const Label string = \"abc\"\n\nfunc main() {}", "str.go:3:7: Label has type string"},
+		{"Non-hex constant", "const Label = \"abc\"\n\nfunc main() {}", "str.go:3:15: constant Label is a string that is not hex"},
+		{"Conversion", "func main() {\n\tvar b [2]byte\n\t_ = string(b)\n}", "str.go:5:6: type string is not supported"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			source := "package main\n\n" + tc.decls + "\n"
+			_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "str.go")
+			if err == nil || !contains(err.Error(), tc.want) {
+				t.Fatalf("Expected error containing '%s', got: %v", tc.want, err)
+			}
+			if !contains(err.Error(), "fixed-size byte array") && !contains(err.Error(), "hex") {
+				t.Errorf("Expected a byte array suggestion, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestStringLiteralsAllowed(t *testing.T) {
+	source := `
+package main
+
+import "simplicity/jet"
+
+const RecipientKey = "0x79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+const Tag = "beef"
+
+func main() {
+	var amount uint64 = 5
+	if amount == 0 {
+		panic("amount must be positive")
+	}
+	jet.Verify(jet.Le64(1, amount))
+}
+`
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "str.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"const RECIPIENT_KEY: [u8; 32] = 0x79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798;",
+		"const TAG: [u8; 2] = 0xbeef;",
+	} {
+		if !contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+	if contains(result, "amount must be positive") {
+		t.Errorf("panic message leaked into the output:\n%s", result)
+	}
+}
+
 func TestSimpleConstants(t *testing.T) {
 	source := `
 package main
@@ -182,8 +312,9 @@ func main() {
 `
 
 	c := compiler.New(compiler.Config{
-		Target: "simplicityhl",
-		Debug:  false,
+		AllowTrivialMain: true,
+		Target:           "simplicityhl",
+		Debug:            false,
 	})
 
 	result, err := c.Compile(source, "test.go")
@@ -376,7 +507,7 @@ func main() {
 `
 	source = strings.Replace(source, "//go:build ignore\n", "", 1)
 
-	c := compiler.New(compiler.Config{Target: "simplicityhl"})
+	c := compiler.New(compiler.Config{Target: "simplicityhl", AllowTrivialMain: true})
 	out, err := c.Compile(source, "test.go")
 	if err != nil {
 		t.Fatalf("compilation failed: %v", err)
@@ -444,8 +575,8 @@ func main() {
 	}
 
 	// Inlined jet calls must appear inside the match arm body (Left arm), not just in fn verify_hashlock
-	if !strings.Contains(out, "Left(data:") {
-		t.Errorf("TestInlinedHelperCall: missing Left(data:...) arm\nfull output:\n%s", out)
+	if !strings.Contains(out, "Left((") {
+		t.Errorf("TestInlinedHelperCall: missing Left((...)) arm\nfull output:\n%s", out)
 	}
 
 	// Count occurrences of sha_256_ctx_8_finalize — should appear at least twice (fn body + inlined)
@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+)
+
+// TestPiecesMakeUpProgram splits every example at every level and joins
+// the pieces back.
+func TestPiecesMakeUpProgram(t *testing.T) {
+	paths, err := filepath.Glob("../examples/*.go")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no examples: %v", err)
+	}
+	for _, level := range []compiler.OptLevel{compiler.OptDefault, compiler.O0, compiler.O1, compiler.O2} {
+		for _, path := range paths {
+			config := exampleConfig(path)
+			config.OptLevel = level
+			c := compiler.New(config)
+			if _, err := c.Compile(loadExample(t, path), path); err != nil {
+				continue // Examples that need imports are compiled elsewhere
+			}
+			result := c.Result()
+			var codes []string
+			for _, piece := range result.Pieces {
+				if strings.HasPrefix(piece.Code, "\n") || strings.HasSuffix(piece.Code, "\n") {
+					t.Errorf("%s %s: piece %s has blank lines around it", path, level, piece.Name)
+				}
+				codes = append(codes, piece.Code)
+			}
+			if got := strings.Join(codes, "\n\n") + "\n"; got != result.Code {
+				t.Errorf("%s %s: pieces joined:\n%s\nprogram:\n%s", path, level, got, result.Code)
+			}
+		}
+	}
+}
+
+func TestPieces(t *testing.T) {
+	source := `package main
+
+import "simplicity/jet"
+
+// aboveMinimum checks an amount.
+func aboveMinimum(amount uint64) bool {
+	return jet.Le64(5, amount)
+}
+
+func main() {
+	var amount uint64
+	jet.Verify(aboveMinimum(amount))
+}
+`
+	c := compiler.New(compiler.Config{Target: "simplicityhl", OptLevel: compiler.O0})
+	if _, err := c.Compile(source, "contract.go"); err != nil {
+		t.Fatal(err)
+	}
+	pieces := c.Result().Pieces
+	if len(pieces) != 3 {
+		t.Fatalf("%d pieces, want 3: %v", len(pieces), pieces)
+	}
+	if pieces[0].Name != "" || !strings.HasPrefix(pieces[0].Code, "mod witness {") {
+		t.Errorf("first piece %q:\n%s", pieces[0].Name, pieces[0].Code)
+	}
+	if pieces[1].Name != "above_minimum" || !strings.HasPrefix(pieces[1].Code, "// aboveMinimum checks an amount.\nfn above_minimum(amount: u64) -> bool {") {
+		t.Errorf("second piece %q:\n%s", pieces[1].Name, pieces[1].Code)
+	}
+	if pieces[2].Name != "main" || !strings.HasSuffix(pieces[2].Code, "}") {
+		t.Errorf("third piece %q:\n%s", pieces[2].Name, pieces[2].Code)
+	}
+}
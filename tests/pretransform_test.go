@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"errors"
+	"go/ast"
+	"go/token"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/ast/astutil"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+)
+
+// positiveContract calls a project DSL helper that the transpiler does not
+// know; lowerMustBePositive rewrites it before transpilation.
+const positiveContract = `
+package main
+
+import "simplicity/jet"
+
+func main() {
+	height := jet.TxLockHeight()
+	ok := MustBePositive(height)
+	jet.Verify(ok)
+}
+`
+
+var errArity = errors.New("MustBePositive takes one argument")
+
+// lowerMustBePositive rewrites MustBePositive(x) into x > 0.
+func lowerMustBePositive(file *ast.File, _ *token.FileSet) error {
+	var err error
+	astutil.Apply(file, func(c *astutil.Cursor) bool {
+		call, ok := c.Node().(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		if ident, ok := call.Fun.(*ast.Ident); !ok || ident.Name != "MustBePositive" {
+			return true
+		}
+		if len(call.Args) != 1 {
+			err = errArity
+			return false
+		}
+		c.Replace(&ast.BinaryExpr{
+			X:     call.Args[0],
+			OpPos: call.Lparen,
+			Op:    token.GTR,
+			Y:     &ast.BasicLit{ValuePos: call.Rparen, Kind: token.INT, Value: "0"},
+		})
+		return false
+	}, nil)
+	return err
+}
+
+func TestPreTransforms(t *testing.T) {
+	var ran []int
+	record := func(i int) func(*ast.File, *token.FileSet) error {
+		return func(*ast.File, *token.FileSet) error {
+			ran = append(ran, i)
+			return nil
+		}
+	}
+	c := compiler.New(compiler.Config{
+		Target:        "simplicityhl",
+		PreTransforms: []func(*ast.File, *token.FileSet) error{record(0), lowerMustBePositive, record(2)},
+	})
+	out, err := c.Compile(positiveContract, "positive.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	if !strings.Contains(out, "let ok: bool = jet::lt_32(0, height);") {
+		t.Errorf("expected the rewritten comparison in output:\n%s", out)
+	}
+	if len(ran) != 2 || ran[0] != 0 || ran[1] != 2 {
+		t.Errorf("hooks ran out of order: %v", ran)
+	}
+}
+
+func TestPreTransformError(t *testing.T) {
+	src := strings.Replace(positiveContract, "MustBePositive(height)", "MustBePositive(height, 1)", 1)
+	c := compiler.New(compiler.Config{
+		Target:        "simplicityhl",
+		PreTransforms: []func(*ast.File, *token.FileSet) error{func(*ast.File, *token.FileSet) error { return nil }, lowerMustBePositive},
+	})
+	_, err := c.Compile(src, "positive.go")
+	if !errors.Is(err, errArity) || !strings.Contains(err.Error(), "pre-transform 1:") {
+		t.Errorf("expected the hook error wrapped with its index, got %v", err)
+	}
+}
+
+func TestPreTransformPanic(t *testing.T) {
+	c := compiler.New(compiler.Config{
+		Target:        "simplicityhl",
+		PreTransforms: []func(*ast.File, *token.FileSet) error{lowerMustBePositive, func(*ast.File, *token.FileSet) error { panic("boom") }},
+	})
+	_, err := c.Compile(positiveContract, "positive.go")
+	var internal *compiler.InternalError
+	if !errors.As(err, &internal) {
+		t.Fatalf("expected an *InternalError, got %v", err)
+	}
+	if internal.Phase != "pre-transform 1" || internal.Value != "boom" || internal.Version != compiler.Version || !strings.Contains(string(internal.Stack), "pretransform_test.go") {
+		t.Errorf("unexpected internal error %+v", internal)
+	}
+	if err.Error() != "panic during pre-transform 1: boom" {
+		t.Errorf("error reads %q", err)
+	}
+
+	// A hook that leaves the AST malformed panics in a later phase; the
+	// process carries on.
+	c = compiler.New(compiler.Config{
+		Target: "simplicityhl",
+		PreTransforms: []func(*ast.File, *token.FileSet) error{lowerMustBePositive, func(file *ast.File, _ *token.FileSet) error {
+			ast.Inspect(file, func(n ast.Node) bool {
+				if call, ok := n.(*ast.CallExpr); ok && len(call.Args) == 1 {
+					call.Args[0] = nil
+					return false
+				}
+				return true
+			})
+			return nil
+		}},
+	})
+	_, err = c.Compile(positiveContract, "positive.go")
+	if !errors.As(err, &internal) || internal.Phase != "resolution" {
+		t.Fatalf("expected a panic during resolution, got %v", err)
+	}
+
+	// A panic with an error unwraps to it.
+	c = compiler.New(compiler.Config{
+		Target:        "simplicityhl",
+		PreTransforms: []func(*ast.File, *token.FileSet) error{func(*ast.File, *token.FileSet) error { panic(errArity) }},
+	})
+	if _, err := c.Compile(positiveContract, "positive.go"); !errors.Is(err, errArity) {
+		t.Errorf("expected the panic value to be unwrapped, got %v", err)
+	}
+	if _, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(strings.Replace(positiveContract, "MustBePositive(height)", "height > 0", 1), "positive.go"); err != nil {
+		t.Errorf("compile after a panic failed: %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/transpiler"
+)
+
+const printerSource = `
+package main
+
+import "simplicity/jet"
+
+const OwnerPubkey = 0x9bef8d556d80e43ae7e0becb3f7de6b4e5e4f7e8d9a0b1c2d3e4f5a6b7c8d9e0
+
+func IsValid(ok bool) bool {
+    return ok
+}
+
+func main() {
+    var sig [64]byte
+    msg := jet.SigAllHash()
+    jet.BIP340Verify(OwnerPubkey, msg, sig)
+}
+`
+
+func compileWithStyle(t *testing.T, style transpiler.Style) string {
+	t.Helper()
+	c := compiler.New(compiler.Config{Target: "simplicityhl", Style: style})
+	out, err := c.Compile(printerSource, "printer.go")
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	return out
+}
+
+// TestPrinterDefaultStyle locks the default layout byte for byte.
+func TestPrinterDefaultStyle(t *testing.T) {
+	want := "mod witness {\n" +
+		"    const SIG: [u8; 64] = 0x" + strings.Repeat("00", 64) + ";\n" +
+		"}\n" +
+		"mod param {\n" +
+		"    const OWNER_PUBKEY: u256 = 0x9bef8d556d80e43ae7e0becb3f7de6b4e5e4f7e8d9a0b1c2d3e4f5a6b7c8d9e0;\n" +
+		"}\n" +
+		"\n" +
+		"fn is_valid(ok: bool) -> bool {\n" +
+		"    ok\n" +
+		"}\n" +
+		"\n" +
+		"fn main() {\n" +
+		"    let msg: u256 = jet::sig_all_hash();\n" +
+		"    jet::bip_0340_verify((param::OWNER_PUBKEY, msg), witness::SIG);\n" +
+		"}\n"
+
+	if got := compileWithStyle(t, transpiler.DefaultStyle()); got != want {
+		t.Errorf("default style mismatch\ngot:\n%s\nwant:\n%s", got, want)
+	}
+	// The zero Style must behave exactly like DefaultStyle.
+	if got := compileWithStyle(t, transpiler.Style{}); got != want {
+		t.Errorf("zero style should select the default\ngot:\n%s", got)
+	}
+}
+
+// TestPrinterCustomStyle checks two-space indentation, extra blank lines, and
+// no trailing newline.
+func TestPrinterCustomStyle(t *testing.T) {
+	out := compileWithStyle(t, transpiler.Style{
+		Indent:                 "  ",
+		BlankLinesBetweenFuncs: 2,
+		TrailingNewline:        false,
+	})
+
+	if !strings.Contains(out, "\n  const OWNER_PUBKEY: u256") {
+		t.Errorf("expected two-space indented param entry\n%s", out)
+	}
+	if !strings.Contains(out, "}\n\n\nfn is_valid(ok: bool) -> bool {\n  ok\n}\n\n\nfn main() {") {
+		t.Errorf("expected two blank lines between functions\n%s", out)
+	}
+	if strings.HasSuffix(out, "\n") {
+		t.Errorf("output should not end with a newline: %q", out[len(out)-10:])
+	}
+	if strings.Contains(out, "    ") {
+		t.Errorf("four-space indentation leaked into two-space output\n%s", out)
+	}
+}
+
+// TestPrinterNestedIndentation checks that nested match arms are re-indented
+// with the configured unit at every depth.
+func TestPrinterNestedIndentation(t *testing.T) {
+	source := `
+package main
+
+import "simplicity/jet"
+
+const AlicePubkey = 0x9bef8d556d80e43ae7e0becb3f7de6b4e5e4f7e8d9a0b1c2d3e4f5a6b7c8d9e0
+const BobPubkey = 0xe37d58a1aae4ba05c9b2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4
+
+type W struct {
+    IsLeft  bool
+    AliceSig [64]byte
+    BobSig   [64]byte
+}
+
+func main() {
+    var w W
+    if w.IsLeft {
+        msg := jet.SigAllHash()
+        jet.BIP340Verify(AlicePubkey, msg, w.AliceSig)
+    } else {
+        msg := jet.SigAllHash()
+        jet.BIP340Verify(BobPubkey, msg, w.BobSig)
+    }
+}
+`
+	c := compiler.New(compiler.Config{Target: "simplicityhl", Style: transpiler.Style{
+		Indent:                 "\t",
+		BlankLinesBetweenFuncs: 1,
+		TrailingNewline:        true,
+	}})
+	out, err := c.Compile(source, "nested.go")
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+
+	for _, want := range []string{
+		"\n\tmatch witness::W {\n",
+		"\n\t\tLeft(data: [u8; 64]) => {\n",
+		"\n\t\t\tlet msg = jet::sig_all_hash();\n",
+		"\n\t\t},\n",
+		"\n\t}\n}\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in tab-indented output\n%s", want, out)
+		}
+	}
+}
+
+// TestTerminatorGolden checks the semicolons of the programs in
+// tests/testdata/terminators against their goldens: one after each
+// statement, none after the result of a function or an arm. Each program
+// must also parse, which the self-check confirms.
+func TestTerminatorGolden(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "terminators", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) < 4 {
+		t.Fatalf("found %d programs in testdata/terminators, want at least 4", len(paths))
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".go")
+		t.Run(name, func(t *testing.T) {
+			src, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := compiler.New(compiler.Config{Target: "simplicityhl", SelfCheck: true}).Compile(string(src), path)
+			if err != nil {
+				t.Fatalf("compile: %v", err)
+			}
+			golden, err := os.ReadFile(filepath.Join("testdata", "terminators", name+".simf"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			header, want, _ := strings.Cut(string(golden), "\n")
+			if header != "// Code generated by simgo from "+name+".go. DO NOT EDIT." || result != want {
+				t.Errorf("output differs from tests/testdata/terminators/%[1]s.simf; regenerate it with\n\tgo run ./cmd/simgo -input tests/testdata/terminators/%[1]s.go -output tests/testdata/terminators/%[1]s.simf\ngot:\n%[2]s", name, result)
+			}
+		})
+	}
+}
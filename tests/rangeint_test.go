@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/equiv"
+)
+
+const rangeIntSource = `package main
+
+import "simplicity/jet"
+
+func Sum(xs [4]uint64) uint64 {
+	var total uint64
+	LOOP {
+		total += xs[i]
+	}
+	return total
+}
+
+func Count(x uint8, flag bool) bool {
+	var n uint8
+	LOOP {
+		n += x
+	}
+	return jet.Le8(n, 90)
+}
+
+func main() {
+	var xs [4]uint64
+	jet.Verify(jet.Le64(10, Sum(xs)))
+}
+`
+
+// TestRangeIntLoop checks that a range over an integer literal compiles
+// to what the counted loop it abbreviates compiles to.
+func TestRangeIntLoop(t *testing.T) {
+	compile := func(loop string) string {
+		t.Helper()
+		src := strings.ReplaceAll(rangeIntSource, "LOOP", loop)
+		out, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(src, "range.go")
+		if err != nil {
+			t.Fatalf("%s: %v", loop, err)
+		}
+		return out
+	}
+	want := compile("for i := 0; i < 4; i++")
+	if got := compile("for i := range 4"); got != want {
+		t.Errorf("for i := range 4 compiles to\n%s\nwant\n%s", got, want)
+	}
+	if !strings.Contains(want, "xs_3") {
+		t.Errorf("expected the loop to be unrolled into four reads of xs:\n%s", want)
+	}
+
+	// A named constant bound is its value, at package level or in the
+	// function, written with iota or other constants, in a range and in the
+	// counted loop written out alike.
+	for _, tt := range []struct{ decl, local string }{
+		{"const N = 4", ""},
+		{"const (\n\tA = iota\n\tB = iota * 2\n\tN = A + B + iota\n)", ""},
+		{"", "const N uint8 = 4"},
+	} {
+		for _, loop := range []string{"for i := range N", "for i := 0; i < N; i++"} {
+			src := strings.ReplaceAll(rangeIntSource, "LOOP", loop)
+			src = strings.Replace(src, "import \"simplicity/jet\"\n", "import \"simplicity/jet\"\n\n"+tt.decl+"\n", 1)
+			src = strings.ReplaceAll(src, "\n\tvar ", "\n\t"+tt.local+"\n\tvar ")
+			out, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(src, "range.go")
+			if err != nil {
+				t.Errorf("%s%s, %s: %v", tt.decl, tt.local, loop, err)
+			} else if !strings.Contains(out, "xs_3") || strings.Contains(out, "xs_4") {
+				t.Errorf("%s%s, %s: expected the loop to be unrolled into four reads of xs:\n%s", tt.decl, tt.local, loop, out)
+			}
+		}
+	}
+
+	for _, loop := range []string{"for range 3", "for i := 0; i < N; i++"} {
+		src := strings.ReplaceAll(rangeIntSource, "LOOP", loop)
+		src = strings.Replace(src, "import \"simplicity/jet\"\n", "import \"simplicity/jet\"\n\nconst N = 3\n", 1)
+		report, err := equiv.Check(src, "range.go", compiler.Config{Entry: "Count"}, equiv.Options{})
+		if err != nil {
+			t.Fatalf("%s: Check: %v", loop, err)
+		}
+		if !report.Exhaustive || len(report.Mismatches) != 0 {
+			t.Errorf("%s: Count: %d cases, exhaustive %v, mismatches %v; want all to agree", loop, report.Cases, report.Exhaustive, report.Mismatches)
+		}
+	}
+}
+
+func TestRangeIntRejected(t *testing.T) {
+	for _, tt := range []struct {
+		name, loop, want string
+	}{
+		{"variable", "n := uint32(4)\n\tfor i := range n {", "range.go:8:2: range loops are not supported in Simplicity: a range over an integer constant, as in for i := range 4 or range N with const N = 4, is unrolled like for i := 0; i < 4; i++, but this one ranges over n"},
+		{"array", "for i := range xs {", "ranges over xs"},
+		{"assign", "var i int\n\tfor i = range 4 {", "ranges over 4"},
+	} {
+		src := "package main\n\nimport \"simplicity/jet\"\n\nfunc Sum(xs [4]uint64) uint64 {\n\tvar total uint64\n\t" + tt.loop + "\n\t\ttotal += xs[i]\n\t}\n\treturn total\n}\n\nfunc main() {\n\tvar xs [4]uint64\n\tjet.Verify(jet.Le64(10, Sum(xs)))\n}\n"
+		_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(src, "range.go")
+		if err == nil || !strings.Contains(err.Error(), tt.want) || !strings.Contains(err.Error(), "["+string(diag.RangeLoop)+"]") {
+			t.Errorf("%s: expected a range loop error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
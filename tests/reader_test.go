@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/transpiler"
+)
+
+// recordingWriter keeps each Write call separately and fails once more than
+// limit bytes have been written, when limit is positive.
+type recordingWriter struct {
+	writes []string
+	n      int
+	limit  int
+}
+
+var errDiskFull = errors.New("disk full")
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	if w.limit > 0 && w.n+len(p) > w.limit {
+		return 0, errDiskFull
+	}
+	w.writes = append(w.writes, string(p))
+	w.n += len(p)
+	return len(p), nil
+}
+
+func TestCompileReaderMatchesCompile(t *testing.T) {
+	for _, style := range []transpiler.Style{
+		transpiler.DefaultStyle(),
+		{Indent: "\t", BlankLinesBetweenFuncs: 2, TrailingNewline: false},
+	} {
+		config := compiler.Config{Target: "simplicityhl", Entry: "Spend", Style: style}
+		want, err := compiler.New(config).Compile(twoPaths, "paths.go")
+		if err != nil {
+			t.Fatalf("Compile: %v", err)
+		}
+
+		var buf bytes.Buffer
+		if err := compiler.New(config).CompileReader(strings.NewReader(twoPaths), "paths.go", &buf); err != nil {
+			t.Fatalf("CompileReader: %v", err)
+		}
+		if buf.String() != want {
+			t.Errorf("streamed output differs\ngot:\n%q\nwant:\n%q", buf.String(), want)
+		}
+	}
+}
+
+func TestCompileReaderFlushesPerItem(t *testing.T) {
+	w := &recordingWriter{}
+	c := compiler.New(compiler.Config{Target: "simplicityhl", Entry: "Spend"})
+	if err := c.CompileReader(strings.NewReader(twoPaths), "paths.go", w); err != nil {
+		t.Fatalf("CompileReader: %v", err)
+	}
+	// Modules, two functions and main.
+	if len(w.writes) != 4 {
+		t.Fatalf("expected 4 writes, got %d: %q", len(w.writes), w.writes)
+	}
+	for _, prefix := range []string{"mod witness", "fn amount_ok", "fn spend", "fn main"} {
+		found := false
+		for _, write := range w.writes {
+			found = found || strings.HasPrefix(strings.TrimLeft(write, "\n"), prefix)
+		}
+		if !found {
+			t.Errorf("no write starts with %q: %q", prefix, w.writes)
+		}
+	}
+}
+
+func TestCompileReaderWriteError(t *testing.T) {
+	w := &recordingWriter{limit: 60}
+	c := compiler.New(compiler.Config{Target: "simplicityhl", Entry: "Spend"})
+	err := c.CompileReader(strings.NewReader(twoPaths), "paths.go", w)
+	if !errors.Is(err, errDiskFull) {
+		t.Errorf("expected the write error, got %v", err)
+	}
+}
+
+func TestCompileReaderWritesNothingOnFailedCheck(t *testing.T) {
+	source := `package main
+
+import "simplicity/jet"
+
+func main() {
+	jet.Verify(jet.Le64(1, 2))
+}
+`
+	w := &recordingWriter{}
+	err := compiler.New(compiler.Config{Target: "simplicityhl"}).CompileReader(strings.NewReader(source), "main.go", w)
+	if code, _ := diag.CodeOf(err); code != diag.TrivialMain {
+		t.Fatalf("expected %s, got %v", diag.TrivialMain, err)
+	}
+	if len(w.writes) != 0 {
+		t.Errorf("expected nothing written for a rejected program, got %q", w.writes)
+	}
+}
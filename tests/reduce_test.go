@@ -0,0 +1,318 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+)
+
+const reduceSource = `package main
+
+import "simplicity/jet"
+
+func Sum(xs [5]uint64) uint64 {
+	var total uint64
+	for i := 0; i < 5; i++ {
+		total += xs[i]
+	}
+	return total
+}
+
+func Any(b [32]byte) byte {
+	acc := byte(0)
+	for i := 0; i < 32; i++ {
+		acc = acc | b[i]
+	}
+	return acc
+}
+
+func Count(flags [8]bool) uint8 {
+	var n uint8
+	for i := 0; i < 8; i++ {
+		if flags[i] {
+			n++
+		}
+	}
+	return n
+}
+
+func Hash(xs [5]uint64) uint64 {
+	h := uint64(7)
+	for i := 0; i < 5; i++ {
+		h = h*31 + xs[i]
+	}
+	return h
+}
+
+func main() {
+	var xs [5]uint64
+	var b [32]byte
+	var flags [8]bool
+	var sum, hash uint64
+	var any, count uint8
+	jet.Verify(jet.Eq64(Sum(xs), sum))
+	jet.Verify(jet.Eq8(Any(b), any))
+	jet.Verify(jet.Eq8(Count(flags), count))
+	jet.Verify(jet.Eq64(Hash(xs), hash))
+}
+`
+
+func TestReductionTree(t *testing.T) {
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(reduceSource, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		// Five elements pair up as ((0 1) (2 3)) 4, in temporaries named
+		// after the loop.
+		"    let [xs_0, xs_1, xs_2, xs_3, xs_4]: [u64; 5] = xs;\n" +
+			"    let (_, t_7_2): (bool, u64) = jet::add_64(xs_0, xs_1);\n" +
+			"    let (_, t_7_2_2): (bool, u64) = jet::add_64(xs_2, xs_3);\n" +
+			"    let (_, t_7_2_3): (bool, u64) = jet::add_64(t_7_2, t_7_2_2);\n" +
+			"    let (_, total): (bool, u64) = jet::add_64(t_7_2_3, xs_4);\n" +
+			"    total\n",
+		"    let t_15_2_30: u8 = jet::or_8(t_15_2_27, t_15_2_28);\n    let acc: u8 = jet::or_8(t_15_2_29, t_15_2_30);\n",
+		"jet::add_8(match flags_0 { true => 1, false => 0, }, match flags_1 { true => 1, false => 0, });",
+		// A multiply and add per element cannot be regrouped; each
+		// temporary is named after its operator.
+		"    let (_, t_34_12): (bool, u64) = jet::add_64(217, xs_0);\n" +
+			"    let (_, t_34_8): (u64, u64) = <u128>::into(jet::multiply_64(t_34_12, 31));\n" +
+			"    let (_, t_34_12_2): (bool, u64) = jet::add_64(t_34_8, xs_1);\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+}
+
+func TestReductionEquivalence(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for run := 0; run < 20; run++ {
+		var xs [5]uint64
+		var b [32]byte
+		var flags [8]bool
+		for i := range xs {
+			xs[i] = rng.Uint64()
+			if run%2 == 0 {
+				xs[i] >>= 40
+			}
+		}
+		for i := range b {
+			if rng.Intn(4) == 0 && run%3 != 0 {
+				b[i] = byte(rng.Intn(256))
+			}
+		}
+		for i := range flags {
+			flags[i] = rng.Intn(2) == 0
+		}
+
+		// The same loops, run by Go.
+		var sum, hash uint64 = 0, 7
+		var any, count uint8
+		for i := 0; i < 5; i++ {
+			sum += xs[i]
+			hash = hash*31 + xs[i]
+		}
+		for i := 0; i < 32; i++ {
+			any |= b[i]
+		}
+		for i := 0; i < 8; i++ {
+			if flags[i] {
+				count++
+			}
+		}
+
+		words := make([]string, len(xs))
+		for i, x := range xs {
+			words[i] = strconv.FormatUint(x, 10)
+		}
+		bools := make([]string, len(flags))
+		for i, f := range flags {
+			bools[i] = strconv.FormatBool(f)
+		}
+		witness := map[string]string{
+			"XS":    "[" + strings.Join(words, ", ") + "]",
+			"B":     fmt.Sprintf("0x%x", b),
+			"FLAGS": "[" + strings.Join(bools, ", ") + "]",
+			"SUM":   strconv.FormatUint(sum, 10),
+			"HASH":  strconv.FormatUint(hash, 10),
+			"ANY":   strconv.Itoa(int(any)),
+			"COUNT": strconv.Itoa(int(count)),
+		}
+		if _, err := runSource(t, compiler.Config{}, reduceSource, witness); err != nil {
+			t.Fatalf("run %d: %v\nwitness %v", run, err, witness)
+		}
+		// Each result off by one is rejected.
+		for name, wrong := range map[string]string{
+			"SUM":   strconv.FormatUint(sum+1, 10),
+			"HASH":  strconv.FormatUint(hash+1, 10),
+			"ANY":   strconv.Itoa(int(any ^ 1)),
+			"COUNT": strconv.Itoa(int(count+1) % 256),
+		} {
+			right := witness[name]
+			witness[name] = wrong
+			_, err := runSource(t, compiler.Config{}, reduceSource, witness)
+			var rejection *eval.Rejection
+			if !errors.As(err, &rejection) {
+				t.Fatalf("run %d: %s = %s was accepted: %v", run, name, wrong, err)
+			}
+			witness[name] = right
+		}
+	}
+}
+
+const boolReduceSource = `package main
+
+import "simplicity/jet"
+
+func Same(a [4]uint8, b [4]uint8) bool {
+	ok := true
+	for i := 0; i < 4; i++ {
+		ok = ok && a[i] == b[i]
+	}
+	return ok
+}
+
+func Has(a [4]uint8, b uint8) bool {
+	found := false
+	for i := 0; i < 4; i++ {
+		found = found || a[i] == b
+	}
+	return found
+}
+
+func main() {
+	var a [4]uint8
+	var b [4]uint8
+	var c uint8
+	jet.Verify(Same(a, b))
+	jet.Verify(Has(a, c))
+}
+`
+
+// TestBoolReduction checks that && and || over a counted loop pair up as
+// the other reductions do, combined by matches, and agree with the Go.
+func TestBoolReduction(t *testing.T) {
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl", OptLevel: compiler.O0, Strict: true}).Compile(boolReduceSource, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"    let t_7_2: bool = match t_8_19 { true => t_8_19_2, false => false, };\n" +
+			"    let t_7_2_2: bool = match t_8_19_3 { true => t_8_19_4, false => false, };\n" +
+			"    let ok: bool = match t_7_2 { true => t_7_2_2, false => false, };\n    ok\n",
+		"    let found: bool = match t_15_2 { true => true, false => t_15_2_2, };\n    found\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for run := 0; run < 20; run++ {
+		var a, b [4]uint8
+		for i := range a {
+			a[i] = uint8(rng.Intn(3))
+			b[i] = a[i]
+			if rng.Intn(8) == 0 {
+				b[i] = uint8(rng.Intn(3))
+			}
+		}
+		c := uint8(rng.Intn(4))
+
+		// The same loops, run by Go.
+		same, has := true, false
+		for i := 0; i < 4; i++ {
+			same = same && a[i] == b[i]
+			has = has || a[i] == c
+		}
+
+		witness := map[string]string{
+			"A": fmt.Sprintf("0x%x", a),
+			"B": fmt.Sprintf("0x%x", b),
+			"C": strconv.Itoa(int(c)),
+		}
+		_, err := runSource(t, compiler.Config{Strict: true}, boolReduceSource, witness)
+		var rejection *eval.Rejection
+		switch {
+		case same && has && err != nil:
+			t.Fatalf("run %d: rejected: %v\nwitness %v", run, err, witness)
+		case !(same && has) && !errors.As(err, &rejection):
+			t.Fatalf("run %d: same %t, has %t was accepted: %v\nwitness %v", run, same, has, err, witness)
+		}
+	}
+}
+
+// TestAccumulationErrors checks that an accumulation loop that is not
+// lowered is an error, not left out of the program.
+func TestAccumulationErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"divides", `package main
+
+import "simplicity/jet"
+
+func Even(a [4]uint8, b uint8) bool {
+	ok := true
+	for i := 0; i < 4; i++ {
+		ok = ok && a[i]/b == 0
+	}
+	return ok
+}
+
+func main() {
+	var a [4]uint8
+	var b uint8
+	jet.Verify(Even(a, b))
+}
+`, "main.go:7:2: the loop is not lowered: every operand of ok && a[i] / b == 0 is computed, and it divides, which may fail where Go skips it"},
+		{"no lowering", `package main
+
+import "simplicity/jet"
+
+func Odd(a [4]bool) bool {
+	ok := false
+	for i := 0; i < 4; i++ {
+		ok = ok != a[i]
+	}
+	return ok
+}
+
+func main() {
+	var a [4]bool
+	jet.Verify(Odd(a))
+}
+`, "main.go:7:2: the loop is not lowered: its update of ok has no lowering"},
+		{"main", `package main
+
+import "simplicity/jet"
+
+func main() {
+	var a [4]uint8
+	var b [4]uint8
+	ok := true
+	for i := 0; i < 4; i++ {
+		ok = ok && a[i] == b[i]
+	}
+	jet.Verify(ok)
+}
+`, "main.go:10:3: the assignment in iteration i = 0 cannot be lowered: in main a loop accumulates only a count of signatures, as if sigs[i].IsSome { count++ } does; compute the result in a helper that accumulates it"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(tc.src, "main.go")
+			if code, _ := diag.CodeOf(err); code != diag.UnsupportedSyntax || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected an error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
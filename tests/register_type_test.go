@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/types"
+)
+
+const oracleContract = `
+package main
+
+import "simplicity/jet"
+
+type OracleKey [33]byte
+
+func Attest(key OracleKey, height uint32) bool {
+	return jet.Le32(800000, height)
+}
+`
+
+const oraclePackageContract = `
+package main
+
+import (
+	"simplicity/jet"
+
+	feed "github.com/acme/oracle"
+)
+
+func Attest(key feed.Key, height uint32) bool {
+	return jet.Le32(800000, height)
+}
+`
+
+func TestRegisterType(t *testing.T) {
+	mapper := types.NewTypeMapper()
+	if err := mapper.RegisterType("OracleKey", "(u8, u256)"); err != nil {
+		t.Fatalf("RegisterType: %v", err)
+	}
+
+	c := compiler.New(compiler.Config{Target: "simplicityhl", Entry: "Attest", TypeMapper: mapper})
+	out, err := c.Compile(oracleContract, "oracle.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{"const KEY: (u8, u256)", "fn attest(key: (u8, u256), height: u32)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+
+	// Other mappers keep the Go declaration.
+	out, err = compiler.New(compiler.Config{Target: "simplicityhl", Entry: "Attest"}).Compile(oracleContract, "oracle.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	if strings.Contains(out, "(u8, u256)") {
+		t.Errorf("registration leaked into the default mapper:\n%s", out)
+	}
+}
+
+func TestRegisterPackageType(t *testing.T) {
+	mapper := types.NewTypeMapper()
+	if err := mapper.RegisterPackageType("github.com/acme/oracle", "Key", "[u8; 33]"); err != nil {
+		t.Fatalf("RegisterPackageType: %v", err)
+	}
+
+	c := compiler.New(compiler.Config{Target: "simplicityhl", Entry: "Attest", TypeMapper: mapper})
+	out, err := c.Compile(oraclePackageContract, "oracle.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	if !strings.Contains(out, "fn attest(key: [u8; 33], height: u32)") {
+		t.Errorf("expected the aliased package type to resolve:\n%s", out)
+	}
+}
+
+func TestRegisterTypeErrors(t *testing.T) {
+	mapper := types.NewTypeMapper()
+	tests := []struct {
+		goName, simType, want string
+	}{
+		{"OracleKey", "(u8, u256", "invalid SimplicityHL type"},
+		{"OracleKey", "Point", "type Point has no known bit size"},
+		{"uint64", "u32", "conflicts with the builtin mapping to u64"},
+	}
+	for _, tt := range tests {
+		err := mapper.RegisterType(tt.goName, tt.simType)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("RegisterType(%q, %q): expected error containing %q, got %v", tt.goName, tt.simType, tt.want, err)
+		}
+	}
+
+	if err := mapper.RegisterType("Height", "u32"); err != nil {
+		t.Fatalf("RegisterType: %v", err)
+	}
+	if err := mapper.RegisterType("Height", "u32"); err != nil {
+		t.Errorf("re-registering the same type should succeed: %v", err)
+	}
+	if err := mapper.RegisterType("Height", "u64"); err == nil || !strings.Contains(err.Error(), "already registered as u32") {
+		t.Errorf("expected a re-registration error, got %v", err)
+	}
+	if err := mapper.OverrideType("uint64", "u32"); err != nil {
+		t.Errorf("OverrideType should replace a builtin: %v", err)
+	}
+}
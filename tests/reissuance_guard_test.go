@@ -0,0 +1,213 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+	"github.com/0ceanslim/go-simplicity/pkg/testkeys"
+)
+
+func TestReissuanceGuardGolden(t *testing.T) {
+	result := compileGolden(t, "reissuance_guard")
+	if !strings.Contains(result, "match unwrap(jet::issuance_asset_amount(index)) {") {
+		t.Errorf("expected a match on the issued amount:\n%s", result)
+	}
+}
+
+func TestReissuanceGuardSpendPaths(t *testing.T) {
+	prog, err := shlparse.Parse(compileGolden(t, "reissuance_guard"))
+	if err != nil {
+		t.Fatalf("generated program does not parse: %v", err)
+	}
+	txJSON := string(readExample(t, "reissuance_guard.tx.json"))
+	witness, err := eval.ParseWitnessJSON(readExample(t, "reissuance_guard.witness.json"))
+	if err != nil {
+		t.Fatalf("ParseWitnessJSON: %v", err)
+	}
+	const guard = "0x4e9b7d3f1a5c8e2b6d0f4a8c2e6b0d4f8a2c6e0b4d8f2a6c0e4b8d2f6a0c4e8b"
+	const token = "0x8f2d4b6a1c3e5f7092a4b6c8d0e2f4a6b8c0d2e4f6a8b0c2d4e6f8a0b2c4d6e8"
+	const reissued = "0xb5e3a17c9d0f2b4a6c8e0d1f3a5b7c9e1d3f5a7b9c0e2d4f6a8b1c3e5d7f9a0b"
+
+	tests := []struct {
+		name   string
+		edit   func(string) string
+		reject string // "" to accept
+	}{
+		{"issuer reissues", nil, ""},
+		{"issuer reissues the cap", func(s string) string {
+			return strings.Replace(s, `"asset_amount": 250000`, `"asset_amount": 1000000`, 1)
+		}, ""},
+		{"issuer reissues over the cap", func(s string) string {
+			return strings.Replace(s, `"asset_amount": 250000`, `"asset_amount": 1000001`, 1)
+		}, "assertion failed"},
+		{"issuer moves the token", func(s string) string {
+			start := strings.Index(s, `,
+      "issuance"`)
+			end := strings.Index(s[start:], "}") + start + 1
+			return s[:start] + s[end:]
+		}, ""},
+		{"token leaves the guard", func(s string) string {
+			return strings.Replace(s, `"script_hash": "`+guard+`"
+    }`, `"script_hash": "0x0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f9"
+    }`, 1)
+		}, "assertion failed"},
+		{"output 0 pays the reissued asset", func(s string) string {
+			i := strings.Index(s, `"outputs"`)
+			return s[:i] + strings.Replace(s[i:], token, reissued, 1)
+		}, "assertion failed"},
+		{"signature for another transaction", func(s string) string {
+			return strings.Replace(s, strings.ToLower(testkeys.Vector1Msg), testkeys.Vector0Msg, 1)
+		}, "bip_0340_verify"},
+	}
+	for _, tt := range tests {
+		data := txJSON
+		if tt.edit != nil {
+			data = tt.edit(data)
+		}
+		tx, err := eval.ParseTx([]byte(data))
+		if err != nil {
+			t.Fatalf("%s: ParseTx: %v", tt.name, err)
+		}
+		err = eval.Run(prog, eval.Options{Witness: witness, Tx: tx})
+		if tt.reject == "" {
+			if err != nil {
+				t.Errorf("%s: expected accept, got %v", tt.name, err)
+			}
+			continue
+		}
+		var rejection *eval.Rejection
+		if !errors.As(err, &rejection) || !strings.Contains(rejection.Reason, tt.reject) {
+			t.Errorf("%s: expected %s to reject, got %v", tt.name, tt.reject, err)
+		}
+	}
+}
+
+// issuanceTx has a reissuance at input 0, a new issuance at input 1 and a
+// peg-in at input 2, which is the one being spent.
+const issuanceTx = `{
+  "current_index": 2,
+  "inputs": [
+    {"prev_txid": "0x` + hex11 + `", "prev_vout": 0, "value": 1, "script_hash": "0x` + hex22 + `",
+     "issuance": {"asset_amount": 500, "token_amount": 0, "blinding_nonce": "0x` + hex33 + `", "entropy": "0x` + hex44 + `"}},
+    {"prev_txid": "0x` + hex11 + `", "prev_vout": 1, "value": 1, "script_hash": "0x` + hex22 + `",
+     "issuance": {"asset_amount": 700, "token_amount": 3, "contract_hash": "0x` + hex55 + `"}},
+    {"prev_txid": "0x` + hex11 + `", "prev_vout": 2, "value": 1, "script_hash": "0x` + hex22 + `", "pegin": "0x` + hex66 + `"}
+  ],
+  "outputs": []
+}`
+
+const (
+	hex11 = "1111111111111111111111111111111111111111111111111111111111111111"
+	hex22 = "2222222222222222222222222222222222222222222222222222222222222222"
+	hex33 = "3333333333333333333333333333333333333333333333333333333333333333"
+	hex44 = "4444444444444444444444444444444444444444444444444444444444444444"
+	hex55 = "5555555555555555555555555555555555555555555555555555555555555555"
+	hex66 = "6666666666666666666666666666666666666666666666666666666666666666"
+)
+
+func TestIssuanceJets(t *testing.T) {
+	tx, err := eval.ParseTx([]byte(issuanceTx))
+	if err != nil {
+		t.Fatalf("ParseTx: %v", err)
+	}
+	// present tests the value the jet reads at an input, absent that the
+	// jet reads none there.
+	present := func(call, check string) string {
+		return "if v := " + call + "; v != nil {\n\t\t" + check + "\n\t} else {\n\t\tjet.Verify(false)\n\t}"
+	}
+	absent := func(call string) string {
+		return "if v := " + call + "; v == nil {\n\t\tjet.Verify(true)\n\t} else {\n\t\tjet.Verify(false)\n\t}"
+	}
+	tests := []struct {
+		name, body, want string
+	}{
+		{"reissuance", "is := jet.InputIsIssuance(0)\n\tif is {\n\t\tjet.Verify(true)\n\t} else {\n\t\tjet.Verify(false)\n\t}", "std_input_is_issuance(0)"},
+		{"new issuance", "is := jet.InputIsIssuance(1)\n\tif is {\n\t\tjet.Verify(true)\n\t} else {\n\t\tjet.Verify(false)\n\t}", "std_input_is_issuance(1)"},
+		{"no issuance", "is := jet.InputIsIssuance(2)\n\tif is {\n\t\tjet.Verify(false)\n\t} else {\n\t\tjet.Verify(true)\n\t}", "std_input_is_issuance(2)"},
+		{"reissued amount", present("jet.IssuanceAssetAmount(0)", "jet.Verify(jet.Eq64(*v, 500))"), "Some(t_6_10: Amount1) =>"},
+		{"issued amount", present("jet.IssuanceAssetAmount(1)", "jet.Verify(jet.Eq64(*v, 700))"), "let v: u64 = unwrap_right::<(u1, u256)>(t_6_10);"},
+		{"issued tokens", present("jet.IssuanceTokenAmount(1)", "jet.Verify(jet.Eq64(*v, 3))"), "Some(t_6_10: TokenAmount1) =>"},
+		{"no issued amount", absent("jet.IssuanceAssetAmount(2)"), "match unwrap(jet::issuance_asset_amount(2)) {"},
+		{"contract hash", present("jet.NewIssuanceContract(1)", "jet.Verify(jet.Eq256(*v, 0x"+hex55+"))"), "match unwrap(jet::new_issuance_contract(1)) {"},
+		{"no contract hash on a reissuance", absent("jet.NewIssuanceContract(0)"), "Some(v: u256) =>"},
+		{"blinding nonce", present("jet.ReissuanceBlinding(0)", "jet.Verify(jet.Eq256(*v, 0x"+hex33+"))"), "match unwrap(jet::reissuance_blinding(0)) {"},
+		{"no blinding nonce on a new issuance", absent("jet.ReissuanceBlinding(1)"), "match unwrap(jet::reissuance_blinding(1)) {"},
+		{"entropy", present("jet.ReissuanceEntropy(0)", "jet.Verify(jet.Eq256(*v, 0x"+hex44+"))"), "match unwrap(jet::reissuance_entropy(0)) {"},
+		{"peg-in", present("jet.InputPegin(2)", "jet.Verify(jet.Eq256(*v, 0x"+hex66+"))"), "match unwrap(jet::input_pegin(2)) {"},
+		{"no peg-in", absent("jet.InputPegin(0)"), "match unwrap(jet::input_pegin(0)) {"},
+		{"current peg-in", present("jet.CurrentPegin()", "jet.Verify(jet.Eq256(*v, 0x"+hex66+"))"), "match jet::current_pegin() {"},
+	}
+	for _, tt := range tests {
+		source := "package main\n\nimport \"simplicity/jet\"\n\nfunc main() {\n\t" + tt.body + "\n}\n"
+		result, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "contract.go")
+		if err != nil {
+			t.Errorf("%s: compile: %v", tt.name, err)
+			continue
+		}
+		if !strings.Contains(result, tt.want) {
+			t.Errorf("%s: expected %q in\n%s", tt.name, tt.want, result)
+		}
+		prog, err := shlparse.Parse(result)
+		if err != nil {
+			t.Errorf("%s: generated program does not parse: %v", tt.name, err)
+			continue
+		}
+		if err := eval.Run(prog, eval.Options{Tx: tx}); err != nil {
+			t.Errorf("%s: expected accept, got %v\n%s", tt.name, err, result)
+		}
+	}
+
+	// The issuance jets unwrap the input at the index, which must exist.
+	source := "package main\n\nimport \"simplicity/jet\"\n\nfunc main() {\n\t" + present("jet.IssuanceAssetAmount(3)", "jet.Verify(true)") + "\n}\n"
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "contract.go")
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	prog, err := shlparse.Parse(result)
+	if err != nil {
+		t.Fatalf("generated program does not parse: %v", err)
+	}
+	var rejection *eval.Rejection
+	if err := eval.Run(prog, eval.Options{Tx: tx}); !errors.As(err, &rejection) {
+		t.Errorf("input 3: expected a rejection, got %v", err)
+	}
+}
+
+func TestIssuanceJetsOnBitcoin(t *testing.T) {
+	source := `package main
+
+import "simplicity/jet"
+
+func main() {
+	is := jet.InputIsIssuance(0)
+	if is {
+		jet.Verify(false)
+	} else {
+		jet.Verify(true)
+	}
+}
+`
+	_, err := compiler.New(compiler.Config{Target: "simplicityhl", Chain: "bitcoin"}).Compile(source, "contract.go")
+	want := "contract.go:6:8: jet.InputIsIssuance (jet::issuance) reads the transaction environment, which SimplicityHL defines only for chain elements; chain bitcoin, set by -chain or Config.Chain, has the core jets alone"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected error containing %q, got %v", want, err)
+	}
+
+	_, err = compiler.New(compiler.Config{Target: "simplicityhl", Chain: "bitcoin"}).Compile(`package main
+
+import "simplicity/jet"
+
+func main() {
+	if v := jet.InputPegin(0); v != nil {
+		jet.Verify(false)
+	}
+}
+`, "contract.go")
+	if err == nil || !strings.Contains(err.Error(), "jet.InputPegin (jet::input_pegin) reads the transaction environment") {
+		t.Errorf("expected a chain error for jet.InputPegin, got %v", err)
+	}
+}
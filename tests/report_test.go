@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/report"
+)
+
+const twoPaths = `
+package main
+
+import "simplicity/jet"
+
+func amountOk(amount uint64) bool {
+	return jet.Le64(1000, amount)
+}
+
+func Spend(amount uint64) bool {
+	return amountOk(amount)
+}
+
+func Refund(height uint32) bool {
+	return jet.Le32(800000, height)
+}
+`
+
+func TestCompileReport(t *testing.T) {
+	var results []*compiler.CompileResult
+	for _, entry := range []string{"Spend", "Refund"} {
+		c := compiler.New(compiler.Config{Target: "simplicityhl", Entry: entry})
+		if _, err := c.Compile(twoPaths, "paths.go"); err != nil {
+			t.Fatalf("Compilation of %s failed: %v", entry, err)
+		}
+		results = append(results, c.Result())
+	}
+
+	var buf bytes.Buffer
+	if err := report.New("paths.go", results...).Write(&buf); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	r, err := report.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	if len(r.Programs) != 2 || r.Programs[0].Witnesses[0].Name != "AMOUNT" || r.Programs[1].Witnesses[0].Type != "u32" {
+		t.Errorf("unexpected programs: %+v", r.Programs)
+	}
+	if w := r.Programs[0].Witnesses[0]; w.Display != "0" || w.Encoding != "0000000000000000" {
+		t.Errorf("expected AMOUNT to display as 0 and encode as 8 zero bytes, got %+v", w)
+	}
+	functions := make(map[string]report.Function)
+	for _, fn := range r.Functions {
+		functions[fn.GoName] = fn
+	}
+	helper := functions["amountOk"]
+	if helper.Name != "amount_ok" || helper.Returns != "bool" || !slices.Equal(helper.Jets, []string{"le_64"}) {
+		t.Errorf("unexpected helper: %+v", helper)
+	}
+	if !slices.Equal(helper.Entries, []string{"Spend"}) {
+		t.Errorf("amountOk should be reached from Spend only, got %v", helper.Entries)
+	}
+	if helper.Nodes == 0 || !strings.HasPrefix(helper.Position, "paths.go:6:") {
+		t.Errorf("expected a size and position, got %+v", helper)
+	}
+	if refund := functions["Refund"]; !slices.Equal(refund.Entries, []string{"Refund"}) || refund.Params[0].Type != "u32" {
+		t.Errorf("unexpected entry function: %+v", refund)
+	}
+	if stats := r.Programs[0].Stats; stats == nil || stats.WallNS <= 0 || len(stats.Phases) == 0 || stats.Phases[0].Name != "parsing" {
+		t.Errorf("expected the compile's stats, got %+v", stats)
+	}
+}
+
+func TestDecodeReportVersion(t *testing.T) {
+	_, err := report.Decode(strings.NewReader(`{"version": 99}`))
+	if err == nil || !strings.Contains(err.Error(), "unsupported report version 99") {
+		t.Errorf("expected a version error, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+)
+
+func TestReservedWordNames(t *testing.T) {
+	const source = `package main
+
+import "simplicity/jet"
+
+func Let(mod uint32, fn uint32) bool {
+	match := jet.Le32(mod, fn)
+	return match
+}
+
+func main() {
+	var witness uint32
+	var param uint32
+	match := jet.Le32(witness, param)
+	let := jet.Add32(param, 1)
+	mod := jet.Lt32(let, 9)
+	jet.Verify(match)
+	jet.Verify(Let(let, 5))
+	jet.Verify(mod)
+}
+`
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl", SelfCheck: true}).Compile(source, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"const WITNESS_: u32 = 0x00000000;",
+		"fn let_(mod_: u32, fn_: u32) -> bool {\n    let match_ = jet::le_32(mod_, fn_);\n    match_\n}",
+		"let match_: bool = jet::le_32(witness::WITNESS_, param_);",
+		"let (_, let_): (bool, u32) = jet::add_32(param_, 1);",
+		"assert!(let_(let_, 5));",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+
+	prog, err := shlparse.Parse(result)
+	if err != nil {
+		t.Fatalf("generated program does not parse: %v\n%s", err, result)
+	}
+	for _, tt := range []struct {
+		witness, param string
+		accept         bool
+	}{{"1", "4", true}, {"5", "4", false}, {"1", "8", false}} {
+		err := eval.Run(prog, eval.Options{Witness: map[string]string{"WITNESS_": tt.witness, "PARAM_": tt.param}})
+		var rejection *eval.Rejection
+		if tt.accept && err != nil || !tt.accept && !errors.As(err, &rejection) {
+			t.Errorf("witness %s, param %s: accept %v, got %v", tt.witness, tt.param, tt.accept, err)
+		}
+	}
+}
+
+// TestTemporaryShapedNames compiles a local spelled like the temporary the
+// compiler names after the multiply on its line: the local takes a trailing
+// underscore, as a keyword does, and the temporary another name.
+func TestTemporaryShapedNames(t *testing.T) {
+	const source = `package main
+
+import "simplicity/jet"
+
+func main() {
+	var amount uint64
+	var rate uint64
+	fee := (amount*rate)/10000 + 1
+	t_8_16 := jet.Le64(100, fee)
+	jet.Verify(t_8_16)
+}
+`
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl", SelfCheck: true}).Compile(source, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"let (_, t_8_16_2): (u64, u64) = <u128>::into(jet::multiply_64(witness::AMOUNT, witness::RATE));",
+		"let t_8_22: u64 = jet::divide_64(t_8_16_2, 10000);",
+		"let t_8_16_: bool = jet::le_64(100, fee);",
+		"assert!(t_8_16_);",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+	"github.com/0ceanslim/go-simplicity/pkg/testkeys"
+)
+
+const amountPredicate = `
+package main
+
+import "simplicity/jet"
+
+func AmountOk(amount uint64) bool {
+	return jet.Le64(1000, amount)
+}
+`
+
+// runSource compiles source and evaluates the result.
+func runSource(t *testing.T, config compiler.Config, source string, witness map[string]string) (*compiler.Compiler, error) {
+	t.Helper()
+	config.Target = "simplicityhl"
+	c := compiler.New(config)
+	result, err := c.Compile(source, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	prog, err := shlparse.Parse(result)
+	if err != nil {
+		t.Fatalf("generated program does not parse: %v\n%s", err, result)
+	}
+	return c, eval.Run(prog, eval.Options{Witness: witness})
+}
+
+func TestRunAcceptsValidSignature(t *testing.T) {
+	source, err := os.ReadFile(filepath.Join("..", "examples", "testable", "p2pk_testable.go"))
+	if err != nil {
+		t.Fatalf("read example: %v", err)
+	}
+	if _, err := runSource(t, compiler.Config{AllowTrivialMain: true}, string(source), nil); err != nil {
+		t.Errorf("expected accept, got %v", err)
+	}
+}
+
+func TestRunRejectsAtSourcePosition(t *testing.T) {
+	source, err := os.ReadFile(filepath.Join("..", "examples", "testable", "p2pk_testable.go"))
+	if err != nil {
+		t.Fatalf("read example: %v", err)
+	}
+	// Flip the last byte of the signature.
+	tampered := strings.ReplaceAll(string(source), testkeys.Vector0Sig, testkeys.Vector0Sig[:126]+"C1")
+
+	c, err := runSource(t, compiler.Config{}, tampered, nil)
+	var rejection *eval.Rejection
+	if !errors.As(err, &rejection) {
+		t.Fatalf("expected rejection, got %v", err)
+	}
+	pos, ok := c.SourcePosition(rejection.Line)
+	if !ok {
+		t.Fatalf("no source position for line %d", rejection.Line)
+	}
+	lines := strings.Split(tampered, "\n")
+	if !strings.Contains(lines[pos.Line-1], "jet.BIP340Verify") {
+		t.Errorf("rejection mapped to %s: %q", pos, lines[pos.Line-1])
+	}
+}
+
+func TestRunWitnessValues(t *testing.T) {
+	config := compiler.Config{Entry: "AmountOk"}
+	if _, err := runSource(t, config, amountPredicate, map[string]string{"AMOUNT": "1000"}); err != nil {
+		t.Errorf("AMOUNT=1000: expected accept, got %v", err)
+	}
+
+	_, err := runSource(t, config, amountPredicate, map[string]string{"AMOUNT": "999"})
+	var rejection *eval.Rejection
+	if !errors.As(err, &rejection) || rejection.Reason != "assertion failed" {
+		t.Errorf("AMOUNT=999: expected assertion failure, got %v", err)
+	}
+
+	_, err = runSource(t, config, amountPredicate, map[string]string{"AMOUNT_TYPO": "1"})
+	if err == nil || !strings.Contains(err.Error(), "unknown witness AMOUNT_TYPO") {
+		t.Errorf("expected unknown witness error, got %v", err)
+	}
+}
+
+func TestRunRequiresTxContext(t *testing.T) {
+	source := `
+package main
+
+import "simplicity/jet"
+
+func main() {
+	height := jet.TxLockHeight()
+	jet.Verify(jet.Le32(800000, height))
+}
+`
+	_, err := runSource(t, compiler.Config{}, source, nil)
+	var needsTx *eval.ContextError
+	if !errors.As(err, &needsTx) || needsTx.Jet != "tx_lock_height" {
+		t.Fatalf("expected a context error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "jet::tx_lock_height requires -tx context") {
+		t.Errorf("unexpected message: %v", err)
+	}
+}
+
+func TestParseWitnessJSON(t *testing.T) {
+	values, err := eval.ParseWitnessJSON([]byte(`{"A": "0x01", "B": {"value": "Left(5)", "type": "Either<u8, u8>"}}`))
+	if err != nil {
+		t.Fatalf("ParseWitnessJSON: %v", err)
+	}
+	if values["A"] != "0x01" || values["B"] != "Left(5)" {
+		t.Errorf("unexpected values: %v", values)
+	}
+	if _, err := eval.ParseWitnessJSON([]byte(`{"A": 5}`)); err == nil {
+		t.Error("expected error for a non-string value")
+	}
+}
+
+func TestShlparseReportsPosition(t *testing.T) {
+	_, err := shlparse.Parse("fn main() {\n    assert!(true)\n    assert!(false);\n}\n")
+	var syntax *shlparse.Error
+	if !errors.As(err, &syntax) || syntax.Line != 3 {
+		t.Errorf("expected a syntax error on line 3, got %v", err)
+	}
+}
+
+func readExample(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "examples", name))
+	if err != nil {
+		t.Fatalf("read example: %v", err)
+	}
+	return data
+}
+
+// compileGolden compiles examples/<name>.go and checks the result against
+// the checked-in examples/<name>.simf, which simgo -output wrote.
+func compileGolden(t *testing.T, name string) string {
+	t.Helper()
+	c := compiler.New(compiler.Config{Target: "simplicityhl"})
+	result, err := c.Compile(string(readExample(t, name+".go")), name+".go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	golden := string(readExample(t, name+".simf"))
+	header, want, _ := strings.Cut(golden, "\n")
+	if header != "// Code generated by simgo from "+name+".go. DO NOT EDIT." || result != want {
+		t.Errorf("output differs from examples/%[1]s.simf; regenerate it with\n\tgo run ./cmd/simgo -input examples/%[1]s.go -output examples/%[1]s.simf\ngot:\n%[2]s", name, result)
+	}
+	return result
+}
+
+func TestRunWithTxEnvironment(t *testing.T) {
+	source := string(readExample(t, "vault.go"))
+	witness, err := eval.ParseWitnessJSON(readExample(t, "vault.witness.json"))
+	if err != nil {
+		t.Fatalf("ParseWitnessJSON: %v", err)
+	}
+	txJSON := readExample(t, "vault.tx.json")
+
+	c := compiler.New(compiler.Config{Target: "simplicityhl"})
+	result, err := c.Compile(source, "vault.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	prog, err := shlparse.Parse(result)
+	if err != nil {
+		t.Fatalf("generated program does not parse: %v", err)
+	}
+
+	tx, err := eval.ParseTx(txJSON)
+	if err != nil {
+		t.Fatalf("ParseTx: %v", err)
+	}
+	if err := eval.Run(prog, eval.Options{Witness: witness, Tx: tx}); err != nil {
+		t.Errorf("cold key path: expected accept, got %v", err)
+	}
+
+	early, err := eval.ParseTx([]byte(strings.Replace(string(txJSON), `"locktime": 1000`, `"locktime": 999`, 1)))
+	if err != nil {
+		t.Fatalf("ParseTx: %v", err)
+	}
+	err = eval.Run(prog, eval.Options{Witness: witness, Tx: early})
+	var rejection *eval.Rejection
+	if !errors.As(err, &rejection) || !strings.Contains(rejection.Reason, "check_lock_height") {
+		t.Errorf("locktime 999: expected the timelock to reject, got %v", err)
+	}
+}
+
+func TestParseTxReportsField(t *testing.T) {
+	input := `{"prev_txid": "` + strings.Repeat("11", 32) + `", "value": 5, "script_hash": "` + strings.Repeat("22", 32) + `"%s}`
+	cases := []struct {
+		name, json, want string
+	}{
+		{"bad asset", `{"inputs": [` + fmt.Sprintf(input, `, "asset": "xyz"`) + `]}`, `inputs[0].asset: expected 32-byte hex, got "xyz"`},
+		{"wrong type", `{"inputs": [` + fmt.Sprintf(input, "") + `], "outputs": [{"value": "lots"}]}`, "outputs[0].value: expected uint64, got string"},
+		{"index", `{"current_index": 2, "inputs": [` + fmt.Sprintf(input, "") + `]}`, "current_index: 2 is out of range for 1 input(s)"},
+		{"chain", `{"chain": "dogecoin", "inputs": [` + fmt.Sprintf(input, "") + `]}`, `chain: unsupported chain "dogecoin"`},
+		{"regtest asset", `{"chain": "elements", "inputs": [` + fmt.Sprintf(input, "") + `]}`, "inputs[0].asset: required on chain elements"},
+		{"unknown field", `{"lock_time": 5}`, `unknown field "lock_time"`},
+		{"reissuance entropy", `{"inputs": [` + fmt.Sprintf(input, `, "issuance": {"asset_amount": 5, "blinding_nonce": "`+strings.Repeat("33", 32)+`"}`) + `]}`, "inputs[0].issuance.entropy: required for a reissuance"},
+		{"new issuance entropy", `{"inputs": [` + fmt.Sprintf(input, `, "issuance": {"asset_amount": 5, "entropy": "`+strings.Repeat("33", 32)+`"}`) + `]}`, "inputs[0].issuance.entropy: a new issuance derives its entropy"},
+		{"bad pegin", `{"inputs": [` + fmt.Sprintf(input, `, "pegin": "00"`) + `]}`, `inputs[0].pegin: expected 32-byte hex, got "00"`},
+		{"confidential fee", `{"inputs": [` + fmt.Sprintf(input, "") + `], "outputs": [{"value": 5, "confidential": true, "script_hash": "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"}]}`, "outputs[0].confidential: a fee output has an explicit value"},
+	}
+	for _, tc := range cases {
+		_, err := eval.ParseTx([]byte(tc.json))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.want, err)
+		}
+	}
+}
+
+func TestRunRejectsAtArmPosition(t *testing.T) {
+	source := string(readExample(t, "htlc.go"))
+	tx, err := eval.ParseTx(readExample(t, "vault.tx.json"))
+	if err != nil {
+		t.Fatalf("ParseTx: %v", err)
+	}
+	c := compiler.New(compiler.Config{Target: "simplicityhl"})
+	result, err := c.Compile(source, "htlc.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	prog, err := shlparse.Parse(result)
+	if err != nil {
+		t.Fatalf("generated program does not parse: %v", err)
+	}
+
+	// Each path fails at its own first check, not at the if both arms
+	// were lowered from.
+	zero32, zero64 := "0x"+strings.Repeat("00", 32), "0x"+strings.Repeat("00", 64)
+	tests := []struct {
+		witness, check string
+	}{
+		{"Left((" + zero32 + ", " + zero64 + "))", "jet.Verify(jet.Eq256(hash, HashLock))"},
+		{"Right(" + zero64 + ")", "jet.CheckLockHeight(RefundHeight)"},
+	}
+	lines := strings.Split(source, "\n")
+	for _, tt := range tests {
+		err := eval.Run(prog, eval.Options{Witness: map[string]string{"W": tt.witness}, Tx: tx})
+		var rejection *eval.Rejection
+		if !errors.As(err, &rejection) {
+			t.Errorf("%s: expected rejection, got %v", tt.witness[:5], err)
+			continue
+		}
+		pos, ok := c.SourcePosition(rejection.Line)
+		if !ok || !strings.Contains(lines[pos.Line-1], tt.check) {
+			t.Errorf("%s: rejection mapped to %s, not to %s", tt.witness[:5], pos, tt.check)
+		}
+	}
+}
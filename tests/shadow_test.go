@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+)
+
+// TestShadowedBindingStaysInBranch checks that a := in one arm of a match
+// declares a local of that arm alone: the other arm and the statements
+// after the if read the outer variable.
+func TestShadowedBindingStaysInBranch(t *testing.T) {
+	source := `package main
+
+import "simplicity/jet"
+
+func main() {
+	var amount uint32
+	small := jet.Lt32(amount, 3)
+	limit := uint32(5)
+	if small {
+		limit := uint32(1)
+		jet.Verify(jet.Lt32(amount, limit))
+	} else {
+		jet.Verify(jet.Lt32(amount, limit))
+	}
+	jet.Verify(jet.Lt32(amount, limit))
+}
+`
+	c := compiler.New(compiler.Config{Target: "simplicityhl", OptLevel: compiler.O0})
+	result, err := c.Compile(source, "shadow.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"    assert!(jet::lt_32(amount, 5));\n    match small {",
+		"true => {\n            let limit = 1;\n            assert!(jet::lt_32(amount, 1));",
+		"false => {\n            assert!(jet::lt_32(amount, 5));",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("output does not contain %q:\n%s", want, result)
+		}
+	}
+}
+
+// TestShadowedBindingKeepsOuterValue checks that folding after an if sees
+// the outer variable a branch shadows, not the branch's.
+func TestShadowedBindingKeepsOuterValue(t *testing.T) {
+	source := `package main
+
+import "simplicity/jet"
+
+func check(small bool) bool {
+	valid := false
+	if small {
+		valid := true
+		jet.Verify(valid)
+	}
+	return valid
+}
+
+func main() {
+	var small bool
+	jet.Verify(check(small))
+}
+`
+	c := compiler.New(compiler.Config{Target: "simplicityhl", OptLevel: compiler.O0})
+	result, err := c.Compile(source, "shadow.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result, "    let valid = false;\n    false\n}") {
+		t.Errorf("check does not return the outer valid:\n%s", result)
+	}
+}
+
+func TestShadowWarning(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		warned string // "" when no warning is expected
+	}{
+		{
+			name: "shadow read after the if",
+			body: `	valid := false
+	if jet.Lt32(amount, 3) {
+		valid := true
+		_ = valid
+	}
+	jet.Verify(valid)`,
+			warned: "shadow.go:11:3: valid := declares a new valid for the branch, shadowing the valid of line 9, which line 14 reads after the if; the branch does not change it. Write valid = to assign the outer valid [SIM0308]",
+		},
+		{
+			name: "shadow in a switch case",
+			body: `	limit := uint32(5)
+	switch {
+	case jet.Lt32(amount, 3):
+		limit := uint32(1)
+		_ = limit
+	}
+	jet.Verify(jet.Lt32(amount, limit))`,
+			warned: "shadow.go:12:3: limit := declares a new limit for the branch, shadowing the limit of line 9, which line 15 reads after the switch; the branch does not change it. Write limit = to assign the outer limit [SIM0308]",
+		},
+		{
+			name: "assignment to the outer variable",
+			body: `	valid := false
+	if jet.Lt32(amount, 3) {
+		valid = true
+	}
+	jet.Verify(valid)`,
+		},
+		{
+			name: "outer variable not read after",
+			body: `	valid := jet.Lt32(amount, 5)
+	jet.Verify(valid)
+	if jet.Lt32(amount, 3) {
+		valid := jet.Lt32(amount, 1)
+		jet.Verify(valid)
+	}`,
+		},
+		{
+			name: "if statement initializer",
+			body: `	ok := jet.Lt32(amount, 5)
+	if ok := jet.Lt32(amount, 3); ok {
+		jet.Verify(ok)
+	}
+	jet.Verify(ok)`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := "package main\n\nimport \"simplicity/jet\"\n\nfunc main() {\n\tvar amount uint32\n\tjet.Verify(jet.Lt32(amount, 9))\n\n" + tt.body + "\n}\n"
+			c := compiler.New(compiler.Config{Target: "simplicityhl"})
+			if _, err := c.Compile(source, "shadow.go"); err != nil {
+				t.Fatal(err)
+			}
+			var shadows []string
+			for _, w := range c.Warnings() {
+				if codes := diag.Find(w); len(codes) > 0 && codes[0] == diag.Shadowed {
+					shadows = append(shadows, w)
+				}
+			}
+			switch {
+			case tt.warned == "" && len(shadows) > 0:
+				t.Errorf("unexpected warnings %q", shadows)
+			case tt.warned != "" && (len(shadows) != 1 || shadows[0] != tt.warned):
+				t.Errorf("warnings %q, want %q", shadows, tt.warned)
+			}
+		})
+	}
+}
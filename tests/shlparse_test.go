@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+)
+
+// corpus returns the .simf snippets in testdata/shlparse/dir.
+func corpus(t *testing.T, dir string) []string {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join("testdata", "shlparse", dir, "*.simf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatalf("no snippets in %s", dir)
+	}
+	return paths
+}
+
+func TestParseValidCorpus(t *testing.T) {
+	for _, path := range corpus(t, "valid") {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			src, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := shlparse.Parse(string(src)); err != nil {
+				t.Errorf("Parse: %v", err)
+			}
+		})
+	}
+}
+
+// TestParseInvalidCorpus checks each invalid snippet against its first line,
+// "// want: line N: message", where message is a prefix of the error.
+func TestParseInvalidCorpus(t *testing.T) {
+	for _, path := range corpus(t, "invalid") {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			src, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			header, _, _ := strings.Cut(string(src), "\n")
+			var line int
+			var want string
+			if _, err := fmt.Sscanf(header, "// want: line %d:", &line); err != nil {
+				t.Fatalf("malformed header %q", header)
+			}
+			_, want, _ = strings.Cut(strings.TrimPrefix(header, "// want: line "), ": ")
+
+			_, err = shlparse.Parse(string(src))
+			var perr *shlparse.Error
+			if !errors.As(err, &perr) {
+				t.Fatalf("expected a parse error, got %v", err)
+			}
+			if perr.Line != line || !strings.HasPrefix(perr.Msg, want) {
+				t.Errorf("got %v, want line %d: %s", err, line, want)
+			}
+		})
+	}
+}
+
+// selfCheckFailures lists examples whose generated code is known not to
+// parse. Remove an entry once the generator bug behind it is fixed.
+var selfCheckFailures = map[string]int{}
+
+func TestSelfCheck(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("..", "examples", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		name := filepath.Base(path)
+		t.Run(name, func(t *testing.T) {
+			src := loadExample(t, path)
+			config := exampleConfig(path)
+			config.SelfCheck = true
+			out, err := compiler.New(config).Compile(src, path)
+			if line, broken := selfCheckFailures[name]; broken {
+				want := fmt.Sprintf("internal error: generated invalid SimplicityHL at line %d:", line)
+				if err == nil || !strings.HasPrefix(err.Error(), want) {
+					t.Errorf("expected %q, got %v", want, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SelfCheck rejected the output: %v", err)
+			}
+			if want := compileExample(t, path); out != want {
+				t.Errorf("SelfCheck changed the output:\n%s\nwant:\n%s", out, want)
+			}
+		})
+	}
+}
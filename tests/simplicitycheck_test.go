@@ -0,0 +1,36 @@
+package tests
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"github.com/0ceanslim/go-simplicity/pkg/analysis/simplicitycheck"
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+)
+
+func TestSimplicityCheck(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), simplicitycheck.Analyzer, "contract")
+}
+
+// TestSignedIntWarning checks that the compiler warns of the signed
+// integers the analyzer reports, and still compiles the contract.
+func TestSignedIntWarning(t *testing.T) {
+	source := `package main
+
+import "simplicity/jet"
+
+func main() {
+	var count int8
+	jet.Verify(jet.Le8(uint8(count), 3))
+}
+`
+	c := compiler.New(compiler.Config{Target: "simplicityhl"})
+	if _, err := c.Compile(source, "contract.go"); err != nil {
+		t.Fatal(err)
+	}
+	want := "contract.go:6:12: int8 has no Simplicity equivalent, whose integers are unsigned and of fixed width; declare uint8, after checking that no value is negative [SIM0307]"
+	if warnings := c.Warnings(); len(warnings) != 1 || warnings[0] != want {
+		t.Errorf("warnings %q, want %q", warnings, want)
+	}
+}
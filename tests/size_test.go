@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+)
+
+// doublingSource inlines H0 into H1 twice, H1 into H2 twice and so on, so
+// that each helper is twice the size of the one before it.
+const doublingSource = `package main
+
+import "simplicity/jet"
+
+func H0(x uint32) uint32 { return jet.And32(x, 3) }
+func H1(x uint32) uint32 { return jet.Xor32(H0(x), H0(x)) }
+func H2(x uint32) uint32 { return jet.Xor32(H1(x), H1(x)) }
+func H3(x uint32) uint32 { return jet.Xor32(H2(x), H2(x)) }
+
+func main() {
+	var x uint32
+	jet.Verify(jet.Le32(H3(x), 30))
+}
+`
+
+func TestMaxNodes(t *testing.T) {
+	c := compiler.New(compiler.Config{Target: "simplicityhl"})
+	if _, err := c.Compile(doublingSource, "contract.go"); err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	// The limit applies to the estimate the result reports per function.
+	result := c.Result()
+	sum := 0
+	for _, fn := range result.Functions {
+		sum += fn.Nodes
+	}
+	if result.Nodes != sum || sum == 0 {
+		t.Errorf("program has %d nodes, its functions %d", result.Nodes, sum)
+	}
+
+	for _, tt := range []struct {
+		maxNodes int
+		want     string // "" to compile
+	}{
+		// H3 alone is too large: analysis stops at its declaration.
+		{30, "contract.go:8:1: the body of H3 generates 31 SimplicityHL nodes, above the limit of 30"},
+		// Each function fits, but the program does not.
+		{45, "contract.go:8:1: fn h3 brings the generated program to 60 SimplicityHL nodes, above the limit of 45"},
+		{sum, ""},
+		{-1, ""},
+	} {
+		var out strings.Builder
+		err := compiler.New(compiler.Config{Target: "simplicityhl", MaxNodes: tt.maxNodes}).CompileReader(strings.NewReader(doublingSource), "contract.go", &out)
+		if tt.want == "" && err != nil {
+			t.Errorf("MaxNodes %d: %v", tt.maxNodes, err)
+		}
+		if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("MaxNodes %d: expected %q, got %v", tt.maxNodes, tt.want, err)
+		}
+		if tt.want != "" && strings.Contains(out.String(), "fn main") {
+			t.Errorf("MaxNodes %d: wrote the program past the limit:\n%s", tt.maxNodes, out.String())
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+	"github.com/0ceanslim/go-simplicity/pkg/testkeys"
+)
+
+const spendPathsSource = `package main
+
+import "simplicity/jet"
+
+const AliceKey = 0xdff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659
+const BobKey = 0xf9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9
+const HashLock = 0x425ed4e4a36b30ea21b90e21c712c649e8214c29b7eaf68089d1039c6e55384c
+
+type ClaimData struct {
+	Preimage [32]byte
+	Sig      [64]byte
+}
+
+type RefundData struct {
+	Sig [64]byte
+}
+
+type Spend struct {
+	Claim  *ClaimData
+	Refund *RefundData
+	Cancel *[64]byte
+}
+
+func main() {
+	var w Spend
+	if w.Claim != nil {
+		hash := jet.SHA256Finalize(jet.SHA256Add32(jet.SHA256Init(), w.Claim.Preimage))
+		jet.Verify(jet.Eq256(hash, HashLock))
+		jet.BIP340Verify(AliceKey, jet.SigAllHash(), w.Claim.Sig)
+	} else if w.Refund != nil {
+		jet.CheckLockHeight(800000)
+		jet.BIP340Verify(BobKey, jet.SigAllHash(), w.Refund.Sig)
+	} else {
+		jet.BIP340Verify(AliceKey, jet.SigAllHash(), *w.Cancel)
+	}
+}
+`
+
+func compileSpendPaths(t *testing.T, source string) string {
+	t.Helper()
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "spend.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	return result
+}
+
+func TestSpendPathsThreeWay(t *testing.T) {
+	result := compileSpendPaths(t, spendPathsSource)
+	for _, want := range []string{
+		"const W: Either<([u8; 32], [u8; 64]), Either<([u8; 64],), [u8; 64]>> = ",
+		"match witness::W {",
+		"Left((claim_preimage, claim_sig): ([u8; 32], [u8; 64])) => {",
+		"Right(rest: Either<([u8; 64],), [u8; 64]>) => {",
+		"match rest {",
+		"Left((refund_sig,): ([u8; 64],)) => {",
+		"jet::bip_0340_verify((param::ALICE_KEY, jet::sig_all_hash()), cancel);",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+
+	// A tagless switch selects the same paths as the if chain.
+	switched := strings.NewReplacer(
+		"if w.Claim != nil {", "switch {\n\tcase w.Claim != nil:",
+		"} else if w.Refund != nil {", "case w.Refund != nil:",
+		"} else {", "default:",
+	).Replace(spendPathsSource)
+	if got := compileSpendPaths(t, switched); got != result {
+		t.Errorf("switch compiles differently from the if chain:\n%s", got)
+	}
+
+	prog, err := shlparse.Parse(result)
+	if err != nil {
+		t.Fatalf("generated program does not parse: %v", err)
+	}
+	txJSON := string(readExample(t, "htlc.tx.json"))
+	refundTx := func(locktime string) func(string) string {
+		return func(s string) string {
+			s = strings.Replace(s, `"locktime": 0`, `"locktime": `+locktime, 1)
+			return strings.Replace(s, strings.ToLower(testkeys.Vector1Msg), testkeys.Vector0Msg, 1)
+		}
+	}
+	claim := "Left((0x" + strings.Repeat("42", 32) + ", 0x" + strings.ToLower(testkeys.Vector1Sig) + "))"
+	refund := "Right(Left((0x" + strings.ToLower(testkeys.Vector0Sig) + ",)))"
+	cancel := "Right(Right(0x" + strings.ToLower(testkeys.Vector1Sig) + "))"
+
+	tests := []struct {
+		name    string
+		witness string
+		edit    func(string) string
+		reject  string // "" to accept
+	}{
+		{"claim", claim, nil, ""},
+		{"claim with the wrong preimage", strings.Replace(claim, "0x4242", "0x4243", 1), nil, "assertion failed"},
+		{"refund at the lock height", refund, refundTx("800000"), ""},
+		{"refund before the lock height", refund, refundTx("799999"), "check_lock_height"},
+		{"cancel", cancel, nil, ""},
+		{"cancel signed by Bob", "Right(Right(0x" + strings.ToLower(testkeys.Vector0Sig) + "))", nil, "bip_0340_verify"},
+	}
+	for _, tt := range tests {
+		data := txJSON
+		if tt.edit != nil {
+			data = tt.edit(data)
+		}
+		tx, err := eval.ParseTx([]byte(data))
+		if err != nil {
+			t.Fatalf("%s: ParseTx: %v", tt.name, err)
+		}
+		err = eval.Run(prog, eval.Options{Witness: map[string]string{"W": tt.witness}, Tx: tx})
+		if tt.reject == "" {
+			if err != nil {
+				t.Errorf("%s: expected accept, got %v", tt.name, err)
+			}
+			continue
+		}
+		var rejection *eval.Rejection
+		if !errors.As(err, &rejection) || !strings.Contains(rejection.Reason, tt.reject) {
+			t.Errorf("%s: expected %s to reject, got %v", tt.name, tt.reject, err)
+		}
+	}
+}
+
+func TestSpendPathsTwoWay(t *testing.T) {
+	source := `package main
+
+import "simplicity/jet"
+
+const Key = 0xdff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659
+
+type Spend struct {
+	Sig    *[64]byte
+	Height *uint32
+}
+
+func Unlock(w Spend) {
+	if w.Sig != nil {
+		jet.BIP340Verify(Key, jet.SigAllHash(), *w.Sig)
+	} else if w.Height != nil {
+		jet.CheckLockHeight(*w.Height)
+	}
+}
+`
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl", Entry: "Unlock"}).Compile(source, "spend.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"const W: Either<[u8; 64], u32> = ",
+		"Left(sig: [u8; 64]) => {",
+		"jet::bip_0340_verify((param::KEY, jet::sig_all_hash()), sig);",
+		"Right(height: u32) => {",
+		"jet::check_lock_height(height);",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+	if strings.Contains(result, "match rest") {
+		t.Errorf("two paths need no nested match:\n%s", result)
+	}
+}
+
+func TestSpendPathsWitnessLayout(t *testing.T) {
+	c := compiler.New(compiler.Config{Target: "simplicityhl"})
+	if _, err := c.Compile(spendPathsSource, "spend.go"); err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	witnesses := c.Witnesses()
+	if len(witnesses) != 1 || witnesses[0].Selector != "" {
+		t.Fatalf("witnesses = %+v, want W without a selector", witnesses)
+	}
+	got := make([]string, 0, len(witnesses[0].Fields))
+	for _, f := range witnesses[0].Fields {
+		got = append(got, f.Branch+" "+f.Name+": "+f.Type)
+	}
+	want := "Left claim.preimage: [u8; 32], Left claim.sig: [u8; 64], Right.Left refund.sig: [u8; 64], Right.Right cancel: [u8; 64]"
+	if strings.Join(got, ", ") != want {
+		t.Errorf("fields = %s, want %s", strings.Join(got, ", "), want)
+	}
+}
+
+func TestSpendPathsErrors(t *testing.T) {
+	tests := []struct {
+		name, from, to, want string
+	}{
+		{"another path's field", "jet.CheckLockHeight(800000)", "jet.Verify(jet.Eq256(jet.SHA256Finalize(jet.SHA256Add32(jet.SHA256Init(), w.Claim.Preimage)), HashLock))", "w.Claim is nil on the Refund path of w"},
+		{"a nil test", "w.Claim != nil", "w.Claim == nil", "select the paths of w by the one that is set: w.Claim != nil"},
+		{"a mixed condition", "w.Refund != nil", "jet.Le64(1, 2)", "every condition of a chain selecting a path of w must test one of its fields against nil"},
+		{"a path tested twice", "w.Refund != nil", "w.Claim != nil", "path Claim of w is selected twice"},
+		{"an unknown path", "w.Refund != nil", "w.Sweep != nil", "w.Sweep is not a path of Spend"},
+	}
+	for _, tt := range tests {
+		source := strings.Replace(spendPathsSource, tt.from, tt.to, 1)
+		_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "spend.go")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
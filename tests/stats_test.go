@@ -0,0 +1,47 @@
+package tests
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+)
+
+func TestCompileStats(t *testing.T) {
+	c := compiler.New(compiler.Config{Target: "simplicityhl", OptLevel: compiler.O2})
+	if _, err := c.Compile(string(readExample(t, "simple_multisig.go")), "simple_multisig.go"); err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	stats := c.Result().Stats
+	var phases []string
+	var wall time.Duration
+	var bytes, objects uint64
+	for _, p := range stats.Phases {
+		phases = append(phases, p.Phase)
+		wall += p.Wall
+		bytes += p.AllocBytes
+		objects += p.Allocs
+	}
+	want := []string{"parsing", "validation", "resolution", "import loading", "analysis", "rendering", "optimization", "checking"}
+	if !slices.Equal(phases, want) {
+		t.Errorf("phases = %v, want %v", phases, want)
+	}
+	if stats.Wall <= 0 || stats.Wall != wall || stats.AllocBytes != bytes || stats.Allocs != objects {
+		t.Errorf("totals %v, %d bytes, %d objects do not add up the phases %+v", stats.Wall, stats.AllocBytes, stats.Allocs, stats.Phases)
+	}
+	if stats.AllocBytes == 0 {
+		t.Error("expected the compile to allocate")
+	}
+
+	// A second compile starts its stats afresh.
+	c = compiler.New(compiler.Config{Target: "simplicityhl"})
+	for range 2 {
+		if _, err := c.Compile(string(readExample(t, "p2pk.go")), "p2pk.go"); err != nil {
+			t.Fatalf("Compilation failed: %v", err)
+		}
+	}
+	if n := len(c.Result().Stats.Phases); n != 7 {
+		t.Errorf("expected the 7 phases of one compile, got %+v", c.Result().Stats.Phases)
+	}
+}
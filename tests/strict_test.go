@@ -0,0 +1,242 @@
+package tests
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+)
+
+// strictFailures records the examples that still rely on a fallback, by
+// the position of the first. An example that stops relying on one, or
+// starts to, must be moved in or out of the table.
+var strictFailures = map[string]string{
+	"basic_swap.go":   "basic_swap.go:24:1: strict mode: main checks nothing, so main asserts result",
+	"multisig.go":     "multisig.go:79:3: strict mode: the ++ statement is not lowered",
+	"simple_logic.go": "simple_logic.go:15:1: strict mode: main checks nothing, so main asserts witness::RESULT1",
+}
+
+// TestStrictConversions checks that conversions to the type a value has,
+// and widening ones, compile under strict mode to the value they convert.
+func TestStrictConversions(t *testing.T) {
+	source := `package main
+
+import "simplicity/jet"
+
+func check(amount uint64) bool {
+	return bool(jet.Le64(1000, amount))
+}
+
+func same(ok bool) bool {
+	return bool(ok)
+}
+
+func wide(n uint8) uint32 {
+	return uint32(n)
+}
+
+func main() {
+	var amount uint64
+	var ok bool
+	var n uint8
+	jet.Verify(check(amount))
+	jet.Verify(same(ok))
+	jet.Verify(jet.Lt32(wide(n), 9))
+}
+`
+	for _, level := range []compiler.OptLevel{compiler.O0, compiler.O2} {
+		result, err := compiler.New(compiler.Config{Target: "simplicityhl", OptLevel: level, Strict: true}).Compile(source, "main.go")
+		if err != nil {
+			t.Fatalf("%v: %v", level, err)
+		}
+		if level != compiler.O0 {
+			continue
+		}
+		for _, want := range []string{
+			"fn check(amount: u64) -> bool {\n    jet::le_64(1000, amount)\n}",
+			"fn same(ok: bool) -> bool {\n    ok\n}",
+			"fn wide(n: u8) -> u32 {\n    <(u16, u16)>::into((0, <(u8, u8)>::into((0, n))))\n}",
+		} {
+			if !strings.Contains(result, want) {
+				t.Errorf("missing %q in\n%s", want, result)
+			}
+		}
+	}
+	for _, tt := range []struct {
+		amount, n string
+		accept    bool
+	}{
+		{"1000", "8", true},
+		{"999", "8", false},
+		{"1000", "9", false},
+	} {
+		_, err := runSource(t, compiler.Config{Strict: true}, source, map[string]string{"AMOUNT": tt.amount, "OK": "true", "N": tt.n})
+		var rejection *eval.Rejection
+		switch {
+		case tt.accept && err != nil:
+			t.Errorf("amount %s, n %s was rejected: %v", tt.amount, tt.n, err)
+		case !tt.accept && !errors.As(err, &rejection):
+			t.Errorf("amount %s, n %s was accepted: %v", tt.amount, tt.n, err)
+		}
+	}
+}
+
+// TestStrictExamples compiles every example under Config.Strict. Those of
+// strictFailures fail at their first fallback, which a compile without
+// it lists first in CompileResult.Fallbacks; the others compile, and
+// list none.
+func TestStrictExamples(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("..", "examples", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testable, err := filepath.Glob(filepath.Join("..", "examples", "testable", "*_testable.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range append(paths, testable...) {
+		name := filepath.Base(path)
+		t.Run(name, func(t *testing.T) {
+			src := loadExample(t, path)
+			config := exampleConfig(path)
+			c := compiler.New(config)
+			if _, err := c.Compile(src, name); err != nil {
+				t.Fatal(err)
+			}
+			fallbacks := c.Result().Fallbacks
+
+			config.Strict = true
+			_, err := compiler.New(config).Compile(src, name)
+			want, relies := strictFailures[name]
+			switch {
+			case !relies && err != nil:
+				t.Fatalf("strict compile failed: %v", err)
+			case !relies && len(fallbacks) > 0:
+				t.Fatalf("the compile relies on fallbacks that strict mode allowed: %v", fallbacks)
+			case !relies:
+				return
+			case err == nil:
+				t.Fatalf("compiled under strict mode; remove it from strictFailures")
+			}
+			if code, _ := diag.CodeOf(err); code != diag.SilentFallback || !strings.Contains(err.Error(), want) {
+				t.Errorf("expected a fallback error containing %q, got %v", want, err)
+			}
+			if len(fallbacks) == 0 || !strings.HasPrefix(strings.Replace(fallbacks[0], ": ", ": strict mode: ", 1), want) {
+				t.Errorf("expected the first fallback to be the one strict mode fails at, got %v", fallbacks)
+			}
+		})
+	}
+}
+
+func TestStrictFallbacks(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"unknown expression", `package main
+
+import "simplicity/jet"
+
+func main() {
+	var a uint32
+	b := [2]uint32{a, a}
+	jet.Verify(jet.Eq32(b[a:][0], 1))
+}
+`, "main.go:8:22: strict mode: the expression b[a:] has no lowering and compiles to true"},
+		{"narrowing conversion", `package main
+
+import "simplicity/jet"
+
+func narrow(a uint64) uint32 {
+	return uint32(a)
+}
+
+func main() {
+	var a uint64
+	jet.Verify(jet.Le32(narrow(a), 10))
+}
+`, "main.go:6:9: strict mode: the conversion uint32(a) has no lowering and compiles to true"},
+		{"call before declaration", `package main
+
+import "simplicity/jet"
+
+func check(a uint64) bool {
+	return later(a)
+}
+
+func later(a uint64) bool {
+	return jet.Le64(1000, a)
+}
+
+func main() {
+	var a uint64
+	jet.Verify(check(a))
+}
+`, "main.go:6:9: strict mode: the call later(a) has no lowering and compiles to true"},
+		{"dropped statement", `package main
+
+import "simplicity/jet"
+
+func main() {
+	var a uint32
+	if a == 0 {
+		return
+	}
+	jet.Verify(jet.Lt32(1, a))
+}
+`, "main.go:7:2: strict mode: the if statement is not lowered and is left out of the program"},
+		{"empty body", `package main
+
+import "simplicity/jet"
+
+func check(a uint32) bool {
+	const limit = 10
+}
+
+func main() {
+	var a uint32
+	jet.Verify(check(a))
+}
+`, "main.go:7:1: strict mode: check computes no result the transpiler can lower, and returns true"},
+		{"guessed type", `package main
+
+import "simplicity/jet"
+
+func main() {
+	var a uint32
+	ok := jet.Lt32(1, a) && jet.Lt32(a, 9)
+	jet.Verify(ok)
+}
+`, "main.go:7:2: strict mode: the type of witness::OK is guessed as bool from its value match"},
+		{"main heuristic", `package main
+
+func check(ok bool) bool {
+	return ok
+}
+
+func main() {
+	var ok bool
+	result := check(ok)
+	_ = result
+}
+`, "main.go:7:1: strict mode: main checks nothing, so main asserts result, whose name contains result"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := compiler.New(compiler.Config{Target: "simplicityhl", AllowTrivialMain: true})
+			if _, err := c.Compile(tc.src, "main.go"); err == nil {
+				if fallbacks := c.Result().Fallbacks; len(fallbacks) == 0 {
+					t.Errorf("expected a fallback without strict mode")
+				}
+			}
+			_, err := compiler.New(compiler.Config{Target: "simplicityhl", AllowTrivialMain: true, Strict: true}).Compile(tc.src, "main.go")
+			if code, _ := diag.CodeOf(err); code != diag.SilentFallback || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected a fallback error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
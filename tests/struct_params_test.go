@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+)
+
+const structParams = `
+package main
+
+import "simplicity/jet"
+
+func validate(p Payment) bool {
+	return jet.Le64(p.Bounds.Min, p.Amount)
+}
+
+type Payment struct {
+	Amount uint64
+	Bounds Limits
+}
+
+type Limits struct {
+	Min, Max uint64
+}
+
+func main() {
+	var amount uint64
+	jet.Verify(validate(Payment{Amount: amount, Bounds: Limits{Min: 10, Max: 100}}))
+	jet.Verify(validate(Payment{Bounds: Limits{5, 50}}))
+}
+`
+
+func TestStructParams(t *testing.T) {
+	c := compiler.New(compiler.Config{Target: "simplicityhl", SelfCheck: true})
+	out, err := c.Compile(structParams, "struct_params.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"fn validate(p: (u64, (u64, u64))) -> bool {",
+		"let (p_amount, (p_bounds_min, p_bounds_max)): (u64, (u64, u64)) = p;",
+		"jet::le_64(p_bounds_min, p_amount)",
+		"assert!(validate((witness::AMOUNT, (10, 100))));",
+		// Omitted fields are zero
+		"assert!(validate((0x0000000000000000, (5, 50))));",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+}
+
+func TestStructLiteralErrors(t *testing.T) {
+	tests := []struct {
+		lit  string
+		want string
+	}{
+		{"Limits{Low: 1}", "unknown field Low in Limits literal"},
+		{"Limits{1, 2, 3}", "too many values in Limits literal"},
+	}
+	for _, tt := range tests {
+		src := "package main\n\nimport \"simplicity/jet\"\n\ntype Limits struct {\n\tMin, Max uint64\n}\n\n" +
+			"func ordered(l Limits) bool {\n\treturn jet.Le64(l.Min, l.Max)\n}\n\n" +
+			"func main() {\n\tjet.Verify(ordered(" + tt.lit + "))\n}\n"
+		_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(src, "limits.go")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q, got %v", tt.lit, tt.want, err)
+		}
+	}
+}
+
+const embeddedStructs = `
+package main
+
+import "simplicity/jet"
+
+type Limits struct {
+	Min, Max uint64
+}
+
+type Payment struct {
+	Amount uint64
+	Bounds Limits
+}
+
+type Signed struct {
+	Payment
+	Sig [64]byte
+}
+
+func check(s Signed) bool {
+	return jet.Le64(s.Bounds.Min, s.Amount)
+}
+
+func checkInner(s Signed) bool {
+	return jet.Le64(s.Payment.Amount, s.Payment.Bounds.Max)
+}
+
+func main() {
+	var sig [64]byte
+	jet.Verify(check(Signed{Payment: Payment{Amount: 5, Bounds: Limits{1, 9}}, Sig: sig}))
+	jet.Verify(checkInner(Signed{Sig: sig}))
+}
+`
+
+func TestEmbeddedStructs(t *testing.T) {
+	out, err := compiler.New(compiler.Config{Target: "simplicityhl", SelfCheck: true}).Compile(embeddedStructs, "embedded.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"fn check(s: (u64, (u64, u64), [u8; 64])) -> bool {",
+		"let (s_payment_amount, (s_payment_bounds_min, s_payment_bounds_max), s_sig): (u64, (u64, u64), [u8; 64]) = s;",
+		// Promoted through the embedding
+		"jet::le_64(s_payment_bounds_min, s_payment_amount)",
+		"jet::le_64(s_payment_amount, s_payment_bounds_max)",
+		"assert!(check((5, (1, 9), sig)));",
+		"assert!(check_inner((0x0000000000000000, (0x0000000000000000, 0x0000000000000000), sig)));",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+}
+
+func TestEmbeddedStructErrors(t *testing.T) {
+	const decls = "package main\n\nimport \"simplicity/jet\"\n\n" +
+		"type Fee struct {\n\tAmount uint64\n}\n\n" +
+		"type Payment struct {\n\tAmount uint64\n}\n\n"
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{
+			"type Both struct {\n\tFee\n\tPayment\n}\n\nfunc f(b Both) bool {\n\treturn jet.Le64(b.Amount, 1)\n}\n",
+			"b.Amount: ambiguous selector Amount",
+		},
+		{
+			"type Both struct {\n\tFee\n\tPayment\n}\n\nfunc f(b Both) bool {\n\treturn jet.Le64(b.Fee.Amount, b.Payment.Amount)\n}\n",
+			"",
+		},
+		{
+			"type Outer struct {\n\tFee\n\tAmount uint64\n}\n\nfunc f(o Outer) bool {\n\treturn jet.Le64(o.Fee.Amount, o.Amount)\n}\n",
+			"", // the shallower Amount wins
+		},
+		{
+			"type Both struct {\n\tFee\n}\n\nfunc f(b Both) bool {\n\treturn jet.Le64(b.Total, 1)\n}\n",
+			"b.Total: type Both has no field Total",
+		},
+		{
+			"type Keyed struct {\n\terror\n}\n",
+			"struct Keyed embeds interface error",
+		},
+		{
+			"type Both struct {\n\tFee\n}\n\nfunc f(b Both) bool {\n\treturn jet.Le64(b.Amount, 1)\n}\n\n" +
+				"func main() {\n\tvar fee Fee\n\tjet.Verify(f(Both{Fee: fee}))\n}\n",
+			"embedded Fee in Both literal must be a Fee literal",
+		},
+	}
+	for _, tt := range tests {
+		src := decls + tt.src
+		if !strings.Contains(src, "func main") {
+			src += "\nfunc main() {}\n"
+		}
+		_, err := compiler.New(compiler.Config{Target: "simplicityhl", AllowTrivialMain: true}).Compile(src, "embedded.go")
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.src, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q, got %v", tt.src, tt.want, err)
+		}
+	}
+}
@@ -136,8 +136,9 @@ func main() {
 `
 
 	c := compiler.New(compiler.Config{
-		Target: "simplicityhl",
-		Debug:  false,
+		AllowTrivialMain: true,
+		Target:           "simplicityhl",
+		Debug:            false,
 	})
 
 	result, err := c.Compile(source, "test.go")
@@ -161,8 +162,9 @@ func main() {
 `
 
 	c := compiler.New(compiler.Config{
-		Target: "simplicityhl",
-		Debug:  false,
+		AllowTrivialMain: true,
+		Target:           "simplicityhl",
+		Debug:            false,
 	})
 
 	result, err := c.Compile(source, "test.go")
@@ -0,0 +1,22 @@
+// Package checks holds audited predicates shared by several contracts.
+package checks
+
+import "simplicity/jet"
+
+// MinAmount is the smallest output value a contract accepts.
+const MinAmount = 1000
+
+// AmountOk reports whether amount clears MinAmount.
+func AmountOk(amount uint64) bool {
+	return jet.Le64(MinAmount, amount)
+}
+
+// SameKey reports whether two x-only keys are equal.
+func SameKey(a [32]byte, b [32]byte) bool {
+	return jet.Eq256(a, b)
+}
+
+// AtLeast reports whether v reaches floor, at any unsigned width.
+func AtLeast[T ~uint32 | ~uint64](v, floor T) bool {
+	return v >= floor
+}
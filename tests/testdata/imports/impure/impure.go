@@ -0,0 +1,19 @@
+// Package impure keeps mutable state and cannot be compiled into a contract.
+package impure
+
+var counter uint64
+
+// Bump increments the shared counter.
+func Bump() uint64 {
+	counter++
+	return counter
+}
+
+// Total sums a slice, which a contract cannot declare.
+func Total(amounts []uint64) uint64 {
+	var total uint64
+	for i := 0; i < 4; i++ {
+		total += amounts[i]
+	}
+	return total
+}
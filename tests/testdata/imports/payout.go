@@ -0,0 +1,15 @@
+//go:build ignore
+
+package main
+
+import (
+	"simplicity/jet"
+
+	"example.com/contracts/checks"
+)
+
+func main() {
+	amount := jet.CurrentAmount()
+	jet.Verify(checks.AmountOk(amount))
+	jet.Verify(jet.Le64(checks.MinAmount, amount))
+}
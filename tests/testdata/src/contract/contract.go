@@ -0,0 +1,22 @@
+// want package:"impure: .*imports impure"
+
+//simplicity:contract
+package main
+
+import (
+	"impure" // want `imported package impure is not pure: .*declares package-level variables \[SIM0210\]`
+
+	"simplicity/jet"
+)
+
+func main() {
+	var amounts []uint64             // want `slices are not supported: .* \[SIM0006\]`
+	var count int                    // want `int has no Simplicity equivalent, .* \[SIM0307\]`
+	limits := map[uint64]bool{}      // want `maps are not supported`
+	for jet.Le64(uint64(count), 3) { // want `unbounded loops are not supported`
+		count++
+	}
+	for range amounts { // want `range loops are not supported in Simplicity: .* ranges over amounts \[SIM0002\]`
+	}
+	jet.Verify(limits[impure.Bump()])
+}
@@ -0,0 +1,10 @@
+package main
+
+// sum is in a file without the directive, which is not checked.
+func sum(amounts []uint64) uint64 {
+	var total uint64
+	for _, a := range amounts {
+		total += a
+	}
+	return total
+}
@@ -0,0 +1,9 @@
+package impure
+
+var count uint64
+
+// Bump counts its calls.
+func Bump() uint64 {
+	count++
+	return count
+}
@@ -0,0 +1,22 @@
+package main
+
+import "simplicity/jet"
+
+// RequireHeight returns nothing, so the call it ends in is a statement.
+func RequireHeight(height uint32) {
+	jet.CheckLockHeight(height)
+}
+
+// WithinFee ends in the call that is its result, after the statement that
+// calls RequireHeight.
+func WithinFee(amount uint64, fee uint64, height uint32) bool {
+	RequireHeight(height)
+	limit := amount / 100
+	return jet.Le64(fee, limit)
+}
+
+func main() {
+	var amount, fee uint64
+	var height uint32
+	jet.Verify(WithinFee(amount, fee, height))
+}
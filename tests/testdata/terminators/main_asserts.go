@@ -0,0 +1,14 @@
+package main
+
+import "simplicity/jet"
+
+const OwnerKey = 0xdff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659
+
+func main() {
+	var amount uint64
+	var sig [64]byte
+	jet.Verify(jet.Le64(546, amount))
+	jet.Verify(jet.Le64(amount, 2100000000000000))
+	jet.CheckLockHeight(800000)
+	jet.BIP340Verify(OwnerKey, jet.SigAllHash(), sig)
+}
@@ -0,0 +1,24 @@
+package main
+
+import "simplicity/jet"
+
+// Approved counts the approvals that hold and requires two: a function that
+// ends in a match, after the lets of its conditions.
+func Approved(a uint32, b uint32, c uint32) bool {
+	n := 0
+	if jet.Eq32(a, 1) {
+		n++
+	}
+	if jet.Eq32(b, 2) {
+		n++
+	}
+	if jet.Eq32(c, 3) {
+		n++
+	}
+	return n >= 2
+}
+
+func main() {
+	var a, b, c uint32
+	jet.Verify(Approved(a, b, c))
+}
@@ -0,0 +1,43 @@
+package main
+
+import "simplicity/jet"
+
+const AliceKey = 0xdff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659
+
+const BobKey = 0xf9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9
+
+const HashLock = 0x425ed4e4a36b30ea21b90e21c712c649e8214c29b7eaf68089d1039c6e55384c
+
+type ClaimData struct {
+	Preimage [32]byte
+	Sig      [64]byte
+}
+
+type RefundData struct {
+	Sig [64]byte
+}
+
+type Spend struct {
+	Claim  *ClaimData
+	Refund *RefundData
+	Cancel *[64]byte
+}
+
+// The arms of the outer match bind lets before their checks, and the
+// second holds the match between the last two paths.
+func main() {
+	var w Spend
+	if w.Claim != nil {
+		hash := jet.SHA256Finalize(jet.SHA256Add32(jet.SHA256Init(), w.Claim.Preimage))
+		jet.Verify(jet.Eq256(hash, HashLock))
+		msg := jet.SigAllHash()
+		jet.BIP340Verify(AliceKey, msg, w.Claim.Sig)
+	} else if w.Refund != nil {
+		jet.CheckLockHeight(800000)
+		msg := jet.SigAllHash()
+		jet.BIP340Verify(BobKey, msg, w.Refund.Sig)
+	} else {
+		msg := jet.SigAllHash()
+		jet.BIP340Verify(AliceKey, msg, *w.Cancel)
+	}
+}
@@ -0,0 +1,32 @@
+//go:build ignore
+
+package main
+
+import "testing"
+
+func TestAmountOk(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount uint64
+		want   bool
+	}{
+		{"exact minimum", 1000, true},
+		{name: "too small", amount: 999, want: false},
+		{"hex", 0x1000, true},
+	}
+	for _, tc := range tests {
+		if got := AmountOk(tc.amount); got != tc.want {
+			t.Errorf("AmountOk(%d) = %v, want %v", tc.amount, got, tc.want)
+		}
+	}
+}
+
+func TestKeyMatches(t *testing.T) {
+	if !KeyMatches([32]byte{0x01, 0x02}, true) {
+		t.Error("expected match")
+	}
+	var key [32]byte
+	if KeyMatches(key, false) {
+		t.Error("expected mismatch")
+	}
+}
@@ -0,0 +1,198 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/testgen"
+)
+
+var testgenContract = filepath.Join("testdata", "testgen", "limits.go")
+
+func TestTestGenExtract(t *testing.T) {
+	suite, warnings, err := testgen.Extract(testgenContract, compiler.Config{})
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+
+	type vec struct {
+		name     string
+		value    string
+		expected bool
+	}
+	want := []vec{
+		{"TestAmountOk/exact minimum", "1000", true},
+		{"TestAmountOk/too small", "999", false},
+		{"TestAmountOk/hex", "4096", true},
+	}
+	if len(suite.Vectors) != len(want)+1 {
+		t.Fatalf("expected %d vectors, got %+v", len(want)+1, suite.Vectors)
+	}
+	for i, w := range want {
+		v := suite.Vectors[i]
+		if v.Name != w.name || v.Entry != "AmountOk" || v.Expected != w.expected {
+			t.Errorf("vector %d: got %+v, want %+v", i, v, w)
+		}
+		if got := v.Witness["AMOUNT"]; got.Value != w.value || got.Type != "u64" {
+			t.Errorf("vector %d: AMOUNT = %+v", i, got)
+		}
+	}
+
+	key := suite.Vectors[3]
+	if key.Entry != "KeyMatches" || !key.Expected {
+		t.Errorf("direct assertion not extracted: %+v", key)
+	}
+	if got := key.Witness["KEY"].Value; got != "0x0102"+strings.Repeat("00", 30) {
+		t.Errorf("KEY = %s", got)
+	}
+
+	// The call with a variable argument is skipped, not guessed.
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Message, "argument key: not a literal") {
+		t.Errorf("expected one non-literal warning, got %v", warnings)
+	}
+}
+
+func TestTestGenWriteJSON(t *testing.T) {
+	suite, _, err := testgen.Extract(testgenContract, compiler.Config{})
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := testgen.WriteJSON(&buf, suite); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var decoded testgen.Suite
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if decoded.Version != testgen.FormatVersion || len(decoded.Vectors) != len(suite.Vectors) {
+		t.Errorf("round trip mismatch: %+v", decoded)
+	}
+}
+
+func TestTestGenWritePairs(t *testing.T) {
+	suite, _, err := testgen.Extract(testgenContract, compiler.Config{})
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	dir := t.TempDir()
+	written, err := testgen.WritePairs(dir, suite, compiler.Config{})
+	if err != nil {
+		t.Fatalf("WritePairs: %v", err)
+	}
+	if len(written) != 2*len(suite.Vectors) {
+		t.Fatalf("expected a .simf and .wit per vector, got %v", written)
+	}
+
+	program, err := os.ReadFile(filepath.Join(dir, "AmountOk_2.simf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(program), "// TestAmountOk/too small: expect reject\n") ||
+		!strings.Contains(string(program), "assert!(amount_ok(witness::AMOUNT));") {
+		t.Errorf("unexpected program\n%s", program)
+	}
+
+	wit, err := os.ReadFile(filepath.Join(dir, "AmountOk_2.wit"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries map[string]testgen.WitnessEntry
+	if err := json.Unmarshal(wit, &entries); err != nil {
+		t.Fatalf("invalid .wit: %v", err)
+	}
+	if entries["AMOUNT"] != (testgen.WitnessEntry{Value: "999", Type: "u64"}) {
+		t.Errorf("unexpected witness file: %s", wit)
+	}
+}
+
+func TestTestGenSumWitnesses(t *testing.T) {
+	dir := t.TempDir()
+	contract := `package spend
+
+const Key = 0xdff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659
+
+type ClaimData struct {
+	Preimage [32]byte
+	Sig      [64]byte
+}
+
+type Spend struct {
+	Claim  *ClaimData
+	Height *uint32
+	Cancel *[64]byte
+}
+
+type Choice struct {
+	IsLeft bool
+	Amount uint64
+	Sig    [64]byte
+}
+
+func Unlock(w Spend) bool {
+	return true
+}
+
+func Pick(c Choice) bool {
+	return true
+}
+`
+	tests := `package spend
+
+import "testing"
+
+func TestUnlock(t *testing.T) {
+	if !Unlock(Spend{Claim: &ClaimData{Preimage: [32]byte{1}, Sig: [64]byte{2}}}) {
+		t.Fatal("claim")
+	}
+	if !Unlock(Spend{Height: &[1]uint32{800000}[0]}) {
+		t.Fatal("height")
+	}
+	if !Unlock(Spend{Cancel: &[64]byte{3}}) {
+		t.Fatal("cancel")
+	}
+	if !Pick(Choice{IsLeft: true, Amount: 5}) {
+		t.Fatal("left")
+	}
+	if !Pick(Choice{Sig: [64]byte{4}}) {
+		t.Fatal("right")
+	}
+}
+`
+	for name, src := range map[string]string{"spend.go": contract, "spend_test.go": tests} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The entries accept any witness; only their shapes are tested.
+	suite, warnings, err := testgen.Extract(filepath.Join(dir, "spend.go"), compiler.Config{AllowTrivialMain: true})
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+
+	hex := func(first string, n int) string { return "0x" + first + strings.Repeat("00", n-1) }
+	want := []string{
+		"Left((" + hex("01", 32) + ", " + hex("02", 64) + "))",
+		"Right(Right(" + hex("03", 64) + "))",
+		"Left(5)",
+		"Right(" + hex("04", 64) + ")",
+	}
+	var got []string
+	for _, v := range suite.Vectors {
+		for _, w := range v.Witness {
+			got = append(got, w.Value)
+		}
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("witness values:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	// &[1]uint32{800000}[0] is not a literal.
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Message, "argument w: height: not a literal") {
+		t.Errorf("expected one non-literal warning, got %v", warnings)
+	}
+}
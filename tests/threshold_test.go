@@ -0,0 +1,293 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+)
+
+// thresholdSource is an entry predicate that counts which of n bool
+// parameters hold and tests the count with test, e.g. "count >= 2".
+func thresholdSource(n int, test string) string {
+	var params, body strings.Builder
+	for i := range n {
+		if i > 0 {
+			params.WriteString(", ")
+		}
+		fmt.Fprintf(&params, "s%d", i)
+		// Each increment form the recognizer accepts.
+		incs := []string{"count++", "count = count + 1", "count += 1", "count = 1 + count"}
+		fmt.Fprintf(&body, "\tif s%d {\n\t\t%s\n\t}\n", i, incs[i%len(incs)])
+	}
+	return fmt.Sprintf("package main\n\nfunc Threshold(%s bool) bool {\n\tcount := 0\n%s\treturn %s\n}\n", params.String(), body.String(), test)
+}
+
+func TestThresholdTreesTruthTable(t *testing.T) {
+	for n := 1; n <= 5; n++ {
+		for k := 0; k <= n+1; k++ {
+			tests := []string{fmt.Sprintf("count >= %d", k), fmt.Sprintf("%d <= count", k)}
+			if k > 0 {
+				tests = append(tests, fmt.Sprintf("count > %d", k-1), fmt.Sprintf("(%d < count)", k-1))
+			}
+			for _, test := range tests {
+				name := fmt.Sprintf("%d of %d, %s", k, n, test)
+				// 0 of n accepts whatever the witnesses.
+				result, err := compiler.New(compiler.Config{Target: "simplicityhl", Entry: "Threshold", AllowTrivialMain: k == 0}).Compile(thresholdSource(n, test), "threshold.go")
+				if err != nil {
+					t.Fatalf("%s: compile: %v", name, err)
+				}
+				if strings.Contains(result, "count") {
+					t.Errorf("%s: counter left in\n%s", name, result)
+				}
+				prog, err := shlparse.Parse(result)
+				if err != nil {
+					t.Fatalf("%s: generated program does not parse: %v\n%s", name, err, result)
+				}
+				for bits := 0; bits < 1<<n; bits++ {
+					witness := make(map[string]string)
+					set := 0
+					for i := range n {
+						witness[fmt.Sprintf("S%d", i)] = fmt.Sprint(bits&(1<<i) != 0)
+						if bits&(1<<i) != 0 {
+							set++
+						}
+					}
+					err := eval.Run(prog, eval.Options{Witness: witness})
+					var rejection *eval.Rejection
+					switch {
+					case set >= k && err != nil:
+						t.Errorf("%s: %d set: expected accept, got %v", name, set, err)
+					case set < k && !errors.As(err, &rejection):
+						t.Errorf("%s: %d set: expected reject, got %v", name, set, err)
+					}
+				}
+			}
+		}
+	}
+}
+
+// countingMultisig is the 2-of-3 counting pattern of a hand-written
+// multisig.
+const countingMultisig = `package main
+
+func MultiSigValidation(sig1Valid bool, sig2Valid bool, sig3Valid bool) bool {
+	validSigs := 0
+
+	if sig1Valid {
+		validSigs = validSigs + 1
+	}
+
+	if sig2Valid {
+		validSigs = validSigs + 1
+	}
+
+	if sig3Valid {
+		validSigs = validSigs + 1
+	}
+
+	return validSigs >= 2
+}
+`
+
+func TestThresholdTreeShape(t *testing.T) {
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl", Entry: "MultiSigValidation"}).Compile(countingMultisig, "multisig.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	want := `fn multi_sig_validation(sig1_valid: bool, sig2_valid: bool, sig3_valid: bool) -> bool {
+    match sig1_valid {
+        true => {
+            match sig2_valid {
+                true => true,
+                false => sig3_valid,
+            }
+        },
+        false => {
+            match sig2_valid {
+                true => sig3_valid,
+                false => false,
+            }
+        },
+    }
+}`
+	if !strings.Contains(result, want) {
+		t.Errorf("missing\n%s\nin\n%s", want, result)
+	}
+
+	// Conditions that are not names are evaluated once, before the tree.
+	source := `package main
+
+import "simplicity/jet"
+
+func Threshold(x uint32, y uint32) bool {
+	n := 0
+	if jet.Eq32(x, 1) {
+		n++
+	}
+	if jet.Eq32(y, 2) {
+		n++
+	}
+	return n >= 2
+}
+`
+	result, err = compiler.New(compiler.Config{Target: "simplicityhl", Entry: "Threshold"}).Compile(source, "threshold.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	if want := "    let t_7_5: bool = jet::eq_32(x, 1);\n    let t_10_5: bool = jet::eq_32(y, 2);\n    match t_7_5 {\n        true => t_10_5,\n        false => false,\n    }\n}"; !strings.Contains(result, want) {
+		t.Errorf("missing %q in\n%s", want, result)
+	}
+
+	// Patterns that only look like counting are left alone.
+	for _, body := range []string{
+		"n := 1\n\tif a {\n\t\tn++\n\t}\n\tif b {\n\t\tn++\n\t}\n\treturn n >= 2",
+		"n := 0\n\tif a {\n\t\tn += 2\n\t}\n\tif b {\n\t\tn++\n\t}\n\treturn n >= 2",
+		"n := 0\n\tif a {\n\t\tn++\n\t} else {\n\t\tn++\n\t}\n\tif b {\n\t\tn++\n\t}\n\treturn n >= 2",
+		"n := 0\n\tif a {\n\t\tn++\n\t}\n\tif b {\n\t\tn++\n\t}\n\treturn n == 2",
+		"n := 0\n\tif a {\n\t\tn++\n\t}\n\tif n > 0 {\n\t\tn++\n\t}\n\treturn n >= 2",
+	} {
+		source := "package main\n\nfunc Threshold(a, b bool) bool {\n\t" + body + "\n}\n"
+		result, err := compiler.New(compiler.Config{Target: "simplicityhl", Entry: "Threshold"}).Compile(source, "threshold.go")
+		if err == nil && strings.Contains(result, "match a {") {
+			t.Errorf("%q: unexpectedly lowered to\n%s", body, result)
+		}
+	}
+	result, err = compiler.New(compiler.Config{Target: "simplicityhl", Entry: "Threshold"}).Compile(thresholdSource(2, "count >= 2"), "threshold.go")
+	if err != nil || !strings.Contains(result, "match s0 {") {
+		t.Errorf("expected the control case to be lowered, got %v\n%s", err, result)
+	}
+
+	// The Config flag turns the lowering off. The statement lowering leaves
+	// the increments of the untyped counter out, so nothing is checked.
+	result, err = compiler.New(compiler.Config{Target: "simplicityhl", Entry: "MultiSigValidation", NoThresholdTrees: true, AllowTrivialMain: true}).Compile(countingMultisig, "multisig.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	if strings.Contains(result, "match sig1_valid {") {
+		t.Errorf("NoThresholdTrees: unexpected comparison tree in\n%s", result)
+	}
+}
+
+func TestSimpleMultisigGolden(t *testing.T) {
+	prog, err := shlparse.Parse(compileGolden(t, "simple_multisig"))
+	if err != nil {
+		t.Fatalf("generated program does not parse: %v", err)
+	}
+	for bits := 0; bits < 8; bits++ {
+		witness := map[string]string{
+			"SIG1": fmt.Sprint(bits&1 != 0),
+			"SIG2": fmt.Sprint(bits&2 != 0),
+			"SIG3": fmt.Sprint(bits&4 != 0),
+		}
+		err := eval.Run(prog, eval.Options{Witness: witness})
+		signed := bits&1 + bits>>1&1 + bits>>2&1
+		var rejection *eval.Rejection
+		switch {
+		case signed >= 2 && err != nil:
+			t.Errorf("%v: expected accept, got %v", witness, err)
+		case signed < 2 && !errors.As(err, &rejection):
+			t.Errorf("%v: expected reject, got %v", witness, err)
+		}
+	}
+}
+
+func TestStdThreshold(t *testing.T) {
+	source := func(call string, n int) string {
+		vars := make([]string, n)
+		for i := range vars {
+			vars[i] = fmt.Sprintf("c%d", i)
+		}
+		decl := ""
+		if n > 0 {
+			decl = "var " + strings.Join(vars, ", ") + " bool\n\t"
+		}
+		return "package main\n\nimport (\n\t\"simplicity/jet\"\n\n\t\"github.com/0ceanslim/go-simplicity/std\"\n)\n\nconst K = 2\n\nfunc main() {\n\t" + decl + strings.ReplaceAll(call, "CONDS", strings.Join(vars, ", ")) + "\n}\n"
+	}
+
+	for n := 1; n <= 5; n++ {
+		for k := 1; k <= n; k++ {
+			name := fmt.Sprintf("%d of %d", k, n)
+			result, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source(fmt.Sprintf("jet.Verify(std.Threshold(%d, CONDS))", k), n), "contract.go")
+			if err != nil {
+				t.Fatalf("%s: compile: %v", name, err)
+			}
+			if want := fmt.Sprintf("fn std_threshold_%d_of_%d(", k, n); !strings.Contains(result, want) {
+				t.Errorf("%s: missing %q in\n%s", name, want, result)
+			}
+			if matches := strings.Count(result, "match "); matches > k*n {
+				t.Errorf("%s: %d matches, more than k×n", name, matches)
+			}
+			prog, err := shlparse.Parse(result)
+			if err != nil {
+				t.Fatalf("%s: generated program does not parse: %v\n%s", name, err, result)
+			}
+			for bits := 0; bits < 1<<n; bits++ {
+				witness := make(map[string]string)
+				set := 0
+				for i := range n {
+					witness[fmt.Sprintf("C%d", i)] = fmt.Sprint(bits&(1<<i) != 0)
+					if bits&(1<<i) != 0 {
+						set++
+					}
+				}
+				err := eval.Run(prog, eval.Options{Witness: witness})
+				var rejection *eval.Rejection
+				switch {
+				case set >= k && err != nil:
+					t.Errorf("%s: %d set: expected accept, got %v", name, set, err)
+				case set < k && !errors.As(err, &rejection):
+					t.Errorf("%s: %d set: expected reject, got %v", name, set, err)
+				}
+			}
+		}
+	}
+
+	// A local, a constant k and a helper shared by two calls.
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source("ok := std.Threshold(K, CONDS)\n\tjet.Verify(ok)\n\tjet.Verify(std.Threshold(2, c2, c1, c0))", 3), "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"let ok: bool = std_threshold_2_of_3(c0, c1, c2);",
+		"assert!(std_threshold_2_of_3(c2, c1, c0));",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+	if n := strings.Count(result, "fn std_threshold_2_of_3("); n != 1 {
+		t.Errorf("std_threshold_2_of_3 defined %d times", n)
+	}
+
+	// The circuit stays small at the largest arity.
+	result, err = compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source("jet.Verify(std.Threshold(8, CONDS))", 16), "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	if matches := strings.Count(result, "match "); matches > 8*16 {
+		t.Errorf("8 of 16: %d matches", matches)
+	}
+
+	for _, tt := range []struct {
+		call string
+		n    int
+		want string
+	}{
+		{"jet.Verify(std.Threshold(4, CONDS))", 3, "std.Threshold: k is 4, more than the 3 conditions"},
+		{"jet.Verify(std.Threshold(0, CONDS))", 3, "std.Threshold: k is 0, but at least one condition must hold"},
+		{"jet.Verify(std.Threshold(2, CONDS))", 17, "std.Threshold takes at most 16 conditions, got 17"},
+		{"k := jet.NumInputs()\n\tjet.Verify(std.Threshold(uint8(k), CONDS))", 3, "std.Threshold: k must be a compile-time constant"},
+		{"conds := [2]bool{c0, c1}\n\tjet.Verify(std.Threshold(1, conds[:]...))", 2, "std.Threshold takes its conditions as separate arguments, not a slice"},
+		{"std.Threshold(1, CONDS)", 2, "std.Threshold is evaluated but not used"},
+	} {
+		_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source(tt.call, tt.n), "contract.go")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.call, tt.want, err)
+		}
+	}
+}
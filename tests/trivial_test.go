@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+)
+
+const trivialSource = `package main
+
+import "simplicity/jet"
+
+const Limit uint32 = 10
+
+func main() {
+	var a uint32
+	BODY
+}
+`
+
+func TestTrivialMain(t *testing.T) {
+	tests := []struct {
+		name, body string
+		allow      bool
+		err        string // "" to compile
+		warn       string // "" for no warning
+	}{
+		{"folds to true", "jet.Verify(Limit > 5)", false, "program accepts unconditionally", ""},
+		{"constant jet", "jet.Verify(jet.Le32(Limit, 20))", false, "program accepts unconditionally", ""},
+		{"allowed", "jet.Verify(Limit > 5)", true, "", ""},
+		{"folds to false", "jet.Verify(Limit < 5)", false, "", "contract.go:9:2: program can never be satisfied: no check of main depends on a witness value or the spending transaction, and this one always fails with assertion failed"},
+		{"fails after a check", "jet.Verify(Limit > 5); jet.Verify(Limit < 5)", false, "", "contract.go:9:25: program can never be satisfied"},
+		{"witness", "jet.Verify(jet.Le32(a, Limit))", false, "", ""},
+		{"transaction", "jet.CheckLockHeight(Limit)", false, "", ""},
+	}
+	for _, tt := range tests {
+		c := compiler.New(compiler.Config{Target: "simplicityhl", AllowTrivialMain: tt.allow})
+		_, err := c.Compile(strings.Replace(trivialSource, "BODY", tt.body, 1), "contract.go")
+		switch {
+		case tt.err != "":
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: expected %q, got %v", tt.name, tt.err, err)
+			}
+			continue
+		case err != nil:
+			t.Errorf("%s: compile: %v", tt.name, err)
+			continue
+		}
+		warnings := c.Warnings()
+		if tt.warn == "" && len(warnings) != 0 {
+			t.Errorf("%s: unexpected warnings %v", tt.name, warnings)
+		}
+		if tt.warn != "" && (len(warnings) != 1 || !strings.Contains(warnings[0], tt.warn)) {
+			t.Errorf("%s: warnings %v, want %q", tt.name, warnings, tt.warn)
+		}
+	}
+}
+
+func TestTrivialMainLibraryMode(t *testing.T) {
+	// A library has no main to spend, so the guard does not apply.
+	source := strings.Replace(trivialSource, "BODY", "jet.Verify(Limit > 5)", 1) + "\nfunc Check() bool {\n\treturn jet.Le32(Limit, 20)\n}\n"
+	c := compiler.New(compiler.Config{Target: "simplicityhl", Mode: "library"})
+	if _, err := c.Compile(source, "contract.go"); err != nil {
+		t.Errorf("library mode: %v", err)
+	}
+}
+
+// TestTrivialMainOptLevels checks that a witness given to a helper that
+// never reads it fails the compile whether the helper is called, at -O0, or
+// inlined, and that one the helper reads, directly or through another
+// helper, does not.
+func TestTrivialMainOptLevels(t *testing.T) {
+	tests := []struct {
+		name, helpers string
+		trivial       bool
+	}{
+		{"unread parameter", "func ok(x uint64) bool {\n\treturn true\n}\n", true},
+		{"read parameter", "func ok(x uint64) bool {\n\treturn jet.Le64(1000, x)\n}\n", false},
+		{"read through a helper", "func at(x, min uint64) bool {\n\treturn jet.Le64(min, x)\n}\n\nfunc ok(x uint64) bool {\n\treturn at(x, 1000)\n}\n", false},
+		{"unread through a helper", "func at(min, x uint64) bool {\n\treturn jet.Le64(min, 2000)\n}\n\nfunc ok(x uint64) bool {\n\treturn at(1000, x)\n}\n", true},
+	}
+	for _, tt := range tests {
+		source := "package main\n\nimport \"simplicity/jet\"\n\n" + tt.helpers + "\nfunc main() {\n\tvar x uint64\n\tjet.Verify(ok(x))\n}\n"
+		for _, level := range []compiler.OptLevel{compiler.O0, compiler.O1, compiler.O2} {
+			_, err := compiler.New(compiler.Config{Target: "simplicityhl", OptLevel: level}).Compile(source, "contract.go")
+			if code, _ := diag.CodeOf(err); (code == diag.TrivialMain) != tt.trivial {
+				t.Errorf("%s at %v: expected trivial %v, got %v", tt.name, level, tt.trivial, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"go/parser"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/types"
+)
+
+func TestParseType(t *testing.T) {
+	tests := []struct {
+		in, want string // want is the canonical rendering
+		bits     int    // -1 for unknown
+	}{
+		{"u64", "u64", 64},
+		{"()", "()", 0},
+		{"( u8 ,bool )", "(u8, bool)", 9},
+		{"(u256,)", "(u256,)", 256},
+		{"[u8;32]", "[u8; 32]", 256},
+		{"[[u8; 2]; 3]", "[[u8; 2]; 3]", 48},
+		{"Option<(u32, [u8; 4])>", "Option<(u32, [u8; 4])>", 65},
+		{"Either<u64,()>", "Either<u64, ()>", 65},
+		{"Either<Ctx8, u8>", "Either<Ctx8, u8>", -1},
+	}
+	for _, tt := range tests {
+		typ, err := types.Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if typ.String() != tt.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.in, typ, tt.want)
+		}
+		again, err := types.Parse(typ.String())
+		if err != nil || !again.Equal(typ) {
+			t.Errorf("%q does not read back as itself: %v, %v", typ, again, err)
+		}
+		bits, ok := typ.BitSize()
+		if !ok {
+			bits = -1
+		}
+		if bits != tt.bits {
+			t.Errorf("Parse(%q).BitSize() = %d, want %d", tt.in, bits, tt.bits)
+		}
+	}
+
+	for _, in := range []string{"", "u3", "(u8", "Option<u8, u8>", "[u8; -1]"} {
+		if typ, err := types.Parse(in); err == nil {
+			t.Errorf("Parse(%q) = %v, want an error", in, typ)
+		}
+	}
+}
+
+func TestTypeEqual(t *testing.T) {
+	pairs := []struct {
+		a, b  string
+		equal bool
+	}{
+		{"(u8, bool)", "(u8,bool)", true},
+		{"(u8, bool)", "(bool, u8)", false},
+		{"[u8; 32]", "u256", false},
+		{"[u8; 32]", "[u8; 31]", false},
+		{"Either<u8, u16>", "Either<u8, u16>", true},
+		{"Option<u8>", "Either<(), u8>", false},
+		{"(u8,)", "u8", false},
+		{"Ctx8", "Ctx8", true},
+	}
+	for _, p := range pairs {
+		a, _ := types.Parse(p.a)
+		b, _ := types.Parse(p.b)
+		if a.Equal(b) != p.equal || b.Equal(a) != p.equal {
+			t.Errorf("%s == %s: got %v, want %v", p.a, p.b, a.Equal(b), p.equal)
+		}
+	}
+}
+
+func TestMapType(t *testing.T) {
+	tm := types.NewTypeMapper()
+	for _, tt := range []struct{ goType, want string }{
+		{"uint64", "u64"},
+		{"[4][32]byte", "[[u8; 32]; 4]"},
+		{"struct{ A uint32; B, C bool }", "(u32, bool, bool)"},
+		{"struct{ A uint8 }", "(u8,)"},
+		{"struct{}", "()"},
+		{"Either[uint64, Option[[64]byte]]", "Either<u64, Option<[u8; 64]>>"},
+		{"Escrow", "Escrow"},
+	} {
+		expr, err := parser.ParseExpr(tt.goType)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.goType, err)
+		}
+		typ, err := tm.MapType(expr)
+		if err != nil {
+			t.Errorf("MapType(%s): %v", tt.goType, err)
+			continue
+		}
+		want, err := types.Parse(tt.want)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.want, err)
+		}
+		if !typ.Equal(want) {
+			t.Errorf("MapType(%s) = %s, want %s", tt.goType, typ, tt.want)
+		}
+		if s, _ := tm.MapGoType(expr); s != tt.want {
+			t.Errorf("MapGoType(%s) = %q, want %q", tt.goType, s, tt.want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+)
+
+func TestTypeCheck(t *testing.T) {
+	jets := func(name string) ([]shlparse.Type, shlparse.Type, bool) {
+		if name == "le_32" {
+			return []shlparse.Type{&shlparse.UInt{Bits: 32}, &shlparse.UInt{Bits: 32}}, &shlparse.Bool{}, true
+		}
+		return nil, nil, false
+	}
+	tests := []struct {
+		name, source string
+		want         []string // Error of each mismatch, in order
+	}{
+		{"well typed", `fn f(x: u32) -> bool {
+    let (_, y): (bool, u32) = jet::add_32(x, 1);
+    jet::le_32(y, 10)
+}
+fn g(h: [u8; 32]) -> u256 {
+    h
+}
+fn main() {
+    assert!(f(5));
+}`, nil},
+		{"return", `fn f(x: u32) -> u64 {
+    jet::le_32(x, 10)
+}
+fn main() {
+    let y: u64 = f(1);
+}`, []string{"line 2: fn f returns u64, but its body is bool"}},
+		{"unit body", `fn f(x: u32) {
+    jet::le_32(x, 10)
+}
+fn main() {
+    f(1);
+}`, []string{"line 2: fn f returns (), but its body is bool"}},
+		{"arguments", `fn f(x: u32, ok: bool) -> bool {
+    ok
+}
+fn main() {
+    let t: (bool, u32) = (true, 1);
+    assert!(f(t, jet::le_32(true, 256)));
+}`, []string{
+			"line 6: argument 1 of jet::le_32 is u32, but bool is passed",
+			"line 6: argument 1 of f is u32, but (bool, u32) is passed",
+		}},
+		{"literal too wide", `fn main() {
+    assert!(jet::le_32(0x100000000, 1));
+}`, []string{"line 2: argument 1 of jet::le_32 is u32, but the literal 0x100000000 is passed"}},
+		{"undefined", `fn f(x: u32) -> bool {
+    match jet::le_32(x, limit) {
+        true => ok,
+        false => false,
+    }
+}
+fn main() {
+    let (a, _): (u32, u32) = (1, 2);
+    assert!(f(a));
+    assert!(match witness::W { Some(v) => v, None => b, });
+}`, []string{
+			"line 2: limit is not defined in fn f",
+			"line 3: ok is not defined in fn f",
+			"line 10: b is not defined in fn main",
+		}},
+		{"constants", `mod witness {
+    const R: u32 = 0x00000010;
+    const H: [u8; 4] = 0x00000000;
+}
+mod param {
+    const MAINNET: u64 = false;
+    const LIMIT: u8 = 1;
+}
+fn main() {
+    assert!(jet::le_32(witness::R, 1));
+}`, []string{"line 6: const param::MAINNET is u64, but its value is bool"}},
+		{"unknown types", `fn main() {
+    let x = jet::current_index();
+    assert!(jet::le_32(x, 1));
+}`, nil},
+	}
+	for _, tt := range tests {
+		prog, err := shlparse.Parse(tt.source)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []string
+		for _, e := range shlparse.TypeCheck(prog, jets) {
+			got = append(got, e.Error())
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestTypeMismatchPosition checks that a mistranslated function fails the
+// compile with the Go positions of the function and the call, and both
+// types of each.
+func TestTypeMismatchPosition(t *testing.T) {
+	source := `package main
+
+import "simplicity/jet"
+
+func narrow(a uint64) uint32 {
+	return uint32(a)
+}
+
+func main() {
+	var a uint64
+	jet.Verify(jet.Le32(narrow(a), 10))
+}
+`
+	_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "contract.go")
+	if code, _ := diag.CodeOf(err); code != diag.TypeMismatch {
+		t.Fatalf("expected %s, got %v", diag.TypeMismatch, err)
+	}
+	for _, want := range []string{
+		"contract.go:5:1: narrow returns u32, but its body was translated to an expression of type bool",
+		"contract.go:11:2: argument 1 of jet::le_32 takes u32, but the call was translated to pass an expression of type bool",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in %v", want, err)
+		}
+	}
+}
+
+// TestUndefinedIdentifier checks that a name the translation leaves
+// undefined, such as an initialized package-level var read in main, fails
+// the compile at its Go position rather than passing both checks.
+func TestUndefinedIdentifier(t *testing.T) {
+	source := `package main
+
+import "simplicity/jet"
+
+var limit uint64 = 100
+
+func main() {
+	var x uint64
+	jet.Verify(x <= limit)
+}
+`
+	for _, selfCheck := range []bool{false, true} {
+		_, err := compiler.New(compiler.Config{Target: "simplicityhl", SelfCheck: selfCheck}).Compile(source, "contract.go")
+		want := "contract.go:9:2: undefined identifier limit: main refers to a name the translation does not define"
+		if code, _ := diag.CodeOf(err); code != diag.TypeMismatch || !strings.Contains(err.Error(), want) {
+			t.Errorf("self-check %v: expected an error containing %q, got %v", selfCheck, want, err)
+		}
+	}
+}
+
+// TestModuleConstants checks that a file constant is a param of the type
+// of its value, folded when it is an expression, so that the module
+// constants type-check.
+func TestModuleConstants(t *testing.T) {
+	source := `package main
+
+import "simplicity/jet"
+
+const Mainnet = false
+const Fee uint64 = 1000 + 24
+const Limit = Fee * 2
+
+func main() {
+	var amount uint64
+	jet.Verify(jet.Le64(Limit, amount))
+	jet.Verify(!Mainnet)
+}
+`
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl", SelfCheck: true}).Compile(source, "contract.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "mod param {\n    const MAINNET: bool = false;\n    const FEE: u64 = 1024;\n    const LIMIT: u64 = 2048;\n}"
+	if !strings.Contains(result, want) {
+		t.Errorf("missing %q in\n%s", want, result)
+	}
+}
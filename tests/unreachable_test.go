@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+)
+
+const unreachableSource = `package main
+
+import (
+	"simplicity/jet"
+
+	"github.com/0ceanslim/go-simplicity/std"
+)
+
+type SpendWitness struct {
+	IsLeft bool
+	Owner  uint32
+	Other  uint32
+}
+
+func Never(a uint32) bool {
+	return std.Unreachable()
+}
+
+func main() {
+	var w SpendWitness
+	switch {
+	case w.IsLeft:
+		jet.Verify(jet.Le32(1, w.Owner))
+	default:
+		std.Unreachable()
+	}
+}
+`
+
+func TestStdUnreachable(t *testing.T) {
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(unreachableSource, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	// The default of a switch with one case is the other arm of the match.
+	for _, want := range []string{
+		"fn never(a: u32) -> bool {\n    panic!()\n}",
+		"        Right(sig: u32) => {\n            panic!();\n        }",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+
+	prog, err := shlparse.Parse(result)
+	if err != nil {
+		t.Fatalf("generated program does not parse: %v\n%s", err, result)
+	}
+	for _, tt := range []struct {
+		witness string
+		reject  string // "" to accept
+	}{
+		{"Left(0x00000005)", ""},
+		{"Left(0x00000000)", "assertion failed"},
+		{"Right(0x00000005)", "panic"},
+	} {
+		err := eval.Run(prog, eval.Options{Witness: map[string]string{"W": tt.witness}})
+		var rejection *eval.Rejection
+		switch {
+		case tt.reject == "" && err != nil:
+			t.Errorf("%s: expected accept, got %v", tt.witness, err)
+		case tt.reject != "" && (!errors.As(err, &rejection) || rejection.Reason != tt.reject):
+			t.Errorf("%s: expected %s, got %v", tt.witness, tt.reject, err)
+		}
+	}
+
+	// As a statement of main, in order with the rest.
+	source := "package main\n\nimport (\n\t\"simplicity/jet\"\n\n\t\"github.com/0ceanslim/go-simplicity/std\"\n)\n\nfunc main() {\n\tvar a uint32\n\tjet.Verify(jet.Le32(1, a))\n\tstd.Unreachable()\n}\n"
+	result, err = compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	if want := "    assert!(jet::le_32(1, witness::A));\n    panic!();\n"; !strings.Contains(result, want) {
+		t.Errorf("missing %q in\n%s", want, result)
+	}
+
+	source = strings.Replace(unreachableSource, "std.Unreachable()\n\t}", "std.Unreachable(1)\n\t}", 1)
+	_, err = compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "contract.go")
+	if err == nil || !strings.Contains(err.Error(), "std.Unreachable takes no arguments, got 1") {
+		t.Errorf("expected an argument count error, got %v", err)
+	}
+}
+
+func TestUnreachableBranchWarnings(t *testing.T) {
+	const source = `package main
+
+import (
+	"simplicity/jet"
+
+	"github.com/0ceanslim/go-simplicity/std"
+)
+
+const Mainnet = false
+const Limit uint32 = 10
+
+func limit() uint32 {
+	return 20
+}
+
+func main() {
+	var a uint32
+	BODY
+}
+`
+	tests := []struct {
+		name, body string
+		warn       []string // A substring of each warning, in order
+	}{
+		{"false condition", "if Mainnet {\n\t\tjet.Verify(jet.Le32(1, a))\n\t}",
+			[]string{"contract.go:18:13: the branch is unreachable: Mainnet is always false"}},
+		{"else of a folded comparison", "if limit() > Limit {\n\t\tjet.Verify(jet.Le32(1, a))\n\t} else {\n\t\tjet.Verify(jet.Le32(2, a))\n\t}",
+			[]string{"contract.go:20:9: the else branch is unreachable: limit() > Limit always holds"}},
+		{"switch cases", "switch {\n\tcase Limit < 5:\n\t\tjet.Verify(jet.Le32(1, a))\n\tcase !Mainnet:\n\t\tjet.Verify(jet.Le32(2, a))\n\tcase a > 3:\n\t\tjet.Verify(jet.Le32(3, a))\n\tdefault:\n\t\tjet.Verify(jet.Le32(4, a))\n\t}",
+			[]string{
+				"contract.go:19:2: the case is unreachable: Limit < 5 is always false",
+				"contract.go:23:2: the case is unreachable: the earlier case !Mainnet always holds",
+				"contract.go:25:2: the default case is unreachable: the case !Mainnet always holds",
+			}},
+		{"marked branch", "if Mainnet {\n\t\tstd.Unreachable()\n\t}\n\tjet.Verify(jet.Le32(1, a))", nil},
+		{"witness condition", "if a > Limit {\n\t\tjet.Verify(jet.Le32(1, a))\n\t}", nil},
+		{"shadowed constant", "Mainnet := a > 2\n\tif Mainnet {\n\t\tjet.Verify(jet.Le32(1, a))\n\t}", nil},
+	}
+	for _, tt := range tests {
+		// Only the warnings count: main drops the checks of a plain if
+		c := compiler.New(compiler.Config{Target: "simplicityhl", AllowTrivialMain: true})
+		if _, err := c.Compile(strings.Replace(source, "BODY", tt.body, 1), "contract.go"); err != nil {
+			t.Errorf("%s: compile: %v", tt.name, err)
+			continue
+		}
+		warnings := c.Warnings()
+		if len(warnings) != len(tt.warn) {
+			t.Errorf("%s: warnings %v, want %d", tt.name, warnings, len(tt.warn))
+			continue
+		}
+		for i, want := range tt.warn {
+			if !strings.Contains(warnings[i], want) {
+				t.Errorf("%s: warning %q lacks %q", tt.name, warnings[i], want)
+			}
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/types"
+	"github.com/0ceanslim/go-simplicity/pkg/value"
+)
+
+// TestValueEncoding checks the compact bit encoding against hand-computed
+// vectors: each integer width, bool and unit, and nested products and sums.
+func TestValueEncoding(t *testing.T) {
+	tests := []struct {
+		typ, value, hex string
+	}{
+		{"()", "()", ""},
+		{"bool", "false", "00"},
+		{"bool", "true", "80"},
+		{"u1", "1", "80"},
+		{"u2", "2", "80"},
+		{"u4", "0xa", "a0"},
+		{"u8", "0xab", "ab"},
+		{"u16", "0x1234", "1234"},
+		{"u32", "0xdeadbeef", "deadbeef"},
+		{"u64", "1", "0000000000000001"},
+		{"u128", "0x0102030405060708090a0b0c0d0e0f10", "0102030405060708090a0b0c0d0e0f10"},
+		{"u256", "0x00ff0000000000000000000000000000000000000000000000000000000000ee", "00ff0000000000000000000000000000000000000000000000000000000000ee"},
+		{"[u8; 3]", "0x010203", "010203"},
+		{"[u8; 2]", "[1, 2]", "0102"},
+		{"(u8, bool)", "(0xff, true)", "ff80"},
+		{"[bool; 3]", "[true, false, true]", "a0"},
+		{"Either<u8, ()>", "Left(1)", "0080"},
+		{"Either<u8, ()>", "Right(())", "80"},
+		{"Option<u16>", "Some(0x0102)", "808100"},
+		{"Option<u16>", "None", "00"},
+		{"Either<(bool, u4), [u8; 2]>", "Left((true, 3))", "4c"},
+		{"Either<(bool, u4), [u8; 2]>", "Right(0xabcd)", "d5e680"},
+		{"(Option<bool>, [(u2, bool); 2])", "(Some(false), [(1, true), (3, false)])", "9e"},
+	}
+	for _, tt := range tests {
+		typ, err := types.Parse(tt.typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := value.Parse(typ, tt.value)
+		if err != nil {
+			t.Errorf("Parse(%s, %s): %v", tt.typ, tt.value, err)
+			continue
+		}
+		got, err := value.Encode(typ, v)
+		if err != nil {
+			t.Errorf("Encode(%s, %s): %v", tt.typ, tt.value, err)
+			continue
+		}
+		if hex.EncodeToString(got) != tt.hex {
+			t.Errorf("Encode(%s, %s) = %x, want %s", tt.typ, tt.value, got, tt.hex)
+		}
+		decoded, err := value.Decode(typ, got)
+		if err != nil {
+			t.Errorf("Decode(%s, %x): %v", tt.typ, got, err)
+			continue
+		}
+		again, err := value.Encode(typ, decoded)
+		if err != nil || !bytes.Equal(again, got) {
+			t.Errorf("%s %s does not decode to itself: %x, %v", tt.typ, tt.value, again, err)
+		}
+	}
+}
+
+func TestValueFormat(t *testing.T) {
+	tests := []struct {
+		typ, value, want string
+	}{
+		{"()", "()", "()"},
+		{"bool", "true", "true"},
+		{"u8", "0xff", "255"},
+		{"u64", "0xffffffffffffffff", "18446744073709551615"},
+		{"u128", "1", "0x00000000000000000000000000000001"},
+		{"[u8; 4]", "[1, 2, 3, 255]", "0x010203ff"},
+		{"[u16; 2]", "[1, 2]", "[1, 2]"},
+		{"(u8,)", "(7,)", "(7,)"},
+		{"(bool, [u8; 2])", "(false, 0xabcd)", "(false, 0xabcd)"},
+		{"Option<u32>", "None", "None"},
+		{"Option<u32>", "Some(9)", "Some(9)"},
+		{"Either<u8, (u4, bool)>", "Right((3, true))", "Right((3, true))"},
+		{"(Either<u8, u8>, [u8; 1])", "(Left(1), [2])", "(Left(1), 0x02)"},
+	}
+	for _, tt := range tests {
+		typ, err := types.Parse(tt.typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := value.Parse(typ, tt.value)
+		if err != nil {
+			t.Errorf("Parse(%s, %s): %v", tt.typ, tt.value, err)
+			continue
+		}
+		got := value.Format(typ, v)
+		if got != tt.want {
+			t.Errorf("Format(%s, %s) = %s, want %s", tt.typ, tt.value, got, tt.want)
+		}
+		if again, err := value.Parse(typ, got); err != nil || value.Format(typ, again) != got {
+			t.Errorf("Format(%s, %s) = %s does not parse back: %v", tt.typ, tt.value, got, err)
+		}
+	}
+
+	if got := value.Display("Ctx8", "ctx"); got != "ctx" {
+		t.Errorf("Display of an unknown type = %q, want the literal", got)
+	}
+}
+
+func TestValueErrors(t *testing.T) {
+	parse := []struct{ typ, value string }{
+		{"u8", "256"},
+		{"u8", "true"},
+		{"[u8; 2]", "0x010203"},
+		{"(u8, u8)", "(1, 2, 3)"},
+		{"Option<u8>", "Left(1)"},
+		{"Ctx8", "0"},
+	}
+	for _, tt := range parse {
+		typ, _ := types.Parse(tt.typ)
+		if v, err := value.Parse(typ, tt.value); err == nil {
+			t.Errorf("Parse(%s, %s) = %v, want an error", tt.typ, tt.value, v)
+		}
+	}
+
+	decode := []struct {
+		typ, hex string
+	}{
+		{"u16", "12"},        // Too short
+		{"u8", "1234"},       // A byte left over
+		{"bool", "c0"},       // Nonzero padding
+		{"Option<u8>", "80"}, // Some without its payload
+		{"(u4, u8)", "12"},   // Ends inside the u8
+	}
+	for _, tt := range decode {
+		typ, _ := types.Parse(tt.typ)
+		data, _ := hex.DecodeString(tt.hex)
+		if v, err := value.Decode(typ, data); err == nil {
+			t.Errorf("Decode(%s, %s) = %v, want an error", tt.typ, tt.hex, v)
+		}
+	}
+}
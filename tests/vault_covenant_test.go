@@ -0,0 +1,220 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/eval"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+)
+
+func TestVaultCovenantGolden(t *testing.T) {
+	result := compileGolden(t, "vault_covenant")
+	if n := strings.Count(result, "fn std_require_output("); n != 1 {
+		t.Errorf("std_require_output defined %d times", n)
+	}
+}
+
+func TestVaultCovenantSpendPaths(t *testing.T) {
+	prog, err := shlparse.Parse(compileGolden(t, "vault_covenant"))
+	if err != nil {
+		t.Fatalf("generated program does not parse: %v", err)
+	}
+	txJSON := string(readExample(t, "vault_covenant.tx.json"))
+	hot, err := eval.ParseWitnessJSON(readExample(t, "vault_covenant.witness.json"))
+	if err != nil {
+		t.Fatalf("ParseWitnessJSON: %v", err)
+	}
+	sweep := map[string]string{"W": "Right(())"}
+	const unvault = "0xa1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8e9f0a1b2"
+	const cold = "0x7d865e959b2466918c9863afca942d0fb89d7c9ac0c99bafc3749504ded97730"
+
+	tests := []struct {
+		name    string
+		witness map[string]string
+		edit    func(string) string
+		reject  string // "" to accept
+	}{
+		{"hot key unvaults", hot, nil, ""},
+		{"hot key to the cold script", hot, func(s string) string { return strings.Replace(s, unvault, cold, 1) }, "assertion failed"},
+		{"hot key short of the value", hot, func(s string) string {
+			output := "\"script_hash\": \"" + unvault
+			return strings.Replace(s, "\"value\": 100000,\n      "+output, "\"value\": 99999,\n      "+output, 1)
+		}, "assertion failed"},
+		{"sweep to the cold script", sweep, func(s string) string { return strings.Replace(s, unvault, cold, 1) }, ""},
+		{"sweep to the unvault script", sweep, nil, "assertion failed"},
+		{"sweep too early", sweep, func(s string) string {
+			return strings.Replace(strings.Replace(s, unvault, cold, 1), `"sequence": 144`, `"sequence": 143`, 1)
+		}, "assertion failed"},
+		{"sweep on the age of the fee input", sweep, func(s string) string {
+			s = strings.Replace(strings.Replace(s, unvault, cold, 1), `"sequence": 144`, `"sequence": 10`, 1)
+			return strings.Replace(s, `"value": 500,`, `"value": 500, "sequence": 144,`, 1)
+		}, "assertion failed"},
+		{"sweep with relative locks disabled", sweep, func(s string) string {
+			return strings.Replace(strings.Replace(s, unvault, cold, 1), `"sequence": 144`, `"sequence": 2147483792`, 1) // 0x80000090
+		}, "assertion failed"},
+		{"sweep with a time-based lock", sweep, func(s string) string {
+			return strings.Replace(strings.Replace(s, unvault, cold, 1), `"sequence": 144`, `"sequence": 4194448`, 1) // 0x00400090
+		}, "assertion failed"},
+		{"sweep in a version 1 transaction", sweep, func(s string) string {
+			return strings.Replace(strings.Replace(s, unvault, cold, 1), `"version": 2`, `"version": 1`, 1)
+		}, "assertion failed"},
+		{"sweep paying its fee from the vault", sweep, func(s string) string {
+			return strings.Replace(strings.Replace(s, unvault, cold, 1), `"value": 100000,
+      "script_hash": "`+cold, `"value": 99000,
+      "script_hash": "`+cold, 1)
+		}, ""},
+		{"sweep short of the value less the fee", sweep, func(s string) string {
+			return strings.Replace(strings.Replace(s, unvault, cold, 1), `"value": 100000,
+      "script_hash": "`+cold, `"value": 98999,
+      "script_hash": "`+cold, 1)
+		}, "assertion failed"},
+		{"sweep paying too high a fee", sweep, func(s string) string {
+			return strings.Replace(strings.Replace(s, unvault, cold, 1), `"value": 500,
+      "script_hash": "0xe3b0`, `"value": 1001,
+      "script_hash": "0xe3b0`, 1)
+		}, "assertion failed"},
+		{"sweep of a vault worth less than the fee", sweep, func(s string) string {
+			return strings.Replace(strings.Replace(s, unvault, cold, 1), `"value": 100000,`, `"value": 999,`, 1)
+		}, "unwrap_left"},
+	}
+	for _, tt := range tests {
+		data := txJSON
+		if tt.edit != nil {
+			data = tt.edit(data)
+		}
+		tx, err := eval.ParseTx([]byte(data))
+		if err != nil {
+			t.Fatalf("%s: ParseTx: %v", tt.name, err)
+		}
+		err = eval.Run(prog, eval.Options{Witness: tt.witness, Tx: tx})
+		if tt.reject == "" {
+			if err != nil {
+				t.Errorf("%s: expected accept, got %v", tt.name, err)
+			}
+			continue
+		}
+		var rejection *eval.Rejection
+		if !errors.As(err, &rejection) || !strings.Contains(rejection.Reason, tt.reject) {
+			t.Errorf("%s: expected %s to reject, got %v", tt.name, tt.reject, err)
+		}
+	}
+}
+
+func TestRequireOutputErrors(t *testing.T) {
+	tests := []struct {
+		body, want string
+	}{
+		{"std.RequireOutput(0, 1000)", "std.RequireOutput takes an output index, a value and a script hash, got 2 arguments"},
+		{"std.CheckSequenceAtLeast(144, 1)", "std.CheckSequenceAtLeast takes a number of blocks, got 2 arguments"},
+		{"std.CheckSequenceAtLeast(0x400090)", "std.CheckSequenceAtLeast(0x400090): 0x400090 sets bits outside the 16-bit BIP-68 block count"},
+		{"std.CheckSequenceAtLeast(Delay)", "std.CheckSequenceAtLeast(Delay): 0x10000 sets bits outside"},
+		{"std.OutputValueAtLeast(0)", "std.OutputValueAtLeast takes an output index and a minimum value, got 1 arguments"},
+		{"std.FeeAtMost(1, 2)", "std.FeeAtMost takes a maximum fee, got 2 arguments"},
+		{"std.FeeAtMost(Delay - 70000)", "std.FeeAtMost: Delay - 70000 is -4464, which is not a u64 amount"},
+		{"_ = std.KeyAggCoefficientless(Key, Key)\n\tstd.KeyAggCoefficientless(Key, Key)", "std.KeyAggCoefficientless is evaluated but not used"},
+	}
+	for _, tt := range tests {
+		source := `package main
+
+import (
+	"simplicity/jet"
+
+	"github.com/0ceanslim/go-simplicity/std"
+)
+
+const Key = 0xdff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659
+
+const Delay = 65536
+
+func main() {
+	jet.Verify(jet.Le64(1, 2))
+	` + tt.body + `
+}
+`
+		_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "contract.go")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.body, tt.want, err)
+		}
+	}
+
+	// A helper called twice is defined once.
+	source := `package main
+
+import "github.com/0ceanslim/go-simplicity/std"
+
+const Script = 0xa1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8e9f0a1b2
+
+func main() {
+	std.RequireOutput(0, 1000, Script)
+	std.RequireOutput(1, 500, Script)
+}
+`
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	if n := strings.Count(result, "fn std_require_output("); n != 1 {
+		t.Errorf("std_require_output defined %d times:\n%s", n, result)
+	}
+	for _, want := range []string{"std_require_output(0, 1000, param::SCRIPT);", "std_require_output(1, 500, param::SCRIPT);"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+}
+
+func TestOutputValueHelpers(t *testing.T) {
+	source := `package main
+
+import (
+	"simplicity/jet"
+
+	"github.com/0ceanslim/go-simplicity/std"
+)
+
+const MaxFee uint64 = 1000
+
+func main() {
+	value := jet.CurrentAmount()
+	std.FeeAtMost(MaxFee)
+	std.OutputValueAtLeast(0, value-MaxFee)
+	std.OutputValueAtLeast(1, (value - MaxFee - 10))
+	std.OutputValueAtLeast(2, MaxFee-10)
+}
+`
+	result, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(source, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"fn std_output_value_at_least(index: u32, min: u64) {\n    let (_, c_amount): (Asset1, Amount1) = unwrap(jet::output_amount(index));",
+		"    assert!(jet::le_64(min, amount));\n}",
+		"fn std_fee_at_most(max: u64) {\n    let asset: u256 = unwrap_right::<(u1, u256)>(jet::current_asset());\n    assert!(jet::le_64(jet::total_fee(asset), max));\n}",
+		"    let (borrow, difference): (bool, u64) = jet::subtract_64(a, b);\n    unwrap_left::<()>(<bool>::into(borrow));\n    difference\n}",
+		"std_fee_at_most(param::MAX_FEE);",
+		"std_output_value_at_least(0, std_checked_subtract_64(value, param::MAX_FEE));",
+		"std_output_value_at_least(1, std_checked_subtract_64(std_checked_subtract_64(value, param::MAX_FEE), 10));",
+		"std_output_value_at_least(2, 990);",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in\n%s", want, result)
+		}
+	}
+	for _, helper := range []string{"std_output_value_at_least", "std_fee_at_most", "std_checked_subtract_64"} {
+		if n := strings.Count(result, "fn "+helper+"("); n != 1 {
+			t.Errorf("%s defined %d times", helper, n)
+		}
+	}
+
+	// No subtraction, no subtraction helper.
+	result, err = compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(strings.Replace(strings.Replace(source, "value-MaxFee", "value", 1), "(value - MaxFee - 10)", "value", 1), "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	if strings.Contains(result, "std_checked_subtract_64") {
+		t.Errorf("unexpected checked subtraction in\n%s", result)
+	}
+}
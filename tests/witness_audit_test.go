@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/diag"
+	"github.com/0ceanslim/go-simplicity/pkg/report"
+	"github.com/0ceanslim/go-simplicity/pkg/types"
+)
+
+func TestBitSizeCompound(t *testing.T) {
+	tm := types.NewTypeMapper()
+	tests := []struct {
+		typ  string
+		want int
+	}{
+		{"(u64, bool)", 65},
+		{"(u32, (u8, [u8; 2]))", 56},
+		{"()", 0},
+		{"Option<u256>", 257},
+		{"Option<()>", 1},
+		{"Either<u64, ()>", 65},
+		{"Either<(u32, u32), u8>", 65},
+		{"[[u8; 32]; 4]", 1024},
+		{"[Option<u8>; 3]", 27},
+		{"Either<[u8; 64], Option<(bool, u16)>>", 513},
+		{"Ctx8", 0},
+	}
+	for _, tt := range tests {
+		if got := tm.GetBitSize(tt.typ); got != tt.want {
+			t.Errorf("GetBitSize(%q) = %d, want %d", tt.typ, got, tt.want)
+		}
+	}
+}
+
+func TestWitnessAudit(t *testing.T) {
+	source := `package main
+
+import "simplicity/jet"
+
+const MinFee = 100
+
+func main() {
+	var outputs [16][32]byte
+	var sig [64]byte
+	var fee uint64 = MinFee * 2
+	var amount uint64 = 1000
+	jet.Verify(jet.Le64(fee, amount))
+	jet.BIP340Verify(outputs[0], outputs[1], sig)
+}
+`
+	c := compiler.New(compiler.Config{Target: "simplicityhl"})
+	if _, err := c.Compile(source, "audit.go"); err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	warnings := strings.Join(c.Warnings(), "\n")
+	for _, want := range []string{
+		"audit.go:8:6: witness OUTPUTS is 4096 bits, more than the limit of 2048",
+		"audit.go:10:6: witness fee is computed from constants alone",
+	} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("missing %q in warnings:\n%s", want, warnings)
+		}
+	}
+	if codes := diag.Find(warnings); len(codes) != 2 || codes[0] != diag.LargeWitness || codes[1] != diag.ConstantWitness {
+		t.Errorf("expected one %s and one %s, got %v", diag.LargeWitness, diag.ConstantWitness, codes)
+	}
+
+	r := report.New("audit.go", c.Result())
+	var sizes []int
+	for _, w := range r.Programs[0].Witnesses {
+		sizes = append(sizes, w.Bits)
+	}
+	if got, want := sizes, []int{4096, 512, 64, 64}; !slices.Equal(got, want) {
+		t.Errorf("witness sizes = %v, want %v", got, want)
+	}
+	if r.Programs[0].WitnessBits != 4736 {
+		t.Errorf("witness_bits = %d, want 4736", r.Programs[0].WitnessBits)
+	}
+
+	c = compiler.New(compiler.Config{Target: "simplicityhl", MaxWitnessBits: -1})
+	if _, err := c.Compile(source, "audit.go"); err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	for _, w := range c.Warnings() {
+		if strings.Contains(w, string(diag.LargeWitness)) {
+			t.Errorf("warned with no limit: %s", w)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+	"github.com/0ceanslim/go-simplicity/pkg/shlparse"
+	"github.com/0ceanslim/go-simplicity/pkg/types"
+)
+
+const byteWitnesses = `
+package main
+
+import "simplicity/jet"
+
+func main() {
+	var addr [20]byte = [20]byte{0xab, 0xcd}
+	var preimage [32]byte = [32]byte{}
+	var digest [32]byte = jet.SHA256Finalize(jet.SHA256Init())
+	var sig [64]byte
+	var lock u256 = 0x1234
+	var small u256 = 7
+	var amount uint64 = 0x10
+	jet.Verify(jet.Eq256(lock, small))
+	_, _, _, _, _ = addr, preimage, digest, sig, amount
+}
+`
+
+func TestByteArrayWitnessEncoding(t *testing.T) {
+	c := compiler.New(compiler.Config{Target: "simplicityhl"})
+	out, err := c.Compile(byteWitnesses, "bytes.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"const ADDR: [u8; 20] = 0xabcd" + strings.Repeat("00", 18) + ";",
+		"const PREIMAGE: [u8; 32] = 0x" + strings.Repeat("00", 32) + ";",
+		// Computed at spend time, so only a placeholder of the right width.
+		"const DIGEST: [u8; 32] = 0x" + strings.Repeat("00", 32) + ";",
+		"const SIG: [u8; 64] = 0x" + strings.Repeat("00", 64) + ";",
+		"const LOCK: u256 = 0x" + strings.Repeat("0", 60) + "1234;",
+		"const SMALL: u256 = 0x" + strings.Repeat("0", 63) + "7;",
+		"const AMOUNT: u64 = 16;",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+
+	for _, w := range c.Witnesses() {
+		typ, err := shlparse.ParseType(w.Type)
+		if err != nil {
+			t.Fatalf("witness %s: %v", w.Name, err)
+		}
+		expr, err := shlparse.ParseExpr(w.Value)
+		if err == nil {
+			err = shlparse.CheckValue(expr, typ)
+		}
+		if err != nil {
+			t.Errorf("witness %s = %s is not a value of %s: %v", w.Name, w.Value, w.Type, err)
+		}
+	}
+}
+
+func TestWitnessEncodingErrors(t *testing.T) {
+	tests := []struct {
+		decl string
+		want string
+	}{
+		{"var tag [2]byte = [2]byte{1, 2, 3}", "witness TAG: 3 elements do not fit in [u8; 2]"},
+		{"var tag [2]byte = [2]byte{1, 256}", "witness TAG: element 1 is not a byte"},
+		{"var tag [2]byte = 0x123456", "witness TAG: 0x123456 does not fit in [u8; 2]"},
+		{"var tag uint8 = 300", "witness TAG: 300 does not fit in u8"},
+	}
+	for _, tt := range tests {
+		src := "package main\n\nfunc main() {\n\t" + tt.decl + "\n\t_ = tag\n}\n"
+		_, err := compiler.New(compiler.Config{Target: "simplicityhl"}).Compile(src, "tag.go")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q, got %v", tt.decl, tt.want, err)
+		}
+	}
+}
+
+func TestEncodeInt(t *testing.T) {
+	tests := []struct {
+		typ   string
+		value string
+		want  string // "" for an error
+	}{
+		{"[u8; 20]", "0x01", "0x" + strings.Repeat("00", 19) + "01"},
+		{"[u8; 32]", "255", "0x" + strings.Repeat("00", 31) + "ff"},
+		{"[u8; 64]", "0x" + strings.Repeat("ff", 64), "0x" + strings.Repeat("ff", 64)},
+		{"[u8; 64]", "0x1" + strings.Repeat("00", 64), ""},
+		{"u256", "1_000", "0x" + strings.Repeat("0", 61) + "3e8"},
+		{"u256", "0x" + strings.Repeat("f", 65), ""},
+		{"u128", "1", "0x" + strings.Repeat("0", 31) + "1"},
+		{"u64", "0xff", "255"},
+		{"bool", "1", ""},
+	}
+	for _, tt := range tests {
+		n, ok := types.ParseIntLiteral(tt.value)
+		if !ok {
+			t.Fatalf("ParseIntLiteral(%s) failed", tt.value)
+		}
+		got, err := types.EncodeInt(tt.typ, n)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("EncodeInt(%s, %s) = %s, want an error", tt.typ, tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("EncodeInt(%s, %s) = %s, %v; want %s", tt.typ, tt.value, got, err, tt.want)
+		}
+	}
+
+	if got, err := types.EncodeBytes("[u8; 4]", []byte{0xde, 0xad, 0xbe, 0xef}); err != nil || got != "0xdeadbeef" {
+		t.Errorf("EncodeBytes = %s, %v", got, err)
+	}
+	if _, err := types.EncodeBytes("[u8; 32]", make([]byte, 20)); err == nil {
+		t.Error("EncodeBytes accepted 20 bytes for [u8; 32]")
+	}
+	if _, ok := types.ParseIntLiteral("-1"); ok {
+		t.Error("ParseIntLiteral accepted a negative value")
+	}
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+)
+
+const spendPath = `
+package main
+
+import "simplicity/jet"
+
+func Spend(key [32]byte, flag bool, amount uint64) bool {
+	return jet.Le64(1000, amount)
+}
+`
+
+func compileWithWitnessValues(values map[string]string) (string, error) {
+	c := compiler.New(compiler.Config{Target: "simplicityhl", Entry: "Spend", WitnessValues: values})
+	return c.Compile(spendPath, "spend.go")
+}
+
+func TestWitnessValuesSubstituted(t *testing.T) {
+	key := "0x" + strings.Repeat("ab", 32)
+	result, err := compileWithWitnessValues(map[string]string{"KEY": key, "AMOUNT": "1000"})
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	for _, want := range []string{
+		"const KEY: [u8; 32] = " + key + ";",
+		"const AMOUNT: u64 = 1000;",
+		// Witnesses without a value keep the placeholder.
+		"const FLAG: bool = false;",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected %q in output\n%s", want, result)
+		}
+	}
+}
+
+func TestWitnessValuesRejected(t *testing.T) {
+	cases := []struct {
+		name   string
+		values map[string]string
+		want   string
+	}{
+		{"unknown name", map[string]string{"AMOUNT_TYPO": "1"}, "unknown witness AMOUNT_TYPO (declared: KEY, FLAG, AMOUNT)"},
+		{"short byte array", map[string]string{"KEY": "0xabcd"}, "witness KEY: expected [u8; 32], got 0xabcd (4 hex digits, want 64)"},
+		{"decimal byte array", map[string]string{"KEY": "5"}, "byte arrays are written in hex"},
+		{"overflow", map[string]string{"AMOUNT": "18446744073709551616"}, "witness AMOUNT: expected u64, got 18446744073709551616 (out of range)"},
+		{"short hex", map[string]string{"AMOUNT": "0x01"}, "expected u64, got 0x01 (2 hex digits, want 16)"},
+		{"wrong kind", map[string]string{"FLAG": "1"}, "witness FLAG: expected bool, got 1"},
+		{"not a literal", map[string]string{"FLAG": "tru e"}, "witness FLAG: expected bool"},
+	}
+	for _, tc := range cases {
+		_, err := compileWithWitnessValues(tc.values)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.want, err)
+		}
+	}
+}
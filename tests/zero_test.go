@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/0ceanslim/go-simplicity/pkg/compiler"
+)
+
+const zeroSource = `package main
+
+import "simplicity/jet"
+
+type Bounds struct {
+	Min uint32
+	Max uint32
+}
+
+type Payment struct {
+	Amount uint64
+	Ok     bool
+	Hash   [32]byte
+	B      Bounds
+	Keys   [2]uint16
+	All    [2]Bounds
+}
+
+type Maybe struct {
+	IsSome bool
+	Value  uint32
+}
+
+var Small uint8
+var Flag bool
+var Wide [16]byte
+var Zero Payment
+
+func inLimit(p Payment, x uint32) bool {
+	return jet.Le32(x, p.B.Max)
+}
+
+func next(x uint32) uint32 {
+	var n uint32
+	var step uint32 = 2
+	return n + step + 1
+}
+
+func main() {
+	var a uint32
+	var m Maybe
+	var pays [2]Payment
+	jet.Verify(inLimit(Payment{Amount: 5}, a))
+	jet.Verify(jet.Le32(next(a), a))
+	_ = m
+	_ = pays
+}
+`
+
+// TestZeroValues checks the zero of every supported type, for a package
+// var, which is a param, a var of main, which is a witness the spender has
+// not set, a local of a helper and a field a struct literal leaves out.
+func TestZeroValues(t *testing.T) {
+	out, err := compiler.New(compiler.Config{Target: "simplicityhl", SelfCheck: true}).Compile(zeroSource, "contract.go")
+	if err != nil {
+		t.Fatalf("Compilation failed: %v", err)
+	}
+	payment := "(0x0000000000000000, false, 0x0000000000000000000000000000000000000000000000000000000000000000, (0x00000000, 0x00000000), [0x0000, 0x0000], [(0x00000000, 0x00000000), (0x00000000, 0x00000000)])"
+	for _, want := range []string{
+		"const SMALL: u8 = 0x00;",
+		"const FLAG: bool = false;",
+		"const WIDE: [u8; 16] = 0x00000000000000000000000000000000;",
+		"const ZERO: (u64, bool, [u8; 32], (u32, u32), [u16; 2], [(u32, u32); 2]) = " + payment + ";",
+		"const A: u32 = 0x00000000;",
+		"const M: Option<u32> = None;",
+		"const PAYS: [(u64, bool, [u8; 32], (u32, u32), [u16; 2], [(u32, u32); 2]); 2] = [" + payment + ", " + payment + "];",
+		"    let n: u32 = 0x00000000;\n    let step: u32 = 2;\n    3\n",
+		"assert!(in_limit((5" + strings.TrimPrefix(payment, "(0x0000000000000000") + ", a));",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+}